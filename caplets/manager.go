@@ -1,6 +1,8 @@
 package caplets
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -8,8 +10,6 @@ import (
 	"sort"
 	"strings"
 	"sync"
-
-	"github.com/evilsocket/islazy/fs"
 )
 
 var (
@@ -73,14 +73,11 @@ func Load(name string) (*Caplet, error) {
 				Name:    baseName,
 				Scripts: make([]Script, 0),
 			}
-			cache[name] = cap
 
-			if reader, err := fs.LineReader(fileName); err != nil {
+			if code, err := readVerified(fileName); err != nil {
 				return nil, fmt.Errorf("error reading caplet %s: %v", fileName, err)
 			} else {
-				for line := range reader {
-					cap.Code = append(cap.Code, line)
-				}
+				cap.Code = code
 
 				// the caplet has a dedicated folder
 				if strings.Contains(baseName, "/") || strings.Contains(baseName, "\\") {
@@ -90,12 +87,12 @@ func Load(name string) (*Caplet, error) {
 						for _, f := range files {
 							subFileName := filepath.Join(dir, f.Name())
 							if subFileName != fileName && (strings.HasSuffix(subFileName, ".cap") || strings.HasSuffix(subFileName, ".js")) {
-								if reader, err := fs.LineReader(subFileName); err == nil {
+								if code, err := readVerified(subFileName); err == nil {
 									script := newScript(subFileName, f.Size())
-									for line := range reader {
-										script.Code = append(script.Code, line)
-									}
+									script.Code = code
 									cap.Scripts = append(cap.Scripts, script)
+								} else if !os.IsNotExist(err) {
+									return nil, fmt.Errorf("error reading %s: %v", subFileName, err)
 								}
 							}
 						}
@@ -103,8 +100,27 @@ func Load(name string) (*Caplet, error) {
 				}
 			}
 
+			cache[name] = cap
 			return cap, nil
 		}
 	}
 	return nil, fmt.Errorf("caplet %s not found", name)
 }
+
+// read the lines of a caplet or script file after verifying its signature
+func readVerified(fileName string) ([]string, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	} else if err = VerifyData(data, fileName+SignatureSuffix); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines, scanner.Err()
+}
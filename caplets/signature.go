@@ -0,0 +1,232 @@
+package caplets
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+// Signatures are in the minisign format (https://jedisct1.github.io/minisign/)
+// and must be created in legacy mode, where the file itself is signed:
+//
+//	minisign -S -l -s bettercap.key -m http-ui.cap
+const (
+	SignatureSuffix = ".minisig"
+	PublicKeySuffix = ".pub"
+
+	sigAlgorithm       = "Ed"
+	sigAlgorithmHashed = "ED"
+	trustedPrefix      = "trusted comment: "
+	untrustedPrefix    = "untrusted comment: "
+)
+
+var (
+	// if true, unsigned caplets and scripts or scripts signed with
+	// a key that is not in the trust store will refuse to load
+	RequireSigned = false
+
+	trustedKeys = make(map[string]*PublicKey)
+	keysLock    = sync.RWMutex{}
+)
+
+type PublicKey struct {
+	ID      string
+	Comment string
+	Key     ed25519.PublicKey
+}
+
+type Signature struct {
+	KeyID   string
+	Comment string
+	Trusted string
+
+	signature []byte
+	global    []byte
+}
+
+func keyID(raw []byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(raw))
+}
+
+// returns the untrusted comment and the decoded payload lines of a minisign file
+func parseMinisign(data string) ([]string, error) {
+	lines := []string{}
+	for _, line := range strings.Split(strings.Replace(data, "\r\n", "\n", -1), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], untrustedPrefix) {
+		return nil, fmt.Errorf("invalid minisign file format")
+	}
+
+	return lines, nil
+}
+
+func ParsePublicKey(data string) (*PublicKey, error) {
+	lines, err := parseMinisign(data)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return nil, fmt.Errorf("error decoding public key: %v", err)
+	} else if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != sigAlgorithm {
+		return nil, fmt.Errorf("unsupported public key format")
+	}
+
+	return &PublicKey{
+		ID:      keyID(raw[2:10]),
+		Comment: strings.TrimPrefix(lines[0], untrustedPrefix),
+		Key:     ed25519.PublicKey(raw[10:]),
+	}, nil
+}
+
+func ParseSignature(data string) (*Signature, error) {
+	lines, err := parseMinisign(data)
+	if err != nil {
+		return nil, err
+	} else if len(lines) != 4 || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return nil, fmt.Errorf("invalid signature format")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signature: %v", err)
+	} else if len(raw) != 2+8+ed25519.SignatureSize {
+		return nil, fmt.Errorf("unsupported signature format")
+	} else if alg := string(raw[:2]); alg == sigAlgorithmHashed {
+		return nil, fmt.Errorf("prehashed signatures are not supported, sign with minisign -l")
+	} else if alg != sigAlgorithm {
+		return nil, fmt.Errorf("unsupported signature algorithm %x", raw[:2])
+	}
+
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil {
+		return nil, fmt.Errorf("error decoding global signature: %v", err)
+	} else if len(global) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("invalid global signature size")
+	}
+
+	return &Signature{
+		KeyID:     keyID(raw[2:10]),
+		Comment:   strings.TrimPrefix(lines[0], untrustedPrefix),
+		Trusted:   strings.TrimPrefix(lines[2], trustedPrefix),
+		signature: raw[10:],
+		global:    global,
+	}, nil
+}
+
+// Verify checks both the signature of data and the one of the trusted comment.
+func (sig *Signature) Verify(key *PublicKey, data []byte) error {
+	if sig.KeyID != key.ID {
+		return fmt.Errorf("signature was created with key %s, not %s", sig.KeyID, key.ID)
+	} else if !ed25519.Verify(key.Key, data, sig.signature) {
+		return fmt.Errorf("invalid signature")
+	} else if !ed25519.Verify(key.Key, append(append([]byte{}, sig.signature...), []byte(sig.Trusted)...), sig.global) {
+		return fmt.Errorf("invalid trusted comment signature")
+	}
+	return nil
+}
+
+// LoadTrustedKeys loads every public key inside the path folder into the trust store.
+func LoadTrustedKeys(path string) error {
+	path, err := fs.Expand(path)
+	if err != nil {
+		return err
+	} else if !fs.Exists(path) {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*"+PublicKeySuffix))
+	if err != nil {
+		return err
+	}
+
+	keysLock.Lock()
+	defer keysLock.Unlock()
+
+	for _, fileName := range files {
+		raw, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", fileName, err)
+		}
+
+		key, err := ParsePublicKey(string(raw))
+		if err != nil {
+			return fmt.Errorf("error loading %s: %v", fileName, err)
+		}
+		trustedKeys[key.ID] = key
+	}
+
+	return nil
+}
+
+func TrustedKeys() []*PublicKey {
+	keysLock.RLock()
+	defer keysLock.RUnlock()
+
+	keys := make([]*PublicKey, 0, len(trustedKeys))
+	for _, key := range trustedKeys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TrustedKey(id string) *PublicKey {
+	keysLock.RLock()
+	defer keysLock.RUnlock()
+	return trustedKeys[id]
+}
+
+// VerifyData verifies data against the signature stored in sigFileName, if the
+// file does not exist an error is returned only if signatures are required.
+func VerifyData(data []byte, sigFileName string) error {
+	raw, err := ioutil.ReadFile(sigFileName)
+	if os.IsNotExist(err) {
+		if RequireSigned {
+			return fmt.Errorf("%s not found and signatures are required", sigFileName)
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	sig, err := ParseSignature(string(raw))
+	if err != nil {
+		return fmt.Errorf("%s: %v", sigFileName, err)
+	}
+
+	key := TrustedKey(sig.KeyID)
+	if key == nil {
+		if RequireSigned {
+			return fmt.Errorf("%s: key %s is not trusted", sigFileName, sig.KeyID)
+		}
+		return nil
+	}
+
+	if err = sig.Verify(key, data); err != nil {
+		return fmt.Errorf("%s: %v", sigFileName, err)
+	}
+
+	return nil
+}
+
+// Verify verifies the signature of a caplet or script file.
+func Verify(fileName string) error {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	return VerifyData(data, fileName+SignatureSuffix)
+}
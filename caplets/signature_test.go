@@ -0,0 +1,85 @@
+package caplets
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"testing"
+)
+
+var testKeyID = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+func testMinisign(t *testing.T, data []byte, trusted string) (string, string, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawKey := append([]byte(sigAlgorithm), testKeyID...)
+	rawKey = append(rawKey, pub...)
+	pubFile := fmt.Sprintf("untrusted comment: minisign public key\n%s\n", base64.StdEncoding.EncodeToString(rawKey))
+
+	sig := ed25519.Sign(priv, data)
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), []byte(trusted)...))
+	rawSig := append([]byte(sigAlgorithm), testKeyID...)
+	rawSig = append(rawSig, sig...)
+	sigFile := fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(rawSig),
+		trusted,
+		base64.StdEncoding.EncodeToString(global))
+
+	return pubFile, sigFile, priv
+}
+
+func TestSignatureVerify(t *testing.T) {
+	data := []byte("set arp.spoof.targets 192.168.1.10\narp.spoof on\n")
+	pubFile, sigFile, _ := testMinisign(t, data, "timestamp:1600000000")
+
+	key, err := ParsePublicKey(pubFile)
+	if err != nil {
+		t.Fatalf("unexpected error parsing public key: %v", err)
+	} else if key.ID != "0807060504030201" {
+		t.Fatalf("unexpected key id %s", key.ID)
+	}
+
+	sig, err := ParseSignature(sigFile)
+	if err != nil {
+		t.Fatalf("unexpected error parsing signature: %v", err)
+	} else if sig.KeyID != key.ID {
+		t.Fatalf("expected key id %s, got %s", key.ID, sig.KeyID)
+	} else if sig.Trusted != "timestamp:1600000000" {
+		t.Fatalf("unexpected trusted comment '%s'", sig.Trusted)
+	}
+
+	if err = sig.Verify(key, data); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	tampered := append([]byte{}, data...)
+	tampered[4] = 'X'
+	if err = sig.Verify(key, tampered); err == nil {
+		t.Fatal("expected tampered data to fail verification")
+	}
+
+	sig.Trusted = "timestamp:1700000000"
+	if err = sig.Verify(key, data); err == nil {
+		t.Fatal("expected tampered trusted comment to fail verification")
+	}
+}
+
+func TestSignatureUnsupported(t *testing.T) {
+	raw := make([]byte, 2+8+ed25519.SignatureSize)
+	copy(raw, sigAlgorithmHashed)
+	sigFile := fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: x\n%s\n",
+		base64.StdEncoding.EncodeToString(raw),
+		base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize)))
+
+	if _, err := ParseSignature(sigFile); err == nil {
+		t.Fatal("expected prehashed signature to be rejected")
+	}
+
+	if _, err := ParseSignature("not a signature"); err == nil {
+		t.Fatal("expected invalid signature to be rejected")
+	}
+}
@@ -34,6 +34,10 @@ func HasBinary(executable string) bool {
 }
 
 func Exec(executable string, args []string) (string, error) {
+	if privHelper != nil && isPrivilegedCommand(executable) {
+		return privHelper.exec(executable, args)
+	}
+
 	path, err := exec.LookPath(executable)
 	if err != nil {
 		return "", err
@@ -18,6 +18,9 @@ type Options struct {
 	MemProfile    *string
 	CapletsPath   *string
 	Script        *string
+	RequireSigned *bool
+	TrustedKeys   *string
+	DropUser      *string
 }
 
 func ParseOptions() (Options, error) {
@@ -37,6 +40,9 @@ func ParseOptions() (Options, error) {
 		MemProfile:    flag.String("mem-profile", "", "Write memory profile to `file`."),
 		CapletsPath:   flag.String("caplets-path", "", "Specify an alternative base path for caplets."),
 		Script:        flag.String("script", "", "Load a session script."),
+		RequireSigned: flag.Bool("require-signed", false, "Refuse to load caplets and scripts that are not signed with a trusted key."),
+		TrustedKeys:   flag.String("trusted-keys", "~/.bettercap-trusted-keys", "Folder containing the minisign public keys trusted to sign caplets and scripts."),
+		DropUser:      flag.String("drop-privileges", "", "If set, once the modules to start, the commands to evaluate and the caplet have been run, switch the session to this user, firewall and interface changes will then be performed by a privileged helper."),
 	}
 
 	flag.Parse()
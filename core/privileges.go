@@ -0,0 +1,193 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// executables that, once privileges have been dropped, are
+// executed by the privileged helper on behalf of the session
+var PrivilegedCommands = []string{
+	"iptables",
+	"ip6tables",
+	"ip",
+	"ifconfig",
+	"iw",
+	"iwconfig",
+	"iwlist",
+	"tc",
+	"ebtables",
+	"sysctl",
+}
+
+// the arguments each of the PrivilegedCommands can be run with, anything
+// that could be used to execute code or touch files as root is rejected
+var privilegedArgs = map[string]func(args []string) error{
+	"iptables":  checkFlags(iptablesFlags),
+	"ip6tables": checkFlags(iptablesFlags),
+	"ebtables":  checkFlags(ebtablesFlags),
+	"ip":        checkIPArgs,
+	"tc":        checkTCArgs,
+	"sysctl":    checkSysctlArgs,
+	"ifconfig":  checkFlags(nil),
+	"iw":        checkFlags(nil),
+	"iwconfig":  checkFlags(nil),
+	"iwlist":    checkFlags(nil),
+}
+
+var (
+	iptablesFlags = []string{
+		"-t", "--table", "-A", "--append", "-D", "--delete", "-I", "--insert",
+		"-C", "--check", "-P", "--policy", "-N", "--new-chain", "-X", "--delete-chain",
+		"-F", "--flush", "-L", "--list", "-n", "--numeric", "-v", "--verbose", "-w", "--wait",
+		"-p", "--protocol", "-s", "--source", "-d", "--destination",
+		"-i", "--in-interface", "-o", "--out-interface", "-m", "--match", "-j", "--jump",
+		"--sport", "--dport", "--sports", "--dports", "--syn", "--tcp-flags", "--icmp-type",
+		"--state", "--ctstate", "--mac-source", "--src-range", "--dst-range", "--mark",
+		"--to", "--to-source", "--to-destination", "--to-ports", "--set-mark",
+		"--queue-num", "--queue-balance", "--queue-bypass",
+	}
+	ebtablesFlags = []string{
+		"-t", "-A", "-D", "-I", "-F", "-L", "-P",
+		"-p", "-s", "-d", "-i", "-o", "-j",
+		"--to-src", "--to-dst", "--snat-target", "--dnat-target",
+	}
+	// objects of the ip command, without netns, exec and the likes
+	ipObjects = []string{"link", "addr", "address", "route", "neigh", "neighbour"}
+	// tc filters and actions loading programs or running other commands
+	tcDenied = []string{"bpf", "ebpf", "xt", "ipt", "exec"}
+)
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFlags accepts any value but only the given options.
+func checkFlags(allowed []string) func(args []string) error {
+	return func(args []string) error {
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-") && !contains(allowed, strings.SplitN(arg, "=", 2)[0]) {
+				return fmt.Errorf("option %s is not allowed", arg)
+			}
+		}
+		return nil
+	}
+}
+
+func checkIPArgs(args []string) error {
+	// only the address family can be selected before the object
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if args[0] == "-4" || args[0] == "-6" {
+			args = args[1:]
+		} else if args[0] == "-f" && len(args) > 1 {
+			args = args[2:]
+		} else {
+			return fmt.Errorf("option %s is not allowed", args[0])
+		}
+	}
+
+	if len(args) == 0 || !contains(ipObjects, args[0]) {
+		return fmt.Errorf("only the %s objects are allowed", strings.Join(ipObjects, ", "))
+	}
+	return checkFlags(nil)(args)
+}
+
+func checkTCArgs(args []string) error {
+	if len(args) == 0 || !contains([]string{"qdisc", "class", "filter"}, args[0]) {
+		return fmt.Errorf("only qdisc, class and filter are allowed")
+	}
+	for _, arg := range args {
+		if contains(tcDenied, arg) {
+			return fmt.Errorf("%s is not allowed", arg)
+		}
+	}
+	return checkFlags(nil)(args)
+}
+
+// only the network parameters can be read or written
+func checkSysctlArgs(args []string) error {
+	if len(args) > 0 && (args[0] == "-w" || args[0] == "-n") {
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("no parameters")
+	}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "net.") || strings.Contains(arg, "/") {
+			return fmt.Errorf("parameter %s is not allowed", arg)
+		}
+	}
+	return nil
+}
+
+// checkPrivilegedArgs returns an error if the privileged helper must not run
+// the executable with the given arguments.
+func checkPrivilegedArgs(executable string, args []string) error {
+	if !isPrivilegedCommand(executable) {
+		return fmt.Errorf("%s can't be executed by the privileged helper", executable)
+	} else if check, found := privilegedArgs[executable]; !found {
+		return fmt.Errorf("%s has no allowed arguments", executable)
+	} else if err := check(args); err != nil {
+		return fmt.Errorf("%s: %v", executable, err)
+	}
+	return nil
+}
+
+type privRequest struct {
+	Executable string   `json:"cmd"`
+	Args       []string `json:"args"`
+}
+
+type privResponse struct {
+	Output string `json:"output"`
+	Error  string `json:"error"`
+}
+
+type privClient struct {
+	sync.Mutex
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+var privHelper = (*privClient)(nil)
+
+func isPrivilegedCommand(executable string) bool {
+	for _, cmd := range PrivilegedCommands {
+		if cmd == executable {
+			return true
+		}
+	}
+	return false
+}
+
+// PrivilegesDropped returns true if the process is running as an
+// unprivileged user with the privileged helper available.
+func PrivilegesDropped() bool {
+	return privHelper != nil
+}
+
+func (c *privClient) exec(executable string, args []string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	var resp privResponse
+	if err := c.enc.Encode(privRequest{Executable: executable, Args: args}); err != nil {
+		return "", fmt.Errorf("error sending request to the privileged helper: %v", err)
+	} else if err = c.dec.Decode(&resp); err != nil {
+		return "", fmt.Errorf("error reading response from the privileged helper: %v", err)
+	} else if resp.Error != "" {
+		return resp.Output, fmt.Errorf("%s", resp.Error)
+	}
+
+	return resp.Output, nil
+}
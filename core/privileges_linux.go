@@ -0,0 +1,176 @@
+// +build linux
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/evilsocket/islazy/str"
+)
+
+const privHelperEnv = "BETTERCAP_PRIV_HELPER_FD"
+
+// DropPrivileges is called once the session has been set up, it starts a
+// privileged helper running the PrivilegedCommands on behalf of the session
+// and then switches the whole process to username. Capabilities are per thread
+// and can't be kept on every thread of a cgo process, so the session keeps none
+// and neither do its children: what has been opened during the setup stays
+// usable, anything else requiring privileges goes through the helper.
+func DropPrivileges(username string) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("bettercap must be started as root in order to drop privileges")
+	}
+
+	usr, err := user.Lookup(username)
+	if err != nil {
+		return err
+	}
+
+	uid, err := strconv.Atoi(usr.Uid)
+	if err != nil {
+		return err
+	}
+
+	gid, err := strconv.Atoi(usr.Gid)
+	if err != nil {
+		return err
+	}
+
+	groups := []int{}
+	if ids, err := usr.GroupIds(); err == nil {
+		for _, id := range ids {
+			if n, err := strconv.Atoi(id); err == nil {
+				groups = append(groups, n)
+			}
+		}
+	}
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+
+	helperFile := os.NewFile(uintptr(fds[0]), "privileged-helper")
+	sessionFile := os.NewFile(uintptr(fds[1]), "privileged-session")
+	defer sessionFile.Close()
+
+	self, err := os.Executable()
+	if err != nil {
+		helperFile.Close()
+		return err
+	}
+
+	// the helper socket is the first of the extra files, hence fd 3
+	cmd := exec.Command(self)
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), privHelperEnv+"=3")
+	cmd.ExtraFiles = []*os.File{helperFile}
+	// out of the process group the terminal sends SIGINT to
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err = cmd.Start()
+	helperFile.Close()
+	if err != nil {
+		return fmt.Errorf("error starting the privileged helper: %v", err)
+	}
+	go cmd.Wait()
+
+	conn, err := net.FileConn(sessionFile)
+	if err != nil {
+		cmd.Process.Kill()
+		return fmt.Errorf("error connecting to the privileged helper: %v", err)
+	}
+
+	if err = switchUser(uid, gid, groups); err != nil {
+		conn.Close()
+		cmd.Process.Kill()
+		return fmt.Errorf("error switching to %s: %v", username, err)
+	}
+
+	os.Setenv("HOME", usr.HomeDir)
+	os.Setenv("USER", usr.Username)
+
+	privHelper = &privClient{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		dec:  json.NewDecoder(conn),
+	}
+
+	return nil
+}
+
+// these apply to all the threads of the process
+func switchUser(uid int, gid int, groups []int) error {
+	if err := syscall.Setgroups(groups); err != nil {
+		return err
+	} else if err = syscall.Setresgid(gid, gid, gid); err != nil {
+		return err
+	}
+	return syscall.Setresuid(uid, uid, uid)
+}
+
+// ServePrivilegedHelper returns false unless this process has been started
+// by DropPrivileges as the privileged helper of a session, in which case it
+// serves its requests until the session exits.
+func ServePrivilegedHelper() bool {
+	fd := os.Getenv(privHelperEnv)
+	if fd == "" {
+		return false
+	}
+	os.Unsetenv(privHelperEnv)
+
+	n, err := strconv.Atoi(fd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid privileged helper descriptor '%s'\n", fd)
+		return true
+	}
+
+	file := os.NewFile(uintptr(n), "privileged-helper")
+	conn, err := net.FileConn(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error starting the privileged helper: %v\n", err)
+		return true
+	}
+
+	servePrivileged(conn)
+	return true
+}
+
+func servePrivileged(conn net.Conn) {
+	defer conn.Close()
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	for {
+		var req privRequest
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+
+		resp := privResponse{}
+		if err := checkPrivilegedArgs(req.Executable, req.Args); err != nil {
+			resp.Error = err.Error()
+		} else if path, err := exec.LookPath(req.Executable); err != nil {
+			resp.Error = err.Error()
+		} else {
+			raw, err := exec.Command(path, req.Args...).CombinedOutput()
+			resp.Output = str.Trim(string(raw))
+			if err != nil {
+				resp.Error = err.Error()
+			}
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
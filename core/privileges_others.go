@@ -0,0 +1,13 @@
+// +build !linux
+
+package core
+
+import "fmt"
+
+func DropPrivileges(username string) error {
+	return fmt.Errorf("dropping privileges is only supported on Linux")
+}
+
+func ServePrivilegedHelper() bool {
+	return false
+}
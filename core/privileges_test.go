@@ -0,0 +1,66 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrivilegedCommandsHaveArgs(t *testing.T) {
+	for _, cmd := range PrivilegedCommands {
+		if _, found := privilegedArgs[cmd]; !found {
+			t.Fatalf("no allowed arguments for %s", cmd)
+		}
+	}
+}
+
+func TestCheckPrivilegedArgs(t *testing.T) {
+	allowed := []string{
+		"iptables -P FORWARD ACCEPT",
+		"iptables -t nat -A PREROUTING -i eth0 -p tcp -d 10.0.0.1 -m mac --mac-source aa:bb:cc:dd:ee:ff --dport 80 -j DNAT --to 10.0.0.2:8080",
+		"iptables -I OUTPUT --destination 8.8.8.8 -j NFQUEUE --queue-num 0 --queue-bypass",
+		"ebtables -t nat -A POSTROUTING -s aa:bb:cc:dd:ee:ff -o eth0 -j snat --to-src 11:22:33:44:55:66",
+		"ip -f inet route",
+		"ip route replace default via 10.0.0.1 dev br0",
+		"ip link set eth0 master br0",
+		"ip neigh replace 10.0.0.1 lladdr aa:bb:cc:dd:ee:ff dev br0 nud permanent",
+		"tc qdisc add dev eth0 root handle 1: htb default 1",
+		"tc filter add dev eth0 protocol ip parent 1:0 prio 1 u32 match ip dst 10.0.0.1/32 flowid 1:2",
+		"sysctl -w net.ipv4.ip_forward=1",
+		"sysctl -w net.ipv6.conf.eth0.disable_ipv6=1",
+		"ifconfig eth0 hw ether aa:bb:cc:dd:ee:ff",
+		"iw dev wlan0 set channel 6",
+		"iw reg set US",
+		"iwconfig wlan0 txpower 30",
+		"iwlist wlan0 freq",
+	}
+
+	denied := []string{
+		"ip netns exec test /bin/sh",
+		"ip -n test link",
+		"ip -batch /tmp/commands",
+		"iptables --modprobe=/tmp/payload -L",
+		"iptables --modprobe /tmp/payload -L",
+		"ebtables --atomic-file /etc/passwd -L",
+		"tc -batch /tmp/commands",
+		"tc filter add dev eth0 parent 1: bpf obj /tmp/prog.o",
+		"sysctl -w kernel.core_pattern=|/tmp/payload",
+		"sysctl -p /tmp/settings",
+		"sysctl -w net.ipv4.ip_forward=1 kernel.modprobe=/tmp/payload",
+		"iw --debug dev wlan0 info",
+		"sh -c id",
+	}
+
+	for _, line := range allowed {
+		parts := strings.Split(line, " ")
+		if err := checkPrivilegedArgs(parts[0], parts[1:]); err != nil {
+			t.Fatalf("'%s' should be allowed: %v", line, err)
+		}
+	}
+
+	for _, line := range denied {
+		parts := strings.Split(line, " ")
+		if err := checkPrivilegedArgs(parts[0], parts[1:]); err == nil {
+			t.Fatalf("'%s' should be denied", line)
+		}
+	}
+}
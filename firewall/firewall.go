@@ -1,8 +1,18 @@
 package firewall
 
+import (
+	"fmt"
+	"strings"
+)
+
 type FirewallManager interface {
 	IsForwardingEnabled() bool
 	EnableForwarding(enabled bool) error
 	EnableRedirection(r *Redirection, enabled bool) error
 	Restore()
 }
+
+// RedirectionResource is the name used by modules to claim the redirection of a port.
+func RedirectionResource(protocol string, port int) string {
+	return fmt.Sprintf("the %s port %d redirection", strings.ToLower(protocol), port)
+}
@@ -27,6 +27,14 @@ type PfFirewall struct {
 	enabled    bool
 }
 
+// Available returns an error if the redirection backend can't be used.
+func Available() error {
+	if !core.HasBinary("pfctl") {
+		return fmt.Errorf("pfctl not found in $PATH")
+	}
+	return nil
+}
+
 func Make(iface *network.Endpoint) FirewallManager {
 	firewall := &PfFirewall{
 		iface:      iface,
@@ -97,9 +105,14 @@ func (f PfFirewall) EnableForwarding(enabled bool) error {
 }
 
 func (f PfFirewall) generateRule(r *Redirection) string {
+	from := "any"
 	src_a := "any"
 	dst_a := "any"
 
+	if r.Client != "" {
+		from = r.Client
+	}
+
 	if r.SrcAddress != "" {
 		src_a = r.SrcAddress
 	}
@@ -108,8 +121,8 @@ func (f PfFirewall) generateRule(r *Redirection) string {
 		dst_a = r.DstAddress
 	}
 
-	return fmt.Sprintf("rdr pass on %s proto %s from any to %s port %d -> %s port %d",
-		r.Interface, r.Protocol, src_a, r.SrcPort, dst_a, r.DstPort)
+	return fmt.Sprintf("rdr pass on %s proto %s from %s to %s port %d -> %s port %d",
+		r.Interface, r.Protocol, from, src_a, r.SrcPort, dst_a, r.DstPort)
 }
 
 func (f *PfFirewall) enable(enabled bool) {
@@ -122,6 +135,10 @@ func (f *PfFirewall) enable(enabled bool) {
 }
 
 func (f PfFirewall) EnableRedirection(r *Redirection, enabled bool) error {
+	if r.ClientIsMAC() {
+		return fmt.Errorf("pf can't redirect the traffic of a MAC address, use its IP address")
+	}
+
 	rule := f.generateRule(r)
 
 	if enabled {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/bettercap/bettercap/core"
 	"github.com/bettercap/bettercap/network"
@@ -23,6 +24,14 @@ const (
 	IPV6ForwardingFile = "/proc/sys/net/ipv6/conf/all/forwarding"
 )
 
+// Available returns an error if the redirection backend can't be used.
+func Available() error {
+	if !core.HasBinary("iptables") {
+		return fmt.Errorf("iptables not found in $PATH")
+	}
+	return nil
+}
+
 func Make(iface *network.Endpoint) FirewallManager {
 	firewall := &LinuxFirewall{
 		iface:        iface,
@@ -43,6 +52,13 @@ func (f LinuxFirewall) enableFeature(filename string, enable bool) error {
 		value = "0"
 	}
 
+	// the unprivileged process can't write to /proc/sys, the helper can
+	if core.PrivilegesDropped() {
+		key := strings.Replace(strings.TrimPrefix(filename, "/proc/sys/"), "/", ".", -1)
+		_, err := core.Exec("sysctl", []string{"-w", key + "=" + value})
+		return err
+	}
+
 	fd, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
 	if err != nil {
 		return err
@@ -77,29 +93,29 @@ func (f *LinuxFirewall) getCommandLine(r *Redirection, enabled bool) (cmdLine []
 		action = "-D"
 	}
 
-	if r.SrcAddress == "" {
-		cmdLine = []string{
-			"-t", "nat",
-			action, "PREROUTING",
-			"-i", r.Interface,
-			"-p", r.Protocol,
-			"--dport", fmt.Sprintf("%d", r.SrcPort),
-			"-j", "DNAT",
-			"--to", fmt.Sprintf("%s:%d", r.DstAddress, r.DstPort),
-		}
-	} else {
-		cmdLine = []string{
-			"-t", "nat",
-			action, "PREROUTING",
-			"-i", r.Interface,
-			"-p", r.Protocol,
-			"-d", r.SrcAddress,
-			"--dport", fmt.Sprintf("%d", r.SrcPort),
-			"-j", "DNAT",
-			"--to", fmt.Sprintf("%s:%d", r.DstAddress, r.DstPort),
-		}
+	cmdLine = []string{
+		"-t", "nat",
+		action, "PREROUTING",
+		"-i", r.Interface,
+		"-p", r.Protocol,
+	}
+
+	if r.SrcAddress != "" {
+		cmdLine = append(cmdLine, "-d", r.SrcAddress)
 	}
 
+	if r.ClientIsMAC() {
+		cmdLine = append(cmdLine, "-m", "mac", "--mac-source", r.Client)
+	} else if r.Client != "" {
+		cmdLine = append(cmdLine, "-s", r.Client)
+	}
+
+	cmdLine = append(cmdLine,
+		"--dport", fmt.Sprintf("%d", r.SrcPort),
+		"-j", "DNAT",
+		"--to", fmt.Sprintf("%s:%d", r.DstAddress, r.DstPort),
+	)
+
 	return
 }
 
@@ -14,6 +14,14 @@ type WindowsFirewall struct {
 	redirections map[string]*Redirection
 }
 
+// Available returns an error if the redirection backend can't be used.
+func Available() error {
+	if !core.HasBinary("netsh") {
+		return fmt.Errorf("netsh not found in $PATH")
+	}
+	return nil
+}
+
 func Make(iface *network.Endpoint) FirewallManager {
 	firewall := &WindowsFirewall{
 		iface:        iface,
@@ -86,6 +94,10 @@ func (f *WindowsFirewall) AllowPort(port int, address string, proto string, allo
 }
 
 func (f *WindowsFirewall) EnableRedirection(r *Redirection, enabled bool) error {
+	if r.Client != "" {
+		return fmt.Errorf("netsh port proxies can't be limited to the traffic of %s", r.Client)
+	}
+
 	if err := f.AllowPort(r.SrcPort, r.DstAddress, r.Protocol, enabled); err != nil {
 		return err
 	} else if err := f.AllowPort(r.DstPort, r.DstAddress, r.Protocol, enabled); err != nil {
@@ -1,6 +1,9 @@
 package firewall
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+)
 
 type Redirection struct {
 	Interface  string
@@ -9,6 +12,8 @@ type Redirection struct {
 	SrcPort    int
 	DstAddress string
 	DstPort    int
+	// only the traffic of this client IP or MAC address is redirected if set
+	Client string
 }
 
 func NewRedirection(iface string, proto string, port_from int, addr_to string, port_to int) *Redirection {
@@ -23,5 +28,15 @@ func NewRedirection(iface string, proto string, port_from int, addr_to string, p
 }
 
 func (r Redirection) String() string {
-	return fmt.Sprintf("[%s] (%s) %s:%d -> %s:%d", r.Interface, r.Protocol, r.SrcAddress, r.SrcPort, r.DstAddress, r.DstPort)
+	s := fmt.Sprintf("[%s] (%s) %s:%d -> %s:%d", r.Interface, r.Protocol, r.SrcAddress, r.SrcPort, r.DstAddress, r.DstPort)
+	if r.Client != "" {
+		s += " for " + r.Client
+	}
+	return s
+}
+
+// ClientIsMAC returns true if the redirection is scoped to a MAC address.
+func (r Redirection) ClientIsMAC() bool {
+	_, err := net.ParseMAC(r.Client)
+	return r.Client != "" && err == nil
 }
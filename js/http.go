@@ -3,6 +3,7 @@ package js
 import (
 	"bytes"
 	"fmt"
+	"github.com/bettercap/bettercap/network"
 	"github.com/robertkrimen/otto"
 	"io"
 	"io/ioutil"
@@ -54,7 +55,7 @@ func (c httpPackage) Request(method string, uri string, headers map[string]strin
 		req.Header.Add(name, value)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := network.DNS.HTTPClient(0).Do(req)
 	if err != nil {
 		return httpResponse{Error: err}
 	}
@@ -100,7 +101,7 @@ func httpRequest(call otto.FunctionCall) otto.Value {
 	method := argv[0].String()
 	url := argv[1].String()
 
-	client := &http.Client{}
+	client := network.DNS.HTTPClient(0)
 	req, err := http.NewRequest(method, url, nil)
 	if argc >= 3 {
 		data := argv[2].String()
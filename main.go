@@ -18,6 +18,11 @@ import (
 )
 
 func main() {
+	// started by a session dropping its privileges
+	if core.ServePrivilegedHelper() {
+		return
+	}
+
 	sess, err := session.New()
 	if err != nil {
 		fmt.Println(err)
@@ -75,6 +80,14 @@ func main() {
 		}
 	}
 
+	// the setup is done, from here on the privileged
+	// commands are run by the helper
+	if *sess.Options.DropUser != "" {
+		if err = core.DropPrivileges(*sess.Options.DropUser); err != nil {
+			log.Fatal("error while dropping privileges: %s", err)
+		}
+	}
+
 	// Eventually start the interactive session.
 	for sess.Active {
 		line, err := sess.ReadLine()
@@ -91,7 +104,7 @@ func main() {
 		}
 
 		for _, cmd := range session.ParseCommands(line) {
-			if err = sess.Run(cmd); err != nil {
+			if err = sess.RunAs(session.LocalOperator, cmd); err != nil {
 				log.Error("%s", err)
 			}
 		}
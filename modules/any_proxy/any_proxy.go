@@ -51,6 +51,8 @@ func NewAnyProxy(s *session.Session) *AnyProxy {
 		"8080",
 		"Port where the proxy is listening."))
 
+	mod.Needs("a redirection backend", firewall.Available)
+
 	mod.AddHandler(session.NewModuleHandler("any.proxy on", "",
 		"Start the custom proxy redirection.",
 		func(args []string) error {
@@ -154,6 +156,16 @@ func (mod *AnyProxy) Configure() error {
 		}
 	}
 
+	claims := make([]string, 0)
+	for _, port := range mod.ports {
+		claims = append(claims, firewall.RedirectionResource(protocol, port))
+	}
+	mod.Claim(claims...)
+
+	if err := mod.CheckDependencies(); err != nil {
+		return err
+	}
+
 	if !mod.Session.Firewall.IsForwardingEnabled() {
 		mod.Info("Enabling forwarding.")
 		mod.Session.Firewall.EnableForwarding(true)
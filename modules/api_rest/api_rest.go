@@ -26,9 +26,11 @@ type RestAPI struct {
 	certFile     string
 	keyFile      string
 	allowOrigin  string
+	operators    map[string]operatorAccount
 	useWebsocket bool
 	upgrader     websocket.Upgrader
 	quit         chan bool
+	backlog      *eventsBacklog
 
 	recClock       int
 	recording      bool
@@ -99,6 +101,11 @@ func NewRestAPI(s *session.Session) *RestAPI {
 		"",
 		"API authentication password."))
 
+	mod.AddParam(session.NewStringParameter("api.rest.operators",
+		"",
+		"",
+		"If set, a JSON file with the list of operators (name, password and allowed command expressions) that can share the session, api.rest.username and api.rest.password will be ignored."))
+
 	mod.AddParam(session.NewStringParameter("api.rest.certificate",
 		"",
 		"",
@@ -115,6 +122,10 @@ func NewRestAPI(s *session.Session) *RestAPI {
 		"false",
 		"If true the /api/events route will be available as a websocket endpoint instead of HTTPS."))
 
+	mod.AddParam(session.NewIntParameter("api.rest.events.backlog",
+		"1024",
+		"Number of events kept for the /api/events/stream and /api/events/poll clients to resume from their cursor."))
+
 	mod.AddHandler(session.NewModuleHandler("api.rest on", "",
 		"Start REST API server.",
 		func(args []string) error {
@@ -186,6 +197,7 @@ func (mod *RestAPI) Configure() error {
 	var err error
 	var ip string
 	var port int
+	var operators string
 
 	if mod.Running() {
 		return session.ErrAlreadyStarted(mod.Name())
@@ -209,6 +221,10 @@ func (mod *RestAPI) Configure() error {
 		return err
 	} else if err, mod.useWebsocket = mod.BoolParam("api.rest.websocket"); err != nil {
 		return err
+	} else if err, operators = mod.StringParam("api.rest.operators"); err != nil {
+		return err
+	} else if err = mod.loadOperators(operators); err != nil {
+		return err
 	}
 
 	if mod.isTLS() {
@@ -239,6 +255,10 @@ func (mod *RestAPI) Configure() error {
 	router.HandleFunc("/api/file", mod.fileRoute)
 
 	router.HandleFunc("/api/events", mod.eventsRoute)
+	router.HandleFunc("/api/events/stream", mod.eventsStreamRoute)
+	router.HandleFunc("/api/events/poll", mod.eventsPollRoute)
+	router.HandleFunc("/api/events/schemas", mod.schemasRoute)
+	router.HandleFunc("/api/events/schemas/{tag}", mod.schemasRoute)
 
 	router.HandleFunc("/api/session", mod.sessionRoute)
 	router.HandleFunc("/api/session/ble", mod.sessionRoute)
@@ -259,7 +279,7 @@ func (mod *RestAPI) Configure() error {
 
 	mod.server.Handler = router
 
-	if mod.username == "" || mod.password == "" {
+	if len(mod.operators) == 0 && (mod.username == "" || mod.password == "") {
 		mod.Warning("api.rest.username and/or api.rest.password parameters are empty, authentication is disabled.")
 	}
 
@@ -271,6 +291,8 @@ func (mod *RestAPI) Start() error {
 		return fmt.Errorf("the api is currently in replay mode, run api.rest.replay off before starting it")
 	} else if err := mod.Configure(); err != nil {
 		return err
+	} else if err := mod.startBacklog(); err != nil {
+		return err
 	}
 
 	mod.SetRunning(true, func() {
@@ -304,6 +326,8 @@ func (mod *RestAPI) Stop() error {
 			mod.quit <- true
 		}()
 
+		mod.stopBacklog()
+
 		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 		defer cancel()
 		mod.server.Shutdown(ctx)
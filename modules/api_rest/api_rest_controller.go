@@ -1,7 +1,6 @@
 package api_rest
 
 import (
-	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -40,6 +39,23 @@ func (mod *RestAPI) toJSON(w http.ResponseWriter, o interface{}) {
 	}
 }
 
+// serializes o without the values the operator can't read
+func (mod *RestAPI) toJSONAs(op *session.Operator, w http.ResponseWriter, o interface{}) {
+	if op.Unrestricted() {
+		mod.toJSON(w, o)
+		return
+	}
+
+	var doc interface{}
+	if raw, err := json.Marshal(o); err != nil {
+		mod.Debug("error while encoding object to JSON: %v", err)
+	} else if err = json.Unmarshal(raw, &doc); err != nil {
+		mod.Debug("error while decoding object from JSON: %v", err)
+	} else {
+		mod.toJSON(w, op.Redact(doc))
+	}
+}
+
 func (mod *RestAPI) setSecurityHeaders(w http.ResponseWriter) {
 	w.Header().Add("X-Frame-Options", "DENY")
 	w.Header().Add("X-Content-Type-Options", "nosniff")
@@ -51,19 +67,6 @@ func (mod *RestAPI) setSecurityHeaders(w http.ResponseWriter) {
 	w.Header().Add("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 }
 
-func (mod *RestAPI) checkAuth(r *http.Request) bool {
-	if mod.username != "" && mod.password != "" {
-		user, pass, _ := r.BasicAuth()
-		// timing attack my ass
-		if subtle.ConstantTimeCompare([]byte(user), []byte(mod.username)) != 1 {
-			return false
-		} else if subtle.ConstantTimeCompare([]byte(pass), []byte(mod.password)) != 1 {
-			return false
-		}
-	}
-	return true
-}
-
 func (mod *RestAPI) patchFrame(buf []byte) (frame map[string]interface{}, err error) {
 	// this is ugly but necessary: since we're replaying, the
 	// api.rest state object is filled with *old* values (the
@@ -92,7 +95,7 @@ func (mod *RestAPI) patchFrame(buf []byte) (frame map[string]interface{}, err er
 	return
 }
 
-func (mod *RestAPI) showSession(w http.ResponseWriter, r *http.Request) {
+func (mod *RestAPI) showSession(op *session.Operator, w http.ResponseWriter, r *http.Request) {
 	if mod.replaying {
 		if !mod.record.Session.Over() {
 			from := mod.record.Session.Index() - 1
@@ -117,7 +120,7 @@ func (mod *RestAPI) showSession(w http.ResponseWriter, r *http.Request) {
 			if frame, err := mod.patchFrame(buf); err != nil {
 				mod.Error("%v", err)
 			} else {
-				mod.toJSON(w, frame)
+				mod.toJSON(w, op.Redact(frame))
 				return
 			}
 		} else {
@@ -125,7 +128,7 @@ func (mod *RestAPI) showSession(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	mod.toJSON(w, mod.Session)
+	mod.toJSONAs(op, w, mod.Session)
 }
 
 func (mod *RestAPI) showBLE(w http.ResponseWriter, r *http.Request) {
@@ -154,8 +157,8 @@ func (mod *RestAPI) showHID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (mod *RestAPI) showEnv(w http.ResponseWriter, r *http.Request) {
-	mod.toJSON(w, mod.Session.Env)
+func (mod *RestAPI) showEnv(op *session.Operator, w http.ResponseWriter, r *http.Request) {
+	mod.toJSONAs(op, w, mod.Session.Env)
 }
 
 func (mod *RestAPI) showGateway(w http.ResponseWriter, r *http.Request) {
@@ -166,8 +169,8 @@ func (mod *RestAPI) showInterface(w http.ResponseWriter, r *http.Request) {
 	mod.toJSON(w, mod.Session.Interface)
 }
 
-func (mod *RestAPI) showModules(w http.ResponseWriter, r *http.Request) {
-	mod.toJSON(w, mod.Session.Modules)
+func (mod *RestAPI) showModules(op *session.Operator, w http.ResponseWriter, r *http.Request) {
+	mod.toJSONAs(op, w, mod.Session.Modules)
 }
 
 func (mod *RestAPI) showLAN(w http.ResponseWriter, r *http.Request) {
@@ -183,8 +186,13 @@ func (mod *RestAPI) showLAN(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (mod *RestAPI) showOptions(w http.ResponseWriter, r *http.Request) {
-	mod.toJSON(w, mod.Session.Options)
+func (mod *RestAPI) showOptions(op *session.Operator, w http.ResponseWriter, r *http.Request) {
+	options := mod.Session.Options
+	// the commands to evaluate can set parameters
+	if !op.Unrestricted() {
+		options.Commands = nil
+	}
+	mod.toJSON(w, options)
 }
 
 func (mod *RestAPI) showPackets(w http.ResponseWriter, r *http.Request) {
@@ -210,7 +218,7 @@ func (mod *RestAPI) showWiFi(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (mod *RestAPI) runSessionCommand(w http.ResponseWriter, r *http.Request) {
+func (mod *RestAPI) runSessionCommand(op *session.Operator, w http.ResponseWriter, r *http.Request) {
 	var err error
 	var cmd CommandRequest
 
@@ -221,7 +229,7 @@ func (mod *RestAPI) runSessionCommand(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, aCommand := range session.ParseCommands(cmd.Command) {
-		if err = mod.Session.Run(aCommand); err != nil {
+		if err = mod.Session.RunAs(op, aCommand); err != nil {
 			http.Error(w, err.Error(), 400)
 			return
 		}
@@ -249,7 +257,7 @@ func (mod *RestAPI) getEvents(limit int) []session.Event {
 	return events[nevents-n:]
 }
 
-func (mod *RestAPI) showEvents(w http.ResponseWriter, r *http.Request) {
+func (mod *RestAPI) showEvents(op *session.Operator, w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 
 	if mod.replaying {
@@ -280,7 +288,7 @@ func (mod *RestAPI) showEvents(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if mod.useWebsocket {
-		mod.startStreamingEvents(w, r)
+		mod.startStreamingEvents(op, w, r)
 	} else {
 		vals := q["n"]
 		limit := 0
@@ -306,11 +314,12 @@ func (mod *RestAPI) corsRoute(w http.ResponseWriter, r *http.Request) {
 func (mod *RestAPI) sessionRoute(w http.ResponseWriter, r *http.Request) {
 	mod.setSecurityHeaders(w)
 
-	if !mod.checkAuth(r) {
+	op := mod.authenticate(r)
+	if op == nil {
 		mod.setAuthFailed(w, r)
 		return
 	} else if r.Method == "POST" {
-		mod.runSessionCommand(w, r)
+		mod.runSessionCommand(op, w, r)
 		return
 	} else if r.Method != "GET" {
 		http.Error(w, "Bad Request", 400)
@@ -323,10 +332,10 @@ func (mod *RestAPI) sessionRoute(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	switch {
 	case path == "/api/session":
-		mod.showSession(w, r)
+		mod.showSession(op, w, r)
 
 	case path == "/api/session/env":
-		mod.showEnv(w, r)
+		mod.showEnv(op, w, r)
 
 	case path == "/api/session/gateway":
 		mod.showGateway(w, r)
@@ -335,13 +344,13 @@ func (mod *RestAPI) sessionRoute(w http.ResponseWriter, r *http.Request) {
 		mod.showInterface(w, r)
 
 	case strings.HasPrefix(path, "/api/session/modules"):
-		mod.showModules(w, r)
+		mod.showModules(op, w, r)
 
 	case strings.HasPrefix(path, "/api/session/lan"):
 		mod.showLAN(w, r)
 
 	case path == "/api/session/options":
-		mod.showOptions(w, r)
+		mod.showOptions(op, w, r)
 
 	case path == "/api/session/packets":
 		mod.showPackets(w, r)
@@ -404,26 +413,57 @@ func (mod *RestAPI) writeFile(fileName string, w http.ResponseWriter, r *http.Re
 func (mod *RestAPI) eventsRoute(w http.ResponseWriter, r *http.Request) {
 	mod.setSecurityHeaders(w)
 
-	if !mod.checkAuth(r) {
+	op := mod.authenticate(r)
+	if op == nil {
 		mod.setAuthFailed(w, r)
 		return
 	}
 
 	if r.Method == "GET" {
-		mod.showEvents(w, r)
+		mod.showEvents(op, w, r)
 	} else if r.Method == "DELETE" {
+		if !op.Can("events.clear") {
+			http.Error(w, "Forbidden", 403)
+			return
+		}
 		mod.clearEvents(w, r)
 	} else {
 		http.Error(w, "Bad Request", 400)
 	}
 }
 
+func (mod *RestAPI) schemasRoute(w http.ResponseWriter, r *http.Request) {
+	mod.setSecurityHeaders(w)
+
+	if op := mod.authenticate(r); op == nil {
+		mod.setAuthFailed(w, r)
+		return
+	} else if r.Method != "GET" {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	tag := mux.Vars(r)["tag"]
+	if tag == "" {
+		mod.toJSON(w, session.EventSchemas())
+	} else if schema := session.EventSchemaFor(tag); schema != nil {
+		mod.toJSON(w, schema)
+	} else {
+		http.Error(w, "Not Found", 404)
+	}
+}
+
 func (mod *RestAPI) fileRoute(w http.ResponseWriter, r *http.Request) {
 	mod.setSecurityHeaders(w)
 
-	if !mod.checkAuth(r) {
+	op := mod.authenticate(r)
+	if op == nil {
 		mod.setAuthFailed(w, r)
 		return
+	} else if !op.Unrestricted() {
+		// reading and writing files is only allowed to operators without restrictions
+		http.Error(w, "Forbidden", 403)
+		return
 	}
 
 	fileName := r.URL.Query().Get("name")
@@ -0,0 +1,283 @@
+package api_rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+)
+
+const (
+	// default and maximum number of seconds a long-poll request is held
+	pollTimeout    = 30
+	pollMaxTimeout = 120
+)
+
+// the last events of the session indexed by id, so that SSE and long-poll
+// clients can resume from a cursor even if the session events are cleared,
+// it's a sink of the session events so they're pushed in sequence order
+type eventsBacklog struct {
+	sync.Mutex
+
+	size   int
+	events []session.Event
+	// closed and replaced every time a new event is added
+	changed chan bool
+	done    chan bool
+}
+
+type EventsPollResponse struct {
+	// id of the last event returned, to be used as the cursor of the next request
+	Cursor uint64 `json:"cursor"`
+	// true if some events after the requested cursor are not available anymore
+	Missed bool            `json:"missed"`
+	Events []session.Event `json:"events"`
+}
+
+func newEventsBacklog(size int) *eventsBacklog {
+	return &eventsBacklog{
+		size:    size,
+		events:  make([]session.Event, 0),
+		changed: make(chan bool),
+		done:    make(chan bool),
+	}
+}
+
+func (b *eventsBacklog) Push(e session.Event) {
+	b.Lock()
+	defer b.Unlock()
+
+	if n := len(b.events); n > 0 && b.events[n-1].ID >= e.ID {
+		return
+	}
+
+	b.events = append(b.events, e)
+
+	if over := len(b.events) - b.size; over > 0 {
+		b.events = b.events[over:]
+	}
+
+	close(b.changed)
+	b.changed = make(chan bool)
+}
+
+// Since returns up to limit events after the cursor, the id of the last one and
+// a channel that will be closed as soon as new events are available.
+func (b *eventsBacklog) Since(cursor uint64, limit int) (events []session.Event, last uint64, missed bool, changed chan bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	last = cursor
+	changed = b.changed
+
+	i := sort.Search(len(b.events), func(i int) bool {
+		return b.events[i].ID > cursor
+	})
+	if i < len(b.events) {
+		missed = cursor > 0 && i == 0 && b.events[0].ID > cursor+1
+		events = b.events[i:]
+		if limit > 0 && len(events) > limit {
+			events = events[:limit]
+		}
+		last = events[len(events)-1].ID
+		events = append([]session.Event{}, events...)
+	}
+
+	return
+}
+
+func (mod *RestAPI) startBacklog() error {
+	err, size := mod.IntParam("api.rest.events.backlog")
+	if err != nil {
+		return err
+	} else if size <= 0 {
+		return fmt.Errorf("api.rest.events.backlog must be greater than 0")
+	}
+
+	mod.backlog = newEventsBacklog(size)
+	mod.Session.Events.AddSink(mod.backlog)
+
+	return nil
+}
+
+func (mod *RestAPI) stopBacklog() {
+	if mod.backlog != nil {
+		mod.Session.Events.RemoveSink(mod.backlog)
+		close(mod.backlog.done)
+	}
+}
+
+func (mod *RestAPI) visibleEvents(events []session.Event) []session.Event {
+	visible := make([]session.Event, 0, len(events))
+	for _, e := range events {
+		if !mod.Session.EventsIgnoreList.Ignored(e) {
+			visible = append(visible, e)
+		}
+	}
+	return visible
+}
+
+// the cursor is the id of the last event received by the client, either from
+// the cursor parameter or from the Last-Event-ID header of reconnecting SSE clients
+func eventsCursor(r *http.Request) (uint64, error) {
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		cursor = r.Header.Get("Last-Event-ID")
+	}
+	if cursor == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(cursor, 10, 64)
+}
+
+func (mod *RestAPI) eventsPollRoute(w http.ResponseWriter, r *http.Request) {
+	mod.setSecurityHeaders(w)
+
+	if op := mod.authenticate(r); op == nil {
+		mod.setAuthFailed(w, r)
+		return
+	} else if r.Method != "GET" {
+		http.Error(w, "Bad Request", 400)
+		return
+	} else if mod.backlog == nil {
+		http.Error(w, "Service Unavailable", 503)
+		return
+	}
+
+	cursor, err := eventsCursor(r)
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 0
+	if n, err := strconv.Atoi(q.Get("n")); err == nil {
+		limit = n
+	}
+	timeout := pollTimeout
+	if n, err := strconv.Atoi(q.Get("timeout")); err == nil && n >= 0 {
+		timeout = n
+	}
+	if timeout > pollMaxTimeout {
+		timeout = pollMaxTimeout
+	}
+
+	resp := EventsPollResponse{
+		Cursor: cursor,
+		Events: make([]session.Event, 0),
+	}
+	deadline := time.After(time.Duration(timeout) * time.Second)
+
+	for {
+		events, last, missed, changed := mod.backlog.Since(resp.Cursor, limit)
+
+		resp.Cursor = last
+		resp.Missed = resp.Missed || missed
+		resp.Events = mod.visibleEvents(events)
+		if len(resp.Events) > 0 {
+			break
+		}
+
+		select {
+		case <-changed:
+			continue
+		case <-deadline:
+		case <-r.Context().Done():
+		case <-mod.backlog.done:
+		}
+		break
+	}
+
+	mod.toJSON(w, resp)
+}
+
+func (mod *RestAPI) writeSSE(w http.ResponseWriter, id uint64, tag string, data interface{}) error {
+	msg, err := json.Marshal(data)
+	if err != nil {
+		mod.Error("Error while creating SSE message: %s", err)
+		return nil
+	}
+
+	if id > 0 {
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, tag, msg)
+	} else {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", tag, msg)
+	}
+	return err
+}
+
+func (mod *RestAPI) eventsStreamRoute(w http.ResponseWriter, r *http.Request) {
+	mod.setSecurityHeaders(w)
+
+	if op := mod.authenticate(r); op == nil {
+		mod.setAuthFailed(w, r)
+		return
+	} else if r.Method != "GET" {
+		http.Error(w, "Bad Request", 400)
+		return
+	} else if mod.backlog == nil {
+		http.Error(w, "Service Unavailable", 503)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming Not Supported", 500)
+		return
+	}
+
+	cursor, err := eventsCursor(r)
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable response buffering of nginx and alike
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	flusher.Flush()
+
+	mod.Debug("SSE streaming started for %s from cursor %d", r.RemoteAddr, cursor)
+
+	pingTicker := time.NewTicker(pingPeriod)
+	defer pingTicker.Stop()
+
+	for {
+		events, last, missed, changed := mod.backlog.Since(cursor, 0)
+		if missed {
+			if err := mod.writeSSE(w, 0, "events.missed", map[string]uint64{"cursor": cursor}); err != nil {
+				return
+			}
+		}
+
+		for _, e := range mod.visibleEvents(events) {
+			if err := mod.writeSSE(w, e.ID, e.Tag, e); err != nil {
+				return
+			}
+		}
+		cursor = last
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-pingTicker.C:
+			if _, err := fmt.Fprintf(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-mod.backlog.done:
+			return
+		}
+	}
+}
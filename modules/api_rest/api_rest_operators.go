@@ -0,0 +1,102 @@
+package api_rest
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+// an operator account as defined in the api.rest.operators file:
+//
+//	[
+//	  {"name": "alice", "password": "...", "commands": []},
+//	  {"name": "bob", "password": "...", "commands": ["net.show", "wifi.*", "events.*"]}
+//	]
+type operatorAccount struct {
+	Name     string   `json:"name"`
+	Password string   `json:"password"`
+	Commands []string `json:"commands"`
+}
+
+func (mod *RestAPI) loadOperators(fileName string) error {
+	mod.operators = make(map[string]operatorAccount)
+
+	if fileName == "" {
+		return nil
+	}
+
+	fileName, err := fs.Expand(fileName)
+	if err != nil {
+		return err
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", fileName, err)
+	}
+
+	var accounts []operatorAccount
+	if err = json.Unmarshal(raw, &accounts); err != nil {
+		return fmt.Errorf("error parsing %s: %v", fileName, err)
+	}
+
+	for _, account := range accounts {
+		if account.Name == "" || account.Password == "" {
+			return fmt.Errorf("operators in %s must have a name and a password", fileName)
+		} else if _, err := session.NewOperator(account.Name, "", account.Commands); err != nil {
+			return err
+		}
+		mod.operators[account.Name] = account
+	}
+
+	mod.Info("loaded %d operators from %s", len(mod.operators), fileName)
+
+	return nil
+}
+
+// authenticate the request and return the operator on whose behalf it's
+// being made, or nil if the credentials are not valid
+func (mod *RestAPI) authenticate(r *http.Request) *session.Operator {
+	user, pass, _ := r.BasicAuth()
+	name := user
+	commands := []string{}
+
+	if len(mod.operators) > 0 {
+		if account, found := mod.operators[user]; !found {
+			return nil
+		} else if subtle.ConstantTimeCompare([]byte(pass), []byte(account.Password)) != 1 {
+			return nil
+		} else {
+			commands = account.Commands
+		}
+	} else if mod.username != "" && mod.password != "" {
+		// timing attack my ass
+		if subtle.ConstantTimeCompare([]byte(user), []byte(mod.username)) != 1 {
+			return nil
+		} else if subtle.ConstantTimeCompare([]byte(pass), []byte(mod.password)) != 1 {
+			return nil
+		}
+	} else if name == "" {
+		name = "api"
+	}
+
+	origin := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(origin); err == nil {
+		origin = host
+	}
+
+	op, err := session.NewOperator(name, origin, commands)
+	if err != nil {
+		mod.Error("%v", err)
+		return nil
+	}
+
+	return op
+}
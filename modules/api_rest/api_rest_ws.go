@@ -88,21 +88,35 @@ func (mod *RestAPI) streamWriter(ws *websocket.Conn, w http.ResponseWriter, r *h
 	}
 }
 
-func (mod *RestAPI) streamReader(ws *websocket.Conn) {
+func (mod *RestAPI) streamReader(op *session.Operator, ws *websocket.Conn) {
 	defer ws.Close()
 	ws.SetReadLimit(512)
 	ws.SetReadDeadline(time.Now().Add(pongWait))
 	ws.SetPongHandler(func(string) error { ws.SetReadDeadline(time.Now().Add(pongWait)); return nil })
 	for {
-		_, _, err := ws.ReadMessage()
+		_, msg, err := ws.ReadMessage()
 		if err != nil {
 			mod.Warning("error reading message from websocket: %v", err)
 			break
 		}
+
+		// operators can send commands on the same channel, their
+		// outcome is broadcast to everyone as a session.command event
+		var cmd CommandRequest
+		if err = json.Unmarshal(msg, &cmd); err != nil {
+			mod.Debug("ignoring websocket message from %s: %v", op, err)
+			continue
+		}
+
+		for _, aCommand := range session.ParseCommands(cmd.Command) {
+			if err = mod.Session.RunAs(op, aCommand); err != nil {
+				break
+			}
+		}
 	}
 }
 
-func (mod *RestAPI) startStreamingEvents(w http.ResponseWriter, r *http.Request) {
+func (mod *RestAPI) startStreamingEvents(op *session.Operator, w http.ResponseWriter, r *http.Request) {
 	ws, err := mod.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		if _, ok := err.(websocket.HandshakeError); !ok {
@@ -113,6 +127,9 @@ func (mod *RestAPI) startStreamingEvents(w http.ResponseWriter, r *http.Request)
 
 	mod.Debug("websocket streaming started for %s", r.RemoteAddr)
 
+	mod.Session.Join(op)
+	defer mod.Session.Leave(op)
+
 	go mod.streamWriter(ws, w, r)
-	mod.streamReader(ws)
+	mod.streamReader(op, ws)
 }
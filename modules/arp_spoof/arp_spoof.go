@@ -2,6 +2,7 @@ package arp_spoof
 
 import (
 	"bytes"
+	"fmt"
 	"net"
 	"strings"
 	"sync"
@@ -11,6 +12,8 @@ import (
 	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
 
+	"github.com/google/gopacket/pcap"
+
 	"github.com/malfunkt/iprange"
 )
 
@@ -25,6 +28,17 @@ type ArpSpoofer struct {
 	ban         bool
 	skipRestore bool
 	waitGroup   *sync.WaitGroup
+
+	healthCheck    bool
+	healthInterval time.Duration
+	healthRetries  int
+	healthHandle   *pcap.Handle
+	healthBPF      string
+	healthLock     *sync.Mutex
+	health         map[string]*TargetHealth
+	interval       time.Duration
+	lastProbe      time.Time
+	probeSeq       uint16
 }
 
 func NewArpSpoofer(s *session.Session) *ArpSpoofer {
@@ -39,6 +53,9 @@ func NewArpSpoofer(s *session.Session) *ArpSpoofer {
 		fullDuplex:    false,
 		skipRestore:   false,
 		waitGroup:     &sync.WaitGroup{},
+		healthLock:    &sync.Mutex{},
+		health:        make(map[string]*TargetHealth),
+		interval:      maxSpoofInterval,
 	}
 
 	mod.SessionModule.Requires("net.recon")
@@ -55,6 +72,18 @@ func NewArpSpoofer(s *session.Session) *ArpSpoofer {
 		"false",
 		"If true, both the targets and the gateway will be attacked, otherwise only the target (if the router has ARP spoofing protections in place this will make the attack fail)."))
 
+	mod.AddParam(session.NewBoolParameter("arp.spoof.health",
+		"false",
+		"If true, periodically verify with spoofed ICMP probes and the forwarded traffic that the ARP caches of the targets (and the one of the gateway in full duplex mode) hold our MAC address, poisoning them again more often when they don't."))
+
+	mod.AddParam(session.NewIntParameter("arp.spoof.health.interval",
+		"5",
+		"Seconds between ARP cache verifications."))
+
+	mod.AddParam(session.NewIntParameter("arp.spoof.health.retries",
+		"3",
+		"Number of failed verifications after which a cache is reported as unpoisonable."))
+
 	noRestore := session.NewBoolParameter("arp.spoof.skip_restore",
 		"false",
 		"If set to true, targets arp cache won't be restored when spoofing is stopped.")
@@ -82,6 +111,12 @@ func NewArpSpoofer(s *session.Session) *ArpSpoofer {
 			return mod.Start()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("arp.spoof.health", "",
+		"Show whether the ARP caches of the targets and the gateway are poisoned.",
+		func(args []string) error {
+			return mod.showHealth()
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("arp.spoof off", "",
 		"Stop ARP spoofer.",
 		func(args []string) error {
@@ -113,6 +148,7 @@ func (mod *ArpSpoofer) Configure() error {
 	var err error
 	var targets string
 	var whitelist string
+	var healthInterval int
 
 	if err, mod.fullDuplex = mod.BoolParam("arp.spoof.fullduplex"); err != nil {
 		return err
@@ -122,12 +158,22 @@ func (mod *ArpSpoofer) Configure() error {
 		return err
 	} else if err, whitelist = mod.StringParam("arp.spoof.whitelist"); err != nil {
 		return err
+	} else if err, mod.healthCheck = mod.BoolParam("arp.spoof.health"); err != nil {
+		return err
+	} else if err, healthInterval = mod.IntParam("arp.spoof.health.interval"); err != nil {
+		return err
+	} else if err, mod.healthRetries = mod.IntParam("arp.spoof.health.retries"); err != nil {
+		return err
+	} else if healthInterval <= 0 || mod.healthRetries <= 0 {
+		return fmt.Errorf("arp.spoof.health.interval and arp.spoof.health.retries must be greater than zero")
 	} else if mod.addresses, mod.macs, err = network.ParseTargets(targets, mod.Session.Lan.Aliases()); err != nil {
 		return err
 	} else if mod.wAddresses, mod.wMacs, err = network.ParseTargets(whitelist, mod.Session.Lan.Aliases()); err != nil {
 		return err
 	}
 
+	mod.healthInterval = time.Duration(healthInterval) * time.Second
+
 	mod.Debug(" addresses=%v macs=%v whitelisted-addresses=%v whitelisted-macs=%v", mod.addresses, mod.macs, mod.wAddresses, mod.wMacs)
 
 	if mod.ban {
@@ -172,6 +218,14 @@ func (mod *ArpSpoofer) Start() error {
 		mod.waitGroup.Add(1)
 		defer mod.waitGroup.Done()
 
+		mod.healthLock.Lock()
+		mod.interval = maxSpoofInterval
+		mod.healthLock.Unlock()
+
+		if mod.healthCheck {
+			mod.startHealthMonitor()
+		}
+
 		gwIP := mod.Session.Gateway.IP
 		myMAC := mod.Session.Interface.HW
 		for mod.Running() {
@@ -182,7 +236,7 @@ func (mod *ArpSpoofer) Start() error {
 				}
 			}
 
-			time.Sleep(1 * time.Second)
+			time.Sleep(mod.spoofInterval())
 		}
 	})
 }
@@ -216,6 +270,8 @@ func (mod *ArpSpoofer) Stop() error {
 		mod.Info("waiting for ARP spoofer to stop ...")
 		mod.unSpoof()
 		mod.ban = false
+		mod.stopHealthMonitor()
+		mod.State.Store("targets", []string{})
 		mod.waitGroup.Wait()
 	})
 }
@@ -281,7 +337,12 @@ func (mod *ArpSpoofer) arpSpoofTargets(saddr net.IP, smac net.HardwareAddr, chec
 		}
 	}
 
-	if targets := mod.getTargets(probe); len(targets) == 0 {
+	targets := mod.getTargets(probe)
+	if check_running && isSpoofing {
+		mod.updateTargets(targets)
+	}
+
+	if len(targets) == 0 {
 		mod.Warning("could not find spoof targets")
 	} else {
 		for ip, mac := range targets {
@@ -0,0 +1,56 @@
+package arp_spoof
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/bettercap/bettercap/packets"
+)
+
+// the resolved targets are exported to the other modules, such as net.sniff,
+// so that they can narrow down their kernel filters
+func (mod *ArpSpoofer) updateTargets(targets map[string]net.HardwareAddr) {
+	gwIP := mod.Session.Gateway.IP.String()
+
+	list := make([]string, 0, len(targets))
+	for ip, mac := range targets {
+		if ip != gwIP && !mod.isWhitelisted(ip, mac) {
+			list = append(list, ip)
+		}
+	}
+	sort.Strings(list)
+
+	mod.State.Store("targets", list)
+
+	mod.healthLock.Lock()
+	defer mod.healthLock.Unlock()
+
+	if mod.healthHandle == nil {
+		return
+	}
+
+	filter := mod.healthFilter(list)
+	if filter == mod.healthBPF {
+		return
+	} else if err := mod.healthHandle.SetBPFFilter(filter); err != nil {
+		mod.Warning("could not update the health monitor filter: %v", err)
+		return
+	}
+
+	mod.Debug("health monitor filter updated to '%s'", filter)
+	mod.healthBPF = filter
+}
+
+// anything sent to our mac but not to our address is traffic we're forwarding, which
+// proves a cache is poisoned, once the targets are resolved only their traffic is captured
+func (mod *ArpSpoofer) healthFilter(targets []string) string {
+	addresses := make([]net.IP, 0, len(targets))
+	for _, ip := range targets {
+		addresses = append(addresses, net.ParseIP(ip))
+	}
+
+	return packets.BPFAnd(
+		fmt.Sprintf("ip and ether dst %s and not dst host %s", mod.Session.Interface.HW, mod.Session.Interface.IP),
+		packets.BPFHosts(addresses, nil))
+}
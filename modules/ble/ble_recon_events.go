@@ -3,9 +3,23 @@
 package ble
 
 import (
+	"github.com/bettercap/bettercap/session"
+
 	"github.com/bettercap/gatt"
 )
 
+func init() {
+	device := session.EventSchemaFor("ble.device.new").Fields
+
+	session.RegisterEventFields("ble.device.connected", 1, "Connected to a BLE device.", "object", device...)
+	session.RegisterEventFields("ble.device.disconnected", 1, "Disconnected from a BLE device.", "object", device...)
+	session.RegisterEventFields("ble.connection.timeout", 1, "The connection to a BLE device timed out.", "object", device...)
+	// the raw gatt objects, their details are in the services of the device
+	session.RegisterEventFields("ble.device.service.discovered", 1, "A service of the connected BLE device has been enumerated.", "object")
+	session.RegisterEventFields("ble.device.characteristic.discovered", 1, "A characteristic of the connected BLE device has been enumerated.", "object")
+	session.RegisterEventSchema("ble.device.characteristic.changed", 1, "The value of a watched characteristic changed.", BLEValueChangedEvent{})
+}
+
 func (mod *BLERecon) onStateChanged(dev gatt.Device, s gatt.State) {
 	mod.Debug("state changed to %v", s)
 
@@ -35,6 +49,7 @@ func (mod *BLERecon) onPeriphDiscovered(p gatt.Peripheral, a *gatt.Advertisement
 func (mod *BLERecon) onPeriphDisconnected(p gatt.Peripheral, err error) {
 	mod.Session.Events.Add("ble.device.disconnected", mod.currDevice)
 	mod.setCurrentDevice(nil)
+	mod.injection = nil
 	if mod.Running() {
 		mod.Debug("device disconnected, restoring discovery.")
 		mod.gattDevice.Scan([]gatt.UUID{}, true)
@@ -64,6 +79,14 @@ func (mod *BLERecon) onPeriphConnected(p gatt.Peripheral, err error) {
 		mod.Warning("failed to set MTU: %s", err)
 	}
 
+	if mod.isWatching() {
+		mod.watchCharacteristic(p)
+		return
+	} else if mod.isInjecting() {
+		mod.injectKeystrokes(p)
+		return
+	}
+
 	mod.Debug("connected, enumerating all the things for %s!", p.ID())
 	services, err := p.DiscoverServices(nil)
 	// https://github.com/bettercap/bettercap/issues/498
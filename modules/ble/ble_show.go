@@ -4,6 +4,7 @@ package ble
 
 import (
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/bettercap/bettercap/network"
@@ -31,6 +32,7 @@ func (mod *BLERecon) getRow(dev *network.BLEDevice, withName bool) []string {
 		lastSeen = tui.Dim(lastSeen)
 		address = tui.Dim(address)
 	}
+	address = network.WithTags(address, dev.Tags)
 
 	if withName {
 		return []string{
@@ -60,7 +62,9 @@ func (mod *BLERecon) doFilter(dev *network.BLEDevice) bool {
 	}
 	return mod.selector.Expression.MatchString(dev.Device.ID()) ||
 		mod.selector.Expression.MatchString(dev.Device.Name()) ||
-		mod.selector.Expression.MatchString(dev.Vendor)
+		mod.selector.Expression.MatchString(dev.Vendor) ||
+		mod.selector.Expression.MatchString(strings.Join(dev.Tags, ",")) ||
+		mod.selector.Expression.MatchString(dev.Note)
 }
 
 func (mod *BLERecon) doSelection() (devices []*network.BLEDevice, err error) {
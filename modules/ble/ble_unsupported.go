@@ -14,6 +14,7 @@ func NewBLERecon(s *session.Session) *BLERecon {
 	mod := &BLERecon{
 		SessionModule: session.NewSessionModule("ble.recon", s),
 	}
+	mod.NotSupported()
 
 	mod.AddHandler(session.NewModuleHandler("ble.recon on", "",
 		"Start Bluetooth Low Energy devices discovery.",
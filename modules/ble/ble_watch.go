@@ -0,0 +1,247 @@
+// +build !windows
+
+package ble
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bettercap/gatt"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const bleWatchMaxHistory = 256
+
+type BLEValue struct {
+	Time  time.Time `json:"time"`
+	Value []byte    `json:"value"`
+}
+
+type BLEValueChangedEvent struct {
+	MAC      string `json:"mac"`
+	UUID     string `json:"uuid"`
+	Name     string `json:"name"`
+	Previous string `json:"previous"`
+	Value    string `json:"value"`
+}
+
+type bleWatch struct {
+	sync.Mutex
+
+	mac      string
+	uuid     gatt.UUID
+	name     string
+	interval time.Duration
+	history  []BLEValue
+	quit     chan bool
+}
+
+func (w *bleWatch) add(value []byte) (changed bool, prev []byte) {
+	w.Lock()
+	defer w.Unlock()
+
+	if n := len(w.history); n > 0 {
+		prev = w.history[n-1].Value
+		if bytes.Equal(prev, value) {
+			return false, prev
+		}
+	}
+
+	w.history = append(w.history, BLEValue{
+		Time:  time.Now(),
+		Value: value,
+	})
+
+	if len(w.history) > bleWatchMaxHistory {
+		w.history = w.history[len(w.history)-bleWatchMaxHistory:]
+	}
+
+	return prev != nil, prev
+}
+
+func (mod *BLERecon) isWatching() bool {
+	mod.watchLock.Lock()
+	defer mod.watchLock.Unlock()
+	return mod.watch != nil && mod.watch.quit != nil
+}
+
+func (mod *BLERecon) startWatching(mac string, uuid gatt.UUID, interval time.Duration) error {
+	if mod.isEnumerating() {
+		return fmt.Errorf("A connection to %s is already active, please wait.", mod.currDevice.Device.ID())
+	}
+
+	mod.watchLock.Lock()
+	mod.watch = &bleWatch{
+		mac:      mac,
+		uuid:     uuid,
+		interval: interval,
+		history:  make([]BLEValue, 0),
+		quit:     make(chan bool),
+	}
+	mod.watchLock.Unlock()
+
+	mod.writeData = nil
+	mod.writeUUID = nil
+
+	return mod.enumAllTheThings(mac)
+}
+
+func (mod *BLERecon) stopWatching() error {
+	mod.watchLock.Lock()
+	defer mod.watchLock.Unlock()
+
+	if mod.watch == nil || mod.watch.quit == nil {
+		return fmt.Errorf("no characteristic is being watched")
+	}
+
+	close(mod.watch.quit)
+	mod.watch.quit = nil
+
+	return nil
+}
+
+func (mod *BLERecon) watchCharacteristic(p gatt.Peripheral) {
+	mod.watchLock.Lock()
+	w := mod.watch
+	// the watch might have been stopped before we managed to connect
+	if w == nil || w.quit == nil {
+		mod.watchLock.Unlock()
+		return
+	}
+	quit := w.quit
+	mod.watchLock.Unlock()
+
+	defer func() {
+		mod.watchLock.Lock()
+		defer mod.watchLock.Unlock()
+		if w.quit != nil {
+			close(w.quit)
+			w.quit = nil
+		}
+	}()
+
+	services, err := p.DiscoverServices(nil)
+	// https://github.com/bettercap/bettercap/issues/498
+	if err != nil && err.Error() != "success" {
+		mod.Error("error discovering services: %s", err)
+		return
+	}
+
+	var target *gatt.Characteristic
+	for _, svc := range services {
+		if chars, err := p.DiscoverCharacteristics(nil, svc); err != nil {
+			mod.Error("error while enumerating chars for service %s: %s", svc.UUID(), err)
+		} else {
+			for _, ch := range chars {
+				if w.uuid.Equal(ch.UUID()) {
+					target = ch
+					break
+				}
+			}
+		}
+
+		if target != nil {
+			break
+		}
+	}
+
+	if target == nil {
+		mod.Error("characteristics %s not found.", w.uuid)
+		return
+	} else if _, isReadable, _, _ := parseProperties(target); !isReadable {
+		mod.Error("characteristics %s is not readable.", w.uuid)
+		return
+	}
+
+	// showWatch reads it concurrently
+	name := target.Name()
+	w.Lock()
+	w.name = name
+	w.Unlock()
+
+	mod.Info("watching characteristics %s of %s every %s ...", w.uuid, w.mac, w.interval)
+
+	for {
+		raw, err := p.ReadCharacteristic(target)
+		if err != nil {
+			mod.Error("error while reading characteristics %s: %s", w.uuid, err)
+			return
+		}
+
+		if changed, prev := w.add(raw); changed {
+			mod.Session.Events.Add("ble.device.characteristic.changed", BLEValueChangedEvent{
+				MAC:      w.mac,
+				UUID:     w.uuid.String(),
+				Name:     name,
+				Previous: hex.EncodeToString(prev),
+				Value:    hex.EncodeToString(raw),
+			})
+		}
+
+		select {
+		case <-quit:
+			mod.Info("stopped watching characteristics %s of %s", w.uuid, w.mac)
+			return
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+func (mod *BLERecon) showWatch() error {
+	mod.watchLock.Lock()
+	w := mod.watch
+	mod.watchLock.Unlock()
+
+	if w == nil {
+		return fmt.Errorf("no characteristic has been watched yet")
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	name := w.uuid.String()
+	if w.name != "" {
+		name = fmt.Sprintf("%s (%s)", tui.Green(w.name), tui.Dim(name))
+	}
+
+	mod.Printf("\n%s %s\n\n", tui.Bold(w.mac), name)
+
+	columns := []string{"Time", "Hex", "Data"}
+	rows := make([][]string, 0)
+	for i, v := range w.history {
+		data := hex.EncodeToString(v.Value)
+		if i > 0 {
+			data = diffHex(w.history[i-1].Value, v.Value)
+		}
+		rows = append(rows, []string{
+			v.Time.Format("15:04:05"),
+			data,
+			parseRawData(v.Value),
+		})
+	}
+
+	if len(rows) > 0 {
+		tui.Table(mod.Session.Events.Stdout, columns, rows)
+	}
+
+	mod.Session.Refresh()
+
+	return nil
+}
+
+// highlight the bytes that changed from the previous value
+func diffHex(prev []byte, curr []byte) string {
+	s := ""
+	for i, b := range curr {
+		h := fmt.Sprintf("%02x", b)
+		if i >= len(prev) || prev[i] != b {
+			h = tui.Bold(tui.Yellow(h))
+		}
+		s += h
+	}
+	return s
+}
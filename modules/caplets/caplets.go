@@ -3,10 +3,10 @@ package caplets
 import (
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 
 	"github.com/bettercap/bettercap/caplets"
+	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/dustin/go-humanize"
@@ -37,6 +37,12 @@ func NewCapletsModule(s *session.Session) *CapletsModule {
 			return mod.Paths()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("caplets.check NAME", `caplets\.check\s+(.+)`,
+		"Parse the caplet and validate its commands and parameters against the loaded modules without running it.",
+		func(args []string) error {
+			return mod.Check(args[0])
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("caplets.update", "",
 		"Install/updates the caplets.",
 		func(args []string) error {
@@ -51,7 +57,7 @@ func (mod *CapletsModule) Name() string {
 }
 
 func (mod *CapletsModule) Description() string {
-	return "A module to list and update caplets."
+	return "A module to list, check and update caplets."
 }
 
 func (mod *CapletsModule) Author() string {
@@ -112,6 +118,42 @@ func (mod *CapletsModule) Paths() error {
 	return nil
 }
 
+func (mod *CapletsModule) Check(name string) error {
+	issues, err := mod.Session.CheckCaplet(name)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		mod.Info("caplet %s is valid", tui.Bold(name))
+		return nil
+	}
+
+	errors := 0
+	rows := [][]string{}
+	for _, issue := range issues {
+		level := tui.Yellow("warning")
+		if !issue.Warning {
+			level = tui.Red("error")
+			errors++
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("%s:%d", issue.Path, issue.Line),
+			level,
+			tui.Dim(issue.Command),
+			issue.Message,
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Line", "Level", "Command", "Problem"}, rows)
+
+	if errors > 0 {
+		return fmt.Errorf("caplet %s has %d errors and %d warnings", name, errors, len(issues)-errors)
+	}
+	mod.Warning("caplet %s has %d warnings", name, len(issues))
+	return nil
+}
+
 func (mod *CapletsModule) Update() error {
 	if !fs.Exists(caplets.InstallBase) {
 		mod.Info("creating caplets install path %s ...", caplets.InstallBase)
@@ -128,7 +170,7 @@ func (mod *CapletsModule) Update() error {
 
 	mod.Info("downloading caplets from %s ...", caplets.InstallArchive)
 
-	resp, err := http.Get(caplets.InstallArchive)
+	resp, err := network.DNS.HTTPClient(0).Get(caplets.InstallArchive)
 	if err != nil {
 		return err
 	}
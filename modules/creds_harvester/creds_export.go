@@ -0,0 +1,149 @@
+package creds_harvester
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+// the hashes are written in the format of hashcat, one file per mode since
+// hashcat can only crack one at a time
+func (mod *CredsHarvester) exportHashcat(filename string, creds []*Credential) error {
+	byMode := make(map[int][]string)
+	for _, c := range creds {
+		if c.IsHash() {
+			byMode[c.HashcatMode] = append(byMode[c.HashcatMode], c.Secret)
+		}
+	}
+
+	if len(byMode) == 0 {
+		return fmt.Errorf("no hashes to export")
+	}
+
+	modes := make([]int, 0, len(byMode))
+	for mode := range byMode {
+		modes = append(modes, mode)
+	}
+	sort.Ints(modes)
+
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for _, mode := range modes {
+		name := fmt.Sprintf("%s.%d%s", base, mode, ext)
+		if err := writeLines(name, byMode[mode]); err != nil {
+			return err
+		}
+		mod.Info("%d hashes saved to %s, crack them with: hashcat -m %d %s", len(byMode[mode]), tui.Bold(name), mode, name)
+	}
+	return nil
+}
+
+// john detects the format of the hashes, they're prefixed by the username
+// unless they already contain it
+func (mod *CredsHarvester) exportJohn(filename string, creds []*Credential) error {
+	lines := []string{}
+	for _, c := range creds {
+		if !c.IsHash() {
+			continue
+		} else if c.HashcatMode == 5500 || c.HashcatMode == 5600 {
+			lines = append(lines, c.Secret)
+		} else {
+			lines = append(lines, fmt.Sprintf("%s:%s", c.Username, c.Secret))
+		}
+	}
+
+	if len(lines) == 0 {
+		return fmt.Errorf("no hashes to export")
+	} else if err := writeLines(filename, lines); err != nil {
+		return err
+	}
+
+	mod.Info("%d hashes saved to %s", len(lines), tui.Bold(filename))
+	return nil
+}
+
+func (mod *CredsHarvester) exportCSV(filename string, creds []*Credential) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"protocol", "client", "client_name", "server", "server_name", "port", "username", "secret", "kind", "hash_type", "hashcat_mode", "module", "first_seen", "last_seen", "seen"})
+	for _, c := range creds {
+		mode := ""
+		if c.IsHash() {
+			mode = strconv.Itoa(c.HashcatMode)
+		}
+		w.Write([]string{
+			c.Protocol,
+			c.Client,
+			c.ClientName,
+			c.Server,
+			c.ServerName,
+			strconv.Itoa(c.Port),
+			c.Username,
+			c.Secret,
+			c.Kind,
+			c.HashType,
+			mode,
+			c.Module,
+			c.FirstSeen.Format("2006-01-02 15:04:05"),
+			c.LastSeen.Format("2006-01-02 15:04:05"),
+			strconv.Itoa(c.Seen),
+		})
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return err
+	}
+
+	mod.Info("%d credentials saved to %s", len(creds), tui.Bold(filename))
+	return nil
+}
+
+func writeLines(filename string, lines []string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, line := range lines {
+		if _, err = fmt.Fprintln(file, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mod *CredsHarvester) export(format string, filename string) error {
+	var err error
+	if filename, err = fs.Expand(filename); err != nil {
+		return err
+	}
+
+	creds := mod.Store.List("")
+	if len(creds) == 0 {
+		return fmt.Errorf("no credentials to export")
+	}
+
+	switch format {
+	case "hashcat":
+		return mod.exportHashcat(filename, creds)
+	case "john":
+		return mod.exportJohn(filename, creds)
+	case "csv":
+		return mod.exportCSV(filename, creds)
+	}
+	return fmt.Errorf("unknown export format %s", format)
+}
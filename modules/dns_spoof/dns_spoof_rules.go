@@ -0,0 +1,255 @@
+package dns_spoof
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// Rule spoofs the domains only for the victims matching a MAC address, a
+// MAC prefix, an IP address or a CIDR.
+type Rule struct {
+	Victim string `json:"victim"`
+	// nil if dns.spoof.address is used
+	Address net.IP `json:"address"`
+	Domains string `json:"domains"`
+	Hits    uint64 `json:"hits"`
+
+	hosts   Hosts
+	prefix  []byte
+	network *net.IPNet
+}
+
+// per victim hit counters
+type VictimStats struct {
+	MAC      string            `json:"mac"`
+	Address  string            `json:"address"`
+	Hits     uint64            `json:"hits"`
+	Domains  map[string]uint64 `json:"domains"`
+	LastSeen time.Time         `json:"last_seen"`
+}
+
+type rulesTable struct {
+	sync.Mutex
+
+	rules []*Rule
+	stats map[string]*VictimStats
+}
+
+func newRulesTable() *rulesTable {
+	return &rulesTable{
+		rules: make([]*Rule, 0),
+		stats: make(map[string]*VictimStats),
+	}
+}
+
+func ParseRule(victim string, domains string, address string) (*Rule, error) {
+	r := &Rule{Victim: victim, Domains: domains}
+
+	if ip := net.ParseIP(victim); ip != nil {
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		r.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	} else if _, network, err := net.ParseCIDR(victim); err == nil {
+		r.network = network
+	} else if raw, err := hex.DecodeString(strings.Replace(strings.Replace(victim, ":", "", -1), "-", "", -1)); err == nil && len(raw) > 0 && len(raw) <= 6 {
+		r.prefix = raw
+	} else {
+		return nil, fmt.Errorf("'%s' is not a MAC address, MAC prefix, IP address or CIDR", victim)
+	}
+
+	if address != "" {
+		if r.Address = net.ParseIP(address); r.Address == nil {
+			return nil, fmt.Errorf("'%s' is not a valid IP address", address)
+		}
+	}
+
+	for _, domain := range strings.Split(domains, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			r.hosts = append(r.hosts, NewHostEntry(strings.ToLower(domain), r.Address))
+		}
+	}
+	if len(r.hosts) == 0 {
+		return nil, fmt.Errorf("empty domains list")
+	}
+
+	return r, nil
+}
+
+func (r *Rule) Matches(mac net.HardwareAddr, ip net.IP) bool {
+	if r.network != nil {
+		return ip != nil && r.network.Contains(ip)
+	}
+	return len(mac) >= len(r.prefix) && bytes.Equal(mac[:len(r.prefix)], r.prefix)
+}
+
+func (t *rulesTable) Add(r *Rule) {
+	t.Lock()
+	defer t.Unlock()
+
+	// redefining the domains of a victim replaces them
+	for i, existing := range t.rules {
+		if existing.Victim == r.Victim && existing.Domains == r.Domains {
+			t.rules[i] = r
+			return
+		}
+	}
+	t.rules = append(t.rules, r)
+}
+
+func (t *rulesTable) Remove(r *Rule) {
+	t.Lock()
+	defer t.Unlock()
+
+	for i, existing := range t.rules {
+		if existing == r {
+			t.rules = append(t.rules[:i], t.rules[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *rulesTable) Clear() {
+	t.Lock()
+	defer t.Unlock()
+	t.rules = make([]*Rule, 0)
+}
+
+func (t *rulesTable) Empty() bool {
+	t.Lock()
+	defer t.Unlock()
+	return len(t.rules) == 0
+}
+
+// Resolve returns the rule matching both the victim and the domain, if any,
+// and the address to reply with.
+func (t *rulesTable) Resolve(mac net.HardwareAddr, ip net.IP, domain string, fallback net.IP) (*Rule, net.IP) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, r := range t.rules {
+		if !r.Matches(mac, ip) {
+			continue
+		}
+		for _, entry := range r.hosts {
+			if entry.Matches(domain) {
+				if entry.Address != nil {
+					return r, entry.Address
+				}
+				return r, fallback
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (t *rulesTable) Hit(r *Rule, mac net.HardwareAddr, ip net.IP, domain string) {
+	t.Lock()
+	defer t.Unlock()
+
+	if r != nil {
+		r.Hits++
+	}
+
+	key := mac.String()
+	s, found := t.stats[key]
+	if !found {
+		s = &VictimStats{
+			MAC:     key,
+			Domains: make(map[string]uint64),
+		}
+		t.stats[key] = s
+	}
+
+	if ip != nil {
+		s.Address = ip.String()
+	}
+	s.Hits++
+	s.Domains[strings.ToLower(domain)]++
+	s.LastSeen = time.Now()
+}
+
+func (t *rulesTable) ClearStats() {
+	t.Lock()
+	defer t.Unlock()
+	t.stats = make(map[string]*VictimStats)
+}
+
+func (mod *DNSSpoofer) showRules() error {
+	mod.Rules.Lock()
+	defer mod.Rules.Unlock()
+
+	if len(mod.Rules.rules) == 0 {
+		mod.Info("no per victim rules, every client gets the dns.spoof.domains, dns.spoof.hosts and dns.spoof.zones records")
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, r := range mod.Rules.rules {
+		address := tui.Dim("dns.spoof.address")
+		if r.Address != nil {
+			address = r.Address.String()
+		}
+		rows = append(rows, []string{tui.Bold(r.Victim), r.Domains, address, fmt.Sprintf("%d", r.Hits)})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Victim", "Domains", "Address", "Hits"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
+
+func (mod *DNSSpoofer) showStats() error {
+	mod.Rules.Lock()
+	defer mod.Rules.Unlock()
+
+	if len(mod.Rules.stats) == 0 {
+		mod.Info("no spoofed replies sent yet")
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, s := range mod.Rules.stats {
+		who := s.MAC
+		if host, found := mod.Session.Lan.Get(s.MAC); found {
+			who = host.String()
+		}
+
+		domains := make([]string, 0, len(s.Domains))
+		for domain := range s.Domains {
+			domains = append(domains, domain)
+		}
+		sort.Slice(domains, func(i, j int) bool {
+			return s.Domains[domains[i]] > s.Domains[domains[j]]
+		})
+		for i, domain := range domains {
+			domains[i] = fmt.Sprintf("%s (%d)", domain, s.Domains[domain])
+		}
+
+		rows = append(rows, []string{
+			tui.Bold(who),
+			s.Address,
+			fmt.Sprintf("%d", s.Hits),
+			strings.Join(domains, ", "),
+			s.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Victim", "Address", "Hits", "Domains", "Last Hit"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
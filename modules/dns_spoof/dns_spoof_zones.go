@@ -0,0 +1,316 @@
+package dns_spoof
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/miekg/dns"
+)
+
+// max number of CNAMEs followed while resolving from the zones
+const maxCNAMEChain = 8
+
+type Zone struct {
+	Origin  string
+	SOA     *dns.SOA
+	NS      []dns.RR
+	Records map[string][]dns.RR
+}
+
+type ZoneAnswer struct {
+	Answers   []dns.RR
+	Authority []dns.RR
+	Rcode     int
+}
+
+type Zones struct {
+	sync.RWMutex
+	names []string
+	files map[string]time.Time
+	zones []*Zone
+}
+
+func NewZones() *Zones {
+	return &Zones{
+		files: make(map[string]time.Time),
+		zones: make([]*Zone, 0),
+	}
+}
+
+// ZoneFromFile parses a BIND zone file, it must contain a SOA record and
+// either an $ORIGIN directive or fully qualified names.
+func ZoneFromFile(filename string) (*Zone, error) {
+	input, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	zone := &Zone{
+		NS:      make([]dns.RR, 0),
+		Records: make(map[string][]dns.RR),
+	}
+
+	parser := dns.NewZoneParser(input, "", filename)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		name := strings.ToLower(rr.Header().Name)
+		rr.Header().Name = name
+
+		if soa, isSOA := rr.(*dns.SOA); isSOA {
+			if zone.SOA != nil {
+				return nil, fmt.Errorf("%s: multiple SOA records", filename)
+			}
+			zone.SOA = soa
+			zone.Origin = name
+		}
+
+		zone.Records[name] = append(zone.Records[name], rr)
+	}
+
+	if err = parser.Err(); err != nil {
+		return nil, err
+	} else if zone.SOA == nil {
+		return nil, fmt.Errorf("%s: missing SOA record", filename)
+	}
+
+	for _, rr := range zone.Records[zone.Origin] {
+		if rr.Header().Rrtype == dns.TypeNS {
+			zone.NS = append(zone.NS, rr)
+		}
+	}
+
+	return zone, nil
+}
+
+// Load parses the zone files, replacing the currently loaded zones only if all of them are valid.
+func (z *Zones) Load(filenames []string) error {
+	files := make(map[string]time.Time)
+	zones := make([]*Zone, 0)
+
+	for _, filename := range filenames {
+		stat, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+
+		zone, err := ZoneFromFile(filename)
+		if err != nil {
+			return err
+		}
+
+		files[filename] = stat.ModTime()
+		zones = append(zones, zone)
+	}
+
+	z.Lock()
+	defer z.Unlock()
+
+	z.names = filenames
+	z.files = files
+	z.zones = zones
+
+	return nil
+}
+
+// Changed returns true if any of the loaded zone files has been modified.
+func (z *Zones) Changed() bool {
+	z.RLock()
+	defer z.RUnlock()
+
+	for filename, modTime := range z.files {
+		if stat, err := os.Stat(filename); err == nil && !stat.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+func (z *Zones) Files() []string {
+	z.RLock()
+	defer z.RUnlock()
+
+	return z.names
+}
+
+func (z *Zones) Empty() bool {
+	z.RLock()
+	defer z.RUnlock()
+	return len(z.zones) == 0
+}
+
+func (z *Zones) Count() (zones int, records int) {
+	z.RLock()
+	defer z.RUnlock()
+
+	for _, zone := range z.zones {
+		for _, rrs := range zone.Records {
+			records += len(rrs)
+		}
+	}
+	return len(z.zones), records
+}
+
+// return the most specific zone the name belongs to
+func (z *Zones) zoneFor(name string) *Zone {
+	var best *Zone
+	for _, zone := range z.zones {
+		if dns.IsSubDomain(zone.Origin, name) && (best == nil || len(zone.Origin) > len(best.Origin)) {
+			best = zone
+		}
+	}
+	return best
+}
+
+// get the records of name, falling back to the closest wildcard
+func (zone *Zone) records(name string) []dns.RR {
+	if rrs, found := zone.Records[name]; found {
+		return rrs
+	}
+
+	labels := dns.SplitDomainName(name)
+	for i := 1; i < len(labels); i++ {
+		wildcard := "*." + dns.Fqdn(strings.Join(labels[i:], "."))
+		if !dns.IsSubDomain(zone.Origin, wildcard) {
+			break
+		} else if rrs, found := zone.Records[wildcard]; found {
+			expanded := make([]dns.RR, 0, len(rrs))
+			for _, rr := range rrs {
+				rr = dns.Copy(rr)
+				rr.Header().Name = name
+				expanded = append(expanded, rr)
+			}
+			return expanded
+		}
+	}
+
+	return nil
+}
+
+// Lookup resolves name and qtype from the zones, returns false if the name
+// doesn't belong to any of them.
+func (z *Zones) Lookup(name string, qtype uint16) (*ZoneAnswer, bool) {
+	z.RLock()
+	defer z.RUnlock()
+
+	name = dns.Fqdn(strings.ToLower(name))
+	zone := z.zoneFor(name)
+	if zone == nil {
+		return nil, false
+	}
+
+	answer := &ZoneAnswer{
+		Answers:   make([]dns.RR, 0),
+		Authority: make([]dns.RR, 0),
+		Rcode:     dns.RcodeSuccess,
+	}
+
+	for i := 0; i < maxCNAMEChain; i++ {
+		rrs := zone.records(name)
+		if rrs == nil {
+			// only the first name determines the response code
+			if i == 0 {
+				answer.Rcode = dns.RcodeNameError
+			}
+			answer.Authority = append(answer.Authority, zone.SOA)
+			return answer, true
+		}
+
+		var cname *dns.CNAME
+		matched := false
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == qtype || qtype == dns.TypeANY {
+				answer.Answers = append(answer.Answers, rr)
+				matched = true
+			} else if c, isCNAME := rr.(*dns.CNAME); isCNAME {
+				cname = c
+			}
+		}
+
+		if matched {
+			break
+		} else if cname == nil {
+			// the name exists but has no records of this type
+			answer.Authority = append(answer.Authority, zone.SOA)
+			return answer, true
+		}
+
+		answer.Answers = append(answer.Answers, cname)
+		name = strings.ToLower(cname.Target)
+		if next := z.zoneFor(name); next == nil {
+			// the target is outside of our zones, let the client resolve it
+			return answer, true
+		} else {
+			zone = next
+		}
+	}
+
+	if qtype != dns.TypeNS {
+		answer.Authority = append(answer.Authority, zone.NS...)
+	}
+
+	return answer, true
+}
+
+func trimDot(name string) []byte {
+	return []byte(strings.TrimSuffix(name, "."))
+}
+
+// convert a zone record to a gopacket one, returns false for unsupported types
+func toResourceRecord(rr dns.RR) (layers.DNSResourceRecord, bool) {
+	hdr := rr.Header()
+	record := layers.DNSResourceRecord{
+		Name:  trimDot(hdr.Name),
+		Type:  layers.DNSType(hdr.Rrtype),
+		Class: layers.DNSClassIN,
+		TTL:   hdr.Ttl,
+	}
+
+	switch v := rr.(type) {
+	case *dns.A:
+		record.IP = v.A
+	case *dns.AAAA:
+		record.IP = v.AAAA
+	case *dns.CNAME:
+		record.CNAME = trimDot(v.Target)
+	case *dns.NS:
+		record.NS = trimDot(v.Ns)
+	case *dns.PTR:
+		record.PTR = trimDot(v.Ptr)
+	case *dns.MX:
+		record.MX = layers.DNSMX{Preference: v.Preference, Name: trimDot(v.Mx)}
+	case *dns.SRV:
+		record.SRV = layers.DNSSRV{Priority: v.Priority, Weight: v.Weight, Port: v.Port, Name: trimDot(v.Target)}
+	case *dns.TXT:
+		for _, txt := range v.Txt {
+			record.TXTs = append(record.TXTs, []byte(txt))
+		}
+	case *dns.SOA:
+		record.SOA = layers.DNSSOA{
+			MName:   trimDot(v.Ns),
+			RName:   trimDot(v.Mbox),
+			Serial:  v.Serial,
+			Refresh: v.Refresh,
+			Retry:   v.Retry,
+			Expire:  v.Expire,
+			Minimum: v.Minttl,
+		}
+	default:
+		return record, false
+	}
+
+	return record, true
+}
+
+func toResourceRecords(rrs []dns.RR) []layers.DNSResourceRecord {
+	records := make([]layers.DNSResourceRecord, 0, len(rrs))
+	for _, rr := range rrs {
+		if record, ok := toResourceRecord(rr); ok {
+			records = append(records, record)
+		}
+	}
+	return records
+}
@@ -181,6 +181,8 @@ func NewEventsStream(s *session.Session) *EventsStream {
 		"",
 		"If not empty, events will be written to this file instead of the standard output."))
 
+	mod.SavesTo("events.stream.output")
+
 	mod.AddParam(session.NewStringParameter("events.stream.time.format",
 		mod.timeFormat,
 		"",
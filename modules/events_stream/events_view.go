@@ -64,6 +64,21 @@ func (mod *EventsStream) viewEndpointEvent(output io.Writer, e session.Event) {
 	}
 }
 
+func (mod *EventsStream) viewEndpointChangedEvent(output io.Writer, e session.Event) {
+	ch := e.Data.(network.EndpointChanged)
+	changes := []string{}
+	for _, c := range ch.Changes {
+		changes = append(changes, c.String())
+	}
+
+	fmt.Fprintf(output, "[%s] [%s] endpoint %s %s changed: %s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(ch.Endpoint.IpAddress),
+		tui.Green(ch.Endpoint.HwAddress),
+		tui.Yellow(strings.Join(changes, ", ")))
+}
+
 func (mod *EventsStream) viewModuleEvent(output io.Writer, e session.Event) {
 	if *mod.Session.Options.Debug {
 		fmt.Fprintf(output, "[%s] [%s] %s\n",
@@ -86,13 +101,39 @@ func (mod *EventsStream) viewSnifferEvent(output io.Writer, e session.Event) {
 
 func (mod *EventsStream) viewSynScanEvent(output io.Writer, e session.Event) {
 	se := e.Data.(syn_scan.SynScanEvent)
-	fmt.Fprintf(output, "[%s] [%s] found open port %d for %s\n",
+	service := ""
+	if se.Service != "" {
+		service = fmt.Sprintf(" (%s)", tui.Yellow(se.Service))
+	}
+	port := fmt.Sprintf("%d", se.Port)
+	if se.Proto == "udp" {
+		port = "udp:" + port
+	}
+	fmt.Fprintf(output, "[%s] [%s] found open port %s%s for %s\n",
 		e.Time.Format(mod.timeFormat),
 		tui.Green(e.Tag),
-		se.Port,
+		port,
+		service,
 		tui.Bold(se.Address))
 }
 
+func (mod *EventsStream) viewSynScanServiceEvent(output io.Writer, e session.Event) {
+	se := e.Data.(syn_scan.SynScanServiceEvent)
+	what := tui.Yellow(se.Service)
+	if se.Version != "" {
+		what += " " + se.Version
+	}
+	if se.Banner != "" && se.Banner != se.Version {
+		what += " " + tui.Dim(se.Banner)
+	}
+	fmt.Fprintf(output, "[%s] [%s] %s:%d is %s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(se.Address),
+		se.Port,
+		what)
+}
+
 func (mod *EventsStream) viewUpdateEvent(output io.Writer, e session.Event) {
 	update := e.Data.(*github.RepositoryRelease)
 
@@ -112,6 +153,8 @@ func (mod *EventsStream) Render(output io.Writer, e session.Event) {
 
 	if e.Tag == "sys.log" {
 		mod.viewLogEvent(output, e)
+	} else if e.Tag == "endpoint.changed" {
+		mod.viewEndpointChangedEvent(output, e)
 	} else if strings.HasPrefix(e.Tag, "endpoint.") {
 		mod.viewEndpointEvent(output, e)
 	} else if strings.HasPrefix(e.Tag, "wifi.") {
@@ -122,12 +165,20 @@ func (mod *EventsStream) Render(output io.Writer, e session.Event) {
 		mod.viewHIDEvent(output, e)
 	} else if strings.HasPrefix(e.Tag, "gps.") {
 		mod.viewGPSEvent(output, e)
+	} else if e.Tag == "session.command" || strings.HasPrefix(e.Tag, "session.operator.") {
+		mod.viewOperatorEvent(output, e)
 	} else if strings.HasPrefix(e.Tag, "mod.") {
 		mod.viewModuleEvent(output, e)
 	} else if strings.HasPrefix(e.Tag, "net.sniff.") {
 		mod.viewSnifferEvent(output, e)
+	} else if e.Tag == "net.profile.anomaly" {
+		mod.viewProfileEvent(output, e)
+	} else if strings.HasSuffix(e.Tag, ".proxy.replayed") {
+		mod.viewProxyEvent(output, e)
 	} else if e.Tag == "syn.scan" {
 		mod.viewSynScanEvent(output, e)
+	} else if e.Tag == "syn.scan.service" {
+		mod.viewSynScanServiceEvent(output, e)
 	} else if e.Tag == "update.available" {
 		mod.viewUpdateEvent(output, e)
 	} else if e.Tag == "gateway.change" {
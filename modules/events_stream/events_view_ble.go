@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/bettercap/bettercap/modules/ble"
 	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
@@ -48,5 +49,19 @@ func (mod *EventsStream) viewBLEEvent(output io.Writer, e session.Event) {
 			name,
 			dev.Device.ID(),
 			vend)
+	} else if e.Tag == "ble.device.characteristic.changed" {
+		change := e.Data.(ble.BLEValueChangedEvent)
+		name := change.UUID
+		if change.Name != "" {
+			name = fmt.Sprintf("%s (%s)", tui.Bold(change.Name), tui.Dim(change.UUID))
+		}
+
+		fmt.Fprintf(output, "[%s] [%s] characteristics %s of %s changed from %s to %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			name,
+			change.MAC,
+			tui.Dim(change.Previous),
+			tui.Yellow(change.Value))
 	}
 }
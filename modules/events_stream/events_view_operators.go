@@ -0,0 +1,46 @@
+package events_stream
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *EventsStream) viewOperatorEvent(output io.Writer, e session.Event) {
+	if e.Tag == "session.command" {
+		cmd := e.Data.(session.CommandEvent)
+		// no need to echo what was typed in this terminal
+		if cmd.Operator == session.LocalOperator.Name && cmd.Origin == session.LocalOperator.Origin {
+			return
+		}
+
+		result := tui.Green("ok")
+		if cmd.Error != "" {
+			result = tui.Red(cmd.Error)
+		}
+
+		fmt.Fprintf(output, "[%s] [%s] %s@%s > %s (%s)\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(cmd.Operator),
+			cmd.Origin,
+			tui.Yellow(cmd.Command),
+			result)
+	} else {
+		op := e.Data.(session.OperatorEvent)
+		action := "joined"
+		if e.Tag == "session.operator.left" {
+			action = "left"
+		}
+
+		fmt.Fprintf(output, "[%s] [%s] operator %s from %s %s the session\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(op.Name),
+			op.Origin,
+			action)
+	}
+}
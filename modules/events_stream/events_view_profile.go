@@ -0,0 +1,23 @@
+package events_stream
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bettercap/bettercap/modules/net_sniff"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *EventsStream) viewProfileEvent(output io.Writer, e session.Event) {
+	anomaly := e.Data.(net_sniff.ProfileAnomaly)
+
+	fmt.Fprintf(output, "[%s] [%s] %s is speaking %s (%s) with %s for the first time\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Red(e.Tag),
+		tui.Bold(anomaly.Address),
+		tui.Yellow(anomaly.Name),
+		anomaly.Protocol,
+		anomaly.Peer)
+}
@@ -0,0 +1,31 @@
+package events_stream
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bettercap/bettercap/modules/http_proxy"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *EventsStream) viewProxyEvent(output io.Writer, e session.Event) {
+	replay := e.Data.(http_proxy.ReplayEvent)
+
+	source := ""
+	if replay.Source != "" {
+		source = fmt.Sprintf(" from %s", tui.Bold(replay.Source))
+	}
+
+	fmt.Fprintf(output, "[%s] [%s] request #%d of %s replayed%s: %s %s -> %d (%d bytes)\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		replay.ID,
+		tui.Bold(replay.Client),
+		source,
+		tui.Yellow(replay.Method),
+		replay.URL,
+		replay.Status,
+		replay.Size)
+}
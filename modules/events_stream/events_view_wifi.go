@@ -87,15 +87,32 @@ func (mod *EventsStream) viewWiFiHandshakeEvent(output io.Writer, e session.Even
 		what += " (half)"
 	}
 
-	fmt.Fprintf(output, "[%s] [%s] captured %s -> %s %s to %s\n",
+	score := fmt.Sprintf("score %d", hand.Score)
+	if hand.Messages != "" {
+		score += " " + hand.Messages
+	}
+
+	fmt.Fprintf(output, "[%s] [%s] captured %s -> %s %s [%s] to %s\n",
 		e.Time.Format(mod.timeFormat),
 		tui.Green(e.Tag),
 		from,
 		to,
 		tui.Red(what),
+		tui.Dim(score),
 		hand.File)
 }
 
+func (mod *EventsStream) viewWiFiCrackEvent(output io.Writer, e session.Event) {
+	crack := e.Data.(wifi.CrackEvent)
+
+	fmt.Fprintf(output, "[%s] [%s] recovered key for %s (%s): %s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(crack.ESSID),
+		tui.Dim(crack.AP),
+		tui.Red(crack.PSK))
+}
+
 func (mod *EventsStream) viewWiFiClientEvent(output io.Writer, e session.Event) {
 	ce := e.Data.(wifi.ClientEvent)
 
@@ -132,7 +149,9 @@ func (mod *EventsStream) viewWiFiDeauthEvent(output io.Writer, e session.Event)
 }
 
 func (mod *EventsStream) viewWiFiEvent(output io.Writer, e session.Event) {
-	if strings.HasPrefix(e.Tag, "wifi.ap.") {
+	if e.Tag == "wifi.ap.cracked" {
+		mod.viewWiFiCrackEvent(output, e)
+	} else if strings.HasPrefix(e.Tag, "wifi.ap.") {
 		mod.viewWiFiApEvent(output, e)
 	} else if e.Tag == "wifi.deauthentication" {
 		mod.viewWiFiDeauthEvent(output, e)
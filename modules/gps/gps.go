@@ -3,6 +3,7 @@ package gps
 import (
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/bettercap/bettercap/session"
@@ -22,6 +23,10 @@ type GPS struct {
 
 	serial *serial.Port
 	gpsd   *gpsd.Session
+
+	track         []trackPoint
+	trackInterval time.Duration
+	trackLock     sync.Mutex
 }
 
 var ModeInfo = [4]string{
@@ -36,6 +41,7 @@ func NewGPS(s *session.Session) *GPS {
 		SessionModule: session.NewSessionModule("gps", s),
 		serialPort:    "/dev/ttyUSB0",
 		baudRate:      4800,
+		track:         make([]trackPoint, 0),
 	}
 
 	mod.AddParam(session.NewStringParameter("gps.device",
@@ -47,6 +53,10 @@ func NewGPS(s *session.Session) *GPS {
 		fmt.Sprintf("%d", mod.baudRate),
 		"Baud rate of the GPS serial device."))
 
+	mod.AddParam(session.NewIntParameter("gps.track.interval",
+		"5",
+		"Minimum number of seconds between two points of the recorded track."))
+
 	mod.AddHandler(session.NewModuleHandler("gps on", "",
 		"Start acquiring from the GPS hardware.",
 		func(args []string) error {
@@ -65,6 +75,24 @@ func NewGPS(s *session.Session) *GPS {
 			return mod.Show()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("gps.track.export FILENAME", `gps\.track\.export\s+(.+)`,
+		"Save the track recorded while the module was running as a GPX file.",
+		func(args []string) error {
+			return mod.exportTrack(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("gps.track.clear", "",
+		"Clear the recorded track.",
+		func(args []string) error {
+			return mod.clearTrack()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("gps.events LAT,LON LAT,LON", `gps\.events\s+([^\s]+)\s+([^\s]+)`,
+		"Show the events generated within the area delimited by two opposite corners.",
+		func(args []string) error {
+			return mod.showEvents(args[0], args[1])
+		}))
+
 	return mod
 }
 
@@ -81,14 +109,20 @@ func (mod *GPS) Author() string {
 }
 
 func (mod *GPS) Configure() (err error) {
+	var interval int
+
 	if mod.Running() {
 		return session.ErrAlreadyStarted(mod.Name())
 	} else if err, mod.serialPort = mod.StringParam("gps.device"); err != nil {
 		return err
 	} else if err, mod.baudRate = mod.IntParam("gps.baudrate"); err != nil {
 		return err
+	} else if err, interval = mod.IntParam("gps.track.interval"); err != nil {
+		return err
 	}
 
+	mod.trackInterval = time.Duration(interval) * time.Second
+
 	if mod.serialPort[0] == '/' || mod.serialPort[0] == '.' {
 		mod.Debug("connecting to serial port %s", mod.serialPort)
 		mod.serial, err = serial.OpenPort(&serial.Config{
@@ -148,6 +182,7 @@ func (mod *GPS) readFromSerial() {
 				mod.Session.GPS.Altitude = m.Altitude
 				mod.Session.GPS.Separation = m.Separation
 
+				mod.onFix()
 				mod.Session.Events.Add("gps.new", mod.Session.GPS)
 			}
 		} else {
@@ -167,6 +202,7 @@ func (mod *GPS) runFromGPSD() {
 		mod.Session.GPS.FixQuality = ModeInfo[report.Mode]
 		mod.Session.GPS.Altitude = report.Alt
 
+		mod.onFix()
 		mod.Session.Events.Add("gps.new", mod.Session.GPS)
 	})
 
@@ -185,6 +221,8 @@ func (mod *GPS) Start() error {
 		return err
 	}
 
+	mod.Session.Events.SetPositionSource(mod.position)
+
 	return mod.SetRunning(true, func() {
 		mod.Info("started on port %s ...", mod.serialPort)
 
@@ -202,6 +240,8 @@ func (mod *GPS) Start() error {
 
 func (mod *GPS) Stop() error {
 	return mod.SetRunning(false, func() {
+		mod.Session.Events.SetPositionSource(nil)
+
 		if mod.serial != nil {
 			// let the read fail and exit
 			mod.serial.Close()
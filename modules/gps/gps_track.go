@@ -0,0 +1,180 @@
+package gps
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+type trackPoint struct {
+	Latitude  float64   `xml:"lat,attr"`
+	Longitude float64   `xml:"lon,attr"`
+	Elevation float64   `xml:"ele"`
+	Time      time.Time `xml:"time"`
+}
+
+type gpxDocument struct {
+	XMLName xml.Name `xml:"gpx"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	Track   struct {
+		Name    string `xml:"name"`
+		Segment struct {
+			Points []trackPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+func (mod *GPS) hasFix() bool {
+	switch mod.Session.GPS.FixQuality {
+	case "", "0", "NoValueSeen", "NoFix":
+		return false
+	}
+	return mod.Session.GPS.Latitude != 0 || mod.Session.GPS.Longitude != 0
+}
+
+// used by the session to geotag the events
+func (mod *GPS) position() *session.Position {
+	if !mod.hasFix() {
+		return nil
+	}
+	return &session.Position{
+		Latitude:  mod.Session.GPS.Latitude,
+		Longitude: mod.Session.GPS.Longitude,
+		Altitude:  mod.Session.GPS.Altitude,
+		Updated:   mod.Session.GPS.Updated,
+	}
+}
+
+// called for every new fix, records a track point every gps.track.interval seconds
+func (mod *GPS) onFix() {
+	if !mod.hasFix() {
+		return
+	}
+
+	mod.trackLock.Lock()
+	defer mod.trackLock.Unlock()
+
+	now := time.Now()
+	if n := len(mod.track); n > 0 {
+		last := mod.track[n-1]
+		if now.Sub(last.Time) < mod.trackInterval {
+			return
+		} else if last.Latitude == mod.Session.GPS.Latitude && last.Longitude == mod.Session.GPS.Longitude {
+			// not moving
+			return
+		}
+	}
+
+	mod.track = append(mod.track, trackPoint{
+		Latitude:  mod.Session.GPS.Latitude,
+		Longitude: mod.Session.GPS.Longitude,
+		Elevation: mod.Session.GPS.Altitude,
+		Time:      now,
+	})
+}
+
+func (mod *GPS) clearTrack() error {
+	mod.trackLock.Lock()
+	defer mod.trackLock.Unlock()
+	mod.track = make([]trackPoint, 0)
+	return nil
+}
+
+func (mod *GPS) exportTrack(fileName string) error {
+	mod.trackLock.Lock()
+	defer mod.trackLock.Unlock()
+
+	if len(mod.track) == 0 {
+		return fmt.Errorf("no track points recorded yet")
+	}
+
+	fileName, err := fs.Expand(fileName)
+	if err != nil {
+		return err
+	}
+
+	doc := gpxDocument{
+		XMLNS:   "http://www.topografix.com/GPX/1/1",
+		Version: "1.1",
+		Creator: "bettercap",
+	}
+	doc.Track.Name = fmt.Sprintf("bettercap session %s", mod.Session.StartedAt.Format(time.RFC3339))
+	doc.Track.Segment.Points = mod.track
+
+	raw, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(fileName, append([]byte(xml.Header), raw...), 0644); err != nil {
+		return err
+	}
+
+	mod.Info("%d track points saved to %s", len(mod.track), fileName)
+	return nil
+}
+
+func parseCoordinates(s string) (lat float64, lon float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		err = fmt.Errorf("'%s' is not a LATITUDE,LONGITUDE pair", s)
+	} else if lat, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+		err = fmt.Errorf("invalid latitude '%s'", parts[0])
+	} else if lon, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+		err = fmt.Errorf("invalid longitude '%s'", parts[1])
+	}
+	return
+}
+
+// show the geotagged events within the bounding box defined by two opposite corners
+func (mod *GPS) showEvents(from string, to string) error {
+	lat1, lon1, err := parseCoordinates(from)
+	if err != nil {
+		return err
+	}
+	lat2, lon2, err := parseCoordinates(to)
+	if err != nil {
+		return err
+	}
+
+	if lat1 > lat2 {
+		lat1, lat2 = lat2, lat1
+	}
+	if lon1 > lon2 {
+		lon1, lon2 = lon2, lon1
+	}
+
+	rows := [][]string{}
+	for _, e := range mod.Session.Events.Sorted() {
+		if pos := e.Position; pos != nil &&
+			pos.Latitude >= lat1 && pos.Latitude <= lat2 &&
+			pos.Longitude >= lon1 && pos.Longitude <= lon2 {
+			rows = append(rows, []string{
+				e.Time.Format("15:04:05"),
+				tui.Yellow(e.Tag),
+				fmt.Sprintf("%f", pos.Latitude),
+				fmt.Sprintf("%f", pos.Longitude),
+			})
+		}
+	}
+
+	if len(rows) == 0 {
+		mod.Info("no events within the area")
+		return nil
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Time", "Event", "Latitude", "Longitude"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
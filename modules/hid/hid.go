@@ -105,7 +105,7 @@ func NewHIDRecon(s *session.Session) *HIDRecon {
 		}))
 
 	inject := session.NewModuleHandler("hid.inject ADDRESS LAYOUT FILENAME", `(?i)^hid\.inject ([a-f0-9]{2}:[a-f0-9]{2}:[a-f0-9]{2}:[a-f0-9]{2}:[a-f0-9]{2})\s+(.+)\s+(.+)$`,
-		"Parse the duckyscript FILENAME and inject it as HID frames spoofing the device ADDRESS, using the LAYOUT keyboard mapping.",
+		"Parse the duckyscript FILENAME and inject it as HID frames spoofing the device ADDRESS, using the LAYOUT keyboard mapping. If FILENAME is a folder, the windows, macos, linux or default script inside it will be selected according to the target operating system.",
 		func(args []string) error {
 			if err := mod.setInjectionMode(args[0]); err != nil {
 				return err
@@ -119,6 +119,16 @@ func NewHIDRecon(s *session.Session) *HIDRecon {
 
 	mod.AddHandler(inject)
 
+	mod.AddParam(session.NewStringParameter("hid.inject.os",
+		TargetOSAuto,
+		fmt.Sprintf("^(%s|%s|%s|%s)$", TargetOSAuto, TargetOSWindows, TargetOSMacOS, TargetOSLinux),
+		"Operating system of the host the target receiver is plugged into, used to select the payload variant from a payload set, or 'auto' to detect it."))
+
+	mod.AddParam(session.NewStringParameter("hid.inject.host",
+		"",
+		"",
+		"IP or MAC address of the host the target receiver is plugged into, if set and hid.inject.os is 'auto', its operating system will be guessed from the net.recon data."))
+
 	mod.AddParam(session.NewIntParameter("hid.ttl",
 		fmt.Sprintf("%d", mod.devTTL),
 		"Seconds of inactivity to consider a device as not in range."))
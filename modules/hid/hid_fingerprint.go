@@ -0,0 +1,147 @@
+package hid
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	TargetOSAuto    = "auto"
+	TargetOSWindows = "windows"
+	TargetOSMacOS   = "macos"
+	TargetOSLinux   = "linux"
+
+	payloadSetDefault = "default"
+)
+
+// guess the operating system of a LAN host from what net.recon, net.probe
+// and net.sniff collected about it, returns an empty string if unsure
+func osFromEndpoint(e *network.Endpoint) (string, string) {
+	if guess := e.OS(); guess != "" {
+		lguess := strings.ToLower(guess)
+		switch {
+		case strings.Contains(lguess, "windows"):
+			return TargetOSWindows, "os fingerprint"
+		case strings.Contains(lguess, "mac"), strings.Contains(lguess, "ios"):
+			return TargetOSMacOS, "os fingerprint"
+		case strings.Contains(lguess, "linux"):
+			return TargetOSLinux, "os fingerprint"
+		}
+	}
+
+	hostname := strings.ToUpper(e.Hostname)
+	for _, prefix := range []string{"DESKTOP-", "LAPTOP-", "WIN-"} {
+		if strings.HasPrefix(hostname, prefix) {
+			return TargetOSWindows, "hostname"
+		}
+	}
+
+	if strings.Contains(e.Vendor, "Apple") {
+		return TargetOSMacOS, "vendor"
+	}
+
+	guess, reason := "", ""
+	e.Meta.Each(func(name string, value interface{}) {
+		if guess != "" {
+			return
+		}
+		v := strings.ToLower(fmt.Sprintf("%v", value))
+		if strings.HasPrefix(name, "nbns:") {
+			guess, reason = TargetOSWindows, "netbios"
+		} else if strings.HasPrefix(name, "mdns:") && (strings.Contains(v, "macbook") || strings.Contains(v, "imac")) {
+			guess, reason = TargetOSMacOS, "mdns"
+		} else if strings.HasPrefix(name, "upnp:") && strings.Contains(v, "windows") {
+			guess, reason = TargetOSWindows, "upnp"
+		} else if strings.HasPrefix(name, "upnp:") && strings.Contains(v, "linux") {
+			guess, reason = TargetOSLinux, "upnp"
+		}
+	})
+
+	return guess, reason
+}
+
+func (mod *HIDRecon) detectTargetOS() (string, string) {
+	if err, target := mod.StringParam("hid.inject.os"); err != nil {
+		mod.Warning("%v", err)
+	} else if target != TargetOSAuto {
+		return target, "hid.inject.os"
+	}
+
+	err, host := mod.StringParam("hid.inject.host")
+	if err != nil {
+		mod.Warning("%v", err)
+		return "", ""
+	} else if host == "" {
+		return "", ""
+	}
+
+	e := mod.Session.Lan.GetByIp(host)
+	if e == nil {
+		if e, _ = mod.Session.Lan.Get(host); e == nil {
+			mod.Warning("host %s not found, make sure net.recon is running and it has been discovered", host)
+			return "", ""
+		}
+	}
+
+	guess, reason := osFromEndpoint(e)
+	if guess == "" {
+		mod.Warning("could not determine the operating system of %s", e.String())
+	} else {
+		reason = fmt.Sprintf("%s of %s", reason, e.IpAddress)
+	}
+
+	return guess, reason
+}
+
+// if the script path is a folder, select the payload variant
+// matching the target operating system from it
+func (mod *HIDRecon) selectPayload(path string) (string, error) {
+	expanded, err := fs.Expand(path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(expanded)
+	if err != nil {
+		return "", err
+	} else if !info.IsDir() {
+		return path, nil
+	}
+
+	files, err := ioutil.ReadDir(expanded)
+	if err != nil {
+		return "", err
+	}
+
+	variants := make(map[string]string)
+	for _, f := range files {
+		if !f.IsDir() {
+			name := strings.ToLower(strings.TrimSuffix(f.Name(), filepath.Ext(f.Name())))
+			variants[name] = filepath.Join(expanded, f.Name())
+		}
+	}
+
+	target, reason := mod.detectTargetOS()
+	if target != "" {
+		if variant, found := variants[target]; found {
+			mod.Info("target operating system is %s (from %s), using %s", tui.Yellow(target), reason, variant)
+			return variant, nil
+		}
+		mod.Warning("payload set %s has no variant for %s", path, target)
+	}
+
+	if variant, found := variants[payloadSetDefault]; found {
+		mod.Info("using default payload %s", variant)
+		return variant, nil
+	}
+
+	return "", fmt.Errorf("could not select a payload from %s for target operating system '%s'", path, target)
+}
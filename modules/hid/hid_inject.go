@@ -74,13 +74,19 @@ func (mod *HIDRecon) prepInjection() (error, *network.HIDDevice, []*Command) {
 		return errNoKeyMap(mod.keyLayout), nil, nil
 	}
 
+	// select the payload variant for the target if this is a payload set
+	scriptPath, err := mod.selectPayload(mod.scriptPath)
+	if err != nil {
+		return err, nil, nil
+	}
+
 	// parse the script into a list of Command objects
-	cmds, err := mod.parser.Parse(keyMap, mod.scriptPath)
+	cmds, err := mod.parser.Parse(keyMap, scriptPath)
 	if err != nil {
 		return err, nil, nil
 	}
 
-	mod.Info("%s loaded ...", mod.scriptPath)
+	mod.Info("%s loaded ...", scriptPath)
 
 	// build the protocol specific frames to send
 	if err := builder.BuildFrames(dev, cmds); err != nil {
@@ -1,6 +1,7 @@
 package http_proxy
 
 import (
+	"github.com/bettercap/bettercap/firewall"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/evilsocket/islazy/str"
@@ -50,10 +51,20 @@ func NewHttpProxy(s *session.Session) *HttpProxy {
 	mod.AddParam(session.NewStringParameter("http.proxy.whitelist", "", "",
 		"Comma separated list of hostnames to proxy if the blacklist is used (wildcard expressions can be used)."))
 
+	mod.AddParam(session.NewStringParameter("http.proxy.targets", "", "",
+		"Comma separated list of IP addresses, MAC addresses or aliases whose traffic is redirected to the proxy, all of the subnet if empty."))
+
 	mod.AddParam(session.NewBoolParameter("http.proxy.sslstrip",
 		"false",
 		"Enable or disable SSL stripping."))
 
+	mod.Needs("a redirection backend", func() error {
+		if err, redirect := mod.BoolParam("http.proxy.redirect"); err == nil && redirect {
+			return firewall.Available()
+		}
+		return nil
+	})
+
 	mod.AddHandler(session.NewModuleHandler("http.proxy on", "",
 		"Start HTTP proxy.",
 		func(args []string) error {
@@ -66,7 +77,10 @@ func NewHttpProxy(s *session.Session) *HttpProxy {
 			return mod.Stop()
 		}))
 
-		mod.InitState("stripper")
+	AddReplayHandlers(&mod.SessionModule, "http.proxy", mod.proxy)
+	AddVaultHandlers(&mod.SessionModule, "http.proxy", mod.proxy)
+
+	mod.InitState("stripper")
 
 	return mod
 }
@@ -94,6 +108,8 @@ func (mod *HttpProxy) Configure() error {
 	var jsToInject string
 	var blacklist string
 	var whitelist string
+	var vaultDomains string
+	var targets string
 
 	if mod.Running() {
 		return session.ErrAlreadyStarted(mod.Name())
@@ -115,10 +131,27 @@ func (mod *HttpProxy) Configure() error {
 		return err
 	} else if err, whitelist = mod.StringParam("http.proxy.whitelist"); err != nil {
 		return err
+	} else if err, vaultDomains = mod.StringParam("http.proxy.vault.domains"); err != nil {
+		return err
+	} else if err, targets = mod.StringParam("http.proxy.targets"); err != nil {
+		return err
+	} else if err = mod.proxy.SetTargets(targets); err != nil {
+		return err
 	}
 
 	mod.proxy.Blacklist = str.Comma(blacklist)
 	mod.proxy.Whitelist = str.Comma(whitelist)
+	mod.proxy.VaultDomains = str.Comma(vaultDomains)
+
+	if doRedirect {
+		mod.Claim(firewall.RedirectionResource("tcp", httpPort))
+	} else {
+		mod.Claim()
+	}
+
+	if err = mod.CheckDependencies(); err != nil {
+		return err
+	}
 
 	error := mod.proxy.Configure(address, proxyPort, httpPort, doRedirect, scriptPath, jsToInject, stripSSL)
 
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/bettercap/bettercap/firewall"
+	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 	btls "github.com/bettercap/bettercap/tls"
 
@@ -35,18 +36,27 @@ const (
 )
 
 type HTTPProxy struct {
-	Name        string
-	Address     string
-	Server      *http.Server
-	Redirection *firewall.Redirection
+	Name         string
+	Address      string
+	Server       *http.Server
+	Redirections []*firewall.Redirection
+	// when set only the traffic of these IP and MAC addresses is redirected
+	Clients     []string
 	Proxy       *goproxy.ProxyHttpServer
 	Script      *HttpProxyScript
 	CertFile    string
 	KeyFile     string
 	Blacklist   []string
 	Whitelist   []string
+	Passthrough []string
+	Scope       []string
 	Sess        *session.Session
 	Stripper    *SSLStripper
+	Requests    *RequestLog
+	Vault       *TokenVault
+
+	// domains to collect cookies and tokens for
+	VaultDomains []string
 
 	jsHook      string
 	isTLS       bool
@@ -78,6 +88,8 @@ func NewHTTPProxy(s *session.Session, tag string) *HTTPProxy {
 		Proxy:      goproxy.NewProxyHttpServer(),
 		Sess:       s,
 		Stripper:   NewSSLStripper(s, false),
+		Requests:   NewRequestLog(),
+		Vault:      NewTokenVault(),
 		isTLS:      false,
 		doRedirect: true,
 		Server:     nil,
@@ -88,6 +100,9 @@ func NewHTTPProxy(s *session.Session, tag string) *HTTPProxy {
 
 	p.Proxy.Verbose = false
 	p.Proxy.Logger = dummyLogger{p}
+	// resolve upstream hosts through the session DNS settings
+	p.Proxy.Tr.Dial = network.DNS.Dial
+	p.Proxy.Tr.DialContext = network.DNS.DialContext
 
 	p.Proxy.NonproxyHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		if p.doProxy(req) {
@@ -235,17 +250,28 @@ func (p *HTTPProxy) Configure(address string, proxyPort int, httpPort int, doRed
 			p.Sess.Firewall.EnableForwarding(true)
 		}
 
-		p.Redirection = firewall.NewRedirection(p.Sess.Interface.Name(),
-			"TCP",
-			httpPort,
-			p.Address,
-			proxyPort)
-
-		if err := p.Sess.Firewall.EnableRedirection(p.Redirection, true); err != nil {
-			return err
+		clients := p.Clients
+		if len(clients) == 0 {
+			clients = []string{""}
 		}
 
-		p.Debug("applied redirection %s", p.Redirection.String())
+		p.Redirections = make([]*firewall.Redirection, 0)
+		for _, client := range clients {
+			redir := firewall.NewRedirection(p.Sess.Interface.Name(),
+				"TCP",
+				httpPort,
+				p.Address,
+				proxyPort)
+			redir.Client = client
+
+			if err := p.Sess.Firewall.EnableRedirection(redir, true); err != nil {
+				p.disableRedirections()
+				return err
+			}
+
+			p.Redirections = append(p.Redirections, redir)
+			p.Debug("applied redirection %s", redir.String())
+		}
 	} else {
 		p.Warning("port redirection disabled, the proxy must be set manually to work")
 	}
@@ -389,6 +415,11 @@ func (p *HTTPProxy) httpsWorker() error {
 				return
 			}
 
+			if p.isPassthrough(hostname) {
+				p.passthrough(tlsConn, hostname)
+				return
+			}
+
 			p.Debug("proxying connection from %s to %s", tui.Bold(stripPort(c.RemoteAddr().String())), tui.Yellow(hostname))
 
 			req := &http.Request{
@@ -431,17 +462,31 @@ func (p *HTTPProxy) Start() {
 	}()
 }
 
+func (p *HTTPProxy) disableRedirections() error {
+	for len(p.Redirections) > 0 {
+		redir := p.Redirections[0]
+		p.Debug("disabling redirection %s", redir.String())
+		if err := p.Sess.Firewall.EnableRedirection(redir, false); err != nil {
+			return err
+		}
+		p.Redirections = p.Redirections[1:]
+	}
+	return nil
+}
+
 func (p *HTTPProxy) Stop() error {
-	if p.doRedirect && p.Redirection != nil {
-		p.Debug("disabling redirection %s", p.Redirection.String())
-		if err := p.Sess.Firewall.EnableRedirection(p.Redirection, false); err != nil {
+	if p.doRedirect {
+		if err := p.disableRedirections(); err != nil {
 			return err
 		}
-		p.Redirection = nil
 	}
 
 	p.Sess.UnkCmdCallback = nil
 
+	if p.Script != nil {
+		p.Script.Unload()
+	}
+
 	if p.isTLS {
 		p.isRunning = false
 		p.sniListener.Close()
@@ -24,6 +24,8 @@ func (p *HTTPProxy) onRequestFilter(req *http.Request, ctx *goproxy.ProxyCtx) (*
 		p.Debug("< %s %s %s%s", req.RemoteAddr, req.Method, req.Host, req.URL.Path)
 
 		p.fixRequestHeaders(req)
+		p.Requests.Add(req)
+		p.vaultRequest(req)
 
 		redir := p.Stripper.Preprocess(req, ctx)
 		if redir != nil {
@@ -108,6 +110,7 @@ func (p *HTTPProxy) onResponseFilter(res *http.Response, ctx *goproxy.ProxyCtx)
 	if p.shouldProxy(res.Request) {
 		p.Debug("> %s %s %s%s", res.Request.RemoteAddr, res.Request.Method, res.Request.Host, res.Request.URL.Path)
 
+		p.vaultResponse(res)
 		p.Stripper.Process(res, ctx)
 
 		// do we have a proxy script?
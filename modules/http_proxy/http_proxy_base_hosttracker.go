@@ -3,6 +3,8 @@ package http_proxy
 import (
 	"net"
 	"sync"
+
+	"github.com/bettercap/bettercap/network"
 )
 
 type Host struct {
@@ -21,7 +23,7 @@ func NewHost(name string) *Host {
 	h.Resolved.Add(1)
 	go func(ph *Host) {
 		defer ph.Resolved.Done()
-		if addrs, err := net.LookupIP(ph.Hostname); err == nil && len(addrs) > 0 {
+		if addrs, err := network.DNS.LookupIP(ph.Hostname); err == nil && len(addrs) > 0 {
 			ph.Address = make(net.IP, len(addrs[0]))
 			copy(ph.Address, addrs[0])
 		} else {
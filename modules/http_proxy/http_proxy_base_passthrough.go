@@ -0,0 +1,74 @@
+package http_proxy
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const passthroughDialTimeout = 10 * time.Second
+
+func (p *HTTPProxy) matchesAny(list []string, hostname string) bool {
+	for _, expr := range list {
+		if matched, err := filepath.Match(expr, hostname); err != nil {
+			p.Error("error while using proxy expression '%s': %v", expr, err)
+		} else if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// a connection is relayed as it is if its SNI matches the passthrough list
+// or, when a scope is set, if it doesn't match the scope
+func (p *HTTPProxy) isPassthrough(hostname string) bool {
+	if p.matchesAny(p.Passthrough, hostname) {
+		return true
+	} else if len(p.Scope) > 0 && !p.matchesAny(p.Scope, hostname) {
+		return true
+	}
+	return false
+}
+
+// relay the connection to the real server without terminating TLS, the
+// client hello peeked by vhost is replayed on the first read from conn
+func (p *HTTPProxy) passthrough(conn net.Conn, hostname string) {
+	defer conn.Close()
+
+	client := stripPort(conn.RemoteAddr().String())
+	server, err := network.DNS.DialTimeout("tcp", net.JoinHostPort(hostname, "443"), passthroughDialTimeout)
+	if err != nil {
+		p.Warning("error connecting to %s for %s: %s.", hostname, client, err)
+		return
+	}
+	defer server.Close()
+
+	p.Debug("passing through connection from %s to %s", tui.Bold(client), tui.Yellow(hostname))
+
+	// long lived connections must not hit the proxy timeouts
+	conn.SetDeadline(time.Time{})
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	splice := func(dst net.Conn, src net.Conn) {
+		defer wg.Done()
+		io.Copy(dst, src)
+		// unblock the other direction
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		} else {
+			dst.Close()
+		}
+	}
+
+	go splice(server, conn)
+	go splice(conn, server)
+
+	wg.Wait()
+}
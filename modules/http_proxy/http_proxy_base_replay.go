@@ -0,0 +1,440 @@
+package http_proxy
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	maxCapturedRequests = 128
+	maxCapturedBody     = 64 * 1024
+	maxReplayPreview    = 1024
+	replayTimeout       = 15 * time.Second
+)
+
+type CapturedRequest struct {
+	ID      int         `json:"id"`
+	Time    time.Time   `json:"time"`
+	Client  string      `json:"client"`
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Host    string      `json:"host"`
+	Headers http.Header `json:"headers"`
+	Body    []byte      `json:"body"`
+}
+
+type ReplayEvent struct {
+	ID      int         `json:"id"`
+	Client  string      `json:"client"`
+	Source  string      `json:"source"`
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Size    int         `json:"size"`
+}
+
+// RequestLog keeps the most recent requests that went through the proxy.
+type RequestLog struct {
+	sync.RWMutex
+	nextID   int
+	requests []*CapturedRequest
+}
+
+func NewRequestLog() *RequestLog {
+	return &RequestLog{
+		nextID:   1,
+		requests: make([]*CapturedRequest, 0),
+	}
+}
+
+func (l *RequestLog) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.List())
+}
+
+func (l *RequestLog) List() []*CapturedRequest {
+	l.RLock()
+	defer l.RUnlock()
+
+	list := make([]*CapturedRequest, len(l.requests))
+	copy(list, l.requests)
+	return list
+}
+
+func (l *RequestLog) Get(id int) *CapturedRequest {
+	l.RLock()
+	defer l.RUnlock()
+
+	for _, r := range l.requests {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// return the most recent request of client to host
+func (l *RequestLog) lastOf(client string, host string) *CapturedRequest {
+	l.RLock()
+	defer l.RUnlock()
+
+	for i := len(l.requests) - 1; i >= 0; i-- {
+		if r := l.requests[i]; r.Client == client && r.Host == host {
+			return r
+		}
+	}
+	return nil
+}
+
+func (l *RequestLog) Add(req *http.Request) {
+	captured := &CapturedRequest{
+		Time:    time.Now(),
+		Client:  stripPort(req.RemoteAddr),
+		Method:  req.Method,
+		Host:    stripPort(req.Host),
+		Headers: cloneHeader(req.Header),
+	}
+
+	u := *req.URL
+	if u.Host == "" {
+		u.Host = req.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+	}
+	captured.URL = u.String()
+
+	if req.Body != nil {
+		// only buffer what's kept, the rest is streamed to the server
+		if head, err := ioutil.ReadAll(io.LimitReader(req.Body, maxCapturedBody)); err == nil {
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+			captured.Body = append([]byte{}, head...)
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	captured.ID = l.nextID
+	l.nextID++
+
+	l.requests = append(l.requests, captured)
+	if len(l.requests) > maxCapturedRequests {
+		l.requests = l.requests[len(l.requests)-maxCapturedRequests:]
+	}
+}
+
+func cloneHeader(h http.Header) http.Header {
+	clone := make(http.Header, len(h))
+	for name, values := range h {
+		clone[name] = append([]string{}, values...)
+	}
+	return clone
+}
+
+// a set of changes to apply to a captured request before replaying it
+type replayMutations struct {
+	Method string
+	URL    string
+	Set    map[string]string
+	Del    []string
+	Body   *string
+	From   net.IP
+	As     string
+}
+
+// split "a=b header=\"X-Foo: bar baz\"" honoring double quotes
+func splitMutations(s string) ([]string, error) {
+	tokens := []string{}
+	current := ""
+	quoted := false
+	for _, c := range s {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ' ' && !quoted {
+			if current != "" {
+				tokens = append(tokens, current)
+				current = ""
+			}
+		} else {
+			current += string(c)
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in '%s'", s)
+	} else if current != "" {
+		tokens = append(tokens, current)
+	}
+	return tokens, nil
+}
+
+func parseMutations(s string) (*replayMutations, error) {
+	m := &replayMutations{
+		Set: make(map[string]string),
+		Del: make([]string, 0),
+	}
+
+	tokens, err := splitMutations(s)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid mutation '%s', expected key=value", token)
+		}
+
+		key, value := strings.ToLower(parts[0]), parts[1]
+		switch key {
+		case "method":
+			m.Method = strings.ToUpper(value)
+		case "url":
+			if _, err := url.Parse(value); err != nil {
+				return nil, err
+			}
+			m.URL = value
+		case "header":
+			kv := strings.SplitN(value, ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid header '%s', expected Name:Value", value)
+			}
+			m.Set[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		case "-header":
+			m.Del = append(m.Del, value)
+		case "body":
+			if strings.HasPrefix(value, "@") {
+				raw, err := ioutil.ReadFile(value[1:])
+				if err != nil {
+					return nil, err
+				}
+				value = string(raw)
+			}
+			m.Body = &value
+		case "from":
+			if m.From = net.ParseIP(value); m.From == nil {
+				return nil, fmt.Errorf("invalid source address '%s'", value)
+			}
+		case "as":
+			m.As = value
+		default:
+			return nil, fmt.Errorf("unknown mutation '%s'", key)
+		}
+	}
+
+	return m, nil
+}
+
+func (p *HTTPProxy) buildReplay(orig *CapturedRequest, m *replayMutations) (*http.Request, error) {
+	method, target, body := orig.Method, orig.URL, orig.Body
+	if m.Method != "" {
+		method = m.Method
+	}
+	if m.URL != "" {
+		target = m.URL
+	}
+	if m.Body != nil {
+		body = []byte(*m.Body)
+	}
+
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = cloneHeader(orig.Headers)
+	// the content length is set by the client from the new body
+	req.Header.Del("Content-Length")
+
+	if m.As != "" {
+		// borrow the identity another client used for the same host
+		other := p.Requests.lastOf(m.As, orig.Host)
+		if other == nil {
+			return nil, fmt.Errorf("no captured requests from %s to %s", m.As, orig.Host)
+		}
+		for _, name := range []string{"Cookie", "Authorization"} {
+			req.Header.Del(name)
+			if value := other.Headers.Get(name); value != "" {
+				req.Header.Set(name, value)
+			}
+		}
+	}
+
+	for name, value := range m.Set {
+		if strings.ToLower(name) == "host" {
+			req.Host = value
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+	for _, name := range m.Del {
+		req.Header.Del(name)
+	}
+
+	return req, nil
+}
+
+// Replay sends again the captured request with the given id after applying the mutations.
+func (p *HTTPProxy) Replay(id int, mutations string) (*ReplayEvent, []byte, error) {
+	orig := p.Requests.Get(id)
+	if orig == nil {
+		return nil, nil, fmt.Errorf("request %d not found", id)
+	}
+
+	m, err := parseMutations(mutations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := p.buildReplay(orig, m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: replayTimeout}
+	if m.From != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: m.From}
+	}
+
+	client := &http.Client{
+		Timeout: replayTimeout,
+		Transport: &http.Transport{
+			Proxy:           nil,
+			DialContext:     network.DNS.DialContextWith(dialer),
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		// we want to see redirects, not follow them
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event := &ReplayEvent{
+		ID:      orig.ID,
+		Client:  orig.Client,
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Status:  res.StatusCode,
+		Headers: res.Header,
+		Size:    len(raw),
+	}
+	if m.From != nil {
+		event.Source = m.From.String()
+	}
+
+	return event, raw, nil
+}
+
+func (p *HTTPProxy) showRequests() error {
+	rows := [][]string{}
+	for _, r := range p.Requests.List() {
+		rows = append(rows, []string{
+			strconv.Itoa(r.ID),
+			r.Time.Format("15:04:05"),
+			tui.Bold(r.Client),
+			tui.Yellow(r.Method),
+			r.URL,
+			strconv.Itoa(len(r.Body)),
+		})
+	}
+
+	if len(rows) == 0 {
+		p.Info("no requests captured yet")
+		return nil
+	}
+
+	tui.Table(p.Sess.Events.Stdout, []string{"ID", "Time", "Client", "Method", "URL", "Body"}, rows)
+	p.Sess.Refresh()
+
+	return nil
+}
+
+func (p *HTTPProxy) doReplay(name string, id int, mutations string) error {
+	event, body, err := p.Replay(id, mutations)
+	if err != nil {
+		return err
+	}
+
+	p.Sess.Events.Add(name+".replayed", *event)
+
+	headers := []string{}
+	for header := range event.Headers {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+
+	fmt.Fprintf(p.Sess.Events.Stdout, "\n%s %s -> %s\n\n", tui.Yellow(event.Method), event.URL, tui.Bold(strconv.Itoa(event.Status)))
+	for _, header := range headers {
+		fmt.Fprintf(p.Sess.Events.Stdout, "  %s: %s\n", tui.Blue(header), strings.Join(event.Headers[header], ", "))
+	}
+
+	if len(body) > 0 {
+		preview := body
+		if len(preview) > maxReplayPreview {
+			preview = preview[:maxReplayPreview]
+		}
+		fmt.Fprintf(p.Sess.Events.Stdout, "\n%s\n", string(preview))
+		if len(body) > len(preview) {
+			fmt.Fprintf(p.Sess.Events.Stdout, "%s\n", tui.Dim(fmt.Sprintf("... %d more bytes", len(body)-len(preview))))
+		}
+	}
+	fmt.Fprintf(p.Sess.Events.Stdout, "\n")
+
+	p.Sess.Refresh()
+
+	return nil
+}
+
+// AddReplayHandlers registers the commands to list and replay the requests
+// captured by the proxy into the module with the given name.
+func AddReplayHandlers(mod *session.SessionModule, name string, p *HTTPProxy) {
+	mod.State.Store("requests", p.Requests)
+
+	mod.AddHandler(session.NewModuleHandler(name+".requests", "",
+		"Show the most recent requests that went through the proxy.",
+		func(args []string) error {
+			return p.showRequests()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler(name+".replay ID MUTATIONS?",
+		strings.Replace(name, ".", `\.`, -1)+`\.replay (\d+)\s*(.*)`,
+		"Send again the captured request ID, optional space separated MUTATIONS can be method=VERB, url=URL, "+
+			"header=\"Name: Value\", -header=Name, body=DATA (or body=@file), from=ADDRESS to use a different source address and "+
+			"as=CLIENT to use the cookies and authorization of another client for the same host.",
+		func(args []string) error {
+			id, _ := strconv.Atoi(args[0])
+			return p.doReplay(name, id, args[1])
+		}))
+}
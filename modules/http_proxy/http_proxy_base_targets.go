@@ -0,0 +1,24 @@
+package http_proxy
+
+import (
+	"github.com/bettercap/bettercap/network"
+)
+
+// SetTargets limits the redirection to the traffic of the comma separated
+// IP addresses, MAC addresses or aliases, all of the subnet if empty.
+func (p *HTTPProxy) SetTargets(targets string) error {
+	ips, macs, err := network.ParseTargets(targets, p.Sess.Lan.Aliases())
+	if err != nil {
+		return err
+	}
+
+	p.Clients = make([]string, 0, len(ips)+len(macs))
+	for _, ip := range ips {
+		p.Clients = append(p.Clients, ip.String())
+	}
+	for _, mac := range macs {
+		p.Clients = append(p.Clients, mac.String())
+	}
+
+	return nil
+}
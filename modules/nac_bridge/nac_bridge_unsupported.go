@@ -0,0 +1,43 @@
+// +build !linux
+
+package nac_bridge
+
+import (
+	"github.com/bettercap/bettercap/session"
+)
+
+type NACBridge struct {
+	session.SessionModule
+}
+
+func NewNACBridge(s *session.Session) *NACBridge {
+	mod := &NACBridge{
+		SessionModule: session.NewSessionModule("nac.bridge", s),
+	}
+	mod.NotSupported()
+	return mod
+}
+
+func (mod *NACBridge) Name() string {
+	return "nac.bridge"
+}
+
+func (mod *NACBridge) Description() string {
+	return "Not supported on this OS"
+}
+
+func (mod *NACBridge) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NACBridge) Configure() (err error) {
+	return session.ErrNotSupported
+}
+
+func (mod *NACBridge) Start() error {
+	return session.ErrNotSupported
+}
+
+func (mod *NACBridge) Stop() error {
+	return session.ErrNotSupported
+}
@@ -0,0 +1,331 @@
+package ndp_recon
+
+import (
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// IPv6 only hosts not seen for this many probe periods start being removed
+const lostAfterPeriods = 3
+
+type NDPRecon struct {
+	session.SessionModule
+	period        int
+	echoID        uint16
+	echoSeq       uint16
+	handle        *pcap.Handle
+	pktSourceChan chan gopacket.Packet
+	quit          chan bool
+	waitGroup     *sync.WaitGroup
+}
+
+func NewNDPRecon(s *session.Session) *NDPRecon {
+	mod := &NDPRecon{
+		SessionModule: session.NewSessionModule("ndp.recon", s),
+		echoID:        uint16(os.Getpid()),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewIntParameter("ndp.recon.period",
+		"10",
+		"Seconds between the router solicitations, the neighbor solicitations of the known addresses and the pings of ff02::1, 0 to only listen."))
+
+	mod.AddHandler(session.NewModuleHandler("ndp.recon on", "",
+		"Start IPv6 hosts discovery.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("ndp.recon off", "",
+		"Stop IPv6 hosts discovery.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("ndp.show", "",
+		"Show the IPv6 addresses of the hosts.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	return mod
+}
+
+func (mod NDPRecon) Name() string {
+	return "ndp.recon"
+}
+
+func (mod NDPRecon) Description() string {
+	return "Discover the IPv6 hosts and their link-local and global addresses with the neighbor discovery protocol and multicast pings."
+}
+
+func (mod NDPRecon) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NDPRecon) Configure() (err error) {
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	} else if err, mod.period = mod.IntParam("ndp.recon.period"); err != nil {
+		return err
+	} else if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 1024, true, pcap.BlockForever); err != nil {
+		return err
+	} else if err = mod.handle.SetBPFFilter("icmp6"); err != nil {
+		mod.handle.Close()
+		return err
+	}
+
+	mod.quit = make(chan bool)
+	return nil
+}
+
+// the IPv6 addresses of the interface, each one gets the answers of its scope
+func (mod *NDPRecon) addresses() []net.IP {
+	addresses := []net.IP{}
+	iface, err := net.InterfaceByName(mod.Session.Interface.Name())
+	if err != nil {
+		return addresses
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return addresses
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() == nil && !ipNet.IP.IsLoopback() {
+			addresses = append(addresses, ipNet.IP)
+		}
+	}
+	return addresses
+}
+
+func (mod *NDPRecon) send(what string, err error, raw []byte) {
+	if err != nil {
+		mod.Error("error creating %s: %v", what, err)
+	} else if err = mod.Session.Queue.Send(raw); err != nil {
+		mod.Debug("error sending %s: %v", what, err)
+	}
+}
+
+func (mod *NDPRecon) probe() {
+	hw := mod.Session.Interface.HW
+	addresses := mod.addresses()
+	if len(addresses) == 0 {
+		mod.Debug("no IPv6 addresses on %s, only listening", mod.Session.Interface.Name())
+		return
+	}
+
+	mod.echoSeq++
+	for _, addr := range addresses {
+		if addr.IsLinkLocalUnicast() {
+			err, raw := packets.ICMP6RouterSolicitation(hw, addr)
+			mod.send("router solicitation", err, raw)
+		}
+		err, raw := packets.ICMP6MulticastEcho(hw, addr, mod.echoID, mod.echoSeq)
+		mod.send("multicast ping", err, raw)
+	}
+
+	// keep the known addresses alive, from the link-local address
+	from := addresses[0]
+	for _, addr := range addresses {
+		if addr.IsLinkLocalUnicast() {
+			from = addr
+			break
+		}
+	}
+	for _, e := range mod.Session.Lan.List() {
+		for _, address := range e.Ip6Addresses {
+			if ip := net.ParseIP(address); ip != nil {
+				err, raw := packets.ICMP6NeighborSolicitation(hw, from, ip)
+				mod.send("neighbor solicitation", err, raw)
+			}
+		}
+	}
+}
+
+// IPv6 only hosts are not in the ARP cache, they're removed as net.recon
+// does once they stop answering
+func (mod *NDPRecon) prune(lostAfter time.Duration) {
+	for _, e := range mod.Session.Lan.List() {
+		if e.IP.To4() == nil && len(e.Ip6Addresses) > 0 && time.Since(e.LastSeen) > lostAfter {
+			mod.Session.Lan.Remove(e.IpAddress, e.HwAddress)
+		}
+	}
+}
+
+func (mod *NDPRecon) prober() {
+	defer mod.waitGroup.Done()
+
+	lostAfter := time.Duration(lostAfterPeriods*mod.period) * time.Second
+	if mod.period == 0 {
+		lostAfter = 2 * time.Minute
+	}
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	elapsed := 0
+	for {
+		if mod.period > 0 && elapsed%mod.period == 0 {
+			mod.probe()
+		}
+
+		select {
+		case <-mod.quit:
+			return
+		case <-tick.C:
+			elapsed++
+			mod.prune(lostAfter)
+		}
+	}
+}
+
+func (mod *NDPRecon) onAddress(hw net.HardwareAddr, ip net.IP, router bool, source string) {
+	e, isNew := mod.Session.Lan.AddIPv6(ip, hw.String())
+	if e == nil {
+		return
+	}
+
+	if isNew {
+		mod.Debug("%s has IPv6 address %s (%s)", hw, ip, source)
+	}
+	if router {
+		if v, ok := e.Meta.Get("ndp:router").(string); !ok || v != "true" {
+			e.OnMeta(map[string]string{"ndp:router": "true"})
+		}
+	}
+}
+
+func (mod *NDPRecon) onPacket(pkt gopacket.Packet) {
+	eth, ok := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	if !ok || eth.SrcMAC.String() == mod.Session.Interface.HwAddress {
+		return
+	}
+
+	if n := packets.ParseNDPNeighbor(pkt); n != nil {
+		mod.onAddress(n.HW, n.IP, n.Router, n.Source)
+		return
+	}
+
+	// the answers to our multicast pings
+	icmp6, ok := pkt.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6)
+	if !ok || icmp6.TypeCode.Type() != layers.ICMPv6TypeEchoReply {
+		return
+	}
+	echo, ok := pkt.Layer(layers.LayerTypeICMPv6Echo).(*layers.ICMPv6Echo)
+	if !ok || echo.Identifier != mod.echoID {
+		return
+	}
+	if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		mod.onAddress(eth.SrcMAC, ip6.SrcIP, false, "echo")
+	}
+}
+
+func (mod *NDPRecon) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		mod.waitGroup.Add(1)
+		go mod.prober()
+
+		src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+		mod.pktSourceChan = src.Packets()
+		for packet := range mod.pktSourceChan {
+			if !mod.Running() {
+				break
+			} else if packet != nil {
+				mod.onPacket(packet)
+			}
+		}
+	})
+}
+
+func (mod *NDPRecon) Show() error {
+	targets := []*network.Endpoint{}
+	for _, e := range append([]*network.Endpoint{mod.Session.Gateway}, mod.Session.Lan.List()...) {
+		if len(e.Ip6Addresses) > 0 {
+			targets = append(targets, e)
+		}
+	}
+
+	if len(targets) == 0 {
+		mod.Info("no IPv6 hosts discovered yet")
+		return nil
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].HwAddress < targets[j].HwAddress
+	})
+
+	rows := [][]string{}
+	for _, e := range targets {
+		ipv4 := ""
+		if e.IP.To4() != nil {
+			ipv4 = e.IpAddress
+		}
+
+		linkLocal, global := []string{}, []string{}
+		for _, address := range e.Ip6Addresses {
+			if ip := net.ParseIP(address); ip != nil && ip.IsLinkLocalUnicast() {
+				linkLocal = append(linkLocal, address)
+			} else {
+				global = append(global, address)
+			}
+		}
+
+		name := e.Hostname
+		if e == mod.Session.Gateway {
+			name = "gateway"
+		} else if e.Alias != "" {
+			name = tui.Green(e.Alias)
+		}
+
+		router := ""
+		if v, ok := e.Meta.Get("ndp:router").(string); ok && v == "true" {
+			router = tui.Bold("yes")
+		}
+
+		rows = append(rows, []string{
+			e.HwAddress,
+			ipv4,
+			strings.Join(linkLocal, ", "),
+			strings.Join(global, ", "),
+			name,
+			router,
+			e.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"MAC", "IPv4", "Link-Local", "Global", "Name", "Router", "Seen"}, rows)
+	mod.Session.Refresh()
+	return nil
+}
+
+func (mod *NDPRecon) Stop() error {
+	return mod.SetRunning(false, func() {
+		close(mod.quit)
+		mod.pktSourceChan <- nil
+		mod.handle.Close()
+		mod.waitGroup.Wait()
+	})
+}
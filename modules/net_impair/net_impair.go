@@ -0,0 +1,395 @@
+// +build linux
+
+package net_impair
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+type impairedTarget struct {
+	Target   string
+	Schedule []scheduleStep
+	Current  int
+	Since    time.Time
+	ClassID  int
+	Address  net.IP
+}
+
+type NetImpair struct {
+	session.SessionModule
+	sync.Mutex
+
+	iface    string
+	profiles map[string]*Profile
+	targets  map[string]*impairedTarget
+}
+
+func NewNetImpair(s *session.Session) *NetImpair {
+	mod := &NetImpair{
+		SessionModule: session.NewSessionModule("net.impair", s),
+		profiles:      make(map[string]*Profile),
+		targets:       make(map[string]*impairedTarget),
+	}
+
+	for name, def := range builtinProfiles {
+		if p, err := parseProfile(name, def); err == nil {
+			mod.profiles[name] = p
+		}
+	}
+
+	mod.AddHandler(session.NewModuleHandler("net.impair on", "",
+		"Start impairing the traffic of the selected targets.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.impair off", "",
+		"Stop impairing traffic and remove the traffic control rules.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.impair.profile NAME DEFINITION", `net\.impair\.profile ([^\s]+) (.+)`,
+		"Define or redefine the NAME impairment profile, for instance: net.impair.profile slow delay=250ms jitter=50ms loss=2% reorder=5% rate=1mbit",
+		func(args []string) error {
+			return mod.defineProfile(args[0], args[1])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.impair.target TARGETS SCHEDULE", `net\.impair\.target ([^\s]+) ([^\s]+)`,
+		"Apply a profile to the comma separated list of IP or MAC addresses TARGETS, SCHEDULE is either a profile name or a comma separated list of profile:seconds steps that will be cycled, for instance 3g:60,down:10",
+		func(args []string) error {
+			return mod.setTargets(args[0], args[1])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.impair.clear TARGETS", `net\.impair\.clear ([^\s]+)`,
+		"Stop impairing the traffic of the comma separated list of IP or MAC addresses TARGETS.",
+		func(args []string) error {
+			return mod.clearTargets(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.impair.show", "",
+		"Show the impairment profiles and targets.",
+		func(args []string) error {
+			return mod.show()
+		}))
+
+	return mod
+}
+
+func (mod *NetImpair) Name() string {
+	return "net.impair"
+}
+
+func (mod *NetImpair) Description() string {
+	return "Apply latency, jitter, packet loss and reordering to the traffic of the targets being MITM'd using tc/netem."
+}
+
+func (mod *NetImpair) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NetImpair) defineProfile(name string, def string) error {
+	p, err := parseProfile(name, def)
+	if err != nil {
+		return err
+	}
+
+	mod.Lock()
+	mod.profiles[name] = p
+	mod.Unlock()
+
+	if mod.Running() {
+		return mod.apply()
+	}
+	return nil
+}
+
+func (mod *NetImpair) parseTargets(targets string) ([]string, error) {
+	ips, macs, err := network.ParseTargets(targets, mod.Session.Lan.Aliases())
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := []string{}
+	for _, ip := range ips {
+		parsed = append(parsed, ip.String())
+	}
+	for _, mac := range macs {
+		parsed = append(parsed, mac.String())
+	}
+
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no valid targets in '%s'", targets)
+	}
+	return parsed, nil
+}
+
+func (mod *NetImpair) setTargets(targets string, schedule string) error {
+	steps, err := parseSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	mod.Lock()
+	for _, step := range steps {
+		if _, found := mod.profiles[step.Profile]; !found {
+			mod.Unlock()
+			return fmt.Errorf("profile %s not found", step.Profile)
+		}
+	}
+	mod.Unlock()
+
+	parsed, err := mod.parseTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	mod.Lock()
+	for _, target := range parsed {
+		mod.targets[target] = &impairedTarget{
+			Target:   target,
+			Schedule: steps,
+			Current:  -1,
+			Since:    time.Now(),
+		}
+	}
+	mod.Unlock()
+
+	if mod.Running() {
+		return mod.apply()
+	}
+	return nil
+}
+
+func (mod *NetImpair) clearTargets(targets string) error {
+	parsed, err := mod.parseTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	mod.Lock()
+	for _, target := range parsed {
+		delete(mod.targets, target)
+	}
+	mod.Unlock()
+
+	if mod.Running() {
+		return mod.apply()
+	}
+	return nil
+}
+
+func (mod *NetImpair) resolve(target string) net.IP {
+	if ip := net.ParseIP(target); ip != nil {
+		return ip
+	} else if e, found := mod.Session.Lan.Get(target); found {
+		return e.IP
+	}
+	return nil
+}
+
+func (mod *NetImpair) tc(args ...string) error {
+	if out, err := core.Exec("tc", args); err != nil {
+		return fmt.Errorf("tc %s: %s (%v)", strings.Join(args, " "), out, err)
+	}
+	return nil
+}
+
+func (mod *NetImpair) teardown() {
+	// this will fail if there's nothing to remove, that's fine
+	mod.tc("qdisc", "del", "dev", mod.iface, "root")
+}
+
+func (mod *NetImpair) setProfile(t *impairedTarget, step int, change bool) error {
+	p := mod.profiles[t.Schedule[step].Profile]
+	action := "add"
+	if change {
+		action = "change"
+	}
+
+	args := []string{"qdisc", action, "dev", mod.iface,
+		"parent", fmt.Sprintf("1:%x", t.ClassID),
+		"handle", fmt.Sprintf("%x:", t.ClassID)}
+
+	if err := mod.tc(append(args, p.NetemArgs()...)...); err != nil {
+		return err
+	}
+
+	t.Current = step
+	return nil
+}
+
+// rebuild the whole traffic control tree: unmatched traffic goes to the default
+// class 1:1, each target gets its own class with a netem qdisc attached
+func (mod *NetImpair) apply() error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	mod.teardown()
+
+	if err := mod.tc("qdisc", "add", "dev", mod.iface, "root", "handle", "1:", "htb", "default", "1"); err != nil {
+		return err
+	} else if err := mod.tc("class", "add", "dev", mod.iface, "parent", "1:", "classid", "1:1", "htb", "rate", "10gbit"); err != nil {
+		return err
+	}
+
+	classID := 0x10
+	for _, t := range mod.targets {
+		if t.Address = mod.resolve(t.Target); t.Address == nil {
+			mod.Warning("could not resolve the ip address of %s, skipping", t.Target)
+			t.Current = -1
+			continue
+		}
+
+		t.ClassID = classID
+		classID++
+
+		class := fmt.Sprintf("1:%x", t.ClassID)
+		if err := mod.tc("class", "add", "dev", mod.iface, "parent", "1:", "classid", class, "htb", "rate", "10gbit"); err != nil {
+			return err
+		} else if err := mod.setProfile(t, currentStep(t.Schedule, time.Since(t.Since)), false); err != nil {
+			return err
+		}
+
+		// forwarded traffic both to and from the target leaves from our interface
+		proto, match, bits := "ip", "ip", "32"
+		if t.Address.To4() == nil {
+			proto, match, bits = "ipv6", "ip6", "128"
+		}
+
+		for _, direction := range []string{"src", "dst"} {
+			if err := mod.tc("filter", "add", "dev", mod.iface, "protocol", proto, "parent", "1:0", "prio", "1",
+				"u32", "match", match, direction, t.Address.String()+"/"+bits, "flowid", class); err != nil {
+				return err
+			}
+		}
+
+		mod.Info("impairing traffic of %s (%s) with profile %s",
+			tui.Bold(t.Target),
+			t.Address,
+			tui.Yellow(t.Schedule[t.Current].Profile))
+	}
+
+	return nil
+}
+
+// move every scheduled target to the profile it should have now
+func (mod *NetImpair) tick() {
+	mod.Lock()
+	defer mod.Unlock()
+
+	for _, t := range mod.targets {
+		if t.Current == -1 {
+			continue
+		} else if step := currentStep(t.Schedule, time.Since(t.Since)); step != t.Current {
+			if err := mod.setProfile(t, step, true); err != nil {
+				mod.Error("%v", err)
+			} else {
+				mod.Info("%s switched to profile %s", tui.Bold(t.Target), tui.Yellow(t.Schedule[step].Profile))
+			}
+		}
+	}
+}
+
+func (mod *NetImpair) show() error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	names := []string{}
+	for name := range mod.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := [][]string{}
+	for _, name := range names {
+		rows = append(rows, []string{tui.Bold(name), mod.profiles[name].String()})
+	}
+	tui.Table(mod.Session.Events.Stdout, []string{"Profile", "Impairment"}, rows)
+
+	if len(mod.targets) > 0 {
+		rows = [][]string{}
+		for _, t := range mod.targets {
+			steps := []string{}
+			for _, step := range t.Schedule {
+				if step.Duration > 0 {
+					steps = append(steps, fmt.Sprintf("%s:%s", step.Profile, step.Duration))
+				} else {
+					steps = append(steps, step.Profile)
+				}
+			}
+
+			current := tui.Dim("inactive")
+			if mod.Running() && t.Current != -1 {
+				current = tui.Yellow(t.Schedule[t.Current].Profile)
+			}
+
+			rows = append(rows, []string{tui.Bold(t.Target), strings.Join(steps, ", "), current})
+		}
+
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i][0] < rows[j][0]
+		})
+
+		tui.Table(mod.Session.Events.Stdout, []string{"Target", "Schedule", "Current"}, rows)
+	}
+
+	mod.Session.Refresh()
+
+	return nil
+}
+
+func (mod *NetImpair) Configure() error {
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	} else if !core.HasBinary("tc") && !core.PrivilegesDropped() {
+		return fmt.Errorf("tc binary not found, please install the iproute2 package")
+	}
+
+	mod.iface = mod.Session.Interface.Name()
+
+	if !mod.Session.Firewall.IsForwardingEnabled() {
+		mod.Warning("forwarding is disabled, traffic of the targets won't go through this interface until they are MITM'd")
+	}
+
+	return nil
+}
+
+func (mod *NetImpair) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	} else if err := mod.apply(); err != nil {
+		mod.teardown()
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		for mod.Running() {
+			mod.tick()
+			time.Sleep(1 * time.Second)
+		}
+	})
+}
+
+func (mod *NetImpair) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.Lock()
+		defer mod.Unlock()
+
+		mod.teardown()
+		for _, t := range mod.targets {
+			t.Current = -1
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package net_impair
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Profile struct {
+	Name    string
+	Delay   time.Duration
+	Jitter  time.Duration
+	Loss    float64
+	Reorder float64
+	Rate    string
+}
+
+var builtinProfiles = map[string]string{
+	"3g":        "delay=100ms jitter=30ms loss=1% rate=2mbit",
+	"edge":      "delay=300ms jitter=100ms loss=2% rate=200kbit",
+	"satellite": "delay=600ms jitter=50ms loss=0.5%",
+	"congested": "delay=200ms jitter=150ms loss=3% reorder=10%",
+	"lossy":     "loss=10%",
+	"down":      "loss=100%",
+}
+
+func parsePercent(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || v < 0 || v > 100 {
+		return 0, fmt.Errorf("invalid percentage '%s'", s)
+	}
+	return v, nil
+}
+
+// parse a profile definition like "delay=100ms jitter=20ms loss=1% reorder=5% rate=1mbit"
+func parseProfile(name string, def string) (*Profile, error) {
+	var err error
+
+	p := &Profile{Name: name}
+	for _, field := range strings.Fields(def) {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid profile field '%s', expected key=value", field)
+		}
+
+		key, value := strings.ToLower(parts[0]), parts[1]
+		switch key {
+		case "delay":
+			if p.Delay, err = time.ParseDuration(value); err != nil {
+				return nil, err
+			}
+		case "jitter":
+			if p.Jitter, err = time.ParseDuration(value); err != nil {
+				return nil, err
+			}
+		case "loss":
+			if p.Loss, err = parsePercent(value); err != nil {
+				return nil, err
+			}
+		case "reorder":
+			if p.Reorder, err = parsePercent(value); err != nil {
+				return nil, err
+			}
+		case "rate":
+			p.Rate = value
+		default:
+			return nil, fmt.Errorf("unknown profile field '%s'", key)
+		}
+	}
+
+	if p.Reorder > 0 && p.Delay == 0 {
+		return nil, fmt.Errorf("reordering requires a delay")
+	} else if p.Jitter > 0 && p.Delay == 0 {
+		return nil, fmt.Errorf("jitter requires a delay")
+	}
+
+	return p, nil
+}
+
+func msec(d time.Duration) string {
+	return fmt.Sprintf("%dms", d/time.Millisecond)
+}
+
+// arguments for the netem qdisc
+func (p *Profile) NetemArgs() []string {
+	args := []string{"netem"}
+	if p.Delay > 0 {
+		args = append(args, "delay", msec(p.Delay))
+		if p.Jitter > 0 {
+			args = append(args, msec(p.Jitter))
+		}
+	}
+	if p.Loss > 0 {
+		args = append(args, "loss", fmt.Sprintf("%g%%", p.Loss))
+	}
+	if p.Reorder > 0 {
+		args = append(args, "reorder", fmt.Sprintf("%g%%", p.Reorder))
+	}
+	if p.Rate != "" {
+		args = append(args, "rate", p.Rate)
+	}
+	return args
+}
+
+func (p *Profile) String() string {
+	parts := []string{}
+	if p.Delay > 0 {
+		parts = append(parts, fmt.Sprintf("delay=%s", p.Delay))
+	}
+	if p.Jitter > 0 {
+		parts = append(parts, fmt.Sprintf("jitter=%s", p.Jitter))
+	}
+	if p.Loss > 0 {
+		parts = append(parts, fmt.Sprintf("loss=%g%%", p.Loss))
+	}
+	if p.Reorder > 0 {
+		parts = append(parts, fmt.Sprintf("reorder=%g%%", p.Reorder))
+	}
+	if p.Rate != "" {
+		parts = append(parts, fmt.Sprintf("rate=%s", p.Rate))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, " ")
+}
+
+// a step of a target schedule, a zero duration means forever
+type scheduleStep struct {
+	Profile  string
+	Duration time.Duration
+}
+
+// parse a schedule like "3g:60,lossy:30" (profile:seconds) or a single profile name
+func parseSchedule(s string) ([]scheduleStep, error) {
+	steps := []scheduleStep{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		step := scheduleStep{Profile: part}
+		if idx := strings.LastIndex(part, ":"); idx != -1 {
+			secs, err := strconv.Atoi(part[idx+1:])
+			if err != nil || secs <= 0 {
+				return nil, fmt.Errorf("invalid duration in schedule step '%s'", part)
+			}
+			step.Profile = part[:idx]
+			step.Duration = time.Duration(secs) * time.Second
+		}
+		steps = append(steps, step)
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty schedule")
+	} else if len(steps) > 1 {
+		for _, step := range steps {
+			if step.Duration == 0 {
+				return nil, fmt.Errorf("every step of a schedule with multiple profiles needs a duration")
+			}
+		}
+	}
+
+	return steps, nil
+}
+
+// return the index of the step active after elapsed time
+func currentStep(steps []scheduleStep, elapsed time.Duration) int {
+	if len(steps) == 1 {
+		return 0
+	}
+
+	total := time.Duration(0)
+	for _, step := range steps {
+		total += step.Duration
+	}
+
+	elapsed %= total
+	for i, step := range steps {
+		if elapsed < step.Duration {
+			return i
+		}
+		elapsed -= step.Duration
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+// +build !linux
+
+package net_impair
+
+import (
+	"github.com/bettercap/bettercap/session"
+)
+
+type NetImpair struct {
+	session.SessionModule
+}
+
+func NewNetImpair(s *session.Session) *NetImpair {
+	mod := &NetImpair{
+		SessionModule: session.NewSessionModule("net.impair", s),
+	}
+	mod.NotSupported()
+	return mod
+}
+
+func (mod *NetImpair) Name() string {
+	return "net.impair"
+}
+
+func (mod *NetImpair) Description() string {
+	return "Not supported on this OS"
+}
+
+func (mod *NetImpair) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NetImpair) Configure() (err error) {
+	return session.ErrNotSupported
+}
+
+func (mod *NetImpair) Start() error {
+	return session.ErrNotSupported
+}
+
+func (mod *NetImpair) Stop() error {
+	return session.ErrNotSupported
+}
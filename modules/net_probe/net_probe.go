@@ -15,6 +15,9 @@ type Probes struct {
 	MDNS bool
 	UPNP bool
 	WSD  bool
+	SMB  bool
+	// anonymous SMB sessions to enumerate the shares
+	SMBShares bool
 }
 
 type Prober struct {
@@ -48,6 +51,14 @@ func NewProber(s *session.Session) *Prober {
 		"true",
 		"Enable WSD discovery probes."))
 
+	mod.AddParam(session.NewBoolParameter("net.probe.smb",
+		"false",
+		"Enable SMB negotiation probes of the known hosts to get their SMB dialect and signing requirements."))
+
+	mod.AddParam(session.NewBoolParameter("net.probe.smb.shares",
+		"false",
+		"If true, the SMB probes also attempt an anonymous session with the hosts to get their names and domain from the NTLM challenge and enumerate their shares."))
+
 	mod.AddParam(session.NewIntParameter("net.probe.throttle",
 		"10",
 		"If greater than 0, probe packets will be throttled by this value in milliseconds."))
@@ -64,6 +75,12 @@ func NewProber(s *session.Session) *Prober {
 			return mod.Stop()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("net.probe.smb.targets", "",
+		"Show the hosts not requiring SMB signing and save their addresses to the net.probe.smb.targets variable.",
+		func(args []string) error {
+			return mod.showRelayTargets()
+		}))
+
 	return mod
 }
 
@@ -91,6 +108,10 @@ func (mod *Prober) Configure() error {
 		return err
 	} else if err, mod.probes.WSD = mod.BoolParam("net.probe.wsd"); err != nil {
 		return err
+	} else if err, mod.probes.SMB = mod.BoolParam("net.probe.smb"); err != nil {
+		return err
+	} else if err, mod.probes.SMBShares = mod.BoolParam("net.probe.smb.shares"); err != nil {
+		return err
 	} else {
 		mod.Debug("Throttling packets of %d ms.", mod.throttle)
 	}
@@ -121,6 +142,10 @@ func (mod *Prober) Start() error {
 			go mod.mdnsProber()
 		}
 
+		if mod.probes.SMB {
+			go mod.smbProber()
+		}
+
 		fromIP := mod.Session.Interface.IP
 		fromHW := mod.Session.Interface.HW
 		addresses := list.Expand()
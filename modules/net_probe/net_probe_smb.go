@@ -0,0 +1,137 @@
+package net_probe
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	smbTimeout = 2 * time.Second
+	// hosts are negotiated with again after this time
+	smbProbeTTL = 10 * time.Minute
+)
+
+func (mod *Prober) negotiateSMB(address string) (*packets.SMBInfo, error) {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", address, packets.SMBPort), smbTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(smbTimeout))
+
+	req := packets.NewSMB2NegotiateRequest()
+	if wrote, err := conn.Write(req); err != nil {
+		mod.Session.Queue.TrackError()
+		return nil, err
+	} else {
+		mod.Session.Queue.TrackSent(uint64(wrote))
+	}
+
+	buf := make([]byte, 4096)
+	n, err := io.ReadAtLeast(conn, buf, 4+64+8)
+	if err != nil {
+		return nil, err
+	}
+
+	return packets.ParseSMB2NegotiateResponse(buf[:n])
+}
+
+func (mod *Prober) smbProber() {
+	mod.Debug("smb prober started")
+	defer mod.Debug("smb prober stopped")
+
+	mod.waitGroup.Add(1)
+	defer mod.waitGroup.Done()
+
+	probed := make(map[string]time.Time)
+	throttle := time.Duration(mod.throttle) * time.Millisecond
+
+	for mod.Running() {
+		for _, host := range mod.Session.Lan.List() {
+			if !mod.Running() {
+				return
+			} else if last, found := probed[host.IpAddress]; found && time.Since(last) < smbProbeTTL {
+				continue
+			}
+
+			probed[host.IpAddress] = time.Now()
+			if info, err := mod.negotiateSMB(host.IpAddress); err != nil {
+				mod.Debug("smb negotiation with %s failed: %v", host.IpAddress, err)
+			} else {
+				mod.Debug("%s speaks smb %s with signing %s", host.IpAddress, info.Dialect, info.Signing())
+				host.OnMeta(map[string]string{
+					"smb:dialect": info.Dialect,
+					"smb:signing": info.Signing(),
+				})
+
+				if mod.probes.SMBShares {
+					enum, err := mod.enumerateSMB(host.IpAddress)
+					mod.onSMBEnumeration(host, enum, err)
+				}
+			}
+
+			time.Sleep(throttle)
+		}
+
+		for slept := time.Duration(0); slept < 5*time.Second && mod.Running(); slept += 100 * time.Millisecond {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
+
+// hosts not requiring smb signing, hence good targets for relay attacks
+func (mod *Prober) relayTargets() []*network.Endpoint {
+	targets := make([]*network.Endpoint, 0)
+	for _, host := range mod.Session.Lan.List() {
+		if signing, ok := host.Meta.Get("smb:signing").(string); ok && signing != "" && signing != "required" {
+			targets = append(targets, host)
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].IpAddressUint32 < targets[j].IpAddressUint32
+	})
+
+	return targets
+}
+
+func (mod *Prober) showRelayTargets() error {
+	targets := mod.relayTargets()
+
+	rows := [][]string{}
+	addresses := []string{}
+	for _, t := range targets {
+		addresses = append(addresses, t.IpAddress)
+		rows = append(rows, []string{
+			tui.Bold(t.IpAddress),
+			t.HwAddress,
+			t.Hostname,
+			fmt.Sprintf("%s", t.Meta.Get("nbns:workgroup")),
+			fmt.Sprintf("%s", t.Meta.Get("smb:dialect")),
+			tui.Yellow(fmt.Sprintf("%s", t.Meta.Get("smb:signing"))),
+		})
+	}
+
+	mod.Session.Env.Set("net.probe.smb.targets", strings.Join(addresses, ","))
+
+	if len(rows) == 0 {
+		mod.Info("no hosts without smb signing found yet (net.probe.smb must be enabled)")
+		return nil
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"IP", "MAC", "Name", "Workgroup", "Dialect", "Signing"}, rows)
+	mod.Info("%d targets saved to {env.net.probe.smb.targets}", len(rows))
+	mod.Session.Refresh()
+
+	return nil
+}
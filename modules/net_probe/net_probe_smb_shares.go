@@ -0,0 +1,266 @@
+package net_probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+)
+
+const (
+	// upper bound of the responses read, shares lists included
+	smbMaxMessage = 1024 * 1024
+	// bytes read from the pipe at a time, a single DCE/RPC fragment fits
+	smbPipeReadSize = 65536
+	// fragments read for a shares list
+	smbMaxFragments = 64
+)
+
+// an SMB2 session with a host over a direct TCP connection
+type smbClient struct {
+	conn      net.Conn
+	messageID uint64
+	sessionID uint64
+	treeID    uint32
+}
+
+// what an anonymous session tells about a host
+type smbEnumeration struct {
+	Challenge *packets.NTLMChallengeInfo
+	// anonymous, guest or denied
+	Session string
+	Shares  []packets.SMBShare
+}
+
+// exchange sends the request created for the next message id and returns
+// its final response.
+func (c *smbClient) exchange(request func(messageID uint64) []byte) (*packets.SMBMessage, error) {
+	c.conn.SetDeadline(time.Now().Add(smbTimeout))
+
+	if _, err := c.conn.Write(request(c.messageID)); err != nil {
+		return nil, err
+	}
+	c.messageID++
+
+	for {
+		frame := make([]byte, 4)
+		if _, err := io.ReadFull(c.conn, frame); err != nil {
+			return nil, err
+		}
+
+		size := binary.BigEndian.Uint32(frame) & 0x00ffffff
+		if size > smbMaxMessage {
+			return nil, fmt.Errorf("response too big (%d bytes)", size)
+		}
+
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(c.conn, msg); err != nil {
+			return nil, err
+		}
+
+		msgs := packets.ParseSMBMessages(append(frame, msg...))
+		if len(msgs) == 0 || msgs[0].Version != 2 {
+			return nil, fmt.Errorf("not an SMB2 response")
+		} else if msgs[0].Status != packets.SMB2StatusPending {
+			return &msgs[0], nil
+		}
+		// the final response of an asynchronous operation follows
+	}
+}
+
+func (c *smbClient) pipeWrite(fileID string, pdu []byte) error {
+	resp, err := c.exchange(func(id uint64) []byte {
+		return packets.NewSMB2WriteRequest(id, c.sessionID, c.treeID, fileID, pdu)
+	})
+	if err != nil {
+		return err
+	} else if resp.Status != packets.SMB2StatusSuccess {
+		return fmt.Errorf("pipe write failed with status 0x%08x", resp.Status)
+	}
+	return nil
+}
+
+func (c *smbClient) pipeRead(fileID string) ([]byte, error) {
+	resp, err := c.exchange(func(id uint64) []byte {
+		return packets.NewSMB2ReadRequest(id, c.sessionID, c.treeID, fileID, smbPipeReadSize)
+	})
+	if err != nil {
+		return nil, err
+	} else if data := resp.ReadData(); data != nil {
+		return data, nil
+	}
+	return nil, fmt.Errorf("pipe read failed with status 0x%08x", resp.Status)
+}
+
+// call sends a DCE/RPC request to an open pipe and reads the fragments of
+// the response, returning its stub data.
+func (c *smbClient) call(fileID string, pdu []byte) ([]byte, error) {
+	if err := c.pipeWrite(fileID, pdu); err != nil {
+		return nil, err
+	}
+
+	stub := []byte{}
+	for i := 0; i < smbMaxFragments; i++ {
+		data, err := c.pipeRead(fileID)
+		if err != nil {
+			return nil, err
+		}
+
+		fragment, last, err := packets.ParseDCERPCResponse(data)
+		if err != nil {
+			return nil, err
+		}
+		stub = append(stub, fragment...)
+		if last {
+			return stub, nil
+		}
+	}
+	return nil, fmt.Errorf("too many fragments")
+}
+
+// shares lists the shares of the host through the server service pipe.
+func (c *smbClient) shares(address string) ([]packets.SMBShare, error) {
+	resp, err := c.exchange(func(id uint64) []byte {
+		return packets.NewSMB2TreeConnectRequest(id, c.sessionID, fmt.Sprintf(`\\%s\IPC$`, address))
+	})
+	if err != nil {
+		return nil, err
+	} else if resp.Status != packets.SMB2StatusSuccess {
+		return nil, fmt.Errorf("IPC$ connection failed with status 0x%08x", resp.Status)
+	}
+	c.treeID = resp.TreeID
+
+	if resp, err = c.exchange(func(id uint64) []byte {
+		return packets.NewSMB2CreatePipeRequest(id, c.sessionID, c.treeID, packets.SrvsvcPipe)
+	}); err != nil {
+		return nil, err
+	}
+
+	fileID := resp.CreateFileID()
+	if fileID == "" {
+		return nil, fmt.Errorf("opening %s failed with status 0x%08x", packets.SrvsvcPipe, resp.Status)
+	}
+
+	if err = c.pipeWrite(fileID, packets.NewSrvsvcBind()); err != nil {
+		return nil, err
+	} else if ack, err := c.pipeRead(fileID); err != nil {
+		return nil, err
+	} else if err = packets.ParseDCERPCBindAck(ack); err != nil {
+		return nil, err
+	}
+
+	stub, err := c.call(fileID, packets.NewSrvsvcNetShareEnumAll(address))
+	if err != nil {
+		return nil, err
+	}
+	return packets.ParseSrvsvcNetShareEnumAll(stub)
+}
+
+// enumerateSMB authenticates anonymously with a host, getting its names from
+// the NTLM challenge, and lists its shares if the session is accepted. The
+// enumeration is returned along with the error that stopped it, if any.
+func (mod *Prober) enumerateSMB(address string) (*smbEnumeration, error) {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", address, packets.SMBPort), smbTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := &smbClient{conn: conn}
+	enum := &smbEnumeration{}
+
+	resp, err := c.exchange(func(id uint64) []byte {
+		return packets.NewSMB2NegotiateRequest()
+	})
+	if err != nil {
+		return nil, err
+	} else if resp.Status != packets.SMB2StatusSuccess {
+		return nil, fmt.Errorf("negotiate failed with status 0x%08x", resp.Status)
+	}
+
+	if resp, err = c.exchange(func(id uint64) []byte {
+		return packets.NewSMB2SessionSetupRequest(id, 0, packets.NewNTLMNegotiate())
+	}); err != nil {
+		return nil, err
+	} else if resp.Status != packets.SMB2StatusMoreProcessingNeeded {
+		return nil, fmt.Errorf("session setup failed with status 0x%08x", resp.Status)
+	}
+
+	challenge, ntype := resp.NTLMSSP()
+	if ntype != packets.NTLM_CHALLENGE {
+		return nil, fmt.Errorf("no NTLM challenge in the session setup response")
+	} else if enum.Challenge, err = packets.ParseNTLMChallengeInfo(challenge); err != nil {
+		return nil, err
+	}
+	c.sessionID = resp.SessionID
+
+	if resp, err = c.exchange(func(id uint64) []byte {
+		return packets.NewSMB2SessionSetupRequest(id, c.sessionID, packets.NewNTLMAnonymousAuthenticate(enum.Challenge))
+	}); err != nil {
+		return enum, err
+	}
+
+	flags, ok := resp.SessionFlags()
+	if !ok {
+		enum.Session = "denied"
+		return enum, nil
+	} else if flags&packets.SMB2SessionGuest != 0 {
+		enum.Session = "guest"
+	} else {
+		enum.Session = "anonymous"
+	}
+
+	enum.Shares, err = c.shares(address)
+	return enum, err
+}
+
+func (enum *smbEnumeration) meta() map[string]string {
+	meta := map[string]string{}
+	if info := enum.Challenge; info != nil {
+		for name, value := range map[string]string{
+			"smb:hostname": info.NetBIOSComputer,
+			"smb:domain":   info.NetBIOSDomain,
+			"smb:fqdn":     info.DNSComputer,
+			"smb:forest":   info.DNSTree,
+			"smb:version":  info.OSVersion,
+		} {
+			if value != "" {
+				meta[name] = value
+			}
+		}
+	}
+
+	if enum.Session != "" {
+		meta["smb:session"] = enum.Session
+	}
+
+	if len(enum.Shares) > 0 {
+		names := make([]string, 0, len(enum.Shares))
+		for _, share := range enum.Shares {
+			names = append(names, share.Name)
+		}
+		meta["smb:shares"] = strings.Join(names, ",")
+	}
+
+	return meta
+}
+
+func (mod *Prober) onSMBEnumeration(host *network.Endpoint, enum *smbEnumeration, err error) {
+	if err != nil {
+		mod.Debug("smb enumeration of %s failed: %v", host.IpAddress, err)
+	}
+	if enum == nil {
+		return
+	}
+
+	meta := enum.meta()
+	if shares, found := meta["smb:shares"]; found {
+		mod.Info("%s accepts %s sessions, shares: %s", host.IpAddress, enum.Session, shares)
+	}
+	host.OnMeta(meta)
+}
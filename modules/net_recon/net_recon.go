@@ -59,6 +59,31 @@ func NewDiscovery(s *session.Session) *Discovery {
 			return mod.showMeta(args[0])
 		}))
 
+	mod.AddParam(session.NewStringParameter("net.snapshot.path",
+		"~/bettercap-snapshots",
+		"",
+		"Folder where the network snapshots are saved."))
+
+	mod.SavesTo("net.snapshot.path")
+
+	mod.AddHandler(session.NewModuleHandler("net.snapshot save NAME", `net\.snapshot save ([\w\-\.]+)`,
+		"Save the current hosts, their open ports and metadata as the snapshot NAME.",
+		func(args []string) error {
+			return mod.saveSnapshot(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.snapshot diff FROM TO", `net\.snapshot diff ([\w\-\.]+)\s+([\w\-\.]+)`,
+		"Show the hosts that appeared, disappeared or changed between two snapshots, use current as TO to compare with the current state.",
+		func(args []string) error {
+			return mod.diffSnapshots(args[0], args[1])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.snapshots", "",
+		"Show the saved network snapshots.",
+		func(args []string) error {
+			return mod.showSnapshots()
+		}))
+
 	mod.selector = utils.ViewSelectorFor(&mod.SessionModule, "net.show", []string{"ip", "mac", "seen", "sent", "rcvd"},
 		"ip asc")
 
@@ -82,7 +107,10 @@ func (mod *Discovery) runDiff(cache network.ArpTable) {
 	var rem network.ArpTable = make(network.ArpTable)
 
 	mod.Session.Lan.EachHost(func(mac string, e *network.Endpoint) {
-		if _, found := cache[mac]; !found {
+		// IPv6 only hosts are not in the ARP cache, ndp.recon keeps track of them
+		if e.IP.To4() == nil {
+			return
+		} else if _, found := cache[mac]; !found {
 			rem[mac] = e.IpAddress
 		}
 	})
@@ -13,7 +13,6 @@ import (
 	"github.com/dustin/go-humanize"
 
 	"github.com/evilsocket/islazy/tui"
-	"github.com/evilsocket/islazy/str"
 )
 
 const (
@@ -59,6 +58,7 @@ func (mod *Discovery) getRow(e *network.Endpoint, withMeta bool) [][]string {
 	} else if e.Hostname != "" {
 		name = tui.Yellow(e.Hostname)
 	}
+	name = network.WithTags(name, e.Tags)
 
 	var traffic *packets.Traffic
 	var found bool
@@ -86,6 +86,7 @@ func (mod *Discovery) getRow(e *network.Endpoint, withMeta bool) [][]string {
 		mac,
 		name,
 		tui.Dim(e.Vendor),
+		e.OS(),
 		humanize.Bytes(traffic.Sent),
 		humanize.Bytes(traffic.Received),
 		seen,
@@ -102,6 +103,8 @@ func (mod *Discovery) getRow(e *network.Endpoint, withMeta bool) [][]string {
 		s := ""
 		if sv, ok := value.(string); ok {
 			s = sv
+		} else if ports, ok := value.(map[int]*syn_scan.OpenPort); ok {
+			s = syn_scan.FormatPorts(ports)
 		} else {
 			s = fmt.Sprintf("%+v", value)
 		}
@@ -115,7 +118,7 @@ func (mod *Discovery) getRow(e *network.Endpoint, withMeta bool) [][]string {
 		if i == 0 {
 			rows = append(rows, append(row, m))
 		} else {
-			rows = append(rows, []string{"", "", "", "", "", "", "", m})
+			rows = append(rows, []string{"", "", "", "", "", "", "", "", m})
 		}
 	}
 
@@ -131,7 +134,10 @@ func (mod *Discovery) doFilter(target *network.Endpoint) bool {
 		mod.selector.Expression.MatchString(target.HwAddress) ||
 		mod.selector.Expression.MatchString(target.Hostname) ||
 		mod.selector.Expression.MatchString(target.Alias) ||
-		mod.selector.Expression.MatchString(target.Vendor)
+		mod.selector.Expression.MatchString(target.Vendor) ||
+		mod.selector.Expression.MatchString(target.OS()) ||
+		mod.selector.Expression.MatchString(strings.Join(target.Tags, ",")) ||
+		mod.selector.Expression.MatchString(target.Note)
 }
 
 func (mod *Discovery) doSelection(arg string) (err error, targets []*network.Endpoint) {
@@ -192,7 +198,7 @@ func (mod *Discovery) doSelection(arg string) (err error, targets []*network.End
 }
 
 func (mod *Discovery) colNames(hasMeta bool) []string {
-	colNames := []string{"IP", "MAC", "Name", "Vendor", "Sent", "Recvd", "Seen"}
+	colNames := []string{"IP", "MAC", "Name", "Vendor", "OS", "Sent", "Recvd", "Seen"}
 	if hasMeta {
 		colNames = append(colNames, "Meta")
 	}
@@ -201,11 +207,11 @@ func (mod *Discovery) colNames(hasMeta bool) []string {
 	case "mac":
 		colNames[1] += " " + mod.selector.SortSymbol
 	case "sent":
-		colNames[4] += " " + mod.selector.SortSymbol
-	case "rcvd":
 		colNames[5] += " " + mod.selector.SortSymbol
-	case "seen":
+	case "rcvd":
 		colNames[6] += " " + mod.selector.SortSymbol
+	case "seen":
+		colNames[7] += " " + mod.selector.SortSymbol
 	case "ip":
 		colNames[0] += " " + mod.selector.SortSymbol
 	}
@@ -304,18 +310,7 @@ func (mod *Discovery) showMeta(arg string) (err error) {
 				if s, ok := meta.(string); ok {
 					val = s
 				} else if ports, ok := meta.(map[int]*syn_scan.OpenPort); ok {
-					val = "ports: "
-					for _, info := range ports {
-						val += fmt.Sprintf("%s:%d", info.Proto, info.Port)
-						if info.Service != "" {
-							val += fmt.Sprintf("(%s)", info.Service)
-						}
-						if info.Banner != "" {
-							val += fmt.Sprintf(" [%s]", info.Banner)
-						}
-						val += " "
-					}
-					val = str.Trim(val)
+					val = "ports: " + syn_scan.FormatPorts(ports)
 				} else {
 					val = fmt.Sprintf("%#v", meta)
 				}
@@ -0,0 +1,186 @@
+package net_recon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	snapshotSuffix = ".json"
+	// the name used to diff a snapshot against the current state of the network
+	snapshotCurrent = "current"
+)
+
+func init() {
+	session.RegisterEventSchema("net.snapshot.diff", 1, "The differences between two snapshots of the network hosts.", network.SnapshotDiff{})
+}
+
+func (mod *Discovery) snapshotFile(name string) (string, error) {
+	err, path := mod.StringParam("net.snapshot.path")
+	if err != nil {
+		return "", err
+	} else if path, err = fs.Expand(path); err != nil {
+		return "", err
+	}
+	return filepath.Join(path, name+snapshotSuffix), nil
+}
+
+func (mod *Discovery) saveSnapshot(name string) error {
+	if name == snapshotCurrent {
+		return fmt.Errorf("'%s' is reserved for the current state of the network", snapshotCurrent)
+	}
+
+	fileName, err := mod.snapshotFile(name)
+	if err != nil {
+		return err
+	} else if err = os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return err
+	}
+
+	snapshot := mod.Session.Lan.Snapshot(name)
+	raw, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	} else if err = ioutil.WriteFile(fileName, raw, 0644); err != nil {
+		return err
+	}
+
+	mod.Info("saved %d hosts to %s", len(snapshot.Hosts), fileName)
+	return nil
+}
+
+func (mod *Discovery) loadSnapshot(name string) (*network.Snapshot, error) {
+	if name == snapshotCurrent {
+		return mod.Session.Lan.Snapshot(name), nil
+	}
+
+	fileName, err := mod.snapshotFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read snapshot %s: %v", name, err)
+	}
+
+	snapshot := &network.Snapshot{}
+	if err = json.Unmarshal(raw, snapshot); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", fileName, err)
+	} else if snapshot.Hosts == nil {
+		snapshot.Hosts = make(map[string]*network.HostSnapshot)
+	}
+
+	return snapshot, nil
+}
+
+func (mod *Discovery) showSnapshots() error {
+	err, path := mod.StringParam("net.snapshot.path")
+	if err != nil {
+		return err
+	} else if path, err = fs.Expand(path); err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	rows := [][]string{}
+	for _, f := range files {
+		if name := f.Name(); !f.IsDir() && strings.HasSuffix(name, snapshotSuffix) {
+			name = strings.TrimSuffix(name, snapshotSuffix)
+			if snapshot, err := mod.loadSnapshot(name); err == nil {
+				rows = append(rows, []string{
+					tui.Bold(name),
+					snapshot.Time.Format("2006-01-02 15:04:05"),
+					fmt.Sprintf("%d", len(snapshot.Hosts)),
+				})
+			}
+		}
+	}
+
+	if len(rows) == 0 {
+		mod.Info("no snapshots saved in %s", path)
+		return nil
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Name", "Time", "Hosts"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
+
+func snapshotHost(h *network.HostSnapshot) string {
+	host := h.IPv4 + " " + tui.Dim(h.MAC)
+	if h.Hostname != "" {
+		host += " " + tui.Yellow(h.Hostname)
+	}
+	return host
+}
+
+func (mod *Discovery) diffSnapshots(from string, to string) error {
+	a, err := mod.loadSnapshot(from)
+	if err != nil {
+		return err
+	}
+
+	b, err := mod.loadSnapshot(to)
+	if err != nil {
+		return err
+	}
+
+	diff := network.DiffSnapshots(a, b)
+	mod.Session.Events.Add("net.snapshot.diff", diff)
+
+	if diff.Empty() {
+		mod.Info("no changes between %s and %s", from, to)
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, h := range diff.Added {
+		ports := []string{}
+		for _, port := range h.Ports {
+			ports = append(ports, strconv.Itoa(port))
+		}
+		rows = append(rows, []string{tui.Green("+"), snapshotHost(h), strings.Join(ports, ",")})
+	}
+	for _, h := range diff.Removed {
+		rows = append(rows, []string{tui.Red("-"), snapshotHost(h), ""})
+	}
+	for _, c := range diff.Changed {
+		host := c.IPv4 + " " + tui.Dim(c.MAC)
+		for _, change := range c.Changes {
+			rows = append(rows, []string{tui.Yellow("~"), host, change.String()})
+		}
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"", "Host", "Change"}, rows)
+	mod.Printf("%d new, %d gone and %d changed hosts from %s to %s\n\n",
+		len(diff.Added),
+		len(diff.Removed),
+		len(diff.Changed),
+		tui.Bold(from),
+		tui.Bold(to))
+	mod.Session.Refresh()
+
+	return nil
+}
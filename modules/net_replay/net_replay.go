@@ -0,0 +1,253 @@
+package net_replay
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/dustin/go-humanize"
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+// gaps between packets shorter than this are not waited for
+const minDelay = time.Millisecond
+
+type replayStats struct {
+	loops   int
+	sent    uint64
+	bytes   uint64
+	errors  uint64
+	started time.Time
+}
+
+type NetReplay struct {
+	session.SessionModule
+
+	file      string
+	speed     float64
+	loops     int
+	rewriter  *packets.Rewriter
+	stats     replayStats
+	quit      chan bool
+	waitGroup *sync.WaitGroup
+}
+
+func NewNetReplay(s *session.Session) *NetReplay {
+	mod := &NetReplay{
+		SessionModule: session.NewSessionModule("net.replay", s),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewStringParameter("net.replay.file",
+		"",
+		"",
+		"The pcap or pcapng file to replay, it must be an Ethernet capture."))
+
+	mod.AddParam(session.NewDecimalParameter("net.replay.speed",
+		"1.0",
+		"Speed factor of the replay, 1.0 keeps the original timing, 2.0 is twice as fast and 0 sends the packets as fast as possible."))
+
+	mod.AddParam(session.NewIntParameter("net.replay.loop",
+		"1",
+		"How many times the file is replayed, 0 to replay it until net.replay off."))
+
+	mod.AddParam(session.NewStringParameter("net.replay.rewrite",
+		"",
+		"",
+		"Comma separated list of OLD>NEW rules rewriting MAC and IP addresses, for instance: 192.168.1.10>10.0.0.5,00:11:22:33:44:55>66:77:88:99:aa:bb"))
+
+	mod.AddHandler(session.NewModuleHandler("net.replay on", "",
+		"Start replaying net.replay.file on the current interface.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.replay off", "",
+		"Stop replaying.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.replay.show", "",
+		"Show the replay progress.",
+		func(args []string) error {
+			return mod.show()
+		}))
+
+	return mod
+}
+
+func (mod *NetReplay) Name() string {
+	return "net.replay"
+}
+
+func (mod *NetReplay) Description() string {
+	return "Replay a capture file on the network with its original or scaled timing, optionally rewriting its addresses."
+}
+
+func (mod *NetReplay) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NetReplay) Configure() (err error) {
+	var rules string
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	} else if err, mod.file = mod.StringParam("net.replay.file"); err != nil {
+		return err
+	} else if mod.file == "" {
+		return fmt.Errorf("net.replay.file is empty")
+	} else if mod.file, err = fs.Expand(mod.file); err != nil {
+		return err
+	} else if !fs.Exists(mod.file) {
+		return fmt.Errorf("%s does not exist", mod.file)
+	} else if err, mod.speed = mod.DecParam("net.replay.speed"); err != nil {
+		return err
+	} else if mod.speed < 0 {
+		return fmt.Errorf("net.replay.speed can't be negative")
+	} else if err, mod.loops = mod.IntParam("net.replay.loop"); err != nil {
+		return err
+	} else if mod.loops < 0 {
+		return fmt.Errorf("net.replay.loop can't be negative")
+	} else if err, rules = mod.StringParam("net.replay.rewrite"); err != nil {
+		return err
+	} else if mod.rewriter, err = packets.ParseRewriteRules(rules); err != nil {
+		return err
+	}
+
+	mod.stats = replayStats{started: time.Now()}
+	mod.quit = make(chan bool)
+	return nil
+}
+
+// waits for the delay unless stopped, returns false if stopped
+func (mod *NetReplay) wait(delay time.Duration) bool {
+	if delay < minDelay {
+		return mod.Running()
+	}
+
+	select {
+	case <-mod.quit:
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+func (mod *NetReplay) replay() error {
+	handle, err := pcap.OpenOffline(mod.file)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	if handle.LinkType() != layers.LinkTypeEthernet {
+		return fmt.Errorf("%s is a %s capture, only Ethernet ones can be replayed", mod.file, handle.LinkType())
+	}
+
+	var prev time.Time
+	for {
+		data, ci, err := handle.ReadPacketData()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if mod.speed > 0 && !prev.IsZero() && ci.Timestamp.After(prev) {
+			if !mod.wait(time.Duration(float64(ci.Timestamp.Sub(prev)) / mod.speed)) {
+				return nil
+			}
+		} else if !mod.Running() {
+			return nil
+		}
+		prev = ci.Timestamp
+
+		if rewritten, _, err := mod.rewriter.Rewrite(data, handle.LinkType()); err != nil {
+			mod.Debug("could not rewrite packet: %v", err)
+		} else {
+			data = rewritten
+		}
+
+		if err := mod.Session.Queue.Send(data); err != nil {
+			mod.stats.errors++
+			mod.Debug("error sending packet: %v", err)
+		} else {
+			mod.stats.sent++
+			mod.stats.bytes += uint64(len(data))
+		}
+	}
+}
+
+func (mod *NetReplay) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		speed := fmt.Sprintf("%.2fx speed", mod.speed)
+		if mod.speed == 0 {
+			speed = "full speed"
+		}
+		mod.Info("replaying %s at %s ...", tui.Bold(mod.file), speed)
+
+		for mod.Running() && (mod.loops == 0 || mod.stats.loops < mod.loops) {
+			if err := mod.replay(); err != nil {
+				mod.Error("%v", err)
+				break
+			}
+			mod.stats.loops++
+		}
+
+		mod.Info("%d packets (%s) sent in %s", mod.stats.sent, humanize.Bytes(mod.stats.bytes), time.Since(mod.stats.started))
+
+		// done by itself
+		if mod.Running() {
+			go mod.Stop()
+		}
+	})
+}
+
+func (mod *NetReplay) show() error {
+	if mod.stats.started.IsZero() {
+		mod.Info("nothing replayed yet")
+		return nil
+	}
+
+	loops := fmt.Sprintf("%d", mod.stats.loops)
+	if mod.loops > 0 {
+		loops += fmt.Sprintf("/%d", mod.loops)
+	}
+
+	rows := [][]string{
+		{"File", mod.file},
+		{"Running", fmt.Sprintf("%v", mod.Running())},
+		{"Loops", loops},
+		{"Packets", fmt.Sprintf("%d", mod.stats.sent)},
+		{"Bytes", humanize.Bytes(mod.stats.bytes)},
+		{"Errors", fmt.Sprintf("%d", mod.stats.errors)},
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Name", "Value"}, rows)
+	mod.Session.Refresh()
+	return nil
+}
+
+func (mod *NetReplay) Stop() error {
+	return mod.SetRunning(false, func() {
+		close(mod.quit)
+		mod.waitGroup.Wait()
+	})
+}
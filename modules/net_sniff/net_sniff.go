@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/google/gopacket"
@@ -14,7 +15,14 @@ type Sniffer struct {
 	session.SessionModule
 	Stats         *SnifferStats
 	Ctx           *SnifferContext
+	Profiles      *Profiles
+	Neighbors     *Neighbors
 	pktSourceChan chan gopacket.Packet
+	decryptedSub  uint64
+	taps          *tapSet
+	script        *SnifferScript
+	sampled       uint64
+	statsQuit     chan bool
 
 	fuzzActive bool
 	fuzzSilent bool
@@ -27,6 +35,9 @@ func NewSniffer(s *session.Session) *Sniffer {
 	mod := &Sniffer{
 		SessionModule: session.NewSessionModule("net.sniff", s),
 		Stats:         nil,
+		Profiles:      NewProfiles(),
+		Neighbors:     NewNeighbors(),
+		taps:          &tapSet{},
 	}
 
 	mod.SessionModule.Requires("net.recon")
@@ -42,7 +53,11 @@ func NewSniffer(s *session.Session) *Sniffer {
 	mod.AddParam(session.NewStringParameter("net.sniff.filter",
 		"not arp",
 		"",
-		"BPF filter for the sniffer."))
+		"BPF filter for the sniffer or a display filter such as 'http.request && ip.dst == 10.0.0.5', supported fields are ip.addr, ip.src, ip.dst, ip.ttl, ip.proto, eth.addr, eth.src, eth.dst, vlan.id, tcp.port, tcp.srcport, tcp.dstport, udp.port, udp.srcport, udp.dstport, frame.len, http.host, http.method, http.user_agent and dns.qry.name."))
+
+	mod.AddParam(session.NewBoolParameter("net.sniff.offload",
+		"true",
+		"If true, the BPF filter installed in the kernel is automatically narrowed down to the traffic of the arp.spoof targets and, unless net.sniff.local is true, excludes the traffic of this computer."))
 
 	mod.AddParam(session.NewStringParameter("net.sniff.regexp",
 		"",
@@ -52,12 +67,93 @@ func NewSniffer(s *session.Session) *Sniffer {
 	mod.AddParam(session.NewStringParameter("net.sniff.output",
 		"",
 		"",
-		"If set, the sniffer will write captured packets to this file."))
+		"If set, the sniffer will write captured packets to this file, if its extension is .pcapng every packet is commented with the protocols the parsers found in it. The name can contain the {timestamp} and {iface} placeholders."))
+
+	mod.AddParam(session.NewDecimalParameter("net.sniff.output.rotate.size",
+		"0",
+		"If greater than 0, the output file is rotated once it reaches this size in MB."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.output.rotate.time",
+		"0",
+		"If greater than 0, the output file is rotated after this many seconds."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.output.rotate.files",
+		"0",
+		"Maximum number of output files kept when rotating, the oldest ones are deleted, 0 to keep all of them."))
+
+	mod.AddParam(session.NewBoolParameter("net.sniff.output.rotate.gzip",
+		"false",
+		"If true, the rotated output files are compressed with gzip."))
+
+	mod.AddParam(session.NewStringParameter("net.sniff.backend",
+		BackendPCAP,
+		"^(pcap|afpacket)$",
+		"How packets are captured, pcap or afpacket for AF_PACKET sockets with TPACKET_V3 ring buffers on Linux, which drop less packets on fast links."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.afpacket.buffer",
+		"64",
+		"Size in MB of the ring buffers shared with the kernel by the afpacket backend."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.afpacket.fanout",
+		"1",
+		"Number of sockets the afpacket backend spreads the packets across by flow, each one read and decoded by its own goroutine."))
 
 	mod.AddParam(session.NewStringParameter("net.sniff.source",
 		"",
 		"",
-		"If set, the sniffer will read from this pcap file instead of the current interface."))
+		"If set, the sniffer will read from this pcap file instead of the current interface, or from a remote host with ssh://[user@]host[:port]/[interface] (tcpdump over ssh, add ?sudo=true to run it with sudo and ?key=FILE for the identity) or rpcap://host[:port]/interface (rpcapd)."))
+
+	mod.AddParam(session.NewStringParameter("net.sniff.rtp.output",
+		"",
+		"",
+		"If set, the payloads of the RTP streams of the sniffed VoIP calls will be saved as raw files in this folder."))
+
+	mod.AddParam(session.NewStringParameter("net.sniff.tftp.output",
+		"",
+		"",
+		"If set, the files transferred over TFTP will be reassembled and saved in this folder."))
+
+	mod.AddParam(session.NewStringParameter("net.sniff.carve.output",
+		"",
+		"",
+		"If set, the files transferred over HTTP, SMB and FTP will be carved and saved in this folder, net.sniff.reassembly is needed for the ones bigger than a packet."))
+
+	mod.AddParam(session.NewStringParameter("net.sniff.carve.types",
+		"",
+		"",
+		"Comma separated list of MIME types, MIME type prefixes such as image/* or extensions such as .exe of the files to carve, empty for all of them."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.carve.maxsize",
+		"50",
+		"Maximum size in MB of the carved files."))
+
+	mod.SavesTo("net.sniff.output", "net.sniff.rtp.output", "net.sniff.tftp.output", "net.sniff.carve.output")
+
+	mod.AddParam(session.NewStringParameter("net.sniff.script",
+		"",
+		"",
+		"Path of a JS script adding custom protocol parsers with registerParser(transport, name, priority, callback), lower priorities run first and the parsers of bettercap have priority 100."))
+
+	mod.AddParam(session.NewStringParameter("net.sniff.patterns.file",
+		"",
+		"",
+		"Path of a file with the payload patterns to load when the sniffer starts, one per line as NAME REGEXP."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.sample",
+		"1",
+		"Process only one every N captured packets, useful on very busy links such as mirrored switch ports, 1 to process all of them."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.rate",
+		"0",
+		"Maximum number of events per second reported for each source host, the exceeding ones are dropped and counted in the stats, 0 for no limit."))
+
+	mod.AddParam(session.NewBoolParameter("net.sniff.reassembly",
+		"false",
+		"If true, TCP streams are reassembled and the parsers work on them instead of single packets, so that logins and messages split across segments are parsed too."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.profile.learning",
+		"3600",
+		"Seconds a host profile is learned for before any new protocol used by the host is reported as an anomaly, 0 to disable."))
 
 	mod.AddHandler(session.NewModuleHandler("net.sniff stats", "",
 		"Print sniffer session configuration and statistics.",
@@ -68,9 +164,37 @@ func NewSniffer(s *session.Session) *Sniffer {
 
 			mod.Ctx.Log(mod.Session)
 
-			return mod.Stats.Print()
+			if err := mod.Stats.Print(); err != nil {
+				return err
+			}
+
+			limiter.Print()
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.stats", "",
+		"Show the packets and bytes processed by protocol, the top talkers, flows and DNS names and the packets dropped by the capture.",
+		func(args []string) error {
+			return mod.showStats()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.stats.live on", "",
+		"Show the sniffer statistics every net.sniff.stats.period seconds while the sniffer is running.",
+		func(args []string) error {
+			return mod.startLiveStats()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.stats.live off", "",
+		"Stop showing the sniffer statistics periodically.",
+		func(args []string) error {
+			mod.stopLiveStats()
+			return nil
 		}))
 
+	mod.AddParam(session.NewIntParameter("net.sniff.stats.period",
+		"5",
+		"Seconds between the updates of net.sniff.stats.live."))
+
 	mod.AddHandler(session.NewModuleHandler("net.sniff on", "",
 		"Start network sniffer in background.",
 		func(args []string) error {
@@ -83,6 +207,99 @@ func NewSniffer(s *session.Session) *Sniffer {
 			return mod.Stop()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("net.sniff.taps", "",
+		"Show the sniffer taps.",
+		func(args []string) error {
+			return mod.showTaps()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.tap.add NAME MODE OUTPUT FILTER", `net\.sniff\.tap\.add\s+([^\s]+)\s+(verbose|normal|quiet)\s+([^\s]+)\s+(.+)`,
+		"Add a tap, a named FILTER with its own OUTPUT file (- for none) and MODE: verbose, normal or quiet to only write the packets. Once taps are defined the sniffer only processes the packets matching at least one of them, for instance: net.sniff.tap.add dns verbose - udp port 53",
+		func(args []string) error {
+			return mod.addTap(args[0], args[1], args[2], args[3])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.tap.del NAME", `net\.sniff\.tap\.del\s+([^\s]+)`,
+		"Remove the tap NAME.",
+		func(args []string) error {
+			return mod.delTap(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.parsers", "",
+		"Show the TCP and UDP protocol parsers of the sniffer by priority.",
+		func(args []string) error {
+			return mod.showParsers()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.patterns", "",
+		"Show the payload patterns and how many times they matched.",
+		func(args []string) error {
+			return mod.showPatterns()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.pattern.add NAME REGEXP", `net\.sniff\.pattern\.add\s+([^\s]+)\s+(.+)`,
+		"Report a net.sniff.pattern event with the match and the flow every time REGEXP matches a TCP or UDP payload, for instance: net.sniff.pattern.add aws-key AKIA[0-9A-Z]{16}",
+		func(args []string) error {
+			return mod.addPattern(args[0], args[1])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.pattern.del NAME", `net\.sniff\.pattern\.del\s+([^\s]+)`,
+		"Remove the payload pattern NAME.",
+		func(args []string) error {
+			return mod.delPattern(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.profiles", "",
+		"Show the protocol usage profiles of the local hosts seen by the sniffer.",
+		func(args []string) error {
+			return mod.showProfiles()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.profile ADDRESS", `net\.profile ([^\s]+)`,
+		"Show the protocols, top peers and active hours of the local host with the given ADDRESS.",
+		func(args []string) error {
+			return mod.showProfile(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.profiles.clear", "",
+		"Clear the host profiles.",
+		func(args []string) error {
+			mod.Profiles.Clear()
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.neighbors", "",
+		"Show the switches, routers and other devices announcing themselves with LLDP, CDP or EDP.",
+		func(args []string) error {
+			return mod.showNeighbors()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.neighbors.clear", "",
+		"Clear the LLDP, CDP and EDP neighbors.",
+		func(args []string) error {
+			mod.Neighbors.Clear()
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.certs", "",
+		"Show the TLS certificates seen in the handshakes of the servers.",
+		func(args []string) error {
+			return mod.showCertificates()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.certs.export FILE", `net\.sniff\.certs\.export\s+(.+)`,
+		"Export the TLS certificates to FILE, as PEM or as JSON if its extension is .json.",
+		func(args []string) error {
+			return mod.exportCertificates(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.certs.clear", "",
+		"Clear the TLS certificates.",
+		func(args []string) error {
+			certificates.Clear()
+			return nil
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("net.fuzz on", "",
 		"Enable fuzzing for every sniffed packet containing the specified layers.",
 		func(args []string) error {
@@ -112,6 +329,10 @@ func NewSniffer(s *session.Session) *Sniffer {
 		"false",
 		"If true it will not report fuzzed packets."))
 
+	mod.State.Store("profiles", mod.Profiles)
+	mod.State.Store("neighbors", mod.Neighbors)
+	mod.State.Store("certificates", certificates)
+
 	return mod
 }
 
@@ -146,10 +367,13 @@ func (mod Sniffer) isLocalPacket(packet gopacket.Packet) bool {
 	return false
 }
 
-func (mod *Sniffer) onPacketMatched(pkt gopacket.Packet) {
-	if mainParser(pkt, mod.Ctx.Verbose) {
+// returns the protocols the parsers reported for the packet
+func (mod *Sniffer) onPacketMatched(pkt gopacket.Packet, verbose bool) []string {
+	annotations.Begin(pkt)
+	if mainParser(pkt, verbose) {
 		mod.Stats.NumDumped++
 	}
+	return annotations.End()
 }
 
 func (mod *Sniffer) Configure() error {
@@ -165,6 +389,33 @@ func (mod *Sniffer) Configure() error {
 		return err
 	}
 
+	var scriptPath string
+	if err, scriptPath = mod.StringParam("net.sniff.script"); err != nil {
+		return err
+	} else if scriptPath != "" {
+		if err, mod.script = LoadSnifferScript(scriptPath, mod.Session); err != nil {
+			mod.script = nil
+			mod.Ctx.Close()
+			mod.Ctx = nil
+			return err
+		}
+		mod.Debug("script %s loaded.", scriptPath)
+	}
+
+	var patternsPath string
+	if err, patternsPath = mod.StringParam("net.sniff.patterns.file"); err != nil {
+		return err
+	} else if patternsPath != "" {
+		if err = payloadPatterns.Load(patternsPath); err != nil {
+			mod.script.Unload()
+			mod.script = nil
+			mod.Ctx.Close()
+			mod.Ctx = nil
+			return err
+		}
+		mod.Debug("patterns loaded from %s.", patternsPath)
+	}
+
 	return nil
 }
 
@@ -175,13 +426,32 @@ func (mod *Sniffer) Start() error {
 
 	return mod.SetRunning(true, func() {
 		mod.Stats = NewSnifferStats()
+		mod.sampled = 0
+		limiter = newEventLimiter(mod.Ctx.RateLimit)
+		recorder = newRTPRecorder(mod.Ctx.RTPOutput)
+		tftpOutput = mod.Ctx.TFTPOutput
+		useCarver(mod.Ctx.Carver)
+		reassembler = newTCPReassembler(mod.Ctx.Reassembly)
+		learning := mod.profileLearning()
+
+		if mod.Ctx.Offload {
+			go mod.offloadWorker(mod.Ctx)
+		}
 
-		src := gopacket.NewPacketSource(mod.Ctx.Handle, mod.Ctx.Handle.LinkType())
-		mod.pktSourceChan = src.Packets()
+		mod.subscribeDecrypted()
+
+		mod.pktSourceChan = mod.Ctx.Handle.Packets()
 		for packet := range mod.pktSourceChan {
 			if !mod.Running() {
 				mod.Debug("end pkt loop (pkt=%v filter='%s')", packet, mod.Ctx.Filter)
 				break
+			} else if !mod.Ctx.Match(packet) || !mod.sample() {
+				continue
+			}
+
+			taps, parse, verbose := mod.Ctx.MatchTaps(packet)
+			if len(mod.Ctx.Taps) > 0 && len(taps) == 0 {
+				continue
 			}
 
 			now := time.Now()
@@ -200,16 +470,27 @@ func (mod *Sniffer) Start() error {
 			}
 
 			if mod.Ctx.DumpLocal || !isLocal {
+				for _, anomaly := range mod.Profiles.Update(packet, mod.Session.Interface.Net, learning) {
+					mod.onProfileAnomaly(anomaly)
+				}
+				if neighbor := packets.ParseDiscovery(packet); neighbor != nil {
+					mod.Neighbors.Update(neighbor, now)
+				}
+
 				data := packet.Data()
 				if mod.Ctx.Compiled == nil || mod.Ctx.Compiled.Match(data) {
 					mod.Stats.NumMatched++
 
-					mod.onPacketMatched(packet)
+					var protos []string
+					if parse {
+						protos = mod.onPacketMatched(packet, verbose)
+					}
+					mod.Stats.Account(packet, protos)
 
-					if mod.Ctx.OutputWriter != nil {
-						mod.Ctx.OutputWriter.WritePacket(packet.Metadata().CaptureInfo, data)
+					if mod.Ctx.WritePacket(packet, protos) {
 						mod.Stats.NumWrote++
 					}
+					mod.Ctx.WriteTaps(packet, protos, taps)
 				}
 			}
 		}
@@ -221,6 +502,8 @@ func (mod *Sniffer) Start() error {
 func (mod *Sniffer) Stop() error {
 	return mod.SetRunning(false, func() {
 		mod.Debug("stopping sniffer")
+		mod.stopLiveStats()
+		mod.Session.Bus.Unsubscribe(mod.decryptedSub)
 		if mod.pktSourceChan != nil {
 			mod.Debug("sending nil")
 			mod.pktSourceChan <- nil
@@ -229,5 +512,10 @@ func (mod *Sniffer) Stop() error {
 		mod.Debug("closing ctx")
 		mod.Ctx.Close()
 		mod.Debug("ctx closed")
+		recorder.Close()
+		reassembler.Close()
+		useCarver(nil)
+		mod.script.Unload()
+		mod.script = nil
 	})
 }
@@ -0,0 +1,298 @@
+// +build linux
+
+package net_sniff
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/log"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/afpacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// blocks are retired after this even if not full, so that the packets
+	// of quiet links are not delayed
+	afpacketBlockTimeout = 64 * time.Millisecond
+	// how often the readers check if the capture has been closed
+	afpacketPollTimeout = 100 * time.Millisecond
+	// packets decoded by the readers and not yet parsed
+	afpacketQueue = 4096
+	// frames per block of the ring
+	afpacketFramesPerBlock = 128
+	// how long a reader waits after a read error, doubled at every
+	// consecutive one, so that a link going down doesn't spin the readers
+	afpacketMinBackoff = 10 * time.Millisecond
+	afpacketMaxBackoff = time.Second
+)
+
+// the link types of the ARPHRD_* interface types we can decode, the frames
+// read from raw AF_PACKET sockets carry the link layer header of the device
+var afpacketLinkTypes = map[int]layers.LinkType{
+	unix.ARPHRD_ETHER:              layers.LinkTypeEthernet,
+	unix.ARPHRD_LOOPBACK:           layers.LinkTypeEthernet,
+	unix.ARPHRD_NONE:               layers.LinkTypeRaw,
+	unix.ARPHRD_IEEE80211_RADIOTAP: layers.LinkTypeIEEE80211Radio,
+}
+
+// AF_PACKET sockets with TPACKET_V3 ring buffers shared with the kernel,
+// with more than one socket the packets are spread by flow hash and every
+// socket is read and decoded by its own goroutine
+type afpacketCapture struct {
+	sockets  []*afpacket.TPacket
+	linkType layers.LinkType
+	promisc  int
+	packets  chan gopacket.Packet
+	done     chan bool
+	readers  sync.WaitGroup
+	closed   sync.Once
+}
+
+// ring sizes for the buffer in MB, the frames must fit a full packet and
+// the blocks must be a multiple of the page size
+func afpacketRingSize(bufferMB int) (frameSize int, blockSize int, numBlocks int) {
+	pageSize := os.Getpagesize()
+	frameSize = captureSnapLen
+	if frameSize%pageSize != 0 {
+		frameSize = (frameSize/pageSize + 1) * pageSize
+	}
+
+	blockSize = frameSize * afpacketFramesPerBlock
+	numBlocks = (bufferMB * 1024 * 1024) / blockSize
+	if numBlocks == 0 {
+		numBlocks = 1
+	}
+	return
+}
+
+// the sockets only see the traffic to us unless one of them asks for the
+// promiscuous mode, which lasts as long as it is open
+func afpacketPromisc(iface *net.Interface) (int, error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		return -1, err
+	}
+
+	mreq := &unix.PacketMreq{
+		Ifindex: int32(iface.Index),
+		Type:    unix.PACKET_MR_PROMISC,
+	}
+	if err = unix.SetsockoptPacketMreq(fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, mreq); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// the link type of the frames read from the interface
+func afpacketLinkType(ifName string) (layers.LinkType, error) {
+	raw, err := ioutil.ReadFile("/sys/class/net/" + ifName + "/type")
+	if err != nil {
+		return 0, fmt.Errorf("could not read the type of %s: %v", ifName, err)
+	}
+
+	arphrd, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected type of %s: %v", ifName, err)
+	} else if linkType, found := afpacketLinkTypes[arphrd]; found {
+		return linkType, nil
+	}
+	return 0, fmt.Errorf("the %s capture backend doesn't support the link type %d of %s, use %s", BackendAFPacket, arphrd, ifName, BackendPCAP)
+}
+
+func newAFPacketCapture(ifName string, bufferMB int, fanout int) (captureHandle, error) {
+	iface, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return nil, err
+	}
+
+	linkType, err := afpacketLinkType(ifName)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &afpacketCapture{
+		sockets:  make([]*afpacket.TPacket, 0, fanout),
+		linkType: linkType,
+		promisc:  -1,
+		packets:  make(chan gopacket.Packet, afpacketQueue),
+		done:     make(chan bool),
+	}
+
+	if c.promisc, err = afpacketPromisc(iface); err != nil {
+		return nil, fmt.Errorf("could not enable promiscuous mode on %s: %v", ifName, err)
+	}
+
+	// the buffer is shared by the sockets
+	frameSize, blockSize, numBlocks := afpacketRingSize(bufferMB / fanout)
+	// the group has to be unique among the processes using fanout
+	group := uint16(os.Getpid() & 0xffff)
+
+	for i := 0; i < fanout; i++ {
+		socket, err := afpacket.NewTPacket(
+			afpacket.OptInterface(ifName),
+			afpacket.OptFrameSize(frameSize),
+			afpacket.OptBlockSize(blockSize),
+			afpacket.OptNumBlocks(numBlocks),
+			afpacket.OptBlockTimeout(afpacketBlockTimeout),
+			afpacket.OptPollTimeout(afpacketPollTimeout),
+			afpacket.TPacketVersion3,
+			afpacket.SocketRaw)
+		if err != nil {
+			c.closeSockets()
+			return nil, err
+		}
+		c.sockets = append(c.sockets, socket)
+
+		// packets of the same flow always go to the same socket
+		if fanout > 1 {
+			if err = socket.SetFanout(afpacket.FanoutHashWithDefrag|afpacket.FanoutHash, group); err != nil {
+				c.closeSockets()
+				return nil, fmt.Errorf("could not enable fanout: %v", err)
+			}
+		}
+	}
+
+	for _, socket := range c.sockets {
+		c.readers.Add(1)
+		go c.reader(socket)
+	}
+
+	go func() {
+		c.readers.Wait()
+		close(c.packets)
+	}()
+
+	return c, nil
+}
+
+// every socket has its own reader, which also decodes the packets so that
+// the decoding is spread among the fanout sockets and the sniffer only
+// parses them
+func (c *afpacketCapture) reader(socket *afpacket.TPacket) {
+	defer c.readers.Done()
+
+	backoff := time.Duration(0)
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		data, ci, err := socket.ReadPacketData()
+		if err == afpacket.ErrTimeout {
+			continue
+		} else if err != nil {
+			if backoff == 0 {
+				log.Warning("afpacket read error: %v", err)
+				backoff = afpacketMinBackoff
+			} else if backoff *= 2; backoff > afpacketMaxBackoff {
+				backoff = afpacketMaxBackoff
+			}
+
+			select {
+			case <-time.After(backoff):
+				continue
+			case <-c.done:
+				return
+			}
+		}
+		backoff = 0
+
+		// ReadPacketData returns a copy of the frame, the decoded layers
+		// can point into it
+		pkt := gopacket.NewPacket(data, c.linkType, gopacket.DecodeOptions{NoCopy: true})
+		m := pkt.Metadata()
+		m.CaptureInfo = ci
+		m.Truncated = m.Truncated || ci.CaptureLength < ci.Length
+
+		select {
+		case c.packets <- pkt:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *afpacketCapture) LinkType() layers.LinkType {
+	return c.linkType
+}
+
+// SetBPFFilter compiles the expression with libpcap and attaches it to
+// every socket.
+func (c *afpacketCapture) SetBPFFilter(expr string) error {
+	compiled, err := pcap.CompileBPFFilter(c.linkType, captureSnapLen, expr)
+	if err != nil {
+		return err
+	}
+
+	raw := make([]bpf.RawInstruction, len(compiled))
+	for i, ins := range compiled {
+		raw[i] = bpf.RawInstruction{
+			Op: ins.Code,
+			Jt: ins.Jt,
+			Jf: ins.Jf,
+			K:  ins.K,
+		}
+	}
+
+	for _, socket := range c.sockets {
+		if err = socket.SetBPF(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *afpacketCapture) Packets() chan gopacket.Packet {
+	return c.packets
+}
+
+func (c *afpacketCapture) Stats() (*captureStats, error) {
+	stats := &captureStats{}
+	for _, socket := range c.sockets {
+		_, v3, err := socket.SocketStats()
+		if err != nil {
+			return nil, err
+		}
+		stats.Received += uint64(v3.Packets())
+		stats.Dropped += uint64(v3.Drops())
+	}
+	return stats, nil
+}
+
+func (c *afpacketCapture) closeSockets() {
+	for _, socket := range c.sockets {
+		socket.Close()
+	}
+	c.sockets = nil
+
+	if c.promisc >= 0 {
+		unix.Close(c.promisc)
+		c.promisc = -1
+	}
+}
+
+// Close stops the readers, which notice it within the poll timeout, and
+// releases the rings.
+func (c *afpacketCapture) Close() {
+	c.closed.Do(func() {
+		close(c.done)
+		c.readers.Wait()
+		c.closeSockets()
+	})
+}
@@ -0,0 +1,12 @@
+// +build !linux
+
+package net_sniff
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func newAFPacketCapture(ifName string, bufferMB int, fanout int) (captureHandle, error) {
+	return nil, fmt.Errorf("the %s capture backend is not supported on %s", BackendAFPacket, runtime.GOOS)
+}
@@ -0,0 +1,111 @@
+package net_sniff
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+const (
+	BackendPCAP     = "pcap"
+	BackendAFPacket = "afpacket"
+
+	captureSnapLen = 65536
+	/*
+	 * We don't want to pcap.BlockForever otherwise pcap_close(handle)
+	 * could hang waiting for a timeout to expire ...
+	 */
+	captureReadTimeout = 500 * time.Millisecond
+)
+
+// what the capture received and what it had to drop
+type captureStats struct {
+	Received  uint64
+	Dropped   uint64
+	IfDropped uint64
+}
+
+// captureHandle is where the sniffer reads the packets from.
+type captureHandle interface {
+	LinkType() layers.LinkType
+	SetBPFFilter(expr string) error
+	Packets() chan gopacket.Packet
+	Stats() (*captureStats, error)
+	Close()
+}
+
+// libpcap, live or reading a capture file
+type pcapCapture struct {
+	handle *pcap.Handle
+}
+
+func (c *pcapCapture) LinkType() layers.LinkType {
+	return c.handle.LinkType()
+}
+
+func (c *pcapCapture) SetBPFFilter(expr string) error {
+	return c.handle.SetBPFFilter(expr)
+}
+
+func (c *pcapCapture) Packets() chan gopacket.Packet {
+	return gopacket.NewPacketSource(c.handle, c.handle.LinkType()).Packets()
+}
+
+func (c *pcapCapture) Stats() (*captureStats, error) {
+	stats, err := c.handle.Stats()
+	if err != nil {
+		return nil, err
+	}
+	return &captureStats{
+		Received:  uint64(stats.PacketsReceived),
+		Dropped:   uint64(stats.PacketsDropped),
+		IfDropped: uint64(stats.PacketsIfDropped),
+	}, nil
+}
+
+func (c *pcapCapture) Close() {
+	c.handle.Close()
+}
+
+func (mod *Sniffer) openCapture(ctx *SnifferContext) (captureHandle, error) {
+	if strings.HasPrefix(ctx.Source, sourceSSH) {
+		return newSSHCapture(&mod.SessionModule, ctx.Source, ctx.BPF)
+	} else if strings.HasPrefix(ctx.Source, sourceRPCAP) {
+		return newRPCAPCapture(ctx.Source)
+	} else if ctx.Source != "" {
+		handle, err := pcap.OpenOffline(ctx.Source)
+		if err != nil {
+			return nil, err
+		}
+		return &pcapCapture{handle}, nil
+	}
+
+	switch ctx.Backend {
+	case BackendAFPacket:
+		var buffer, fanout int
+		var err error
+		if err, buffer = mod.IntParam("net.sniff.afpacket.buffer"); err != nil {
+			return nil, err
+		} else if err, fanout = mod.IntParam("net.sniff.afpacket.fanout"); err != nil {
+			return nil, err
+		} else if buffer <= 0 {
+			return nil, fmt.Errorf("net.sniff.afpacket.buffer must be greater than zero")
+		} else if fanout < 1 {
+			return nil, fmt.Errorf("net.sniff.afpacket.fanout must be greater than zero")
+		}
+		return newAFPacketCapture(mod.Session.Interface.Name(), buffer, fanout)
+
+	case BackendPCAP:
+		handle, err := pcap.OpenLive(mod.Session.Interface.Name(), captureSnapLen, true, captureReadTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return &pcapCapture{handle}, nil
+	}
+
+	return nil, fmt.Errorf("unknown capture backend %s", ctx.Backend)
+}
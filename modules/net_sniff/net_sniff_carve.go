@@ -0,0 +1,300 @@
+package net_sniff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/log"
+
+	"github.com/google/gopacket"
+
+	"github.com/dustin/go-humanize"
+	"github.com/evilsocket/islazy/tui"
+)
+
+// carveBuffer reassembles a file from chunks at their offsets, the chunks
+// can come in any order and more than once
+type carveBuffer struct {
+	chunks   map[uint64][]byte
+	size     uint64
+	max      uint64
+	buffered uint64
+	tooBig   bool
+	updated  time.Time
+}
+
+func newCarveBuffer(maxSize int64) *carveBuffer {
+	return &carveBuffer{
+		chunks:  make(map[uint64][]byte),
+		max:     uint64(maxSize),
+		updated: time.Now(),
+	}
+}
+
+func (b *carveBuffer) WriteAt(offset uint64, data []byte) {
+	b.updated = time.Now()
+	if b.tooBig || len(data) == 0 {
+		return
+	}
+
+	end := offset + uint64(len(data))
+	if end > b.max {
+		// not going to be saved anyway
+		b.tooBig = true
+		b.chunks = nil
+		b.buffered = 0
+		return
+	}
+
+	if existing, found := b.chunks[offset]; !found || len(existing) < len(data) {
+		b.chunks[offset] = append([]byte{}, data...)
+		b.buffered += uint64(len(data) - len(existing))
+	}
+	if end > b.size {
+		b.size = end
+	}
+
+	// chunks overlapping at different offsets are stored more times
+	if b.buffered > b.max {
+		b.compact()
+	}
+}
+
+func (b *carveBuffer) offsets() []uint64 {
+	offsets := make([]uint64, 0, len(b.chunks))
+	for offset := range b.chunks {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets
+}
+
+// compact merges the overlapping chunks, what's left can't be bigger
+// than the file.
+func (b *carveBuffer) compact() {
+	chunks := make(map[uint64][]byte)
+	buffered := uint64(0)
+	start := uint64(0)
+	var merged []byte
+
+	for _, offset := range b.offsets() {
+		chunk := b.chunks[offset]
+		if merged != nil && offset <= start+uint64(len(merged)) {
+			// like in Bytes, the data at the later offsets wins
+			if end := offset + uint64(len(chunk)); end > start+uint64(len(merged)) {
+				merged = append(merged, make([]byte, end-start-uint64(len(merged)))...)
+			}
+			copy(merged[offset-start:], chunk)
+			continue
+		}
+		if merged != nil {
+			chunks[start] = merged
+			buffered += uint64(len(merged))
+		}
+		start = offset
+		merged = append([]byte{}, chunk...)
+	}
+	if merged != nil {
+		chunks[start] = merged
+		buffered += uint64(len(merged))
+	}
+
+	b.chunks = chunks
+	b.buffered = buffered
+}
+
+// Bytes returns the file and false if parts of it are missing.
+func (b *carveBuffer) Bytes() ([]byte, bool) {
+	if b.tooBig || len(b.chunks) == 0 {
+		return nil, false
+	}
+
+	data := make([]byte, b.size)
+	covered := uint64(0)
+	complete := true
+	for _, offset := range b.offsets() {
+		chunk := b.chunks[offset]
+		if offset > covered {
+			complete = false
+		}
+		copy(data[offset:], chunk)
+		if end := offset + uint64(len(chunk)); end > covered {
+			covered = end
+		}
+	}
+
+	return data, complete
+}
+
+// saves the files transferred over the supported protocols
+type fileCarver struct {
+	output  string
+	maxSize int64
+	// mime types, mime type prefixes such as image/ or extensions such as .exe
+	types []string
+}
+
+// the carver of the running sniffer context, the parsers get it with
+// activeCarver since they can run while another context is started
+var (
+	carverLock    = sync.RWMutex{}
+	runningCarver *fileCarver
+)
+
+func useCarver(c *fileCarver) {
+	carverLock.Lock()
+	defer carverLock.Unlock()
+	runningCarver = c
+}
+
+func activeCarver() *fileCarver {
+	carverLock.RLock()
+	defer carverLock.RUnlock()
+	return runningCarver
+}
+
+func newFileCarver(output string, types string, maxSize int64) *fileCarver {
+	if output == "" {
+		return nil
+	}
+
+	c := &fileCarver{
+		output:  output,
+		maxSize: maxSize,
+		types:   make([]string, 0),
+	}
+	for _, t := range strings.Split(types, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			c.types = append(c.types, strings.TrimSuffix(t, "*"))
+		}
+	}
+	return c
+}
+
+func (c *fileCarver) Enabled() bool {
+	return c != nil
+}
+
+func (c *fileCarver) MaxSize() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.maxSize
+}
+
+func (c *fileCarver) allowed(mimeType string, ext string) bool {
+	if len(c.types) == 0 {
+		return true
+	}
+
+	ext = strings.ToLower(ext)
+	for _, t := range c.types {
+		if strings.HasPrefix(t, ".") {
+			if t == ext {
+				return true
+			}
+		} else if strings.HasSuffix(t, "/") {
+			if strings.HasPrefix(mimeType, t) {
+				return true
+			}
+		} else if t == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+func carveMimeType(data []byte, declared string) string {
+	// the content is more reliable than what the server says
+	detected := http.DetectContentType(data)
+	if detected == "application/octet-stream" && declared != "" {
+		detected = declared
+	}
+	if parsed, _, err := mime.ParseMediaType(detected); err == nil {
+		return parsed
+	}
+	return detected
+}
+
+// Carve saves a file if it matches the filters, name is the file name in
+// the transfer if known, declared its content type if any.
+func (c *fileCarver) Carve(pkt gopacket.Packet, proto string, srcIP, dstIP net.IP, name string, declared string, data []byte, complete bool) {
+	if !c.Enabled() || len(data) == 0 || int64(len(data)) > c.maxSize {
+		return
+	}
+
+	mimeType := carveMimeType(data, declared)
+	name = filepath.Base(strings.Replace(name, "\\", "/", -1))
+	ext := filepath.Ext(name)
+	if ext == "" {
+		if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	if !c.allowed(mimeType, ext) {
+		return
+	}
+
+	if name == "" || name == "." || name == "/" {
+		name = proto + ext
+	} else if filepath.Ext(name) == "" {
+		name += ext
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if err := os.MkdirAll(c.output, os.ModePerm); err != nil {
+		log.Error("could not create %s: %v", c.output, err)
+		return
+	}
+
+	// the same file carved more times is saved once
+	fileName := fmt.Sprintf("%s_%s_%s", hash[:16], proto, name)
+	path := filepath.Join(c.output, tftpUnsafeChars.ReplaceAllString(fileName, "_"))
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		log.Error("could not save %s: %v", path, err)
+		return
+	}
+
+	what := ""
+	if !complete {
+		what = tui.Red(" incomplete")
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"file",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"protocol": proto,
+			"name":     name,
+			"path":     path,
+			"size":     len(data),
+			"mime":     mimeType,
+			"sha256":   hash,
+			"complete": complete,
+		},
+		"%s %s > %s %s %s %s%s %s",
+		tui.Wrap(tui.BACKGREEN+tui.FOREBLACK, "file"),
+		vIP(srcIP),
+		vIP(dstIP),
+		tui.Dim(proto),
+		tui.Yellow(name),
+		tui.Dim(fmt.Sprintf("(%s, %s)", mimeType, humanize.Bytes(uint64(len(data))))),
+		what,
+		tui.Dim("sha256:"+hash),
+	).Push()
+}
@@ -0,0 +1,249 @@
+package net_sniff
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+// upper bound of the certificates kept in the table
+const maxCertificates = 8192
+
+// HostCertificate is the certificate a server presented in its handshakes.
+type HostCertificate struct {
+	*packets.TLSCertificate
+	Host      string    `json:"host"`
+	SNI       []string  `json:"sni"`
+	Chain     int       `json:"chain"`
+	Seen      int       `json:"seen"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Flags returns what's wrong with the certificate at the given time.
+func (c *HostCertificate) Flags(at time.Time) []string {
+	flags := []string{}
+	if c.SelfSigned {
+		flags = append(flags, "self-signed")
+	}
+	if c.Expired(at) {
+		flags = append(flags, "expired")
+	}
+	return flags
+}
+
+type Certificates struct {
+	sync.RWMutex
+	certs map[string]*HostCertificate
+}
+
+func NewCertificates() *Certificates {
+	return &Certificates{
+		certs: make(map[string]*HostCertificate),
+	}
+}
+
+// the certificates are collected by the TLS parser
+var certificates = NewCertificates()
+
+func (c *Certificates) MarshalJSON() ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return json.Marshal(c.certs)
+}
+
+func (c *Certificates) Clear() {
+	c.Lock()
+	defer c.Unlock()
+	c.certs = make(map[string]*HostCertificate)
+}
+
+// Update adds or refreshes the leaf certificate presented by a server,
+// returning it and whether it's the first time it's been seen there.
+func (c *Certificates) Update(host string, sni string, chain []*x509.Certificate, at time.Time) (*HostCertificate, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	leaf := packets.NewTLSCertificate(chain[0])
+	key := host + "/" + leaf.SHA256
+	cert, found := c.certs[key]
+	if !found {
+		cert = &HostCertificate{
+			TLSCertificate: leaf,
+			Host:           host,
+			Chain:          len(chain),
+			FirstSeen:      at,
+		}
+		if len(c.certs) < maxCertificates {
+			c.certs[key] = cert
+		}
+	}
+
+	cert.Seen++
+	cert.LastSeen = at
+	if sni != "" {
+		known := false
+		for _, name := range cert.SNI {
+			if known = name == sni; known {
+				break
+			}
+		}
+		if !known {
+			cert.SNI = append(cert.SNI, sni)
+		}
+	}
+
+	return cert, !found
+}
+
+// List returns the certificates sorted by host.
+func (c *Certificates) List() []*HostCertificate {
+	c.RLock()
+	defer c.RUnlock()
+
+	list := make([]*HostCertificate, 0, len(c.certs))
+	for _, cert := range c.certs {
+		list = append(list, cert)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Host != list[j].Host {
+			return list[i].Host < list[j].Host
+		}
+		return list[i].FirstSeen.Before(list[j].FirstSeen)
+	})
+	return list
+}
+
+func tlsOnCertificates(srcIP, dstIP net.IP, pkt gopacket.Packet, tcp *layers.TCP, sni string, chain []*x509.Certificate) {
+	now := pkt.Metadata().Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	host := net.JoinHostPort(srcIP.String(), vPort(tcp.SrcPort))
+	cert, isNew := certificates.Update(host, sni, chain, now)
+	if !isNew {
+		return
+	}
+
+	// self-signed and expired certificates are reported on their own
+	proto := "tls.cert"
+	flags := cert.Flags(now)
+	tag := tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "cert")
+	if len(flags) > 0 {
+		proto = "tls.cert.warning"
+		tag = tui.Wrap(tui.BACKRED+tui.FOREWHITE, "cert")
+	}
+
+	name := cert.CommonName
+	if name == "" {
+		name = cert.Subject
+	}
+
+	NewSnifferEvent(
+		pkt,
+		proto,
+		host,
+		dstIP.String(),
+		SniffData{
+			"sni":         sni,
+			"common_name": cert.CommonName,
+			"subject":     cert.Subject,
+			"issuer":      cert.Issuer,
+			"names":       cert.Names,
+			"serial":      cert.Serial,
+			"sha1":        cert.SHA1,
+			"sha256":      cert.SHA256,
+			"not_before":  cert.NotBefore,
+			"not_after":   cert.NotAfter,
+			"self_signed": cert.SelfSigned,
+			"expired":     cert.Expired(now),
+			"chain":       cert.Chain,
+		},
+		"%s %s:%s > %s %s %s %s %s",
+		tag,
+		vIP(srcIP),
+		vPort(tcp.SrcPort),
+		tui.Bold(name),
+		tui.Dim("issuer:"+cert.Issuer),
+		tui.Dim("expires:"+cert.NotAfter.Format("2006-01-02")),
+		tui.Red(strings.Join(flags, ",")),
+		tui.Dim("sha256:"+cert.SHA256[:16]),
+	).Push()
+}
+
+func (mod *Sniffer) showCertificates() error {
+	list := certificates.List()
+	if len(list) == 0 {
+		mod.Info("no TLS certificates seen yet")
+		return nil
+	}
+
+	now := time.Now()
+	rows := [][]string{}
+	for _, cert := range list {
+		expires := cert.NotAfter.Format("2006-01-02")
+		if cert.Expired(now) {
+			expires = tui.Red(expires)
+		}
+		rows = append(rows, []string{
+			tui.Bold(cert.Host),
+			strings.Join(cert.SNI, ", "),
+			cert.CommonName,
+			strings.Join(cert.Names, ", "),
+			cert.Issuer,
+			expires,
+			tui.Red(strings.Join(cert.Flags(now), ", ")),
+			tui.Dim(cert.SHA256[:16]),
+			fmt.Sprintf("%d", cert.Seen),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Host", "SNI", "Common Name", "Names", "Issuer", "Expires", "Flags", "SHA256", "Seen"}, rows)
+	mod.Session.Refresh()
+	return nil
+}
+
+// exportCertificates writes the certificates to a PEM file, each one
+// preceded by where it's been seen, or as JSON if the file is a .json one.
+func (mod *Sniffer) exportCertificates(fileName string) error {
+	fileName, err := fs.Expand(fileName)
+	if err != nil {
+		return err
+	}
+
+	list := certificates.List()
+	data := []byte{}
+	if strings.HasSuffix(strings.ToLower(fileName), ".json") {
+		if data, err = json.MarshalIndent(list, "", "  "); err != nil {
+			return err
+		}
+	} else {
+		for _, cert := range list {
+			data = append(data, fmt.Sprintf("# host: %s\n# subject: %s\n# issuer: %s\n# sha256: %s\n",
+				cert.Host, cert.Subject, cert.Issuer, cert.SHA256)...)
+			data = append(data, cert.PEM()...)
+		}
+	}
+
+	if err = ioutil.WriteFile(fileName, data, 0644); err != nil {
+		return err
+	}
+
+	mod.Info("%d certificates exported to %s", len(list), fileName)
+	return nil
+}
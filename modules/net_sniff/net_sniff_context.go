@@ -1,30 +1,52 @@
 package net_sniff
 
 import (
-	"os"
+	"fmt"
 	"regexp"
+	"runtime"
+	"strings"
 	"time"
 
+	"github.com/bettercap/bettercap/core"
 	"github.com/bettercap/bettercap/log"
+	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
 
-	"github.com/google/gopacket/pcap"
+	"github.com/google/gopacket"
 	"github.com/google/gopacket/pcapgo"
 
+	"github.com/dustin/go-humanize"
+	"github.com/evilsocket/islazy/fs"
 	"github.com/evilsocket/islazy/tui"
 )
 
 type SnifferContext struct {
-	Handle       *pcap.Handle
-	Source       string
-	DumpLocal    bool
-	Verbose      bool
-	Filter       string
-	Expression   string
-	Compiled     *regexp.Regexp
-	Output       string
-	OutputFile   *os.File
-	OutputWriter *pcapgo.Writer
+	Handle     captureHandle
+	Backend    string
+	Source     string
+	DumpLocal  bool
+	Verbose    bool
+	Filter     string
+	BPF        string
+	Kernel     string
+	Offload    bool
+	Display    *packets.DisplayFilter
+	Expression string
+	Compiled   *regexp.Regexp
+	Output     string
+	Capture    *captureOutput
+	Taps       []*SnifferTap
+	SessionID  string
+	Sample     int
+	RateLimit  int
+	RTPOutput  string
+	TFTPOutput string
+	Reassembly bool
+	// where and which transferred files are carved
+	CarveOutput  string
+	CarveTypes   string
+	CarveMaxSize int64
+	Carver       *fileCarver
 }
 
 func (mod *Sniffer) GetContext() (error, *SnifferContext) {
@@ -36,21 +58,6 @@ func (mod *Sniffer) GetContext() (error, *SnifferContext) {
 		return err, ctx
 	}
 
-	if ctx.Source == "" {
-		/*
-		 * We don't want to pcap.BlockForever otherwise pcap_close(handle)
-		 * could hang waiting for a timeout to expire ...
-		 */
-		readTimeout := 500 * time.Millisecond
-		if ctx.Handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, readTimeout); err != nil {
-			return err, ctx
-		}
-	} else {
-		if ctx.Handle, err = pcap.OpenOffline(ctx.Source); err != nil {
-			return err, ctx
-		}
-	}
-
 	if err, ctx.Verbose = mod.BoolParam("net.sniff.verbose"); err != nil {
 		return err, ctx
 	}
@@ -62,12 +69,37 @@ func (mod *Sniffer) GetContext() (error, *SnifferContext) {
 	if err, ctx.Filter = mod.StringParam("net.sniff.filter"); err != nil {
 		return err, ctx
 	} else if ctx.Filter != "" {
-		err = ctx.Handle.SetBPFFilter(ctx.Filter)
-		if err != nil {
+		// anything that is not a valid display filter is used as a raw BPF expression
+		bpf := ctx.Filter
+		if display, err := packets.CompileDisplayFilter(ctx.Filter); err == nil {
+			mod.Debug("display filter '%s' compiled to bpf '%s'", ctx.Filter, display.BPF)
+			ctx.Display = display
+			bpf = display.BPF
+		}
+
+		ctx.BPF = bpf
+	}
+
+	// only what at least one of the taps wants is captured
+	ctx.BPF = packets.BPFAnd(ctx.BPF, mod.taps.BPF())
+
+	// remote sources are filtered before the packets are sent
+	if err, ctx.Backend = mod.StringParam("net.sniff.backend"); err != nil {
+		return err, ctx
+	} else if ctx.Handle, err = mod.openCapture(ctx); err != nil {
+		return err, ctx
+	}
+
+	if ctx.Source == "" {
+		if err, ctx.Offload = mod.BoolParam("net.sniff.offload"); err != nil {
 			return err, ctx
 		}
 	}
 
+	if err = mod.setKernelFilter(ctx); err != nil {
+		return err, ctx
+	}
+
 	if err, ctx.Expression = mod.StringParam("net.sniff.regexp"); err != nil {
 		return err, ctx
 	} else if ctx.Expression != "" {
@@ -76,16 +108,61 @@ func (mod *Sniffer) GetContext() (error, *SnifferContext) {
 		}
 	}
 
+	if err, ctx.Sample = mod.IntParam("net.sniff.sample"); err != nil {
+		return err, ctx
+	} else if err, ctx.RateLimit = mod.IntParam("net.sniff.rate"); err != nil {
+		return err, ctx
+	}
+
 	if err, ctx.Output = mod.StringParam("net.sniff.output"); err != nil {
 		return err, ctx
 	} else if ctx.Output != "" {
-		if ctx.OutputFile, err = os.Create(ctx.Output); err != nil {
+		if ctx.Capture, err = mod.newCaptureOutput(ctx, ctx.Output); err != nil {
+			return err, ctx
+		} else if err = ctx.Capture.Open(); err != nil {
+			return err, ctx
+		}
+	}
+
+	if err = mod.openTaps(ctx); err != nil {
+		return err, ctx
+	}
+
+	if err, ctx.RTPOutput = mod.StringParam("net.sniff.rtp.output"); err != nil {
+		return err, ctx
+	} else if ctx.RTPOutput != "" {
+		if ctx.RTPOutput, err = fs.Expand(ctx.RTPOutput); err != nil {
 			return err, ctx
 		}
+	}
 
-		ctx.OutputWriter = pcapgo.NewWriter(ctx.OutputFile)
-		ctx.OutputWriter.WriteFileHeader(65536, ctx.Handle.LinkType())
+	if err, ctx.Reassembly = mod.BoolParam("net.sniff.reassembly"); err != nil {
+		return err, ctx
+	}
+
+	if err, ctx.TFTPOutput = mod.StringParam("net.sniff.tftp.output"); err != nil {
+		return err, ctx
+	} else if ctx.TFTPOutput != "" {
+		if ctx.TFTPOutput, err = fs.Expand(ctx.TFTPOutput); err != nil {
+			return err, ctx
+		}
+	}
+
+	var maxSize int
+	if err, ctx.CarveOutput = mod.StringParam("net.sniff.carve.output"); err != nil {
+		return err, ctx
+	} else if ctx.CarveOutput != "" {
+		if ctx.CarveOutput, err = fs.Expand(ctx.CarveOutput); err != nil {
+			return err, ctx
+		}
+	}
+	if err, ctx.CarveTypes = mod.StringParam("net.sniff.carve.types"); err != nil {
+		return err, ctx
+	} else if err, maxSize = mod.IntParam("net.sniff.carve.maxsize"); err != nil {
+		return err, ctx
 	}
+	ctx.CarveMaxSize = int64(maxSize) * 1024 * 1024
+	ctx.Carver = newFileCarver(ctx.CarveOutput, ctx.CarveTypes, ctx.CarveMaxSize)
 
 	return nil, ctx
 }
@@ -93,14 +170,28 @@ func (mod *Sniffer) GetContext() (error, *SnifferContext) {
 func NewSnifferContext() *SnifferContext {
 	return &SnifferContext{
 		Handle:       nil,
+		Backend:      BackendPCAP,
 		DumpLocal:    false,
 		Verbose:      false,
 		Filter:       "",
+		BPF:          "",
+		Kernel:       "",
+		Offload:      false,
+		Display:      nil,
 		Expression:   "",
 		Compiled:     nil,
 		Output:       "",
-		OutputFile:   nil,
-		OutputWriter: nil,
+		Capture:      nil,
+		SessionID:    "",
+		Sample:       1,
+		RateLimit:    0,
+		RTPOutput:    "",
+		TFTPOutput:   "",
+		Reassembly:   false,
+		CarveOutput:  "",
+		CarveTypes:   "",
+		CarveMaxSize: 0,
+		Carver:       nil,
 	}
 }
 
@@ -114,11 +205,142 @@ var (
 )
 
 func (c *SnifferContext) Log(sess *session.Session) {
+	if isRemoteSource(c.Source) {
+		log.Info("Remote source      : '%s'", tui.Yellow(redactSource(c.Source)))
+	} else if c.Source != "" {
+		log.Info("Source             : '%s'", tui.Yellow(c.Source))
+	} else {
+		log.Info("Capture backend    : %s", c.Backend)
+	}
 	log.Info("Skip local packets : %s", yn[c.DumpLocal])
 	log.Info("Verbose            : %s", yn[c.Verbose])
-	log.Info("BPF Filter         : '%s'", tui.Yellow(c.Filter))
+	if c.Display != nil {
+		log.Info("Display Filter     : '%s'", tui.Yellow(c.Display.Expression))
+		log.Info("BPF Filter         : '%s'", tui.Yellow(c.Display.BPF))
+	} else {
+		log.Info("BPF Filter         : '%s'", tui.Yellow(c.Filter))
+	}
+	if c.Offload {
+		log.Info("Kernel Filter      : '%s'", tui.Yellow(c.Kernel))
+	}
+	for _, t := range c.Taps {
+		output := ""
+		if t.capture != nil {
+			output = " > " + t.capture.Path()
+		}
+		log.Info("Tap %-14s : '%s' (%s)%s", t.Name, tui.Yellow(t.Filter), t.Mode, output)
+	}
 	log.Info("Regular expression : '%s'", tui.Yellow(c.Expression))
-	log.Info("File output        : '%s'", tui.Yellow(c.Output))
+	if c.Capture != nil {
+		log.Info("File output        : '%s'", tui.Yellow(c.Capture.Path()))
+		if c.Capture.Rotates() {
+			log.Info("Output rotation    : size %s, time %s, files %d, gzip %s",
+				humanize.Bytes(uint64(c.Capture.MaxSize)), c.Capture.MaxAge, c.Capture.MaxFiles, yn[c.Capture.Gzip])
+		}
+	} else {
+		log.Info("File output        : '%s'", tui.Yellow(c.Output))
+	}
+	if c.RTPOutput != "" {
+		log.Info("RTP output         : '%s'", tui.Yellow(c.RTPOutput))
+	}
+	if c.TFTPOutput != "" {
+		log.Info("TFTP output        : '%s'", tui.Yellow(c.TFTPOutput))
+	}
+	if c.CarveOutput != "" {
+		types := c.CarveTypes
+		if types == "" {
+			types = "all"
+		}
+		log.Info("Carved files       : '%s' (%s, up to %s)", tui.Yellow(c.CarveOutput), types, humanize.Bytes(uint64(c.CarveMaxSize)))
+	}
+	log.Info("TCP reassembly     : %s", yn[c.Reassembly])
+	if c.Sample > 1 {
+		log.Info("Sampling           : 1 every %d packets", c.Sample)
+	}
+	if c.RateLimit > 0 {
+		log.Info("Rate limit         : %d events per second per host", c.RateLimit)
+	}
+}
+
+// Match returns true if the packet passes the parts of the
+// display filter that couldn't be compiled to BPF
+func (c *SnifferContext) Match(pkt gopacket.Packet) bool {
+	return c.Display == nil || c.Display.Match(pkt)
+}
+
+// the pcapng output describes the capture and comments every packet with
+// the protocols the parsers reported for it
+func (mod *Sniffer) newCaptureOutput(ctx *SnifferContext, path string) (*captureOutput, error) {
+	var err error
+	var size float64
+	var secs int
+
+	out := newCaptureOutput(path, mod.Session.Interface.Name(), ctx.Handle.LinkType())
+	if err, size = mod.DecParam("net.sniff.output.rotate.size"); err != nil {
+		return nil, err
+	} else if err, secs = mod.IntParam("net.sniff.output.rotate.time"); err != nil {
+		return nil, err
+	} else if err, out.MaxFiles = mod.IntParam("net.sniff.output.rotate.files"); err != nil {
+		return nil, err
+	} else if err, out.Gzip = mod.BoolParam("net.sniff.output.rotate.gzip"); err != nil {
+		return nil, err
+	}
+	out.MaxSize = int64(size * 1024 * 1024)
+	out.MaxAge = time.Duration(secs) * time.Second
+
+	if out.IsPcapNG() {
+		// there's no session identifier, when it started is unique enough
+		ctx.SessionID = mod.Session.StartedAt.Format("20060102-150405")
+
+		filter := ctx.BPF
+		if filter == "" {
+			filter = ctx.Filter
+		}
+
+		out.section = &pcapgo.NgSectionInfo{
+			Application: fmt.Sprintf("%s v%s", core.Name, core.Version),
+			OS:          fmt.Sprintf("%s %s", runtime.GOOS, runtime.GOARCH),
+			Comment:     fmt.Sprintf("%s session %s", core.Name, ctx.SessionID),
+		}
+		out.intf = &pcapgo.NgInterface{
+			Name:        mod.Session.Interface.Name(),
+			Description: fmt.Sprintf("%s (%s)", mod.Session.Interface.Name(), mod.Session.Interface.HwAddress),
+			Filter:      filter,
+			LinkType:    ctx.Handle.LinkType(),
+			SnapLength:  65536,
+		}
+	}
+
+	return out, nil
+}
+
+func (c *SnifferContext) writeTo(out *captureOutput, pkt gopacket.Packet, protos []string) bool {
+	comment := ""
+	if out.IsPcapNG() && len(protos) > 0 {
+		comment = fmt.Sprintf("%s %s (session %s)", core.Name, strings.Join(protos, ", "), c.SessionID)
+	}
+	if err := out.Write(pkt.Metadata().CaptureInfo, pkt.Data(), comment); err != nil {
+		log.Debug("could not write packet to %s: %v", out.Path(), err)
+		return false
+	}
+	return true
+}
+
+// WritePacket writes the packet to the output file, if any.
+func (c *SnifferContext) WritePacket(pkt gopacket.Packet, protos []string) bool {
+	if c.Capture == nil {
+		return false
+	}
+	return c.writeTo(c.Capture, pkt, protos)
+}
+
+// WriteTaps writes the packet to the output files of the taps it matched.
+func (c *SnifferContext) WriteTaps(pkt gopacket.Packet, protos []string, taps []*SnifferTap) {
+	for _, t := range taps {
+		if t.capture != nil {
+			c.writeTo(t.capture, pkt, protos)
+		}
+	}
 }
 
 func (c *SnifferContext) Close() {
@@ -129,10 +351,18 @@ func (c *SnifferContext) Close() {
 		c.Handle = nil
 	}
 
-	if c.OutputFile != nil {
+	if c.Capture != nil {
 		log.Debug("closing output")
-		c.OutputFile.Close()
+		c.Capture.Close()
 		log.Debug("output closed")
-		c.OutputFile = nil
+		c.Capture = nil
+	}
+
+	for _, t := range c.Taps {
+		if t.capture != nil {
+			t.capture.Close()
+			t.capture = nil
+		}
 	}
+	c.Taps = nil
 }
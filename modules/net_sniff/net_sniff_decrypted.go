@@ -0,0 +1,42 @@
+package net_sniff
+
+import (
+	"sync/atomic"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// subscribeDecrypted feeds the frames decrypted by the wifi module to the
+// same parsers of the sniffed ones.
+func (mod *Sniffer) subscribeDecrypted() {
+	id, err := mod.Session.Bus.Subscribe(mod.Name(), session.TopicDecrypted, mod.onDecrypted)
+	if err != nil {
+		mod.Warning("could not subscribe to decrypted frames: %v", err)
+		return
+	}
+	mod.decryptedSub = id
+}
+
+func (mod *Sniffer) onDecrypted(f session.Fact) {
+	frame, ok := f.Data.(session.DecryptedFrameFact)
+	if !ok || !mod.Running() {
+		return
+	}
+
+	packet := gopacket.NewPacket(frame.Data, layers.LayerTypeEthernet, gopacket.Default)
+	packet.Metadata().Timestamp = frame.Time
+	packet.Metadata().CaptureLength = len(frame.Data)
+	packet.Metadata().Length = len(frame.Data)
+
+	if !mod.Ctx.Match(packet) {
+		return
+	} else if mod.Ctx.Compiled != nil && !mod.Ctx.Compiled.Match(frame.Data) {
+		return
+	}
+
+	atomic.AddUint64(&mod.Stats.NumDecrypted, 1)
+	mainParser(packet, mod.Ctx.Verbose)
+}
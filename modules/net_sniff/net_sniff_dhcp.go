@@ -0,0 +1,148 @@
+package net_sniff
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of transactions tracked while waiting for their ACK
+	dhcpMaxTracked = 4096
+)
+
+var (
+	dhcpLock = sync.Mutex{}
+	// requests of the clients by transaction id
+	dhcpRequests = make(map[uint32]*packets.DHCPInfo)
+)
+
+// feeds what the client told about itself into the host table, adding the
+// host if the ACK just assigned it an address
+func dhcpUpdateHost(mac string, address net.IP, info *packets.DHCPInfo) {
+	if address != nil && !address.IsUnspecified() {
+		session.I.Lan.AddIfNew(address.String(), mac)
+	}
+
+	endpoint, found := session.I.Lan.Get(mac)
+	if !found {
+		return
+	}
+
+	meta := map[string]string{}
+	if info.Hostname != "" {
+		meta["dhcp:hostname"] = info.Hostname
+	}
+	if info.VendorClass != "" {
+		meta["dhcp:vendor"] = info.VendorClass
+	}
+	if info.Fingerprint != "" {
+		meta["dhcp:fingerprint"] = info.Fingerprint
+	}
+	// the guesses of more specific fingerprints win
+	if guess, ok := endpoint.Meta.Get("os").(string); (!ok || guess == "") && info.OS() != "" {
+		meta["os"] = info.OS()
+	}
+
+	if len(meta) > 0 {
+		endpoint.OnMeta(meta)
+	}
+}
+
+func dhcpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.DHCPServerPort && udp.DstPort != packets.DHCPClientPort {
+		return false
+	}
+
+	dhcp, ok := pkt.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok {
+		return false
+	}
+
+	info := packets.ParseDHCPOptions(dhcp)
+	mac := dhcp.ClientHWAddr.String()
+	what := ""
+
+	switch info.Type {
+	case layers.DHCPMsgTypeDiscover, layers.DHCPMsgTypeRequest, layers.DHCPMsgTypeInform:
+		dhcpLock.Lock()
+		if len(dhcpRequests) >= dhcpMaxTracked {
+			dhcpRequests = make(map[uint32]*packets.DHCPInfo)
+		}
+		dhcpRequests[dhcp.Xid] = info
+		dhcpLock.Unlock()
+
+		dhcpUpdateHost(mac, dhcp.ClientIP, info)
+
+		what = tui.Bold(info.Hostname)
+		if info.Requested != nil {
+			what += " wants " + tui.Yellow(info.Requested.String())
+		}
+		if os := info.OS(); os != "" {
+			what += " " + tui.Dim(os)
+		}
+	case layers.DHCPMsgTypeOffer, layers.DHCPMsgTypeAck:
+		if info.Type == layers.DHCPMsgTypeAck {
+			dhcpLock.Lock()
+			request, found := dhcpRequests[dhcp.Xid]
+			delete(dhcpRequests, dhcp.Xid)
+			dhcpLock.Unlock()
+
+			if found {
+				dhcpUpdateHost(mac, dhcp.YourClientIP, request)
+			}
+		}
+
+		what = tui.Yellow(dhcp.YourClientIP.String()) + " to " + tui.Bold(mac)
+		if info.Router != nil {
+			what += tui.Dim(" gw " + info.Router.String())
+		}
+	case layers.DHCPMsgTypeNak, layers.DHCPMsgTypeDecline, layers.DHCPMsgTypeRelease:
+		what = tui.Bold(mac)
+	default:
+		return false
+	}
+
+	dns := make([]string, len(info.DNS))
+	for i, ip := range info.DNS {
+		dns[i] = ip.String()
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"dhcp",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"type":         strings.ToLower(info.Type.String()),
+			"xid":          dhcp.Xid,
+			"mac":          mac,
+			"address":      dhcp.YourClientIP.String(),
+			"requested":    info.Requested,
+			"hostname":     info.Hostname,
+			"vendor_class": info.VendorClass,
+			"fingerprint":  info.Fingerprint,
+			"os":           info.OS(),
+			"server":       info.ServerID,
+			"router":       info.Router,
+			"dns":          dns,
+			"lease_time":   info.LeaseTime,
+		},
+		"%s %s > %s : %s %s",
+		tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, "dhcp"),
+		vIP(srcIP),
+		vIP(dstIP),
+		tui.Blue(strings.ToUpper(info.Type.String())),
+		what,
+	).Push()
+
+	return true
+}
@@ -0,0 +1,222 @@
+package net_sniff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// upper bound of the devices kept in the neighbors table
+const maxNeighbors = 1024
+
+// Neighbor is a device announcing itself with LLDP, CDP or EDP.
+type Neighbor struct {
+	*packets.DiscoveryNeighbor
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type Neighbors struct {
+	sync.RWMutex
+	devices map[string]*Neighbor
+}
+
+func NewNeighbors() *Neighbors {
+	return &Neighbors{
+		devices: make(map[string]*Neighbor),
+	}
+}
+
+func (n *Neighbors) MarshalJSON() ([]byte, error) {
+	n.RLock()
+	defer n.RUnlock()
+	return json.Marshal(n.devices)
+}
+
+func (n *Neighbors) Clear() {
+	n.Lock()
+	defer n.Unlock()
+	n.devices = make(map[string]*Neighbor)
+}
+
+// Update adds or refreshes the device announced by a discovery frame, the
+// same port can speak more than one protocol.
+func (n *Neighbors) Update(d *packets.DiscoveryNeighbor, at time.Time) {
+	n.Lock()
+	defer n.Unlock()
+
+	key := d.Protocol + ":" + d.HW.String()
+	if neighbor, found := n.devices[key]; found {
+		neighbor.DiscoveryNeighbor = d
+		neighbor.LastSeen = at
+	} else if len(n.devices) < maxNeighbors {
+		n.devices[key] = &Neighbor{
+			DiscoveryNeighbor: d,
+			FirstSeen:         at,
+			LastSeen:          at,
+		}
+	}
+}
+
+func neighborVLANs(d *packets.DiscoveryNeighbor) string {
+	vlans := []string{}
+	if d.NativeVLAN != 0 {
+		vlans = append(vlans, fmt.Sprintf("%d", d.NativeVLAN))
+	}
+	if d.VoiceVLAN != 0 {
+		vlans = append(vlans, fmt.Sprintf("voice %d", d.VoiceVLAN))
+	}
+
+	ids := make([]int, 0, len(d.VLANs))
+	for id := range d.VLANs {
+		if id != d.NativeVLAN {
+			ids = append(ids, int(id))
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if name := d.VLANs[uint16(id)]; name != "" {
+			vlans = append(vlans, fmt.Sprintf("%d (%s)", id, name))
+		} else {
+			vlans = append(vlans, fmt.Sprintf("%d", id))
+		}
+	}
+
+	return strings.Join(vlans, ", ")
+}
+
+func neighborEndpoint(d *packets.DiscoveryNeighbor) *network.Endpoint {
+	if endpoint, found := session.I.Lan.Get(d.HW.String()); found {
+		return endpoint
+	} else if d.Address != nil {
+		return session.I.Lan.GetByIp(d.Address.String())
+	}
+	return nil
+}
+
+func onDiscovery(pkt gopacket.Packet, verbose bool) bool {
+	d := packets.ParseDiscovery(pkt)
+	if d == nil {
+		return false
+	}
+
+	vlans := neighborVLANs(d)
+	if endpoint := neighborEndpoint(d); endpoint != nil {
+		meta := map[string]string{
+			d.Protocol + ":hostname": d.Name,
+		}
+		if d.Port != "" {
+			meta[d.Protocol+":port"] = d.Port
+		}
+		if d.Platform != "" {
+			meta[d.Protocol+":platform"] = d.Platform
+		}
+		if vlans != "" {
+			meta[d.Protocol+":vlans"] = vlans
+		}
+		endpoint.OnMeta(meta)
+	}
+
+	address := ""
+	if d.Address != nil {
+		address = d.Address.String()
+	}
+	caps := d.CapabilityNames()
+
+	what := tui.Yellow(d.Name)
+	if d.Port != "" {
+		what += " port " + tui.Bold(d.Port)
+	}
+	if vlans != "" {
+		what += " vlan " + tui.Bold(vlans)
+	}
+	if len(caps) > 0 {
+		what += " " + tui.Dim("["+strings.Join(caps, ", ")+"]")
+	}
+	if d.Platform != "" {
+		what += " " + d.Platform
+	}
+
+	NewSnifferEvent(
+		pkt,
+		d.Protocol,
+		d.HW.String(),
+		address,
+		SniffData{
+			"name":         d.Name,
+			"chassis":      d.Chassis,
+			"port":         d.Port,
+			"description":  d.Description,
+			"platform":     d.Platform,
+			"version":      d.Version,
+			"address":      address,
+			"capabilities": caps,
+			"native_vlan":  d.NativeVLAN,
+			"voice_vlan":   d.VoiceVLAN,
+			"vlans":        d.VLANs,
+			"ttl":          d.TTL,
+		},
+		"%s %s %s",
+		tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, d.Protocol),
+		tui.Bold(d.HW.String()),
+		what,
+	).Push()
+
+	return true
+}
+
+func (mod *Sniffer) showNeighbors() error {
+	mod.Neighbors.RLock()
+	defer mod.Neighbors.RUnlock()
+
+	if len(mod.Neighbors.devices) == 0 {
+		mod.Info("no LLDP, CDP or EDP neighbors yet")
+		return nil
+	}
+
+	list := make([]*Neighbor, 0, len(mod.Neighbors.devices))
+	for _, n := range mod.Neighbors.devices {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Port < list[j].Port
+	})
+
+	rows := [][]string{}
+	for _, n := range list {
+		address := ""
+		if n.Address != nil {
+			address = n.Address.String()
+		}
+		rows = append(rows, []string{
+			tui.Bold(n.Name),
+			n.HW.String(),
+			address,
+			n.Port,
+			neighborVLANs(n.DiscoveryNeighbor),
+			strings.Join(n.CapabilityNames(), ", "),
+			n.Platform,
+			n.Protocol,
+			n.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Device", "MAC", "Address", "Port", "VLANs", "Capabilities", "Platform", "Protocol", "Seen"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func dnp3Objects(frame *packets.DNP3Frame) string {
+	objects := []string{}
+	for _, obj := range frame.Objects {
+		desc := fmt.Sprintf("g%dv%d", obj.Group, obj.Variation)
+		if obj.Count > 0 && obj.Stop > obj.Start {
+			desc += fmt.Sprintf(" [%d-%d]", obj.Start, obj.Stop)
+		} else if obj.Count > 0 {
+			desc += fmt.Sprintf(" x%d", obj.Count)
+		}
+		objects = append(objects, desc)
+	}
+	return strings.Join(objects, ", ")
+}
+
+func dnp3Parser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.DstPort != packets.DNP3Port && tcp.SrcPort != packets.DNP3Port {
+		return false
+	}
+
+	frame, err := packets.ParseDNP3(tcp.Payload)
+	if err != nil {
+		return false
+	}
+
+	outstation := dstIP
+	if tcp.SrcPort == packets.DNP3Port {
+		outstation = srcIP
+	}
+	addMeta(outstation, icsProtocolsMeta, "dnp3")
+
+	// link layer frames and fragment continuations are not worth a line
+	if !frame.First {
+		return true
+	}
+
+	kind := "request"
+	label := tui.Wrap(tui.BACKYELLOW+tui.FOREBLACK, "dnp3")
+	what := fmt.Sprintf("%s %s", tui.Yellow(frame.FunctionName()), dnp3Objects(frame))
+	if frame.IsResponse() {
+		kind = "response"
+		if frame.IIN != 0 {
+			what += tui.Dim(fmt.Sprintf(" iin 0x%04x", frame.IIN))
+		}
+	} else if frame.IsControl() {
+		label = tui.Wrap(tui.BACKRED+tui.FOREBLACK, "dnp3")
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"ics.dnp3",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"type":     kind,
+			"function": frame.FunctionName(),
+			"control":  frame.IsControl(),
+			"frame":    frame,
+		},
+		"%s %s > %s : %d > %d %s",
+		label,
+		vIP(srcIP),
+		vIP(dstIP),
+		frame.Source,
+		frame.Destination,
+		strings.TrimSpace(what),
+	).Push()
+
+	return true
+}
@@ -49,7 +49,7 @@ func dnsParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *la
 
 	for hostname, ips := range m {
 		NewSnifferEvent(
-			pkt.Metadata().Timestamp,
+			pkt,
 			"dns",
 			srcIP.String(),
 			dstIP.String(),
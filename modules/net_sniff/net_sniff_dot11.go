@@ -7,7 +7,7 @@ import (
 
 func onDOT11(radiotap *layers.RadioTap, dot11 *layers.Dot11, pkt gopacket.Packet, verbose bool) {
 	NewSnifferEvent(
-		pkt.Metadata().Timestamp,
+		pkt,
 		"802.11",
 		"-",
 		"-",
@@ -2,9 +2,18 @@ package net_sniff
 
 import (
 	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+
+	"github.com/evilsocket/islazy/tui"
 )
 
 type SniffData map[string]interface{}
@@ -18,18 +27,128 @@ type SnifferEvent struct {
 	Data        interface{} `json:"data"`
 }
 
-func NewSnifferEvent(t time.Time, proto string, src string, dst string, data interface{}, format string, args ...interface{}) SnifferEvent {
+func init() {
+	// the data depends on the protocol, usually a SniffData with its fields
+	session.RegisterEventSchema("net.sniff.", 1, "A packet parsed by the sniffer, the tag ends with its protocol.", SnifferEvent{})
+	session.RegisterEventSchema("net.sniff.output.rotated", 1, "The sniffer output file has been rotated.", OutputRotation{})
+	session.RegisterEventSchema("net.profile.anomaly", 1, "A host is speaking a protocol for the first time.", ProfileAnomaly{})
+}
+
+// the protocols reported by the parsers for the captured packet being
+// parsed, to comment it in the pcapng output
+type packetAnnotations struct {
+	sync.Mutex
+	active bool
+	at     time.Time
+	protos []string
+}
+
+var annotations = &packetAnnotations{}
+
+func (a *packetAnnotations) Begin(pkt gopacket.Packet) {
+	a.Lock()
+	defer a.Unlock()
+	a.active = true
+	a.at = pkt.Metadata().Timestamp
+	a.protos = nil
+}
+
+// decrypted packets are parsed concurrently, their timestamps differ
+func (a *packetAnnotations) Add(pkt gopacket.Packet, proto string) {
+	a.Lock()
+	defer a.Unlock()
+	if !a.active || !pkt.Metadata().Timestamp.Equal(a.at) {
+		return
+	}
+	for _, p := range a.protos {
+		if p == proto {
+			return
+		}
+	}
+	a.protos = append(a.protos, proto)
+}
+
+func (a *packetAnnotations) End() []string {
+	a.Lock()
+	defer a.Unlock()
+	a.active = false
+	return a.protos
+}
+
+func NewSnifferEvent(pkt gopacket.Packet, proto string, src string, dst string, data interface{}, format string, args ...interface{}) SnifferEvent {
+	annotations.Add(pkt, proto)
+
+	message := fmt.Sprintf(format, args...)
+
+	// the 802.1Q tags of trunk ports, the outer one is the service tag of QinQ
+	fields, isFields := data.(SniffData)
+	if isFields {
+		if ids := packets.VLANIDs(pkt); len(ids) > 0 {
+			fields["vlan"] = ids[len(ids)-1]
+			if len(ids) > 1 {
+				fields["outer_vlan"] = ids[0]
+			}
+		}
+	}
+
+	// decapsulated from an overlay
+	if tunneled, ok := pkt.(tunneledPacket); ok {
+		tunnel := tunneled.Tunnel()
+		if isFields {
+			fields["tunnel"] = tunnel
+		}
+		message = tui.Dim("["+tunnel+"]") + " " + message
+	}
+
 	return SnifferEvent{
-		PacketTime:  t,
+		PacketTime:  pkt.Metadata().Timestamp,
 		Protocol:    proto,
 		Source:      src,
 		Destination: dst,
-		Message:     fmt.Sprintf(format, args...),
+		Message:     message,
 		Data:        data,
 	}
 }
 
+// addMeta adds the value to the sorted, comma separated ones of a metadata
+// field of the host, if it's known.
+func addMeta(ip net.IP, name string, value string) {
+	endpoint := session.I.Lan.GetByIp(ip.String())
+	if endpoint == nil || value == "" {
+		return
+	}
+
+	values := []string{}
+	if existing, ok := endpoint.Meta.Get(name).(string); ok && existing != "" {
+		values = strings.Split(existing, ",")
+	}
+	for _, v := range values {
+		if v == value {
+			return
+		}
+	}
+
+	values = append(values, value)
+	sort.Strings(values)
+	endpoint.Meta.Set(name, strings.Join(values, ","))
+}
+
+// publish credentials seen by the sniffer on the facts bus
+func publishCredential(proto string, client net.IP, server string, port int, username string, password string) {
+	session.I.Bus.Publish(session.TopicCredential+"."+proto, "net.sniff", session.CredentialFact{
+		Client:   client.String(),
+		Server:   server,
+		Port:     port,
+		Protocol: proto,
+		Username: username,
+		Password: password,
+	})
+}
+
 func (e SnifferEvent) Push() {
+	if !limiter.Allow(e.Source) {
+		return
+	}
 	session.I.Events.Add("net.sniff."+e.Protocol, e)
 	session.I.Refresh()
 }
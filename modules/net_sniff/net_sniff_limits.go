@@ -0,0 +1,98 @@
+package net_sniff
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/log"
+)
+
+// caps the events per second of every source host, the sniffer parsers
+// push their events without any reference to the module so this is global
+// like session.I
+type eventLimiter struct {
+	sync.Mutex
+	rate    int
+	second  int64
+	counts  map[string]int
+	dropped map[string]uint64
+	total   uint64
+}
+
+var limiter *eventLimiter
+
+func newEventLimiter(rate int) *eventLimiter {
+	return &eventLimiter{
+		rate:    rate,
+		counts:  make(map[string]int),
+		dropped: make(map[string]uint64),
+	}
+}
+
+// Allow returns false if the host already sent rate events in the current second.
+func (l *eventLimiter) Allow(host string) bool {
+	if l == nil || l.rate <= 0 {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if now := time.Now().Unix(); now != l.second {
+		l.second = now
+		l.counts = make(map[string]int)
+	}
+
+	if l.counts[host]++; l.counts[host] > l.rate {
+		if l.dropped[host] == 0 {
+			log.Debug("%s is sending more than %d events per second, rate limiting", host, l.rate)
+		}
+		l.dropped[host]++
+		l.total++
+		return false
+	}
+	return true
+}
+
+func (l *eventLimiter) Print() {
+	if l == nil || l.rate <= 0 {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	log.Info("Rate Limited Events: %d", l.total)
+
+	hosts := make([]string, 0, len(l.dropped))
+	for host := range l.dropped {
+		hosts = append(hosts, host)
+	}
+
+	sort.Slice(hosts, func(i, j int) bool {
+		return l.dropped[hosts[i]] > l.dropped[hosts[j]]
+	})
+
+	for i, host := range hosts {
+		if i == 10 {
+			log.Info("  ... and %d more hosts", len(hosts)-i)
+			break
+		}
+		log.Info("  %-16s : %d", host, l.dropped[host])
+	}
+}
+
+// returns true if the packet has to be processed
+func (mod *Sniffer) sample() bool {
+	if mod.Ctx.Sample <= 1 {
+		return true
+	}
+
+	mod.sampled++
+	if mod.sampled%uint64(mod.Ctx.Sample) != 0 {
+		mod.Stats.NumSkipped++
+		return false
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package net_sniff
+
+import (
+	"net"
+	"strings"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// endpoint meta with the broadcast name resolution protocols used by a
+	// host, the ones poisoned by Responder-like attacks
+	nameResolutionMeta = "name.resolution"
+)
+
+func llmnrParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.LLMNRPort && udp.SrcPort != packets.LLMNRPort {
+		return false
+	}
+
+	dns, err := packets.ParseLLMNR(udp.Payload)
+	if err != nil || len(dns.Questions) == 0 {
+		return false
+	}
+
+	name := string(dns.Questions[0].Name)
+	qtype := dns.Questions[0].Type.String()
+
+	if !dns.QR {
+		addMeta(srcIP, nameResolutionMeta, "llmnr")
+
+		NewSnifferEvent(
+			pkt,
+			"llmnr",
+			srcIP.String(),
+			dstIP.String(),
+			SniffData{
+				"type":  "query",
+				"name":  name,
+				"qtype": qtype,
+			},
+			"%s %s > %s : who has %s %s",
+			tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, "llmnr"),
+			vIP(srcIP),
+			vIP(dstIP),
+			tui.Yellow(name),
+			tui.Dim(qtype),
+		).Push()
+		return true
+	}
+
+	addresses := []string{}
+	for _, answer := range dns.Answers {
+		if answer.IP != nil {
+			addresses = append(addresses, answer.IP.String())
+		}
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"llmnr",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"type":      "response",
+			"name":      name,
+			"qtype":     qtype,
+			"addresses": addresses,
+		},
+		"%s %s > %s : %s is %s",
+		tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, "llmnr"),
+		vIP(srcIP),
+		vIP(dstIP),
+		tui.Yellow(name),
+		tui.Dim(strings.Join(addresses, ", ")),
+	).Push()
+
+	return true
+}
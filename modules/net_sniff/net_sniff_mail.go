@@ -0,0 +1,247 @@
+package net_sniff
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of sessions tracked while waiting for the credentials
+	mailMaxTracked = 4096
+)
+
+const (
+	mailIdle = iota
+	mailPlain
+	mailLoginUser
+	mailLoginPass
+)
+
+var mailPorts = map[layers.TCPPort]string{
+	25:   "smtp",
+	587:  "smtp",
+	2525: "smtp",
+	110:  "pop3",
+	143:  "imap",
+}
+
+// a client in the middle of a multi step AUTH exchange
+type mailSession struct {
+	state     int
+	mechanism string
+	user      string
+}
+
+var (
+	mailLock     = sync.Mutex{}
+	mailSessions = make(map[string]*mailSession)
+)
+
+func mailDecode(s string) (string, bool) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return "", false
+	}
+	return string(raw), true
+}
+
+// authzid \0 authcid \0 password
+func mailDecodePlain(s string) (string, string, bool) {
+	if raw, ok := mailDecode(s); ok {
+		if parts := strings.Split(raw, "\x00"); len(parts) == 3 {
+			return parts[1], parts[2], true
+		}
+	}
+	return "", "", false
+}
+
+// IMAP arguments are atoms or quoted strings
+func imapArgs(line string) []string {
+	args := []string{}
+	buf := bytes.Buffer{}
+	quoted, escaped, started := false, false, false
+	for _, c := range line {
+		switch {
+		case escaped:
+			buf.WriteRune(c)
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+			started = true
+		case c == ' ' && !quoted:
+			if started || buf.Len() > 0 {
+				args = append(args, buf.String())
+			}
+			buf.Reset()
+			started = false
+		default:
+			buf.WriteRune(c)
+		}
+	}
+	if started || buf.Len() > 0 {
+		args = append(args, buf.String())
+	}
+	return args
+}
+
+func mailEvent(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, proto string, data SniffData, format string, args ...interface{}) {
+	NewSnifferEvent(
+		pkt,
+		proto,
+		srcIP.String(),
+		dstIP.String(),
+		data,
+		"%s %s > %s:%s - %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, proto),
+		vIP(srcIP),
+		vIP(dstIP),
+		vPort(tcp.DstPort),
+		fmt.Sprintf(format, args...),
+	).Push()
+}
+
+func mailCredential(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, proto string, mechanism string, user string, pass string) {
+	mailEvent(pkt, srcIP, dstIP, tcp, proto, SniffData{
+		"mechanism": mechanism,
+		"user":      user,
+		"pass":      pass,
+	}, "%s %s %s", tui.Dim(mechanism), tui.Bold(user), tui.Red(pass))
+
+	publishCredential(proto, srcIP, dstIP.String(), int(tcp.DstPort), user, pass)
+}
+
+// the AUTH or AUTHENTICATE command with its mechanism and optional initial response
+func mailAuth(sess *mailSession, args []string) {
+	sess.state = mailIdle
+	sess.mechanism = strings.ToUpper(args[0])
+	switch sess.mechanism {
+	case "PLAIN":
+		sess.state = mailPlain
+	case "LOGIN":
+		sess.state = mailLoginUser
+	}
+}
+
+func mailLine(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, proto string, sess *mailSession, line string) bool {
+	line = strings.TrimSpace(line)
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+
+	// continuation of an AUTH exchange
+	if sess.state != mailIdle {
+		if fields[0] == "*" {
+			sess.state = mailIdle
+			return true
+		}
+
+		switch sess.state {
+		case mailPlain:
+			sess.state = mailIdle
+			if user, pass, ok := mailDecodePlain(fields[0]); ok {
+				mailCredential(pkt, srcIP, dstIP, tcp, proto, sess.mechanism, user, pass)
+				return true
+			}
+		case mailLoginUser:
+			if user, ok := mailDecode(fields[0]); ok {
+				sess.user = user
+				sess.state = mailLoginPass
+				return true
+			}
+			sess.state = mailIdle
+		case mailLoginPass:
+			sess.state = mailIdle
+			if pass, ok := mailDecode(fields[0]); ok {
+				mailCredential(pkt, srcIP, dstIP, tcp, proto, sess.mechanism, sess.user, pass)
+				return true
+			}
+		}
+	}
+
+	// IMAP commands are prefixed by a tag
+	if proto == "imap" && len(fields) > 1 {
+		fields = fields[1:]
+		line = strings.TrimSpace(line[strings.IndexAny(line, " \t"):])
+	}
+
+	command := strings.ToUpper(fields[0])
+	switch {
+	case command == "STARTTLS" || command == "STLS":
+		mailEvent(pkt, srcIP, dstIP, tcp, proto+".starttls", nil, "%s", tui.Yellow("STARTTLS"))
+		return true
+
+	case (command == "AUTH" || command == "AUTHENTICATE") && len(fields) > 1:
+		mailAuth(sess, fields[1:])
+		if len(fields) > 2 && fields[2] != "=" {
+			// initial response sent along with the command
+			mailLine(pkt, srcIP, dstIP, tcp, proto, sess, fields[2])
+		}
+		return true
+
+	case proto == "pop3" && command == "USER" && len(fields) > 1:
+		sess.user = strings.TrimSpace(line[len(fields[0]):])
+		return true
+
+	case proto == "pop3" && command == "PASS" && len(fields) > 1 && sess.user != "":
+		mailCredential(pkt, srcIP, dstIP, tcp, proto, "USER", sess.user, strings.TrimSpace(line[len(fields[0]):]))
+		sess.user = ""
+		return true
+
+	case proto == "imap" && command == "LOGIN":
+		if args := imapArgs(line); len(args) == 3 {
+			mailCredential(pkt, srcIP, dstIP, tcp, proto, "LOGIN", args[1], args[2])
+			return true
+		}
+	}
+
+	return false
+}
+
+func mailParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	// only what the clients send
+	proto, found := mailPorts[tcp.DstPort]
+	if !found || len(tcp.Payload) == 0 {
+		return false
+	}
+
+	key := fmt.Sprintf("%s:%d>%s:%d", srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+
+	mailLock.Lock()
+	defer mailLock.Unlock()
+
+	sess, found := mailSessions[key]
+	if !found {
+		if len(mailSessions) >= mailMaxTracked {
+			mailSessions = make(map[string]*mailSession)
+		}
+		sess = &mailSession{}
+		mailSessions[key] = sess
+	}
+
+	parsed := false
+	for _, line := range strings.Split(string(tcp.Payload), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			if mailLine(pkt, srcIP, dstIP, tcp, proto, sess, line) {
+				parsed = true
+			}
+		}
+	}
+
+	if tcp.FIN || tcp.RST {
+		delete(mailSessions, key)
+	}
+
+	return parsed
+}
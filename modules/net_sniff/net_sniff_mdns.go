@@ -19,7 +19,7 @@ func mdnsParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *l
 		if err := dns.DecodeFromBytes(udp.Payload, gopacket.NilDecodeFeedback); err == nil && dns.OpCode == layers.DNSOpCodeQuery {
 			for _, q := range dns.Questions {
 				NewSnifferEvent(
-					pkt.Metadata().Timestamp,
+					pkt,
 					"mdns",
 					srcIP.String(),
 					dstIP.String(),
@@ -55,7 +55,7 @@ func mdnsParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *l
 				}
 
 				NewSnifferEvent(
-					pkt.Metadata().Timestamp,
+					pkt,
 					"mdns",
 					srcIP.String(),
 					dstIP.String(),
@@ -0,0 +1,86 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// endpoint meta with the industrial protocols served by a host
+	icsProtocolsMeta = "ics.protocols"
+	// maximum number of values printed for each message
+	modbusMaxShown = 8
+)
+
+func modbusValues(msg *packets.ModbusMessage) string {
+	if len(msg.Values) == 0 {
+		return ""
+	} else if len(msg.Values) > modbusMaxShown {
+		return fmt.Sprintf("%v %s", msg.Values[:modbusMaxShown], tui.Dim(fmt.Sprintf("(+%d)", len(msg.Values)-modbusMaxShown)))
+	}
+	return fmt.Sprintf("%v", msg.Values)
+}
+
+func modbusParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.DstPort != packets.ModbusPort && tcp.SrcPort != packets.ModbusPort {
+		return false
+	}
+
+	request := tcp.DstPort == packets.ModbusPort
+	msgs, err := packets.ParseModbus(tcp.Payload, request)
+	if err != nil || len(msgs) == 0 {
+		return false
+	}
+
+	server := srcIP
+	if request {
+		server = dstIP
+	}
+	addMeta(server, icsProtocolsMeta, "modbus")
+
+	for _, msg := range msgs {
+		kind := "response"
+		label := tui.Wrap(tui.BACKYELLOW+tui.FOREBLACK, "modbus")
+		what := fmt.Sprintf("%s %s", tui.Yellow(msg.FunctionName()), modbusValues(msg))
+		if request {
+			kind = "request"
+			what = fmt.Sprintf("%s @%d x%d %s", tui.Yellow(msg.FunctionName()), msg.Address, msg.Quantity, modbusValues(msg))
+			if msg.IsWrite() {
+				label = tui.Wrap(tui.BACKRED+tui.FOREBLACK, "modbus")
+			}
+		} else if msg.Exception != 0 {
+			kind = "exception"
+			what = fmt.Sprintf("%s %s", tui.Yellow(msg.FunctionName()), tui.Red(msg.ExceptionName()))
+		}
+
+		NewSnifferEvent(
+			pkt,
+			"ics.modbus",
+			srcIP.String(),
+			dstIP.String(),
+			SniffData{
+				"type":      kind,
+				"unit":      msg.UnitID,
+				"function":  msg.FunctionName(),
+				"write":     msg.IsWrite(),
+				"exception": msg.Exception,
+				"message":   msg,
+			},
+			"%s %s > %s : unit %d %s",
+			label,
+			vIP(srcIP),
+			vIP(dstIP),
+			msg.UnitID,
+			what,
+		).Push()
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+package net_sniff
+
+import (
+	"net"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func mssqlParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.DstPort != packets.TDSPort {
+		return false
+	}
+
+	login, err := packets.ParseTDSLogin(tcp.Payload)
+	if err != nil {
+		return false
+	}
+
+	method := "sql"
+	if login.SSPI {
+		method = "sspi"
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"mssql",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"method":      method,
+			"hostname":    login.Hostname,
+			"user":        login.User,
+			"password":    login.Password,
+			"application": login.Application,
+			"server":      login.Server,
+			"database":    login.Database,
+		},
+		"%s %s > %s:%s - %s %s %s %s %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "mssql"),
+		vIP(srcIP),
+		vIP(dstIP),
+		vPort(tcp.DstPort),
+		tui.Dim(method),
+		tui.Bold(login.User),
+		tui.Red(login.Password),
+		tui.Yellow(login.Database),
+		tui.Dim(login.Application),
+	).Push()
+
+	// integrated logins are handled by the NTLM parser
+	if !login.SSPI && login.User != "" {
+		publishCredential("mssql", srcIP, dstIP.String(), int(tcp.DstPort), login.User, login.Password)
+	}
+
+	return true
+}
@@ -0,0 +1,94 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of greetings tracked while waiting for the login
+	mysqlMaxTracked = 4096
+)
+
+var (
+	mysqlLock  = sync.Mutex{}
+	mysqlSalts = make(map[string][]byte)
+)
+
+func mysqlEvent(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, data SniffData, format string, args ...interface{}) {
+	NewSnifferEvent(
+		pkt,
+		"mysql",
+		srcIP.String(),
+		dstIP.String(),
+		data,
+		"%s %s > %s:%s - %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "mysql"),
+		vIP(srcIP),
+		vIP(dstIP),
+		vPort(tcp.DstPort),
+		fmt.Sprintf(format, args...),
+	).Push()
+}
+
+func mysqlParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.SrcPort == packets.MySQLPort {
+		// the greeting of the server with the salt
+		if hs, err := packets.ParseMySQLHandshake(tcp.Payload); err == nil {
+			mysqlLock.Lock()
+			if len(mysqlSalts) >= mysqlMaxTracked {
+				mysqlSalts = make(map[string][]byte)
+			}
+			mysqlSalts[fmt.Sprintf("%s:%d>%s:%d", dstIP, tcp.DstPort, srcIP, tcp.SrcPort)] = hs.Salt
+			mysqlLock.Unlock()
+		}
+		return false
+	} else if tcp.DstPort != packets.MySQLPort {
+		return false
+	}
+
+	if db, err := packets.ParseMySQLInitDB(tcp.Payload); err == nil {
+		mysqlEvent(pkt, srcIP, dstIP, tcp, SniffData{
+			"database": db,
+		}, "use %s", tui.Bold(db))
+		return true
+	}
+
+	login, err := packets.ParseMySQLLogin(tcp.Payload)
+	if err != nil || login.SSL {
+		return false
+	}
+
+	key := fmt.Sprintf("%s:%d>%s:%d", srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+	mysqlLock.Lock()
+	salt := mysqlSalts[key]
+	delete(mysqlSalts, key)
+	mysqlLock.Unlock()
+
+	hash, err := login.Hashcat(salt)
+	if err != nil {
+		// another plugin, or we missed the greeting
+		hash = fmt.Sprintf("%x", login.Response)
+	}
+
+	mysqlEvent(pkt, srcIP, dstIP, tcp, SniffData{
+		"user":     login.User,
+		"database": login.Database,
+		"plugin":   login.Plugin,
+		"hash":     hash,
+	}, "%s %s %s %s", tui.Dim(login.Plugin), tui.Bold(login.User), tui.Red(hash), tui.Yellow(login.Database))
+
+	if err == nil {
+		publishCredential("mysql", srcIP, dstIP.String(), int(tcp.DstPort), login.User, hash)
+	}
+
+	return true
+}
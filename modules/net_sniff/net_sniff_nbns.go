@@ -0,0 +1,79 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func nbnsParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.NBNSPort && udp.SrcPort != packets.NBNSPort {
+		return false
+	}
+
+	msg, err := packets.NBNSParseMessage(udp.Payload)
+	if err != nil || (msg.Opcode != packets.NBNSOpQuery && msg.Opcode != packets.NBNSOpRegistration) {
+		return false
+	}
+
+	// <NAME> with the suffix telling the service, as shown by nbtstat
+	name := fmt.Sprintf("%s<%02x>", msg.Name, msg.Suffix)
+	kind, what := "query", ""
+	addresses := make([]string, len(msg.Addresses))
+	for i, ip := range msg.Addresses {
+		addresses[i] = ip.String()
+	}
+
+	switch {
+	case msg.Opcode == packets.NBNSOpRegistration && !msg.Response:
+		kind = "registration"
+		what = fmt.Sprintf("%s registers %s", tui.Yellow(name), tui.Dim(strings.Join(addresses, ", ")))
+	case msg.Response:
+		kind = "response"
+		if msg.Type == packets.NBNSTypeNBSTAT {
+			if status, err := packets.NBNSParseNodeStatus(udp.Payload); err == nil && status.Hostname() != "" {
+				name = status.Hostname()
+			}
+			what = fmt.Sprintf("node status of %s", tui.Yellow(name))
+		} else {
+			what = fmt.Sprintf("%s is %s", tui.Yellow(name), tui.Dim(strings.Join(addresses, ", ")))
+		}
+	case msg.Type == packets.NBNSTypeNBSTAT:
+		what = fmt.Sprintf("node status of %s", tui.Yellow(dstIP.String()))
+	default:
+		addMeta(srcIP, nameResolutionMeta, "nbns")
+		what = fmt.Sprintf("who has %s", tui.Yellow(name))
+	}
+
+	if msg.Broadcast {
+		what += tui.Dim(" (broadcast)")
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"nbns",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"type":      kind,
+			"name":      msg.Name,
+			"suffix":    msg.Suffix,
+			"broadcast": msg.Broadcast,
+			"addresses": addresses,
+		},
+		"%s %s > %s : %s",
+		tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, "nbns"),
+		vIP(srcIP),
+		vIP(dstIP),
+		what,
+	).Push()
+
+	return true
+}
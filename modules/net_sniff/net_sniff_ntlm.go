@@ -49,7 +49,7 @@ func ntlmParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *l
 				ok = true
 				ntlm.AddClientResponse(tcp.Seq, tokens[2], func(data packets.NTLMChallengeResponseParsed) {
 					NewSnifferEvent(
-						pkt.Metadata().Timestamp,
+						pkt,
 						"ntlm.response",
 						srcIP.String(),
 						dstIP.String(),
@@ -60,6 +60,8 @@ func ntlmParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *l
 						vIP(dstIP),
 						data.LcString(),
 					).Push()
+
+					publishCredential("ntlm", srcIP, dstIP.String(), int(tcp.DstPort), data.Domain+"\\"+data.User, strings.TrimSpace(data.LcString()))
 				})
 			}
 		}
@@ -0,0 +1,86 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// endpoint meta with the time servers used by a host
+	ntpServersMeta = "ntp.servers"
+	// endpoint meta with the mode 6 and 7 requests a server answered
+	ntpAmplificationMeta = "ntp.amplification"
+)
+
+func ntpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.NTPPort && udp.SrcPort != packets.NTPPort {
+		return false
+	}
+
+	p, err := packets.ParseNTP(udp.Payload)
+	if err != nil {
+		return false
+	}
+
+	label := tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, "ntp")
+	what := ""
+	if p.IsManagement() {
+		label = tui.Wrap(tui.BACKRED+tui.FOREBLACK, "ntp")
+		kind := "request"
+		if p.Response {
+			kind = "response"
+			addMeta(srcIP, ntpAmplificationMeta, p.OpcodeName())
+		}
+		what = fmt.Sprintf("%s %s %s %s", tui.Yellow(p.ModeName()), tui.Bold(p.OpcodeName()), kind,
+			tui.Red("amplification vector"))
+	} else {
+		switch p.Mode {
+		case packets.NTPModeClient:
+			addMeta(srcIP, ntpServersMeta, dstIP.String())
+		case packets.NTPModeServer:
+			addMeta(dstIP, ntpServersMeta, srcIP.String())
+		}
+
+		what = tui.Yellow(p.ModeName())
+		if p.Mode != packets.NTPModeClient {
+			what += fmt.Sprintf(" stratum %d", p.Stratum)
+			if p.ReferenceID != "" {
+				what += " ref " + p.ReferenceID
+			}
+		}
+		if !p.Transmit.IsZero() {
+			what += " " + tui.Dim(p.Transmit.Format("2006-01-02 15:04:05 MST"))
+		}
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"ntp",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"version":       p.Version,
+			"mode":          p.ModeName(),
+			"amplification": p.IsManagement(),
+			"monlist":       p.IsMonlist(),
+			"size":          len(udp.Payload),
+			"packet":        p,
+		},
+		"%s %s > %s : v%d %s %s",
+		label,
+		vIP(srcIP),
+		vIP(dstIP),
+		p.Version,
+		what,
+		tui.Dim(fmt.Sprintf("%d bytes", len(udp.Payload))),
+	).Push()
+
+	return true
+}
@@ -0,0 +1,75 @@
+package net_sniff
+
+import (
+	"net"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+)
+
+// how often the kernel filter is checked against the active targets
+const offloadPeriod = 2 * time.Second
+
+// returns the arp.spoof targets while the spoofer is running
+func (mod *Sniffer) spoofTargets() []net.IP {
+	err, m := mod.Session.Module("arp.spoof")
+	if err != nil || !m.Running() {
+		return nil
+	}
+
+	list, _ := m.Extra()["targets"].([]string)
+	targets := make([]net.IP, 0, len(list))
+	for _, ip := range list {
+		if addr := net.ParseIP(ip); addr != nil {
+			targets = append(targets, addr)
+		}
+	}
+	return targets
+}
+
+// the user BPF filter narrowed down to what would be dropped in userspace anyway:
+// our own traffic if net.sniff.local is false and, while arp.spoof is running,
+// anything that is not from or to its targets; in both cases extended to the
+// tagged frames of trunk ports
+func (mod *Sniffer) kernelFilter(ctx *SnifferContext) string {
+	if !ctx.Offload {
+		return packets.BPFVLAN(ctx.BPF)
+	}
+
+	local := ""
+	if !ctx.DumpLocal && !mod.fuzzActive && mod.Session.Interface.IP != nil {
+		local = "not host " + mod.Session.Interface.IP.String()
+		if mod.Session.Interface.IPv6 != nil {
+			local += " and not host " + mod.Session.Interface.IPv6.String()
+		}
+	}
+
+	return packets.BPFVLAN(packets.BPFAnd(ctx.BPF, local, packets.BPFHosts(mod.spoofTargets(), nil)))
+}
+
+func (mod *Sniffer) setKernelFilter(ctx *SnifferContext) error {
+	filter := mod.kernelFilter(ctx)
+	if filter == ctx.Kernel {
+		return nil
+	} else if err := ctx.Handle.SetBPFFilter(filter); err != nil {
+		return err
+	}
+
+	mod.Debug("kernel filter set to '%s'", filter)
+	ctx.Kernel = filter
+	return nil
+}
+
+// keeps the kernel filter in sync with the targets
+func (mod *Sniffer) offloadWorker(ctx *SnifferContext) {
+	ticker := time.NewTicker(offloadPeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !mod.Running() || mod.Ctx != ctx || ctx.Handle == nil {
+			return
+		} else if err := mod.setKernelFilter(ctx); err != nil {
+			mod.Warning("could not update the kernel filter: %v", err)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package net_sniff
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/log"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+const (
+	outputTimeFormat = "20060102-150405"
+	// placeholders of the output file name
+	outputTimestampVar = "{timestamp}"
+	outputIfaceVar     = "{iface}"
+)
+
+// OutputRotation is the event of a capture file being rotated.
+type OutputRotation struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Packets uint64 `json:"packets"`
+	Next    string `json:"next"`
+}
+
+// captureOutput writes the captured packets to a pcap or pcapng file,
+// rotating it by size or time if configured to do so
+type captureOutput struct {
+	sync.Mutex
+	template string
+	iface    string
+	linkType layers.LinkType
+	// if set the files are pcapng
+	section *pcapgo.NgSectionInfo
+	intf    *pcapgo.NgInterface
+
+	MaxSize  int64
+	MaxAge   time.Duration
+	MaxFiles int
+	Gzip     bool
+
+	path    string
+	file    *os.File
+	pcap    *pcapgo.Writer
+	ng      *packets.PcapNGWriter
+	size    int64
+	packets uint64
+	opened  time.Time
+	// rotated files, from the oldest
+	archived []string
+	archiver sync.WaitGroup
+}
+
+func newCaptureOutput(template string, iface string, linkType layers.LinkType) *captureOutput {
+	return &captureOutput{
+		template: template,
+		iface:    iface,
+		linkType: linkType,
+	}
+}
+
+func (o *captureOutput) Rotates() bool {
+	return o.MaxSize > 0 || o.MaxAge > 0
+}
+
+func (o *captureOutput) IsPcapNG() bool {
+	return strings.HasSuffix(strings.ToLower(o.template), ".pcapng")
+}
+
+// the file name for a capture started at the given time, rotated files
+// get a timestamp even if the template has none
+func (o *captureOutput) fileName(at time.Time) string {
+	name := strings.Replace(o.template, outputIfaceVar, o.iface, -1)
+	if strings.Contains(name, outputTimestampVar) {
+		name = strings.Replace(name, outputTimestampVar, at.Format(outputTimeFormat), -1)
+	} else if o.Rotates() {
+		ext := filepath.Ext(name)
+		name = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), at.Format(outputTimeFormat), ext)
+	}
+
+	// more rotations in the same second
+	path := name
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) || !o.Rotates() {
+			return path
+		}
+		ext := filepath.Ext(name)
+		path = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+}
+
+func (o *captureOutput) open(at time.Time) (err error) {
+	o.path = o.fileName(at)
+	if o.file, err = os.Create(o.path); err != nil {
+		return err
+	}
+
+	o.pcap, o.ng = nil, nil
+	if o.section != nil {
+		o.ng, err = packets.NewPcapNGWriter(o.file, *o.section, *o.intf)
+	} else {
+		o.pcap = pcapgo.NewWriter(o.file)
+		err = o.pcap.WriteFileHeader(65536, o.linkType)
+	}
+
+	o.size, o.packets, o.opened = 0, 0, at
+	return err
+}
+
+// Open creates the first file.
+func (o *captureOutput) Open() error {
+	o.Lock()
+	defer o.Unlock()
+	return o.open(time.Now())
+}
+
+func (o *captureOutput) Path() string {
+	o.Lock()
+	defer o.Unlock()
+	return o.path
+}
+
+// Write writes a packet, rotating the file first if needed.
+func (o *captureOutput) Write(ci gopacket.CaptureInfo, data []byte, comment string) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.file == nil {
+		return os.ErrClosed
+	}
+
+	at := ci.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if o.packets > 0 && ((o.MaxSize > 0 && o.size >= o.MaxSize) || (o.MaxAge > 0 && at.Sub(o.opened) >= o.MaxAge)) {
+		if err := o.rotate(at); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if o.ng != nil {
+		err = o.ng.WritePacket(ci, data, comment)
+	} else {
+		err = o.pcap.WritePacket(ci, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	o.packets++
+	if pos, err := o.file.Seek(0, io.SeekCurrent); err == nil {
+		o.size = pos
+	}
+	return nil
+}
+
+func (o *captureOutput) rotate(at time.Time) error {
+	rotated := OutputRotation{
+		Path:    o.path,
+		Size:    o.size,
+		Packets: o.packets,
+	}
+
+	if err := o.file.Close(); err != nil {
+		return err
+	}
+	o.file = nil
+
+	// compressing can take a while, the capture goes on meanwhile
+	o.archiver.Add(1)
+	go o.archive(o.path)
+
+	if err := o.open(at); err != nil {
+		return err
+	}
+
+	rotated.Next = o.path
+	session.I.Events.Add("net.sniff.output.rotated", rotated)
+	return nil
+}
+
+func gzipFile(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	name := path + ".gz"
+	out, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	gz.Name = filepath.Base(path)
+	if _, err = io.Copy(gz, in); err != nil {
+		return "", err
+	} else if err = gz.Close(); err != nil {
+		return "", err
+	}
+
+	return name, os.Remove(path)
+}
+
+// compresses a closed file if needed and deletes the oldest ones if
+// there are too many
+func (o *captureOutput) archive(path string) {
+	defer o.archiver.Done()
+
+	if o.Gzip {
+		if name, err := gzipFile(path); err != nil {
+			log.Error("could not compress %s: %v", path, err)
+		} else {
+			path = name
+		}
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	o.archived = append(o.archived, path)
+	// the file being written counts as well
+	for o.MaxFiles > 0 && len(o.archived) >= o.MaxFiles {
+		if err := os.Remove(o.archived[0]); err != nil && !os.IsNotExist(err) {
+			log.Error("could not delete %s: %v", o.archived[0], err)
+		}
+		o.archived = o.archived[1:]
+	}
+}
+
+// Close closes the current file, compressing it if needed.
+func (o *captureOutput) Close() {
+	o.Lock()
+	if o.file == nil {
+		o.Unlock()
+		return
+	}
+	o.file.Close()
+	o.file = nil
+	path := o.path
+	o.Unlock()
+
+	if o.Rotates() {
+		o.archiver.Add(1)
+		o.archive(path)
+	}
+	o.archiver.Wait()
+}
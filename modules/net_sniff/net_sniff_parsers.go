@@ -17,7 +17,7 @@ func onUNK(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, verbose boo
 	if verbose {
 		sz := len(payload)
 		NewSnifferEvent(
-			pkt.Metadata().Timestamp,
+			pkt,
 			pkt.TransportLayer().LayerType().String(),
 			vIP(srcIP),
 			vIP(dstIP),
@@ -40,6 +40,11 @@ func mainParser(pkt gopacket.Packet, verbose bool) bool {
 		}
 	}()
 
+	// parse what overlays carry instead of the tunnels themselves
+	if inner := decapsulate(pkt); inner != nil {
+		return mainParser(inner, verbose)
+	}
+
 	// simple networking sniffing mode?
 	nlayer := pkt.NetworkLayer()
 	if nlayer != nil {
@@ -69,6 +74,9 @@ func mainParser(pkt gopacket.Packet, verbose bool) bool {
 
 		tlayer := pkt.TransportLayer()
 		if tlayer == nil {
+			if onICMP(srcIP, dstIP, basePayload, pkt, verbose) {
+				return true
+			}
 			log.Debug("Missing transport layer skipping packet.")
 			log.Debug("%s", pkt.Dump())
 			return false
@@ -86,6 +94,9 @@ func mainParser(pkt gopacket.Packet, verbose bool) bool {
 		// are we sniffing in monitor mode?
 		onDOT11(radiotap, dot11, pkt, verbose)
 		return true
+	} else if onDiscovery(pkt, verbose) {
+		// link layer neighbors announcing themselves
+		return true
 	}
 	return false
 }
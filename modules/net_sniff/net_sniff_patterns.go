@@ -0,0 +1,221 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/gopacket"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// matches reported for every pattern and payload
+	patternMaxMatches = 8
+	// bytes of payload around the match in the excerpt
+	patternContext = 16
+	// longer matches are truncated in the excerpt
+	patternMaxMatch = 128
+)
+
+// PayloadPattern is a named regular expression the TCP and UDP payloads
+// are matched against.
+type PayloadPattern struct {
+	Name       string
+	Expression string
+	Hits       uint64
+
+	re *regexp.Regexp
+}
+
+// the user defined patterns, the slice is replaced on every change so that
+// the payloads being matched keep their list
+type patternSet struct {
+	sync.RWMutex
+	patterns []*PayloadPattern
+}
+
+var payloadPatterns = &patternSet{}
+
+// Add defines a pattern or replaces the one with the same name.
+func (s *patternSet) Add(name string, expression string) error {
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return fmt.Errorf("invalid expression for pattern %s: %v", name, err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	patterns := make([]*PayloadPattern, 0, len(s.patterns)+1)
+	for _, p := range s.patterns {
+		if p.Name != name {
+			patterns = append(patterns, p)
+		}
+	}
+	s.patterns = append(patterns, &PayloadPattern{
+		Name:       name,
+		Expression: expression,
+		re:         re,
+	})
+	return nil
+}
+
+func (s *patternSet) Remove(name string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	patterns := make([]*PayloadPattern, 0, len(s.patterns))
+	for _, p := range s.patterns {
+		if p.Name != name {
+			patterns = append(patterns, p)
+		}
+	}
+
+	removed := len(patterns) != len(s.patterns)
+	s.patterns = patterns
+	return removed
+}
+
+func (s *patternSet) List() []*PayloadPattern {
+	s.RLock()
+	defer s.RUnlock()
+	return s.patterns
+}
+
+// Load adds the patterns of a file, one per line as NAME REGEXP, empty
+// lines and lines starting with # are skipped.
+func (s *patternSet) Load(path string) error {
+	reader, err := fs.LineReader(path)
+	if err != nil {
+		return err
+	}
+
+	lineno := 0
+	for line := range reader {
+		lineno++
+		if line = strings.TrimSpace(line); line == "" || line[0] == '#' {
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf("%s:%d: expected NAME REGEXP", path, lineno)
+		} else if err = s.Add(parts[0], strings.TrimSpace(parts[1])); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, lineno, err)
+		}
+	}
+	return nil
+}
+
+// the match with some payload around it, unprintable bytes are replaced
+func patternExcerpt(payload []byte, start int, end int) string {
+	if end-start > patternMaxMatch {
+		end = start + patternMaxMatch
+	}
+
+	from, to := start-patternContext, end+patternContext
+	if from < 0 {
+		from = 0
+	}
+	if to > len(payload) {
+		to = len(payload)
+	}
+
+	excerpt := make([]byte, 0, to-from)
+	for _, b := range payload[from:to] {
+		if b >= 0x20 && b < 0x7f {
+			excerpt = append(excerpt, b)
+		} else {
+			excerpt = append(excerpt, '.')
+		}
+	}
+	return string(excerpt)
+}
+
+// matchPatterns reports the matches of the user defined patterns in the
+// payload of a TCP or UDP packet.
+func matchPatterns(pkt gopacket.Packet, transport string, srcIP, dstIP net.IP, srcPort, dstPort int, payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+
+	for _, p := range payloadPatterns.List() {
+		for _, loc := range p.re.FindAllIndex(payload, patternMaxMatches) {
+			if loc[0] == loc[1] {
+				continue
+			}
+
+			atomic.AddUint64(&p.Hits, 1)
+			match := patternExcerpt(payload[loc[0]:loc[1]], 0, loc[1]-loc[0])
+
+			NewSnifferEvent(
+				pkt,
+				"pattern",
+				net.JoinHostPort(srcIP.String(), strconv.Itoa(srcPort)),
+				net.JoinHostPort(dstIP.String(), strconv.Itoa(dstPort)),
+				SniffData{
+					"pattern":   p.Name,
+					"match":     match,
+					"excerpt":   patternExcerpt(payload, loc[0], loc[1]),
+					"offset":    loc[0],
+					"transport": transport,
+					"src_ip":    srcIP.String(),
+					"src_port":  srcPort,
+					"dst_ip":    dstIP.String(),
+					"dst_port":  dstPort,
+				},
+				"%s %s:%d > %s:%d %s %s",
+				tui.Wrap(tui.BACKRED+tui.FOREBLACK, "pattern"),
+				vIP(srcIP),
+				srcPort,
+				vIP(dstIP),
+				dstPort,
+				tui.Bold(p.Name),
+				tui.Yellow(match),
+			).Push()
+		}
+	}
+}
+
+func (mod *Sniffer) addPattern(name string, expression string) error {
+	if err := payloadPatterns.Add(name, expression); err != nil {
+		return err
+	}
+	mod.Info("pattern %s added", tui.Bold(name))
+	return nil
+}
+
+func (mod *Sniffer) delPattern(name string) error {
+	if !payloadPatterns.Remove(name) {
+		return fmt.Errorf("pattern %s not found", name)
+	}
+	return nil
+}
+
+func (mod *Sniffer) showPatterns() error {
+	patterns := payloadPatterns.List()
+	if len(patterns) == 0 {
+		mod.Info("no patterns defined, add them with net.sniff.pattern.add NAME REGEXP")
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, p := range patterns {
+		rows = append(rows, []string{
+			tui.Bold(p.Name),
+			p.Expression,
+			strconv.FormatUint(atomic.LoadUint64(&p.Hits), 10),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Pattern", "Expression", "Hits"}, rows)
+	mod.Session.Refresh()
+	return nil
+}
@@ -0,0 +1,123 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of sessions tracked while waiting for the password
+	postgresMaxTracked = 4096
+)
+
+// a client between the startup message and the password one
+type postgresSession struct {
+	user     string
+	database string
+	auth     *packets.PostgresAuth
+}
+
+var (
+	postgresLock     = sync.Mutex{}
+	postgresSessions = make(map[string]*postgresSession)
+)
+
+func postgresEvent(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, data SniffData, format string, args ...interface{}) {
+	NewSnifferEvent(
+		pkt,
+		"postgresql",
+		srcIP.String(),
+		dstIP.String(),
+		data,
+		"%s %s > %s:%s - %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "postgresql"),
+		vIP(srcIP),
+		vIP(dstIP),
+		vPort(tcp.DstPort),
+		fmt.Sprintf(format, args...),
+	).Push()
+}
+
+func postgresParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.SrcPort == packets.PostgresPort {
+		// the authentication method requested by the server
+		if auth, err := packets.ParsePostgresAuth(tcp.Payload); err == nil {
+			postgresLock.Lock()
+			if sess, found := postgresSessions[fmt.Sprintf("%s:%d>%s:%d", dstIP, tcp.DstPort, srcIP, tcp.SrcPort)]; found {
+				sess.auth = auth
+			}
+			postgresLock.Unlock()
+		}
+		return false
+	} else if tcp.DstPort != packets.PostgresPort {
+		return false
+	}
+
+	key := fmt.Sprintf("%s:%d>%s:%d", srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+	if startup, err := packets.ParsePostgresStartup(tcp.Payload); err == nil {
+		if startup.SSL {
+			return false
+		}
+
+		postgresLock.Lock()
+		if len(postgresSessions) >= postgresMaxTracked {
+			postgresSessions = make(map[string]*postgresSession)
+		}
+		postgresSessions[key] = &postgresSession{
+			user:     startup.User,
+			database: startup.Database,
+		}
+		postgresLock.Unlock()
+
+		postgresEvent(pkt, srcIP, dstIP, tcp, SniffData{
+			"user":       startup.User,
+			"database":   startup.Database,
+			"parameters": startup.Parameters,
+		}, "%s %s", tui.Bold(startup.User), tui.Yellow(startup.Database))
+		return true
+	}
+
+	password, err := packets.ParsePostgresPassword(tcp.Payload)
+	if err != nil {
+		return false
+	}
+
+	postgresLock.Lock()
+	sess, found := postgresSessions[key]
+	delete(postgresSessions, key)
+	postgresLock.Unlock()
+
+	if !found || sess.auth == nil {
+		return false
+	}
+
+	method := sess.auth.Name()
+	switch sess.auth.Type {
+	case packets.PostgresAuthMD5:
+		if password, err = packets.PostgresHashcat(sess.user, sess.auth.Salt, password); err != nil {
+			return false
+		}
+	case packets.PostgresAuthCleartext:
+	default:
+		return false
+	}
+
+	postgresEvent(pkt, srcIP, dstIP, tcp, SniffData{
+		"user":     sess.user,
+		"database": sess.database,
+		"method":   method,
+		"password": password,
+	}, "%s %s %s %s", tui.Dim(method), tui.Bold(sess.user), tui.Red(password), tui.Yellow(sess.database))
+
+	publishCredential("postgresql", srcIP, dstIP.String(), int(tcp.DstPort), sess.user, password)
+
+	return true
+}
@@ -0,0 +1,356 @@
+package net_sniff
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// upper bounds to keep the memory usage of a profile under control
+	maxProfileProtocols    = 128
+	maxProfileDestinations = 256
+	// protocols accounted as other that are remembered as used
+	maxProfileOther = 4096
+	// entries shown by net.profile
+	profileTop = 10
+)
+
+type Usage struct {
+	Name      string    `json:"name"`
+	Packets   uint64    `json:"packets"`
+	Bytes     uint64    `json:"bytes"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type HostProfile struct {
+	Address      string            `json:"address"`
+	FirstSeen    time.Time         `json:"first_seen"`
+	LastSeen     time.Time         `json:"last_seen"`
+	Packets      uint64            `json:"packets"`
+	Bytes        uint64            `json:"bytes"`
+	Protocols    map[string]*Usage `json:"protocols"`
+	Destinations map[string]*Usage `json:"destinations"`
+	Hours        [24]uint64        `json:"hours"`
+
+	// the protocols accounted as other
+	other map[string]bool
+}
+
+type ProfileAnomaly struct {
+	Address  string `json:"address"`
+	Protocol string `json:"protocol"`
+	Name     string `json:"name"`
+	Peer     string `json:"peer"`
+}
+
+type Profiles struct {
+	sync.RWMutex
+	hosts map[string]*HostProfile
+}
+
+func NewProfiles() *Profiles {
+	return &Profiles{
+		hosts: make(map[string]*HostProfile),
+	}
+}
+
+func (p *Profiles) MarshalJSON() ([]byte, error) {
+	p.RLock()
+	defer p.RUnlock()
+	return json.Marshal(p.hosts)
+}
+
+func (p *Profiles) Get(address string) (*HostProfile, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	h, found := p.hosts[address]
+	return h, found
+}
+
+func (p *Profiles) Clear() {
+	p.Lock()
+	defer p.Unlock()
+	p.hosts = make(map[string]*HostProfile)
+}
+
+func (u *Usage) add(size uint64, at time.Time) {
+	if u.FirstSeen.IsZero() {
+		u.FirstSeen = at
+	}
+	u.LastSeen = at
+	u.Packets++
+	u.Bytes += size
+}
+
+// returns true if the key was not used before, once the map is full the new
+// keys are accounted as other and, if other is not nil, remembered there so
+// that they're new only the first time
+func addUsage(m map[string]*Usage, other map[string]bool, max int, key string, name string, size uint64, at time.Time) bool {
+	u, found := m[key]
+	if found {
+		u.add(size, at)
+		return false
+	} else if len(m) < max {
+		u = &Usage{Name: name}
+		m[key] = u
+		u.add(size, at)
+		return true
+	}
+
+	isNew := false
+	if other != nil && !other[key] && len(other) < maxProfileOther {
+		other[key] = true
+		isNew = true
+	}
+
+	if u, found = m["other"]; !found {
+		u = &Usage{Name: "other"}
+		m["other"] = u
+	}
+	u.add(size, at)
+
+	return isNew
+}
+
+// identify the protocol of a packet by its service port, which is either
+// the destination port of a connection attempt or the lowest of the two
+func packetProtocol(pkt gopacket.Packet) (key string, name string) {
+	if tcp, ok := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
+		port := tcp.DstPort
+		if !(tcp.SYN && !tcp.ACK) && tcp.SrcPort < tcp.DstPort {
+			port = tcp.SrcPort
+		}
+		return fmt.Sprintf("tcp/%d", port), portName(port.String())
+	} else if udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP); ok {
+		port := udp.DstPort
+		if udp.SrcPort < udp.DstPort {
+			port = udp.SrcPort
+		}
+		return fmt.Sprintf("udp/%d", port), portName(port.String())
+	} else if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		name := strings.ToLower(ip4.Protocol.String())
+		return name, name
+	} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		name := strings.ToLower(ip6.NextHeader.String())
+		return name, name
+	}
+	return "", ""
+}
+
+// gopacket formats known ports as 443(https)
+func portName(s string) string {
+	if start := strings.Index(s, "("); start != -1 && strings.HasSuffix(s, ")") {
+		return s[start+1 : len(s)-1]
+	}
+	return s
+}
+
+// update the profiles of the local endpoints of the packet, returning the
+// protocols never used before by hosts profiled for longer than learning
+func (p *Profiles) Update(pkt gopacket.Packet, local *net.IPNet, learning time.Duration) []ProfileAnomaly {
+	var src, dst net.IP
+
+	if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		src, dst = ip4.SrcIP, ip4.DstIP
+	} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		src, dst = ip6.SrcIP, ip6.DstIP
+	} else {
+		return nil
+	}
+
+	key, name := packetProtocol(pkt)
+	if key == "" {
+		return nil
+	}
+
+	at := pkt.Metadata().Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	size := uint64(pkt.Metadata().Length)
+
+	p.Lock()
+	defer p.Unlock()
+
+	anomalies := []ProfileAnomaly{}
+	for _, pair := range [][]net.IP{{src, dst}, {dst, src}} {
+		host, peer := pair[0], pair[1]
+		if local != nil && !local.Contains(host) {
+			continue
+		}
+
+		address := host.String()
+		h, found := p.hosts[address]
+		if !found {
+			h = &HostProfile{
+				Address:      address,
+				FirstSeen:    at,
+				Protocols:    make(map[string]*Usage),
+				Destinations: make(map[string]*Usage),
+				other:        make(map[string]bool),
+			}
+			p.hosts[address] = h
+		}
+
+		h.LastSeen = at
+		h.Packets++
+		h.Bytes += size
+		h.Hours[at.Hour()]++
+
+		isNew := addUsage(h.Protocols, h.other, maxProfileProtocols, key, name, size, at)
+		addUsage(h.Destinations, nil, maxProfileDestinations, peer.String(), peer.String(), size, at)
+
+		if isNew && learning > 0 && at.Sub(h.FirstSeen) > learning {
+			anomalies = append(anomalies, ProfileAnomaly{
+				Address:  address,
+				Protocol: key,
+				Name:     name,
+				Peer:     peer.String(),
+			})
+		}
+	}
+
+	return anomalies
+}
+
+func topUsage(m map[string]*Usage) []*Usage {
+	list := make([]*Usage, 0, len(m))
+	for _, u := range m {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Bytes > list[j].Bytes
+	})
+	if len(list) > profileTop {
+		list = list[:profileTop]
+	}
+	return list
+}
+
+// one character per hour of the day, the darker the busier
+func activeHours(hours [24]uint64) string {
+	levels := []rune(" .:-=+*#")
+	max := uint64(0)
+	for _, n := range hours {
+		if n > max {
+			max = n
+		}
+	}
+
+	s := ""
+	for _, n := range hours {
+		if max == 0 || n == 0 {
+			s += string(levels[0])
+		} else {
+			s += string(levels[1+int(n*uint64(len(levels)-2)/max)])
+		}
+	}
+	return "|" + s + "|"
+}
+
+func (mod *Sniffer) onProfileAnomaly(a ProfileAnomaly) {
+	mod.Warning("%s is speaking %s (%s) with %s for the first time", tui.Bold(a.Address), tui.Red(a.Name), a.Protocol, a.Peer)
+	mod.Session.Events.Add("net.profile.anomaly", a)
+}
+
+func (mod *Sniffer) showProfiles() error {
+	mod.Profiles.RLock()
+	defer mod.Profiles.RUnlock()
+
+	if len(mod.Profiles.hosts) == 0 {
+		mod.Info("no host profiles yet")
+		return nil
+	}
+
+	list := make([]*HostProfile, 0, len(mod.Profiles.hosts))
+	for _, h := range mod.Profiles.hosts {
+		list = append(list, h)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Bytes > list[j].Bytes
+	})
+
+	rows := [][]string{}
+	for _, h := range list {
+		protos := []string{}
+		for _, u := range topUsage(h.Protocols) {
+			protos = append(protos, u.Name)
+			if len(protos) == 3 {
+				break
+			}
+		}
+
+		rows = append(rows, []string{
+			tui.Bold(h.Address),
+			strconv.FormatUint(h.Packets, 10),
+			humanize.Bytes(h.Bytes),
+			strconv.Itoa(len(h.Protocols)),
+			strings.Join(protos, ", "),
+			strconv.Itoa(len(h.Destinations)),
+			h.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Host", "Packets", "Bytes", "Protocols", "Top", "Peers", "Seen"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
+
+func (mod *Sniffer) showProfile(address string) error {
+	h, found := mod.Profiles.Get(address)
+	if !found {
+		return fmt.Errorf("no profile for %s", address)
+	}
+
+	mod.Profiles.RLock()
+	defer mod.Profiles.RUnlock()
+
+	out := mod.Session.Events.Stdout
+
+	fmt.Fprintf(out, "\n%s seen from %s to %s, %d packets, %s\n\n",
+		tui.Bold(h.Address),
+		h.FirstSeen.Format("2006-01-02 15:04:05"),
+		h.LastSeen.Format("2006-01-02 15:04:05"),
+		h.Packets,
+		humanize.Bytes(h.Bytes))
+
+	rows := [][]string{}
+	for _, u := range topUsage(h.Protocols) {
+		rows = append(rows, []string{tui.Bold(u.Name), strconv.FormatUint(u.Packets, 10), humanize.Bytes(u.Bytes), u.FirstSeen.Format("15:04:05")})
+	}
+	tui.Table(out, []string{"Protocol", "Packets", "Bytes", "First Seen"}, rows)
+
+	rows = [][]string{}
+	for _, u := range topUsage(h.Destinations) {
+		rows = append(rows, []string{tui.Bold(u.Name), strconv.FormatUint(u.Packets, 10), humanize.Bytes(u.Bytes), u.LastSeen.Format("15:04:05")})
+	}
+	tui.Table(out, []string{"Peer", "Packets", "Bytes", "Last Seen"}, rows)
+
+	fmt.Fprintf(out, "active hours  %s\n               0     6     12    18   \n\n", activeHours(h.Hours))
+
+	mod.Session.Refresh()
+
+	return nil
+}
+
+func (mod *Sniffer) profileLearning() time.Duration {
+	if err, secs := mod.IntParam("net.sniff.profile.learning"); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
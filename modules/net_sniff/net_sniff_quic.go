@@ -0,0 +1,111 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// endpoint meta with the QUIC versions spoken by a host
+	quicVersionsMeta = "quic.versions"
+	// maximum number of handshakes tracked while reassembling their ClientHello
+	quicMaxTracked = 4096
+)
+
+var (
+	quicLock = sync.Mutex{}
+	// a nil stream is a handshake already reported
+	quicStreams = make(map[string]*packets.QUICCryptoStream)
+)
+
+// the ClientHello can be split across several initial packets sharing the
+// same destination connection id
+func quicClientHello(srcIP net.IP, udp *layers.UDP, initial *packets.QUICInitial) *packets.TLSClientHello {
+	key := fmt.Sprintf("%s:%d/%x", srcIP, udp.SrcPort, initial.DCID)
+
+	quicLock.Lock()
+	defer quicLock.Unlock()
+
+	stream, found := quicStreams[key]
+	if found && stream == nil {
+		return nil
+	} else if !found {
+		if len(quicStreams) >= quicMaxTracked {
+			quicStreams = make(map[string]*packets.QUICCryptoStream)
+		}
+		stream = &packets.QUICCryptoStream{}
+		quicStreams[key] = stream
+	}
+
+	stream.Add(initial.Crypto...)
+	hello, ok := stream.ClientHello()
+	if !ok {
+		return nil
+	}
+	quicStreams[key] = nil
+	return hello
+}
+
+func quicParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	initial, err := packets.ParseQUICInitial(udp.Payload)
+	if err == packets.ErrQUICNotInitial {
+		return false
+	} else if err != nil {
+		// a QUIC packet we can't decrypt, like the initial packets of the server
+		return true
+	}
+
+	version := packets.QUICVersionName(initial.Version)
+	addMeta(srcIP, quicVersionsMeta, version)
+	addMeta(dstIP, quicVersionsMeta, version)
+
+	hello := quicClientHello(srcIP, udp, initial)
+	if hello == nil {
+		return true
+	}
+
+	ja3 := hello.JA3()
+	addMeta(srcIP, tlsJA3Meta, ja3)
+
+	server := hello.SNI
+	if server == "" {
+		server = dstIP.String()
+	}
+	if udp.DstPort != 443 {
+		server = vHostPort(server, udp.DstPort)
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"quic",
+		srcIP.String(),
+		server,
+		SniffData{
+			"version":    version,
+			"sni":        hello.SNI,
+			"dcid":       fmt.Sprintf("%x", initial.DCID),
+			"ja3":        ja3,
+			"ja3_string": hello.JA3String(),
+			"ciphers":    hello.CipherSuites,
+			"alpn":       hello.ALPN,
+		},
+		"%s %s > %s %s %s %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "quic"),
+		vIP(srcIP),
+		tui.Yellow("https://"+server),
+		tui.Dim(version),
+		tui.Dim(strings.Join(hello.ALPN, ",")),
+		tui.Dim("ja3:"+ja3),
+	).Push()
+
+	return true
+}
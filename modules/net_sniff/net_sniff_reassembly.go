@@ -0,0 +1,169 @@
+package net_sniff
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/reassembly"
+)
+
+const (
+	// bytes of a direction kept while none of the parsers recognizes them,
+	// after that the direction is considered bulk data and parsed as it comes
+	reassemblyMaxPending = 16 * 1024
+	// connections without packets for this long are flushed and forgotten
+	reassemblyTimeout    = 2 * time.Minute
+	reassemblyFlushEvery = 10 * time.Second
+	// pages of out of order segments buffered, a page is about 2KB
+	reassemblyMaxPages        = 8192
+	reassemblyMaxPagesPerConn = 256
+)
+
+// the packet being assembled, whatever is reassembled with it is parsed
+// in its context
+type reassemblyContext struct {
+	pkt     gopacket.Packet
+	tcp     *layers.TCP
+	matched bool
+}
+
+func (c *reassemblyContext) GetCaptureInfo() gopacket.CaptureInfo {
+	return c.pkt.Metadata().CaptureInfo
+}
+
+// the two directions of a connection, from the first packet seen
+type reassemblyStream struct {
+	client     net.IP
+	server     net.IP
+	clientPort layers.TCPPort
+	serverPort layers.TCPPort
+	bulk       [2]bool
+}
+
+func (s *reassemblyStream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+	// connections already established when the sniffer started
+	*start = true
+	return true
+}
+
+func (s *reassemblyStream) ReassembledSG(sg reassembly.ScatterGather, ac reassembly.AssemblerContext) {
+	available, _ := sg.Lengths()
+	ctx, ok := ac.(*reassemblyContext)
+	if available == 0 || !ok {
+		return
+	}
+
+	dir, _, _, _ := sg.Info()
+	srcIP, dstIP, srcPort, dstPort := s.client, s.server, s.clientPort, s.serverPort
+	side := 0
+	if dir == reassembly.TCPDirServerToClient {
+		srcIP, dstIP, srcPort, dstPort = s.server, s.client, s.serverPort, s.clientPort
+		side = 1
+	}
+
+	// the scatter gather is reused, parsers might keep references
+	data := append([]byte{}, sg.Fetch(available)...)
+
+	tcp := *ctx.tcp
+	tcp.SrcPort, tcp.DstPort = srcPort, dstPort
+	tcp.Payload = data
+
+	if runTCPParsers(srcIP, dstIP, data, ctx.pkt, &tcp) {
+		ctx.matched = true
+	} else if !s.bulk[side] {
+		if available < reassemblyMaxPending {
+			// try again once more data is there
+			sg.KeepFrom(0)
+		} else {
+			s.bulk[side] = true
+		}
+	}
+}
+
+func (s *reassemblyStream) ReassemblyComplete(ac reassembly.AssemblerContext) bool {
+	return true
+}
+
+type reassemblyFactory struct{}
+
+func (f *reassemblyFactory) New(netFlow, tcpFlow gopacket.Flow, tcp *layers.TCP, ac reassembly.AssemblerContext) reassembly.Stream {
+	return &reassemblyStream{
+		client:     net.IP(netFlow.Src().Raw()),
+		server:     net.IP(netFlow.Dst().Raw()),
+		clientPort: tcp.SrcPort,
+		serverPort: tcp.DstPort,
+	}
+}
+
+// tcpReassembler feeds the TCP parsers with the reassembled streams instead
+// of single segments, so that messages split across segments are parsed too
+type tcpReassembler struct {
+	sync.Mutex
+	assembler *reassembly.Assembler
+	lastFlush time.Time
+}
+
+var reassembler *tcpReassembler
+
+func newTCPReassembler(enabled bool) *tcpReassembler {
+	if !enabled {
+		return nil
+	}
+
+	assembler := reassembly.NewAssembler(reassembly.NewStreamPool(&reassemblyFactory{}))
+	assembler.MaxBufferedPagesTotal = reassemblyMaxPages
+	assembler.MaxBufferedPagesPerConnection = reassemblyMaxPagesPerConn
+
+	return &tcpReassembler{
+		assembler: assembler,
+	}
+}
+
+// Assemble returns true if any parser recognized the data reassembled
+// with the packet.
+func (r *tcpReassembler) Assemble(pkt gopacket.Packet, tcp *layers.TCP) bool {
+	nlayer := pkt.NetworkLayer()
+	if nlayer == nil {
+		return false
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	ctx := &reassemblyContext{
+		pkt: pkt,
+		tcp: tcp,
+	}
+	r.assembler.AssembleWithContext(nlayer.NetworkFlow(), tcp, ctx)
+
+	// by capture time, to work with pcap files too
+	at := pkt.Metadata().Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if r.lastFlush.IsZero() {
+		r.lastFlush = at
+	} else if at.Sub(r.lastFlush) > reassemblyFlushEvery {
+		r.assembler.FlushCloseOlderThan(at.Add(-reassemblyTimeout))
+		r.lastFlush = at
+	}
+
+	return ctx.matched
+}
+
+func (r *tcpReassembler) Enabled() bool {
+	return r != nil
+}
+
+func (r *tcpReassembler) Close() {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.assembler.FlushAll()
+}
@@ -0,0 +1,147 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// endpoint meta set on the servers answering commands without a password
+	redisNoAuthMeta = "redis.noauth"
+	// maximum number of connections tracked since their handshake
+	redisMaxTracked = 4096
+	// maximum number and length of the arguments printed for each command
+	redisMaxShown    = 4
+	redisMaxShownArg = 32
+)
+
+// a connection seen from its handshake, so we know if the client
+// authenticated before its commands were answered
+type redisConn struct {
+	authenticated bool
+	commands      int
+}
+
+var (
+	redisLock  = sync.Mutex{}
+	redisConns = make(map[string]*redisConn)
+)
+
+func redisArgs(cmd []string) string {
+	args := []string{}
+	for i, arg := range cmd[1:] {
+		if i == redisMaxShown {
+			args = append(args, tui.Dim(fmt.Sprintf("(+%d)", len(cmd)-1-i)))
+			break
+		} else if len(arg) > redisMaxShownArg {
+			arg = arg[:redisMaxShownArg] + "..."
+		}
+		args = append(args, fmt.Sprintf("%q", arg))
+	}
+	return strings.Join(args, " ")
+}
+
+func redisEvent(pkt gopacket.Packet, srcIP, dstIP net.IP, label string, data SniffData, format string, args ...interface{}) {
+	NewSnifferEvent(
+		pkt,
+		"redis",
+		srcIP.String(),
+		dstIP.String(),
+		data,
+		"%s %s > %s : %s",
+		label,
+		vIP(srcIP),
+		vIP(dstIP),
+		fmt.Sprintf(format, args...),
+	).Push()
+}
+
+func redisReply(srcIP, dstIP net.IP, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	reply, err := packets.ParseRedisReply(tcp.Payload)
+	if err != nil {
+		return false
+	}
+
+	key := fmt.Sprintf("%s:%d>%s:%d", dstIP, tcp.DstPort, srcIP, tcp.SrcPort)
+	redisLock.Lock()
+	conn, found := redisConns[key]
+	exposed := found && !conn.authenticated && conn.commands > 0 && !reply.IsError()
+	if exposed {
+		// one report for each connection is enough
+		delete(redisConns, key)
+	}
+	redisLock.Unlock()
+
+	if exposed {
+		addMeta(srcIP, redisNoAuthMeta, "true")
+		redisEvent(pkt, srcIP, dstIP, tui.Wrap(tui.BACKRED+tui.FOREBLACK, "redis"), SniffData{
+			"type": "noauth",
+		}, "%s", tui.Red("answering commands without authentication"))
+	}
+
+	return true
+}
+
+func redisParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.SrcPort == packets.RedisPort {
+		return redisReply(srcIP, dstIP, pkt, tcp)
+	} else if tcp.DstPort != packets.RedisPort {
+		return false
+	}
+
+	key := fmt.Sprintf("%s:%d>%s:%d", srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+	if tcp.SYN && !tcp.ACK {
+		redisLock.Lock()
+		if len(redisConns) >= redisMaxTracked {
+			redisConns = make(map[string]*redisConn)
+		}
+		redisConns[key] = &redisConn{}
+		redisLock.Unlock()
+		return false
+	}
+
+	cmds, err := packets.ParseRedisCommands(tcp.Payload)
+	if err != nil {
+		return false
+	}
+
+	for _, cmd := range cmds {
+		user, pass, isAuth := packets.RedisAuth(cmd)
+
+		redisLock.Lock()
+		if conn, found := redisConns[key]; found {
+			conn.commands++
+			conn.authenticated = conn.authenticated || isAuth
+		}
+		redisLock.Unlock()
+
+		name := strings.ToUpper(cmd[0])
+		if isAuth {
+			publishCredential("redis", srcIP, dstIP.String(), int(tcp.DstPort), user, pass)
+			redisEvent(pkt, srcIP, dstIP, tui.Wrap(tui.BACKRED+tui.FOREBLACK, "redis"), SniffData{
+				"type":     "auth",
+				"command":  name,
+				"username": user,
+				"password": pass,
+			}, "%s %s %s", tui.Dim(name), tui.Bold(user), tui.Red(pass))
+			continue
+		}
+
+		redisEvent(pkt, srcIP, dstIP, tui.Wrap(tui.BACKYELLOW+tui.FOREBLACK, "redis"), SniffData{
+			"type":    "command",
+			"command": name,
+			"args":    cmd[1:],
+		}, "%s %s", tui.Bold(name), redisArgs(cmd))
+	}
+
+	return true
+}
@@ -0,0 +1,171 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// TCPParser is called with every TCP payload until a parser returns true,
+// meaning that it recognized the protocol.
+type TCPParser func(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool
+
+// UDPParser is called with every UDP payload until a parser returns true,
+// meaning that it recognized the protocol.
+type UDPParser func(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool
+
+// BuiltinParserPriority is the priority of the parsers of bettercap, parsers
+// with a lower priority are tried before them and the others after them.
+const BuiltinParserPriority = 100
+
+const (
+	parserSourceBuiltin = "builtin"
+	parserSourceGo      = "go"
+)
+
+// Parser is a protocol parser of the sniffer.
+type Parser struct {
+	Name      string
+	Transport string
+	Priority  int
+	// builtin, go or the path of the script registering it
+	Source string
+
+	tcp TCPParser
+	udp UDPParser
+}
+
+// the parsers of a transport by priority, the slice is replaced on every
+// change so that the packets being parsed keep their chain
+type parserChain struct {
+	sync.RWMutex
+	transport string
+	parsers   []*Parser
+}
+
+var (
+	tcpParsers = &parserChain{transport: "tcp"}
+	udpParsers = &parserChain{transport: "udp"}
+)
+
+func (c *parserChain) Add(p *Parser) error {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, other := range c.parsers {
+		if other.Name == p.Name {
+			return fmt.Errorf("%s parser %s already registered by %s", c.transport, p.Name, other.Source)
+		}
+	}
+
+	parsers := make([]*Parser, 0, len(c.parsers)+1)
+	parsers = append(parsers, c.parsers...)
+	parsers = append(parsers, p)
+	// parsers with the same priority keep the registration order
+	sort.SliceStable(parsers, func(i, j int) bool {
+		return parsers[i].Priority < parsers[j].Priority
+	})
+	c.parsers = parsers
+	return nil
+}
+
+func (c *parserChain) Remove(name string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	parsers := make([]*Parser, 0, len(c.parsers))
+	for _, p := range c.parsers {
+		if p.Name != name {
+			parsers = append(parsers, p)
+		}
+	}
+
+	removed := len(parsers) != len(c.parsers)
+	c.parsers = parsers
+	return removed
+}
+
+// removes the parsers registered by a source
+func (c *parserChain) RemoveSource(source string) {
+	c.Lock()
+	defer c.Unlock()
+
+	parsers := make([]*Parser, 0, len(c.parsers))
+	for _, p := range c.parsers {
+		if p.Source != source {
+			parsers = append(parsers, p)
+		}
+	}
+	c.parsers = parsers
+}
+
+func (c *parserChain) List() []*Parser {
+	c.RLock()
+	defer c.RUnlock()
+	return c.parsers
+}
+
+// RegisterTCPParser adds a parser for TCP payloads, lower priorities are
+// tried first.
+func RegisterTCPParser(name string, priority int, parser TCPParser) error {
+	return tcpParsers.Add(&Parser{
+		Name:      name,
+		Transport: "tcp",
+		Priority:  priority,
+		Source:    parserSourceGo,
+		tcp:       parser,
+	})
+}
+
+// RegisterUDPParser adds a parser for UDP payloads, lower priorities are
+// tried first.
+func RegisterUDPParser(name string, priority int, parser UDPParser) error {
+	return udpParsers.Add(&Parser{
+		Name:      name,
+		Transport: "udp",
+		Priority:  priority,
+		Source:    parserSourceGo,
+		udp:       parser,
+	})
+}
+
+// UnregisterTCPParser removes a TCP parser, returns false if there's no
+// parser with this name.
+func UnregisterTCPParser(name string) bool {
+	return tcpParsers.Remove(name)
+}
+
+// UnregisterUDPParser removes a UDP parser, returns false if there's no
+// parser with this name.
+func UnregisterUDPParser(name string) bool {
+	return udpParsers.Remove(name)
+}
+
+func (mod *Sniffer) showParsers() error {
+	rows := [][]string{}
+	for _, chain := range []*parserChain{tcpParsers, udpParsers} {
+		for _, p := range chain.List() {
+			source := p.Source
+			if source == parserSourceBuiltin {
+				source = tui.Dim(source)
+			}
+			rows = append(rows, []string{
+				p.Transport,
+				strconv.Itoa(p.Priority),
+				tui.Bold(p.Name),
+				source,
+			})
+		}
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Transport", "Priority", "Parser", "Source"}, rows)
+	mod.Session.Refresh()
+	return nil
+}
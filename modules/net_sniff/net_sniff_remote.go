@@ -0,0 +1,206 @@
+package net_sniff
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bettercap/bettercap/log"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"github.com/google/gopacket/pcapgo"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+const (
+	sourceSSH   = "ssh://"
+	sourceRPCAP = "rpcap://"
+)
+
+func isRemoteSource(source string) bool {
+	return strings.HasPrefix(source, sourceSSH) || strings.HasPrefix(source, sourceRPCAP)
+}
+
+// the source without the password, if any, to log it
+func redactSource(source string) string {
+	if u, err := url.Parse(source); err == nil && u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "xxx")
+			return u.String()
+		}
+	}
+	return source
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// tcpdump running on a remote host over ssh and writing the packets to its
+// standard output, the ssh connection itself is excluded from the capture
+type sshCapture struct {
+	sync.Mutex
+	mod      *session.SessionModule
+	cmd      *exec.Cmd
+	stderr   *bytes.Buffer
+	reader   *pcapgo.Reader
+	filter   *pcap.BPF
+	received uint64
+}
+
+// the command tcpdump runs with on the remote host, SSH_CLIENT is the client
+// address and port followed by the server port
+func sshRemoteCommand(iface string, filter string, sudo bool) string {
+	cmd := "set -- $SSH_CLIENT; exec "
+	if sudo {
+		cmd += "sudo -n "
+	}
+	cmd += "tcpdump -U -n -s " + fmt.Sprintf("%d", captureSnapLen) + " -w -"
+	if iface != "" {
+		cmd += " -i " + shellQuote(iface)
+	}
+	cmd += ` "not (host $1 and tcp port $3)"`
+	if filter != "" {
+		cmd += " and " + shellQuote("("+filter+")")
+	}
+	return cmd
+}
+
+// newSSHCapture starts capturing from an ssh://[user@]host[:port]/[interface]
+// source, the query can have sudo=true to run tcpdump with sudo and key=FILE
+// for the identity file. The filter is applied remotely to save bandwidth.
+func newSSHCapture(mod *session.SessionModule, source string, filter string) (captureHandle, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	} else if u.Hostname() == "" {
+		return nil, fmt.Errorf("no host in %s", source)
+	} else if strings.HasPrefix(u.Hostname(), "-") {
+		return nil, fmt.Errorf("invalid host %s", u.Hostname())
+	} else if u.User != nil && strings.HasPrefix(u.User.Username(), "-") {
+		// ssh would parse it as an option
+		return nil, fmt.Errorf("invalid user %s", u.User.Username())
+	}
+
+	args := []string{"-T", "-o", "BatchMode=yes"}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+
+	query := u.Query()
+	if key := query.Get("key"); key != "" {
+		if key, err = fs.Expand(key); err != nil {
+			return nil, err
+		}
+		args = append(args, "-i", key)
+	}
+
+	target := u.Hostname()
+	if u.User != nil && u.User.Username() != "" {
+		target = u.User.Username() + "@" + target
+	}
+
+	sudo := query.Get("sudo") == "true" || query.Get("sudo") == "1"
+	iface := strings.Trim(u.Path, "/")
+	args = append(args, "--", target, sshRemoteCommand(iface, filter, sudo))
+
+	c := &sshCapture{
+		mod:    mod,
+		cmd:    exec.Command("ssh", args...),
+		stderr: &bytes.Buffer{},
+	}
+	c.cmd.Stderr = c.stderr
+
+	stdout, err := c.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	} else if err = c.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not run ssh: %v", err)
+	}
+	mod.AddChild(c.cmd.Process)
+
+	log.Debug("capturing from %s with: ssh %s", target, strings.Join(args, " "))
+
+	// tcpdump writes the file header once it's capturing
+	if c.reader, err = pcapgo.NewReader(stdout); err != nil {
+		c.Close()
+		if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+			return nil, fmt.Errorf("could not capture from %s: %s", target, msg)
+		}
+		return nil, fmt.Errorf("could not capture from %s: %v", target, err)
+	}
+
+	return c, nil
+}
+
+func (c *sshCapture) LinkType() layers.LinkType {
+	return c.reader.LinkType()
+}
+
+// SetBPFFilter filters the packets locally, the remote filter can't be
+// changed once tcpdump is running.
+func (c *sshCapture) SetBPFFilter(expr string) error {
+	filter, err := pcap.NewBPF(c.LinkType(), captureSnapLen, expr)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.filter = filter
+	return nil
+}
+
+func (c *sshCapture) ReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	for {
+		if data, ci, err = c.reader.ReadPacketData(); err != nil {
+			return
+		}
+
+		c.Lock()
+		filter := c.filter
+		c.Unlock()
+
+		if filter == nil || filter.Matches(ci, data) {
+			atomic.AddUint64(&c.received, 1)
+			return
+		}
+	}
+}
+
+func (c *sshCapture) Packets() chan gopacket.Packet {
+	return gopacket.NewPacketSource(c, c.LinkType()).Packets()
+}
+
+// Stats only has what was received, tcpdump reports its drops on exit.
+func (c *sshCapture) Stats() (*captureStats, error) {
+	return &captureStats{
+		Received: atomic.LoadUint64(&c.received),
+	}, nil
+}
+
+func (c *sshCapture) Close() {
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+		c.mod.RemoveChild(c.cmd.Process)
+	}
+}
+
+// libpcap opens rpcap://host[:port]/interface sources on the rpcapd
+// running on the host, if built with remote capture support
+func newRPCAPCapture(source string) (captureHandle, error) {
+	handle, err := pcap.OpenLive(source, captureSnapLen, true, captureReadTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not capture from %s: %v", redactSource(source), err)
+	}
+	return &pcapCapture{handle}, nil
+}
@@ -0,0 +1,149 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/log"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var rtpUnsafeChars = regexp.MustCompile(`[^\w\-\.]`)
+
+// saves the payloads of the RTP streams to raw files, one per stream, so
+// that the calls can be decoded later with the codec reported by the events
+type rtpRecorder struct {
+	sync.Mutex
+	path  string
+	files map[uint32]*os.File
+}
+
+var recorder *rtpRecorder
+
+func newRTPRecorder(path string) *rtpRecorder {
+	return &rtpRecorder{
+		path:  path,
+		files: make(map[uint32]*os.File),
+	}
+}
+
+func (r *rtpRecorder) Enabled() bool {
+	return r != nil && r.path != ""
+}
+
+func (r *rtpRecorder) FileName(call *sipCall, ssrc uint32, codec string) string {
+	name := fmt.Sprintf("%s_%08x.%s.raw", call.ID, ssrc, strings.ToLower(codec))
+	return filepath.Join(r.path, rtpUnsafeChars.ReplaceAllString(name, "_"))
+}
+
+func (r *rtpRecorder) Write(call *sipCall, ssrc uint32, codec string, payload []byte) {
+	if !r.Enabled() {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	file, found := r.files[ssrc]
+	if !found {
+		var err error
+		if err = os.MkdirAll(r.path, os.ModePerm); err == nil {
+			file, err = os.OpenFile(r.FileName(call, ssrc, codec), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		}
+		if err != nil {
+			log.Error("could not save rtp stream %08x: %v", ssrc, err)
+			// don't try again for every packet
+			r.files[ssrc] = nil
+			return
+		}
+		r.files[ssrc] = file
+	}
+
+	if file != nil {
+		file.Write(payload)
+	}
+}
+
+func (r *rtpRecorder) Close() {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	for ssrc, file := range r.files {
+		if file != nil {
+			file.Close()
+		}
+		delete(r.files, ssrc)
+	}
+}
+
+func rtpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	sipLock.Lock()
+	call, found := sipMedia[sipEndpoint(dstIP, int(udp.DstPort))]
+	sipLock.Unlock()
+	if !found {
+		return false
+	}
+
+	header, data, err := packets.ParseRTP(udp.Payload)
+	if err != nil {
+		return false
+	}
+
+	sipLock.Lock()
+	codec := packets.RTPCodecs[header.PayloadType]
+	for _, media := range call.Media {
+		if media.Port == int(udp.DstPort) && media.Address.Equal(dstIP) {
+			codec = media.CodecOf(header.PayloadType)
+		}
+	}
+	if codec == "" {
+		codec = fmt.Sprintf("%d", header.PayloadType)
+	}
+	first := !call.Streams[header.SSRC]
+	call.Streams[header.SSRC] = true
+	sipLock.Unlock()
+
+	recorder.Write(call, header.SSRC, codec, data)
+
+	if first {
+		NewSnifferEvent(
+			pkt,
+			"rtp",
+			srcIP.String(),
+			dstIP.String(),
+			SniffData{
+				"call_id": call.ID,
+				"from":    call.From,
+				"to":      call.To,
+				"ssrc":    header.SSRC,
+				"codec":   codec,
+			},
+			"%s %s:%s > %s:%s - %s > %s %s %s",
+			tui.Wrap(tui.BACKLIGHTBLUE+tui.FOREBLACK, "rtp"),
+			vIP(srcIP),
+			vPort(udp.SrcPort),
+			vIP(dstIP),
+			vPort(udp.DstPort),
+			tui.Yellow(call.From),
+			tui.Yellow(call.To),
+			tui.Bold(codec),
+			tui.Dim(fmt.Sprintf("ssrc:%08x", header.SSRC)),
+		).Push()
+	}
+
+	return true
+}
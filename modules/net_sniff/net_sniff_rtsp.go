@@ -0,0 +1,93 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// endpoint meta with the streams served by a camera or media server
+const rtspStreamsMeta = "rtsp.streams"
+
+func rtspEvent(pkt gopacket.Packet, srcIP, dstIP net.IP, proto string, label string, data SniffData, what string) {
+	NewSnifferEvent(
+		pkt,
+		proto,
+		srcIP.String(),
+		dstIP.String(),
+		data,
+		"%s %s > %s : %s",
+		label,
+		vIP(srcIP),
+		vIP(dstIP),
+		what,
+	).Push()
+}
+
+func rtspParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.DstPort != packets.RTSPPort && tcp.SrcPort != packets.RTSPPort {
+		return false
+	}
+
+	msg, err := packets.ParseRTSP(tcp.Payload)
+	if err != nil {
+		return false
+	}
+
+	label := tui.Wrap(tui.BACKLIGHTBLUE+tui.FOREBLACK, "rtsp")
+	authLabel := tui.Wrap(tui.BACKRED+tui.FOREBLACK, "rtsp")
+
+	if !msg.IsRequest() {
+		what := tui.Bold(fmt.Sprintf("%d %s", msg.StatusCode, msg.Status))
+		if server := msg.Header("server"); server != "" {
+			what += " " + tui.Yellow(server)
+		}
+		rtspEvent(pkt, srcIP, dstIP, "rtsp", label, SniffData{
+			"status_code": msg.StatusCode,
+			"server":      msg.Header("server"),
+			"message":     msg,
+		}, what)
+		return true
+	}
+
+	stream := msg.StreamURL()
+	switch msg.Method {
+	case "DESCRIBE", "SETUP", "PLAY":
+		addMeta(dstIP, rtspStreamsMeta, stream)
+	}
+
+	if user, pass, ok := msg.BasicAuth(); ok {
+		publishCredential("rtsp", srcIP, dstIP.String(), int(tcp.DstPort), user, pass)
+		rtspEvent(pkt, srcIP, dstIP, "rtsp.auth", authLabel, SniffData{
+			"method":   msg.Method,
+			"url":      stream,
+			"username": user,
+			"password": pass,
+		}, tui.Dim(msg.Method)+" "+tui.Yellow(stream)+" "+tui.Bold(user)+":"+tui.Red(pass))
+	} else if digest := msg.Digest(); digest != nil {
+		// same format of the SIP digests
+		hash := digest.Hashcat(msg.Method, dstIP.String(), srcIP.String())
+		publishCredential("rtsp", srcIP, dstIP.String(), int(tcp.DstPort), digest.Username, hash)
+		rtspEvent(pkt, srcIP, dstIP, "rtsp.auth", authLabel, SniffData{
+			"method":   msg.Method,
+			"url":      stream,
+			"username": digest.Username,
+			"realm":    digest.Realm,
+			"hash":     hash,
+		}, tui.Dim(msg.Method)+" "+tui.Yellow(stream)+" "+tui.Bold(digest.Username)+"@"+digest.Realm+" "+tui.Red(hash))
+	}
+
+	rtspEvent(pkt, srcIP, dstIP, "rtsp", label, SniffData{
+		"method":  msg.Method,
+		"url":     stream,
+		"message": msg,
+	}, tui.Bold(msg.Method)+" "+tui.Yellow(stream))
+
+	return true
+}
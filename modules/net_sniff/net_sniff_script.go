@@ -0,0 +1,177 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/caplets"
+	"github.com/bettercap/bettercap/js"
+	"github.com/bettercap/bettercap/log"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+
+	"github.com/robertkrimen/otto"
+)
+
+// SnifferScript is a JS script adding parsers to the sniffer, in onLoad it
+// can call registerParser(transport, name, priority, callback) where the
+// callback gets the source and destination addresses and ports and the
+// payload, and returns false if it doesn't recognize the protocol, true if
+// it does or an object with proto, message and data to report an event.
+type SnifferScript struct {
+	*session.Script
+}
+
+func LoadSnifferScript(path string, sess *session.Session) (err error, s *SnifferScript) {
+	log.Info("loading sniffer script %s ...", path)
+
+	if err = caplets.Verify(path); err != nil {
+		return
+	}
+
+	plug, err := session.LoadPlugin(path)
+	if err != nil {
+		return
+	}
+
+	s = &SnifferScript{
+		Script: plug,
+	}
+
+	// define session pointer
+	if err = plug.Set("env", sess.Env.Data); err != nil {
+		log.Error("error while defining environment: %+v", err)
+		s.Unload()
+		return
+	} else if err = plug.Set("registerParser", s.registerParser); err != nil {
+		log.Error("error while defining registerParser: %+v", err)
+		s.Unload()
+		return
+	}
+
+	// run onLoad if defined
+	if plug.HasFunc("onLoad") {
+		if _, err = plug.Call("onLoad"); err != nil {
+			log.Error("error while executing onLoad callback: %s", "\ntraceback:\n  "+err.(*otto.Error).String())
+			s.Unload()
+			return
+		}
+	}
+
+	return
+}
+
+func (s *SnifferScript) registerParser(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 4 {
+		return js.ReportError("registerParser: expected 4 arguments, got %d", len(call.ArgumentList))
+	}
+
+	transport := call.Argument(0).String()
+	name := call.Argument(1).String()
+	priority, err := call.Argument(2).ToInteger()
+	if err != nil {
+		return js.ReportError("registerParser: invalid priority: %v", err)
+	}
+	callback := call.Argument(3)
+	if !callback.IsFunction() {
+		return js.ReportError("registerParser: the callback of %s is not a function", name)
+	}
+
+	parser := &Parser{
+		Name:      name,
+		Transport: transport,
+		Priority:  int(priority),
+		Source:    s.Path,
+	}
+
+	switch transport {
+	case "tcp":
+		parser.tcp = func(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+			return s.parse(name, callback, srcIP, dstIP, int(tcp.SrcPort), int(tcp.DstPort), payload, pkt)
+		}
+		err = tcpParsers.Add(parser)
+	case "udp":
+		parser.udp = func(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+			return s.parse(name, callback, srcIP, dstIP, int(udp.SrcPort), int(udp.DstPort), payload, pkt)
+		}
+		err = udpParsers.Add(parser)
+	default:
+		err = fmt.Errorf("unknown transport %s", transport)
+	}
+
+	if err != nil {
+		return js.ReportError("registerParser: %v", err)
+	}
+	return otto.TrueValue()
+}
+
+func (s *SnifferScript) parse(name string, callback otto.Value, srcIP, dstIP net.IP, srcPort, dstPort int, payload []byte, pkt gopacket.Packet) bool {
+	if len(payload) == 0 {
+		return false
+	}
+
+	// the vm can't run callbacks concurrently
+	s.Lock()
+	ret, err := callback.Call(otto.NullValue(), srcIP.String(), dstIP.String(), srcPort, dstPort, payload)
+	s.Unlock()
+	if err != nil {
+		log.Error("error while executing the %s parser: %s", name, err)
+		return false
+	} else if ret.IsBoolean() {
+		matched, _ := ret.ToBoolean()
+		return matched
+	} else if !ret.IsObject() {
+		return false
+	}
+
+	exported, err := ret.Export()
+	if err != nil {
+		log.Error("error while exporting the result of the %s parser: %s", name, err)
+		return false
+	}
+	result, ok := exported.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	proto := name
+	if value, ok := result["proto"].(string); ok && value != "" {
+		proto = value
+	}
+	message, _ := result["message"].(string)
+	data := SniffData{}
+	if fields, ok := result["data"].(map[string]interface{}); ok {
+		for key, value := range fields {
+			data[key] = value
+		}
+	}
+
+	NewSnifferEvent(
+		pkt,
+		proto,
+		vHostPort(srcIP.String(), srcPort),
+		vHostPort(dstIP.String(), dstPort),
+		data,
+		"%s %s > %s %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREBLACK, proto),
+		vHostPort(vIP(srcIP), srcPort),
+		vHostPort(vIP(dstIP), dstPort),
+		message,
+	).Push()
+
+	return true
+}
+
+// Unload removes the parsers and the fact subscriptions of the script.
+func (s *SnifferScript) Unload() {
+	if s == nil {
+		return
+	}
+	s.Script.Unload()
+	tcpParsers.RemoveSource(s.Path)
+	udpParsers.RemoveSource(s.Path)
+}
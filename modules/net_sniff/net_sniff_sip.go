@@ -0,0 +1,194 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of calls tracked at the same time
+	sipMaxTracked = 4096
+)
+
+// a call negotiated by INVITE and its media streams
+type sipCall struct {
+	ID      string
+	From    string
+	To      string
+	Codec   string
+	Started time.Time
+	Media   []packets.SDPMedia
+	// RTP streams already reported, by SSRC
+	Streams map[uint32]bool
+}
+
+var (
+	sipLock  = sync.Mutex{}
+	sipCalls = make(map[string]*sipCall)
+	// media endpoints announced by the SDP bodies -> call
+	sipMedia = make(map[string]*sipCall)
+)
+
+func sipEndpoint(ip net.IP, port int) string {
+	return fmt.Sprintf("%s:%d", ip, port)
+}
+
+func sipEvent(pkt gopacket.Packet, srcIP, dstIP net.IP, dstPort string, proto string, data SniffData, format string, args ...interface{}) {
+	NewSnifferEvent(
+		pkt,
+		proto,
+		srcIP.String(),
+		dstIP.String(),
+		data,
+		"%s %s > %s:%s - %s",
+		tui.Wrap(tui.BACKLIGHTBLUE+tui.FOREBLACK, proto),
+		vIP(srcIP),
+		vIP(dstIP),
+		dstPort,
+		fmt.Sprintf(format, args...),
+	).Push()
+}
+
+// sipTrack updates the call the message belongs to and returns it, nil for
+// messages outside of an INVITE dialog.
+func sipTrack(msg *packets.SIPMessage, when time.Time) *sipCall {
+	id := msg.CallID()
+	call, found := sipCalls[id]
+	if !found {
+		if msg.Method != "INVITE" || id == "" {
+			return nil
+		} else if len(sipCalls) >= sipMaxTracked {
+			sipCalls = make(map[string]*sipCall)
+			sipMedia = make(map[string]*sipCall)
+		}
+		call = &sipCall{
+			ID:      id,
+			From:    msg.From(),
+			To:      msg.To(),
+			Started: when,
+			Media:   make([]packets.SDPMedia, 0),
+			Streams: make(map[uint32]bool),
+		}
+		sipCalls[id] = call
+	}
+
+	// both the offer and the answer announce where the RTP streams go
+	for _, media := range msg.Media() {
+		if media.Address == nil || media.Port == 0 {
+			continue
+		}
+		if media.Type == "audio" && call.Codec == "" {
+			call.Codec = media.Codec()
+		}
+		call.Media = append(call.Media, media)
+		sipMedia[sipEndpoint(media.Address, media.Port)] = call
+	}
+
+	return call
+}
+
+func sipForget(call *sipCall) {
+	delete(sipCalls, call.ID)
+	for _, media := range call.Media {
+		delete(sipMedia, sipEndpoint(media.Address, media.Port))
+	}
+}
+
+func sipMessage(srcIP, dstIP net.IP, srcPort, dstPort int, payload []byte, pkt gopacket.Packet) bool {
+	if srcPort != packets.SIPPort && dstPort != packets.SIPPort {
+		return false
+	}
+
+	msg, err := packets.ParseSIP(payload)
+	if err != nil {
+		return false
+	}
+
+	sipLock.Lock()
+	defer sipLock.Unlock()
+
+	call := sipTrack(msg, pkt.Metadata().Timestamp)
+	port := vPort(dstPort)
+
+	if msg.IsRequest() {
+		data := SniffData{
+			"method":  msg.Method,
+			"uri":     msg.URI,
+			"from":    msg.From(),
+			"to":      msg.To(),
+			"call_id": msg.CallID(),
+		}
+
+		parsed := false
+		if digest := msg.Digest(); digest != nil {
+			parsed = true
+			hash := digest.Hashcat(msg.Method, dstIP.String(), srcIP.String())
+			sipEvent(pkt, srcIP, dstIP, port, "sip.auth", SniffData{
+				"method":   msg.Method,
+				"username": digest.Username,
+				"realm":    digest.Realm,
+				"uri":      digest.URI,
+				"hash":     hash,
+			}, "%s %s@%s %s", tui.Dim(msg.Method), tui.Bold(digest.Username), digest.Realm, tui.Red(hash))
+
+			publishCredential("sip", srcIP, dstIP.String(), dstPort, digest.Username, hash)
+		}
+
+		switch msg.Method {
+		case "REGISTER":
+			sipEvent(pkt, srcIP, dstIP, port, "sip", data, "%s %s", tui.Bold("REGISTER"), tui.Yellow(msg.From()))
+		case "INVITE":
+			codec := ""
+			if call != nil {
+				codec = call.Codec
+			}
+			data["codec"] = codec
+			sipEvent(pkt, srcIP, dstIP, port, "sip", data, "%s %s > %s %s", tui.Bold("INVITE"), tui.Yellow(msg.From()), tui.Yellow(msg.To()), tui.Dim(codec))
+		case "BYE", "CANCEL":
+			if call != nil {
+				data["duration"] = pkt.Metadata().Timestamp.Sub(call.Started).Seconds()
+				sipForget(call)
+			}
+			sipEvent(pkt, srcIP, dstIP, port, "sip", data, "%s %s > %s", tui.Bold(msg.Method), tui.Yellow(msg.From()), tui.Yellow(msg.To()))
+		default:
+			return parsed
+		}
+		return true
+	}
+
+	// only the answers to the calls
+	if call == nil || msg.CSeqMethod() != "INVITE" || msg.StatusCode < 200 {
+		return false
+	}
+
+	sipEvent(pkt, srcIP, dstIP, port, "sip", SniffData{
+		"status":  msg.StatusCode,
+		"from":    call.From,
+		"to":      call.To,
+		"call_id": call.ID,
+		"codec":   call.Codec,
+	}, "%d %s %s > %s %s", msg.StatusCode, tui.Bold(msg.Status), tui.Yellow(call.From), tui.Yellow(call.To), tui.Dim(call.Codec))
+
+	if msg.StatusCode >= 300 {
+		sipForget(call)
+	}
+
+	return true
+}
+
+func sipParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	return sipMessage(srcIP, dstIP, int(udp.SrcPort), int(udp.DstPort), udp.Payload, pkt)
+}
+
+func sipTCPParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	return sipMessage(srcIP, dstIP, int(tcp.SrcPort), int(tcp.DstPort), tcp.Payload, pkt)
+}
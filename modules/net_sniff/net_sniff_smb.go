@@ -0,0 +1,222 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/dustin/go-humanize"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	smbNetbiosPort = 139
+	// maximum number of pending challenges, creates and open files tracked
+	smbMaxTracked = 4096
+)
+
+type smbState struct {
+	sync.Mutex
+	// NTLMSSP challenges sent by the servers, by flow
+	challenges map[string][]byte
+	// file names of the create requests waiting for a response, by flow and message id
+	creates map[string]string
+	// file names of the open files, by server and file id
+	files map[string]string
+	// read requests waiting for a response and the files being carved
+	reads   map[string]smbRead
+	carving map[string]*smbCarving
+}
+
+type smbRead struct {
+	file   string
+	offset uint64
+}
+
+type smbCarving struct {
+	buf   *carveBuffer
+	write bool
+}
+
+var smb = smbState{
+	challenges: make(map[string][]byte),
+	creates:    make(map[string]string),
+	files:      make(map[string]string),
+	reads:      make(map[string]smbRead),
+	carving:    make(map[string]*smbCarving),
+}
+
+func smbTrack(m map[string]string, key string, value string) {
+	if len(m) >= smbMaxTracked {
+		for k := range m {
+			delete(m, k)
+		}
+	}
+	m[key] = value
+}
+
+func smbCarveFile(carver *fileCarver, file string, write bool) *smbCarving {
+	carving, found := smb.carving[file]
+	if !found {
+		if len(smb.carving) >= smbMaxTracked {
+			smb.carving = make(map[string]*smbCarving)
+		}
+		carving = &smbCarving{buf: newCarveBuffer(carver.MaxSize())}
+		smb.carving[file] = carving
+	}
+	carving.write = carving.write || write
+	return carving
+}
+
+// the files read or written are carved once closed
+func smbCarve(carver *fileCarver, pkt gopacket.Packet, srcIP, dstIP net.IP, flow string, server string, msg packets.SMBMessage) {
+	if fileID, offset, _, ok := msg.IO(); ok {
+		file := server + "#" + fileID
+		if msg.Command == packets.SMB2CommandWrite {
+			smbCarveFile(carver, file, true).buf.WriteAt(offset, msg.WriteData())
+		} else {
+			if len(smb.reads) >= smbMaxTracked {
+				smb.reads = make(map[string]smbRead)
+			}
+			smb.reads[fmt.Sprintf("%s#%d", flow, msg.MessageID)] = smbRead{file: file, offset: offset}
+		}
+	} else if data := msg.ReadData(); data != nil {
+		key := fmt.Sprintf("%s#%d", flow, msg.MessageID)
+		if read, found := smb.reads[key]; found {
+			delete(smb.reads, key)
+			smbCarveFile(carver, read.file, false).buf.WriteAt(read.offset, data)
+		}
+	} else if fileID := msg.CloseFileID(); fileID != "" {
+		file := server + "#" + fileID
+		if carving, found := smb.carving[file]; found {
+			delete(smb.carving, file)
+			if data, complete := carving.buf.Bytes(); data != nil {
+				// close requests go from the client to the server
+				if carving.write {
+					carver.Carve(pkt, "smb", srcIP, dstIP, smb.files[file], "", data, complete)
+				} else {
+					carver.Carve(pkt, "smb", dstIP, srcIP, smb.files[file], "", data, complete)
+				}
+			}
+		}
+	}
+}
+
+func isSMB(tcp *layers.TCP) bool {
+	return tcp.SrcPort == packets.SMBPort || tcp.DstPort == packets.SMBPort ||
+		tcp.SrcPort == smbNetbiosPort || tcp.DstPort == smbNetbiosPort
+}
+
+func smbEvent(pkt gopacket.Packet, srcIP, dstIP net.IP, proto string, data SniffData, format string, args ...interface{}) {
+	NewSnifferEvent(
+		pkt,
+		proto,
+		srcIP.String(),
+		dstIP.String(),
+		data,
+		"%s %s > %s %s",
+		tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, proto),
+		vIP(srcIP),
+		vIP(dstIP),
+		fmt.Sprintf(format, args...),
+	).Push()
+}
+
+func smbParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if !isSMB(tcp) {
+		return false
+	}
+
+	msgs := packets.ParseSMBMessages(tcp.Payload)
+	if len(msgs) == 0 {
+		return false
+	}
+
+	// flows are always keyed from the client to the server
+	flow := fmt.Sprintf("%s:%d>%s:%d", srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+	server := dstIP.String()
+	if msgs[0].Response {
+		flow = fmt.Sprintf("%s:%d>%s:%d", dstIP, tcp.DstPort, srcIP, tcp.SrcPort)
+		server = srcIP.String()
+	}
+
+	carver := activeCarver()
+
+	smb.Lock()
+	defer smb.Unlock()
+
+	for _, msg := range msgs {
+		if carver.Enabled() {
+			smbCarve(carver, pkt, srcIP, dstIP, flow, server, msg)
+		}
+
+		if msg.IsSessionSetup() {
+			smbSessionSetup(pkt, srcIP, dstIP, tcp, flow, msg)
+		} else if path := msg.TreePath(); path != "" {
+			smbEvent(pkt, srcIP, dstIP, "smb", SniffData{"share": path}, "tree connect %s", tui.Yellow(path))
+		} else if name := msg.CreateName(); name != "" {
+			smbTrack(smb.creates, fmt.Sprintf("%s#%d", flow, msg.MessageID), name)
+		} else if fileID := msg.CreateFileID(); fileID != "" {
+			key := fmt.Sprintf("%s#%d", flow, msg.MessageID)
+			if name, found := smb.creates[key]; found {
+				delete(smb.creates, key)
+				smbTrack(smb.files, server+"#"+fileID, name)
+				smbEvent(pkt, dstIP, srcIP, "smb", SniffData{"file": name}, "open %s", tui.Yellow(name))
+			}
+		} else if fileID, offset, length, ok := msg.IO(); ok {
+			op := "read"
+			if msg.Command == packets.SMB2CommandWrite {
+				op = "write"
+			}
+			name, found := smb.files[server+"#"+fileID]
+			if !found {
+				name = fileID
+			}
+			smbEvent(pkt, srcIP, dstIP, "smb", SniffData{
+				"file":   name,
+				"op":     op,
+				"offset": offset,
+				"length": length,
+			}, "%s %s %s at offset %d", op, humanize.Bytes(uint64(length)), tui.Yellow(name), offset)
+		}
+	}
+
+	return true
+}
+
+func smbSessionSetup(pkt gopacket.Packet, srcIP, dstIP net.IP, tcp *layers.TCP, flow string, msg packets.SMBMessage) {
+	ntlmssp, msgType := msg.NTLMSSP()
+	if msgType == packets.NTLM_CHALLENGE && msg.Response {
+		if len(smb.challenges) >= smbMaxTracked {
+			smb.challenges = make(map[string][]byte)
+		}
+		smb.challenges[flow] = append([]byte{}, ntlmssp...)
+	} else if msgType == packets.NTLM_AUTHENTICATE && !msg.Response {
+		challenge, found := smb.challenges[flow]
+		if !found {
+			return
+		}
+		delete(smb.challenges, flow)
+
+		pair := packets.NewNTLMChallengeResponse(challenge, ntlmssp)
+		data, err := pair.Parsed()
+		if err != nil || data.User == "" {
+			return
+		}
+
+		hash := strings.TrimSpace(data.LcString())
+		smbEvent(pkt, srcIP, dstIP, "smb.ntlm", SniffData{
+			"user":   data.User,
+			"domain": data.Domain,
+			"hash":   hash,
+		}, "%s\\%s %s", data.Domain, tui.Bold(data.User), tui.Red(hash))
+
+		publishCredential("smb", srcIP, dstIP.String(), int(tcp.DstPort), data.Domain+"\\"+data.User, hash)
+	}
+}
@@ -0,0 +1,89 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// endpoint meta with the community strings seen for an agent
+	snmpCommunitiesMeta = "snmp.communities"
+	// maximum number of OIDs printed for each message
+	snmpMaxShown = 5
+)
+
+func snmpOIDs(msg *packets.SNMPMessage, withValues bool) string {
+	oids := []string{}
+	for i, bind := range msg.VarBinds {
+		if i == snmpMaxShown {
+			oids = append(oids, tui.Dim(fmt.Sprintf("(+%d)", len(msg.VarBinds)-i)))
+			break
+		} else if withValues && bind.Value != "" {
+			oids = append(oids, fmt.Sprintf("%s=%s", tui.Blue(bind.OID), tui.Yellow(bind.Value)))
+		} else {
+			oids = append(oids, tui.Blue(bind.OID))
+		}
+	}
+	return strings.Join(oids, " ")
+}
+
+func snmpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.SNMPPort && udp.SrcPort != packets.SNMPPort && udp.DstPort != packets.SNMPTrapPort {
+		return false
+	}
+
+	msg, err := packets.ParseSNMP(udp.Payload)
+	if err != nil {
+		return false
+	}
+
+	// requests go to the agent while responses and traps come from it
+	agent := dstIP
+	if udp.SrcPort == packets.SNMPPort || msg.IsTrap() {
+		agent = srcIP
+	}
+	addMeta(agent, snmpCommunitiesMeta, msg.Community)
+
+	proto := "snmp"
+	label := tui.Wrap(tui.BACKRED+tui.FOREBLACK, "snmp")
+	details := ""
+	if msg.IsTrap() {
+		proto = "snmp.trap"
+		label = tui.Wrap(tui.BACKRED+tui.FOREBLACK, "snmp-trap")
+		if msg.PDU == packets.SNMPTrapV1 {
+			details = fmt.Sprintf("%s generic:%d specific:%d ", tui.Blue(msg.Enterprise), msg.GenericTrap, msg.SpecificTrap)
+		}
+	}
+
+	NewSnifferEvent(
+		pkt,
+		proto,
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"version":   msg.VersionName(),
+			"community": msg.Community,
+			"pdu":       msg.PDUName(),
+			"message":   msg,
+		},
+		"%s %s > %s : %s %s %s %s%s",
+		label,
+		vIP(srcIP),
+		vIP(dstIP),
+		tui.Dim(msg.VersionName()),
+		tui.Red(msg.Community),
+		tui.Bold(msg.PDUName()),
+		details,
+		snmpOIDs(msg, msg.PDU == packets.SNMPResponse || msg.PDU == packets.SNMPSetRequest || msg.IsTrap()),
+	).Push()
+
+	return true
+}
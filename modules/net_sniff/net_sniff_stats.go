@@ -1,29 +1,167 @@
 package net_sniff
 
 import (
-	"github.com/bettercap/bettercap/log"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/bettercap/bettercap/log"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/dustin/go-humanize"
+	"github.com/evilsocket/islazy/tui"
 )
 
+const (
+	// rows of the top talkers, flows and DNS names tables
+	statsTopEntries = 10
+	// keys tracked for every table, the new ones are counted as other
+	statsMaxEntries = 10000
+	statsOther      = "other"
+)
+
+// packets and bytes of a protocol, host or flow
+type TrafficCounter struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+type trafficTable map[string]*TrafficCounter
+
+func (t trafficTable) Add(key string, size uint64) {
+	c, found := t[key]
+	if !found {
+		if len(t) >= statsMaxEntries {
+			key = statsOther
+			if c, found = t[key]; !found {
+				c = &TrafficCounter{}
+				t[key] = c
+			}
+		} else {
+			c = &TrafficCounter{}
+			t[key] = c
+		}
+	}
+	c.Packets++
+	c.Bytes += size
+}
+
+// Top returns the keys with the most bytes.
+func (t trafficTable) Top(n int) []string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if t[keys[i]].Bytes == t[keys[j]].Bytes {
+			return keys[i] < keys[j]
+		}
+		return t[keys[i]].Bytes > t[keys[j]].Bytes
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
 type SnifferStats struct {
-	NumLocal    uint64
-	NumMatched  uint64
-	NumDumped   uint64
-	NumWrote    uint64
-	Started     time.Time
-	FirstPacket time.Time
-	LastPacket  time.Time
+	NumLocal     uint64
+	NumMatched   uint64
+	NumDumped    uint64
+	NumWrote     uint64
+	NumSkipped   uint64
+	NumDecrypted uint64
+	Started      time.Time
+	FirstPacket  time.Time
+	LastPacket   time.Time
+
+	traffic   sync.Mutex
+	Protocols trafficTable
+	Talkers   trafficTable
+	Flows     trafficTable
+	DNSNames  map[string]uint64
 }
 
 func NewSnifferStats() *SnifferStats {
 	return &SnifferStats{
-		NumLocal:    0,
-		NumMatched:  0,
-		NumDumped:   0,
-		NumWrote:    0,
-		Started:     time.Now(),
-		FirstPacket: time.Time{},
-		LastPacket:  time.Time{},
+		NumLocal:     0,
+		NumMatched:   0,
+		NumDumped:    0,
+		NumWrote:     0,
+		NumSkipped:   0,
+		NumDecrypted: 0,
+		Started:      time.Now(),
+		FirstPacket:  time.Time{},
+		LastPacket:   time.Time{},
+		Protocols:    make(trafficTable),
+		Talkers:      make(trafficTable),
+		Flows:        make(trafficTable),
+		DNSNames:     make(map[string]uint64),
+	}
+}
+
+// Account counts a processed packet for the protocols the parsers reported.
+func (s *SnifferStats) Account(pkt gopacket.Packet, protos []string) {
+	size := uint64(pkt.Metadata().Length)
+	if size == 0 {
+		size = uint64(len(pkt.Data()))
+	}
+
+	var srcIP, dstIP net.IP
+	if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		srcIP, dstIP = ip4.SrcIP, ip4.DstIP
+	} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		srcIP, dstIP = ip6.SrcIP, ip6.DstIP
+	}
+
+	// packets no parser recognized are counted by transport
+	if len(protos) == 0 {
+		proto := statsOther
+		if tl := pkt.TransportLayer(); tl != nil {
+			proto = strings.ToLower(tl.LayerType().String())
+		} else if nl := pkt.NetworkLayer(); nl != nil {
+			proto = strings.ToLower(nl.LayerType().String())
+		}
+		protos = []string{proto}
+	}
+
+	s.traffic.Lock()
+	defer s.traffic.Unlock()
+
+	for _, proto := range protos {
+		s.Protocols.Add(proto, size)
+	}
+
+	if srcIP == nil {
+		return
+	}
+
+	s.Talkers.Add(srcIP.String(), size)
+
+	flow := fmt.Sprintf("%s > %s", srcIP, dstIP)
+	if tcp, ok := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
+		flow = fmt.Sprintf("%s > %s tcp", net.JoinHostPort(srcIP.String(), fmt.Sprintf("%d", tcp.SrcPort)), net.JoinHostPort(dstIP.String(), fmt.Sprintf("%d", tcp.DstPort)))
+	} else if udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP); ok {
+		flow = fmt.Sprintf("%s > %s udp", net.JoinHostPort(srcIP.String(), fmt.Sprintf("%d", udp.SrcPort)), net.JoinHostPort(dstIP.String(), fmt.Sprintf("%d", udp.DstPort)))
+	}
+	s.Flows.Add(flow, size)
+
+	// the names are counted once per query, not for the responses
+	if dns, ok := pkt.Layer(layers.LayerTypeDNS).(*layers.DNS); ok && !dns.QR {
+		for _, q := range dns.Questions {
+			name := strings.ToLower(string(q.Name))
+			if _, found := s.DNSNames[name]; found || len(s.DNSNames) < statsMaxEntries {
+				s.DNSNames[name]++
+			} else {
+				s.DNSNames[statsOther]++
+			}
+		}
 	}
 }
 
@@ -45,6 +183,141 @@ func (s *SnifferStats) Print() error {
 	log.Info("Matched Packets    : %d", s.NumMatched)
 	log.Info("Dumped Packets     : %d", s.NumDumped)
 	log.Info("Wrote Packets      : %d", s.NumWrote)
+	if s.NumSkipped > 0 {
+		log.Info("Skipped Packets    : %d", s.NumSkipped)
+	}
+	if decrypted := atomic.LoadUint64(&s.NumDecrypted); decrypted > 0 {
+		log.Info("Decrypted Packets  : %d", decrypted)
+	}
 
 	return nil
 }
+
+func trafficRows(t trafficTable, n int) [][]string {
+	rows := [][]string{}
+	for _, key := range t.Top(n) {
+		rows = append(rows, []string{
+			key,
+			fmt.Sprintf("%d", t[key].Packets),
+			humanize.Bytes(t[key].Bytes),
+		})
+	}
+	return rows
+}
+
+// showStats prints what the sniffer is processing by protocol, host, flow
+// and DNS name, with the packets dropped by the capture.
+func (mod *Sniffer) showStats() error {
+	stats := mod.Stats
+	if stats == nil {
+		return fmt.Errorf("No stats yet.")
+	}
+
+	out := mod.Session.Events.Stdout
+	elapsed := time.Since(stats.Started).Round(time.Second)
+
+	if mod.Ctx != nil && mod.Ctx.Handle != nil {
+		if cs, err := mod.Ctx.Handle.Stats(); err == nil {
+			fmt.Fprintf(out, "\n%s\n\n", tui.Bold("Capture"))
+			tui.Table(out, []string{"Received", "Dropped", "Interface Dropped", "Skipped", "Running"}, [][]string{{
+				fmt.Sprintf("%d", cs.Received),
+				fmt.Sprintf("%d", cs.Dropped),
+				fmt.Sprintf("%d", cs.IfDropped),
+				fmt.Sprintf("%d", stats.NumSkipped),
+				elapsed.String(),
+			}})
+		}
+	}
+
+	stats.traffic.Lock()
+	protocols := trafficRows(stats.Protocols, 0)
+	talkers := trafficRows(stats.Talkers, statsTopEntries)
+	flows := trafficRows(stats.Flows, statsTopEntries)
+
+	names := make([]string, 0, len(stats.DNSNames))
+	for name := range stats.DNSNames {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if stats.DNSNames[names[i]] == stats.DNSNames[names[j]] {
+			return names[i] < names[j]
+		}
+		return stats.DNSNames[names[i]] > stats.DNSNames[names[j]]
+	})
+	if len(names) > statsTopEntries {
+		names = names[:statsTopEntries]
+	}
+	dnsRows := [][]string{}
+	for _, name := range names {
+		dnsRows = append(dnsRows, []string{name, fmt.Sprintf("%d", stats.DNSNames[name])})
+	}
+	stats.traffic.Unlock()
+
+	columns := []string{"", "Packets", "Bytes"}
+	for _, table := range []struct {
+		title string
+		rows  [][]string
+	}{
+		{"Protocols", protocols},
+		{"Top Talkers", talkers},
+		{"Top Flows", flows},
+	} {
+		if len(table.rows) > 0 {
+			fmt.Fprintf(out, "\n%s\n\n", tui.Bold(table.title))
+			columns[0] = strings.TrimPrefix(table.title, "Top ")
+			tui.Table(out, columns, table.rows)
+		}
+	}
+
+	if len(dnsRows) > 0 {
+		fmt.Fprintf(out, "\n%s\n\n", tui.Bold("Top DNS Names"))
+		tui.Table(out, []string{"Name", "Queries"}, dnsRows)
+	}
+
+	mod.Session.Refresh()
+	return nil
+}
+
+// statsWorker prints the stats periodically until stopped or the sniffer
+// stops.
+func (mod *Sniffer) statsWorker(period time.Duration, quit chan bool) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			if !mod.Running() {
+				return
+			}
+			mod.showStats()
+		}
+	}
+}
+
+func (mod *Sniffer) startLiveStats() error {
+	if !mod.Running() {
+		return fmt.Errorf("the sniffer is not running")
+	}
+
+	err, period := mod.IntParam("net.sniff.stats.period")
+	if err != nil {
+		return err
+	} else if period <= 0 {
+		return fmt.Errorf("net.sniff.stats.period must be greater than zero")
+	}
+
+	mod.stopLiveStats()
+	mod.statsQuit = make(chan bool)
+	go mod.statsWorker(time.Duration(period)*time.Second, mod.statsQuit)
+	return nil
+}
+
+func (mod *Sniffer) stopLiveStats() {
+	if mod.statsQuit != nil {
+		close(mod.statsQuit)
+		mod.statsQuit = nil
+	}
+}
@@ -0,0 +1,82 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// endpoint meta with the public addresses a STUN server reported to a host
+	stunMappedMeta = "stun.mapped"
+	// endpoint meta with the STUN and TURN servers used by a host
+	stunServersMeta = "stun.servers"
+)
+
+func stunParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	// WebRTC uses any port, the magic cookie is checked instead
+	msg, err := packets.ParseSTUN(udp.Payload)
+	if err != nil {
+		return false
+	}
+
+	client, server := srcIP, dstIP
+	if msg.Class == packets.STUNSuccess || msg.Class == packets.STUNError {
+		client, server = dstIP, srcIP
+	}
+	if msg.Method == packets.STUNBinding || msg.Method == packets.STUNAllocate {
+		addMeta(client, stunServersMeta, server.String())
+	}
+
+	proto := "stun"
+	if msg.IsTURN() {
+		proto = "turn"
+	}
+
+	what := ""
+	if msg.MappedAddress != nil {
+		addMeta(client, stunMappedMeta, msg.MappedAddress.IP.String())
+		what += " mapped " + tui.Yellow(msg.MappedAddress.String())
+	}
+	if msg.RelayedAddress != nil {
+		what += " relayed " + tui.Yellow(msg.RelayedAddress.String())
+	}
+	if msg.PeerAddress != nil {
+		what += " peer " + tui.Yellow(msg.PeerAddress.String())
+	}
+	if msg.Username != "" {
+		what += " user " + tui.Bold(msg.Username)
+	}
+	if msg.ErrorCode != 0 {
+		what += " " + tui.Red(fmt.Sprintf("%d %s", msg.ErrorCode, msg.ErrorReason))
+	}
+
+	NewSnifferEvent(
+		pkt,
+		proto,
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"method":         msg.MethodName(),
+			"class":          msg.ClassName(),
+			"transaction_id": msg.TransactionID,
+			"message":        msg,
+		},
+		"%s %s > %s : %s %s%s %s",
+		tui.Wrap(tui.BACKLIGHTBLUE+tui.FOREBLACK, proto),
+		vHostPort(vIP(srcIP), udp.SrcPort),
+		vHostPort(vIP(dstIP), udp.DstPort),
+		tui.Blue(msg.MethodName()),
+		msg.ClassName(),
+		what,
+		tui.Dim(msg.TransactionID),
+	).Push()
+
+	return true
+}
@@ -0,0 +1,80 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// endpoint meta with the collectors a host sends its logs to
+const syslogServersMeta = "syslog.servers"
+
+func syslogSeverity(msg *packets.SyslogMessage) string {
+	name := fmt.Sprintf("%s.%s", msg.FacilityName(), msg.SeverityName())
+	if msg.Severity <= 3 {
+		return tui.Red(name)
+	} else if msg.Severity == 4 {
+		return tui.Yellow(name)
+	}
+	return tui.Dim(name)
+}
+
+func syslogParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.SyslogPort {
+		return false
+	}
+
+	msg, err := packets.ParseSyslog(udp.Payload)
+	if err != nil {
+		return false
+	}
+
+	// many devices put their own address in there
+	if msg.Hostname != "" && net.ParseIP(msg.Hostname) == nil {
+		if endpoint := session.I.Lan.GetByIp(srcIP.String()); endpoint != nil {
+			endpoint.OnMeta(map[string]string{
+				"syslog:hostname": msg.Hostname,
+			})
+		}
+	}
+	addMeta(srcIP, syslogServersMeta, dstIP.String())
+
+	app := msg.AppName
+	if msg.ProcID != "" {
+		app += "[" + msg.ProcID + "]"
+	}
+	if app != "" {
+		app = tui.Bold(app) + " "
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"syslog",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"facility": msg.FacilityName(),
+			"severity": msg.SeverityName(),
+			"hostname": msg.Hostname,
+			"app":      msg.AppName,
+			"pid":      msg.ProcID,
+			"message":  msg.Message,
+		},
+		"%s %s > %s : %s %s%s",
+		tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, "syslog"),
+		vIP(srcIP),
+		vIP(dstIP),
+		syslogSeverity(msg),
+		app,
+		msg.Message,
+	).Push()
+
+	return true
+}
@@ -0,0 +1,232 @@
+package net_sniff
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+// how the packets of a tap are reported
+const (
+	TapVerbose = "verbose"
+	TapNormal  = "normal"
+	TapQuiet   = "quiet"
+	// the tap has no file output
+	tapNoOutput = "-"
+)
+
+// SnifferTap is a named filter with its own output file and verbosity, the
+// sniffer only processes the packets matching at least one of them.
+type SnifferTap struct {
+	Name    string
+	Mode    string
+	Output  string
+	Filter  string
+	Packets uint64
+
+	bpf     string
+	display *packets.DisplayFilter
+	matcher *pcap.BPF
+	capture *captureOutput
+}
+
+func newSnifferTap(name string, mode string, output string, filter string) (*SnifferTap, error) {
+	t := &SnifferTap{
+		Name:   name,
+		Mode:   mode,
+		Filter: filter,
+		bpf:    filter,
+	}
+
+	if output != tapNoOutput {
+		var err error
+		if t.Output, err = fs.Expand(output); err != nil {
+			return nil, err
+		}
+	}
+
+	// as for net.sniff.filter, anything that is not a display filter is BPF
+	if display, err := packets.CompileDisplayFilter(filter); err == nil {
+		t.display = display
+		t.bpf = display.BPF
+	} else if _, err = pcap.CompileBPFFilter(layers.LinkTypeEthernet, captureSnapLen, filter); err != nil {
+		return nil, fmt.Errorf("invalid filter for tap %s: %v", name, err)
+	}
+
+	return t, nil
+}
+
+// Match returns true if the packet passes the filter of the tap.
+func (t *SnifferTap) Match(pkt gopacket.Packet) bool {
+	if t.matcher != nil && !t.matcher.Matches(pkt.Metadata().CaptureInfo, pkt.Data()) {
+		return false
+	}
+	return t.display == nil || t.display.Match(pkt)
+}
+
+// the taps defined with net.sniff.tap.add, they're used from the next start
+type tapSet struct {
+	sync.RWMutex
+	taps []*SnifferTap
+}
+
+func (s *tapSet) Add(t *SnifferTap) {
+	s.Lock()
+	defer s.Unlock()
+
+	taps := make([]*SnifferTap, 0, len(s.taps)+1)
+	for _, other := range s.taps {
+		if other.Name != t.Name {
+			taps = append(taps, other)
+		}
+	}
+	s.taps = append(taps, t)
+}
+
+func (s *tapSet) Remove(name string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	taps := make([]*SnifferTap, 0, len(s.taps))
+	for _, t := range s.taps {
+		if t.Name != name {
+			taps = append(taps, t)
+		}
+	}
+
+	removed := len(taps) != len(s.taps)
+	s.taps = taps
+	return removed
+}
+
+func (s *tapSet) List() []*SnifferTap {
+	s.RLock()
+	defer s.RUnlock()
+	return s.taps
+}
+
+// the capture filter is the union of the filters of the taps
+func (s *tapSet) BPF() string {
+	exprs := []string{}
+	for _, t := range s.List() {
+		if t.bpf == "" {
+			// this one wants everything
+			return ""
+		}
+		exprs = append(exprs, t.bpf)
+	}
+	return packets.BPFOr(exprs...)
+}
+
+// openTaps prepares the taps for a capture, with their matchers for its
+// link type and their outputs.
+func (mod *Sniffer) openTaps(ctx *SnifferContext) error {
+	for _, t := range mod.taps.List() {
+		t.matcher = nil
+		if t.bpf != "" {
+			matcher, err := pcap.NewBPF(ctx.Handle.LinkType(), captureSnapLen, packets.BPFVLAN(t.bpf))
+			if err != nil {
+				return fmt.Errorf("invalid filter for tap %s: %v", t.Name, err)
+			}
+			t.matcher = matcher
+		}
+
+		t.capture = nil
+		if t.Output != "" {
+			out, err := mod.newCaptureOutput(ctx, t.Output)
+			if err != nil {
+				return err
+			} else if err = out.Open(); err != nil {
+				return err
+			}
+			t.capture = out
+		}
+
+		atomic.StoreUint64(&t.Packets, 0)
+		ctx.Taps = append(ctx.Taps, t)
+	}
+	return nil
+}
+
+// MatchTaps returns the taps matching the packet, whether it has to be
+// parsed and with what verbosity.
+func (c *SnifferContext) MatchTaps(pkt gopacket.Packet) (matched []*SnifferTap, parse bool, verbose bool) {
+	if len(c.Taps) == 0 {
+		return nil, true, c.Verbose
+	}
+
+	for _, t := range c.Taps {
+		if !t.Match(pkt) {
+			continue
+		}
+
+		atomic.AddUint64(&t.Packets, 1)
+		matched = append(matched, t)
+		switch t.Mode {
+		case TapVerbose:
+			parse, verbose = true, true
+		case TapNormal:
+			parse = true
+			verbose = verbose || c.Verbose
+		}
+	}
+	return
+}
+
+func (mod *Sniffer) addTap(name string, mode string, output string, filter string) error {
+	if mod.Running() {
+		return fmt.Errorf("taps can't be changed while the sniffer is running")
+	}
+
+	t, err := newSnifferTap(name, mode, output, filter)
+	if err != nil {
+		return err
+	}
+	mod.taps.Add(t)
+	return nil
+}
+
+func (mod *Sniffer) delTap(name string) error {
+	if mod.Running() {
+		return fmt.Errorf("taps can't be changed while the sniffer is running")
+	} else if !mod.taps.Remove(name) {
+		return fmt.Errorf("tap %s not found", name)
+	}
+	return nil
+}
+
+func (mod *Sniffer) showTaps() error {
+	taps := mod.taps.List()
+	if len(taps) == 0 {
+		mod.Info("no taps defined, the sniffer uses net.sniff.filter and net.sniff.output")
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, t := range taps {
+		output := t.Output
+		if output == "" {
+			output = tui.Dim("none")
+		}
+		rows = append(rows, []string{
+			tui.Bold(t.Name),
+			tui.Yellow(t.Filter),
+			t.Mode,
+			output,
+			fmt.Sprintf("%d", atomic.LoadUint64(&t.Packets)),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Tap", "Filter", "Mode", "Output", "Packets"}, rows)
+	mod.Session.Refresh()
+	return nil
+}
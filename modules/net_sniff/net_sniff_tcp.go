@@ -10,18 +10,72 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
-var tcpParsers = []func(net.IP, net.IP, []byte, gopacket.Packet, *layers.TCP) bool{
-	sniParser,
-	ntlmParser,
-	httpParser,
-	ftpParser,
-	teamViewerParser,
+// the parsers of bettercap, tried in this order
+var builtinTCPParsers = []struct {
+	name   string
+	parser TCPParser
+}{
+	{"tls", tlsParser},
+	{"smb", smbParser},
+	{"ntlm", ntlmParser},
+	{"ldap", ldapParser},
+	{"krb5", krb5TCPParser},
+	{"ws", wsParser},
+	{"http2", http2Parser},
+	{"http", httpParser},
+	{"ftp", ftpParser},
+	{"ftp-data", ftpDataParser},
+	{"telnet", telnetParser},
+	{"mail", mailParser},
+	{"mysql", mysqlParser},
+	{"postgres", postgresParser},
+	{"mssql", mssqlParser},
+	{"sip", sipTCPParser},
+	{"teamviewer", teamViewerParser},
+	{"modbus", modbusParser},
+	{"dnp3", dnp3Parser},
+	{"iec104", iec104Parser},
+	{"rtsp", rtspParser},
+	{"redis", redisParser},
+}
+
+func init() {
+	for _, p := range builtinTCPParsers {
+		tcpParsers.Add(&Parser{
+			Name:      p.name,
+			Transport: "tcp",
+			Priority:  BuiltinParserPriority,
+			Source:    parserSourceBuiltin,
+			tcp:       p.parser,
+		})
+	}
+}
+
+func runTCPParsers(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	for _, p := range tcpParsers.List() {
+		if p.tcp(srcIP, dstIP, payload, pkt, tcp) {
+			return true
+		}
+	}
+	return false
 }
 
 func onTCP(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, verbose bool) {
 	tcp := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP)
-	for _, parser := range tcpParsers {
-		if parser(srcIP, dstIP, payload, pkt, tcp) {
+	// segment by segment, the reassembled data can be seen more than once
+	matchPatterns(pkt, "tcp", srcIP, dstIP, int(tcp.SrcPort), int(tcp.DstPort), tcp.Payload)
+
+	if reassembler.Enabled() && len(tcp.Payload) > 0 {
+		// the parsers see the data once the stream is in order
+		if reassembler.Assemble(pkt, tcp) {
+			return
+		}
+	} else {
+		// handshakes and teardowns are still seen by the parsers tracking connections
+		if reassembler.Enabled() {
+			reassembler.Assemble(pkt, tcp)
+		}
+		if runTCPParsers(srcIP, dstIP, payload, pkt, tcp) {
 			return
 		}
 	}
@@ -29,10 +83,10 @@ func onTCP(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, verbose boo
 	if verbose {
 		sz := len(payload)
 		NewSnifferEvent(
-			pkt.Metadata().Timestamp,
+			pkt,
 			"tcp",
-			fmt.Sprintf("%s:%s", srcIP, vPort(tcp.SrcPort)),
-			fmt.Sprintf("%s:%s", dstIP, vPort(tcp.DstPort)),
+			net.JoinHostPort(srcIP.String(), vPort(tcp.SrcPort)),
+			net.JoinHostPort(dstIP.String(), vPort(tcp.DstPort)),
 			SniffData{
 				"Size": len(payload),
 			},
@@ -14,7 +14,7 @@ func teamViewerParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet,
 	if tcp.SrcPort == packets.TeamViewerPort || tcp.DstPort == packets.TeamViewerPort {
 		if tv := packets.ParseTeamViewer(tcp.Payload); tv != nil {
 			NewSnifferEvent(
-				pkt.Metadata().Timestamp,
+				pkt,
 				"teamviewer",
 				srcIP.String(),
 				dstIP.String(),
@@ -0,0 +1,188 @@
+package net_sniff
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	telnetPort = 23
+	// maximum number of sessions tracked and length of the fields being typed
+	telnetMaxTracked = 4096
+	telnetMaxField   = 256
+
+	telnetIAC  = 0xff
+	telnetSB   = 0xfa
+	telnetSE   = 0xf0
+	telnetWILL = 0xfb
+	telnetDONT = 0xfe
+)
+
+const (
+	telnetIdle = iota
+	telnetUser
+	telnetPass
+)
+
+var (
+	telnetUserPrompts = [][]byte{[]byte("login:"), []byte("username:"), []byte("user:"), []byte("user name:")}
+	telnetPassPrompts = [][]byte{[]byte("password:"), []byte("passcode:")}
+)
+
+// a client typing its credentials, one character at a time most of the times
+type telnetSession struct {
+	state int
+	field []byte
+	user  string
+}
+
+var (
+	telnetLock     = sync.Mutex{}
+	telnetSessions = make(map[string]*telnetSession)
+)
+
+// telnetData removes the option negotiations from the stream.
+func telnetData(payload []byte) []byte {
+	data := make([]byte, 0, len(payload))
+	for i := 0; i < len(payload); i++ {
+		if payload[i] != telnetIAC {
+			data = append(data, payload[i])
+			continue
+		} else if i+1 >= len(payload) {
+			break
+		}
+
+		switch cmd := payload[i+1]; {
+		case cmd == telnetIAC:
+			// escaped 0xff
+			data = append(data, telnetIAC)
+			i++
+		case cmd == telnetSB:
+			// skip the subnegotiation up to IAC SE
+			end := bytes.Index(payload[i:], []byte{telnetIAC, telnetSE})
+			if end == -1 {
+				return data
+			}
+			i += end + 1
+		case cmd >= telnetWILL && cmd <= telnetDONT:
+			// WILL, WONT, DO, DONT + option
+			i += 2
+		default:
+			i++
+		}
+	}
+	return data
+}
+
+func telnetHasPrompt(data []byte, prompts [][]byte) bool {
+	data = bytes.ToLower(bytes.TrimSpace(data))
+	for _, prompt := range prompts {
+		if bytes.HasSuffix(data, prompt) {
+			return true
+		}
+	}
+	return false
+}
+
+func telnetParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.SrcPort != telnetPort && tcp.DstPort != telnetPort {
+		return false
+	}
+
+	data := telnetData(tcp.Payload)
+	if len(data) == 0 {
+		return false
+	}
+
+	fromServer := tcp.SrcPort == telnetPort
+	key := fmt.Sprintf("%s:%d>%s:%d", srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+	if fromServer {
+		key = fmt.Sprintf("%s:%d>%s:%d", dstIP, tcp.DstPort, srcIP, tcp.SrcPort)
+	}
+
+	telnetLock.Lock()
+	defer telnetLock.Unlock()
+
+	sess, found := telnetSessions[key]
+	if fromServer {
+		state := telnetIdle
+		if telnetHasPrompt(data, telnetUserPrompts) {
+			state = telnetUser
+		} else if telnetHasPrompt(data, telnetPassPrompts) {
+			state = telnetPass
+		}
+
+		if state != telnetIdle {
+			if !found {
+				if len(telnetSessions) >= telnetMaxTracked {
+					telnetSessions = make(map[string]*telnetSession)
+				}
+				sess = &telnetSession{}
+				telnetSessions[key] = sess
+			}
+			sess.state = state
+			sess.field = sess.field[:0]
+		}
+		return false
+	} else if !found || sess.state == telnetIdle {
+		return false
+	}
+
+	for _, c := range data {
+		switch c {
+		case '\r', '\n':
+			if len(sess.field) == 0 {
+				continue
+			}
+			field := string(sess.field)
+			sess.field = sess.field[:0]
+
+			if sess.state == telnetUser {
+				sess.user = field
+				sess.state = telnetIdle
+			} else {
+				sess.state = telnetIdle
+				delete(telnetSessions, key)
+
+				NewSnifferEvent(
+					pkt,
+					"telnet",
+					srcIP.String(),
+					dstIP.String(),
+					SniffData{
+						"user": sess.user,
+						"pass": field,
+					},
+					"%s %s > %s:%s - %s %s",
+					tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "telnet"),
+					vIP(srcIP),
+					vIP(dstIP),
+					vPort(tcp.DstPort),
+					tui.Bold(sess.user),
+					tui.Red(field),
+				).Push()
+
+				publishCredential("telnet", srcIP, dstIP.String(), int(tcp.DstPort), sess.user, field)
+				return true
+			}
+		case 0x08, 0x7f:
+			// backspace and delete
+			if len(sess.field) > 0 {
+				sess.field = sess.field[:len(sess.field)-1]
+			}
+		default:
+			if c >= 0x20 && len(sess.field) < telnetMaxField {
+				sess.field = append(sess.field, c)
+			}
+		}
+	}
+
+	return false
+}
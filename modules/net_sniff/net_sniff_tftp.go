@@ -0,0 +1,208 @@
+package net_sniff
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/log"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of transfers tracked at the same time
+	tftpMaxTracked = 4096
+	// files bigger than this are logged but not saved
+	tftpMaxFileSize = 64 * 1024 * 1024
+)
+
+var tftpUnsafeChars = regexp.MustCompile(`[^\w\-\.]`)
+
+// a read or write transfer, the server answers the request from a new port
+// so transfers are identified by the address and port of the client
+type tftpTransfer struct {
+	Client    net.IP
+	Server    net.IP
+	Filename  string
+	Mode      string
+	Write     bool
+	BlockSize int
+	Started   time.Time
+	// last block received in sequence and the data so far, only kept if
+	// the file is going to be saved
+	Block     uint16
+	Size      int
+	Save      bool
+	Data      bytes.Buffer
+	Truncated bool
+}
+
+var (
+	tftpLock      = sync.Mutex{}
+	tftpTransfers = make(map[string]*tftpTransfer)
+	// folder where the transferred files are saved, empty to disable
+	tftpOutput = ""
+)
+
+func tftpEndpoint(ip net.IP, port layers.UDPPort) string {
+	return fmt.Sprintf("%s:%d", ip, port)
+}
+
+func (t *tftpTransfer) direction() string {
+	if t.Write {
+		return "write"
+	}
+	return "read"
+}
+
+// adds a data block, returns true when it's the last one
+func (t *tftpTransfer) add(block uint16, data []byte) bool {
+	// retransmissions and blocks out of order
+	if block != t.Block+1 {
+		return false
+	}
+
+	t.Block = block
+	t.Size += len(data)
+	if t.Save && !t.Truncated {
+		if t.Data.Len()+len(data) > tftpMaxFileSize {
+			t.Truncated = true
+			t.Data.Reset()
+		} else {
+			t.Data.Write(data)
+		}
+	}
+
+	return len(data) < t.BlockSize
+}
+
+func (t *tftpTransfer) save() (string, error) {
+	if err := os.MkdirAll(tftpOutput, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s_%s_%s", t.Started.Format("20060102150405"), t.Client, filepath.Base(t.Filename))
+	path := filepath.Join(tftpOutput, tftpUnsafeChars.ReplaceAllString(name, "_"))
+	return path, ioutil.WriteFile(path, t.Data.Bytes(), 0644)
+}
+
+func tftpEvent(pkt gopacket.Packet, srcIP, dstIP net.IP, t *tftpTransfer, kind string, data SniffData, what string) {
+	data["type"] = kind
+	data["filename"] = t.Filename
+	data["mode"] = t.Mode
+	data["direction"] = t.direction()
+
+	NewSnifferEvent(
+		pkt,
+		"tftp",
+		srcIP.String(),
+		dstIP.String(),
+		data,
+		"%s %s > %s : %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREBLACK, "tftp"),
+		vIP(srcIP),
+		vIP(dstIP),
+		what,
+	).Push()
+}
+
+func tftpRequest(pkt gopacket.Packet, srcIP, dstIP net.IP, udp *layers.UDP, req *packets.TFTPPacket) {
+	t := &tftpTransfer{
+		Client:    srcIP,
+		Server:    dstIP,
+		Filename:  req.Filename,
+		Mode:      req.Mode,
+		Write:     req.Opcode == packets.TFTPWriteRequest,
+		BlockSize: req.BlockSize(),
+		Started:   time.Now(),
+		Save:      tftpOutput != "",
+	}
+
+	tftpLock.Lock()
+	if len(tftpTransfers) >= tftpMaxTracked {
+		tftpTransfers = make(map[string]*tftpTransfer)
+	}
+	tftpTransfers[tftpEndpoint(srcIP, udp.SrcPort)] = t
+	tftpLock.Unlock()
+
+	tftpEvent(pkt, srcIP, dstIP, t, "request", SniffData{"options": req.Options},
+		fmt.Sprintf("%s %s %s", tui.Blue(packets.TFTPOpcodeNames[req.Opcode]), tui.Yellow(req.Filename), tui.Dim(req.Mode)))
+}
+
+func tftpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort == packets.TFTPPort {
+		if req, err := packets.ParseTFTP(udp.Payload); err == nil && req.IsRequest() {
+			tftpRequest(pkt, srcIP, dstIP, udp, req)
+			return true
+		}
+		return false
+	}
+
+	// either the client is sending or receiving
+	key := tftpEndpoint(srcIP, udp.SrcPort)
+	tftpLock.Lock()
+	t, found := tftpTransfers[key]
+	if !found {
+		key = tftpEndpoint(dstIP, udp.DstPort)
+		t, found = tftpTransfers[key]
+	}
+	tftpLock.Unlock()
+	if !found {
+		return false
+	}
+
+	p, err := packets.ParseTFTP(udp.Payload)
+	if err != nil {
+		return false
+	}
+
+	tftpLock.Lock()
+	done := false
+	switch p.Opcode {
+	case packets.TFTPOptionAck:
+		t.BlockSize = p.BlockSize()
+	case packets.TFTPData:
+		done = t.add(p.Block, p.Data)
+	case packets.TFTPError:
+		done = true
+	}
+	if done {
+		delete(tftpTransfers, key)
+	}
+	tftpLock.Unlock()
+
+	if !done {
+		return true
+	}
+
+	// the transfer is not shared anymore
+	if p.Opcode == packets.TFTPData {
+		data := SniffData{"size": t.Size}
+		what := fmt.Sprintf("%s %s %s", t.direction(), tui.Yellow(t.Filename), tui.Dim(fmt.Sprintf("%d bytes", t.Size)))
+		if t.Save && !t.Truncated {
+			if path, err := t.save(); err != nil {
+				log.Error("could not save tftp file %s: %v", t.Filename, err)
+			} else {
+				data["saved"] = path
+				what += " saved to " + tui.Bold(path)
+			}
+		}
+		tftpEvent(pkt, srcIP, dstIP, t, "complete", data, what)
+	} else {
+		tftpEvent(pkt, srcIP, dstIP, t, "error", SniffData{"error_code": p.ErrorCode, "message": p.Message},
+			fmt.Sprintf("%s %s %s", t.direction(), tui.Yellow(t.Filename), tui.Red(p.Message)))
+	}
+
+	return true
+}
@@ -0,0 +1,226 @@
+package net_sniff
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"regexp"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// endpoint meta with the fingerprints of the TLS clients of a host
+	tlsJA3Meta = "tls.ja3"
+	// endpoint meta with the fingerprints of the TLS servers of a host
+	tlsJA3SMeta = "tls.ja3s"
+	// maximum number of handshakes tracked while waiting for the certificates
+	tlsMaxTracked = 4096
+)
+
+// the server side of a handshake, from the ClientHello to its certificates
+type tlsHandshake struct {
+	sni    string
+	stream *packets.TLSHandshakeStream
+}
+
+var (
+	tlsLock       = sync.Mutex{}
+	tlsHandshakes = make(map[string]*tlsHandshake)
+)
+
+func tlsServerKey(server net.IP, serverPort layers.TCPPort, client net.IP, clientPort layers.TCPPort) string {
+	return fmt.Sprintf("%s:%d>%s:%d", server, serverPort, client, clientPort)
+}
+
+func tlsTrack(key string) *tlsHandshake {
+	if len(tlsHandshakes) >= tlsMaxTracked {
+		tlsHandshakes = make(map[string]*tlsHandshake)
+	}
+	h := &tlsHandshake{}
+	tlsHandshakes[key] = h
+	return h
+}
+
+// poor man's TLS Client Hello with SNI extension parser :P
+// used when the hello doesn't fit in a single segment
+var sniRe = regexp.MustCompile("\x00\x00.{4}\x00.{2}([a-z0-9]+([\\-\\.]{1}[a-z0-9]+)*\\.[a-z]{2,6})\x00")
+
+func sniParser(srcIP, dstIP net.IP, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	m := sniRe.FindSubmatch(tcp.Payload)
+	if len(m) < 2 {
+		return false
+	}
+
+	domain := string(m[1])
+	if tcp.DstPort != 443 {
+		domain = vHostPort(domain, tcp.DstPort)
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"https",
+		srcIP.String(),
+		domain,
+		nil,
+		"%s %s > %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "sni"),
+		vIP(srcIP),
+		tui.Yellow("https://"+domain),
+	).Push()
+
+	return true
+}
+
+func tlsOnClientHello(srcIP, dstIP net.IP, pkt gopacket.Packet, tcp *layers.TCP, hello *packets.TLSClientHello) {
+	ja3 := hello.JA3()
+	addMeta(srcIP, tlsJA3Meta, ja3)
+
+	tlsLock.Lock()
+	tlsTrack(tlsServerKey(dstIP, tcp.DstPort, srcIP, tcp.SrcPort)).sni = hello.SNI
+	tlsLock.Unlock()
+
+	server := hello.SNI
+	if server == "" {
+		server = dstIP.String()
+	}
+	if tcp.DstPort != 443 {
+		server = vHostPort(server, tcp.DstPort)
+	}
+
+	alpn := ""
+	if len(hello.ALPN) > 0 {
+		alpn = tui.Dim(strings.Join(hello.ALPN, ","))
+	}
+
+	NewSnifferEvent(
+		pkt,
+		"tls",
+		srcIP.String(),
+		server,
+		SniffData{
+			"type":       "client",
+			"sni":        hello.SNI,
+			"version":    packets.TLSVersionName(hello.NegotiableVersion()),
+			"ja3":        ja3,
+			"ja3_string": hello.JA3String(),
+			"ciphers":    hello.CipherSuites,
+			"alpn":       hello.ALPN,
+		},
+		"%s %s > %s %s %s %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "tls"),
+		vIP(srcIP),
+		tui.Yellow("https://"+server),
+		tui.Dim(packets.TLSVersionName(hello.NegotiableVersion())),
+		alpn,
+		tui.Dim("ja3:"+ja3),
+	).Push()
+}
+
+func tlsOnServerHello(srcIP, dstIP net.IP, pkt gopacket.Packet, tcp *layers.TCP, hello *packets.TLSServerHello) {
+	ja3s := hello.JA3S()
+	addMeta(srcIP, tlsJA3SMeta, ja3s)
+
+	cipher := fmt.Sprintf("0x%04x", hello.CipherSuite)
+
+	NewSnifferEvent(
+		pkt,
+		"tls",
+		vHostPort(srcIP.String(), tcp.SrcPort),
+		dstIP.String(),
+		SniffData{
+			"type":        "server",
+			"version":     packets.TLSVersionName(hello.NegotiatedVersion()),
+			"cipher":      hello.CipherSuite,
+			"ja3s":        ja3s,
+			"ja3s_string": hello.JA3SString(),
+			"alpn":        hello.ALPN,
+		},
+		"%s %s:%s > %s %s %s %s %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "tls"),
+		vIP(srcIP),
+		vPort(tcp.SrcPort),
+		vIP(dstIP),
+		tui.Dim(packets.TLSVersionName(hello.NegotiatedVersion())),
+		tui.Dim(cipher),
+		tui.Dim(hello.ALPN),
+		tui.Dim("ja3s:"+ja3s),
+	).Push()
+}
+
+// feeds the handshake sent by the server until its certificates are there
+func tlsOnServerData(srcIP, dstIP net.IP, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	key := tlsServerKey(srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+
+	tlsLock.Lock()
+	h, found := tlsHandshakes[key]
+	if !found || h.stream == nil {
+		tlsLock.Unlock()
+		return false
+	}
+
+	messages, err := h.stream.Feed(tcp.Payload)
+	done := err != nil || h.stream.Closed
+	var certs []*x509.Certificate
+	for _, msg := range messages {
+		if packets.IsTLSCertificateMessage(msg) {
+			certs, _ = packets.ParseTLSCertificates(msg)
+			done = true
+		} else if packets.IsTLSServerHelloDone(msg) {
+			done = true
+		}
+	}
+	if done {
+		delete(tlsHandshakes, key)
+	}
+	tlsLock.Unlock()
+
+	if len(certs) > 0 {
+		tlsOnCertificates(srcIP, dstIP, pkt, tcp, h.sni, certs)
+	}
+	return true
+}
+
+func tlsParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	data := tcp.Payload
+	if tlsOnServerData(srcIP, dstIP, pkt, tcp) {
+		return true
+	} else if len(data) < 2 || data[0] != 0x16 || data[1] != 0x03 {
+		return false
+	}
+
+	if hello, err := packets.ParseTLSClientHello(data); err == nil {
+		tlsOnClientHello(srcIP, dstIP, pkt, tcp, hello)
+		return true
+	} else if hello, err := packets.ParseTLSServerHello(data); err == nil {
+		tlsOnServerHello(srcIP, dstIP, pkt, tcp, hello)
+
+		key := tlsServerKey(srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+		tlsLock.Lock()
+		// the certificates follow in clear text up to TLS 1.2
+		if hello.NegotiatedVersion() >= 0x0304 {
+			delete(tlsHandshakes, key)
+			tlsLock.Unlock()
+			return true
+		}
+		h, found := tlsHandshakes[key]
+		if !found {
+			h = tlsTrack(key)
+		}
+		h.stream = &packets.TLSHandshakeStream{}
+		tlsLock.Unlock()
+
+		tlsOnServerData(srcIP, dstIP, pkt, tcp)
+		return true
+	}
+
+	return sniParser(srcIP, dstIP, pkt, tcp)
+}
@@ -0,0 +1,41 @@
+package net_sniff
+
+import (
+	"strings"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+)
+
+// tunneledPacket is a packet carried by one or more GRE, VXLAN or Geneve
+// tunnels, from the outermost one, parsed as if it was captured directly.
+type tunneledPacket struct {
+	gopacket.Packet
+	tunnels []*packets.Tunnel
+}
+
+func (p tunneledPacket) Tunnel() string {
+	parts := make([]string, len(p.tunnels))
+	for i, t := range p.tunnels {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, " / ")
+}
+
+// decapsulate returns the packet carried by a tunnel, if any.
+func decapsulate(pkt gopacket.Packet) gopacket.Packet {
+	inner, tunnel := packets.Decapsulate(pkt)
+	if inner == nil {
+		return nil
+	}
+
+	tunnels := []*packets.Tunnel{}
+	if outer, ok := pkt.(tunneledPacket); ok {
+		tunnels = append(tunnels, outer.tunnels...)
+	}
+	return tunneledPacket{
+		Packet:  inner,
+		tunnels: append(tunnels, tunnel),
+	}
+}
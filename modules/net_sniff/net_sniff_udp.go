@@ -10,17 +10,46 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
-var udpParsers = []func(net.IP, net.IP, []byte, gopacket.Packet, *layers.UDP) bool{
-	dnsParser,
-	mdnsParser,
-	krb5Parser,
-	upnpParser,
+// the parsers of bettercap, tried in this order
+var builtinUDPParsers = []struct {
+	name   string
+	parser UDPParser
+}{
+	{"dns", dnsParser},
+	{"mdns", mdnsParser},
+	{"llmnr", llmnrParser},
+	{"nbns", nbnsParser},
+	{"dhcp", dhcpParser},
+	{"krb5", krb5Parser},
+	{"upnp", upnpParser},
+	{"snmp", snmpParser},
+	{"sip", sipParser},
+	{"quic", quicParser},
+	{"rtp", rtpParser},
+	{"tftp", tftpParser},
+	{"syslog", syslogParser},
+	{"ntp", ntpParser},
+	{"stun", stunParser},
+}
+
+func init() {
+	for _, p := range builtinUDPParsers {
+		udpParsers.Add(&Parser{
+			Name:      p.name,
+			Transport: "udp",
+			Priority:  BuiltinParserPriority,
+			Source:    parserSourceBuiltin,
+			udp:       p.parser,
+		})
+	}
 }
 
 func onUDP(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, verbose bool) {
 	udp := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
-	for _, parser := range udpParsers {
-		if parser(srcIP, dstIP, payload, pkt, udp) {
+	matchPatterns(pkt, "udp", srcIP, dstIP, int(udp.SrcPort), int(udp.DstPort), udp.Payload)
+
+	for _, p := range udpParsers.List() {
+		if p.udp(srcIP, dstIP, payload, pkt, udp) {
 			return
 		}
 	}
@@ -28,10 +57,10 @@ func onUDP(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, verbose boo
 	if verbose {
 		sz := len(payload)
 		NewSnifferEvent(
-			pkt.Metadata().Timestamp,
+			pkt,
 			"udp",
-			fmt.Sprintf("%s:%s", srcIP, vPort(udp.SrcPort)),
-			fmt.Sprintf("%s:%s", dstIP, vPort(udp.DstPort)),
+			net.JoinHostPort(srcIP.String(), vPort(udp.SrcPort)),
+			net.JoinHostPort(dstIP.String(), vPort(udp.DstPort)),
 			SniffData{
 				"Size": sz,
 			},
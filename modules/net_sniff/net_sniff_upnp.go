@@ -3,38 +3,70 @@ package net_sniff
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 
-	"github.com/evilsocket/islazy/str"
 	"github.com/evilsocket/islazy/tui"
 )
 
 func upnpParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, udp *layers.UDP) bool {
-	if data := packets.UPNPGetMeta(pkt); len(data) > 0 {
-		s := ""
-		for name, value := range data {
-			s += fmt.Sprintf("%s:%s ", tui.Blue(name), tui.Yellow(value))
+	if udp.SrcPort != packets.UPNPPort && udp.DstPort != packets.UPNPPort {
+		return false
+	}
+
+	msg, err := packets.ParseSSDP(udp.Payload)
+	if err != nil {
+		return false
+	}
+
+	kind := "response"
+	what := ""
+	switch {
+	case msg.IsSearch():
+		kind = "search"
+		what = fmt.Sprintf("searching %s", tui.Yellow(msg.Target))
+	case msg.Method == "NOTIFY":
+		kind = "notify"
+		what = tui.Dim(strings.TrimPrefix(msg.SubType, "ssdp:")) + " "
+	}
+
+	if !msg.IsSearch() {
+		if endpoint := session.I.Lan.GetByIp(srcIP.String()); endpoint != nil {
+			endpoint.OnMeta(msg.Meta())
 		}
 
-		NewSnifferEvent(
-			pkt.Metadata().Timestamp,
-			"upnp",
-			srcIP.String(),
-			dstIP.String(),
-			nil,
-			"%s %s -> %s : %s",
-			tui.Wrap(tui.BACKRED+tui.FOREBLACK, "upnp"),
-			vIP(srcIP),
-			vIP(dstIP),
-			str.Trim(s),
-		).Push()
-
-		return true
+		device := msg.DeviceType()
+		if device == "" {
+			device = msg.Target
+		}
+		what += fmt.Sprintf("%s %s %s", tui.Yellow(device), tui.Dim(msg.Server), msg.Location)
 	}
 
-	return false
+	NewSnifferEvent(
+		pkt,
+		"upnp",
+		srcIP.String(),
+		dstIP.String(),
+		SniffData{
+			"type":     kind,
+			"nts":      msg.SubType,
+			"target":   msg.Target,
+			"device":   msg.DeviceType(),
+			"usn":      msg.USN,
+			"server":   msg.Server,
+			"location": msg.Location,
+		},
+		"%s %s -> %s : %s",
+		tui.Wrap(tui.BACKRED+tui.FOREBLACK, "upnp"),
+		vIP(srcIP),
+		vIP(dstIP),
+		strings.TrimSpace(what),
+	).Push()
+
+	return true
 }
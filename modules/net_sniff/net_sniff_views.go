@@ -11,9 +11,9 @@ import (
 )
 
 func vIP(ip net.IP) string {
-	if session.I.Interface.IP.Equal(ip) {
+	if session.I.Interface.IP.Equal(ip) || session.I.Interface.IPv6.Equal(ip) {
 		return tui.Dim("local")
-	} else if session.I.Gateway.IP.Equal(ip) {
+	} else if session.I.Gateway.IP.Equal(ip) || session.I.Gateway.IPv6.Equal(ip) {
 		return "gateway"
 	}
 
@@ -28,6 +28,11 @@ func vIP(ip net.IP) string {
 	return address
 }
 
+// host:port with the brackets needed by IPv6 addresses
+func vHostPort(host string, port interface{}) string {
+	return net.JoinHostPort(host, fmt.Sprintf("%d", port))
+}
+
 func vPort(p interface{}) string {
 	sp := fmt.Sprintf("%d", p)
 	if tcp, ok := p.(layers.TCPPort); ok {
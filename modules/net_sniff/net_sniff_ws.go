@@ -0,0 +1,138 @@
+package net_sniff
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// maximum number of upgraded connections tracked, two entries each
+	wsMaxTracked = 4096
+	// bytes of the payload shown for text and binary frames
+	wsMaxTextPreview   = 64
+	wsMaxBinaryPreview = 16
+)
+
+// one direction of an upgraded connection
+type wsStream struct {
+	// bytes of a frame split across segments still to come
+	skip uint64
+}
+
+var (
+	wsLock    = sync.Mutex{}
+	wsStreams = make(map[string]*wsStream)
+)
+
+func wsKey(srcIP net.IP, srcPort layers.TCPPort, dstIP net.IP, dstPort layers.TCPPort) string {
+	return fmt.Sprintf("%s:%d>%s:%d", srcIP, srcPort, dstIP, dstPort)
+}
+
+// wsTrack starts decoding the frames of a connection, called once the
+// server accepted its upgrade.
+func wsTrack(client net.IP, clientPort layers.TCPPort, server net.IP, serverPort layers.TCPPort) {
+	wsLock.Lock()
+	defer wsLock.Unlock()
+
+	if len(wsStreams) >= wsMaxTracked*2 {
+		wsStreams = make(map[string]*wsStream)
+	}
+	wsStreams[wsKey(client, clientPort, server, serverPort)] = &wsStream{}
+	wsStreams[wsKey(server, serverPort, client, clientPort)] = &wsStream{}
+}
+
+func wsForget(srcIP net.IP, srcPort layers.TCPPort, dstIP net.IP, dstPort layers.TCPPort) {
+	delete(wsStreams, wsKey(srcIP, srcPort, dstIP, dstPort))
+	delete(wsStreams, wsKey(dstIP, dstPort, srcIP, srcPort))
+}
+
+func wsPreview(f *packets.WebSocketFrame) string {
+	data := f.Payload
+	switch {
+	case f.Opcode == packets.WebSocketClose && len(data) >= 2:
+		return fmt.Sprintf("%d %s", int(data[0])<<8|int(data[1]), data[2:])
+	case utf8.Valid(data) && f.Opcode != packets.WebSocketBinary:
+		if len(data) > wsMaxTextPreview {
+			return fmt.Sprintf("%q...", data[:wsMaxTextPreview])
+		}
+		return fmt.Sprintf("%q", data)
+	case len(data) > wsMaxBinaryPreview:
+		return fmt.Sprintf("%x...", data[:wsMaxBinaryPreview])
+	}
+	return fmt.Sprintf("%x", data)
+}
+
+func wsParser(srcIP, dstIP net.IP, payload []byte, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	key := wsKey(srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+
+	wsLock.Lock()
+	defer wsLock.Unlock()
+
+	stream, found := wsStreams[key]
+	if !found {
+		return false
+	} else if tcp.FIN || tcp.RST {
+		defer wsForget(srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+	}
+
+	data := tcp.Payload
+	if len(data) == 0 {
+		return true
+	}
+
+	// the rest of a frame we already reported
+	if stream.skip > 0 {
+		if uint64(len(data)) <= stream.skip {
+			stream.skip -= uint64(len(data))
+			return true
+		}
+		data = data[stream.skip:]
+		stream.skip = 0
+	}
+
+	frames, err := packets.ParseWebSocketFrames(data)
+	if err != nil {
+		// lost track of the frame boundaries
+		wsForget(srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+		return false
+	}
+
+	for _, f := range frames {
+		stream.skip = f.Missing
+		if f.Opcode == packets.WebSocketClose {
+			wsForget(srcIP, tcp.SrcPort, dstIP, tcp.DstPort)
+		}
+
+		NewSnifferEvent(
+			pkt,
+			"ws",
+			vHostPort(srcIP.String(), tcp.SrcPort),
+			vHostPort(dstIP.String(), tcp.DstPort),
+			SniffData{
+				"opcode":  f.OpcodeName(),
+				"fin":     f.Fin,
+				"masked":  f.Masked,
+				"length":  f.Length,
+				"payload": f.Payload,
+			},
+			"%s %s > %s : %s %s %s",
+			tui.Wrap(tui.BACKLIGHTBLUE+tui.FOREBLACK, "ws"),
+			vHostPort(vIP(srcIP), tcp.SrcPort),
+			vHostPort(vIP(dstIP), tcp.DstPort),
+			tui.Blue(f.OpcodeName()),
+			tui.Dim(fmt.Sprintf("%d bytes", f.Length)),
+			wsPreview(f),
+		).Push()
+	}
+
+	return true
+}
@@ -0,0 +1,132 @@
+package netflow_export
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// flows are unidirectional, as NetFlow and IPFIX want them
+type flowKey struct {
+	src     string
+	dst     string
+	srcPort uint16
+	dstPort uint16
+	proto   uint8
+}
+
+type flow struct {
+	record packets.FlowRecord
+	// a FIN or RST has been seen
+	finished bool
+}
+
+type flowCache struct {
+	sync.Mutex
+	flows    map[flowKey]*flow
+	active   time.Duration
+	inactive time.Duration
+}
+
+func newFlowCache(active time.Duration, inactive time.Duration) *flowCache {
+	return &flowCache{
+		flows:    make(map[flowKey]*flow),
+		active:   active,
+		inactive: inactive,
+	}
+}
+
+func tcpFlags(tcp *layers.TCP) (flags uint8) {
+	for i, set := range []bool{tcp.FIN, tcp.SYN, tcp.RST, tcp.PSH, tcp.ACK, tcp.URG, tcp.ECE, tcp.CWR} {
+		if set {
+			flags |= 1 << uint(i)
+		}
+	}
+	return
+}
+
+// Add accounts a packet to its flow.
+func (c *flowCache) Add(pkt gopacket.Packet) {
+	var src, dst net.IP
+	var proto uint8
+	var size uint64
+
+	if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		src, dst, proto, size = ip4.SrcIP, ip4.DstIP, uint8(ip4.Protocol), uint64(ip4.Length)
+	} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		// the payload length doesn't include the fixed header
+		src, dst, proto, size = ip6.SrcIP, ip6.DstIP, uint8(ip6.NextHeader), uint64(ip6.Length)+40
+	} else {
+		return
+	}
+
+	key := flowKey{src: src.String(), dst: dst.String(), proto: proto}
+	flags := uint8(0)
+	finished := false
+	if tcp, ok := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
+		key.srcPort, key.dstPort = uint16(tcp.SrcPort), uint16(tcp.DstPort)
+		key.proto = uint8(layers.IPProtocolTCP)
+		flags = tcpFlags(tcp)
+		finished = tcp.FIN || tcp.RST
+	} else if udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP); ok {
+		key.srcPort, key.dstPort = uint16(udp.SrcPort), uint16(udp.DstPort)
+		key.proto = uint8(layers.IPProtocolUDP)
+	}
+
+	seen := pkt.Metadata().Timestamp
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	f, found := c.flows[key]
+	if !found {
+		f = &flow{
+			record: packets.FlowRecord{
+				SrcIP:    src,
+				DstIP:    dst,
+				SrcPort:  key.srcPort,
+				DstPort:  key.dstPort,
+				Protocol: key.proto,
+				Start:    seen,
+			},
+		}
+		c.flows[key] = f
+	}
+
+	f.record.Packets++
+	f.record.Bytes += size
+	f.record.TCPFlags |= flags
+	f.record.End = seen
+	f.finished = f.finished || finished
+}
+
+// Expire removes and returns the flows that are finished, idle for longer
+// than the inactive timeout or that lasted longer than the active one, all
+// of them if flush is true.
+func (c *flowCache) Expire(now time.Time, flush bool) []packets.FlowRecord {
+	c.Lock()
+	defer c.Unlock()
+
+	expired := make([]packets.FlowRecord, 0)
+	for key, f := range c.flows {
+		if flush || f.finished || now.Sub(f.record.End) >= c.inactive || now.Sub(f.record.Start) >= c.active {
+			expired = append(expired, f.record)
+			delete(c.flows, key)
+		}
+	}
+	return expired
+}
+
+func (c *flowCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.flows)
+}
@@ -0,0 +1,237 @@
+package netflow_export
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// how often the flow cache is checked for expired flows
+const expirePeriod = time.Second
+
+type NetFlowExport struct {
+	session.SessionModule
+
+	handle    *pcap.Handle
+	conn      net.Conn
+	collector string
+	exporter  *packets.FlowExporter
+	cache     *flowCache
+	exported  uint64
+	sent      uint64
+	quit      chan bool
+	waitGroup *sync.WaitGroup
+}
+
+func NewNetFlowExport(s *session.Session) *NetFlowExport {
+	mod := &NetFlowExport{
+		SessionModule: session.NewSessionModule("netflow.export", s),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewStringParameter("netflow.export.collector",
+		"127.0.0.1:2055",
+		"",
+		"Address and port of the collector the flows are sent to over UDP."))
+
+	mod.AddParam(session.NewStringParameter("netflow.export.version",
+		"v9",
+		"^(v9|ipfix)$",
+		"Export protocol, v9 for NetFlow v9 or ipfix."))
+
+	mod.AddParam(session.NewStringParameter("netflow.export.filter",
+		"ip or ip6",
+		"",
+		"BPF filter of the traffic accounted in the flows."))
+
+	mod.AddParam(session.NewIntParameter("netflow.export.active",
+		"60",
+		"Seconds after which a flow that is still active is exported."))
+
+	mod.AddParam(session.NewIntParameter("netflow.export.inactive",
+		"15",
+		"Seconds without packets after which a flow is exported."))
+
+	mod.AddParam(session.NewIntParameter("netflow.export.domain",
+		"0",
+		"Source id (NetFlow v9) or observation domain id (IPFIX) of the exported flows."))
+
+	mod.AddHandler(session.NewModuleHandler("netflow.export on", "",
+		"Start exporting the sniffed traffic as flow records to the collector.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("netflow.export off", "",
+		"Stop exporting flow records, the flows still in the cache are exported.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("netflow.export.show", "",
+		"Show the flow export statistics.",
+		func(args []string) error {
+			return mod.show()
+		}))
+
+	return mod
+}
+
+func (mod *NetFlowExport) Name() string {
+	return "netflow.export"
+}
+
+func (mod *NetFlowExport) Description() string {
+	return "Turn the sniffed traffic into flow records and export them to a NetFlow v9 or IPFIX collector."
+}
+
+func (mod *NetFlowExport) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NetFlowExport) Configure() (err error) {
+	var version, filter string
+	var active, inactive, domain int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	} else if err, mod.collector = mod.StringParam("netflow.export.collector"); err != nil {
+		return err
+	} else if err, version = mod.StringParam("netflow.export.version"); err != nil {
+		return err
+	} else if err, filter = mod.StringParam("netflow.export.filter"); err != nil {
+		return err
+	} else if err, active = mod.IntParam("netflow.export.active"); err != nil {
+		return err
+	} else if err, inactive = mod.IntParam("netflow.export.inactive"); err != nil {
+		return err
+	} else if err, domain = mod.IntParam("netflow.export.domain"); err != nil {
+		return err
+	} else if active <= 0 || inactive <= 0 {
+		return fmt.Errorf("netflow.export.active and netflow.export.inactive must be greater than zero")
+	} else if domain < 0 {
+		return fmt.Errorf("netflow.export.domain can't be negative")
+	} else if _, _, err = net.SplitHostPort(mod.collector); err != nil {
+		return fmt.Errorf("invalid collector address %s: %v", mod.collector, err)
+	} else if mod.conn, err = network.DNS.Dial("udp", mod.collector); err != nil {
+		return err
+	} else if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
+		mod.conn.Close()
+		return err
+	} else if filter != "" {
+		if err = mod.handle.SetBPFFilter(filter); err != nil {
+			mod.handle.Close()
+			mod.conn.Close()
+			return err
+		}
+	}
+
+	if version == "ipfix" {
+		mod.exporter = packets.NewFlowExporter(packets.IPFIX, uint32(domain))
+	} else {
+		mod.exporter = packets.NewFlowExporter(packets.NetFlowV9, uint32(domain))
+	}
+
+	mod.cache = newFlowCache(time.Duration(active)*time.Second, time.Duration(inactive)*time.Second)
+	mod.exported = 0
+	mod.sent = 0
+	mod.quit = make(chan bool)
+
+	return nil
+}
+
+func (mod *NetFlowExport) export(flush bool) {
+	records := mod.cache.Expire(time.Now(), flush)
+	if len(records) == 0 {
+		return
+	}
+
+	for _, msg := range mod.exporter.Encode(records, time.Now()) {
+		if _, err := mod.conn.Write(msg); err != nil {
+			mod.Debug("could not send flows to %s: %v", mod.collector, err)
+			continue
+		}
+		atomic.AddUint64(&mod.sent, 1)
+	}
+	atomic.AddUint64(&mod.exported, uint64(len(records)))
+}
+
+func (mod *NetFlowExport) expireWorker() {
+	defer mod.waitGroup.Done()
+
+	ticker := time.NewTicker(expirePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mod.export(false)
+		case <-mod.quit:
+			mod.export(true)
+			return
+		}
+	}
+}
+
+func (mod *NetFlowExport) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("exporting flows to %s ...", tui.Bold(mod.collector))
+
+		mod.waitGroup.Add(1)
+		go mod.expireWorker()
+
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+		for pkt := range src.Packets() {
+			if !mod.Running() {
+				break
+			}
+			mod.cache.Add(pkt)
+		}
+	})
+}
+
+func (mod *NetFlowExport) show() error {
+	if mod.cache == nil {
+		mod.Info("no flows exported yet")
+		return nil
+	}
+
+	rows := [][]string{
+		{"Collector", mod.collector},
+		{"Active flows", fmt.Sprintf("%d", mod.cache.Len())},
+		{"Exported flows", fmt.Sprintf("%d", atomic.LoadUint64(&mod.exported))},
+		{"Packets sent", fmt.Sprintf("%d", atomic.LoadUint64(&mod.sent))},
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Name", "Value"}, rows)
+	mod.Session.Refresh()
+	return nil
+}
+
+func (mod *NetFlowExport) Stop() error {
+	return mod.SetRunning(false, func() {
+		close(mod.quit)
+		mod.handle.Close()
+		mod.waitGroup.Wait()
+		mod.conn.Close()
+		mod.Info("%d flows exported to %s", mod.exported, mod.collector)
+	})
+}
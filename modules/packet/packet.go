@@ -0,0 +1,169 @@
+package packet
+
+import (
+	"net"
+	"sync"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/plugin"
+	"github.com/evilsocket/islazy/tui"
+)
+
+type PacketCrafter struct {
+	session.SessionModule
+
+	replaying bool
+	quit      chan bool
+	waitGroup *sync.WaitGroup
+}
+
+func NewPacketCrafter(s *session.Session) *PacketCrafter {
+	mod := &PacketCrafter{
+		SessionModule: session.NewSessionModule("packet", s),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddHandler(session.NewModuleHandler("packet.send SPEC", `packet\.send\s+(.+)`,
+		"Craft and send a packet described by a list of field=value pairs (like ip.dst=192.168.1.10 tcp.dport=80 tcp.flags=S payload=\"hello\") or by a raw hex template (hex:ffffffffffff...), the missing fields are filled with the interface addresses.",
+		func(args []string) error {
+			return mod.send(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("packet.replay FILENAME", `packet\.replay\s+(.+)`,
+		"Replay the packets of a PCAP file on the interface.",
+		func(args []string) error {
+			return mod.replay(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("packet.replay.stop", "",
+		"Stop the replay in progress.",
+		func(args []string) error {
+			return mod.stopReplay()
+		}))
+
+	mod.AddParam(session.NewDecimalParameter("packet.replay.speed",
+		"1.0",
+		"Replay speed as a multiplier of the original timing, 0 to send the packets as fast as possible."))
+
+	mod.AddParam(session.NewIntParameter("packet.replay.pps",
+		"0",
+		"If greater than 0, maximum number of packets per second to replay."))
+
+	mod.AddParam(session.NewIntParameter("packet.replay.loop",
+		"1",
+		"Number of times the PCAP file is replayed, 0 to loop until packet.replay.stop."))
+
+	plugin.Defines["packet"] = packetPackage{mod}
+
+	return mod
+}
+
+func (mod *PacketCrafter) Name() string {
+	return "packet"
+}
+
+func (mod *PacketCrafter) Description() string {
+	return "A module to craft arbitrary packets and to replay PCAP files."
+}
+
+func (mod *PacketCrafter) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *PacketCrafter) Configure() error {
+	return nil
+}
+
+func (mod *PacketCrafter) Start() error {
+	return nil
+}
+
+func (mod *PacketCrafter) Stop() error {
+	if mod.replaying {
+		return mod.stopReplay()
+	}
+	return nil
+}
+
+// multicast addresses map to their own ethernet groups
+func multicastMAC(ip net.IP) net.HardwareAddr {
+	if ip4 := ip.To4(); ip4 != nil {
+		return net.HardwareAddr{0x01, 0x00, 0x5e, ip4[1] & 0x7f, ip4[2], ip4[3]}
+	}
+	return net.HardwareAddr{0x33, 0x33, ip[12], ip[13], ip[14], ip[15]}
+}
+
+func (mod *PacketCrafter) dstMAC(spec *packets.PacketSpec) net.HardwareAddr {
+	ip := spec.DstIP()
+	if ip == nil || spec.Has("eth.dst") {
+		return network.BroadcastHw
+	} else if ip.IsMulticast() {
+		return multicastMAC(ip)
+	} else if ip.Equal(net.IPv4bcast) {
+		return network.BroadcastHw
+	} else if ip.To4() != nil && !mod.Session.Interface.Net.Contains(ip) {
+		return mod.Session.Gateway.HW
+	}
+
+	hw, err := mod.Session.FindMAC(ip, true)
+	if err != nil {
+		mod.Warning("could not resolve the MAC address of %s, using broadcast: %v", ip, err)
+		return network.BroadcastHw
+	}
+	return hw
+}
+
+func (mod *PacketCrafter) send(line string) error {
+	spec, err := packets.ParsePacketSpec(line)
+	if err != nil {
+		return err
+	}
+
+	err, raw := spec.Build(packets.CraftDefaults{
+		SrcMAC:  mod.Session.Interface.HW,
+		DstMAC:  mod.dstMAC(spec),
+		SrcIP:   mod.Session.Interface.IP,
+		SrcIPv6: mod.Session.Interface.IPv6,
+	})
+	if err != nil {
+		return err
+	}
+
+	mod.Info("sending %d bytes: %s", len(raw), tui.Dim(spec.String()))
+
+	return mod.Session.Queue.Send(raw)
+}
+
+// packet.send and packet.replay from the scripts, both return an empty
+// string on success or the error message
+type packetPackage struct {
+	mod *PacketCrafter
+}
+
+func (p packetPackage) Send(spec string) string {
+	if err := p.mod.send(spec); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (p packetPackage) Replay(filename string) string {
+	if err := p.mod.replay(filename); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (p packetPackage) Stop() string {
+	if err := p.mod.stopReplay(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (p packetPackage) Fields() []string {
+	return packets.CraftFields
+}
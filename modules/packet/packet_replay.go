@@ -0,0 +1,156 @@
+package packet
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+type replayOptions struct {
+	speed float64
+	pps   int
+	loop  int
+}
+
+func (mod *PacketCrafter) replayOptions() (err error, opts replayOptions) {
+	if err, opts.speed = mod.DecParam("packet.replay.speed"); err != nil {
+		return
+	} else if err, opts.pps = mod.IntParam("packet.replay.pps"); err != nil {
+		return
+	} else if err, opts.loop = mod.IntParam("packet.replay.loop"); err != nil {
+		return
+	}
+
+	if opts.speed < 0 {
+		err = fmt.Errorf("packet.replay.speed can't be negative")
+	} else if opts.pps < 0 {
+		err = fmt.Errorf("packet.replay.pps can't be negative")
+	} else if opts.loop < 0 {
+		err = fmt.Errorf("packet.replay.loop can't be negative")
+	}
+	return
+}
+
+func (mod *PacketCrafter) replay(filename string) error {
+	if mod.replaying {
+		return fmt.Errorf("a replay is already in progress, use packet.replay.stop first")
+	}
+
+	err, opts := mod.replayOptions()
+	if err != nil {
+		return err
+	}
+
+	if filename, err = fs.Expand(filename); err != nil {
+		return err
+	}
+
+	// make sure the file can be read before going in background
+	handle, err := pcap.OpenOffline(filename)
+	if err != nil {
+		return err
+	}
+	handle.Close()
+
+	mod.replaying = true
+	mod.quit = make(chan bool)
+	mod.waitGroup.Add(1)
+	go mod.replayWorker(filename, opts)
+
+	return nil
+}
+
+// returns false if the replay has been stopped while waiting
+func (mod *PacketCrafter) wait(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-mod.quit:
+			return false
+		default:
+			return true
+		}
+	}
+
+	select {
+	case <-mod.quit:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func (mod *PacketCrafter) replayWorker(filename string, opts replayOptions) {
+	defer func() {
+		mod.replaying = false
+		mod.waitGroup.Done()
+	}()
+
+	var minDelay time.Duration
+	if opts.pps > 0 {
+		minDelay = time.Second / time.Duration(opts.pps)
+	}
+
+	sent := 0
+	started := time.Now()
+
+	mod.Info("replaying %s (speed:%.2f pps:%d loop:%d) ...", tui.Bold(filename), opts.speed, opts.pps, opts.loop)
+
+	for n := 0; opts.loop == 0 || n < opts.loop; n++ {
+		handle, err := pcap.OpenOffline(filename)
+		if err != nil {
+			mod.Error("error opening %s: %v", filename, err)
+			return
+		}
+
+		var prev time.Time
+		for {
+			data, ci, err := handle.ReadPacketData()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				mod.Debug("error reading packet from %s: %v", filename, err)
+				continue
+			}
+
+			delay := minDelay
+			if opts.speed > 0 && !prev.IsZero() {
+				if original := time.Duration(float64(ci.Timestamp.Sub(prev)) / opts.speed); original > delay {
+					delay = original
+				}
+			}
+			prev = ci.Timestamp
+
+			if !mod.wait(delay) {
+				handle.Close()
+				mod.Info("replay stopped after %d packets", sent)
+				return
+			}
+
+			if err := mod.Session.Queue.Send(data); err != nil {
+				mod.Error("error sending packet #%d: %v", sent, err)
+			} else {
+				sent++
+			}
+		}
+
+		handle.Close()
+	}
+
+	mod.Info("replayed %d packets in %s", sent, time.Since(started))
+}
+
+func (mod *PacketCrafter) stopReplay() error {
+	if !mod.replaying {
+		return fmt.Errorf("no replay in progress")
+	}
+
+	close(mod.quit)
+	mod.waitGroup.Wait()
+
+	return nil
+}
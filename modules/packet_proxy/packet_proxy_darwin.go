@@ -9,9 +9,11 @@ type PacketProxy struct {
 }
 
 func NewPacketProxy(s *session.Session) *PacketProxy {
-	return &PacketProxy{
+	mod := &PacketProxy{
 		SessionModule: session.NewSessionModule("packet.proxy", s),
 	}
+	mod.NotSupported()
+	return mod
 }
 
 func (mod PacketProxy) Name() string {
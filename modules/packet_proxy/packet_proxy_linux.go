@@ -9,6 +9,7 @@ import (
 	"syscall"
 
 	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/firewall"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/chifflier/nfqueue-go/nfqueue"
@@ -44,6 +45,8 @@ func NewPacketProxy(s *session.Session) *PacketProxy {
 		chainName:     "OUTPUT",
 	}
 
+	mod.Needs("iptables", firewall.Available)
+
 	mod.AddHandler(session.NewModuleHandler("packet.proxy on", "",
 		"Start the NFQUEUE based packet proxy.",
 		func(args []string) error {
@@ -142,6 +145,12 @@ func (mod *PacketProxy) Configure() (err error) {
 		return
 	}
 
+	mod.Claim(fmt.Sprintf("NFQUEUE %d", mod.queueNum))
+
+	if err = mod.CheckDependencies(); err != nil {
+		return
+	}
+
 	if mod.pluginPath == "" {
 		return fmt.Errorf("The parameter %s can not be empty.", tui.Bold("packet.proxy.plugin"))
 	} else if !fs.Exists(mod.pluginPath) {
@@ -0,0 +1,368 @@
+package port_knock
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	knockFilter = "tcp[tcpflags] & tcp-syn != 0"
+	// max connection attempts tracked for every client and host couple
+	maxAttempts = 32
+	// source port of the knocks we send
+	knockSourcePort = 31337
+)
+
+type attempt struct {
+	srcPort int
+	port    int
+	seq     uint32
+	seen    time.Time
+	// answered by a SYN+ACK, a knock is either reset or not answered at all
+	opened bool
+}
+
+// a knock sequence that opened a service
+type Sequence struct {
+	Client   string    `json:"client"`
+	Host     string    `json:"host"`
+	Knocks   []int     `json:"knocks"`
+	Port     int       `json:"port"`
+	Seen     time.Time `json:"seen"`
+	Sequence string    `json:"sequence"`
+}
+
+type PortKnock struct {
+	session.SessionModule
+	sync.Mutex
+
+	handle    *pcap.Handle
+	window    time.Duration
+	minKnocks int
+	attempts  map[string][]*attempt
+	sequences map[string]*Sequence
+	waitGroup *sync.WaitGroup
+}
+
+func NewPortKnock(s *session.Session) *PortKnock {
+	mod := &PortKnock{
+		SessionModule: session.NewSessionModule("port.knock", s),
+		attempts:      make(map[string][]*attempt),
+		sequences:     make(map[string]*Sequence),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewIntParameter("port.knock.window",
+		"10",
+		"Seconds within which the knocks and the connection to the opened service must happen."))
+
+	mod.AddParam(session.NewIntParameter("port.knock.min",
+		"2",
+		"Minimum number of distinct closed or filtered ports knocked on before a connection for it to be reported as a knock sequence."))
+
+	mod.AddParam(session.NewIntParameter("port.knock.delay",
+		"200",
+		"Milliseconds between the knocks sent by port.knock.send."))
+
+	mod.AddHandler(session.NewModuleHandler("port.knock on", "",
+		"Start detecting port knocking sequences in the sniffed traffic.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("port.knock off", "",
+		"Stop detecting port knocking sequences.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("port.knock.show", "",
+		"Show the detected port knocking sequences.",
+		func(args []string) error {
+			return mod.show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("port.knock.send ADDRESS SEQUENCE", `port\.knock\.send ([^\s]+) ([^\s]+)`,
+		"Knock on ADDRESS with SEQUENCE, a comma separated list of ports optionally prefixed by the protocol, for instance: port.knock.send 192.168.1.10 7000,udp:8000,9000",
+		func(args []string) error {
+			return mod.send(args[0], args[1])
+		}))
+
+	mod.InitState("sequences")
+
+	return mod
+}
+
+func (mod *PortKnock) Name() string {
+	return "port.knock"
+}
+
+func (mod *PortKnock) Description() string {
+	return "Detect port knocking sequences in the sniffed traffic and replay them."
+}
+
+func (mod *PortKnock) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *PortKnock) show() error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	if len(mod.sequences) == 0 {
+		mod.Info("no knock sequences detected yet")
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, seq := range mod.sequences {
+		rows = append(rows, []string{
+			seq.Host,
+			tui.Bold(seq.Sequence),
+			tui.Green(strconv.Itoa(seq.Port)),
+			seq.Client,
+			seq.Seen.Format("15:04:05"),
+		})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Host", "Sequence", "Opens", "Client", "Seen"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
+
+func (mod *PortKnock) send(address string, sequence string) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("'%s' is not a valid IP address", address)
+	}
+
+	seq, err := packets.ParseKnockSequence(sequence)
+	if err != nil {
+		return err
+	}
+
+	err, delay := mod.IntParam("port.knock.delay")
+	if err != nil {
+		return err
+	}
+
+	mac, err := mod.Session.FindMAC(ip, true)
+	if err != nil {
+		return fmt.Errorf("could not get MAC for %s: %v", ip, err)
+	}
+
+	from := mod.Session.Interface.IP
+	if ip.To4() == nil {
+		from = mod.Session.Interface.IPv6
+	}
+
+	for _, k := range seq {
+		if err, raw := packets.NewKnockPacket(from, mod.Session.Interface.HW, ip, mac, knockSourcePort, k); err != nil {
+			return err
+		} else if err = mod.Session.Queue.Send(raw); err != nil {
+			return err
+		}
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+	}
+
+	mod.Info("knocked on %s with %s", tui.Bold(ip.String()), packets.KnockSequenceString(seq))
+
+	return nil
+}
+
+func (mod *PortKnock) onOpened(client string, host string, port int, knocks []*attempt) {
+	ports := make([]int, 0)
+	seq := make([]packets.Knock, 0)
+	for _, a := range knocks {
+		ports = append(ports, a.port)
+		seq = append(seq, packets.Knock{Protocol: "tcp", Port: a.port})
+	}
+
+	key := fmt.Sprintf("%s|%s|%d|%v", client, host, port, ports)
+	if existing, found := mod.sequences[key]; found {
+		existing.Seen = time.Now()
+		return
+	}
+
+	found := &Sequence{
+		Client:   client,
+		Host:     host,
+		Knocks:   ports,
+		Port:     port,
+		Seen:     time.Now(),
+		Sequence: packets.KnockSequenceString(seq),
+	}
+	mod.sequences[key] = found
+	mod.State.Store("sequences", mod.sequences)
+
+	mod.Info("%s knocked on %s with %s and opened port %s",
+		client,
+		tui.Bold(host),
+		tui.Yellow(found.Sequence),
+		tui.Green(strconv.Itoa(port)))
+
+	mod.Session.Events.Add("port.knock.detected", *found)
+}
+
+func (mod *PortKnock) onPacket(pkt gopacket.Packet) {
+	var src, dst net.IP
+
+	if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		src, dst = ip4.SrcIP, ip4.DstIP
+	} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		src, dst = ip6.SrcIP, ip6.DstIP
+	} else {
+		return
+	}
+
+	tcp, ok := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP)
+	if !ok {
+		return
+	}
+
+	mod.Lock()
+	defer mod.Unlock()
+
+	now := time.Now()
+
+	if !tcp.ACK {
+		// connection attempt from client to host
+		key := src.String() + "|" + dst.String()
+		list := mod.attempts[key]
+
+		// forget the attempts outside of the window
+		for len(list) > 0 && now.Sub(list[0].seen) > mod.window {
+			list = list[1:]
+		}
+
+		// a retransmission is not another knock
+		for _, a := range list {
+			if a.srcPort == int(tcp.SrcPort) && a.port == int(tcp.DstPort) && a.seq == tcp.Seq {
+				return
+			}
+		}
+
+		if len(list) >= maxAttempts {
+			list = list[1:]
+		}
+
+		mod.attempts[key] = append(list, &attempt{
+			srcPort: int(tcp.SrcPort),
+			port:    int(tcp.DstPort),
+			seq:     tcp.Seq,
+			seen:    now,
+		})
+		return
+	}
+
+	// the host accepted a connection from the client
+	key := dst.String() + "|" + src.String()
+	port := int(tcp.SrcPort)
+	list, found := mod.attempts[key]
+	if !found {
+		return
+	}
+
+	accepted := -1
+	for i, a := range list {
+		if a.port == port && a.srcPort == int(tcp.DstPort) {
+			a.opened = true
+			accepted = i
+			break
+		}
+	}
+	if accepted == -1 {
+		return
+	}
+
+	// the attempts before it to ports that didn't accept a connection
+	knocks := make([]*attempt, 0)
+	ports := make(map[int]bool)
+	for _, a := range list[:accepted] {
+		if !a.opened && a.port != port && now.Sub(a.seen) <= mod.window {
+			knocks = append(knocks, a)
+			ports[a.port] = true
+		}
+	}
+
+	// a client retrying a closed or filtered port is not knocking
+	if len(ports) >= mod.minKnocks {
+		mod.onOpened(dst.String(), src.String(), port, knocks)
+		delete(mod.attempts, key)
+	}
+}
+
+func (mod *PortKnock) Configure() (err error) {
+	var window int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted(mod.Name())
+	} else if err, window = mod.IntParam("port.knock.window"); err != nil {
+		return err
+	} else if err, mod.minKnocks = mod.IntParam("port.knock.min"); err != nil {
+		return err
+	} else if window <= 0 {
+		return fmt.Errorf("port.knock.window must be greater than zero")
+	} else if mod.minKnocks < 1 {
+		return fmt.Errorf("port.knock.min must be greater than zero")
+	} else if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
+		return err
+	} else if err = mod.handle.SetBPFFilter(knockFilter); err != nil {
+		mod.handle.Close()
+		return err
+	}
+
+	mod.window = time.Duration(window) * time.Second
+
+	mod.Lock()
+	mod.attempts = make(map[string][]*attempt)
+	mod.Unlock()
+
+	return nil
+}
+
+func (mod *PortKnock) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("detecting knock sequences of at least %d ports within %s ...", mod.minKnocks, mod.window)
+
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+		for pkt := range src.Packets() {
+			if !mod.Running() {
+				break
+			}
+			mod.onPacket(pkt)
+		}
+	})
+}
+
+func (mod *PortKnock) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.handle.Close()
+		mod.waitGroup.Wait()
+	})
+}
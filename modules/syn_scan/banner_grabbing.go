@@ -4,12 +4,22 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bettercap/bettercap/packets"
+
 	"github.com/evilsocket/islazy/async"
 )
 
 const bannerGrabTimeout = time.Duration(5) * time.Second
 
-type bannerGrabberFn func(mod *SynScanner, ip string, port int) string
+// what a grabber found out about the service listening on a port, an empty
+// service means the one registered for the port number
+type serviceInfo struct {
+	Service string
+	Version string
+	Banner  string
+}
+
+type bannerGrabberFn func(mod *SynScanner, ip string, port int) serviceInfo
 
 type grabberJob struct {
 	IP   string
@@ -31,11 +41,25 @@ func (mod *SynScanner) bannerGrabber(arg async.Job) {
 		fn = httpGrabber
 	} else if port == 53 || port == 5353 {
 		fn = dnsGrabber
+	} else if port == packets.SMBPort {
+		fn = smbGrabber
 	}
 
 	mod.Debug("grabbing banner for %s:%d", ip, port)
-	job.Port.Banner = fn(mod, ip, port)
-	if job.Port.Banner != "" {
-		mod.Info("found banner for %s:%d -> %s", ip, port, job.Port.Banner)
+	info := fn(mod, ip, port)
+	if info.Service != "" {
+		job.Port.Service = info.Service
+	}
+	job.Port.Version = info.Version
+	job.Port.Banner = info.Banner
+
+	if info.Version != "" {
+		mod.Info("found %s %s on %s:%d", job.Port.Service, info.Version, ip, port)
+	} else if info.Banner != "" {
+		mod.Info("found banner for %s:%d -> %s", ip, port, info.Banner)
+	}
+
+	if info.Version != "" || info.Banner != "" {
+		NewSynScanServiceEvent(ip, mod.lookupHost(ip), job.Port).Push()
 	}
 }
@@ -23,12 +23,12 @@ func grabChaos(addr string, q string) string {
 	return ""
 }
 
-func dnsGrabber(mod *SynScanner, ip string, port int) string {
+func dnsGrabber(mod *SynScanner, ip string, port int) serviceInfo {
 	addr := fmt.Sprintf("%s:%d", ip, port)
 	if v := grabChaos(addr, "version.bind."); v != "" {
-		return v
+		return serviceInfo{Version: v, Banner: v}
 	} else if v := grabChaos(addr, "hostname.bind."); v != "" {
-		return v
+		return serviceInfo{Banner: v}
 	}
-	return ""
+	return serviceInfo{}
 }
@@ -27,7 +27,7 @@ func searchForTitle(n *html.Node) string {
 	return ""
 }
 
-func httpGrabber(mod *SynScanner, ip string, port int) string {
+func httpGrabber(mod *SynScanner, ip string, port int) serviceInfo {
 	schema := "http"
 	client := &http.Client{
 		Timeout: bannerGrabTimeout,
@@ -64,10 +64,19 @@ func httpGrabber(mod *SynScanner, ip string, port int) string {
 	resp, err := client.Get(url)
 	if err != nil {
 		mod.Debug("error while grabbing banner from %s: %v", url, err)
-		return ""
+		return serviceInfo{}
 	}
 	defer resp.Body.Close()
 
+	// the server software, or the framework if it doesn't tell
+	info := serviceInfo{
+		Service: schema,
+		Version: resp.Header.Get("Server"),
+	}
+	if info.Version == "" {
+		info.Version = resp.Header.Get("X-Powered-By")
+	}
+
 	fallback := ""
 	for name, values := range resp.Header {
 		for _, value := range values {
@@ -82,12 +91,13 @@ func httpGrabber(mod *SynScanner, ip string, port int) string {
 	doc, err := html.Parse(resp.Body)
 	if err != nil {
 		mod.Debug("error while reading and parsing response from %s: %v", url, err)
-		return fallback
+		info.Banner = fallback
+		return info
 	}
 
-	if title := searchForTitle(doc); title != "" {
-		return title
+	if info.Banner = searchForTitle(doc); info.Banner == "" {
+		info.Banner = fallback
 	}
 
-	return fallback
+	return info
 }
@@ -0,0 +1,53 @@
+package syn_scan
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+)
+
+func smbGrabber(mod *SynScanner, ip string, port int) serviceInfo {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, fmt.Sprintf("%d", port)), bannerGrabTimeout)
+	if err != nil {
+		mod.Debug("%s:%d : %v", ip, port, err)
+		return serviceInfo{}
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(bannerGrabTimeout))
+
+	if _, err = conn.Write(packets.NewSMB2NegotiateRequest()); err != nil {
+		mod.Debug("%s:%d : %v", ip, port, err)
+		return serviceInfo{}
+	}
+
+	buf := make([]byte, 4096)
+	n, err := io.ReadAtLeast(conn, buf, 4+64+8)
+	if err != nil {
+		mod.Debug("%s:%d : %v", ip, port, err)
+		return serviceInfo{}
+	}
+
+	smb, err := packets.ParseSMB2NegotiateResponse(buf[:n])
+	if err != nil {
+		mod.Debug("smb negotiation with %s failed: %v", ip, err)
+		return serviceInfo{}
+	}
+
+	// same meta as net.probe.smb, so that the host shows up as a relay target
+	if host := mod.lookupHost(ip); host != nil {
+		host.OnMeta(map[string]string{
+			"smb:dialect": smb.Dialect,
+			"smb:signing": smb.Signing(),
+		})
+	}
+
+	return serviceInfo{
+		Service: "smb",
+		Version: smb.Dialect,
+		Banner:  fmt.Sprintf("signing %s", smb.Signing()),
+	}
+}
@@ -16,7 +16,17 @@ import (
 	"github.com/google/gopacket/pcap"
 )
 
-const synSourcePort = 666
+const (
+	synSourcePort = 666
+	// the knocks are sent from another port so that their answers, like
+	// the SYN+ACK of a knock port that is actually open, are not taken
+	// for scan results
+	knockSourcePort = 667
+)
+
+// how long to wait for the late answers of a UDP scan before
+// considering the silent ports open|filtered
+const udpReplyTimeout = time.Duration(2) * time.Second
 
 type synScannerStats struct {
 	numPorts     uint64
@@ -30,11 +40,17 @@ type synScannerStats struct {
 type SynScanner struct {
 	session.SessionModule
 	addresses     []net.IP
-	startPort     int
-	endPort       int
+	ports         []int
+	portsSpec     string
+	udp           bool
+	banners       bool
 	handle        *pcap.Handle
 	packets       chan gopacket.Packet
 	progressEvery time.Duration
+	knocks        []packets.Knock
+	knockDelay    time.Duration
+	changes       portChanges
+	udpReplies    udpReplies
 	stats         synScannerStats
 	waitGroup     *sync.WaitGroup
 	scanQueue     *async.WorkQueue
@@ -59,6 +75,23 @@ func NewSynScanner(s *session.Session) *SynScanner {
 		"1",
 		"Period in seconds for the scanning progress reporting."))
 
+	mod.AddParam(session.NewStringParameter("syn.scan.knock",
+		"",
+		"",
+		"If not empty, a port knocking sequence to send to every address before scanning it, for instance: 7000,udp:8000,9000"))
+
+	mod.AddParam(session.NewIntParameter("syn.scan.knock.delay",
+		"200",
+		"Milliseconds to wait after every knock of syn.scan.knock."))
+
+	mod.AddParam(session.NewBoolParameter("syn.scan.udp",
+		"false",
+		"If true, probe UDP ports with the payloads of their services (DNS, NTP, NetBIOS, SNMP) instead of sending SYNs, ports answering with an ICMP port unreachable are closed and the silent ones open|filtered, the default ports are the udp preset."))
+
+	mod.AddParam(session.NewBoolParameter("syn.scan.banners",
+		"true",
+		"If true, connect to the open TCP ports to grab their banners and detect the version of their services with light probes (HTTP, SSH, SMB, DNS)."))
+
 	mod.AddHandler(session.NewModuleHandler("syn.scan stop", "syn\\.scan (stop|off)",
 		"Stop the current syn scanning session.",
 		func(args []string) error {
@@ -68,18 +101,24 @@ func NewSynScanner(s *session.Session) *SynScanner {
 			return mod.Stop()
 		}))
 
-	mod.AddHandler(session.NewModuleHandler("syn.scan IP-RANGE START-PORT END-PORT", "syn.scan ([^\\s]+) ?(\\d+)?([\\s\\d]*)?",
-		"Perform a syn port scanning against an IP address within the provided ports range.",
+	mod.AddHandler(session.NewModuleHandler("syn.scan IP-RANGE START-PORT END-PORT", "syn.scan ([^\\s]+) ?([\\w,\\-]+)?([\\s\\d]*)?",
+		"Perform a syn port scanning against an IP address within the provided ports range, instead of the range a comma separated list of ports, ranges and presets (top100, top1000, web, windows, udp) can be used, for instance: syn.scan 192.168.1.1 top100,8000-8100",
 		func(args []string) error {
 			period := 0
 			if mod.Running() {
 				return fmt.Errorf("A scan is already running, wait for it to end before starting a new one.")
 			} else if err := mod.parseTargets(args[0]); err != nil {
 				return err
+			} else if err, mod.udp = mod.BoolParam("syn.scan.udp"); err != nil {
+				return err
+			} else if err, mod.banners = mod.BoolParam("syn.scan.banners"); err != nil {
+				return err
 			} else if err = mod.parsePorts(args); err != nil {
 				return err
 			} else if err, period = mod.IntParam("syn.scan.show-progress-every"); err != nil {
 				return err
+			} else if err = mod.parseKnocks(); err != nil {
+				return err
 			} else {
 				mod.progressEvery = time.Duration(period) * time.Second
 			}
@@ -103,7 +142,7 @@ func (mod *SynScanner) Name() string {
 }
 
 func (mod *SynScanner) Description() string {
-	return "A module to perform SYN port scanning."
+	return "A module to perform SYN and UDP port scanning."
 }
 
 func (mod *SynScanner) Author() string {
@@ -117,12 +156,16 @@ func (mod *SynScanner) Configure() (err error) {
 	if mod.handle == nil {
 		if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
 			return err
-		} else if err = mod.handle.SetBPFFilter(fmt.Sprintf("tcp dst port %d", synSourcePort)); err != nil {
-			return err
 		}
 		mod.packets = gopacket.NewPacketSource(mod.handle, mod.handle.LinkType()).Packets()
 	}
-	return nil
+
+	// closed UDP ports answer with ICMP errors
+	filter := fmt.Sprintf("tcp dst port %d", synSourcePort)
+	if mod.udp {
+		filter = fmt.Sprintf("udp dst port %d or icmp or icmp6", synSourcePort)
+	}
+	return mod.handle.SetBPFFilter(filter)
 }
 
 func (mod *SynScanner) Start() error {
@@ -173,29 +216,74 @@ func (mod *SynScanner) scanWorker(job async.Job) {
 		fromIP = mod.Session.Interface.IPv6
 	}
 
-	for dstPort := mod.startPort; dstPort < mod.endPort+1; dstPort++ {
+	mod.knock(fromIP, fromHW, scan)
+
+	probe, newProbe := "SYN packet", packets.NewTCPSyn
+	if mod.udp {
+		probe, newProbe = "UDP probe", packets.NewUDPScanProbe
+		mod.udpReplies.add(scan.Address.String())
+	}
+
+	for _, dstPort := range mod.ports {
 		if !mod.Running() {
 			break
 		}
 
 		atomic.AddUint64(&mod.stats.doneProbes, 1)
 
-		err, raw := packets.NewTCPSyn(fromIP, fromHW, scan.Address, scan.Mac, synSourcePort, dstPort)
+		err, raw := newProbe(fromIP, fromHW, scan.Address, scan.Mac, synSourcePort, dstPort)
 		if err != nil {
-			mod.Error("error creating SYN packet: %s", err)
+			mod.Error("error creating %s: %s", probe, err)
 			continue
 		}
 
 		if err := mod.Session.Queue.Send(raw); err != nil {
-			mod.Error("error sending SYN packet: %s", err)
+			mod.Error("error sending %s: %s", probe, err)
 		} else {
-			mod.Debug("sent %d bytes of SYN packet to %s for port %d", len(raw), scan.Address.String(), dstPort)
+			mod.Debug("sent %d bytes of %s to %s for port %d", len(raw), probe, scan.Address.String(), dstPort)
 		}
 
 		time.Sleep(time.Duration(15) * time.Millisecond)
 	}
 }
 
+func (mod *SynScanner) parseKnocks() error {
+	err, knock := mod.StringParam("syn.scan.knock")
+	if err != nil {
+		return err
+	}
+
+	err, delay := mod.IntParam("syn.scan.knock.delay")
+	if err != nil {
+		return err
+	}
+	mod.knockDelay = time.Duration(delay) * time.Millisecond
+
+	mod.knocks = nil
+	if knock != "" {
+		mod.knocks, err = packets.ParseKnockSequence(knock)
+	}
+	return err
+}
+
+// open any service hidden behind a port knocking sequence before scanning
+func (mod *SynScanner) knock(fromIP net.IP, fromHW net.HardwareAddr, scan scanJob) {
+	if len(mod.knocks) == 0 {
+		return
+	}
+
+	for _, k := range mod.knocks {
+		if err, raw := packets.NewKnockPacket(fromIP, fromHW, scan.Address, scan.Mac, knockSourcePort, k); err != nil {
+			mod.Error("error creating knock packet: %s", err)
+		} else if err = mod.Session.Queue.Send(raw); err != nil {
+			mod.Error("error sending knock packet: %s", err)
+		}
+		time.Sleep(mod.knockDelay)
+	}
+
+	mod.Debug("knocked on %s with %s", scan.Address.String(), packets.KnockSequenceString(mod.knocks))
+}
+
 func (mod *SynScanner) synScan() error {
 	if err := mod.Configure(); err != nil {
 		return err
@@ -214,7 +302,7 @@ func (mod *SynScanner) synScan() error {
 		})
 
 		mod.stats.openPorts = 0
-		mod.stats.numPorts = uint64(mod.endPort - mod.startPort + 1)
+		mod.stats.numPorts = uint64(len(mod.ports))
 		mod.stats.started = time.Now()
 		mod.stats.numAddresses = uint64(len(mod.addresses))
 		mod.stats.totProbes = mod.stats.numAddresses * mod.stats.numPorts
@@ -224,14 +312,18 @@ func (mod *SynScanner) synScan() error {
 			plural = ""
 		}
 
-		if mod.stats.numPorts > 1 {
-			mod.Info("scanning %d address%s from port %d to port %d ...", mod.stats.numAddresses, plural, mod.startPort, mod.endPort)
-		} else {
-			mod.Info("scanning %d address%s on port %d ...", mod.stats.numAddresses, plural, mod.startPort)
+		scanning := "scanning"
+		if mod.udp {
+			scanning = "udp scanning"
 		}
 
+		mod.Info("%s %d address%s %s ...", scanning, mod.stats.numAddresses, plural, mod.portsSpec)
+
 		mod.State.Store("progress", 0.0)
 
+		mod.snapshotPorts()
+		mod.udpReplies.reset()
+
 		// start the collector
 		mod.waitGroup.Add(1)
 		go func() {
@@ -276,6 +368,16 @@ func (mod *SynScanner) synScan() error {
 		}
 
 		mod.scanQueue.WaitDone()
+
+		// only complete scans can tell which ports were closed
+		if mod.Running() && mod.udp {
+			time.Sleep(udpReplyTimeout)
+			if mod.Running() {
+				mod.reportUDPPorts()
+			}
+		} else if mod.Running() {
+			mod.notifyPortChanges()
+		}
 	})
 
 	return nil
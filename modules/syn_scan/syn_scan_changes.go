@@ -0,0 +1,100 @@
+package syn_scan
+
+import (
+	"sync"
+
+	"github.com/bettercap/bettercap/network"
+)
+
+// open ports of the scanned hosts before and during a scan, used to
+// notify the changes of their open ports set once the scan is over
+type portChanges struct {
+	sync.Mutex
+	before map[string][]int
+	found  map[string][]int
+}
+
+func portsOf(host *network.Endpoint) []int {
+	list := make([]int, 0)
+	if ports, ok := host.Meta.Get("ports").(map[int]*OpenPort); ok {
+		for port := range ports {
+			list = append(list, port)
+		}
+	}
+	return list
+}
+
+func (mod *SynScanner) lookupHost(address string) *network.Endpoint {
+	if address == mod.Session.Interface.IpAddress {
+		return mod.Session.Interface
+	} else if address == mod.Session.Gateway.IpAddress {
+		return mod.Session.Gateway
+	}
+	return mod.Session.Lan.GetByIp(address)
+}
+
+func (mod *SynScanner) snapshotPorts() {
+	mod.changes.Lock()
+	defer mod.changes.Unlock()
+
+	mod.changes.before = make(map[string][]int)
+	mod.changes.found = make(map[string][]int)
+	for _, address := range mod.addresses {
+		if host := mod.lookupHost(address.String()); host != nil {
+			mod.changes.before[address.String()] = portsOf(host)
+		}
+	}
+}
+
+func (mod *SynScanner) portFound(address string, port int) {
+	mod.changes.Lock()
+	defer mod.changes.Unlock()
+
+	if mod.changes.found != nil {
+		mod.changes.found[address] = append(mod.changes.found[address], port)
+	}
+}
+
+func (mod *SynScanner) notifyPortChanges() {
+	mod.changes.Lock()
+	defer mod.changes.Unlock()
+
+	scanned := make(map[int]bool)
+	for _, port := range mod.ports {
+		scanned[port] = true
+	}
+
+	for address, before := range mod.changes.before {
+		found := mod.changes.found[address]
+		// first scan of the host or the host is down
+		if len(before) == 0 || len(found) == 0 {
+			continue
+		}
+
+		host := mod.lookupHost(address)
+		if host == nil {
+			continue
+		}
+
+		// ports we didn't scan this time are still considered open
+		open := append([]int{}, found...)
+		for _, port := range before {
+			if !scanned[port] {
+				open = append(open, port)
+			}
+		}
+
+		if change := network.DiffPorts(before, open); change != nil {
+			if ports, ok := host.Meta.Get("ports").(map[int]*OpenPort); ok {
+				for _, port := range change.Removed {
+					delete(ports, port)
+				}
+				host.Meta.Set("ports", ports)
+			}
+			mod.Session.Lan.Changed(host, change)
+		}
+	}
+
+	mod.changes.before = nil
+	mod.changes.found = nil
+}
@@ -8,18 +8,62 @@ import (
 type SynScanEvent struct {
 	Address string
 	Host    *network.Endpoint
+	Proto   string
 	Port    int
+	Service string
 }
 
-func NewSynScanEvent(address string, h *network.Endpoint, port int) SynScanEvent {
+// SynScanServiceEvent is what the banner grabbing found out about the
+// service listening on an open port.
+type SynScanServiceEvent struct {
+	Address string
+	Host    *network.Endpoint
+	Proto   string
+	Port    int
+	Service string
+	Version string
+	Banner  string
+}
+
+func init() {
+	session.RegisterEventSchema("syn.scan", 2, "An open port has been found.", SynScanEvent{})
+	session.RegisterEventSchema("syn.scan.service", 1, "The service listening on an open port has been identified.", SynScanServiceEvent{})
+}
+
+func NewSynScanEvent(address string, h *network.Endpoint, proto string, port int, service string) SynScanEvent {
 	return SynScanEvent{
 		Address: address,
 		Host:    h,
+		Proto:   proto,
 		Port:    port,
+		Service: service,
 	}
 }
 
 func (e SynScanEvent) Push() {
 	session.I.Events.Add("syn.scan", e)
+	session.I.Bus.Publish(session.TopicOpenPort, "syn.scan", session.OpenPortFact{
+		Address:  e.Address,
+		Port:     e.Port,
+		Protocol: e.Proto,
+		Service:  e.Service,
+	})
+	session.I.Refresh()
+}
+
+func NewSynScanServiceEvent(address string, h *network.Endpoint, port *OpenPort) SynScanServiceEvent {
+	return SynScanServiceEvent{
+		Address: address,
+		Host:    h,
+		Proto:   port.Proto,
+		Port:    port.Port,
+		Service: port.Service,
+		Version: port.Version,
+		Banner:  port.Banner,
+	}
+}
+
+func (e SynScanServiceEvent) Push() {
+	session.I.Events.Add("syn.scan.service", e)
 	session.I.Refresh()
 }
@@ -33,26 +33,58 @@ func (mod *SynScanner) parsePorts(args []string) (err error) {
 	argc := len(args)
 	mod.stats.totProbes = 0
 	mod.stats.doneProbes = 0
-	mod.startPort = 1
-	mod.endPort = 65535
+	startPort := 1
+	endPort := 65535
 
+	// a list of ports, ranges and presets such as top100,8000-8100
 	if argc > 1 && str.Trim(args[1]) != "" {
-		if mod.startPort, err = strconv.Atoi(str.Trim(args[1])); err != nil {
+		if _, err := strconv.Atoi(str.Trim(args[1])); err != nil {
+			if argc > 2 && str.Trim(args[2]) != "" {
+				return fmt.Errorf("unexpected end port %s after the ports list %s", str.Trim(args[2]), args[1])
+			}
+			if mod.ports, err = parsePortList(str.Trim(args[1])); err != nil {
+				return err
+			}
+			mod.portsSpec = fmt.Sprintf("on %d ports (%s)", len(mod.ports), str.Trim(args[1]))
+			return nil
+		}
+	}
+
+	// scanning every UDP port would take ages
+	if mod.udp && (argc < 2 || str.Trim(args[1]) == "") {
+		mod.ports = portPresets["udp"]
+		mod.portsSpec = fmt.Sprintf("on %d ports (udp)", len(mod.ports))
+		return nil
+	}
+
+	if argc > 1 && str.Trim(args[1]) != "" {
+		if startPort, err = strconv.Atoi(str.Trim(args[1])); err != nil {
 			return fmt.Errorf("invalid start port %s: %s", args[1], err)
-		} else if mod.startPort > 65535 {
-			mod.startPort = 65535
+		} else if startPort > 65535 {
+			startPort = 65535
 		}
-		mod.endPort = mod.startPort
+		endPort = startPort
 	}
 
 	if argc > 2 && str.Trim(args[2]) != "" {
-		if mod.endPort, err = strconv.Atoi(str.Trim(args[2])); err != nil {
+		if endPort, err = strconv.Atoi(str.Trim(args[2])); err != nil {
 			return fmt.Errorf("invalid end port %s: %s", args[2], err)
 		}
 	}
 
-	if mod.endPort < mod.startPort {
-		return fmt.Errorf("end port %d is greater than start port %d", mod.endPort, mod.startPort)
+	if endPort < startPort {
+		return fmt.Errorf("end port %d is greater than start port %d", endPort, startPort)
+	}
+
+	mod.ports = make([]int, 0, endPort-startPort+1)
+	for port := startPort; port <= endPort; port++ {
+		mod.ports = append(mod.ports, port)
+	}
+
+	if startPort == endPort {
+		mod.portsSpec = fmt.Sprintf("on port %d", startPort)
+	} else {
+		mod.portsSpec = fmt.Sprintf("from port %d to port %d", startPort, endPort)
 	}
 
 	return
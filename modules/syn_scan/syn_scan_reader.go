@@ -1,6 +1,9 @@
 package syn_scan
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"sync/atomic"
 
 	"github.com/bettercap/bettercap/network"
@@ -15,14 +18,48 @@ type OpenPort struct {
 	Proto   string `json:"proto"`
 	Banner  string `json:"banner"`
 	Service string `json:"service"`
+	Version string `json:"version"`
 	Port    int    `json:"port"`
 }
 
+func (p *OpenPort) String() string {
+	s := fmt.Sprintf("%s:%d", p.Proto, p.Port)
+	if p.Service != "" && p.Version != "" {
+		s += fmt.Sprintf("(%s %s)", p.Service, p.Version)
+	} else if p.Service != "" {
+		s += fmt.Sprintf("(%s)", p.Service)
+	}
+	if p.Banner != "" {
+		s += fmt.Sprintf(" [%s]", p.Banner)
+	}
+	return s
+}
+
+// FormatPorts returns the open ports of a host sorted by number.
+func FormatPorts(ports map[int]*OpenPort) string {
+	list := make([]int, 0, len(ports))
+	for port := range ports {
+		list = append(list, port)
+	}
+	sort.Ints(list)
+
+	parts := make([]string, 0, len(list))
+	for _, port := range list {
+		parts = append(parts, ports[port].String())
+	}
+	return strings.Join(parts, " ")
+}
+
 func (mod *SynScanner) onPacket(pkt gopacket.Packet) {
 	if pkt == nil || pkt.Data() == nil {
 		return
 	}
 
+	if mod.udp {
+		mod.onUDPPacket(pkt)
+		return
+	}
+
 	var eth layers.Ethernet
 	var ip4 layers.IPv4
 	var ip6 layers.IPv6
@@ -99,8 +136,12 @@ func (mod *SynScanner) onPacket(pkt gopacket.Packet) {
 			host.Meta.Set("ports", ports)
 		}
 
-		mod.bannerQueue.Add(async.Job(grabberJob{from, openPort}))
+		mod.portFound(from, port)
+
+		if mod.banners {
+			mod.bannerQueue.Add(async.Job(grabberJob{from, openPort}))
+		}
 
-		NewSynScanEvent(from, host, port).Push()
+		NewSynScanEvent(from, host, "tcp", port, openPort.Service).Push()
 	}
 }
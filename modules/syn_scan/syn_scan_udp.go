@@ -0,0 +1,166 @@
+package syn_scan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// silent ports listed for each address once a UDP scan is over
+const maxListedPorts = 64
+
+// states of the UDP ports that answered the probes, by address
+type udpReplies struct {
+	sync.Mutex
+	states map[string]map[int]string
+}
+
+func (r *udpReplies) reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.states = make(map[string]map[int]string)
+}
+
+func (r *udpReplies) add(address string) {
+	r.Lock()
+	defer r.Unlock()
+	if r.states != nil {
+		r.states[address] = make(map[int]string)
+	}
+}
+
+// set returns false if the address has not been probed or the state of the
+// port is already known
+func (r *udpReplies) set(address string, port int, state string) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	states, found := r.states[address]
+	if !found {
+		return false
+	} else if _, found = states[port]; found {
+		return false
+	}
+	states[port] = state
+	return true
+}
+
+// what the services answered to the probes of packets.UDPScanPayload
+func udpBanner(port int, payload []byte) string {
+	switch port {
+	case 53:
+		dns := layers.DNS{}
+		if err := dns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err == nil {
+			for _, answer := range dns.Answers {
+				if len(answer.TXTs) > 0 {
+					return string(answer.TXTs[0])
+				}
+			}
+		}
+	case 123:
+		if ntp, err := packets.ParseNTP(payload); err == nil && ntp.Mode == packets.NTPModeServer {
+			return fmt.Sprintf("ntpv%d stratum %d", ntp.Version, ntp.Stratum)
+		}
+	case 137:
+		if status, err := packets.NBNSParseNodeStatus(payload); err == nil {
+			return status.Hostname()
+		}
+	case 161:
+		if msg, err := packets.ParseSNMP(payload); err == nil && len(msg.VarBinds) > 0 {
+			return msg.VarBinds[0].Value
+		}
+	}
+	return ""
+}
+
+func (mod *SynScanner) onUDPPacket(pkt gopacket.Packet) {
+	reply := packets.ParseUDPScanReply(pkt, synSourcePort)
+	if reply == nil {
+		return
+	}
+
+	from := reply.Address.String()
+	port := reply.Port
+	if !mod.udpReplies.set(from, port, reply.State) {
+		return
+	} else if reply.State != packets.UDPPortOpen {
+		mod.Debug("udp port %d of %s is %s", port, from, reply.State)
+		return
+	}
+
+	atomic.AddUint64(&mod.stats.openPorts, 1)
+
+	openPort := &OpenPort{
+		Proto:   "udp",
+		Port:    port,
+		Service: network.GetServiceByPort(port, "udp"),
+		Banner:  udpBanner(port, reply.Payload),
+	}
+
+	host := mod.lookupHost(from)
+	if host != nil {
+		ports := host.Meta.GetOr("udp:ports", map[int]*OpenPort{}).(map[int]*OpenPort)
+		ports[port] = openPort
+		host.Meta.Set("udp:ports", ports)
+	}
+
+	if openPort.Banner != "" {
+		mod.Info("found banner for udp %s:%d -> %s", from, port, openPort.Banner)
+	}
+
+	NewSynScanEvent(from, host, "udp", port, openPort.Service).Push()
+}
+
+// the ports that didn't answer at all are either open or filtered
+func (mod *SynScanner) reportUDPPorts() {
+	mod.udpReplies.Lock()
+	defer mod.udpReplies.Unlock()
+
+	for _, address := range mod.addresses {
+		states, found := mod.udpReplies.states[address.String()]
+		if !found {
+			continue
+		}
+
+		counts := make(map[string]int)
+		silent := make([]string, 0)
+		for _, port := range mod.ports {
+			if state, found := states[port]; found {
+				counts[state]++
+			} else {
+				silent = append(silent, strconv.Itoa(port))
+			}
+		}
+
+		if len(silent) == 0 {
+			continue
+		} else if len(silent) == len(mod.ports) {
+			mod.Info("%s: all %d udp ports are %s", address, len(silent), packets.UDPPortOpenFiltered)
+			continue
+		}
+
+		list := strings.Join(silent, ",")
+		if len(silent) > maxListedPorts {
+			list = strings.Join(silent[:maxListedPorts], ",") + ",..."
+		}
+
+		mod.Info("%s: %d udp ports open, %d closed, %d filtered, %d %s: %s",
+			address,
+			counts[packets.UDPPortOpen],
+			counts[packets.UDPPortClosed],
+			counts[packets.UDPPortFiltered],
+			len(silent),
+			packets.UDPPortOpenFiltered,
+			list)
+	}
+
+	mod.udpReplies.states = nil
+}
@@ -6,6 +6,9 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
 )
 
 func cleanBanner(banner string) string {
@@ -18,17 +21,23 @@ func cleanBanner(banner string) string {
 	return clean
 }
 
-func tcpGrabber(mod *SynScanner, ip string, port int) string {
+func tcpGrabber(mod *SynScanner, ip string, port int) serviceInfo {
 	dialer := net.Dialer{
 		Timeout: bannerGrabTimeout,
 	}
 
 	if conn, err := dialer.Dial("tcp", fmt.Sprintf("%s:%d", ip, port)); err == nil {
 		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(bannerGrabTimeout))
 		msg, _ := bufio.NewReader(conn).ReadString('\n')
-		return cleanBanner(strings.Trim(msg, "\r\n\t "))
+		banner := cleanBanner(strings.Trim(msg, "\r\n\t "))
+		// ssh servers introduce themselves whatever the port
+		if v, err := packets.ParseSSHVersion(banner); err == nil {
+			return serviceInfo{Service: "ssh", Version: v.String(), Banner: banner}
+		}
+		return serviceInfo{Banner: banner}
 	} else {
 		mod.Debug("%s:%d : %v", ip, port, err)
 	}
-	return ""
+	return serviceInfo{}
 }
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/bettercap/bettercap/firewall"
+	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/robertkrimen/otto"
@@ -20,6 +21,8 @@ type TcpProxy struct {
 	tunnelAddr  *net.TCPAddr
 	listener    *net.TCPListener
 	script      *TcpProxyScript
+	proxySend   string
+	proxyAccept bool
 }
 
 func NewTcpProxy(s *session.Session) *TcpProxy {
@@ -59,6 +62,17 @@ func NewTcpProxy(s *session.Session) *TcpProxy {
 		"0",
 		"Port to redirect the TCP tunnel to (optional)."))
 
+	mod.AddParam(session.NewStringParameter("tcp.proxy.protocol.send",
+		"",
+		"^(v1|v2)?$",
+		"If set to v1 or v2, a PROXY protocol header with the original source and destination of the connection is sent upstream, so that backend tools know the address of the victim."))
+
+	mod.AddParam(session.NewBoolParameter("tcp.proxy.protocol.accept",
+		"false",
+		"If true, a PROXY protocol v1 or v2 header sent by the client is consumed and the source address it announces is used, note that protocols where the server speaks first will be delayed while waiting for it."))
+
+	mod.Needs("a redirection backend", firewall.Available)
+
 	mod.AddHandler(session.NewModuleHandler("tcp.proxy on", "",
 		"Start TCP proxy.",
 		func(args []string) error {
@@ -112,11 +126,17 @@ func (mod *TcpProxy) Configure() error {
 		return err
 	} else if err, scriptPath = mod.StringParam("tcp.proxy.script"); err != nil {
 		return err
-	} else if mod.localAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", proxyAddress, proxyPort)); err != nil {
+	} else if err, mod.proxySend = mod.StringParam("tcp.proxy.protocol.send"); err != nil {
+		return err
+	} else if !validProxyProtocol(mod.proxySend) {
+		return fmt.Errorf("unsupported PROXY protocol version '%s'", mod.proxySend)
+	} else if err, mod.proxyAccept = mod.BoolParam("tcp.proxy.protocol.accept"); err != nil {
+		return err
+	} else if mod.localAddr, err = network.DNS.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", proxyAddress, proxyPort)); err != nil {
 		return err
-	} else if mod.remoteAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", address, port)); err != nil {
+	} else if mod.remoteAddr, err = network.DNS.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", address, port)); err != nil {
 		return err
-	} else if mod.tunnelAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", tunnelAddress, tunnelPort)); err != nil {
+	} else if mod.tunnelAddr, err = network.DNS.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", tunnelAddress, tunnelPort)); err != nil {
 		return err
 	} else if mod.listener, err = net.ListenTCP("tcp", mod.localAddr); err != nil {
 		return err
@@ -130,6 +150,13 @@ func (mod *TcpProxy) Configure() error {
 		}
 	}
 
+	mod.Claim(firewall.RedirectionResource("tcp", port))
+
+	if err := mod.CheckDependencies(); err != nil {
+		mod.listener.Close()
+		return err
+	}
+
 	if !mod.Session.Firewall.IsForwardingEnabled() {
 		mod.Info("enabling forwarding.")
 		mod.Session.Firewall.EnableForwarding(true)
@@ -152,7 +179,7 @@ func (mod *TcpProxy) Configure() error {
 	return nil
 }
 
-func (mod *TcpProxy) doPipe(from, to net.Addr, src *net.TCPConn, dst io.ReadWriter, wg *sync.WaitGroup) {
+func (mod *TcpProxy) doPipe(from, to net.Addr, src net.Conn, dst io.ReadWriter, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	buff := make([]byte, 0xffff)
@@ -195,7 +222,23 @@ func (mod *TcpProxy) doPipe(from, to net.Addr, src *net.TCPConn, dst io.ReadWrit
 func (mod *TcpProxy) handleConnection(c *net.TCPConn) {
 	defer c.Close()
 
-	mod.Info("got a connection from %s", c.RemoteAddr().String())
+	var client net.Conn = c
+	from := c.RemoteAddr()
+	if mod.proxyAccept {
+		var err error
+		if client, from, err = acceptProxyHeader(c); err != nil {
+			mod.Warning("error while reading the PROXY header of %s: %s", c.RemoteAddr().String(), err)
+			return
+		}
+	}
+
+	mod.Info("got a connection from %s", from.String())
+
+	// the destination the victim was connecting to
+	var to net.Addr = mod.remoteAddr
+	if mod.remoteAddr.IP == nil {
+		to = c.LocalAddr()
+	}
 
 	// tcp tunnel enabled
 	if mod.tunnelAddr.IP.To4() != nil {
@@ -210,12 +253,19 @@ func (mod *TcpProxy) handleConnection(c *net.TCPConn) {
 	}
 	defer remote.Close()
 
+	if mod.proxySend != "" {
+		if err := writeProxyHeader(remote, mod.proxySend, from, to); err != nil {
+			mod.Warning("error while sending the PROXY header to %s: %s", mod.remoteAddr.String(), err)
+			return
+		}
+	}
+
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 
 	// start pipeing
-	go mod.doPipe(c.RemoteAddr(), mod.remoteAddr, c, remote, &wg)
-	go mod.doPipe(mod.remoteAddr, c.RemoteAddr(), remote, c, &wg)
+	go mod.doPipe(from, mod.remoteAddr, client, remote, &wg)
+	go mod.doPipe(mod.remoteAddr, from, remote, client, &wg)
 
 	wg.Wait()
 }
@@ -252,5 +302,8 @@ func (mod *TcpProxy) Stop() error {
 
 	return mod.SetRunning(false, func() {
 		mod.listener.Close()
+		if mod.script != nil {
+			mod.script.Unload()
+		}
 	})
 }
@@ -0,0 +1,186 @@
+package tcp_proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HAProxy PROXY protocol, see https://www.haproxy.org/download/2.0/doc/proxy-protocol.txt
+
+const (
+	proxyProtocolV1 = "v1"
+	proxyProtocolV2 = "v2"
+	// maximum length of a v1 header, CRLF included
+	proxyV1MaxLength = 107
+	// time a client has to send its header
+	proxyHeaderTimeout = 5 * time.Second
+)
+
+var proxyV2Signature = []byte{0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a}
+
+func validProxyProtocol(version string) bool {
+	return version == "" || version == proxyProtocolV1 || version == proxyProtocolV2
+}
+
+// writeProxyHeader sends the header announcing the original src and dst of the connection.
+func writeProxyHeader(w io.Writer, version string, src net.Addr, dst net.Addr) error {
+	from, okFrom := src.(*net.TCPAddr)
+	to, okTo := dst.(*net.TCPAddr)
+	if !okFrom || !okTo {
+		return fmt.Errorf("unsupported addresses %s -> %s", src, dst)
+	}
+
+	srcIP, dstIP := from.IP.To4(), to.IP.To4()
+	ipv4 := srcIP != nil && dstIP != nil
+	if !ipv4 {
+		srcIP, dstIP = from.IP.To16(), to.IP.To16()
+	}
+
+	var header []byte
+	if version == proxyProtocolV1 {
+		family := "TCP4"
+		if !ipv4 {
+			family = "TCP6"
+		}
+		header = []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", family, srcIP, dstIP, from.Port, to.Port))
+	} else {
+		buf := bytes.NewBuffer(append([]byte{}, proxyV2Signature...))
+		// version 2, PROXY command
+		buf.WriteByte(0x21)
+		if ipv4 {
+			// AF_INET over STREAM
+			buf.WriteByte(0x11)
+			binary.Write(buf, binary.BigEndian, uint16(12))
+		} else {
+			// AF_INET6 over STREAM
+			buf.WriteByte(0x21)
+			binary.Write(buf, binary.BigEndian, uint16(36))
+		}
+		buf.Write(srcIP)
+		buf.Write(dstIP)
+		binary.Write(buf, binary.BigEndian, uint16(from.Port))
+		binary.Write(buf, binary.BigEndian, uint16(to.Port))
+		header = buf.Bytes()
+	}
+
+	_, err := w.Write(header)
+	return err
+}
+
+func parseProxyV1(line string) (*net.TCPAddr, error) {
+	parts := strings.Fields(strings.TrimSpace(line))
+	if len(parts) >= 2 && parts[1] == "UNKNOWN" {
+		return nil, nil
+	} else if len(parts) != 6 || (parts[1] != "TCP4" && parts[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed PROXY v1 header '%s'", strings.TrimSpace(line))
+	}
+
+	ip := net.ParseIP(parts[2])
+	port, err := strconv.Atoi(parts[4])
+	if ip == nil || err != nil || port < 0 || port > 65535 {
+		return nil, fmt.Errorf("malformed PROXY v1 header '%s'", strings.TrimSpace(line))
+	}
+
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+func parseProxyV2(header []byte, payload []byte) (*net.TCPAddr, error) {
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY v2 version %d", header[12]>>4)
+	} else if header[12]&0x0f == 0 {
+		// LOCAL command, health checks and alike
+		return nil, nil
+	}
+
+	switch header[13] {
+	case 0x11:
+		if len(payload) >= 12 {
+			return &net.TCPAddr{
+				IP:   net.IP(append([]byte{}, payload[0:4]...)),
+				Port: int(binary.BigEndian.Uint16(payload[8:10])),
+			}, nil
+		}
+	case 0x21:
+		if len(payload) >= 36 {
+			return &net.TCPAddr{
+				IP:   net.IP(append([]byte{}, payload[0:16]...)),
+				Port: int(binary.BigEndian.Uint16(payload[32:34])),
+			}, nil
+		}
+	default:
+		// unspecified or unix sockets
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("short PROXY v2 address block")
+}
+
+// readProxyHeader consumes the PROXY header if the client sent one and returns
+// the source address it announces, or nil if there's no header or it has no address.
+func readProxyHeader(r *bufio.Reader) (*net.TCPAddr, error) {
+	if peek, err := r.Peek(len(proxyV2Signature)); err == nil && bytes.Equal(peek, proxyV2Signature) {
+		header := make([]byte, 16)
+		if _, err = io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+		if _, err = io.ReadFull(r, payload); err != nil {
+			return nil, err
+		}
+		return parseProxyV2(header, payload)
+	}
+
+	if peek, err := r.Peek(6); err != nil || string(peek) != "PROXY " {
+		return nil, nil
+	}
+
+	line := make([]byte, 0, proxyV1MaxLength)
+	for len(line) < proxyV1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			return parseProxyV1(string(line))
+		}
+	}
+
+	return nil, fmt.Errorf("PROXY v1 header too long")
+}
+
+// proxiedConn reads the client data after its PROXY header.
+type proxiedConn struct {
+	*net.TCPConn
+	reader *bufio.Reader
+}
+
+func (c *proxiedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// acceptProxyHeader consumes the PROXY header of the client, if any, and returns
+// the connection to read its data from and the original source address.
+func acceptProxyHeader(c *net.TCPConn) (net.Conn, net.Addr, error) {
+	conn := &proxiedConn{
+		TCPConn: c,
+		reader:  bufio.NewReader(c),
+	}
+
+	c.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	src, err := readProxyHeader(conn.reader)
+	c.SetReadDeadline(time.Time{})
+	if err != nil {
+		return nil, nil, err
+	} else if src == nil {
+		return conn, c.RemoteAddr(), nil
+	}
+	return conn, src, nil
+}
@@ -4,23 +4,26 @@ import (
 	"net"
 	"strings"
 
+	"github.com/bettercap/bettercap/caplets"
 	"github.com/bettercap/bettercap/log"
 	"github.com/bettercap/bettercap/session"
 
-	"github.com/evilsocket/islazy/plugin"
-
 	"github.com/robertkrimen/otto"
 )
 
 type TcpProxyScript struct {
-	*plugin.Plugin
+	*session.Script
 	doOnData bool
 }
 
 func LoadTcpProxyScript(path string, sess *session.Session) (err error, s *TcpProxyScript) {
 	log.Info("loading tcp proxy script %s ...", path)
 
-	plug, err := plugin.Load(path)
+	if err = caplets.Verify(path); err != nil {
+		return
+	}
+
+	plug, err := session.LoadPlugin(path)
 	if err != nil {
 		return
 	}
@@ -28,6 +31,7 @@ func LoadTcpProxyScript(path string, sess *session.Session) (err error, s *TcpPr
 	// define session pointer
 	if err = plug.Set("env", sess.Env.Data); err != nil {
 		log.Error("error while defining environment: %+v", err)
+		plug.Unload()
 		return
 	}
 
@@ -35,12 +39,13 @@ func LoadTcpProxyScript(path string, sess *session.Session) (err error, s *TcpPr
 	if plug.HasFunc("onLoad") {
 		if _, err = plug.Call("onLoad"); err != nil {
 			log.Error("error while executing onLoad callback: %s", "\ntraceback:\n  "+err.(*otto.Error).String())
+			plug.Unload()
 			return
 		}
 	}
 
 	s = &TcpProxyScript{
-		Plugin:   plug,
+		Script:   plug,
 		doOnData: plug.HasFunc("onData"),
 	}
 	return
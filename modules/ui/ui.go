@@ -5,12 +5,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"time"
 
+	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/google/go-github/github"
@@ -40,7 +41,7 @@ func getDefaultInstallBase() string {
 func NewUIModule(s *session.Session) *UIModule {
 	mod := &UIModule{
 		SessionModule: session.NewSessionModule("ui", s),
-		client:        github.NewClient(nil),
+		client:        github.NewClient(network.DNS.HTTPClient(30 * time.Second)),
 	}
 
 	mod.AddParam(session.NewStringParameter("ui.basepath",
@@ -115,7 +116,7 @@ func (mod *UIModule) download(version, url string) error {
 
 	mod.Info("downloading ui %s from %s ...", tui.Bold(version), url)
 
-	resp, err := http.Get(url)
+	resp, err := network.DNS.HTTPClient(0).Get(url)
 	if err != nil {
 		return err
 	}
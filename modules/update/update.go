@@ -5,8 +5,10 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/google/go-github/github"
@@ -22,7 +24,7 @@ type UpdateModule struct {
 func NewUpdateModule(s *session.Session) *UpdateModule {
 	mod := &UpdateModule{
 		SessionModule: session.NewSessionModule("update", s),
-		client:        github.NewClient(nil),
+		client:        github.NewClient(network.DNS.HTTPClient(30 * time.Second)),
 	}
 
 	mod.AddHandler(session.NewModuleHandler("update.check on", "",
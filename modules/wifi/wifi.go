@@ -64,6 +64,13 @@ type WiFiModule struct {
 	reads               *sync.WaitGroup
 	chanLock            *sync.Mutex
 	selector            *utils.ViewSelector
+	crack               *crackState
+	shakesMinScore      int
+	shakesRedeauth      bool
+	redeauth            *redeauthState
+	policies            *policyTable
+	deauths             *deauthStats
+	decrypt             *decryptState
 }
 
 func NewWiFiModule(s *session.Session) *WiFiModule {
@@ -93,10 +100,22 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 		writes:          &sync.WaitGroup{},
 		reads:           &sync.WaitGroup{},
 		chanLock:        &sync.Mutex{},
+		crack:           newCrackState(),
+		redeauth:        newRedeauthState(),
+		policies:        newPolicyTable(),
+		deauths:         newDeauthStats(),
+		decrypt:         newDecryptState(),
 	}
 
 	mod.InitState("channels")
 
+	mod.Needs("an interface supporting monitor mode", func() error {
+		if mod.source != "" || mod.iface == nil {
+			return nil
+		}
+		return network.SupportsMonitorMode(mod.iface.Name())
+	})
+
 	mod.AddParam(session.NewStringParameter("wifi.interface",
 		"",
 		"",
@@ -232,6 +251,27 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 		"false",
 		"Send wifi deauth packets from AP's for which key material was already acquired."))
 
+	mod.AddParam(session.NewIntParameter("wifi.deauth.alert.threshold",
+		"20",
+		"Number of deauthentication or disassociation frames we did not send, with the same source, BSSID and reason, that trigger a wifi.deauth.attack event within wifi.deauth.alert.window seconds, 0 to disable the alerts."))
+
+	mod.AddParam(session.NewIntParameter("wifi.deauth.alert.window",
+		"10",
+		"Seconds over which the frames are counted for wifi.deauth.alert.threshold."))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.deauth.stats", "",
+		"Show the deauthentication and disassociation frames sent by others, grouped by source, BSSID and reason code.",
+		func(args []string) error {
+			return mod.showDeauthStats()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.deauth.stats.clear", "",
+		"Clear the deauthentication and disassociation statistics.",
+		func(args []string) error {
+			mod.clearDeauthStats()
+			return nil
+		}))
+
 	assoc := session.NewModuleHandler("wifi.assoc BSSID", `wifi\.assoc ((?:[a-fA-F0-9:]{11,})|all|\*)`,
 		"Send an association request to the selected BSSID in order to receive a RSN PMKID key. Use 'all', '*' or a broadcast BSSID (ff:ff:ff:ff:ff:ff) to iterate for every access point.",
 		func(args []string) error {
@@ -318,6 +358,40 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 		"true",
 		"If true, all handshakes will be saved inside a single file, otherwise a folder with per-network pcap files will be created."))
 
+	mod.SavesTo("wifi.handshakes.file")
+
+	mod.AddParam(session.NewIntParameter("wifi.handshakes.min-score",
+		"70",
+		"Minimum crackability score (0-100) of a captured handshake to be considered good enough."))
+
+	mod.AddParam(session.NewBoolParameter("wifi.handshakes.redeauth",
+		"false",
+		"If true, clients whose best captured handshake scores below wifi.handshakes.min-score will be deauthenticated again."))
+
+	mod.AddParam(session.NewStringParameter("wifi.crack.url",
+		"",
+		"",
+		"If set, captured handshakes and PMKIDs will be submitted in hashcat 22000 format to this HTTPS cracking service endpoint and polled for results."))
+
+	mod.AddParam(session.NewStringParameter("wifi.crack.key",
+		"",
+		"",
+		"API key to send as bearer token to the cracking service."))
+
+	mod.AddParam(session.NewIntParameter("wifi.crack.poll",
+		"60",
+		"Seconds between each poll of the cracking service for results."))
+
+	mod.AddParam(session.NewBoolParameter("wifi.decrypt",
+		"false",
+		"If true, the data frames of the WPA2 CCMP networks with a known passphrase are decrypted with the keys derived from the captured handshakes and passed to net.sniff."))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.decrypt.psk BSSID PASSPHRASE", `wifi\.decrypt\.psk ((?:[a-fA-F0-9]{2}:){5}[a-fA-F0-9]{2}) (.+)`,
+		"Set the passphrase of the access point with the given BSSID, used to decrypt the traffic of its clients when wifi.decrypt is true.",
+		func(args []string) error {
+			return mod.setDecryptionPSK(args[0], args[1])
+		}))
+
 	mod.AddParam(session.NewStringParameter("wifi.ap.ssid",
 		"FreeWiFi",
 		"",
@@ -336,6 +410,32 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 		"true",
 		"If true, the fake access point will use WPA2, otherwise it'll result as an open AP."))
 
+	mod.AddHandler(session.NewModuleHandler("wifi.policy PATTERN ACTIONS", `wifi\.policy ([^\s]+) ([^\s]+)`,
+		"Perform the comma separated ACTIONS when an access point whose ESSID or BSSID matches the glob PATTERN is discovered, the first matching policy wins. Actions are capture (associate for the PMKID and deauth its clients until key material is acquired), deauth (deauth every client as it's discovered), clone (start a rogue access point with the same ESSID, BSSID and channel) and ignore, for instance: wifi.policy Corp* capture,clone",
+		func(args []string) error {
+			p, err := ParsePolicy(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			mod.policies.Add(p)
+			mod.State.Store("policies", mod.policies.policies)
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.policies", "",
+		"Show the access point policies and how many networks matched them.",
+		func(args []string) error {
+			return mod.showPolicies()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.policies.clear", "",
+		"Remove every access point policy.",
+		func(args []string) error {
+			mod.policies.Clear()
+			mod.State.Store("policies", mod.policies.policies)
+			return nil
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("wifi.show.wps BSSID",
 		`wifi\.show\.wps ((?:[a-fA-F0-9:]{11,})|all|\*)`,
 		"Show WPS information about a given station (use 'all', '*' or a broadcast BSSID for all).",
@@ -466,6 +566,10 @@ func (mod *WiFiModule) Configure() error {
 
 	if err, mod.shakesAggregate = mod.BoolParam("wifi.handshakes.aggregate"); err != nil {
 		return err
+	} else if err, mod.shakesMinScore = mod.IntParam("wifi.handshakes.min-score"); err != nil {
+		return err
+	} else if err, mod.shakesRedeauth = mod.BoolParam("wifi.handshakes.redeauth"); err != nil {
+		return err
 	} else if err, mod.shakesFile = mod.StringParam("wifi.handshakes.file"); err != nil {
 		return err
 	} else if mod.shakesFile != "" {
@@ -474,6 +578,14 @@ func (mod *WiFiModule) Configure() error {
 		}
 	}
 
+	if err = mod.configureCracking(); err != nil {
+		return err
+	} else if err = mod.configureDeauthStats(); err != nil {
+		return err
+	} else if err = mod.configureDecryption(); err != nil {
+		return err
+	}
+
 	if err, ifName = mod.StringParam("wifi.interface"); err != nil {
 		return err
 	} else if ifName == "" {
@@ -485,6 +597,11 @@ func (mod *WiFiModule) Configure() error {
 		return fmt.Errorf("could not find interface %s", ifName)
 	}
 
+	// before putting it in monitor mode
+	if err = mod.CheckDependencies(); err != nil {
+		return err
+	}
+
 	mod.Info("using interface %s (%s)", ifName, mod.iface.HwAddress)
 
 	if mod.source != "" {
@@ -648,6 +765,11 @@ func (mod *WiFiModule) Start() error {
 		// start the pruner
 		go mod.stationPruner()
 
+		// start polling the cracking service if needed
+		if mod.crackingEnabled() {
+			go mod.crackPoller()
+		}
+
 		mod.reads.Add(1)
 		defer mod.reads.Done()
 
@@ -676,7 +798,9 @@ func (mod *WiFiModule) Start() error {
 				mod.discoverAccessPoints(radiotap, dot11, packet)
 				mod.discoverClients(radiotap, dot11, packet)
 				mod.discoverHandshakes(radiotap, dot11, packet)
+				mod.decryptFrame(dot11, packet)
 				mod.discoverDeauths(radiotap, dot11, packet)
+				mod.trackDeauths(radiotap, dot11, packet)
 				mod.updateInfo(dot11, packet)
 				mod.updateStats(dot11, packet)
 			}
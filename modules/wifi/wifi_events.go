@@ -2,6 +2,7 @@ package wifi
 
 import (
 	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
 )
 
 type ClientEvent struct {
@@ -34,4 +35,30 @@ type HandshakeEvent struct {
 	Half       bool   `json:"half"`
 	Full       bool   `json:"full"`
 	PMKID      []byte `json:"pmkid"`
+	Score      int    `json:"score"`
+	Messages   string `json:"messages"`
+}
+
+type CrackEvent struct {
+	AP    string `json:"ap"`
+	ESSID string `json:"essid"`
+	PSK   string `json:"psk"`
+}
+
+type DecryptKeyEvent struct {
+	AP      string `json:"ap"`
+	Station string `json:"station"`
+	Type    string `json:"type"`
+	KeyID   int    `json:"key_id"`
+}
+
+func init() {
+	session.RegisterEventSchema("wifi.client.new", 1, "A new client of an access point has been discovered.", ClientEvent{})
+	session.RegisterEventSchema("wifi.client.lost", 1, "A client is not connected to its access point anymore.", ClientEvent{})
+	session.RegisterEventSchema("wifi.client.probe", 1, "A probe request has been captured.", ProbeEvent{})
+	session.RegisterEventSchema("wifi.deauthentication", 1, "A deauthentication frame has been captured.", DeauthEvent{})
+	session.RegisterEventSchema("wifi.deauth.attack", 1, "Deauthentication or disassociation frames sent by someone else exceeded the alert threshold.", DeauthAttackEvent{})
+	session.RegisterEventSchema("wifi.client.handshake", 1, "Key material of a WPA handshake has been captured.", HandshakeEvent{})
+	session.RegisterEventSchema("wifi.decrypt.key", 1, "A key to decrypt the traffic of a station has been derived from its handshake.", DecryptKeyEvent{})
+	session.RegisterEventSchema("wifi.ap.cracked", 1, "The key of an access point has been cracked.", CrackEvent{})
 }
@@ -0,0 +1,226 @@
+package wifi
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// associate for the PMKID and deauth the clients until key material is acquired
+	policyCapture = "capture"
+	// deauth every client as it's discovered
+	policyDeauth = "deauth"
+	// start a rogue access point with the same ESSID, BSSID and channel
+	policyClone = "clone"
+	// no automatic action, to exclude networks from broader patterns
+	policyIgnore = "ignore"
+)
+
+var policyActions = []string{policyCapture, policyDeauth, policyClone, policyIgnore}
+
+// Policy maps the access points with an ESSID or BSSID matching the pattern
+// to the actions to perform as soon as they and their clients are discovered.
+type Policy struct {
+	Pattern string   `json:"pattern"`
+	Actions []string `json:"actions"`
+	Hits    int      `json:"hits"`
+}
+
+type policyTable struct {
+	sync.Mutex
+
+	policies []*Policy
+}
+
+func newPolicyTable() *policyTable {
+	return &policyTable{
+		policies: make([]*Policy, 0),
+	}
+}
+
+// ParsePolicy creates a policy for a glob pattern and a comma separated list of actions.
+func ParsePolicy(pattern string, actions string) (*Policy, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid pattern: %v", pattern, err)
+	}
+
+	p := &Policy{Pattern: pattern}
+	for _, action := range strings.Split(actions, ",") {
+		action = strings.ToLower(strings.TrimSpace(action))
+		if action == "" {
+			continue
+		}
+
+		valid := false
+		for _, known := range policyActions {
+			if action == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown action '%s', valid actions are %s", action, strings.Join(policyActions, ", "))
+		}
+
+		p.Actions = append(p.Actions, action)
+	}
+
+	if len(p.Actions) == 0 {
+		return nil, fmt.Errorf("empty actions list")
+	} else if p.Has(policyIgnore) && len(p.Actions) > 1 {
+		return nil, fmt.Errorf("%s can't be combined with other actions", policyIgnore)
+	}
+
+	return p, nil
+}
+
+func (p *Policy) Has(action string) bool {
+	for _, a := range p.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the pattern matches the ESSID or the BSSID of the access point.
+func (p *Policy) Matches(essid string, bssid string) bool {
+	if matched, _ := filepath.Match(p.Pattern, essid); matched {
+		return true
+	}
+	matched, _ := filepath.Match(strings.ToLower(p.Pattern), strings.ToLower(bssid))
+	return matched
+}
+
+func (t *policyTable) Add(p *Policy) {
+	t.Lock()
+	defer t.Unlock()
+
+	// redefining a pattern replaces its actions
+	for i, existing := range t.policies {
+		if existing.Pattern == p.Pattern {
+			t.policies[i] = p
+			return
+		}
+	}
+	t.policies = append(t.policies, p)
+}
+
+func (t *policyTable) Clear() {
+	t.Lock()
+	defer t.Unlock()
+	t.policies = make([]*Policy, 0)
+}
+
+// For returns the first policy matching the access point, nil if none or if it's ignored.
+func (t *policyTable) For(ap *network.AccessPoint, hit bool) *Policy {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, p := range t.policies {
+		if p.Matches(ap.ESSID(), ap.BSSID()) {
+			if hit {
+				p.Hits++
+			}
+			if p.Has(policyIgnore) {
+				return nil
+			}
+			return p
+		}
+	}
+	return nil
+}
+
+func (mod *WiFiModule) onPolicyAccessPoint(ap *network.AccessPoint) {
+	p := mod.policies.For(ap, true)
+	if p == nil {
+		return
+	}
+
+	mod.Info("%s (%s) matches policy %s: %s", tui.Bold(ap.ESSID()), ap.BSSID(), tui.Yellow(p.Pattern), strings.Join(p.Actions, ", "))
+
+	if p.Has(policyCapture) && !ap.HasKeyMaterial() && !mod.skipAssoc(ap.HW) {
+		mod.writes.Add(1)
+		go func() {
+			defer mod.writes.Done()
+
+			if mod.Running() {
+				mod.Debug("policy: associating with %s for its PMKID", ap.BSSID())
+				mod.onChannel(ap.Channel, func() {
+					mod.sendAssocPacket(ap)
+				})
+			}
+		}()
+	}
+
+	if p.Has(policyClone) {
+		if mod.apRunning {
+			mod.Warning("policy: can't clone %s, a rogue access point is already running", ap.ESSID())
+			return
+		}
+
+		mod.apConfig.SSID = ap.ESSID()
+		mod.apConfig.BSSID = ap.HW
+		mod.apConfig.Channel = ap.Channel
+		if err, encryption := mod.BoolParam("wifi.ap.encryption"); err == nil {
+			mod.apConfig.Encryption = encryption
+		}
+		if err := mod.startAp(); err != nil {
+			mod.Error("policy: can't clone %s: %v", ap.ESSID(), err)
+		}
+	}
+}
+
+func (mod *WiFiModule) onPolicyClient(ap *network.AccessPoint, station *network.Station) {
+	p := mod.policies.For(ap, false)
+	if p == nil || mod.skipDeauth(ap.HW) || mod.skipDeauth(station.HW) {
+		return
+	}
+
+	deauth := p.Has(policyDeauth) || (p.Has(policyCapture) && !ap.HasKeyMaterial())
+	if !deauth {
+		return
+	}
+
+	mod.writes.Add(1)
+	go func() {
+		defer mod.writes.Done()
+
+		if mod.Running() {
+			mod.Debug("policy: deauthing client %s from %s", station.HwAddress, ap.ESSID())
+			mod.onChannel(ap.Channel, func() {
+				mod.sendDeauthPacket(ap.HW, station.HW)
+			})
+		}
+	}()
+}
+
+func (mod *WiFiModule) showPolicies() error {
+	mod.policies.Lock()
+	defer mod.policies.Unlock()
+
+	if len(mod.policies.policies) == 0 {
+		mod.Info("no policies defined, use wifi.policy PATTERN ACTIONS to add one")
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, p := range mod.policies.policies {
+		rows = append(rows, []string{
+			tui.Bold(p.Pattern),
+			strings.Join(p.Actions, ", "),
+			fmt.Sprintf("%d", p.Hits),
+		})
+	}
+
+	tui.Table(mod.Session.Events.Stdout, []string{"Pattern", "Actions", "Hits"}, rows)
+	mod.Session.Refresh()
+
+	return nil
+}
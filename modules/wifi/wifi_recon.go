@@ -71,7 +71,9 @@ func (mod *WiFiModule) discoverAccessPoints(radiotap *layers.RadioTap, dot11 *la
 					frequency = int(radiotap.ChannelFrequency)
 				}
 
-				if ap, isNew := mod.Session.WiFi.AddIfNew(ssid, bssid, frequency, radiotap.DBMAntennaSignal); !isNew {
+				if ap, isNew := mod.Session.WiFi.AddIfNew(ssid, bssid, frequency, radiotap.DBMAntennaSignal); isNew {
+					mod.onPolicyAccessPoint(ap)
+				} else {
 					//set beacon packet on the access point station.
 					//This is for it to be included in the saved handshake file for wifi.assoc
 					ap.Station.Handshake.Beacon = packet
@@ -176,6 +178,7 @@ func (mod *WiFiModule) discoverClients(radiotap *layers.RadioTap, dot11 *layers.
 					AP:     ap,
 					Client: station,
 				})
+				mod.onPolicyClient(ap, station)
 			}
 		}
 	})
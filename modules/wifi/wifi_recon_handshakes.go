@@ -48,6 +48,8 @@ func (mod *WiFiModule) discoverHandshakes(radiotap *layers.RadioTap, dot11 *laye
 			station, staAdded = ap.AddClientIfNew(staMac.String(), ap.Frequency, ap.RSSI)
 		}
 
+		mod.decryptHandshake(ap, staMac, key)
+
 		rawPMKID := []byte(nil)
 		if !key.Install && key.KeyACK && !key.KeyMIC {
 			// [1] (ACK) AP is sending ANonce to the client
@@ -113,6 +115,7 @@ func (mod *WiFiModule) discoverHandshakes(radiotap *layers.RadioTap, dot11 *laye
 		//   if we captured am half handshake which is not ours OR
 		//   if we captured a full handshake
 		if doSave && (validPMKID || validHalfHandshake || validFullHandshake) {
+			quality := station.Handshake.Quality()
 			mod.Session.Events.Add("wifi.client.handshake", HandshakeEvent{
 				File:       shakesFileName,
 				NewPackets: numUnsaved,
@@ -121,10 +124,17 @@ func (mod *WiFiModule) discoverHandshakes(radiotap *layers.RadioTap, dot11 *laye
 				PMKID:      rawPMKID,
 				Half:       station.Handshake.Half(),
 				Full:       station.Handshake.Complete(),
+				Score:      quality.Score,
+				Messages:   quality.Messages,
 			})
+			if !staIsUs {
+				mod.onHandshakeQuality(ap, station, quality)
+			}
 			// make sure the info that we have key material for this AP
 			// is persisted even after stations are pruned due to inactivity
 			ap.WithKeyMaterial(true)
+			// submit the new key material to the cracking service, if any
+			go mod.crackSubmit(ap, station)
 		}
 		// if we added ourselves as a client station but we didn't get any
 		// PMKID, just remove it from the list of clients of this AP.
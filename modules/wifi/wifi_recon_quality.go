@@ -0,0 +1,69 @@
+package wifi
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// max deauths sent to a client because of a poor handshake capture
+	maxRedeauths = 3
+	// time for the client to settle before deauthing it again
+	redeauthDelay = 5 * time.Second
+)
+
+type redeauthState struct {
+	sync.Mutex
+
+	attempts map[string]int
+}
+
+func newRedeauthState() *redeauthState {
+	return &redeauthState{
+		attempts: make(map[string]int),
+	}
+}
+
+func (mod *WiFiModule) onHandshakeQuality(ap *network.AccessPoint, station *network.Station, q network.HandshakeQuality) {
+	mod.redeauth.Lock()
+	defer mod.redeauth.Unlock()
+
+	if q.Score >= mod.shakesMinScore {
+		delete(mod.redeauth.attempts, station.HwAddress)
+		return
+	} else if !mod.shakesRedeauth {
+		return
+	}
+
+	attempts := mod.redeauth.attempts[station.HwAddress]
+	if attempts >= maxRedeauths {
+		mod.Debug("giving up on a better handshake for %s after %d deauths", station.HwAddress, attempts)
+		return
+	}
+	mod.redeauth.attempts[station.HwAddress] = attempts + 1
+
+	mod.Info("handshake of %s with %s scored %d (%s), deauthing it again (%d/%d)",
+		station.HwAddress,
+		ap.ESSID(),
+		q.Score,
+		tui.Yellow(strings.Join(q.Issues, ", ")),
+		attempts+1,
+		maxRedeauths)
+
+	mod.writes.Add(1)
+	go func() {
+		defer mod.writes.Done()
+
+		time.Sleep(redeauthDelay)
+		if mod.Running() && !mod.skipDeauth(ap.HW) && !mod.skipDeauth(station.HW) {
+			mod.onChannel(ap.Channel, func() {
+				mod.sendDeauthPacket(ap.HW, station.HW)
+			})
+		}
+	}()
+}
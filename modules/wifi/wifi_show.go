@@ -56,11 +56,30 @@ func (mod *WiFiModule) getRow(station *network.Station) ([]string, bool) {
 		// this is ugly, but necessary in order to have this
 		// method handle both access point and clients
 		// transparently
-		if ap, found := mod.Session.WiFi.Get(station.HwAddress); found && ap.HasKeyMaterial() {
-			encryption = tui.Red(encryption)
+		if ap, found := mod.Session.WiFi.Get(station.HwAddress); found {
+			psk := ap.PSK()
+			if psk == "" {
+				// the access point might have been pruned and discovered again
+				if psk = mod.crackedPSK(ap.BSSID()); psk != "" {
+					ap.SetPSK(psk)
+				}
+			}
+
+			if psk != "" {
+				encryption = fmt.Sprintf("%s %s", tui.Red(encryption), tui.Bold(psk))
+			} else if ap.HasKeyMaterial() {
+				encryption = tui.Red(encryption)
+			}
 		}
 	}
 
+	// clients have no ESSID column
+	if mod.isApSelected() {
+		bssid = network.WithTags(bssid, station.Tags)
+	} else {
+		ssid = network.WithTags(ssid, station.Tags)
+	}
+
 	sent := ops.Ternary(station.Sent > 0, humanize.Bytes(station.Sent), "").(string)
 	recvd := ops.Ternary(station.Received > 0, humanize.Bytes(station.Received), "").(string)
 
@@ -168,7 +187,9 @@ func (mod *WiFiModule) doFilter(station *network.Station) bool {
 		mod.selector.Expression.MatchString(station.ESSID()) ||
 		mod.selector.Expression.MatchString(station.Alias) ||
 		mod.selector.Expression.MatchString(station.Vendor) ||
-		mod.selector.Expression.MatchString(station.Encryption)
+		mod.selector.Expression.MatchString(station.Encryption) ||
+		mod.selector.Expression.MatchString(strings.Join(station.Tags, ",")) ||
+		mod.selector.Expression.MatchString(station.Note)
 }
 
 func (mod *WiFiModule) doSelection() (err error, stations []*network.Station) {
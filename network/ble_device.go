@@ -27,6 +27,8 @@ type BLEService struct {
 
 type BLEDevice struct {
 	Alias         string
+	Tags          []string
+	Note          string
 	LastSeen      time.Time
 	DeviceName    string
 	Vendor        string
@@ -41,6 +43,8 @@ type bleDeviceJSON struct {
 	Name        string       `json:"name"`
 	MAC         string       `json:"mac"`
 	Alias       string       `json:"alias"`
+	Tags        []string     `json:"tags"`
+	Note        string       `json:"note"`
 	Vendor      string       `json:"vendor"`
 	RSSI        int          `json:"rssi"`
 	Connectable bool         `json:"connectable"`
@@ -77,12 +81,18 @@ func (d *BLEDevice) Name() string {
 	return name
 }
 
+func (d *BLEDevice) MAC() string {
+	return NormalizeMac(d.Device.ID())
+}
+
 func (d *BLEDevice) MarshalJSON() ([]byte, error) {
 	doc := bleDeviceJSON{
 		LastSeen:    d.LastSeen,
 		Name:        d.Name(),
 		MAC:         d.Device.ID(),
 		Alias:       d.Alias,
+		Tags:        d.Tags,
+		Note:        d.Note,
 		Vendor:      d.Vendor,
 		RSSI:        d.RSSI,
 		Connectable: d.Advertisement.Connectable,
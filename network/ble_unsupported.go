@@ -12,6 +12,8 @@ import (
 type BLEDevice struct {
 	LastSeen time.Time
 	Alias    string
+	Tags     []string
+	Note     string
 }
 
 func NewBLEDevice() *BLEDevice {
@@ -20,6 +22,10 @@ func NewBLEDevice() *BLEDevice {
 	}
 }
 
+func (d *BLEDevice) MAC() string {
+	return ""
+}
+
 type BLEDevNewCallback func(dev *BLEDevice)
 type BLEDevLostCallback func(dev *BLEDevice)
 
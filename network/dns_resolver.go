@@ -0,0 +1,471 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// route the lookups to the system resolver
+	DNSSystem = "system"
+	// route the lookups to the DNS-over-HTTPS endpoint
+	DNSOverHTTPS = "doh"
+
+	dnsDefaultPort = "53"
+	dnsTimeout     = 5 * time.Second
+)
+
+// DNSRoute sends the lookups of the domains matching Pattern, either a domain
+// and its subdomains or a wildcard like *.corp.local, to Upstream.
+type DNSRoute struct {
+	Pattern  string `json:"pattern"`
+	Upstream string `json:"upstream"`
+}
+
+func (r DNSRoute) Matches(host string) bool {
+	pattern := strings.ToLower(strings.TrimSuffix(r.Pattern, "."))
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if pattern == "*" {
+		return true
+	} else if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// Resolver performs the lookups of bettercap itself, so that they can be sent to
+// upstreams of choice instead of the resolver of the network we're attacking.
+type Resolver struct {
+	sync.RWMutex
+
+	upstreams []string
+	doh       string
+	routes    []DNSRoute
+}
+
+// DNS is the resolver used by the modules for their own connections.
+var DNS = NewResolver()
+
+func NewResolver() *Resolver {
+	return &Resolver{
+		upstreams: make([]string, 0),
+		routes:    make([]DNSRoute, 0),
+	}
+}
+
+func parseUpstream(upstream string) (string, error) {
+	upstream = strings.TrimSpace(upstream)
+	if upstream == DNSSystem || upstream == DNSOverHTTPS {
+		return upstream, nil
+	} else if ip := net.ParseIP(strings.Trim(upstream, "[]")); ip != nil {
+		return net.JoinHostPort(ip.String(), dnsDefaultPort), nil
+	} else if host, port, err := net.SplitHostPort(upstream); err == nil && net.ParseIP(host) != nil {
+		return net.JoinHostPort(host, port), nil
+	}
+	return "", fmt.Errorf("'%s' is not a valid DNS upstream, expected IP[:PORT], %s or %s", upstream, DNSSystem, DNSOverHTTPS)
+}
+
+// Configure sets the comma separated list of upstreams used by default, the URL
+// of a DNS-over-HTTPS endpoint and the comma separated list of DOMAIN=UPSTREAM routes.
+func (r *Resolver) Configure(upstreams string, doh string, routes string) error {
+	parsedUpstreams := make([]string, 0)
+	for _, upstream := range strings.Split(upstreams, ",") {
+		if upstream = strings.TrimSpace(upstream); upstream == "" {
+			continue
+		} else if parsed, err := parseUpstream(upstream); err != nil {
+			return err
+		} else if parsed == DNSSystem || parsed == DNSOverHTTPS {
+			return fmt.Errorf("default upstreams must be IP[:PORT] addresses")
+		} else {
+			parsedUpstreams = append(parsedUpstreams, parsed)
+		}
+	}
+
+	if doh = strings.TrimSpace(doh); doh != "" {
+		if u, err := url.Parse(doh); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("'%s' is not a valid DNS-over-HTTPS URL", doh)
+		}
+	}
+
+	parsedRoutes := make([]DNSRoute, 0)
+	for _, route := range strings.Split(routes, ",") {
+		if route = strings.TrimSpace(route); route == "" {
+			continue
+		}
+
+		parts := strings.SplitN(route, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return fmt.Errorf("'%s' is not a valid DOMAIN=UPSTREAM route", route)
+		}
+
+		upstream, err := parseUpstream(parts[1])
+		if err != nil {
+			return err
+		} else if upstream == DNSOverHTTPS && doh == "" {
+			return fmt.Errorf("route %s uses %s but no DNS-over-HTTPS URL is set", route, DNSOverHTTPS)
+		}
+
+		parsedRoutes = append(parsedRoutes, DNSRoute{
+			Pattern:  strings.TrimSpace(parts[0]),
+			Upstream: upstream,
+		})
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.upstreams = parsedUpstreams
+	r.doh = doh
+	r.routes = parsedRoutes
+
+	return nil
+}
+
+// Upstreams returns where the lookups of the host are sent, either the system
+// resolver, the DNS-over-HTTPS endpoint or a list of DNS servers.
+func (r *Resolver) Upstreams(host string) []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, route := range r.routes {
+		if route.Matches(host) {
+			return []string{route.Upstream}
+		}
+	}
+
+	if len(r.upstreams) > 0 {
+		return append([]string{}, r.upstreams...)
+	} else if r.doh != "" {
+		return []string{DNSOverHTTPS}
+	}
+	return []string{DNSSystem}
+}
+
+// resolver sending the queries to a DNS server
+func upstreamResolver(upstream string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: dnsTimeout}
+			return d.DialContext(ctx, network, upstream)
+		},
+	}
+}
+
+func (r *Resolver) lookupUpstream(ctx context.Context, upstream string, host string) ([]string, error) {
+	if upstream == DNSSystem {
+		return net.DefaultResolver.LookupHost(ctx, host)
+	} else if upstream == DNSOverHTTPS {
+		return r.lookupDoH(ctx, host, layers.DNSTypeA, layers.DNSTypeAAAA)
+	}
+	return upstreamResolver(upstream).LookupHost(ctx, host)
+}
+
+// LookupHost resolves the host through its upstreams.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return []string{ip.String()}, nil
+	}
+
+	var err error
+	for _, upstream := range r.Upstreams(host) {
+		var addrs []string
+		if addrs, err = r.lookupUpstream(ctx, upstream, host); err == nil && len(addrs) > 0 {
+			return addrs, nil
+		}
+	}
+
+	if err == nil {
+		err = fmt.Errorf("no addresses found for %s", host)
+	}
+	return nil, err
+}
+
+// LookupIP resolves the host through its upstreams.
+func (r *Resolver) LookupIP(host string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// ReverseName returns the in-addr.arpa or ip6.arpa name of the address.
+func ReverseName(addr string) (string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", fmt.Errorf("'%s' is not a valid IP address", addr)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+	}
+
+	name := ""
+	for i := len(ip) - 1; i >= 0; i-- {
+		name += fmt.Sprintf("%x.%x.", ip[i]&0xf, ip[i]>>4)
+	}
+	return name + "ip6.arpa.", nil
+}
+
+// LookupAddr performs a reverse lookup of the address through the upstreams
+// of its in-addr.arpa or ip6.arpa name.
+func (r *Resolver) LookupAddr(addr string) ([]string, error) {
+	name, err := ReverseName(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	for _, upstream := range r.Upstreams(name) {
+		var names []string
+		if upstream == DNSSystem {
+			names, err = net.DefaultResolver.LookupAddr(ctx, addr)
+		} else if upstream == DNSOverHTTPS {
+			names, err = r.lookupDoH(ctx, name, layers.DNSTypePTR)
+		} else {
+			names, err = upstreamResolver(upstream).LookupAddr(ctx, addr)
+		}
+
+		if err == nil && len(names) > 0 {
+			return names, nil
+		}
+	}
+
+	if err == nil {
+		err = fmt.Errorf("no names found for %s", addr)
+	}
+	return nil, err
+}
+
+// ResolveTCPAddr is net.ResolveTCPAddr with the host resolved through the
+// resolver.
+func (r *Resolver) ResolveTCPAddr(network, address string) (*net.TCPAddr, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	} else if host == "" || net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return net.ResolveTCPAddr(network, address)
+	}
+
+	ips, err := r.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+			continue
+		}
+		return net.ResolveTCPAddr(network, net.JoinHostPort(ip.String(), port))
+	}
+	return nil, fmt.Errorf("no %s addresses found for %s", network, host)
+}
+
+// DialContextWith returns a dial function resolving the addresses through
+// the resolver before connecting with the dialer.
+func (r *Resolver) DialContextWith(d *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			var conn net.Conn
+			if conn, err = d.DialContext(ctx, network, net.JoinHostPort(addr, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+func (r *Resolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return r.DialContextWith(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})(ctx, network, address)
+}
+
+func (r *Resolver) Dial(network, address string) (net.Conn, error) {
+	return r.DialContext(context.Background(), network, address)
+}
+
+func (r *Resolver) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return r.DialContextWith(&net.Dialer{Timeout: timeout})(ctx, network, address)
+}
+
+// Transport returns an HTTP transport resolving the hosts through the resolver.
+func (r *Resolver) Transport() *http.Transport {
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           r.DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// HTTPClient returns an HTTP client resolving the hosts through the resolver.
+func (r *Resolver) HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: r.Transport(),
+	}
+}
+
+// NewDNSQuery creates a wire format query for the name and record type.
+func NewDNSQuery(name string, qtype layers.DNSType) ([]byte, error) {
+	query := &layers.DNS{
+		ID:      uint16(rand.Intn(0xffff)),
+		RD:      true,
+		QDCount: 1,
+		Questions: []layers.DNSQuestion{
+			{
+				Name:  []byte(strings.TrimSuffix(name, ".")),
+				Type:  qtype,
+				Class: layers.DNSClassIN,
+			},
+		},
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := query.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseDNSAnswers returns the addresses of the A and AAAA records and the fully
+// qualified names of the PTR records of a wire format response.
+func ParseDNSAnswers(raw []byte) ([]string, error) {
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(raw, gopacket.NilDecodeFeedback); err != nil {
+		return nil, err
+	} else if dns.ResponseCode != layers.DNSResponseCodeNoErr {
+		return nil, fmt.Errorf("DNS error: %s", dns.ResponseCode)
+	}
+
+	addrs := make([]string, 0)
+	for _, answer := range dns.Answers {
+		if (answer.Type == layers.DNSTypeA || answer.Type == layers.DNSTypeAAAA) && answer.IP != nil {
+			addrs = append(addrs, answer.IP.String())
+		} else if answer.Type == layers.DNSTypePTR && len(answer.PTR) > 0 {
+			addrs = append(addrs, strings.TrimSuffix(string(answer.PTR), ".")+".")
+		}
+	}
+	return addrs, nil
+}
+
+// RFC 8484 lookup of the records of the given types, the endpoint itself is
+// resolved through the default upstreams or the system resolver if its URL
+// doesn't use an address
+func (r *Resolver) lookupDoH(ctx context.Context, host string, qtypes ...layers.DNSType) ([]string, error) {
+	r.RLock()
+	endpoint := r.doh
+	bootstrap := append([]string{}, r.upstreams...)
+	r.RUnlock()
+
+	if endpoint == "" {
+		return nil, fmt.Errorf("no DNS-over-HTTPS endpoint set")
+	} else if len(bootstrap) == 0 {
+		bootstrap = []string{DNSSystem}
+	}
+
+	dialer := &net.Dialer{Timeout: dnsTimeout}
+	client := &http.Client{
+		Timeout: dnsTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				h, port, err := net.SplitHostPort(address)
+				if err != nil {
+					return nil, err
+				}
+				for _, upstream := range bootstrap {
+					var addrs []string
+					if addrs, err = r.lookupUpstream(ctx, upstream, h); err == nil && len(addrs) > 0 {
+						return dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0], port))
+					}
+				}
+				if err == nil {
+					err = fmt.Errorf("could not resolve %s", h)
+				}
+				return nil, err
+			},
+			TLSHandshakeTimeout: dnsTimeout,
+		},
+	}
+
+	addrs := make([]string, 0)
+	var lastErr error
+	for _, qtype := range qtypes {
+		query, err := NewDNSQuery(host, qtype)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest("POST", endpoint, bytes.NewReader(query))
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/dns-message")
+		req.Header.Set("Accept", "application/dns-message")
+
+		res, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		raw, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		} else if res.StatusCode != 200 {
+			lastErr = fmt.Errorf("%s returned %s", endpoint, res.Status)
+			continue
+		}
+
+		if found, err := ParseDNSAnswers(raw); err != nil {
+			lastErr = err
+		} else {
+			addrs = append(addrs, found...)
+		}
+	}
+
+	if len(addrs) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return addrs, nil
+}
@@ -0,0 +1,140 @@
+package network
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket/layers"
+)
+
+func TestDNSRouteMatches(t *testing.T) {
+	cases := []struct {
+		pattern string
+		host    string
+		exp     bool
+	}{
+		{"corp.local", "corp.local", true},
+		{"corp.local", "dc.corp.local", true},
+		{"corp.local", "notcorp.local", false},
+		{"*.corp.local", "dc.corp.local", true},
+		{"*.corp.local", "corp.local", false},
+		{"*.onion", "FOO.ONION.", true},
+		{"*", "anything.com", true},
+	}
+
+	for _, c := range cases {
+		if got := (DNSRoute{Pattern: c.pattern}).Matches(c.host); got != c.exp {
+			t.Fatalf("expected %s matching %s to be %t, got %t", c.pattern, c.host, c.exp, got)
+		}
+	}
+}
+
+func TestResolverConfigure(t *testing.T) {
+	r := NewResolver()
+
+	if err := r.Configure("1.1.1.1, 9.9.9.9:5353", "https://dns.example.com/dns-query", "*.corp.local=10.0.0.1,*.onion=system,example.org=doh"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cases := map[string][]string{
+		"dc.corp.local": {"10.0.0.1:53"},
+		"foo.onion":     {DNSSystem},
+		"example.org":   {DNSOverHTTPS},
+		"google.com":    {"1.1.1.1:53", "9.9.9.9:5353"},
+	}
+	for host, exp := range cases {
+		if got := r.Upstreams(host); !reflect.DeepEqual(got, exp) {
+			t.Fatalf("expected %v upstreams for %s, got %v", exp, host, got)
+		}
+	}
+
+	if err := r.Configure("", "", ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if got := r.Upstreams("google.com"); !reflect.DeepEqual(got, []string{DNSSystem}) {
+		t.Fatalf("expected the system resolver, got %v", got)
+	}
+
+	if err := r.Configure("", "https://dns.example.com/dns-query", ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if got := r.Upstreams("google.com"); !reflect.DeepEqual(got, []string{DNSOverHTTPS}) {
+		t.Fatalf("expected DNS-over-HTTPS, got %v", got)
+	}
+}
+
+func TestResolverConfigureErrors(t *testing.T) {
+	r := NewResolver()
+	bad := [][3]string{
+		{"not-an-ip", "", ""},
+		{"system", "", ""},
+		{"", "http://dns.example.com/", ""},
+		{"", "", "corp.local"},
+		{"", "", "corp.local=nope"},
+		{"", "", "corp.local=doh"},
+	}
+
+	for _, b := range bad {
+		if err := r.Configure(b[0], b[1], b[2]); err == nil {
+			t.Fatalf("expected error for %v", b)
+		}
+	}
+}
+
+func TestResolverLookupLiteral(t *testing.T) {
+	r := NewResolver()
+	r.Configure("192.0.2.1", "", "")
+
+	if addrs, err := r.LookupHost(context.Background(), "10.0.0.1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !reflect.DeepEqual(addrs, []string{"10.0.0.1"}) {
+		t.Fatalf("unexpected addresses %v", addrs)
+	}
+}
+
+func TestDNSQuery(t *testing.T) {
+	raw, err := NewDNSQuery("www.example.com.", layers.DNSTypeA)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// a query has no answers
+	if addrs, err := ParseDNSAnswers(raw); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(addrs) != 0 {
+		t.Fatalf("unexpected addresses %v", addrs)
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	tests := map[string]string{
+		"192.168.1.10": "10.1.168.192.in-addr.arpa.",
+		"2001:db8::1":  "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+	}
+
+	for addr, expected := range tests {
+		if name, err := ReverseName(addr); err != nil {
+			t.Fatalf("unexpected error for %s: %s", addr, err)
+		} else if name != expected {
+			t.Fatalf("expected %s for %s, got %s", expected, addr, name)
+		}
+	}
+
+	if _, err := ReverseName("not an address"); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestResolveTCPAddrLiteral(t *testing.T) {
+	r := NewResolver()
+	r.Configure("192.0.2.1", "", "")
+
+	if addr, err := r.ResolveTCPAddr("tcp", "10.0.0.1:8080"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if addr.String() != "10.0.0.1:8080" {
+		t.Fatalf("unexpected address %s", addr)
+	} else if addr, err = r.ResolveTCPAddr("tcp", ":3306"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if addr.Port != 3306 || addr.IP != nil {
+		t.Fatalf("unexpected address %s", addr)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/evilsocket/islazy/data"
 )
@@ -16,28 +17,30 @@ type EndpointLostCallback func(e *Endpoint)
 
 type LAN struct {
 	sync.Mutex
-	hosts   map[string]*Endpoint
-	iface   *Endpoint
-	gateway *Endpoint
-	ttl     map[string]uint
-	aliases *data.UnsortedKV
-	newCb   EndpointNewCallback
-	lostCb  EndpointLostCallback
+	hosts     map[string]*Endpoint
+	iface     *Endpoint
+	gateway   *Endpoint
+	ttl       map[string]uint
+	aliases   *data.UnsortedKV
+	newCb     EndpointNewCallback
+	lostCb    EndpointLostCallback
+	changedCb EndpointChangedCallback
 }
 
 type lanJSON struct {
 	Hosts []*Endpoint `json:"hosts"`
 }
 
-func NewLAN(iface, gateway *Endpoint, aliases *data.UnsortedKV, newcb EndpointNewCallback, lostcb EndpointLostCallback) *LAN {
+func NewLAN(iface, gateway *Endpoint, aliases *data.UnsortedKV, newcb EndpointNewCallback, lostcb EndpointLostCallback, changedcb EndpointChangedCallback) *LAN {
 	return &LAN{
-		iface:   iface,
-		gateway: gateway,
-		hosts:   make(map[string]*Endpoint),
-		ttl:     make(map[string]uint),
-		aliases: aliases,
-		newCb:   newcb,
-		lostCb:  lostcb,
+		iface:     iface,
+		gateway:   gateway,
+		hosts:     make(map[string]*Endpoint),
+		ttl:       make(map[string]uint),
+		aliases:   aliases,
+		newCb:     newcb,
+		lostCb:    lostcb,
+		changedCb: changedcb,
 	}
 }
 
@@ -75,14 +78,14 @@ func (lan *LAN) GetByIp(ip string) *Endpoint {
 	lan.Lock()
 	defer lan.Unlock()
 
-	if ip == lan.iface.IpAddress {
+	if lan.iface.hasIp(ip) {
 		return lan.iface
-	} else if ip == lan.gateway.IpAddress {
+	} else if lan.gateway.hasIp(ip) {
 		return lan.gateway
 	}
 
 	for _, e := range lan.hosts {
-		if e.IpAddress == ip {
+		if e.hasIp(ip) {
 			return e
 		}
 	}
@@ -190,19 +193,77 @@ func (lan *LAN) AddIfNew(ip, mac string) *Endpoint {
 		if lan.ttl[mac] < LANDefaultttl {
 			lan.ttl[mac]++
 		}
+		// same hardware, another address
+		if t.IpAddress != ip && isIPv4(ip) {
+			if !isIPv4(t.IpAddress) {
+				// an IPv6 only host getting its IPv4 address is not a change
+				t.SetIP(ip)
+			} else if from := t.ipv4Addresses(); t.AddIPv4(ip) {
+				lan.Changed(t, &EndpointChange{Field: "addresses", From: from, To: t.ipv4Addresses()})
+			}
+		}
 		return t
 	}
 
 	e := NewEndpointWithAlias(ip, mac, lan.aliases.GetOr(mac, ""))
 
+	// same address, new hardware
+	var previous *Endpoint
+	if isIPv4(ip) {
+		for _, h := range lan.hosts {
+			if h.IpAddress == ip {
+				previous = h
+				break
+			}
+		}
+	}
+
 	lan.hosts[mac] = e
 	lan.ttl[mac] = LANDefaultttl
 
 	lan.newCb(e)
 
+	if previous != nil {
+		lan.Changed(e, &EndpointChange{Field: "mac", From: previous.HwAddress, To: mac})
+	}
+
 	return nil
 }
 
+// AddIPv6 adds an IPv6 address to the endpoint with the given MAC, adding
+// the endpoint if it's new, and returns it and whether the address is new.
+func (lan *LAN) AddIPv6(ip net.IP, mac string) (*Endpoint, bool) {
+	lan.Lock()
+	defer lan.Unlock()
+
+	mac = NormalizeMac(mac)
+	address := ip.String()
+
+	var e *Endpoint
+	if mac == lan.iface.HwAddress {
+		return nil, false
+	} else if mac == lan.gateway.HwAddress {
+		e = lan.gateway
+	} else if t, found := lan.hosts[mac]; found {
+		e = t
+		if lan.ttl[mac] < LANDefaultttl {
+			lan.ttl[mac]++
+		}
+	} else if lan.shouldIgnore(address, mac) {
+		return nil, false
+	} else {
+		e = NewEndpointWithAlias(address, mac, lan.aliases.GetOr(mac, ""))
+		e.AddIPv6(ip)
+		lan.hosts[mac] = e
+		lan.ttl[mac] = LANDefaultttl
+		lan.newCb(e)
+		return e, true
+	}
+
+	e.LastSeen = time.Now()
+	return e, e.AddIPv6(ip)
+}
+
 func (lan *LAN) GetAlias(mac string) string {
 	return lan.aliases.GetOr(mac, "")
 }
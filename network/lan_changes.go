@@ -0,0 +1,118 @@
+package network
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type EndpointChangedCallback func(e *Endpoint, changes []EndpointChange)
+
+// EndpointChange is a property of a known endpoint that changed, one of
+// addresses, mac, hostname or ports.
+type EndpointChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	// only set for ports
+	Added   []int `json:"added,omitempty"`
+	Removed []int `json:"removed,omitempty"`
+}
+
+// EndpointChanged is the payload of the endpoint.changed event.
+type EndpointChanged struct {
+	Endpoint *Endpoint        `json:"endpoint"`
+	Changes  []EndpointChange `json:"changes"`
+}
+
+func (c EndpointChange) String() string {
+	if c.Field == "ports" {
+		parts := []string{}
+		for _, port := range c.Added {
+			parts = append(parts, "+"+strconv.Itoa(port))
+		}
+		for _, port := range c.Removed {
+			parts = append(parts, "-"+strconv.Itoa(port))
+		}
+		return "ports " + strings.Join(parts, " ")
+	}
+	return c.Field + " " + c.From + " -> " + c.To
+}
+
+func joinPorts(ports []int) string {
+	list := make([]string, len(ports))
+	for i, port := range ports {
+		list[i] = strconv.Itoa(port)
+	}
+	return strings.Join(list, ",")
+}
+
+// DiffPorts returns the change between two open ports sets or nil if they're the same.
+func DiffPorts(from, to []int) *EndpointChange {
+	before := make(map[int]bool)
+	after := make(map[int]bool)
+	for _, port := range from {
+		before[port] = true
+	}
+	for _, port := range to {
+		after[port] = true
+	}
+
+	c := &EndpointChange{Field: "ports"}
+	for port := range after {
+		if !before[port] {
+			c.Added = append(c.Added, port)
+		}
+	}
+	for port := range before {
+		if !after[port] {
+			c.Removed = append(c.Removed, port)
+		}
+	}
+
+	if len(c.Added) == 0 && len(c.Removed) == 0 {
+		return nil
+	}
+
+	sort.Ints(c.Added)
+	sort.Ints(c.Removed)
+
+	sortedFrom := append([]int{}, from...)
+	sortedTo := append([]int{}, to...)
+	sort.Ints(sortedFrom)
+	sort.Ints(sortedTo)
+
+	c.From = joinPorts(sortedFrom)
+	c.To = joinPorts(sortedTo)
+
+	return c
+}
+
+// HostnameChange returns the change between two hostnames or nil if there
+// was no previous hostname to change, learning a name is not a change.
+func HostnameChange(from, to string) *EndpointChange {
+	if from == "" || from == to {
+		return nil
+	}
+	return &EndpointChange{Field: "hostname", From: from, To: to}
+}
+
+// Changed notifies the changes of a known endpoint.
+func (lan *LAN) Changed(e *Endpoint, changes ...*EndpointChange) {
+	list := make([]EndpointChange, 0)
+	for _, c := range changes {
+		if c != nil {
+			list = append(list, *c)
+		}
+	}
+
+	if len(list) > 0 && lan.changedCb != nil {
+		lan.changedCb(e, list)
+	}
+}
+
+func isIPv4(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil && addr.To4() != nil
+}
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/evilsocket/islazy/data"
+)
+
+func TestDiffPorts(t *testing.T) {
+	if c := DiffPorts([]int{22, 80}, []int{80, 22}); c != nil {
+		t.Fatalf("expected no change, got %v", c)
+	}
+
+	c := DiffPorts([]int{80, 22}, []int{443, 22})
+	if c == nil {
+		t.Fatalf("expected a change")
+	} else if c.Field != "ports" {
+		t.Fatalf("unexpected field %s", c.Field)
+	} else if !reflect.DeepEqual(c.Added, []int{443}) {
+		t.Fatalf("unexpected added ports %v", c.Added)
+	} else if !reflect.DeepEqual(c.Removed, []int{80}) {
+		t.Fatalf("unexpected removed ports %v", c.Removed)
+	} else if c.From != "22,80" || c.To != "22,443" {
+		t.Fatalf("unexpected sets %s -> %s", c.From, c.To)
+	} else if c.String() != "ports +443 -80" {
+		t.Fatalf("unexpected string '%s'", c.String())
+	}
+}
+
+func TestHostnameChange(t *testing.T) {
+	if c := HostnameChange("", "foo"); c != nil {
+		t.Fatalf("learning a name should not be a change")
+	} else if c = HostnameChange("foo", "foo"); c != nil {
+		t.Fatalf("expected no change")
+	} else if c = HostnameChange("foo", "bar"); c == nil || c.From != "foo" || c.To != "bar" {
+		t.Fatalf("unexpected change %v", c)
+	}
+}
+
+func TestEndpointOnMetaRename(t *testing.T) {
+	e := NewEndpointNoResolve("192.168.1.10", "aa:bb:cc:dd:ee:ff", "", 24)
+
+	e.OnMeta(map[string]string{"dhcp:hostname": "laptop"})
+	if e.Hostname != "laptop" {
+		t.Fatalf("expected hostname 'laptop', got '%s'", e.Hostname)
+	}
+
+	// another source doesn't override the name
+	e.OnMeta(map[string]string{"mdns:hostname": "other"})
+	if e.Hostname != "laptop" {
+		t.Fatalf("expected hostname 'laptop', got '%s'", e.Hostname)
+	}
+
+	// the same one does
+	e.OnMeta(map[string]string{"dhcp:hostname": "desktop"})
+	if e.Hostname != "desktop" {
+		t.Fatalf("expected hostname 'desktop', got '%s'", e.Hostname)
+	}
+}
+
+func TestAddIfNewOtherAddress(t *testing.T) {
+	iface := NewEndpointNoResolve("192.168.1.2", "aa:aa:aa:aa:aa:aa", "eth0", 24)
+	gateway := NewEndpointNoResolve("192.168.1.1", "bb:bb:bb:bb:bb:bb", "", 24)
+
+	changes := make([]EndpointChange, 0)
+	lan := NewLAN(iface, gateway, &data.UnsortedKV{}, func(e *Endpoint) {}, func(e *Endpoint) {}, func(e *Endpoint, c []EndpointChange) {
+		changes = append(changes, c...)
+	})
+
+	mac := "cc:cc:cc:cc:cc:cc"
+	lan.AddIfNew("192.168.1.10", mac)
+	// a multi-homed host answering for both addresses
+	for i := 0; i < 3; i++ {
+		lan.AddIfNew("192.168.1.20", mac)
+		lan.AddIfNew("192.168.1.10", mac)
+	}
+
+	e, found := lan.Get(mac)
+	if !found {
+		t.Fatalf("expected the host to be found")
+	} else if e.IpAddress != "192.168.1.10" {
+		t.Fatalf("expected the main address to be kept, got %s", e.IpAddress)
+	} else if !reflect.DeepEqual(e.Ip4Addresses, []string{"192.168.1.20"}) {
+		t.Fatalf("unexpected other addresses %v", e.Ip4Addresses)
+	} else if len(changes) != 1 {
+		t.Fatalf("expected one change, got %v", changes)
+	} else if c := changes[0]; c.Field != "addresses" || c.From != "192.168.1.10" || c.To != "192.168.1.10,192.168.1.20" {
+		t.Fatalf("unexpected change %v", c)
+	} else if lan.GetByIp("192.168.1.20") != e {
+		t.Fatalf("expected the host to be found by its other address")
+	}
+}
@@ -13,6 +13,10 @@ import (
 
 type OnHostResolvedCallback func(e *Endpoint)
 
+// the meta with the passive guesses about the operating system of a host,
+// from the most to the least reliable
+var osMetaNames = []string{"tcp:os", "http:os", "dhcp:os", "os"}
+
 type Endpoint struct {
 	Index            int                    `json:"-"`
 	IP               net.IP                 `json:"-"`
@@ -20,17 +24,23 @@ type Endpoint struct {
 	IPv6             net.IP                 `json:"-"`
 	HW               net.HardwareAddr       `json:"-"`
 	IpAddress        string                 `json:"ipv4"`
+	Ip4Addresses     []string               `json:"ipv4_addresses,omitempty"`
 	Ip6Address       string                 `json:"ipv6"`
+	Ip6Addresses     []string               `json:"ipv6_addresses"`
 	SubnetBits       uint32                 `json:"-"`
 	IpAddressUint32  uint32                 `json:"-"`
 	HwAddress        string                 `json:"mac"`
 	Hostname         string                 `json:"hostname"`
 	Alias            string                 `json:"alias"`
+	Tags             []string               `json:"tags"`
+	Note             string                 `json:"note"`
 	Vendor           string                 `json:"vendor"`
 	ResolvedCallback OnHostResolvedCallback `json:"-"`
 	FirstSeen        time.Time              `json:"first_seen"`
 	LastSeen         time.Time              `json:"last_seen"`
 	Meta             *Meta                  `json:"meta"`
+	// the meta key the hostname was taken from
+	hostnameKey string
 }
 
 func NewEndpointNoResolve(ip, mac, name string, bits uint32) *Endpoint {
@@ -64,7 +74,7 @@ func NewEndpoint(ip, mac string) *Endpoint {
 	e := NewEndpointNoResolve(ip, mac, "", 0)
 	// start resolver goroutine
 	go func() {
-		if names, err := net.LookupAddr(e.IpAddress); err == nil && len(names) > 0 {
+		if names, err := DNS.LookupAddr(e.IpAddress); err == nil && len(names) > 0 {
 			e.Hostname = names[0]
 			if e.ResolvedCallback != nil {
 				e.ResolvedCallback(e)
@@ -115,6 +125,65 @@ func (t *Endpoint) SetIP(ip string) {
 	t.IpAddressUint32 = ip2int(addr)
 }
 
+// AddIPv6 adds one of the IPv6 addresses of the endpoint and returns false if
+// it was already known, a global address is preferred to a link-local one as
+// its main IPv6 address.
+func (t *Endpoint) AddIPv6(ip net.IP) bool {
+	address := ip.String()
+	for _, known := range t.Ip6Addresses {
+		if known == address {
+			return false
+		}
+	}
+
+	t.Ip6Addresses = append(t.Ip6Addresses, address)
+	if t.IPv6 == nil || (t.IPv6.IsLinkLocalUnicast() && !ip.IsLinkLocalUnicast()) {
+		t.IPv6 = ip
+		t.Ip6Address = address
+	}
+	return true
+}
+
+// AddIPv4 records another IPv4 address of the endpoint, like the ones of a
+// multi-homed host or those a proxy-ARP router answers for, and returns false
+// if it was already known. The main address is never replaced by them.
+func (t *Endpoint) AddIPv4(ip string) bool {
+	if ip == t.IpAddress {
+		return false
+	}
+	for _, known := range t.Ip4Addresses {
+		if known == ip {
+			return false
+		}
+	}
+
+	t.Ip4Addresses = append(t.Ip4Addresses, ip)
+	return true
+}
+
+// the main IPv4 address of the endpoint followed by the others
+func (t *Endpoint) ipv4Addresses() string {
+	return strings.Join(append([]string{t.IpAddress}, t.Ip4Addresses...), ",")
+}
+
+// true if the address is one of the IPv4 or IPv6 addresses of the endpoint
+func (t *Endpoint) hasIp(ip string) bool {
+	if ip == t.IpAddress || (ip != "" && ip == t.Ip6Address) {
+		return true
+	}
+	for _, address := range t.Ip4Addresses {
+		if ip == address {
+			return true
+		}
+	}
+	for _, address := range t.Ip6Addresses {
+		if ip == address {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *Endpoint) SetBits(bits uint32) {
 	t.SubnetBits = bits
 	_, netw, _ := net.ParseCIDR(t.CIDR())
@@ -161,19 +230,36 @@ func (t *Endpoint) String() string {
 	return fmt.Sprintf("%s%s (%s) - %s", ipPart, t.HwAddress, t.Vendor, tui.Bold(name))
 }
 
+// OS returns the best guess about the operating system of the host, if any.
+func (t *Endpoint) OS() string {
+	for _, name := range osMetaNames {
+		if guess, ok := t.Meta.Get(name).(string); ok && guess != "" {
+			return guess
+		}
+	}
+	return ""
+}
+
 func (t *Endpoint) OnMeta(meta map[string]string) {
 	host := ""
+	hostKey := ""
 	for k, v := range meta {
 		// simple heuristics to get the longest candidate name
 		if strings.HasSuffix(k, ":hostname") && len(v) > len(host) {
 			host = v
+			hostKey = k
 		} else if k == "mdns:md" && len(v) > len(host) {
 			host = v
+			hostKey = k
 		}
 		t.Meta.Set(k, v)
 	}
 
 	if t.Hostname == "" {
 		t.Hostname = host
+		t.hostnameKey = hostKey
+	} else if v, found := meta[t.hostnameKey]; found && t.hostnameKey != "" && v != "" {
+		// the same source is now announcing a different name
+		t.Hostname = v
 	}
 }
@@ -0,0 +1,194 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// HostSnapshot is the state of an endpoint at the time of a snapshot.
+type HostSnapshot struct {
+	MAC       string            `json:"mac"`
+	IPv4      string            `json:"ipv4"`
+	IPv6      string            `json:"ipv6"`
+	Hostname  string            `json:"hostname"`
+	Alias     string            `json:"alias"`
+	Vendor    string            `json:"vendor"`
+	Ports     []int             `json:"ports"`
+	Meta      map[string]string `json:"meta"`
+	FirstSeen time.Time         `json:"first_seen"`
+	LastSeen  time.Time         `json:"last_seen"`
+}
+
+// Snapshot is the state of the LAN at a given time, hosts are indexed by MAC.
+type Snapshot struct {
+	Name  string                   `json:"name"`
+	Time  time.Time                `json:"time"`
+	Hosts map[string]*HostSnapshot `json:"hosts"`
+}
+
+// HostDiff is a host found in both snapshots with the properties that changed.
+type HostDiff struct {
+	MAC     string           `json:"mac"`
+	IPv4    string           `json:"ipv4"`
+	Changes []EndpointChange `json:"changes"`
+}
+
+// SnapshotDiff lists what appeared, disappeared or changed between two snapshots.
+type SnapshotDiff struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Added   []*HostSnapshot `json:"added"`
+	Removed []*HostSnapshot `json:"removed"`
+	Changed []HostDiff      `json:"changed"`
+}
+
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// the ports of the "ports" meta, whatever the type of its values
+func metaPorts(value interface{}) []int {
+	ports := make([]int, 0)
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Map {
+		for _, key := range v.MapKeys() {
+			if key.Kind() == reflect.Int {
+				ports = append(ports, int(key.Int()))
+			}
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+func NewHostSnapshot(e *Endpoint) *HostSnapshot {
+	h := &HostSnapshot{
+		MAC:       e.HwAddress,
+		IPv4:      e.IpAddress,
+		IPv6:      e.Ip6Address,
+		Hostname:  e.Hostname,
+		Alias:     e.Alias,
+		Vendor:    e.Vendor,
+		Ports:     make([]int, 0),
+		Meta:      make(map[string]string),
+		FirstSeen: e.FirstSeen,
+		LastSeen:  e.LastSeen,
+	}
+
+	e.Meta.Each(func(name string, value interface{}) {
+		if name == "ports" {
+			h.Ports = metaPorts(value)
+		} else if s, ok := value.(string); ok {
+			h.Meta[name] = s
+		} else if raw, err := json.Marshal(value); err == nil {
+			h.Meta[name] = string(raw)
+		} else {
+			h.Meta[name] = fmt.Sprintf("%v", value)
+		}
+	})
+
+	return h
+}
+
+// Snapshot returns the current state of the LAN hosts.
+func (lan *LAN) Snapshot(name string) *Snapshot {
+	s := &Snapshot{
+		Name:  name,
+		Time:  time.Now(),
+		Hosts: make(map[string]*HostSnapshot),
+	}
+
+	for _, e := range lan.List() {
+		s.Hosts[e.HwAddress] = NewHostSnapshot(e)
+	}
+
+	return s
+}
+
+func valueChange(field, from, to string) *EndpointChange {
+	if from == to {
+		return nil
+	}
+	return &EndpointChange{Field: field, From: from, To: to}
+}
+
+// DiffHosts returns the changes between two states of the same host.
+func DiffHosts(from, to *HostSnapshot) []EndpointChange {
+	changes := []*EndpointChange{
+		valueChange("ipv4", from.IPv4, to.IPv4),
+		valueChange("ipv6", from.IPv6, to.IPv6),
+		valueChange("hostname", from.Hostname, to.Hostname),
+		valueChange("alias", from.Alias, to.Alias),
+		DiffPorts(from.Ports, to.Ports),
+	}
+
+	keys := make(map[string]bool)
+	for key := range from.Meta {
+		keys[key] = true
+	}
+	for key := range to.Meta {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		changes = append(changes, valueChange("meta."+key, from.Meta[key], to.Meta[key]))
+	}
+
+	list := make([]EndpointChange, 0)
+	for _, c := range changes {
+		if c != nil {
+			list = append(list, *c)
+		}
+	}
+	return list
+}
+
+func sortHosts(hosts []*HostSnapshot) {
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].MAC < hosts[j].MAC
+	})
+}
+
+// DiffSnapshots returns the hosts that appeared, disappeared or changed from a to b.
+func DiffSnapshots(a, b *Snapshot) *SnapshotDiff {
+	d := &SnapshotDiff{
+		From:    a.Name,
+		To:      b.Name,
+		Added:   make([]*HostSnapshot, 0),
+		Removed: make([]*HostSnapshot, 0),
+		Changed: make([]HostDiff, 0),
+	}
+
+	for mac, before := range a.Hosts {
+		if after, found := b.Hosts[mac]; !found {
+			d.Removed = append(d.Removed, before)
+		} else if changes := DiffHosts(before, after); len(changes) > 0 {
+			d.Changed = append(d.Changed, HostDiff{
+				MAC:     mac,
+				IPv4:    after.IPv4,
+				Changes: changes,
+			})
+		}
+	}
+
+	for mac, after := range b.Hosts {
+		if _, found := a.Hosts[mac]; !found {
+			d.Added = append(d.Added, after)
+		}
+	}
+
+	sortHosts(d.Added)
+	sortHosts(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool {
+		return d.Changed[i].MAC < d.Changed[j].MAC
+	})
+
+	return d
+}
@@ -0,0 +1,70 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testOpenPort struct {
+	Banner string
+}
+
+func TestNewHostSnapshot(t *testing.T) {
+	e := NewEndpointNoResolve("192.168.1.10", "aa:bb:cc:dd:ee:ff", "laptop", 24)
+	e.Meta.Set("ports", map[int]*testOpenPort{443: {}, 22: {}})
+	e.Meta.Set("mdns:model", "MacBookPro")
+	e.Meta.Set("count", 3)
+
+	h := NewHostSnapshot(e)
+	if h.MAC != "aa:bb:cc:dd:ee:ff" || h.IPv4 != "192.168.1.10" || h.Hostname != "laptop" {
+		t.Fatalf("unexpected snapshot %+v", h)
+	} else if !reflect.DeepEqual(h.Ports, []int{22, 443}) {
+		t.Fatalf("unexpected ports %v", h.Ports)
+	} else if h.Meta["mdns:model"] != "MacBookPro" || h.Meta["count"] != "3" {
+		t.Fatalf("unexpected meta %v", h.Meta)
+	} else if _, found := h.Meta["ports"]; found {
+		t.Fatalf("ports should not be part of the meta")
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	a := &Snapshot{
+		Name: "a",
+		Hosts: map[string]*HostSnapshot{
+			"aa:aa:aa:aa:aa:aa": {MAC: "aa:aa:aa:aa:aa:aa", IPv4: "10.0.0.1", Ports: []int{22}},
+			"bb:bb:bb:bb:bb:bb": {MAC: "bb:bb:bb:bb:bb:bb", IPv4: "10.0.0.2", Meta: map[string]string{"os": "linux"}},
+			"cc:cc:cc:cc:cc:cc": {MAC: "cc:cc:cc:cc:cc:cc", IPv4: "10.0.0.3"},
+		},
+	}
+	b := &Snapshot{
+		Name: "b",
+		Hosts: map[string]*HostSnapshot{
+			"aa:aa:aa:aa:aa:aa": {MAC: "aa:aa:aa:aa:aa:aa", IPv4: "10.0.0.1", Ports: []int{22}},
+			"bb:bb:bb:bb:bb:bb": {MAC: "bb:bb:bb:bb:bb:bb", IPv4: "10.0.0.20", Ports: []int{80}, Meta: map[string]string{"os": "windows"}},
+			"dd:dd:dd:dd:dd:dd": {MAC: "dd:dd:dd:dd:dd:dd", IPv4: "10.0.0.4"},
+		},
+	}
+
+	d := DiffSnapshots(a, b)
+	if d.From != "a" || d.To != "b" {
+		t.Fatalf("unexpected names %s -> %s", d.From, d.To)
+	} else if len(d.Added) != 1 || d.Added[0].MAC != "dd:dd:dd:dd:dd:dd" {
+		t.Fatalf("unexpected added hosts %v", d.Added)
+	} else if len(d.Removed) != 1 || d.Removed[0].MAC != "cc:cc:cc:cc:cc:cc" {
+		t.Fatalf("unexpected removed hosts %v", d.Removed)
+	} else if len(d.Changed) != 1 || d.Changed[0].MAC != "bb:bb:bb:bb:bb:bb" {
+		t.Fatalf("unexpected changed hosts %v", d.Changed)
+	}
+
+	fields := []string{}
+	for _, c := range d.Changed[0].Changes {
+		fields = append(fields, c.Field)
+	}
+	if !reflect.DeepEqual(fields, []string{"ipv4", "ports", "meta.os"}) {
+		t.Fatalf("unexpected changes %v", fields)
+	}
+
+	if d = DiffSnapshots(a, a); !d.Empty() {
+		t.Fatalf("expected no differences, got %+v", d)
+	}
+}
@@ -1,6 +1,7 @@
 package network
 
 import (
+	"net"
 	"testing"
 
 	"github.com/evilsocket/islazy/data"
@@ -12,7 +13,7 @@ func buildExampleLAN() *LAN {
 	exNewCallback := func(e *Endpoint) {}
 	exLostCallback := func(e *Endpoint) {}
 	aliases := &data.UnsortedKV{}
-	return NewLAN(iface, gateway, aliases, exNewCallback, exLostCallback)
+	return NewLAN(iface, gateway, aliases, exNewCallback, exLostCallback, nil)
 }
 
 func buildExampleEndpoint() *Endpoint {
@@ -32,7 +33,7 @@ func TestNewLAN(t *testing.T) {
 	exNewCallback := func(e *Endpoint) {}
 	exLostCallback := func(e *Endpoint) {}
 	aliases := &data.UnsortedKV{}
-	lan := NewLAN(iface, gateway, aliases, exNewCallback, exLostCallback)
+	lan := NewLAN(iface, gateway, aliases, exNewCallback, exLostCallback, nil)
 	if lan.iface != iface {
 		t.Fatalf("expected '%v', got '%v'", iface, lan.iface)
 	}
@@ -60,7 +61,7 @@ func TestMarshalJSON(t *testing.T) {
 	exNewCallback := func(e *Endpoint) {}
 	exLostCallback := func(e *Endpoint) {}
 	aliases := &data.UnsortedKV{}
-	lan := NewLAN(iface, gateway, aliases, exNewCallback, exLostCallback)
+	lan := NewLAN(iface, gateway, aliases, exNewCallback, exLostCallback, nil)
 	_, err = lan.MarshalJSON()
 	if err != nil {
 		t.Error(err)
@@ -171,6 +172,19 @@ func TestGetByIp(t *testing.T) {
 	}
 }
 
+func TestGetByIpv6(t *testing.T) {
+	exampleLAN := buildExampleLAN()
+	exampleEndpoint := NewEndpointNoResolve("192.168.1.42", "aa:bb:cc:dd:ee:ff", "", 24)
+	exampleEndpoint.SetIPv6("fe80::a8bb:ccff:fedd:eeff/64")
+	exampleLAN.hosts[exampleEndpoint.HwAddress] = exampleEndpoint
+
+	if got := exampleLAN.GetByIp("fe80::a8bb:ccff:fedd:eeff"); got != exampleEndpoint {
+		t.Fatalf("expected '%v', got '%v'", exampleEndpoint, got)
+	} else if got := exampleLAN.GetByIp("fe80::1"); got != nil {
+		t.Fatalf("expected nil, got '%v'", got)
+	}
+}
+
 func TestAddIfNew(t *testing.T) {
 	exampleLAN := buildExampleLAN()
 	iface, _ := FindInterface("")
@@ -207,3 +221,44 @@ func TestShouldIgnore(t *testing.T) {
 		t.Fatalf("expected '%v', got '%v'", exp, got)
 	}
 }
+
+func TestEndpointOS(t *testing.T) {
+	e := NewEndpointNoResolve("192.168.1.10", "aa:bb:cc:dd:ee:ff", "", 24)
+	if os := e.OS(); os != "" {
+		t.Fatalf("expected no guess, got '%s'", os)
+	}
+
+	e.OnMeta(map[string]string{"dhcp:os": "android"})
+	if os := e.OS(); os != "android" {
+		t.Fatalf("expected 'android', got '%s'", os)
+	}
+
+	// the SYN fingerprint is more reliable
+	e.OnMeta(map[string]string{"tcp:os": "linux", "http:os": "windows"})
+	if os := e.OS(); os != "linux" {
+		t.Fatalf("expected 'linux', got '%s'", os)
+	}
+}
+
+func TestAddIPv6(t *testing.T) {
+	exampleLAN := buildExampleLAN()
+	hw := "aa:bb:cc:dd:ee:01"
+
+	e, isNew := exampleLAN.AddIPv6(net.ParseIP("fe80::a8bb:ccff:fedd:ee01"), hw)
+	if e == nil || !isNew {
+		t.Fatalf("expected a new host")
+	} else if e.Ip6Address != "fe80::a8bb:ccff:fedd:ee01" {
+		t.Fatalf("unexpected IPv6 address %s", e.Ip6Address)
+	}
+
+	// the global address is preferred
+	if same, isNew := exampleLAN.AddIPv6(net.ParseIP("2001:db8::1"), hw); same != e || !isNew {
+		t.Fatalf("expected a new address of the same host")
+	} else if e.Ip6Address != "2001:db8::1" || len(e.Ip6Addresses) != 2 {
+		t.Fatalf("unexpected IPv6 addresses %s %v", e.Ip6Address, e.Ip6Addresses)
+	} else if _, isNew = exampleLAN.AddIPv6(net.ParseIP("fe80::a8bb:ccff:fedd:ee01"), hw); isNew {
+		t.Fatalf("the address was already known")
+	} else if got := exampleLAN.GetByIp("fe80::a8bb:ccff:fedd:ee01"); got != e {
+		t.Fatalf("expected '%v', got '%v'", e, got)
+	}
+}
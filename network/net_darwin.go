@@ -50,6 +50,12 @@ func getFrequenciesFromChannels(output string) ([]int, error) {
 	return freqs, nil
 }
 
+// SupportsMonitorMode can't tell in advance, libpcap fails to enable it if
+// the interface doesn't support it.
+func SupportsMonitorMode(iface string) error {
+	return nil
+}
+
 func GetSupportedFrequencies(iface string) ([]int, error) {
 	out, err := core.Exec("system_profiler", []string{"SPAirPortDataType"})
 	if err != nil {
@@ -75,11 +75,12 @@ func iwlistSupportedFrequencies(iface string) ([]int, error) {
 var iwPhyParser = regexp.MustCompile(`^\s*wiphy\s+(\d+)$`)
 var iwFreqParser = regexp.MustCompile(`^\s+\*\s+(\d+)\s+MHz.+$`)
 
-func iwSupportedFrequencies(iface string) ([]int, error) {
+// the output of iw phyN info for the phy of the interface
+func iwPhyInfo(iface string) (string, error) {
 	// first determine phy index
 	out, err := core.Exec("iw", []string{iface, "info"})
 	if err != nil {
-		return nil, fmt.Errorf("error getting %s phy index: %v", iface, err)
+		return "", fmt.Errorf("error getting %s phy index: %v", iface, err)
 	}
 
 	phy := int64(-1)
@@ -89,24 +90,32 @@ func iwSupportedFrequencies(iface string) ([]int, error) {
 		matches := iwPhyParser.FindStringSubmatch(line)
 		if len(matches) == 2 {
 			if phy, err = strconv.ParseInt(matches[1], 10, 32); err != nil {
-				return nil, fmt.Errorf("error parsing %s phy index: %v (line: %s)", iface, err, line)
+				return "", fmt.Errorf("error parsing %s phy index: %v (line: %s)", iface, err, line)
 			}
 		}
 	}
 
 	if phy == -1 {
-		return nil, fmt.Errorf("could not find %s phy index", iface)
+		return "", fmt.Errorf("could not find %s phy index", iface)
 	}
 
 	// then get phyN info
 	phyName := fmt.Sprintf("phy%d", phy)
 	out, err = core.Exec("iw", []string{phyName, "info"})
 	if err != nil {
-		return nil, fmt.Errorf("error getting %s (%s) info: %v", phyName, iface, err)
+		return "", fmt.Errorf("error getting %s (%s) info: %v", phyName, iface, err)
+	}
+	return out, nil
+}
+
+func iwSupportedFrequencies(iface string) ([]int, error) {
+	out, err := iwPhyInfo(iface)
+	if err != nil {
+		return nil, err
 	}
 
 	freqs := []int{}
-	scanner = bufio.NewScanner(strings.NewReader(out))
+	scanner := bufio.NewScanner(strings.NewReader(out))
 	for scanner.Scan() {
 		line := scanner.Text()
 		matches := iwFreqParser.FindStringSubmatch(line)
@@ -122,6 +131,22 @@ func iwSupportedFrequencies(iface string) ([]int, error) {
 	return freqs, nil
 }
 
+// SupportsMonitorMode returns an error if the phy of the interface can't be
+// put in monitor mode, without iw it can't be told and it's assumed it can.
+func SupportsMonitorMode(iface string) error {
+	if !core.HasBinary("iw") {
+		return nil
+	}
+
+	out, err := iwPhyInfo(iface)
+	if err != nil {
+		return err
+	} else if !iwSupportsMonitor(out) {
+		return fmt.Errorf("%s doesn't support monitor mode", iface)
+	}
+	return nil
+}
+
 func GetSupportedFrequencies(iface string) ([]int, error) {
 	if core.HasBinary("iw") {
 		return iwSupportedFrequencies(iface)
@@ -2,6 +2,7 @@ package network
 
 import (
 	"net"
+	"strings"
 	"testing"
 
 	"github.com/evilsocket/islazy/data"
@@ -167,3 +168,25 @@ func TestFindInterface(t *testing.T) {
 		t.Error("unable to find a given interface by name to build endpoint")
 	}
 }
+
+func TestIwSupportsMonitor(t *testing.T) {
+	info := `Wiphy phy0
+	max # scan SSIDs: 4
+	Supported interface modes:
+		 * IBSS
+		 * managed
+		 * AP
+		 * monitor
+	Band 1:
+		Frequencies:
+			* 2412 MHz [1] (20.0 dBm)
+`
+	if !iwSupportsMonitor(info) {
+		t.Fatalf("expected monitor mode to be supported")
+	}
+
+	info = strings.Replace(info, "* monitor", "* P2P-client", 1)
+	if iwSupportsMonitor(info) {
+		t.Fatalf("expected monitor mode not to be supported")
+	}
+}
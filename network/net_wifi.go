@@ -1,6 +1,7 @@
 package network
 
 import (
+	"strings"
 	"sync"
 )
 
@@ -25,3 +26,22 @@ func SetInterfaceCurrentChannel(iface string, channel int) {
 	defer currChannelLock.Unlock()
 	currChannels[iface] = channel
 }
+
+// iwSupportsMonitor returns true if monitor is among the supported interface
+// modes listed by iw phyN info.
+func iwSupportsMonitor(info string) bool {
+	modes := false
+	for _, line := range strings.Split(info, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Supported interface modes:") {
+			modes = true
+		} else if modes {
+			if !strings.HasPrefix(trimmed, "* ") {
+				return false
+			} else if strings.TrimSpace(trimmed[2:]) == "monitor" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -49,6 +49,12 @@ func SetInterfaceChannel(iface string, channel int) error {
 	return fmt.Errorf("Windows does not support WiFi channel hopping.")
 }
 
+// SupportsMonitorMode can't tell in advance, libpcap fails to enable it if
+// the interface doesn't support it.
+func SupportsMonitorMode(iface string) error {
+	return nil
+}
+
 func GetSupportedFrequencies(iface string) ([]int, error) {
 	freqs := make([]int, 0)
 	return freqs, fmt.Errorf("Windows does not support WiFi channel hopping.")
@@ -315,6 +315,54 @@ var portServices = map[string]map[int]string{
 		88:    "kerberos",
 		105:   "csnet-ns",
 		4600:  "distmp3",
+		3389:  "ms-wbt-server",
+		1723:  "pptp",
+		5900:  "vnc",
+		1025:  "NFS-or-IIS",
+		8888:  "sun-answerbook",
+		1720:  "h323q931",
+		81:    "hosts2-ns",
+		1026:  "LSA-or-nterm",
+		8443:  "https-alt",
+		8000:  "http-alt",
+		32768: "filenet-tms",
+		26:    "rsftp",
+		2001:  "dc",
+		8008:  "http",
+		1027:  "IIS",
+		646:   "ldp",
+		5000:  "upnp",
+		5631:  "pcanywheredata",
+		5800:  "vnc-http",
+		1110:  "nfsd-status",
+		5357:  "wsdapi",
+		5101:  "admdog",
+		144:   "news",
+		8009:  "ajp13",
+		3128:  "squid-http",
+		9999:  "abyss",
+		5009:  "airport-admin",
+		7070:  "realserver",
+		3000:  "ppp",
+		1900:  "upnp",
+		3986:  "mapper-ws_ethd",
+		1029:  "ms-lsa",
+		1755:  "wms",
+		2717:  "pn-requester",
+		9100:  "jetdirect",
+		591:   "http-alt",
+		593:   "http-rpc-epmap",
+		2080:  "autodesk-nlm",
+		4443:  "pharos",
+		9000:  "cslistener",
+		9090:  "zeus-admin",
+		9443:  "tungsten-https",
+		3268:  "globalcatLDAP",
+		3269:  "globalcatLDAPssl",
+		5985:  "wsman",
+		5986:  "wsmans",
+		9389:  "adws",
+		47001: "winrm",
 	},
 	"udp": {
 		2086:  "gnunet",
@@ -0,0 +1,63 @@
+package network
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// ParseTags splits a comma separated list of tags, returning them lowercase,
+// sorted and without duplicates.
+func ParseTags(list string) []string {
+	unique := make(map[string]bool)
+	for _, tag := range strings.Split(list, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			unique[tag] = true
+		}
+	}
+
+	tags := make([]string, 0, len(unique))
+	for tag := range unique {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+// AddTags returns the union of the two lists of tags.
+func AddTags(tags []string, add []string) []string {
+	return ParseTags(strings.Join(append(append([]string{}, tags...), add...), ","))
+}
+
+// RemoveTags returns the tags that are not in the del list.
+func RemoveTags(tags []string, del []string) []string {
+	kept := make([]string, 0)
+	for _, tag := range tags {
+		if !HasTag(del, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+func HasTag(tags []string, tag string) bool {
+	tag = strings.ToLower(tag)
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// WithTags appends the tags to a value shown in the tables.
+func WithTags(value string, tags []string) string {
+	if len(tags) == 0 {
+		return value
+	} else if value == "" {
+		return tui.Dim(strings.Join(tags, ","))
+	}
+	return value + " " + tui.Dim("["+strings.Join(tags, ",")+"]")
+}
@@ -0,0 +1,33 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	exp := []string{"critical", "dc"}
+	if got := ParseTags(" DC, critical,,dc "); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected '%v', got '%v'", exp, got)
+	}
+
+	if got := ParseTags(""); len(got) != 0 {
+		t.Fatalf("expected no tags, got '%v'", got)
+	}
+}
+
+func TestAddRemoveTags(t *testing.T) {
+	tags := AddTags([]string{"dc"}, []string{"critical", "dc"})
+	if exp := []string{"critical", "dc"}; !reflect.DeepEqual(tags, exp) {
+		t.Fatalf("expected '%v', got '%v'", exp, tags)
+	}
+
+	tags = RemoveTags(tags, []string{"dc", "printer"})
+	if exp := []string{"critical"}; !reflect.DeepEqual(tags, exp) {
+		t.Fatalf("expected '%v', got '%v'", exp, tags)
+	}
+
+	if !HasTag(tags, "Critical") || HasTag(tags, "dc") {
+		t.Fatalf("unexpected HasTag result for '%v'", tags)
+	}
+}
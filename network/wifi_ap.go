@@ -15,12 +15,14 @@ type AccessPoint struct {
 	aliases         *data.UnsortedKV
 	clients         map[string]*Station
 	withKeyMaterial bool
+	psk             string
 }
 
 type apJSON struct {
 	*Station
 	Clients   []*Station `json:"clients"`
 	Handshake bool       `json:"handshake"`
+	PSK       string     `json:"psk,omitempty"`
 }
 
 func NewAccessPoint(essid, bssid string, frequency int, rssi int8, aliases *data.UnsortedKV) *AccessPoint {
@@ -39,6 +41,7 @@ func (ap *AccessPoint) MarshalJSON() ([]byte, error) {
 		Station:   ap.Station,
 		Clients:   make([]*Station, 0, len(ap.clients)),
 		Handshake: ap.withKeyMaterial,
+		PSK:       ap.psk,
 	}
 
 	for _, c := range ap.clients {
@@ -134,6 +137,20 @@ func (ap *AccessPoint) HasKeyMaterial() bool {
 	return ap.withKeyMaterial
 }
 
+func (ap *AccessPoint) SetPSK(psk string) {
+	ap.Lock()
+	defer ap.Unlock()
+
+	ap.psk = psk
+}
+
+func (ap *AccessPoint) PSK() string {
+	ap.RLock()
+	defer ap.RUnlock()
+
+	return ap.psk
+}
+
 func (ap *AccessPoint) NumHandshakes() int {
 	ap.RLock()
 	defer ap.RUnlock()
@@ -15,6 +15,7 @@ type Handshake struct {
 	Responses     []gopacket.Packet
 	Confirmations []gopacket.Packet
 	hasPMKID      bool
+	pmkid         []byte
 	unsaved       []gopacket.Packet
 }
 
@@ -53,6 +54,7 @@ func (h *Handshake) AddAndGetPMKID(pkt gopacket.Packet) []byte {
 				h.Lock()
 				defer h.Unlock()
 				h.hasPMKID = true
+				h.pmkid = info.Info
 				return info.Info
 			}
 		}
@@ -121,6 +123,12 @@ func (h *Handshake) HasPMKID() bool {
 	return h.hasPMKID
 }
 
+func (h *Handshake) PMKID() []byte {
+	h.RLock()
+	defer h.RUnlock()
+	return h.pmkid
+}
+
 func (h *Handshake) Any() bool {
 	return h.HasPMKID() || h.Half() || h.Complete()
 }
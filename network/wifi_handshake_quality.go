@@ -0,0 +1,127 @@
+package network
+
+import (
+	"bytes"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// HandshakeQuality tells how likely a captured handshake is to be crackable.
+type HandshakeQuality struct {
+	Score int `json:"score"`
+	// the best usable combination of messages, like M1+M2+M3 or PMKID
+	Messages string   `json:"messages"`
+	Issues   []string `json:"issues"`
+}
+
+type eapolKeyInfo struct {
+	ReplayCounter uint64
+	Nonce         []byte
+}
+
+func zeroNonce(nonce []byte) bool {
+	for _, b := range nonce {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func keyInfos(pkts []gopacket.Packet) []eapolKeyInfo {
+	infos := make([]eapolKeyInfo, 0)
+	for _, pkt := range pkts {
+		// the beacon is stored along the responses
+		if key, ok := pkt.Layer(layers.LayerTypeEAPOLKey).(*layers.EAPOLKey); ok {
+			infos = append(infos, eapolKeyInfo{
+				ReplayCounter: key.ReplayCounter,
+				Nonce:         key.Nonce,
+			})
+		}
+	}
+	return infos
+}
+
+// Quality evaluates the captured messages of the handshake.
+func (h *Handshake) Quality() HandshakeQuality {
+	h.RLock()
+	defer h.RUnlock()
+
+	return scoreHandshake(keyInfos(h.Challenges), keyInfos(h.Responses), keyInfos(h.Confirmations), h.hasPMKID)
+}
+
+func addIssue(q *HandshakeQuality, issue string) {
+	for _, i := range q.Issues {
+		if i == issue {
+			return
+		}
+	}
+	q.Issues = append(q.Issues, issue)
+}
+
+/*
+ * Messages can only be combined if they belong to the same exchange:
+ * M2 echoes the replay counter of M1, M3 increments it and carries the
+ * same ANonce of M1, while both nonces must not be zero.
+ */
+func scoreHandshake(m1, m2, m3 []eapolKeyInfo, hasPMKID bool) HandshakeQuality {
+	q := HandshakeQuality{Issues: make([]string, 0)}
+
+	candidate := func(score int, messages string) {
+		if score > q.Score {
+			q.Score = score
+			q.Messages = messages
+		}
+	}
+
+	if hasPMKID {
+		candidate(90, "PMKID")
+	}
+
+	for _, res := range m2 {
+		if zeroNonce(res.Nonce) {
+			addIssue(&q, "zero SNonce in M2")
+			continue
+		}
+
+		for _, chal := range m1 {
+			if zeroNonce(chal.Nonce) {
+				addIssue(&q, "zero ANonce in M1")
+				continue
+			} else if chal.ReplayCounter != res.ReplayCounter {
+				addIssue(&q, "replay counters of M1 and M2 don't match")
+				candidate(30, "M1+M2")
+				continue
+			}
+
+			candidate(80, "M1+M2")
+			for _, conf := range m3 {
+				if conf.ReplayCounter != chal.ReplayCounter+1 {
+					continue
+				} else if !bytes.Equal(conf.Nonce, chal.Nonce) {
+					addIssue(&q, "ANonce of M3 doesn't match M1")
+				} else {
+					candidate(100, "M1+M2+M3")
+				}
+			}
+		}
+
+		for _, conf := range m3 {
+			if zeroNonce(conf.Nonce) {
+				addIssue(&q, "zero ANonce in M3")
+			} else if conf.ReplayCounter != res.ReplayCounter+1 {
+				addIssue(&q, "replay counters of M2 and M3 don't match")
+				candidate(20, "M2+M3")
+			} else {
+				candidate(70, "M2+M3")
+			}
+		}
+	}
+
+	if q.Score == 0 {
+		addIssue(&q, "incomplete exchange")
+	}
+
+	return q
+}
@@ -0,0 +1,40 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestScoreHandshake(t *testing.T) {
+	anonce := []byte{1, 2, 3, 4}
+	snonce := []byte{5, 6, 7, 8}
+	zero := []byte{0, 0, 0, 0}
+
+	cases := []struct {
+		name     string
+		m1       []eapolKeyInfo
+		m2       []eapolKeyInfo
+		m3       []eapolKeyInfo
+		pmkid    bool
+		score    int
+		messages string
+	}{
+		{"empty", nil, nil, nil, false, 0, ""},
+		{"pmkid", nil, nil, nil, true, 90, "PMKID"},
+		{"full", []eapolKeyInfo{{1, anonce}}, []eapolKeyInfo{{1, snonce}}, []eapolKeyInfo{{2, anonce}}, false, 100, "M1+M2+M3"},
+		{"m1m2", []eapolKeyInfo{{1, anonce}}, []eapolKeyInfo{{1, snonce}}, nil, false, 80, "M1+M2"},
+		{"m2m3", nil, []eapolKeyInfo{{1, snonce}}, []eapolKeyInfo{{2, anonce}}, false, 70, "M2+M3"},
+		{"wrong anonce", []eapolKeyInfo{{1, anonce}}, []eapolKeyInfo{{1, snonce}}, []eapolKeyInfo{{2, snonce}}, false, 80, "M1+M2"},
+		{"different exchanges", []eapolKeyInfo{{1, anonce}}, []eapolKeyInfo{{5, snonce}}, nil, false, 30, "M1+M2"},
+		{"zero snonce", []eapolKeyInfo{{1, anonce}}, []eapolKeyInfo{{1, zero}}, nil, false, 0, ""},
+	}
+
+	for _, c := range cases {
+		q := scoreHandshake(c.m1, c.m2, c.m3, c.pmkid)
+		if q.Score != c.score || q.Messages != c.messages {
+			t.Fatalf("%s: expected %d (%s), got %d (%s)", c.name, c.score, c.messages, q.Score, q.Messages)
+		}
+		if q.Score < 70 && len(q.Issues) == 0 && !c.pmkid {
+			t.Fatalf("%s: expected some issue", c.name)
+		}
+	}
+}
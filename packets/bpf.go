@@ -0,0 +1,66 @@
+package packets
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// BPFHosts returns a filter matching the traffic of any of the given
+// addresses, an empty string if there are none.
+func BPFHosts(addresses []net.IP, macs []net.HardwareAddr) string {
+	terms := make([]string, 0, len(addresses)+len(macs))
+	for _, ip := range addresses {
+		terms = append(terms, "host "+ip.String())
+	}
+	for _, hw := range macs {
+		terms = append(terms, "ether host "+hw.String())
+	}
+
+	if len(terms) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(terms, " or ") + ")"
+}
+
+// BPFAnd joins the non empty expressions into a single filter.
+func BPFAnd(exprs ...string) string {
+	terms := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		if expr = strings.TrimSpace(expr); expr != "" {
+			terms = append(terms, expr)
+		}
+	}
+
+	if len(terms) <= 1 {
+		return strings.Join(terms, "")
+	}
+	// even "(a) or (b)" needs them, and and or have the same precedence
+	return "(" + strings.Join(terms, ") and (") + ")"
+}
+
+// BPFOr returns a filter matching any of the non empty expressions.
+func BPFOr(exprs ...string) string {
+	terms := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		if expr = strings.TrimSpace(expr); expr != "" {
+			terms = append(terms, expr)
+		}
+	}
+
+	if len(terms) <= 1 {
+		return strings.Join(terms, "")
+	}
+	return "((" + strings.Join(terms, ") or (") + "))"
+}
+
+// BPFVLAN extends a filter to the frames with one or two (QinQ) 802.1Q tags,
+// which would never match it since its primitives use the untagged offsets.
+// Every vlan keyword shifts the offsets of the rest of the expression, so the
+// double tagged case is nested in the single tagged one.
+func BPFVLAN(expr string) string {
+	if expr = strings.TrimSpace(expr); expr == "" {
+		return ""
+	}
+	return fmt.Sprintf("(%s) or (vlan and ((%s) or (vlan and (%s))))", expr, expr, expr)
+}
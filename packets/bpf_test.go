@@ -0,0 +1,131 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+func TestBPFHosts(t *testing.T) {
+	if got := BPFHosts(nil, nil); got != "" {
+		t.Fatalf("expected an empty filter, got '%s'", got)
+	}
+
+	hw, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	exp := "(host 10.0.0.1 or host 10.0.0.2 or ether host aa:bb:cc:dd:ee:ff)"
+	if got := BPFHosts([]net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, []net.HardwareAddr{hw}); got != exp {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+}
+
+func TestBPFAnd(t *testing.T) {
+	for _, c := range []struct {
+		exprs []string
+		exp   string
+	}{
+		{[]string{"", " "}, ""},
+		{[]string{"udp", ""}, "udp"},
+		{[]string{"udp or tcp", "(host 10.0.0.1)", "not host 10.0.0.2"}, "(udp or tcp) and ((host 10.0.0.1)) and (not host 10.0.0.2)"},
+		{[]string{"not host 10.0.0.1", "(tcp) or (udp)"}, "(not host 10.0.0.1) and ((tcp) or (udp))"},
+	} {
+		if got := BPFAnd(c.exprs...); got != c.exp {
+			t.Fatalf("expected '%s', got '%s'", c.exp, got)
+		}
+	}
+}
+
+func TestBPFOr(t *testing.T) {
+	for _, c := range []struct {
+		exprs []string
+		exp   string
+	}{
+		{[]string{"", " "}, ""},
+		{[]string{"", "udp port 53"}, "udp port 53"},
+		{[]string{"udp port 53", "tcp port 445 or tcp port 139"}, "((udp port 53) or (tcp port 445 or tcp port 139))"},
+	} {
+		if got := BPFOr(c.exprs...); got != c.exp {
+			t.Fatalf("expected '%s', got '%s'", c.exp, got)
+		}
+	}
+}
+
+func TestBPFVLAN(t *testing.T) {
+	if got := BPFVLAN(" "); got != "" {
+		t.Fatalf("expected an empty filter, got '%s'", got)
+	}
+
+	exp := "(tcp port 80) or (vlan and ((tcp port 80) or (vlan and (tcp port 80))))"
+	if got := BPFVLAN("tcp port 80"); got != exp {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+}
+
+func taggedFrame(t *testing.T, port layers.TCPPort, vlans ...uint16) []byte {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP("10.0.0.1"),
+		DstIP:    net.ParseIP("10.0.0.2"),
+	}
+	tcp := layers.TCP{
+		SrcPort: 12345,
+		DstPort: port,
+		SYN:     true,
+	}
+	tcp.SetNetworkLayerForChecksum(&ip4)
+
+	stack := []gopacket.SerializableLayer{&eth}
+	for i, id := range vlans {
+		next := layers.EthernetTypeDot1Q
+		if i == len(vlans)-1 {
+			next = layers.EthernetTypeIPv4
+		}
+		if i == 0 {
+			eth.EthernetType = layers.EthernetTypeDot1Q
+		}
+		stack = append(stack, &layers.Dot1Q{VLANIdentifier: id, Type: next})
+	}
+	stack = append(stack, &ip4, &tcp)
+
+	err, raw := Serialize(stack...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestBPFVLANMatches(t *testing.T) {
+	bpf, err := pcap.NewBPF(layers.LinkTypeEthernet, 65535, BPFVLAN("tcp port 80"))
+	if err != nil {
+		t.Skipf("could not compile the filter: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		match bool
+	}{
+		{"untagged", taggedFrame(t, 80), true},
+		{"tagged", taggedFrame(t, 80, 10), true},
+		{"double tagged", taggedFrame(t, 80, 10, 20), true},
+		{"untagged other port", taggedFrame(t, 81), false},
+		{"tagged other port", taggedFrame(t, 81, 10), false},
+		{"double tagged other port", taggedFrame(t, 81, 10, 20), false},
+	}
+
+	for _, test := range tests {
+		ci := gopacket.CaptureInfo{CaptureLength: len(test.frame), Length: len(test.frame)}
+		if got := bpf.Matches(ci, test.frame); got != test.match {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.match, got)
+		}
+	}
+}
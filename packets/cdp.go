@@ -0,0 +1,174 @@
+package packets
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	CDPMulticastMAC = net.HardwareAddr{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc}
+)
+
+const (
+	CDPVersion = 2
+
+	cdpCapRouter = 0x01
+	cdpCapBridge = 0x02
+	cdpCapSwitch = 0x08
+	cdpCapHost   = 0x10
+	cdpCapIGMP   = 0x20
+	cdpCapPhone  = 0x80
+
+	// what IP phones send in the VoIP VLAN query
+	cdpVoiceQueryID = 0x20
+)
+
+// CDPAnnounce is a serializable CDP payload, gopacket only decodes them.
+type CDPAnnounce struct {
+	Identity *DiscoveryIdentity
+}
+
+func cdpTLV(t layers.CDPTLVType, value []byte) []byte {
+	tlv := make([]byte, 4+len(value))
+	binary.BigEndian.PutUint16(tlv[0:], uint16(t))
+	binary.BigEndian.PutUint16(tlv[2:], uint16(len(tlv)))
+	copy(tlv[4:], value)
+	return tlv
+}
+
+func cdpCapabilities(id *DiscoveryIdentity) uint32 {
+	caps := uint32(0)
+	if id.Has(DiscoveryCapRouter) {
+		caps |= cdpCapRouter
+	}
+	if id.Has(DiscoveryCapSwitch) {
+		caps |= cdpCapSwitch | cdpCapIGMP
+	}
+	if id.Has(DiscoveryCapAccessPoint) {
+		caps |= cdpCapBridge
+	}
+	if id.Has(DiscoveryCapHost) {
+		caps |= cdpCapHost
+	}
+	if id.Has(DiscoveryCapPhone) {
+		caps |= cdpCapPhone
+	}
+	return caps
+}
+
+// CDPChecksum computes the CDP checksum, which differs from the IP one
+// when the packet has an odd length.
+func CDPChecksum(data []byte) uint16 {
+	if n := len(data); n%2 == 1 {
+		last := data[n-1]
+		padded := make([]byte, n+1)
+		copy(padded, data[:n-1])
+		if last <= 0x80 {
+			padded[n-1], padded[n] = 0x00, last
+		} else {
+			padded[n-1], padded[n] = 0xff, last-1
+		}
+		data = padded
+	}
+
+	sum := uint32(0)
+	for i := 0; i < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+func (c *CDPAnnounce) Payload() []byte {
+	id := c.Identity
+	data := []byte{CDPVersion, byte(id.TTL), 0x00, 0x00}
+
+	data = append(data, cdpTLV(layers.CDPTLVDevID, []byte(id.Name))...)
+
+	if ip4 := id.Address.To4(); ip4 != nil {
+		// one NLPID address: protocol type, length, IP, address length
+		addr := []byte{0x00, 0x00, 0x00, 0x01, 0x01, 0x01, 0xcc, 0x00, 0x04}
+		data = append(data, cdpTLV(layers.CDPTLVAddress, append(addr, ip4...))...)
+	}
+
+	data = append(data, cdpTLV(layers.CDPTLVPortID, []byte(id.Port))...)
+
+	caps := make([]byte, 4)
+	binary.BigEndian.PutUint32(caps, cdpCapabilities(id))
+	data = append(data, cdpTLV(layers.CDPTLVCapabilities, caps)...)
+
+	if id.Version != "" {
+		data = append(data, cdpTLV(layers.CDPTLVVersion, []byte(id.Version))...)
+	}
+	if id.Platform != "" {
+		data = append(data, cdpTLV(layers.CDPTLVPlatform, []byte(id.Platform))...)
+	}
+	if id.NativeVLAN != 0 {
+		vlan := make([]byte, 2)
+		binary.BigEndian.PutUint16(vlan, id.NativeVLAN)
+		data = append(data, cdpTLV(layers.CDPTLVNativeVLAN, vlan)...)
+	}
+
+	data = append(data, cdpTLV(layers.CDPTLVFullDuplex, []byte{0x01})...)
+
+	if id.VoiceQuery {
+		data = append(data, cdpTLV(layers.CDPTLVVLANQuery, []byte{cdpVoiceQueryID, 0x00, 0x01})...)
+	} else if id.VoiceVLAN != 0 {
+		data = append(data, cdpTLV(layers.CDPTLVVLANReply, []byte{0x01, byte(id.VoiceVLAN >> 8), byte(id.VoiceVLAN)})...)
+	}
+
+	binary.BigEndian.PutUint16(data[2:], CDPChecksum(data))
+
+	return data
+}
+
+func (c *CDPAnnounce) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	payload := c.Payload()
+	bytes, err := b.PrependBytes(len(payload))
+	if err != nil {
+		return err
+	}
+	copy(bytes, payload)
+	return nil
+}
+
+func (c *CDPAnnounce) LayerType() gopacket.LayerType {
+	return layers.LayerTypeCiscoDiscovery
+}
+
+func NewCDPAnnounce(id *DiscoveryIdentity) (layers.Ethernet, layers.LLC, layers.SNAP, CDPAnnounce) {
+	eth := layers.Ethernet{
+		SrcMAC:       id.HW,
+		DstMAC:       CDPMulticastMAC,
+		EthernetType: layers.EthernetTypeLLC,
+	}
+	llc := layers.LLC{
+		DSAP:    0xaa,
+		SSAP:    0xaa,
+		Control: 0x03,
+	}
+	snap := layers.SNAP{
+		OrganizationalCode: []byte{0x00, 0x00, 0x0c},
+		Type:               layers.EthernetTypeCiscoDiscovery,
+	}
+
+	return eth, llc, snap, CDPAnnounce{Identity: id}
+}
+
+func NewCDPAnnouncePacket(id *DiscoveryIdentity) (error, []byte) {
+	eth, llc, snap, cdp := NewCDPAnnounce(id)
+	return Serialize(&eth, &llc, &snap, &cdp)
+}
+
+// CDPVoiceVLAN returns the voice VLAN announced with CDP by a switch, if any.
+func CDPVoiceVLAN(info *layers.CiscoDiscoveryInfo) (uint16, bool) {
+	if info.VLANReply.VLAN != 0 {
+		return info.VLANReply.VLAN, true
+	}
+	return 0, false
+}
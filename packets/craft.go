@@ -0,0 +1,394 @@
+package packets
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// fields accepted by ParsePacketSpec
+var CraftFields = []string{
+	"eth.src", "eth.dst", "eth.type",
+	"ip.src", "ip.dst", "ip.ttl", "ip.id", "ip.tos", "ip.proto",
+	"tcp.sport", "tcp.dport", "tcp.flags", "tcp.seq", "tcp.ack", "tcp.win",
+	"udp.sport", "udp.dport",
+	"payload", "payload.hex",
+}
+
+// PacketSpec is a packet described as a list of field=value pairs, like
+// ip.dst=10.0.0.1 tcp.dport=80 tcp.flags=S, or a raw hex template when it
+// starts with hex:
+type PacketSpec struct {
+	Fields map[string]string
+	Raw    []byte
+}
+
+// CraftDefaults are the values of the fields not set by the spec.
+type CraftDefaults struct {
+	SrcMAC  net.HardwareAddr
+	DstMAC  net.HardwareAddr
+	SrcIP   net.IP
+	SrcIPv6 net.IP
+}
+
+// split on white spaces outside of double quotes
+func tokenize(spec string) ([]string, error) {
+	tokens := []string{}
+	current := ""
+	quoted := false
+	escaped := false
+
+	for _, c := range spec {
+		switch {
+		case escaped:
+			current += string(c)
+			escaped = false
+		case c == '\\' && quoted:
+			current += string(c)
+			escaped = true
+		case c == '"':
+			current += string(c)
+			quoted = !quoted
+		case (c == ' ' || c == '\t') && !quoted:
+			if current != "" {
+				tokens = append(tokens, current)
+				current = ""
+			}
+		default:
+			current += string(c)
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quoted value")
+	} else if current != "" {
+		tokens = append(tokens, current)
+	}
+
+	return tokens, nil
+}
+
+func ParsePacketSpec(spec string) (*PacketSpec, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "hex:") {
+		raw, err := hex.DecodeString(strings.Replace(spec[4:], " ", "", -1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex template: %v", err)
+		} else if len(raw) == 0 {
+			return nil, fmt.Errorf("empty hex template")
+		}
+		return &PacketSpec{Raw: raw}, nil
+	}
+
+	tokens, err := tokenize(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PacketSpec{Fields: make(map[string]string)}
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("'%s' is not a field=value pair", token)
+		}
+
+		name, value := strings.ToLower(parts[0]), parts[1]
+		known := false
+		for _, field := range CraftFields {
+			if name == field {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown field '%s', supported fields are %s", name, strings.Join(CraftFields, ", "))
+		}
+
+		if strings.HasPrefix(value, "\"") {
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("invalid quoted value for %s: %v", name, err)
+			}
+		}
+
+		s.Fields[name] = value
+	}
+
+	if len(s.Fields) == 0 {
+		return nil, fmt.Errorf("empty packet spec")
+	}
+
+	return s, nil
+}
+
+// Has returns true if any field with the given prefix has been set.
+func (s *PacketSpec) Has(prefix string) bool {
+	for name := range s.Fields {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DstIP returns the destination address of the packet, if any.
+func (s *PacketSpec) DstIP() net.IP {
+	if v, found := s.Fields["ip.dst"]; found {
+		return net.ParseIP(v)
+	}
+	return nil
+}
+
+func (s *PacketSpec) uint(name string, bits int, def uint64) (uint64, error) {
+	v, found := s.Fields[name]
+	if !found {
+		return def, nil
+	}
+	n, err := strconv.ParseUint(v, 0, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s' for %s: %v", v, name, err)
+	}
+	return n, nil
+}
+
+func (s *PacketSpec) mac(name string, def net.HardwareAddr) (net.HardwareAddr, error) {
+	v, found := s.Fields[name]
+	if !found {
+		if def == nil {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+		return def, nil
+	}
+	return net.ParseMAC(v)
+}
+
+func (s *PacketSpec) ip(name string, def net.IP) (net.IP, error) {
+	v, found := s.Fields[name]
+	if !found {
+		if def == nil {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+		return def, nil
+	}
+	if ip := net.ParseIP(v); ip != nil {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("invalid address '%s' for %s", v, name)
+}
+
+func (s *PacketSpec) payload() ([]byte, error) {
+	if v, found := s.Fields["payload.hex"]; found {
+		return hex.DecodeString(strings.Replace(v, " ", "", -1))
+	}
+	return []byte(s.Fields["payload"]), nil
+}
+
+func parseTCPFlags(tcp *layers.TCP, flags string) error {
+	for _, f := range strings.ToUpper(flags) {
+		switch f {
+		case 'S':
+			tcp.SYN = true
+		case 'A':
+			tcp.ACK = true
+		case 'F':
+			tcp.FIN = true
+		case 'R':
+			tcp.RST = true
+		case 'P':
+			tcp.PSH = true
+		case 'U':
+			tcp.URG = true
+		case 'E':
+			tcp.ECE = true
+		case 'C':
+			tcp.CWR = true
+		default:
+			return fmt.Errorf("unknown tcp flag '%c', supported flags are SAFRPUEC", f)
+		}
+	}
+	return nil
+}
+
+// Build serializes the packet, using the defaults for the missing fields.
+func (s *PacketSpec) Build(defaults CraftDefaults) (error, []byte) {
+	if s.Raw != nil {
+		return nil, s.Raw
+	}
+
+	payload, err := s.payload()
+	if err != nil {
+		return fmt.Errorf("invalid payload: %v", err), nil
+	}
+
+	eth := &layers.Ethernet{}
+	if eth.SrcMAC, err = s.mac("eth.src", defaults.SrcMAC); err != nil {
+		return err, nil
+	} else if eth.DstMAC, err = s.mac("eth.dst", defaults.DstMAC); err != nil {
+		return err, nil
+	}
+
+	isTCP := s.Has("tcp.")
+	isUDP := s.Has("udp.")
+	if isTCP && isUDP {
+		return fmt.Errorf("tcp and udp fields can't be mixed"), nil
+	}
+
+	stack := []gopacket.SerializableLayer{eth}
+
+	if !s.Has("ip.") && !isTCP && !isUDP {
+		etype, err := s.uint("eth.type", 16, 0x0800)
+		if err != nil {
+			return err, nil
+		}
+		eth.EthernetType = layers.EthernetType(etype)
+		stack = append(stack, gopacket.Payload(payload))
+		return Serialize(stack...)
+	}
+
+	dst, err := s.ip("ip.dst", nil)
+	if err != nil {
+		return err, nil
+	}
+
+	var proto layers.IPProtocol
+	if isTCP {
+		proto = layers.IPProtocolTCP
+	} else if isUDP {
+		proto = layers.IPProtocolUDP
+	} else if p, err := s.uint("ip.proto", 8, 255); err != nil {
+		return err, nil
+	} else {
+		proto = layers.IPProtocol(p)
+	}
+
+	ttl, err := s.uint("ip.ttl", 8, 64)
+	if err != nil {
+		return err, nil
+	}
+
+	var network gopacket.NetworkLayer
+	if dst.To4() != nil {
+		src, err := s.ip("ip.src", defaults.SrcIP)
+		if err != nil {
+			return err, nil
+		}
+		id, err := s.uint("ip.id", 16, 0)
+		if err != nil {
+			return err, nil
+		}
+		tos, err := s.uint("ip.tos", 8, 0)
+		if err != nil {
+			return err, nil
+		}
+
+		eth.EthernetType = layers.EthernetTypeIPv4
+		ip4 := &layers.IPv4{
+			Version:  4,
+			SrcIP:    src,
+			DstIP:    dst,
+			TTL:      uint8(ttl),
+			Id:       uint16(id),
+			TOS:      uint8(tos),
+			Protocol: proto,
+		}
+		network = ip4
+		stack = append(stack, ip4)
+	} else {
+		src, err := s.ip("ip.src", defaults.SrcIPv6)
+		if err != nil {
+			return err, nil
+		}
+
+		eth.EthernetType = layers.EthernetTypeIPv6
+		ip6 := &layers.IPv6{
+			Version:    6,
+			SrcIP:      src,
+			DstIP:      dst,
+			HopLimit:   uint8(ttl),
+			NextHeader: proto,
+		}
+		network = ip6
+		stack = append(stack, ip6)
+	}
+
+	if isTCP {
+		tcp := &layers.TCP{}
+		if v, err := s.uint("tcp.sport", 16, 0); err != nil {
+			return err, nil
+		} else {
+			tcp.SrcPort = layers.TCPPort(v)
+		}
+		if v, err := s.uint("tcp.dport", 16, 0); err != nil {
+			return err, nil
+		} else {
+			tcp.DstPort = layers.TCPPort(v)
+		}
+		if v, err := s.uint("tcp.seq", 32, 0); err != nil {
+			return err, nil
+		} else {
+			tcp.Seq = uint32(v)
+		}
+		if v, err := s.uint("tcp.ack", 32, 0); err != nil {
+			return err, nil
+		} else {
+			tcp.Ack = uint32(v)
+		}
+		if v, err := s.uint("tcp.win", 16, 65535); err != nil {
+			return err, nil
+		} else {
+			tcp.Window = uint16(v)
+		}
+		if err := parseTCPFlags(tcp, s.Fields["tcp.flags"]); err != nil {
+			return err, nil
+		}
+
+		tcp.SetNetworkLayerForChecksum(network)
+		stack = append(stack, tcp)
+	} else if isUDP {
+		udp := &layers.UDP{}
+		if v, err := s.uint("udp.sport", 16, 0); err != nil {
+			return err, nil
+		} else {
+			udp.SrcPort = layers.UDPPort(v)
+		}
+		if v, err := s.uint("udp.dport", 16, 0); err != nil {
+			return err, nil
+		} else {
+			udp.DstPort = layers.UDPPort(v)
+		}
+
+		udp.SetNetworkLayerForChecksum(network)
+		stack = append(stack, udp)
+	}
+
+	stack = append(stack, gopacket.Payload(payload))
+	return Serialize(stack...)
+}
+
+// String returns the spec in its canonical form.
+func (s *PacketSpec) String() string {
+	if s.Raw != nil {
+		return "hex:" + hex.EncodeToString(s.Raw)
+	}
+
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		value := s.Fields[name]
+		if strings.ContainsAny(value, " \t\"") {
+			value = strconv.Quote(value)
+		}
+		parts[i] = name + "=" + value
+	}
+	return strings.Join(parts, " ")
+}
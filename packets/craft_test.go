@@ -0,0 +1,100 @@
+package packets
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var craftDefaults = CraftDefaults{
+	SrcMAC: net.HardwareAddr{0x01, 0x23, 0x45, 0x67, 0x89, 0xab},
+	DstMAC: net.HardwareAddr{0xab, 0x89, 0x67, 0x45, 0x23, 0x01},
+	SrcIP:  net.ParseIP("192.168.1.2"),
+}
+
+func TestParsePacketSpecErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"ip.dst",
+		"foo.bar=1",
+		"payload=\"unterminated",
+		"hex:zz",
+	} {
+		if _, err := ParsePacketSpec(spec); err == nil {
+			t.Fatalf("expected an error for '%s'", spec)
+		}
+	}
+}
+
+func TestPacketSpecTCP(t *testing.T) {
+	spec, err := ParsePacketSpec(`ip.dst=192.168.1.10 tcp.dport=80 tcp.flags=SA payload="GET / HTTP/1.0\r\n\r\n"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err, raw := spec.Build(craftDefaults)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	eth := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	if !bytes.Equal(eth.SrcMAC, craftDefaults.SrcMAC) || !bytes.Equal(eth.DstMAC, craftDefaults.DstMAC) {
+		t.Fatalf("unexpected ethernet addresses %s -> %s", eth.SrcMAC, eth.DstMAC)
+	}
+
+	ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	if !ok {
+		t.Fatalf("expected an ipv4 layer")
+	} else if !ip4.SrcIP.Equal(craftDefaults.SrcIP) || ip4.DstIP.String() != "192.168.1.10" || ip4.TTL != 64 {
+		t.Fatalf("unexpected ipv4 layer %+v", ip4)
+	}
+
+	tcp, ok := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP)
+	if !ok {
+		t.Fatalf("expected a tcp layer")
+	} else if tcp.DstPort != 80 || !tcp.SYN || !tcp.ACK || tcp.FIN {
+		t.Fatalf("unexpected tcp layer %+v", tcp)
+	} else if string(tcp.Payload) != "GET / HTTP/1.0\r\n\r\n" {
+		t.Fatalf("unexpected payload %q", tcp.Payload)
+	}
+}
+
+func TestPacketSpecUDPv6(t *testing.T) {
+	spec, err := ParsePacketSpec("ip.src=fe80::1 ip.dst=ff02::1 udp.sport=5353 udp.dport=5353 payload.hex=dead")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err, raw := spec.Build(craftDefaults)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); !ok || ip6.DstIP.String() != "ff02::1" {
+		t.Fatalf("expected an ipv6 layer to ff02::1")
+	} else if udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP); !ok || udp.SrcPort != 5353 || !bytes.Equal(udp.Payload, []byte{0xde, 0xad}) {
+		t.Fatalf("unexpected udp layer")
+	}
+}
+
+func TestPacketSpecRaw(t *testing.T) {
+	spec, err := ParsePacketSpec("hex:ffff ffff ffff")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err, raw := spec.Build(craftDefaults); err != nil || len(raw) != 6 {
+		t.Fatalf("unexpected raw packet %x (%v)", raw, err)
+	} else if spec.String() != "hex:ffffffffffff" {
+		t.Fatalf("unexpected string %s", spec.String())
+	}
+
+	if spec, err = ParsePacketSpec("ip.dst=10.0.0.1 tcp.dport=1 udp.dport=2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err, _ := spec.Build(craftDefaults); err == nil {
+		t.Fatalf("expected an error mixing tcp and udp")
+	}
+}
@@ -0,0 +1,109 @@
+package packets
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	DHCPServerPort = 67
+	DHCPClientPort = 68
+)
+
+// DHCPInfo has the options of a DHCP message needed to follow a transaction
+// and to identify its client.
+type DHCPInfo struct {
+	Type        layers.DHCPMsgType `json:"type"`
+	Hostname    string             `json:"hostname"`
+	VendorClass string             `json:"vendor_class"`
+	Fingerprint string             `json:"fingerprint"`
+	Requested   net.IP             `json:"requested"`
+	ServerID    net.IP             `json:"server_id"`
+	LeaseTime   uint32             `json:"lease_time"`
+	Router      net.IP             `json:"router"`
+	DNS         []net.IP           `json:"dns"`
+}
+
+// vendor class (option 60) prefixes and the operating system sending them
+var dhcpVendorOS = []struct {
+	prefix string
+	os     string
+}{
+	{"MSFT", "windows"},
+	{"android-dhcp", "android"},
+	{"dhcpcd", "linux"},
+	{"udhcp", "linux"},
+}
+
+// parameter request list (option 55) of the clients without a vendor class
+var dhcpFingerprintOS = map[string]string{
+	"1,3,6,15,31,33,43,44,46,47,119,121,249,252": "windows",
+	"1,15,3,6,44,46,47,31,33,121,249,43,252":     "windows",
+	"1,15,3,6,44,46,47,31,33,121,249,43":         "windows",
+	"1,121,3,6,15,119,252,95,44,46":              "macos",
+	"1,121,3,6,15,108,114,119,162,252,95,44,46":  "macos",
+	"1,121,3,6,15,119,252":                       "ios",
+	"1,121,3,6,15,108,114,119,162,252":           "ios",
+	"1,28,2,3,15,6,119,12,44,47,26,121,42":       "linux",
+}
+
+func dhcpIP(data []byte) net.IP {
+	if len(data) < 4 {
+		return nil
+	}
+	return net.IP(append([]byte{}, data[:4]...))
+}
+
+// ParseDHCPOptions returns the options of a DHCP message relevant to identify
+// the client and to follow the transaction.
+func ParseDHCPOptions(dhcp *layers.DHCPv4) *DHCPInfo {
+	info := &DHCPInfo{}
+	for _, opt := range dhcp.Options {
+		switch opt.Type {
+		case layers.DHCPOptMessageType:
+			if len(opt.Data) == 1 {
+				info.Type = layers.DHCPMsgType(opt.Data[0])
+			}
+		case layers.DHCPOptHostname:
+			info.Hostname = string(opt.Data)
+		case layers.DHCPOptClassID:
+			info.VendorClass = string(opt.Data)
+		case layers.DHCPOptParamsRequest:
+			params := make([]string, len(opt.Data))
+			for i, p := range opt.Data {
+				params[i] = strconv.Itoa(int(p))
+			}
+			info.Fingerprint = strings.Join(params, ",")
+		case layers.DHCPOptRequestIP:
+			info.Requested = dhcpIP(opt.Data)
+		case layers.DHCPOptServerID:
+			info.ServerID = dhcpIP(opt.Data)
+		case layers.DHCPOptLeaseTime:
+			if len(opt.Data) == 4 {
+				info.LeaseTime = binary.BigEndian.Uint32(opt.Data)
+			}
+		case layers.DHCPOptRouter:
+			info.Router = dhcpIP(opt.Data)
+		case layers.DHCPOptDNS:
+			for i := 0; i+4 <= len(opt.Data); i += 4 {
+				info.DNS = append(info.DNS, dhcpIP(opt.Data[i:]))
+			}
+		}
+	}
+	return info
+}
+
+// OS guesses the operating system of the client from its vendor class or
+// its option 55 fingerprint, returns an empty string if unknown.
+func (i *DHCPInfo) OS() string {
+	for _, v := range dhcpVendorOS {
+		if strings.HasPrefix(i.VendorClass, v.prefix) {
+			return v.os
+		}
+	}
+	return dhcpFingerprintOS[i.Fingerprint]
+}
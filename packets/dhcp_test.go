@@ -0,0 +1,107 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func buildTestDHCP(t *testing.T, options layers.DHCPOptions) *layers.DHCPv4 {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0x01},
+		DstMAC:       layers.EthernetBroadcast,
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.IPv4zero,
+		DstIP:    net.IPv4bcast,
+	}
+	udp := layers.UDP{SrcPort: DHCPClientPort, DstPort: DHCPServerPort}
+	udp.SetNetworkLayerForChecksum(&ip4)
+	dhcp := layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		HardwareType: layers.LinkTypeEthernet,
+		HardwareLen:  6,
+		Xid:          0x1234,
+		ClientHWAddr: eth.SrcMAC,
+		Options:      options,
+	}
+
+	err, raw := Serialize(&eth, &ip4, &udp, &dhcp)
+	if err != nil {
+		t.Fatalf("error serializing packet: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	parsed, ok := pkt.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok {
+		t.Fatal("expected a DHCPv4 layer")
+	}
+	return parsed
+}
+
+func TestParseDHCPOptions(t *testing.T) {
+	dhcp := buildTestDHCP(t, layers.DHCPOptions{
+		layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeRequest)}),
+		layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{192, 168, 1, 42}),
+		layers.NewDHCPOption(layers.DHCPOptServerID, []byte{192, 168, 1, 1}),
+		layers.NewDHCPOption(layers.DHCPOptHostname, []byte("DESKTOP-1234")),
+		layers.NewDHCPOption(layers.DHCPOptClassID, []byte("MSFT 5.0")),
+		layers.NewDHCPOption(layers.DHCPOptParamsRequest, []byte{1, 3, 6, 15, 31, 33, 43, 44, 46, 47, 119, 121, 249, 252}),
+		layers.NewDHCPOption(layers.DHCPOptEnd, nil),
+	})
+
+	info := ParseDHCPOptions(dhcp)
+	if info.Type != layers.DHCPMsgTypeRequest {
+		t.Fatalf("unexpected type %s", info.Type)
+	} else if info.Hostname != "DESKTOP-1234" || info.VendorClass != "MSFT 5.0" {
+		t.Fatalf("unexpected info %+v", info)
+	} else if !info.Requested.Equal(net.ParseIP("192.168.1.42")) || !info.ServerID.Equal(net.ParseIP("192.168.1.1")) {
+		t.Fatalf("unexpected addresses %s %s", info.Requested, info.ServerID)
+	} else if info.Fingerprint != "1,3,6,15,31,33,43,44,46,47,119,121,249,252" {
+		t.Fatalf("unexpected fingerprint %s", info.Fingerprint)
+	} else if info.OS() != "windows" {
+		t.Fatalf("unexpected os %s", info.OS())
+	}
+
+	dhcp = buildTestDHCP(t, layers.DHCPOptions{
+		layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeAck)}),
+		layers.NewDHCPOption(layers.DHCPOptLeaseTime, []byte{0, 0, 0x0e, 0x10}),
+		layers.NewDHCPOption(layers.DHCPOptRouter, []byte{192, 168, 1, 1}),
+		layers.NewDHCPOption(layers.DHCPOptDNS, []byte{1, 1, 1, 1, 8, 8, 8, 8}),
+		layers.NewDHCPOption(layers.DHCPOptEnd, nil),
+	})
+
+	info = ParseDHCPOptions(dhcp)
+	if info.Type != layers.DHCPMsgTypeAck || info.LeaseTime != 3600 || !info.Router.Equal(net.ParseIP("192.168.1.1")) {
+		t.Fatalf("unexpected info %+v", info)
+	} else if len(info.DNS) != 2 || !info.DNS[1].Equal(net.ParseIP("8.8.8.8")) {
+		t.Fatalf("unexpected dns servers %v", info.DNS)
+	} else if info.OS() != "" {
+		t.Fatalf("unexpected os %s", info.OS())
+	}
+}
+
+func TestDHCPInfoOS(t *testing.T) {
+	for _, c := range []struct {
+		vendor      string
+		fingerprint string
+		os          string
+	}{
+		{"android-dhcp-13", "1,3,6,15,26,28,51,58,59,43,114", "android"},
+		{"udhcp 1.30.1", "", "linux"},
+		{"", "1,121,3,6,15,119,252,95,44,46", "macos"},
+		{"", "1,121,3,6,15,119,252", "ios"},
+		{"", "1,3,6", ""},
+	} {
+		info := DHCPInfo{VendorClass: c.vendor, Fingerprint: c.fingerprint}
+		if got := info.OS(); got != c.os {
+			t.Fatalf("expected '%s' for %+v, got '%s'", c.os, c, got)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package packets
+
+import (
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// generic device capabilities, converted to the LLDP and CDP specific bits
+const (
+	DiscoveryCapRouter uint32 = 1 << iota
+	DiscoveryCapSwitch
+	DiscoveryCapPhone
+	DiscoveryCapHost
+	DiscoveryCapAccessPoint
+)
+
+// DiscoveryIdentity describes the device announced with LLDP and CDP frames.
+type DiscoveryIdentity struct {
+	Name         string
+	Description  string
+	Platform     string
+	Version      string
+	Port         string
+	Capabilities uint32
+	HW           net.HardwareAddr
+	Address      net.IP
+	TTL          uint16
+	// if true request the voice VLAN to the switch as an IP phone would do
+	VoiceQuery bool
+	// if not zero advertise these VLANs as a switch would do
+	NativeVLAN uint16
+	VoiceVLAN  uint16
+}
+
+func (id *DiscoveryIdentity) Has(cap uint32) bool {
+	return id.Capabilities&cap != 0
+}
+
+var discoveryCapNames = []struct {
+	cap  uint32
+	name string
+}{
+	{DiscoveryCapRouter, "router"},
+	{DiscoveryCapSwitch, "switch"},
+	{DiscoveryCapPhone, "phone"},
+	{DiscoveryCapHost, "host"},
+	{DiscoveryCapAccessPoint, "ap"},
+}
+
+// CapabilityNames returns the names of the capabilities of the device.
+func (id *DiscoveryIdentity) CapabilityNames() []string {
+	names := []string{}
+	for _, c := range discoveryCapNames {
+		if id.Has(c.cap) {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// DiscoveryNeighbor is a device learned from its LLDP, CDP or EDP frames.
+type DiscoveryNeighbor struct {
+	DiscoveryIdentity
+	Protocol string
+	// chassis identifier, when it differs from the name
+	Chassis string
+	// VLAN names by id, as announced on the port
+	VLANs map[uint16]string
+}
+
+func lldpNeighborCapabilities(caps layers.LLDPCapabilities) uint32 {
+	n := uint32(0)
+	if caps.Router {
+		n |= DiscoveryCapRouter
+	}
+	if caps.Bridge {
+		n |= DiscoveryCapSwitch
+	}
+	if caps.Phone {
+		n |= DiscoveryCapPhone
+	}
+	if caps.StationOnly {
+		n |= DiscoveryCapHost
+	}
+	if caps.WLANAP {
+		n |= DiscoveryCapAccessPoint
+	}
+	return n
+}
+
+func cdpNeighborCapabilities(caps layers.CDPCapabilities) uint32 {
+	n := uint32(0)
+	if caps.L3Router {
+		n |= DiscoveryCapRouter
+	}
+	if caps.L2Switch || caps.SPBridge {
+		n |= DiscoveryCapSwitch
+	}
+	if caps.IsPhone {
+		n |= DiscoveryCapPhone
+	}
+	if caps.IsHost {
+		n |= DiscoveryCapHost
+	}
+	// what access points announce
+	if caps.TBBridge {
+		n |= DiscoveryCapAccessPoint
+	}
+	return n
+}
+
+// mac addresses and network addresses are binary, anything else is text
+func lldpID(isMAC bool, isAddress bool, id []byte) string {
+	if isMAC && len(id) == 6 {
+		return net.HardwareAddr(id).String()
+	} else if isAddress && (len(id) == 1+net.IPv4len || len(id) == 1+net.IPv6len) {
+		// prefixed by the address family
+		return net.IP(id[1:]).String()
+	}
+	return string(id)
+}
+
+// LLDPNeighbor converts the decoded LLDP layers to a neighbor.
+func LLDPNeighbor(lldp *layers.LinkLayerDiscovery, info *layers.LinkLayerDiscoveryInfo) *DiscoveryNeighbor {
+	n := &DiscoveryNeighbor{
+		Protocol: "lldp",
+		Chassis: lldpID(
+			lldp.ChassisID.Subtype == layers.LLDPChassisIDSubTypeMACAddr,
+			lldp.ChassisID.Subtype == layers.LLDPChassisIDSubTypeNetworkAddr,
+			lldp.ChassisID.ID),
+		VLANs: make(map[uint16]string),
+	}
+	n.TTL = lldp.TTL
+	n.Port = lldpID(
+		lldp.PortID.Subtype == layers.LLDPPortIDSubtypeMACAddr,
+		lldp.PortID.Subtype == layers.LLDPPortIDSubtypeNetworkAddr,
+		lldp.PortID.ID)
+
+	if info != nil {
+		n.Name = info.SysName
+		n.Description = info.SysDescription
+		n.Capabilities = lldpNeighborCapabilities(info.SysCapabilities.EnabledCap)
+		if n.Capabilities == 0 {
+			n.Capabilities = lldpNeighborCapabilities(info.SysCapabilities.SystemCap)
+		}
+		if info.PortDescription != "" && info.PortDescription != n.Port {
+			n.Port += " (" + info.PortDescription + ")"
+		}
+
+		switch addr := info.MgmtAddress; addr.Subtype {
+		case layers.IANAAddressFamilyIPV4, layers.IANAAddressFamilyIPV6:
+			if len(addr.Address) == net.IPv4len || len(addr.Address) == net.IPv6len {
+				n.Address = net.IP(addr.Address)
+			}
+		}
+
+		if dot1, err := info.Decode8021(); err == nil {
+			n.NativeVLAN = dot1.PVID
+			for _, v := range dot1.VLANNames {
+				n.VLANs[v.ID] = v.Name
+			}
+		}
+		n.VoiceVLAN, _ = LLDPVoiceVLAN(info)
+	}
+
+	if n.Name == "" {
+		n.Name = n.Chassis
+	}
+	if n.Chassis == n.Name {
+		n.Chassis = ""
+	}
+
+	return n
+}
+
+// CDPNeighbor converts the decoded CDP layers to a neighbor.
+func CDPNeighbor(cdp *layers.CiscoDiscovery, info *layers.CiscoDiscoveryInfo) *DiscoveryNeighbor {
+	n := &DiscoveryNeighbor{
+		Protocol: "cdp",
+		VLANs:    make(map[uint16]string),
+	}
+	if cdp != nil {
+		n.TTL = uint16(cdp.TTL)
+	}
+
+	n.Name = info.DeviceID
+	n.Description = info.SysName
+	n.Platform = info.Platform
+	n.Version = info.Version
+	n.Port = info.PortID
+	n.Capabilities = cdpNeighborCapabilities(info.Capabilities)
+	n.NativeVLAN = info.NativeVLAN
+	n.VoiceVLAN, _ = CDPVoiceVLAN(info)
+	if len(info.MgmtAddresses) > 0 {
+		n.Address = info.MgmtAddresses[0]
+	} else if len(info.Addresses) > 0 {
+		n.Address = info.Addresses[0]
+	}
+
+	return n
+}
+
+// ParseDiscovery returns the neighbor announced by an LLDP, CDP or EDP
+// frame, or nil if the packet is none of them.
+func ParseDiscovery(pkt gopacket.Packet) *DiscoveryNeighbor {
+	var n *DiscoveryNeighbor
+
+	if lldp, ok := pkt.Layer(layers.LayerTypeLinkLayerDiscovery).(*layers.LinkLayerDiscovery); ok {
+		info, _ := pkt.Layer(layers.LayerTypeLinkLayerDiscoveryInfo).(*layers.LinkLayerDiscoveryInfo)
+		n = LLDPNeighbor(lldp, info)
+	} else if info, ok := pkt.Layer(layers.LayerTypeCiscoDiscoveryInfo).(*layers.CiscoDiscoveryInfo); ok {
+		cdp, _ := pkt.Layer(layers.LayerTypeCiscoDiscovery).(*layers.CiscoDiscovery)
+		n = CDPNeighbor(cdp, info)
+	} else if snap, ok := pkt.Layer(layers.LayerTypeSNAP).(*layers.SNAP); ok && IsEDP(snap) {
+		n, _ = ParseEDP(snap.Payload)
+	}
+
+	// the address of the port the frame was sent from is the one the
+	// neighbor is known with on the LAN
+	if n != nil {
+		if eth, ok := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+			n.HW = eth.SrcMAC
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,174 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func testDiscoveryIdentity() *DiscoveryIdentity {
+	hw, _ := net.ParseMAC("00:11:22:33:44:55")
+	return &DiscoveryIdentity{
+		Name:         "SEP001122334455",
+		Description:  "Cisco IP Phone 7975",
+		Platform:     "Cisco IP Phone 7975",
+		Version:      "SCCP75.9-3-1SR2-1S",
+		Port:         "Port 1",
+		Capabilities: DiscoveryCapHost | DiscoveryCapPhone,
+		HW:           hw,
+		Address:      net.ParseIP("10.0.0.42"),
+		TTL:          180,
+		VoiceQuery:   true,
+	}
+}
+
+func TestLLDPAnnounce(t *testing.T) {
+	id := testDiscoveryIdentity()
+	err, raw := NewLLDPAnnouncePacket(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	layer := pkt.Layer(layers.LayerTypeLinkLayerDiscoveryInfo)
+	if layer == nil {
+		t.Fatalf("could not decode lldp info: %v", pkt.ErrorLayer())
+	}
+
+	info := layer.(*layers.LinkLayerDiscoveryInfo)
+	if info.SysName != id.Name {
+		t.Fatalf("expected system name '%s', got '%s'", id.Name, info.SysName)
+	} else if info.SysDescription != id.Description {
+		t.Fatalf("expected system description '%s', got '%s'", id.Description, info.SysDescription)
+	} else if !info.SysCapabilities.SystemCap.Phone || !info.SysCapabilities.SystemCap.StationOnly {
+		t.Fatalf("unexpected capabilities %+v", info.SysCapabilities.SystemCap)
+	}
+
+	media, err := info.DecodeMedia()
+	if err != nil {
+		t.Fatalf("unexpected error decoding lldp-med: %v", err)
+	} else if media.NetworkPolicy.Defined {
+		t.Fatal("expected an unknown voice policy")
+	} else if _, found := LLDPVoiceVLAN(info); found {
+		t.Fatal("expected no voice vlan")
+	}
+
+	id.VoiceQuery = false
+	id.VoiceVLAN = 120
+	id.Capabilities = DiscoveryCapSwitch
+	if err, raw = NewLLDPAnnouncePacket(id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt = gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	info = pkt.Layer(layers.LayerTypeLinkLayerDiscoveryInfo).(*layers.LinkLayerDiscoveryInfo)
+	if vlan, found := LLDPVoiceVLAN(info); !found || vlan != 120 {
+		t.Fatalf("expected voice vlan 120, got %d", vlan)
+	}
+}
+
+func TestCDPAnnounce(t *testing.T) {
+	id := testDiscoveryIdentity()
+	err, raw := NewCDPAnnouncePacket(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	layer := pkt.Layer(layers.LayerTypeCiscoDiscoveryInfo)
+	if layer == nil {
+		t.Fatalf("could not decode cdp info: %v", pkt.ErrorLayer())
+	}
+
+	info := layer.(*layers.CiscoDiscoveryInfo)
+	if info.DeviceID != id.Name {
+		t.Fatalf("expected device id '%s', got '%s'", id.Name, info.DeviceID)
+	} else if info.Platform != id.Platform {
+		t.Fatalf("expected platform '%s', got '%s'", id.Platform, info.Platform)
+	} else if info.PortID != id.Port {
+		t.Fatalf("expected port '%s', got '%s'", id.Port, info.PortID)
+	} else if !info.Capabilities.IsPhone || !info.Capabilities.IsHost {
+		t.Fatalf("unexpected capabilities %+v", info.Capabilities)
+	} else if info.VLANQuery.ID != cdpVoiceQueryID {
+		t.Fatalf("expected a voice vlan query, got %+v", info.VLANQuery)
+	} else if len(info.Addresses) != 1 || !info.Addresses[0].Equal(id.Address) {
+		t.Fatalf("unexpected addresses %v", info.Addresses)
+	}
+
+	cdp := pkt.Layer(layers.LayerTypeCiscoDiscovery).(*layers.CiscoDiscovery)
+	if cdp.TTL != byte(id.TTL) {
+		t.Fatalf("expected ttl %d, got %d", id.TTL, cdp.TTL)
+	}
+
+	payload := (&CDPAnnounce{Identity: id}).Payload()
+	checksum := cdp.Checksum
+	payload[2], payload[3] = 0, 0
+	if expected := CDPChecksum(payload); checksum != expected {
+		t.Fatalf("expected checksum %x, got %x", expected, checksum)
+	}
+}
+
+func TestCDPChecksumOddLength(t *testing.T) {
+	// the last byte of odd sized packets is handled like cisco does
+	if a, b := CDPChecksum([]byte{0x02, 0xb4, 0x7f}), CDPChecksum([]byte{0x02, 0xb4, 0x00, 0x7f}); a != b {
+		t.Fatalf("expected %x, got %x", b, a)
+	} else if a, b := CDPChecksum([]byte{0x02, 0xb4, 0x81}), CDPChecksum([]byte{0x02, 0xb4, 0xff, 0x80}); a != b {
+		t.Fatalf("expected %x, got %x", b, a)
+	}
+}
+
+func TestLLDPNeighbor(t *testing.T) {
+	id := testDiscoveryIdentity()
+	id.Capabilities = DiscoveryCapSwitch | DiscoveryCapRouter
+	id.VoiceQuery = false
+	id.NativeVLAN = 10
+	id.VoiceVLAN = 120
+	_, raw := NewLLDPAnnouncePacket(id)
+
+	n := ParseDiscovery(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default))
+	if n == nil {
+		t.Fatal("expected a neighbor")
+	} else if n.Protocol != "lldp" || n.Name != id.Name || n.Description != id.Description {
+		t.Fatalf("unexpected neighbor %+v", n)
+	} else if n.Chassis != id.HW.String() || n.HW.String() != id.HW.String() || n.Port != id.Port {
+		t.Fatalf("unexpected chassis %s, port %s or mac %s", n.Chassis, n.Port, n.HW)
+	} else if !n.Address.Equal(id.Address) || n.TTL != id.TTL {
+		t.Fatalf("unexpected address %s or ttl %d", n.Address, n.TTL)
+	} else if n.NativeVLAN != 10 || n.VoiceVLAN != 120 {
+		t.Fatalf("unexpected vlans %d and %d", n.NativeVLAN, n.VoiceVLAN)
+	} else if caps := n.CapabilityNames(); len(caps) != 2 || caps[0] != "router" || caps[1] != "switch" {
+		t.Fatalf("unexpected capabilities %v", caps)
+	}
+}
+
+func TestCDPNeighbor(t *testing.T) {
+	id := testDiscoveryIdentity()
+	id.Capabilities = DiscoveryCapSwitch
+	id.VoiceQuery = false
+	id.NativeVLAN = 10
+	id.VoiceVLAN = 120
+	_, raw := NewCDPAnnouncePacket(id)
+
+	n := ParseDiscovery(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default))
+	if n == nil {
+		t.Fatal("expected a neighbor")
+	} else if n.Protocol != "cdp" || n.Name != id.Name || n.Platform != id.Platform || n.Version != id.Version {
+		t.Fatalf("unexpected neighbor %+v", n)
+	} else if n.Port != id.Port || n.HW.String() != id.HW.String() || !n.Address.Equal(id.Address) {
+		t.Fatalf("unexpected port %s, mac %s or address %s", n.Port, n.HW, n.Address)
+	} else if n.NativeVLAN != 10 || n.VoiceVLAN != 120 || n.TTL != id.TTL {
+		t.Fatalf("unexpected vlans %d and %d or ttl %d", n.NativeVLAN, n.VoiceVLAN, n.TTL)
+	} else if caps := n.CapabilityNames(); len(caps) != 1 || caps[0] != "switch" {
+		t.Fatalf("unexpected capabilities %v", caps)
+	}
+}
+
+func TestParseDiscoveryOther(t *testing.T) {
+	hw, _ := net.ParseMAC("00:11:22:33:44:55")
+	_, raw := NewUDPProbe(net.ParseIP("10.0.0.42"), hw, net.ParseIP("10.0.0.1"), 53)
+	if n := ParseDiscovery(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)); n != nil {
+		t.Fatalf("unexpected neighbor %+v", n)
+	}
+}
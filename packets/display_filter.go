@@ -0,0 +1,751 @@
+package packets
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// DisplayFilter is a wireshark-like filter expression such as:
+//
+//	http.request && ip.dst == 10.0.0.5
+//
+// compiled to a BPF expression that can be set on the capture handle, plus
+// a packet predicate for the conditions BPF alone can't express.
+type DisplayFilter struct {
+	Expression string
+	BPF        string
+
+	root *dfNode
+}
+
+// a compiled sub expression: bpf is a necessary condition for the packet to
+// match ("" means any packet), if exact is true it's also a sufficient one
+type dfNode struct {
+	bpf   string
+	exact bool
+	match func(pkt gopacket.Packet) bool
+}
+
+func CompileDisplayFilter(expr string) (*DisplayFilter, error) {
+	tokens, err := dfLex(expr)
+	if err != nil {
+		return nil, err
+	} else if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+
+	p := &dfParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	} else if !p.done() {
+		return nil, fmt.Errorf("unexpected '%s'", p.peek())
+	}
+
+	return &DisplayFilter{
+		Expression: expr,
+		BPF:        root.bpf,
+		root:       root,
+	}, nil
+}
+
+// NeedsPredicate returns true if the BPF expression alone is not enough
+// to select the packets matching the filter.
+func (f *DisplayFilter) NeedsPredicate() bool {
+	return !f.root.exact
+}
+
+// Match evaluates the filter on a packet that already passed the BPF expression.
+func (f *DisplayFilter) Match(pkt gopacket.Packet) bool {
+	// the BPF expression is extended to tagged frames, where its negations
+	// are not reliable
+	if f.root.exact && pkt.Layer(layers.LayerTypeDot1Q) == nil {
+		return true
+	}
+	return f.root.match(pkt)
+}
+
+func dfAnd(a, b *dfNode) *dfNode {
+	bpf := ""
+	if a.bpf == "" {
+		bpf = b.bpf
+	} else if b.bpf == "" {
+		bpf = a.bpf
+	} else {
+		bpf = fmt.Sprintf("(%s) and (%s)", a.bpf, b.bpf)
+	}
+
+	return &dfNode{
+		bpf:   bpf,
+		exact: a.exact && b.exact,
+		match: func(pkt gopacket.Packet) bool {
+			return a.match(pkt) && b.match(pkt)
+		},
+	}
+}
+
+func dfOr(a, b *dfNode) *dfNode {
+	bpf := ""
+	if a.bpf != "" && b.bpf != "" {
+		bpf = fmt.Sprintf("(%s) or (%s)", a.bpf, b.bpf)
+	}
+
+	return &dfNode{
+		bpf:   bpf,
+		exact: a.exact && b.exact,
+		match: func(pkt gopacket.Packet) bool {
+			return a.match(pkt) || b.match(pkt)
+		},
+	}
+}
+
+func dfNot(a *dfNode) *dfNode {
+	bpf := ""
+	// the negation of a necessary condition is not a necessary condition
+	if a.exact && a.bpf != "" {
+		bpf = fmt.Sprintf("not (%s)", a.bpf)
+	}
+
+	return &dfNode{
+		bpf:   bpf,
+		exact: a.exact && a.bpf != "",
+		match: func(pkt gopacket.Packet) bool {
+			return !a.match(pkt)
+		},
+	}
+}
+
+const dfOperators = "=!<>&|()"
+
+func dfLex(expr string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := strings.IndexByte(expr[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, expr[i:i+end+2])
+			i += end + 2
+		case strings.IndexByte(dfOperators, c) != -1:
+			if i+1 < len(expr) {
+				if op := expr[i : i+2]; op == "==" || op == "!=" || op == ">=" || op == "<=" || op == "&&" || op == "||" {
+					tokens = append(tokens, op)
+					i += 2
+					continue
+				}
+			}
+			if c == '&' || c == '|' || c == '=' {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", c, i)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			start := i
+			for i < len(expr) && expr[i] != ' ' && expr[i] != '\t' && expr[i] != '"' && strings.IndexByte(dfOperators, expr[i]) == -1 {
+				i++
+			}
+			tokens = append(tokens, expr[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+type dfParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *dfParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *dfParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *dfParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *dfParser) parseOr() (*dfNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for tok := p.peek(); tok == "||" || tok == "or"; tok = p.peek() {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = dfOr(left, right)
+	}
+
+	return left, nil
+}
+
+func (p *dfParser) parseAnd() (*dfNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for tok := p.peek(); tok == "&&" || tok == "and"; tok = p.peek() {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = dfAnd(left, right)
+	}
+
+	return left, nil
+}
+
+func (p *dfParser) parseNot() (*dfNode, error) {
+	if tok := p.peek(); tok == "!" || tok == "not" {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return dfNot(node), nil
+	}
+	return p.parsePrimary()
+}
+
+var dfRelations = map[string]string{
+	"==":       "==",
+	"eq":       "==",
+	"!=":       "!=",
+	"ne":       "!=",
+	">":        ">",
+	"gt":       ">",
+	"<":        "<",
+	"lt":       "<",
+	">=":       ">=",
+	"ge":       ">=",
+	"<=":       "<=",
+	"le":       "<=",
+	"contains": "contains",
+}
+
+func (p *dfParser) parsePrimary() (*dfNode, error) {
+	tok := p.next()
+	if tok == "" {
+		return nil, fmt.Errorf("unexpected end of filter")
+	} else if tok == "(" {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		} else if p.next() != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return node, nil
+	}
+
+	field := strings.ToLower(tok)
+	if op, found := dfRelations[strings.ToLower(p.peek())]; found {
+		p.next()
+		value := p.next()
+		if value == "" {
+			return nil, fmt.Errorf("missing value for %s %s", field, op)
+		}
+		return dfCompare(field, op, strings.Trim(value, "\""))
+	}
+
+	return dfProtocol(field)
+}
+
+// helpers to access packet fields
+
+func dfIPs(pkt gopacket.Packet) (net.IP, net.IP) {
+	if l := pkt.Layer(layers.LayerTypeIPv4); l != nil {
+		ip := l.(*layers.IPv4)
+		return ip.SrcIP, ip.DstIP
+	} else if l := pkt.Layer(layers.LayerTypeIPv6); l != nil {
+		ip := l.(*layers.IPv6)
+		return ip.SrcIP, ip.DstIP
+	}
+	return nil, nil
+}
+
+func dfPorts(pkt gopacket.Packet, proto string) (int, int, bool) {
+	if proto == "tcp" {
+		if l := pkt.Layer(layers.LayerTypeTCP); l != nil {
+			tcp := l.(*layers.TCP)
+			return int(tcp.SrcPort), int(tcp.DstPort), true
+		}
+	} else if l := pkt.Layer(layers.LayerTypeUDP); l != nil {
+		udp := l.(*layers.UDP)
+		return int(udp.SrcPort), int(udp.DstPort), true
+	}
+	return 0, 0, false
+}
+
+func dfMACs(pkt gopacket.Packet) (net.HardwareAddr, net.HardwareAddr) {
+	if l := pkt.Layer(layers.LayerTypeEthernet); l != nil {
+		eth := l.(*layers.Ethernet)
+		return eth.SrcMAC, eth.DstMAC
+	}
+	return nil, nil
+}
+
+func dfTCPPayload(pkt gopacket.Packet) []byte {
+	if l := pkt.Layer(layers.LayerTypeTCP); l != nil {
+		return l.(*layers.TCP).Payload
+	}
+	return nil
+}
+
+var dfHTTPMethods = []string{"GET ", "POST ", "HEAD ", "PUT ", "DELETE ", "OPTIONS ", "PATCH ", "CONNECT ", "TRACE "}
+
+func dfIsHTTPRequest(pkt gopacket.Packet) bool {
+	payload := dfTCPPayload(pkt)
+	for _, method := range dfHTTPMethods {
+		if bytes.HasPrefix(payload, []byte(method)) {
+			return true
+		}
+	}
+	return false
+}
+
+func dfIsHTTPResponse(pkt gopacket.Packet) bool {
+	return bytes.HasPrefix(dfTCPPayload(pkt), []byte("HTTP/1."))
+}
+
+func dfHTTPHeader(pkt gopacket.Packet, name string) string {
+	if !dfIsHTTPRequest(pkt) && !dfIsHTTPResponse(pkt) {
+		return ""
+	}
+
+	head := string(dfTCPPayload(pkt))
+	if end := strings.Index(head, "\r\n\r\n"); end != -1 {
+		head = head[:end]
+	}
+
+	for _, line := range strings.Split(head, "\r\n")[1:] {
+		if parts := strings.SplitN(line, ":", 2); len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), name) {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+func dfHTTPMethod(pkt gopacket.Packet) string {
+	if !dfIsHTTPRequest(pkt) {
+		return ""
+	}
+	payload := dfTCPPayload(pkt)
+	return string(payload[:bytes.IndexByte(payload, ' ')])
+}
+
+func dfDNSNames(pkt gopacket.Packet) []string {
+	names := []string{}
+	if l := pkt.Layer(layers.LayerTypeDNS); l != nil {
+		for _, q := range l.(*layers.DNS).Questions {
+			names = append(names, string(q.Name))
+		}
+	}
+	return names
+}
+
+func dfHasLayer(t gopacket.LayerType) func(gopacket.Packet) bool {
+	return func(pkt gopacket.Packet) bool {
+		return pkt.Layer(t) != nil
+	}
+}
+
+func dfProtocol(name string) (*dfNode, error) {
+	switch name {
+	case "eth", "frame":
+		return &dfNode{bpf: "", exact: true, match: func(gopacket.Packet) bool { return true }}, nil
+	case "arp":
+		return &dfNode{bpf: "arp", exact: true, match: dfHasLayer(layers.LayerTypeARP)}, nil
+	case "ip":
+		return &dfNode{bpf: "ip", exact: true, match: dfHasLayer(layers.LayerTypeIPv4)}, nil
+	case "ip6", "ipv6":
+		return &dfNode{bpf: "ip6", exact: true, match: dfHasLayer(layers.LayerTypeIPv6)}, nil
+	case "tcp":
+		return &dfNode{bpf: "tcp", exact: true, match: dfHasLayer(layers.LayerTypeTCP)}, nil
+	case "udp":
+		return &dfNode{bpf: "udp", exact: true, match: dfHasLayer(layers.LayerTypeUDP)}, nil
+	case "icmp":
+		return &dfNode{bpf: "icmp", exact: true, match: dfHasLayer(layers.LayerTypeICMPv4)}, nil
+	case "icmp6", "icmpv6":
+		return &dfNode{bpf: "icmp6", exact: true, match: dfHasLayer(layers.LayerTypeICMPv6)}, nil
+	case "vlan":
+		// BPF vlan primitives shift the offsets of the ones following them
+		return &dfNode{bpf: "", exact: false, match: dfHasLayer(layers.LayerTypeDot1Q)}, nil
+	case "dns":
+		return &dfNode{bpf: "port 53", exact: false, match: dfHasLayer(layers.LayerTypeDNS)}, nil
+	case "http":
+		return &dfNode{bpf: "tcp", exact: false, match: func(pkt gopacket.Packet) bool {
+			return dfIsHTTPRequest(pkt) || dfIsHTTPResponse(pkt)
+		}}, nil
+	case "http.request":
+		return &dfNode{bpf: "tcp", exact: false, match: dfIsHTTPRequest}, nil
+	case "http.response":
+		return &dfNode{bpf: "tcp", exact: false, match: dfIsHTTPResponse}, nil
+	case "tls":
+		return &dfNode{bpf: "tcp", exact: false, match: func(pkt gopacket.Packet) bool {
+			// record type (change_cipher_spec ... application_data) followed by the major version
+			payload := dfTCPPayload(pkt)
+			return len(payload) > 5 && payload[0] >= 20 && payload[0] <= 23 && payload[1] == 0x03
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown protocol or field '%s'", name)
+}
+
+func dfCompareInt(a int, op string, b int) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case "<":
+		return a < b
+	case ">=":
+		return a >= b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+func dfCompareString(a string, op string, b string) bool {
+	switch op {
+	case "==":
+		return strings.EqualFold(a, b)
+	case "!=":
+		return !strings.EqualFold(a, b)
+	case "contains":
+		return strings.Contains(strings.ToLower(a), strings.ToLower(b))
+	}
+	return false
+}
+
+// returns the BPF relational operator for op
+func dfBPFRelation(op string) string {
+	if op == "==" {
+		return "="
+	}
+	return op
+}
+
+// negate a node if op is "!=", the comparison is then done with "=="
+func dfNegateIf(op string, node *dfNode) *dfNode {
+	if op == "!=" {
+		return dfNot(node)
+	}
+	return node
+}
+
+func dfCompare(field string, op string, value string) (*dfNode, error) {
+	switch field {
+	case "ip.addr", "ip.src", "ip.dst":
+		return dfCompareIP(field, op, value)
+	case "eth.addr", "eth.src", "eth.dst":
+		return dfCompareMAC(field, op, value)
+	case "tcp.port", "tcp.srcport", "tcp.dstport", "udp.port", "udp.srcport", "udp.dstport":
+		return dfComparePort(field, op, value)
+	case "vlan.id":
+		return dfCompareVLAN(field, op, value)
+	case "frame.len", "len", "ip.ttl", "ip.proto":
+		return dfCompareNumber(field, op, value)
+	case "http.host", "http.method", "http.user_agent", "dns.qry.name":
+		return dfCompareText(field, op, value)
+	}
+
+	return nil, fmt.Errorf("unknown field '%s'", field)
+}
+
+func dfCompareIP(field string, op string, value string) (*dfNode, error) {
+	if op != "==" && op != "!=" {
+		return nil, fmt.Errorf("operator %s not supported for %s", op, field)
+	}
+
+	var network *net.IPNet
+	if strings.Contains(value, "/") {
+		var err error
+		if _, network, err = net.ParseCIDR(value); err != nil {
+			return nil, fmt.Errorf("invalid network '%s' for %s", value, field)
+		}
+	} else if ip := net.ParseIP(value); ip == nil {
+		return nil, fmt.Errorf("invalid address '%s' for %s", value, field)
+	} else {
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+
+	direction, primitive := "", "host"
+	if field == "ip.src" {
+		direction = "src "
+	} else if field == "ip.dst" {
+		direction = "dst "
+	}
+	if ones, bits := network.Mask.Size(); ones != bits {
+		primitive = "net"
+	}
+
+	node := &dfNode{
+		bpf:   fmt.Sprintf("%s%s %s", direction, primitive, value),
+		exact: true,
+		match: func(pkt gopacket.Packet) bool {
+			src, dst := dfIPs(pkt)
+			if src == nil {
+				return false
+			} else if field == "ip.src" {
+				return network.Contains(src)
+			} else if field == "ip.dst" {
+				return network.Contains(dst)
+			}
+			return network.Contains(src) || network.Contains(dst)
+		},
+	}
+
+	return dfNegateIf(op, node), nil
+}
+
+func dfCompareMAC(field string, op string, value string) (*dfNode, error) {
+	if op != "==" && op != "!=" {
+		return nil, fmt.Errorf("operator %s not supported for %s", op, field)
+	}
+
+	mac, err := net.ParseMAC(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mac address '%s' for %s", value, field)
+	}
+
+	direction := ""
+	if field == "eth.src" {
+		direction = "src "
+	} else if field == "eth.dst" {
+		direction = "dst "
+	}
+
+	node := &dfNode{
+		bpf:   fmt.Sprintf("ether %shost %s", direction, mac),
+		exact: true,
+		match: func(pkt gopacket.Packet) bool {
+			src, dst := dfMACs(pkt)
+			if src == nil {
+				return false
+			} else if field == "eth.src" {
+				return bytes.Equal(src, mac)
+			} else if field == "eth.dst" {
+				return bytes.Equal(dst, mac)
+			}
+			return bytes.Equal(src, mac) || bytes.Equal(dst, mac)
+		},
+	}
+
+	return dfNegateIf(op, node), nil
+}
+
+func dfComparePort(field string, op string, value string) (*dfNode, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port '%s' for %s", value, field)
+	} else if op == "contains" {
+		return nil, fmt.Errorf("operator %s not supported for %s", op, field)
+	}
+
+	parts := strings.SplitN(field, ".", 2)
+	proto, which := parts[0], parts[1]
+	// port == N can be expressed with the port primitive
+	positive := op
+	if op == "!=" {
+		positive = "=="
+	}
+
+	bpf := ""
+	if positive == "==" {
+		switch which {
+		case "srcport":
+			bpf = fmt.Sprintf("%s src port %d", proto, port)
+		case "dstport":
+			bpf = fmt.Sprintf("%s dst port %d", proto, port)
+		default:
+			bpf = fmt.Sprintf("%s port %d", proto, port)
+		}
+	} else {
+		rel := dfBPFRelation(positive)
+		switch which {
+		case "srcport":
+			bpf = fmt.Sprintf("%s and %s[0:2] %s %d", proto, proto, rel, port)
+		case "dstport":
+			bpf = fmt.Sprintf("%s and %s[2:2] %s %d", proto, proto, rel, port)
+		default:
+			bpf = fmt.Sprintf("%s and (%s[0:2] %s %d or %s[2:2] %s %d)", proto, proto, rel, port, proto, rel, port)
+		}
+	}
+
+	node := &dfNode{
+		bpf:   bpf,
+		exact: true,
+		match: func(pkt gopacket.Packet) bool {
+			src, dst, found := dfPorts(pkt, proto)
+			if !found {
+				return false
+			} else if which == "srcport" {
+				return dfCompareInt(src, positive, port)
+			} else if which == "dstport" {
+				return dfCompareInt(dst, positive, port)
+			}
+			return dfCompareInt(src, positive, port) || dfCompareInt(dst, positive, port)
+		},
+	}
+
+	if op == "!=" {
+		// tcp.port != 80 still requires the packet to be tcp
+		proto, _ := dfProtocol(proto)
+		return dfAnd(proto, dfNot(node)), nil
+	}
+
+	return node, nil
+}
+
+func dfCompareNumber(field string, op string, value string) (*dfNode, error) {
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number '%s' for %s", value, field)
+	} else if op == "contains" {
+		return nil, fmt.Errorf("operator %s not supported for %s", op, field)
+	}
+
+	rel := dfBPFRelation(op)
+
+	switch field {
+	case "ip.ttl":
+		return &dfNode{
+			bpf:   fmt.Sprintf("ip and ip[8] %s %d", rel, num),
+			exact: true,
+			match: func(pkt gopacket.Packet) bool {
+				if l := pkt.Layer(layers.LayerTypeIPv4); l != nil {
+					return dfCompareInt(int(l.(*layers.IPv4).TTL), op, num)
+				}
+				return false
+			},
+		}, nil
+	case "ip.proto":
+		return &dfNode{
+			bpf:   fmt.Sprintf("ip and ip[9] %s %d", rel, num),
+			exact: true,
+			match: func(pkt gopacket.Packet) bool {
+				if l := pkt.Layer(layers.LayerTypeIPv4); l != nil {
+					return dfCompareInt(int(l.(*layers.IPv4).Protocol), op, num)
+				}
+				return false
+			},
+		}, nil
+	}
+
+	return &dfNode{
+		bpf:   fmt.Sprintf("len %s %d", rel, num),
+		exact: true,
+		match: func(pkt gopacket.Packet) bool {
+			return dfCompareInt(pkt.Metadata().Length, op, num)
+		},
+	}, nil
+}
+
+// matched by any of the tags of QinQ frames, != by tagged frames only
+func dfCompareVLAN(field string, op string, value string) (*dfNode, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 || id > 4095 {
+		return nil, fmt.Errorf("invalid VLAN identifier '%s' for %s", value, field)
+	} else if op == "contains" {
+		return nil, fmt.Errorf("operator %s not supported for %s", op, field)
+	}
+
+	positive := op
+	if op == "!=" {
+		positive = "=="
+	}
+
+	return &dfNode{
+		bpf:   "",
+		exact: false,
+		match: func(pkt gopacket.Packet) bool {
+			ids := VLANIDs(pkt)
+			for _, tag := range ids {
+				if dfCompareInt(int(tag), positive, id) {
+					return op != "!="
+				}
+			}
+			return op == "!=" && len(ids) > 0
+		},
+	}, nil
+}
+
+func dfCompareText(field string, op string, value string) (*dfNode, error) {
+	if op != "==" && op != "!=" && op != "contains" {
+		return nil, fmt.Errorf("operator %s not supported for %s", op, field)
+	}
+
+	switch field {
+	case "dns.qry.name":
+		return &dfNode{
+			bpf:   "port 53",
+			exact: false,
+			match: func(pkt gopacket.Packet) bool {
+				for _, name := range dfDNSNames(pkt) {
+					if dfCompareString(name, op, value) {
+						return true
+					}
+				}
+				return false
+			},
+		}, nil
+	case "http.method":
+		return &dfNode{
+			bpf:   "tcp",
+			exact: false,
+			match: func(pkt gopacket.Packet) bool {
+				method := dfHTTPMethod(pkt)
+				return method != "" && dfCompareString(method, op, value)
+			},
+		}, nil
+	}
+
+	header := "Host"
+	if field == "http.user_agent" {
+		header = "User-Agent"
+	}
+
+	return &dfNode{
+		bpf:   "tcp",
+		exact: false,
+		match: func(pkt gopacket.Packet) bool {
+			found := dfHTTPHeader(pkt, header)
+			return found != "" && dfCompareString(found, op, value)
+		},
+	}, nil
+}
@@ -0,0 +1,175 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func buildTestTCPPacket(t *testing.T, src, dst string, srcPort, dstPort int, payload []byte) gopacket.Packet {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0x01},
+		DstMAC:       net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0x02},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP(src),
+		DstIP:    net.ParseIP(dst),
+	}
+	tcp := layers.TCP{
+		SrcPort: layers.TCPPort(srcPort),
+		DstPort: layers.TCPPort(dstPort),
+		PSH:     true,
+		ACK:     true,
+	}
+	tcp.SetNetworkLayerForChecksum(&ip4)
+
+	err, raw := Serialize(&eth, &ip4, &tcp, gopacket.Payload(payload))
+	if err != nil {
+		t.Fatalf("error serializing packet: %v", err)
+	}
+
+	return gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+// a QinQ frame with service tag 100 and customer tag 20
+func buildTestQinQPacket(t *testing.T, src, dst string, dstPort int) gopacket.Packet {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0x01},
+		DstMAC:       net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0x02},
+		EthernetType: layers.EthernetTypeQinQ,
+	}
+	outer := layers.Dot1Q{VLANIdentifier: 100, Type: layers.EthernetTypeDot1Q}
+	inner := layers.Dot1Q{VLANIdentifier: 20, Type: layers.EthernetTypeIPv4}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP(src),
+		DstIP:    net.ParseIP(dst),
+	}
+	tcp := layers.TCP{
+		SrcPort: 51000,
+		DstPort: layers.TCPPort(dstPort),
+		SYN:     true,
+	}
+	tcp.SetNetworkLayerForChecksum(&ip4)
+
+	err, raw := Serialize(&eth, &outer, &inner, &ip4, &tcp)
+	if err != nil {
+		t.Fatalf("error serializing packet: %v", err)
+	}
+
+	return gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestDisplayFilterBPF(t *testing.T) {
+	cases := map[string]string{
+		"not arp":                            "not (arp)",
+		"tcp":                                "tcp",
+		"ip.dst == 10.0.0.5":                 "dst host 10.0.0.5",
+		"ip.addr == 10.0.0.0/24":             "net 10.0.0.0/24",
+		"tcp.port == 80 || udp":              "(tcp port 80) or (udp)",
+		"tcp.dstport >= 1024":                "tcp and tcp[2:2] >= 1024",
+		"eth.src == AA:BB:CC:DD:EE:FF":       "ether src host aa:bb:cc:dd:ee:ff",
+		"http.request && ip.dst == 10.0.0.5": "(tcp) and (dst host 10.0.0.5)",
+	}
+
+	for expr, expected := range cases {
+		f, err := CompileDisplayFilter(expr)
+		if err != nil {
+			t.Errorf("unexpected error compiling '%s': %v", expr, err)
+		} else if f.BPF != expected {
+			t.Errorf("expected '%s' to compile to '%s', got '%s'", expr, expected, f.BPF)
+		}
+	}
+}
+
+func TestDisplayFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"tcp port 80",
+		"host 10.0.0.1",
+		"ip.dst == nope",
+		"(tcp",
+		"tcp.port contains 80",
+		"http.host == \"unterminated",
+	} {
+		if _, err := CompileDisplayFilter(expr); err == nil {
+			t.Errorf("expected error compiling '%s'", expr)
+		}
+	}
+}
+
+func TestDisplayFilterPredicate(t *testing.T) {
+	req := buildTestTCPPacket(t, "10.0.0.2", "10.0.0.5", 51000, 80, []byte("GET / HTTP/1.1\r\nHost: www.example.com\r\n\r\n"))
+	res := buildTestTCPPacket(t, "10.0.0.5", "10.0.0.2", 80, 51000, []byte("HTTP/1.1 200 OK\r\n\r\n"))
+
+	f, err := CompileDisplayFilter("http.request && ip.dst == 10.0.0.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !f.NeedsPredicate() {
+		t.Fatal("expected filter to need a predicate")
+	} else if !f.Match(req) {
+		t.Fatal("expected request to match")
+	} else if f.Match(res) {
+		t.Fatal("expected response not to match")
+	}
+
+	if f, err = CompileDisplayFilter("http.host contains example && !http.response"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !f.Match(req) {
+		t.Fatal("expected request to match")
+	} else if f.Match(res) {
+		t.Fatal("expected response not to match")
+	}
+
+	if f, err = CompileDisplayFilter("tcp.port == 80 and ip.src == 10.0.0.2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if f.NeedsPredicate() {
+		t.Fatal("expected filter to be fully expressed in BPF")
+	} else if !f.root.match(req) || f.root.match(res) {
+		t.Fatal("unexpected evaluation of the BPF equivalent predicate")
+	}
+}
+
+func TestDisplayFilterVLAN(t *testing.T) {
+	tagged := buildTestQinQPacket(t, "10.0.0.2", "10.0.0.5", 80)
+	untagged := buildTestTCPPacket(t, "10.0.0.2", "10.0.0.5", 51000, 80, nil)
+
+	if ids := VLANIDs(tagged); len(ids) != 2 || ids[0] != 100 || ids[1] != 20 {
+		t.Fatalf("unexpected vlan ids %v", ids)
+	} else if ids := VLANIDs(untagged); len(ids) != 0 {
+		t.Fatalf("unexpected vlan ids %v", ids)
+	}
+
+	for expr, expected := range map[string][]bool{
+		"vlan":                            {true, false},
+		"!vlan":                           {false, true},
+		"vlan.id == 20":                   {true, false},
+		"vlan.id == 100":                  {true, false},
+		"vlan.id == 30":                   {false, false},
+		"vlan.id != 30":                   {true, false},
+		"vlan.id == 20 && tcp.port == 80": {true, false},
+		// exact in BPF, but the tagged frames still need the predicate
+		"!tcp": {false, false},
+	} {
+		f, err := CompileDisplayFilter(expr)
+		if err != nil {
+			t.Fatalf("unexpected error compiling '%s': %v", expr, err)
+		} else if got := f.Match(tagged); got != expected[0] {
+			t.Errorf("expected '%s' to match the tagged packet: %v, got %v", expr, expected[0], got)
+		} else if got := f.root.match(untagged); got != expected[1] {
+			t.Errorf("expected '%s' to match the untagged packet: %v, got %v", expr, expected[1], got)
+		}
+	}
+
+	if _, err := CompileDisplayFilter("vlan.id == 5000"); err == nil {
+		t.Fatal("expected error for an invalid vlan identifier")
+	}
+}
@@ -0,0 +1,229 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	DNP3Port = 20000
+
+	dnp3HeaderSize = 10
+	dnp3BlockSize  = 16
+	dnp3CRCSize    = 2
+	dnp3Start      = 0x0564
+	// transport header flags
+	dnp3First = 0x40
+)
+
+const (
+	DNP3Confirm             = 0x00
+	DNP3Read                = 0x01
+	DNP3Write               = 0x02
+	DNP3Select              = 0x03
+	DNP3Operate             = 0x04
+	DNP3DirectOperate       = 0x05
+	DNP3DirectOperateNoAck  = 0x06
+	DNP3ColdRestart         = 0x0d
+	DNP3WarmRestart         = 0x0e
+	DNP3StopApplication     = 0x12
+	DNP3EnableUnsolicited   = 0x14
+	DNP3DisableUnsolicited  = 0x15
+	DNP3Response            = 0x81
+	DNP3UnsolicitedResponse = 0x82
+)
+
+var DNP3FunctionNames = map[uint8]string{
+	DNP3Confirm:             "confirm",
+	DNP3Read:                "read",
+	DNP3Write:               "write",
+	DNP3Select:              "select",
+	DNP3Operate:             "operate",
+	DNP3DirectOperate:       "direct operate",
+	DNP3DirectOperateNoAck:  "direct operate no ack",
+	0x07:                    "immediate freeze",
+	0x08:                    "immediate freeze no ack",
+	0x09:                    "freeze clear",
+	0x0a:                    "freeze clear no ack",
+	DNP3ColdRestart:         "cold restart",
+	DNP3WarmRestart:         "warm restart",
+	0x0f:                    "initialize data",
+	0x10:                    "initialize application",
+	0x11:                    "start application",
+	DNP3StopApplication:     "stop application",
+	DNP3EnableUnsolicited:   "enable unsolicited",
+	DNP3DisableUnsolicited:  "disable unsolicited",
+	0x17:                    "delay measure",
+	0x18:                    "record current time",
+	DNP3Response:            "response",
+	DNP3UnsolicitedResponse: "unsolicited response",
+	0x83:                    "authentication response",
+}
+
+var ErrDNP3Invalid = errors.New("not a valid DNP3 frame")
+
+// DNP3Object is the header of an object in an application fragment, with
+// the range of indexes it refers to when the qualifier has one.
+type DNP3Object struct {
+	Group     uint8  `json:"group"`
+	Variation uint8  `json:"variation"`
+	Qualifier uint8  `json:"qualifier"`
+	Start     uint32 `json:"start"`
+	Stop      uint32 `json:"stop"`
+	Count     uint32 `json:"count"`
+}
+
+// DNP3Frame is a DNP3 link layer frame with its application header.
+type DNP3Frame struct {
+	Source      uint16 `json:"source"`
+	Destination uint16 `json:"destination"`
+	// false for the frames continuing a fragment, which have no application header
+	First    bool         `json:"first"`
+	Function uint8        `json:"function"`
+	IIN      uint16       `json:"iin"`
+	Objects  []DNP3Object `json:"objects"`
+}
+
+// CRC-16/DNP, reflected 0x3d65 polynomial
+func dnp3CRC(data []byte) uint16 {
+	crc := uint16(0)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa6bc
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
+
+func dnp3CheckCRC(block []byte) bool {
+	size := len(block) - dnp3CRCSize
+	return binary.LittleEndian.Uint16(block[size:]) == dnp3CRC(block[:size])
+}
+
+// FunctionName returns the name of the application function code.
+func (f *DNP3Frame) FunctionName() string {
+	if name, found := DNP3FunctionNames[f.Function]; found {
+		return name
+	}
+	return fmt.Sprintf("function 0x%02x", f.Function)
+}
+
+// IsResponse returns true for solicited and unsolicited responses.
+func (f *DNP3Frame) IsResponse() bool {
+	return f.Function >= DNP3Response
+}
+
+// IsControl returns true for the functions operating outputs or changing the
+// state of the outstation.
+func (f *DNP3Frame) IsControl() bool {
+	switch f.Function {
+	case DNP3Write, DNP3Select, DNP3Operate, DNP3DirectOperate, DNP3DirectOperateNoAck,
+		DNP3ColdRestart, DNP3WarmRestart, DNP3StopApplication:
+		return true
+	}
+	return false
+}
+
+// the object headers we can parse without knowing the size of each object,
+// all of them for reads and only the first one otherwise
+func (f *DNP3Frame) parseObjects(data []byte) {
+	for len(data) >= 3 {
+		obj := DNP3Object{Group: data[0], Variation: data[1], Qualifier: data[2]}
+		data = data[3:]
+
+		size := 0
+		switch obj.Qualifier & 0x0f {
+		case 0x00, 0x01:
+			size = 1 << (obj.Qualifier & 0x0f)
+			if len(data) < size*2 {
+				return
+			}
+			if size == 1 {
+				obj.Start, obj.Stop = uint32(data[0]), uint32(data[1])
+			} else {
+				obj.Start, obj.Stop = uint32(binary.LittleEndian.Uint16(data)), uint32(binary.LittleEndian.Uint16(data[2:]))
+			}
+			if obj.Stop >= obj.Start {
+				obj.Count = obj.Stop - obj.Start + 1
+			}
+			size *= 2
+		case 0x06:
+			// all the objects of the group
+		case 0x07, 0x08:
+			size = 1 << (obj.Qualifier&0x0f - 0x07)
+			if len(data) < size {
+				return
+			} else if size == 1 {
+				obj.Count = uint32(data[0])
+			} else {
+				obj.Count = uint32(binary.LittleEndian.Uint16(data))
+			}
+		default:
+			f.Objects = append(f.Objects, obj)
+			return
+		}
+
+		f.Objects = append(f.Objects, obj)
+		if f.Function != DNP3Read {
+			return
+		}
+		data = data[size:]
+	}
+}
+
+// ParseDNP3 parses the first DNP3 frame of a segment, checking the CRC of
+// the header and of each data block.
+func ParseDNP3(payload []byte) (*DNP3Frame, error) {
+	if len(payload) < dnp3HeaderSize || binary.BigEndian.Uint16(payload) != dnp3Start || payload[2] < 5 {
+		return nil, ErrDNP3Invalid
+	} else if !dnp3CheckCRC(payload[:dnp3HeaderSize]) {
+		return nil, ErrDNP3Invalid
+	}
+
+	frame := &DNP3Frame{
+		Destination: binary.LittleEndian.Uint16(payload[4:]),
+		Source:      binary.LittleEndian.Uint16(payload[6:]),
+	}
+
+	// user data without the CRC of each block
+	left := int(payload[2]) - 5
+	blocks := payload[dnp3HeaderSize:]
+	data := make([]byte, 0, left)
+	for left > 0 {
+		size := left
+		if size > dnp3BlockSize {
+			size = dnp3BlockSize
+		}
+		if len(blocks) < size+dnp3CRCSize || !dnp3CheckCRC(blocks[:size+dnp3CRCSize]) {
+			return nil, ErrDNP3Invalid
+		}
+		data = append(data, blocks[:size]...)
+		blocks = blocks[size+dnp3CRCSize:]
+		left -= size
+	}
+
+	// link layer only frames, or fragments after the first
+	if len(data) < 3 || data[0]&dnp3First == 0 {
+		return frame, nil
+	}
+
+	frame.First = true
+	frame.Function = data[2]
+	objects := data[3:]
+	if frame.IsResponse() {
+		if len(objects) < 2 {
+			return nil, ErrDNP3Invalid
+		}
+		frame.IIN = binary.BigEndian.Uint16(objects)
+		objects = objects[2:]
+	}
+	frame.parseObjects(objects)
+
+	return frame, nil
+}
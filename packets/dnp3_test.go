@@ -0,0 +1,92 @@
+package packets
+
+import (
+	"testing"
+)
+
+// builds a frame from its link header fields and user data, adding the CRCs
+func dnp3TestFrame(dst, src uint16, data []byte) []byte {
+	withCRC := func(block []byte) []byte {
+		crc := dnp3CRC(block)
+		return append(block, byte(crc), byte(crc>>8))
+	}
+
+	frame := withCRC([]byte{0x05, 0x64, byte(5 + len(data)), 0xc4, byte(dst), byte(dst >> 8), byte(src), byte(src >> 8)})
+	for len(data) > 0 {
+		size := len(data)
+		if size > dnp3BlockSize {
+			size = dnp3BlockSize
+		}
+		frame = append(frame, withCRC(append([]byte{}, data[:size]...))...)
+		data = data[size:]
+	}
+	return frame
+}
+
+func TestDNP3CRC(t *testing.T) {
+	if crc := dnp3CRC([]byte("123456789")); crc != 0xea82 {
+		t.Fatalf("unexpected crc 0x%04x", crc)
+	}
+}
+
+func TestParseDNP3Read(t *testing.T) {
+	// read class 1, 2, 3 and 0 data
+	data := []byte{0xc0, 0xc1, DNP3Read, 60, 2, 0x06, 60, 3, 0x06, 60, 4, 0x06, 60, 1, 0x06}
+	frame, err := ParseDNP3(dnp3TestFrame(10, 1, data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if frame.Source != 1 || frame.Destination != 10 || !frame.First {
+		t.Fatalf("unexpected frame %+v", frame)
+	} else if frame.FunctionName() != "read" || frame.IsControl() || frame.IsResponse() {
+		t.Fatalf("unexpected function %s", frame.FunctionName())
+	} else if len(frame.Objects) != 4 {
+		t.Fatalf("expected 4 objects, got %d", len(frame.Objects))
+	} else if obj := frame.Objects[3]; obj.Group != 60 || obj.Variation != 1 || obj.Qualifier != 0x06 {
+		t.Fatalf("unexpected object %+v", obj)
+	}
+}
+
+func TestParseDNP3Control(t *testing.T) {
+	// direct operate of the CROB at index 3
+	data := []byte{0xc0, 0xc2, DNP3DirectOperate, 12, 1, 0x28, 0x01, 0x00, 0x03, 0x00, 0x03, 0x01, 0xe8, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	frame, err := ParseDNP3(dnp3TestFrame(10, 1, data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !frame.IsControl() || frame.FunctionName() != "direct operate" {
+		t.Fatalf("unexpected frame %+v", frame)
+	} else if len(frame.Objects) != 1 || frame.Objects[0].Group != 12 || frame.Objects[0].Count != 1 {
+		t.Fatalf("unexpected objects %+v", frame.Objects)
+	}
+
+	// response with its internal indications and a range of binary inputs
+	data = []byte{0xc0, 0xc2, DNP3Response, 0x80, 0x00, 1, 2, 0x00, 0x00, 0x07, 0x81, 0x81, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01}
+	if frame, err = ParseDNP3(dnp3TestFrame(1, 10, data)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !frame.IsResponse() || frame.IIN != 0x8000 {
+		t.Fatalf("unexpected frame %+v", frame)
+	} else if obj := frame.Objects[0]; obj.Start != 0 || obj.Stop != 7 || obj.Count != 8 {
+		t.Fatalf("unexpected object %+v", obj)
+	}
+}
+
+func TestParseDNP3Invalid(t *testing.T) {
+	valid := dnp3TestFrame(10, 1, []byte{0xc0, 0xc1, DNP3Read, 60, 1, 0x06})
+
+	header := append([]byte{}, valid...)
+	header[4] ^= 0xff
+	block := append([]byte{}, valid...)
+	block[len(block)-3] ^= 0xff
+
+	for _, payload := range [][]byte{
+		valid[:8],
+		header,
+		block,
+		// truncated user data
+		valid[:len(valid)-4],
+		[]byte("GET / HTTP/1.1\r\n\r\n"),
+	} {
+		if _, err := ParseDNP3(payload); err != ErrDNP3Invalid {
+			t.Fatalf("expected %v for %x, got %v", ErrDNP3Invalid, payload, err)
+		}
+	}
+}
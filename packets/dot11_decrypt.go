@@ -0,0 +1,326 @@
+package packets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	dot11PMKSize     = 32
+	dot11PMKRounds   = 4096
+	dot11PTKSize     = 48
+	dot11CCMPHdrSize = 8
+	dot11CCMPMICSize = 8
+	dot11KDEType     = 0xdd
+	dot11GTKKDE      = 1
+)
+
+var (
+	ErrDot11NotCCMP     = errors.New("frame is not CCMP encrypted")
+	ErrDot11Short       = errors.New("encrypted frame too short")
+	ErrDot11MIC         = errors.New("MIC verification failed")
+	ErrDot11Unwrap      = errors.New("key data integrity check failed")
+	ErrDot11NoGTK       = errors.New("no GTK in key data")
+	ErrDot11NotSNAP     = errors.New("decrypted payload is not LLC/SNAP encapsulated")
+	dot11KeyWrapIV      = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+	dot11SNAPHeader     = []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00}
+	dot11KDEOUI         = []byte{0x00, 0x0f, 0xac}
+	dot11PairwiseLabel  = "Pairwise key expansion"
+	dot11BridgeTunnelID = []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0xf8}
+)
+
+// Dot11PTK is the pairwise transient key derived from a 4-way handshake,
+// only the CCMP one is supported.
+type Dot11PTK struct {
+	KCK []byte
+	KEK []byte
+	TK  []byte
+}
+
+// PBKDF2-HMAC-SHA1, as it's only used to derive the PMK there's no need
+// for an external dependency
+func dot11PBKDF2(password, salt []byte, rounds, size int) []byte {
+	prf := hmac.New(sha1.New, password)
+	blocks := (size + prf.Size() - 1) / prf.Size()
+	key := make([]byte, 0, blocks*prf.Size())
+	counter := make([]byte, 4)
+
+	for block := 1; block <= blocks; block++ {
+		binary.BigEndian.PutUint32(counter, uint32(block))
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for n := 1; n < rounds; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+		key = append(key, t...)
+	}
+
+	return key[:size]
+}
+
+// the PRF of 802.11i based on HMAC-SHA1
+func dot11PRF(key []byte, label string, data []byte, size int) []byte {
+	out := make([]byte, 0, size+sha1.Size)
+	for i := byte(0); len(out) < size; i++ {
+		h := hmac.New(sha1.New, key)
+		h.Write([]byte(label))
+		h.Write([]byte{0})
+		h.Write(data)
+		h.Write([]byte{i})
+		out = h.Sum(out)
+	}
+	return out[:size]
+}
+
+func dot11MinMax(a, b []byte) ([]byte, []byte) {
+	if bytes.Compare(a, b) < 0 {
+		return a, b
+	}
+	return b, a
+}
+
+// Dot11PMK derives the pairwise master key of a WPA/WPA2 personal network
+// from its passphrase and ESSID.
+func Dot11PMK(psk string, essid string) []byte {
+	return dot11PBKDF2([]byte(psk), []byte(essid), dot11PMKRounds, dot11PMKSize)
+}
+
+// Dot11DerivePTK derives the pairwise keys of a station from the PMK and
+// the nonces exchanged during the 4-way handshake.
+func Dot11DerivePTK(pmk []byte, apMac net.HardwareAddr, staMac net.HardwareAddr, anonce []byte, snonce []byte) *Dot11PTK {
+	minMac, maxMac := dot11MinMax(apMac, staMac)
+	minNonce, maxNonce := dot11MinMax(anonce, snonce)
+
+	data := make([]byte, 0, 12+len(anonce)+len(snonce))
+	data = append(data, minMac...)
+	data = append(data, maxMac...)
+	data = append(data, minNonce...)
+	data = append(data, maxNonce...)
+
+	ptk := dot11PRF(pmk, dot11PairwiseLabel, data, dot11PTKSize)
+	return &Dot11PTK{
+		KCK: ptk[0:16],
+		KEK: ptk[16:32],
+		TK:  ptk[32:48],
+	}
+}
+
+// AES key unwrap as per RFC 3394
+func dot11KeyUnwrap(kek []byte, data []byte) ([]byte, error) {
+	if len(data) < 24 || len(data)%8 != 0 {
+		return nil, ErrDot11Unwrap
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(data)/8 - 1
+	a := append([]byte(nil), data[:8]...)
+	r := append([]byte(nil), data[8:]...)
+	buf := make([]byte, aes.BlockSize)
+
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a)^uint64(n*j+i))
+			copy(buf[8:], r[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(r[(i-1)*8:i*8], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, dot11KeyWrapIV) != 1 {
+		return nil, ErrDot11Unwrap
+	}
+	return r, nil
+}
+
+// Dot11UnwrapGTK decrypts the key data of the third message of the 4-way
+// handshake, or of the first message of the group key handshake, and returns
+// the group key with its index.
+func Dot11UnwrapGTK(kek []byte, keyData []byte) (int, []byte, error) {
+	data, err := dot11KeyUnwrap(kek, keyData)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for len(data) >= 2 {
+		kind, size := data[0], int(data[1])
+		if len(data) < 2+size {
+			break
+		}
+		kde := data[2 : 2+size]
+		// GTK KDE: OUI, type, key id, reserved, GTK
+		if kind == dot11KDEType && size > 6 && bytes.Equal(kde[:3], dot11KDEOUI) && kde[3] == dot11GTKKDE {
+			return int(kde[4] & 0x03), append([]byte(nil), kde[6:]...), nil
+		}
+		data = data[2+size:]
+	}
+
+	return 0, nil, ErrDot11NoGTK
+}
+
+// Dot11CCMPKeyID returns the index of the key used to encrypt the frame,
+// 0 for the pairwise key and 1 to 3 for the group keys.
+func Dot11CCMPKeyID(dot11 *layers.Dot11) (int, error) {
+	if len(dot11.Payload) < dot11CCMPHdrSize+dot11CCMPMICSize {
+		return 0, ErrDot11Short
+	} else if dot11.Payload[3]&0x20 == 0 {
+		// no extended IV, WEP
+		return 0, ErrDot11NotCCMP
+	}
+	return int(dot11.Payload[3] >> 6), nil
+}
+
+// CCM decryption with a 2 bytes length field, RFC 3610
+func dot11CCMDecrypt(block cipher.Block, nonce []byte, aad []byte, data []byte, mic []byte) ([]byte, error) {
+	plain := make([]byte, len(data))
+	ctr := make([]byte, aes.BlockSize)
+	stream := make([]byte, aes.BlockSize)
+
+	ctr[0] = 0x01
+	copy(ctr[1:14], nonce)
+	for i := 0; i*aes.BlockSize < len(data); i++ {
+		binary.BigEndian.PutUint16(ctr[14:], uint16(i+1))
+		block.Encrypt(stream, ctr)
+		for j := 0; j < aes.BlockSize && i*aes.BlockSize+j < len(data); j++ {
+			plain[i*aes.BlockSize+j] = data[i*aes.BlockSize+j] ^ stream[j]
+		}
+	}
+
+	// CBC-MAC of B0, the additional data and the plaintext
+	mac := make([]byte, aes.BlockSize)
+	mac[0] = 0x40 | byte((len(mic)-2)/2)<<3 | 0x01
+	copy(mac[1:14], nonce)
+	binary.BigEndian.PutUint16(mac[14:], uint16(len(plain)))
+	block.Encrypt(mac, mac)
+
+	update := func(data []byte) {
+		for off := 0; off < len(data); off += aes.BlockSize {
+			for j := 0; j < aes.BlockSize && off+j < len(data); j++ {
+				mac[j] ^= data[off+j]
+			}
+			block.Encrypt(mac, mac)
+		}
+	}
+	update(append([]byte{byte(len(aad) >> 8), byte(len(aad))}, aad...))
+	update(plain)
+
+	binary.BigEndian.PutUint16(ctr[14:], 0)
+	block.Encrypt(stream, ctr)
+	for j := range mic {
+		mac[j] ^= stream[j]
+	}
+
+	if subtle.ConstantTimeCompare(mac[:len(mic)], mic) != 1 {
+		return nil, ErrDot11MIC
+	}
+	return plain, nil
+}
+
+// Dot11DecryptCCMP decrypts the body of a protected data frame with the
+// given temporal key, the MIC is verified so a wrong key results in an error.
+func Dot11DecryptCCMP(tk []byte, dot11 *layers.Dot11) ([]byte, error) {
+	if _, err := Dot11CCMPKeyID(dot11); err != nil {
+		return nil, err
+	}
+
+	header, body := dot11.Contents, dot11.Payload
+	fourAddr := dot11.Flags.ToDS() && dot11.Flags.FromDS()
+	if len(header) < 24 || (fourAddr && len(header) < 30) {
+		return nil, ErrDot11Short
+	}
+
+	// PN5 | PN4 | PN3 | PN2 | PN1 | PN0
+	pn := []byte{body[7], body[6], body[5], body[4], body[1], body[0]}
+
+	nonce := make([]byte, 0, 13)
+	if dot11.QOS != nil {
+		nonce = append(nonce, dot11.QOS.TID)
+	} else {
+		nonce = append(nonce, 0)
+	}
+	nonce = append(nonce, dot11.Address2...)
+	nonce = append(nonce, pn...)
+
+	// frame control without subtype, retry, power management and more data bits
+	fc := []byte{header[0] & 0x8f, header[1]&0xc7 | 0x40}
+	if dot11.QOS != nil {
+		// the order bit is masked too for QoS frames
+		fc[1] &= 0x7f
+	}
+
+	aad := make([]byte, 0, 30)
+	aad = append(aad, fc...)
+	aad = append(aad, header[4:22]...)
+	// only the fragment number of the sequence control
+	aad = append(aad, header[22]&0x0f, 0)
+	qos := 24
+	if fourAddr {
+		aad = append(aad, header[24:30]...)
+		qos = 30
+	}
+	if dot11.QOS != nil && len(header) >= qos+2 {
+		aad = append(aad, header[qos]&0x0f, 0)
+	}
+
+	block, err := aes.NewCipher(tk)
+	if err != nil {
+		return nil, err
+	}
+
+	data := body[dot11CCMPHdrSize : len(body)-dot11CCMPMICSize]
+	mic := body[len(body)-dot11CCMPMICSize:]
+
+	return dot11CCMDecrypt(block, nonce, aad, data, mic)
+}
+
+// Dot11Addresses returns the source and destination addresses of a data frame.
+func Dot11Addresses(dot11 *layers.Dot11) (src net.HardwareAddr, dst net.HardwareAddr) {
+	switch toDS, fromDS := dot11.Flags.ToDS(), dot11.Flags.FromDS(); {
+	case toDS && fromDS:
+		return dot11.Address4, dot11.Address3
+	case toDS:
+		return dot11.Address2, dot11.Address3
+	case fromDS:
+		return dot11.Address3, dot11.Address1
+	default:
+		return dot11.Address2, dot11.Address1
+	}
+}
+
+// Dot11ToEthernet converts the decrypted LLC/SNAP payload of a data frame to
+// an ethernet frame that can be decoded as if it was sniffed from a wire.
+func Dot11ToEthernet(dot11 *layers.Dot11, plain []byte) ([]byte, error) {
+	if len(plain) < 8 || (!bytes.Equal(plain[:6], dot11SNAPHeader) && !bytes.Equal(plain[:6], dot11BridgeTunnelID)) {
+		return nil, ErrDot11NotSNAP
+	}
+
+	src, dst := Dot11Addresses(dot11)
+	frame := make([]byte, 0, 14+len(plain)-8)
+	frame = append(frame, dst...)
+	frame = append(frame, src...)
+	// ethertype and payload
+	frame = append(frame, plain[6:]...)
+
+	return frame, nil
+}
@@ -0,0 +1,131 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDot11PMK(t *testing.T) {
+	// IEEE 802.11i-2004 H.4.1 test vectors
+	tests := []struct {
+		psk   string
+		essid string
+		pmk   string
+	}{
+		{"password", "IEEE", "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e"},
+		{"ThisIsAPassword", "ThisIsASSID", "0dc0d6eb90555ed6419756b9a15ec3e3209b63df707dd508d14581f8982721af"},
+	}
+
+	for _, test := range tests {
+		if pmk := hex.EncodeToString(Dot11PMK(test.psk, test.essid)); pmk != test.pmk {
+			t.Fatalf("expected pmk %s for %s/%s, got %s", test.pmk, test.psk, test.essid, pmk)
+		}
+	}
+}
+
+func TestDot11DerivePTK(t *testing.T) {
+	pmk := Dot11PMK("password", "IEEE")
+	ap, _ := net.ParseMAC("00:11:22:33:44:55")
+	sta, _ := net.ParseMAC("66:77:88:99:aa:bb")
+	anonce := bytes.Repeat([]byte{0x01}, 32)
+	snonce := bytes.Repeat([]byte{0x02}, 32)
+
+	a := Dot11DerivePTK(pmk, ap, sta, anonce, snonce)
+	// the order of addresses and nonces doesn't matter
+	b := Dot11DerivePTK(pmk, sta, ap, snonce, anonce)
+	if len(a.KCK) != 16 || len(a.KEK) != 16 || len(a.TK) != 16 {
+		t.Fatalf("unexpected key sizes %d/%d/%d", len(a.KCK), len(a.KEK), len(a.TK))
+	} else if !bytes.Equal(a.TK, b.TK) || !bytes.Equal(a.KEK, b.KEK) {
+		t.Fatal("expected the same keys regardless of the order")
+	}
+}
+
+func TestDot11KeyUnwrap(t *testing.T) {
+	// RFC 3394 4.1
+	kek := unhex(t, "000102030405060708090a0b0c0d0e0f")
+	wrapped := unhex(t, "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5")
+	expected := unhex(t, "00112233445566778899aabbccddeeff")
+
+	if data, err := dot11KeyUnwrap(kek, wrapped); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !bytes.Equal(data, expected) {
+		t.Fatalf("expected %x, got %x", expected, data)
+	}
+
+	wrapped[0] ^= 0xff
+	if _, err := dot11KeyUnwrap(kek, wrapped); err != ErrDot11Unwrap {
+		t.Fatalf("expected %v, got %v", ErrDot11Unwrap, err)
+	}
+}
+
+func TestDot11DecryptCCMP(t *testing.T) {
+	// IEEE 802.11-2016 M.6.4 CCMP test vector
+	tk := unhex(t, "c97c1f67ce371185514a8a19f2bdd52f")
+	frame := unhex(t, "0848c32c0fd2e128a57c5030f1844408abaea5b8fcba8033")
+	// CCMP header, encrypted data and MIC
+	frame = append(frame, unhex(t, "0ce70020769703b5")...)
+	frame = append(frame, unhex(t, "f3d0a2fe9a3dbf2342a643e43246e80c3c04d019")...)
+	frame = append(frame, unhex(t, "7845ce0b16f97623")...)
+	// gopacket expects the FCS
+	frame = append(frame, 0, 0, 0, 0)
+
+	packet := gopacket.NewPacket(frame, layers.LayerTypeDot11, gopacket.Default)
+	dot11, ok := packet.Layer(layers.LayerTypeDot11).(*layers.Dot11)
+	if !ok {
+		t.Fatalf("could not decode frame: %v", packet.ErrorLayer())
+	}
+
+	expected := unhex(t, "f8ba1a55d02f85ae967bb62fb6cda8eb7e78a050")
+	if id, err := Dot11CCMPKeyID(dot11); err != nil || id != 0 {
+		t.Fatalf("unexpected key id %d (%v)", id, err)
+	} else if plain, err := Dot11DecryptCCMP(tk, dot11); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !bytes.Equal(plain, expected) {
+		t.Fatalf("expected %x, got %x", expected, plain)
+	}
+
+	tk[0] ^= 0xff
+	if _, err := Dot11DecryptCCMP(tk, dot11); err != ErrDot11MIC {
+		t.Fatalf("expected %v, got %v", ErrDot11MIC, err)
+	}
+}
+
+func TestDot11ToEthernet(t *testing.T) {
+	ap, _ := net.ParseMAC("00:11:22:33:44:55")
+	sta, _ := net.ParseMAC("66:77:88:99:aa:bb")
+	dst, _ := net.ParseMAC("de:ad:be:ef:00:01")
+	// station to the distribution system
+	dot11 := &layers.Dot11{
+		Flags:    layers.Dot11FlagsToDS,
+		Address1: ap,
+		Address2: sta,
+		Address3: dst,
+	}
+
+	plain := append([]byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00, 0x08, 0x00}, 0x45, 0x00)
+	frame, err := Dot11ToEthernet(dot11, plain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !bytes.Equal(frame[0:6], dst) || !bytes.Equal(frame[6:12], sta) {
+		t.Fatalf("unexpected addresses in %x", frame)
+	} else if !bytes.Equal(frame[12:], []byte{0x08, 0x00, 0x45, 0x00}) {
+		t.Fatalf("unexpected payload in %x", frame)
+	}
+
+	if _, err := Dot11ToEthernet(dot11, []byte{0x45, 0x00}); err != ErrDot11NotSNAP {
+		t.Fatalf("expected %v, got %v", ErrDot11NotSNAP, err)
+	}
+}
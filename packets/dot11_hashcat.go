@@ -0,0 +1,78 @@
+package packets
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// hashcat 22000 message pair identifiers
+// ref. https://hashcat.net/wiki/doku.php?id=cracking_wpawpa2
+const (
+	HashcatPairM1M2 = 0x00
+	HashcatPairM2M3 = 0x02
+)
+
+const (
+	// offset of the MIC field inside a raw EAPOL frame
+	eapolMICOffset = 81
+	eapolMICSize   = 16
+)
+
+func hashcatMac(mac net.HardwareAddr) string {
+	return strings.Replace(mac.String(), ":", "", -1)
+}
+
+// Dot11HashcatPMKID returns the hashcat 22000 (WPA*01) line for a RSN PMKID.
+func Dot11HashcatPMKID(pmkid []byte, apMac net.HardwareAddr, staMac net.HardwareAddr, essid string) string {
+	// the PMKID is the last 16 bytes of the vendor information element
+	if len(pmkid) > 16 {
+		pmkid = pmkid[len(pmkid)-16:]
+	}
+	return fmt.Sprintf("WPA*01*%x*%s*%s*%x***",
+		pmkid,
+		hashcatMac(apMac),
+		hashcatMac(staMac),
+		essid)
+}
+
+// Dot11HashcatEAPOL returns the hashcat 22000 (WPA*02) line for the EAPOL frame contained
+// in response (message 2 of the handshake), given the ANonce sent by the access point.
+func Dot11HashcatEAPOL(anonce []byte, response gopacket.Packet, apMac net.HardwareAddr, staMac net.HardwareAddr, essid string, pair byte) (bool, string) {
+	eapolLayer := response.Layer(layers.LayerTypeEAPOL)
+	if eapolLayer == nil {
+		return false, ""
+	}
+
+	eapol := eapolLayer.(*layers.EAPOL)
+	raw := make([]byte, 0, len(eapol.Contents)+len(eapol.Payload))
+	raw = append(raw, eapol.Contents...)
+	raw = append(raw, eapol.Payload...)
+	if size := 4 + int(eapol.Length); size <= len(raw) {
+		raw = raw[:size]
+	}
+
+	if len(raw) < eapolMICOffset+eapolMICSize {
+		return false, ""
+	}
+
+	mic := make([]byte, eapolMICSize)
+	copy(mic, raw[eapolMICOffset:eapolMICOffset+eapolMICSize])
+	// the MIC must be zeroed in the frame used for verification
+	for i := eapolMICOffset; i < eapolMICOffset+eapolMICSize; i++ {
+		raw[i] = 0x00
+	}
+
+	return true, fmt.Sprintf("WPA*02*%x*%s*%s*%x*%x*%s*%02x",
+		mic,
+		hashcatMac(apMac),
+		hashcatMac(staMac),
+		essid,
+		anonce,
+		hex.EncodeToString(raw),
+		pair)
+}
@@ -0,0 +1,20 @@
+package packets
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDot11HashcatPMKID(t *testing.T) {
+	ap, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	sta, _ := net.ParseMAC("11:22:33:44:55:66")
+	// vendor IE info: OUI + type + PMKID
+	info := append([]byte{0x00, 0x0f, 0xac, 0x04}, make([]byte, 16)...)
+	info[4] = 0xde
+	info[19] = 0xad
+
+	exp := "WPA*01*de0000000000000000000000000000ad*aabbccddeeff*112233445566*74657374***"
+	if got := Dot11HashcatPMKID(info, ap, sta, "test"); got != exp {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+}
@@ -0,0 +1,18 @@
+package packets
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// VLANIDs returns the identifiers of the 802.1Q tags of a packet, from the
+// outer one, the service tag of QinQ frames, to the inner one.
+func VLANIDs(pkt gopacket.Packet) []uint16 {
+	var ids []uint16
+	for _, layer := range pkt.Layers() {
+		if tag, ok := layer.(*layers.Dot1Q); ok {
+			ids = append(ids, tag.VLANIdentifier)
+		}
+	}
+	return ids
+}
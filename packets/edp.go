@@ -0,0 +1,114 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket/layers"
+)
+
+// Extreme Discovery Protocol, sent with SNAP to the 00:e0:2b:00:00:00
+// multicast address.
+var (
+	EDPMulticastMAC = net.HardwareAddr{0x00, 0xe0, 0x2b, 0x00, 0x00, 0x00}
+	EDPOUI          = []byte{0x00, 0xe0, 0x2b}
+)
+
+const (
+	EDPProtocolID = 0x00bb
+
+	edpHeaderSize = 16
+	edpTLVMarker  = 0x99
+
+	EDPTLVNull    = 0x00
+	EDPTLVDisplay = 0x01
+	EDPTLVInfo    = 0x02
+	EDPTLVVLAN    = 0x05
+)
+
+var ErrEDPInvalid = errors.New("not a valid EDP frame")
+
+// IsEDP returns true if the SNAP header is the one of EDP frames.
+func IsEDP(snap *layers.SNAP) bool {
+	return bytes.Equal(snap.OrganizationalCode, EDPOUI) && uint16(snap.Type) == EDPProtocolID
+}
+
+// EDP strings are zero terminated
+func edpString(data []byte) string {
+	if end := bytes.IndexByte(data, 0); end != -1 {
+		data = data[:end]
+	}
+	return string(data)
+}
+
+// ParseEDP parses the payload of an EDP frame, only sent by switches.
+func ParseEDP(payload []byte) (*DiscoveryNeighbor, error) {
+	if len(payload) < edpHeaderSize || payload[0] != 1 {
+		return nil, ErrEDPInvalid
+	}
+	size := int(binary.BigEndian.Uint16(payload[2:]))
+	if size < edpHeaderSize || size > len(payload) {
+		return nil, ErrEDPInvalid
+	}
+	payload = payload[:size]
+
+	n := &DiscoveryNeighbor{
+		Protocol: "edp",
+		// the machine id, which is the system mac address
+		Chassis: net.HardwareAddr(payload[10:16]).String(),
+		VLANs:   make(map[uint16]string),
+	}
+	n.Capabilities = DiscoveryCapSwitch
+
+	data := payload[edpHeaderSize:]
+	for len(data) >= 4 {
+		if data[0] != edpTLVMarker {
+			return nil, ErrEDPInvalid
+		}
+		tlvType := data[1]
+		// the length includes the marker, type and length fields
+		tlvLen := int(binary.BigEndian.Uint16(data[2:]))
+		if tlvLen < 4 || tlvLen > len(data) {
+			return nil, ErrEDPInvalid
+		}
+		value := data[4:tlvLen]
+		data = data[tlvLen:]
+
+		switch tlvType {
+		case EDPTLVNull:
+			data = nil
+		case EDPTLVDisplay:
+			n.Name = edpString(value)
+		case EDPTLVInfo:
+			// slot, port, virtual chassis, reserved and the version
+			if len(value) < 16 {
+				return nil, ErrEDPInvalid
+			}
+			// slots and ports are zero based
+			slot := binary.BigEndian.Uint16(value[0:])
+			port := binary.BigEndian.Uint16(value[2:])
+			n.Port = fmt.Sprintf("%d:%d", slot+1, port+1)
+			n.Version = fmt.Sprintf("%d.%d.%d.%d", value[12], value[13], value[14], value[15])
+		case EDPTLVVLAN:
+			// flags, reserved, id, reserved, address and the name
+			if len(value) < 12 {
+				return nil, ErrEDPInvalid
+			}
+			id := binary.BigEndian.Uint16(value[2:])
+			n.VLANs[id] = edpString(value[12:])
+			if ip := net.IP(append([]byte{}, value[8:12]...)); n.Address == nil && !ip.IsUnspecified() {
+				n.Address = ip
+			}
+		}
+	}
+
+	if n.Name == "" {
+		n.Name = n.Chassis
+		n.Chassis = ""
+	}
+
+	return n, nil
+}
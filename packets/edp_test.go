@@ -0,0 +1,87 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func edpTestTLV(tlvType byte, value []byte) []byte {
+	size := 4 + len(value)
+	return append([]byte{edpTLVMarker, tlvType, byte(size >> 8), byte(size)}, value...)
+}
+
+func edpTestPayload() []byte {
+	payload := []byte{
+		// version, reserved, length, checksum, sequence, machine id type
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a, 0x00, 0x00,
+		0x00, 0x04, 0x96, 0x1a, 0x2b, 0x3c,
+	}
+	// slot 0 port 23, version 12.6.2.6
+	info := make([]byte, 32)
+	info[3] = 23
+	info[12], info[13], info[14], info[15] = 12, 6, 2, 6
+	payload = append(payload, edpTestTLV(EDPTLVInfo, info)...)
+	payload = append(payload, edpTestTLV(EDPTLVDisplay, []byte("core-sw1\x00"))...)
+	vlan := []byte{0x80, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x00, 10, 0, 0, 254}
+	payload = append(payload, edpTestTLV(EDPTLVVLAN, append(vlan, "users\x00"...))...)
+	payload = append(payload, edpTestTLV(EDPTLVNull, nil)...)
+	payload[2], payload[3] = byte(len(payload)>>8), byte(len(payload))
+	return payload
+}
+
+func TestParseEDP(t *testing.T) {
+	n, err := ParseEDP(edpTestPayload())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n.Protocol != "edp" || n.Name != "core-sw1" || n.Chassis != "00:04:96:1a:2b:3c" {
+		t.Fatalf("unexpected neighbor %+v", n)
+	} else if n.Port != "1:24" || n.Version != "12.6.2.6" {
+		t.Fatalf("unexpected port %s or version %s", n.Port, n.Version)
+	} else if n.VLANs[10] != "users" || !n.Address.Equal(net.ParseIP("10.0.0.254")) {
+		t.Fatalf("unexpected vlans %v or address %s", n.VLANs, n.Address)
+	} else if !n.Has(DiscoveryCapSwitch) {
+		t.Fatal("expected a switch")
+	}
+}
+
+func TestParseDiscoveryEDP(t *testing.T) {
+	hw, _ := net.ParseMAC("00:04:96:1a:2b:3d")
+	eth := layers.Ethernet{
+		SrcMAC:       hw,
+		DstMAC:       EDPMulticastMAC,
+		EthernetType: layers.EthernetTypeLLC,
+	}
+	llc := layers.LLC{DSAP: 0xaa, SSAP: 0xaa, Control: 0x03}
+	snap := layers.SNAP{OrganizationalCode: EDPOUI, Type: EDPProtocolID}
+	err, raw := Serialize(&eth, &llc, &snap, gopacket.Payload(edpTestPayload()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n := ParseDiscovery(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default))
+	if n == nil {
+		t.Fatal("expected a neighbor")
+	} else if n.Name != "core-sw1" || n.HW.String() != hw.String() {
+		t.Fatalf("unexpected neighbor %+v", n)
+	}
+}
+
+func TestParseEDPInvalid(t *testing.T) {
+	truncated := edpTestPayload()
+	truncated[2], truncated[3] = 0xff, 0xff
+	badMarker := edpTestPayload()
+	badMarker[edpHeaderSize] = 0x42
+
+	for _, payload := range [][]byte{
+		{0x01, 0x00},
+		truncated,
+		badMarker,
+	} {
+		if _, err := ParseEDP(payload); err != ErrEDPInvalid {
+			t.Fatalf("expected %v for %x, got %v", ErrEDPInvalid, payload, err)
+		}
+	}
+}
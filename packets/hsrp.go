@@ -0,0 +1,228 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	HSRPPort = 1985
+
+	HSRPOpHello  = 0
+	HSRPOpCoup   = 1
+	HSRPOpResign = 2
+
+	HSRPStateInitial = 0
+	HSRPStateLearn   = 1
+	HSRPStateListen  = 2
+	HSRPStateSpeak   = 4
+	HSRPStateStandby = 8
+	HSRPStateActive  = 16
+
+	hsrpV1Size         = 20
+	hsrpV2GroupTLV     = 1
+	hsrpV2GroupTLVSize = 40
+	hsrpV2TextAuthTLV  = 3
+	hsrpV2MD5AuthTLV   = 4
+)
+
+var (
+	HSRPv1DestIP = net.ParseIP("224.0.0.2")
+	HSRPv2DestIP = net.ParseIP("224.0.0.102")
+
+	HSRPDefaultAuth = []byte("cisco\x00\x00\x00")
+
+	ErrHSRPShort = errors.New("HSRP packet too short")
+)
+
+// HSRP represents both version 1 and version 2 HSRP messages.
+type HSRP struct {
+	Version    uint8
+	OpCode     uint8
+	State      uint8
+	HelloTime  uint32 // milliseconds
+	HoldTime   uint32 // milliseconds
+	Priority   uint32
+	Group      uint16
+	Identifier net.HardwareAddr
+	// plain text authentication data, nil if MD5 authentication is used
+	Auth      []byte
+	MD5       bool
+	VirtualIP net.IP
+}
+
+func HSRPStateName(state uint8) string {
+	switch state {
+	case HSRPStateInitial:
+		return "initial"
+	case HSRPStateLearn:
+		return "learn"
+	case HSRPStateListen:
+		return "listen"
+	case HSRPStateSpeak:
+		return "speak"
+	case HSRPStateStandby:
+		return "standby"
+	case HSRPStateActive:
+		return "active"
+	}
+	return fmt.Sprintf("unknown(%d)", state)
+}
+
+// HSRPVirtualMAC returns the MAC address used by the active router of the group.
+func HSRPVirtualMAC(version uint8, group uint16) net.HardwareAddr {
+	if version == 2 {
+		return net.HardwareAddr{0x00, 0x00, 0x0c, 0x9f, 0xf0 | byte(group>>8&0x0f), byte(group)}
+	}
+	return net.HardwareAddr{0x00, 0x00, 0x0c, 0x07, 0xac, byte(group)}
+}
+
+// DefaultAuth returns true if the group uses the default (or no) authentication.
+func (h *HSRP) DefaultAuth() bool {
+	if h.MD5 {
+		return false
+	}
+	return len(h.Auth) == 0 || bytes.Equal(h.Auth, HSRPDefaultAuth)
+}
+
+func ParseHSRP(data []byte) (*HSRP, error) {
+	if len(data) < 2 {
+		return nil, ErrHSRPShort
+	}
+
+	// version 1 packets start with a zero version byte, version 2 ones with a TLV
+	if data[0] == 0 {
+		if len(data) < hsrpV1Size {
+			return nil, ErrHSRPShort
+		}
+		return &HSRP{
+			Version:   1,
+			OpCode:    data[1],
+			State:     data[2],
+			HelloTime: uint32(data[3]) * 1000,
+			HoldTime:  uint32(data[4]) * 1000,
+			Priority:  uint32(data[5]),
+			Group:     uint16(data[6]),
+			Auth:      append([]byte{}, data[8:16]...),
+			VirtualIP: net.IP(append([]byte{}, data[16:20]...)),
+		}, nil
+	}
+
+	h := &HSRP{Version: 2}
+	found := false
+	for len(data) >= 2 {
+		t, l := data[0], int(data[1])
+		if len(data) < 2+l {
+			return nil, ErrHSRPShort
+		}
+		value := data[2 : 2+l]
+
+		switch t {
+		case hsrpV2GroupTLV:
+			if l < hsrpV2GroupTLVSize {
+				return nil, ErrHSRPShort
+			}
+			h.OpCode = value[1]
+			h.State = value[2]
+			h.Group = binary.BigEndian.Uint16(value[4:6])
+			h.Identifier = net.HardwareAddr(append([]byte{}, value[6:12]...))
+			h.Priority = binary.BigEndian.Uint32(value[12:16])
+			h.HelloTime = binary.BigEndian.Uint32(value[16:20])
+			h.HoldTime = binary.BigEndian.Uint32(value[20:24])
+			if value[3] == 4 {
+				h.VirtualIP = net.IP(append([]byte{}, value[24:28]...))
+			} else {
+				h.VirtualIP = net.IP(append([]byte{}, value[24:40]...))
+			}
+			found = true
+		case hsrpV2TextAuthTLV:
+			h.Auth = append([]byte{}, value...)
+		case hsrpV2MD5AuthTLV:
+			h.MD5 = true
+		}
+
+		data = data[2+l:]
+	}
+
+	if !found {
+		return nil, fmt.Errorf("HSRPv2 packet without group state")
+	}
+	return h, nil
+}
+
+func (h *HSRP) Payload() []byte {
+	auth := make([]byte, 8)
+	if h.Auth != nil {
+		copy(auth, h.Auth)
+	} else {
+		copy(auth, HSRPDefaultAuth)
+	}
+
+	if h.Version != 2 {
+		data := make([]byte, hsrpV1Size)
+		data[1] = h.OpCode
+		data[2] = h.State
+		data[3] = byte(h.HelloTime / 1000)
+		data[4] = byte(h.HoldTime / 1000)
+		data[5] = byte(h.Priority)
+		data[6] = byte(h.Group)
+		copy(data[8:], auth)
+		copy(data[16:], h.VirtualIP.To4())
+		return data
+	}
+
+	group := make([]byte, 2+hsrpV2GroupTLVSize)
+	group[0] = hsrpV2GroupTLV
+	group[1] = hsrpV2GroupTLVSize
+	group[2] = 2
+	group[3] = h.OpCode
+	group[4] = h.State
+	binary.BigEndian.PutUint16(group[6:], h.Group)
+	copy(group[8:], h.Identifier)
+	binary.BigEndian.PutUint32(group[14:], h.Priority)
+	binary.BigEndian.PutUint32(group[18:], h.HelloTime)
+	binary.BigEndian.PutUint32(group[22:], h.HoldTime)
+	if ip4 := h.VirtualIP.To4(); ip4 != nil {
+		group[5] = 4
+		copy(group[26:], ip4)
+	} else {
+		group[5] = 6
+		copy(group[26:], h.VirtualIP.To16())
+	}
+
+	return append(group, append([]byte{hsrpV2TextAuthTLV, 8}, auth...)...)
+}
+
+// NewHSRPPacket creates a HSRP message from our address to the group multicast address.
+func NewHSRPPacket(from net.IP, fromHW net.HardwareAddr, h *HSRP) (error, []byte) {
+	dst := HSRPv1DestIP
+	if h.Version == 2 {
+		dst = HSRPv2DestIP
+	}
+
+	eth := layers.Ethernet{
+		SrcMAC:       fromHW,
+		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, dst.To4()[3]},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Protocol: layers.IPProtocolUDP,
+		Version:  4,
+		TTL:      1,
+		SrcIP:    from,
+		DstIP:    dst,
+	}
+	udp := layers.UDP{
+		SrcPort: layers.UDPPort(HSRPPort),
+		DstPort: layers.UDPPort(HSRPPort),
+	}
+	udp.SetNetworkLayerForChecksum(&ip4)
+
+	return Serialize(&eth, &ip4, &udp, gopacket.Payload(h.Payload()))
+}
@@ -0,0 +1,91 @@
+package packets
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestHSRPv1(t *testing.T) {
+	h := &HSRP{
+		Version:   1,
+		OpCode:    HSRPOpHello,
+		State:     HSRPStateActive,
+		HelloTime: 3000,
+		HoldTime:  10000,
+		Priority:  110,
+		Group:     1,
+		VirtualIP: net.ParseIP("192.168.1.254"),
+	}
+
+	parsed, err := ParseHSRP(h.Payload())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if parsed.Version != 1 || parsed.State != HSRPStateActive || parsed.Priority != 110 || parsed.Group != 1 {
+		t.Fatalf("unexpected fields %+v", parsed)
+	} else if parsed.HelloTime != 3000 || parsed.HoldTime != 10000 {
+		t.Fatalf("unexpected timers %+v", parsed)
+	} else if !parsed.VirtualIP.Equal(h.VirtualIP) {
+		t.Fatalf("expected virtual ip %s, got %s", h.VirtualIP, parsed.VirtualIP)
+	} else if !parsed.DefaultAuth() {
+		t.Fatal("expected default authentication")
+	}
+
+	h.Auth = []byte("s3cr3t")
+	if parsed, _ = ParseHSRP(h.Payload()); parsed.DefaultAuth() {
+		t.Fatal("expected non default authentication")
+	}
+}
+
+func TestHSRPv2(t *testing.T) {
+	hw, _ := net.ParseMAC("00:11:22:33:44:55")
+	h := &HSRP{
+		Version:    2,
+		OpCode:     HSRPOpCoup,
+		State:      HSRPStateSpeak,
+		HelloTime:  3000,
+		HoldTime:   10000,
+		Priority:   255,
+		Group:      300,
+		Identifier: hw,
+		VirtualIP:  net.ParseIP("10.0.0.1"),
+	}
+
+	parsed, err := ParseHSRP(h.Payload())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if parsed.Version != 2 || parsed.OpCode != HSRPOpCoup || parsed.Priority != 255 || parsed.Group != 300 {
+		t.Fatalf("unexpected fields %+v", parsed)
+	} else if !bytes.Equal(parsed.Identifier, hw) {
+		t.Fatalf("expected identifier %s, got %s", hw, parsed.Identifier)
+	} else if !parsed.VirtualIP.Equal(h.VirtualIP) {
+		t.Fatalf("expected virtual ip %s, got %s", h.VirtualIP, parsed.VirtualIP)
+	} else if !parsed.DefaultAuth() {
+		t.Fatal("expected default authentication")
+	}
+
+	if mac := HSRPVirtualMAC(2, 300).String(); mac != "00:00:0c:9f:f1:2c" {
+		t.Fatalf("unexpected virtual mac %s", mac)
+	}
+}
+
+func TestHSRPPacket(t *testing.T) {
+	hw, _ := net.ParseMAC("00:11:22:33:44:55")
+	h := &HSRP{Version: 1, State: HSRPStateActive, Priority: 255, Group: 7, VirtualIP: net.ParseIP("10.0.0.1")}
+
+	err, raw := NewHSRPPacket(net.ParseIP("10.0.0.42"), hw, h)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok || udp.DstPort != HSRPPort {
+		t.Fatal("expected an HSRP udp packet")
+	} else if parsed, err := ParseHSRP(udp.Payload); err != nil || parsed.Group != 7 {
+		t.Fatalf("unexpected payload %+v (%v)", parsed, err)
+	}
+}
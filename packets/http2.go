@@ -0,0 +1,224 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+const (
+	// sent by the client at the beginning of every cleartext or TLS connection
+	HTTP2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+	http2FrameHeaderSize = 9
+	http2MaxFrameSize    = 1<<24 - 1
+	// dynamic table size until the peer changes it with its settings, and
+	// the biggest one we accept since we can't see what was negotiated
+	http2HeaderTableSize    = 4096
+	http2MaxHeaderTableSize = 1024 * 1024
+	// longest header block buffered waiting for its CONTINUATION frames
+	http2MaxHeaderBlock = 256 * 1024
+)
+
+const (
+	HTTP2FrameData         = 0x0
+	HTTP2FrameHeaders      = 0x1
+	HTTP2FramePriority     = 0x2
+	HTTP2FrameRSTStream    = 0x3
+	HTTP2FrameSettings     = 0x4
+	HTTP2FramePushPromise  = 0x5
+	HTTP2FramePing         = 0x6
+	HTTP2FrameGoAway       = 0x7
+	HTTP2FrameWindowUpdate = 0x8
+	HTTP2FrameContinuation = 0x9
+)
+
+const (
+	HTTP2FlagEndStream  = 0x1
+	HTTP2FlagEndHeaders = 0x4
+	HTTP2FlagPadded     = 0x8
+	HTTP2FlagPriority   = 0x20
+)
+
+var ErrHTTP2Invalid = errors.New("not a valid HTTP/2 frame")
+
+// HTTP2Frame is a HTTP/2 frame with its payload.
+type HTTP2Frame struct {
+	Type     uint8
+	Flags    uint8
+	StreamID uint32
+	Payload  []byte
+}
+
+// HTTP2Headers is a decoded header block with its pseudo headers split from
+// the regular ones, whose names are lowercase.
+type HTTP2Headers struct {
+	StreamID  uint32              `json:"stream_id"`
+	Method    string              `json:"method"`
+	Scheme    string              `json:"scheme"`
+	Authority string              `json:"authority"`
+	Path      string              `json:"path"`
+	Status    string              `json:"status"`
+	Headers   map[string][]string `json:"headers"`
+	EndStream bool                `json:"end_stream"`
+	// true for the requests promised by the server
+	Pushed bool `json:"pushed"`
+}
+
+// IsRequest returns true for the header blocks of requests.
+func (h *HTTP2Headers) IsRequest() bool {
+	return h.Method != ""
+}
+
+// Header returns the first value of a regular header.
+func (h *HTTP2Headers) Header(name string) string {
+	if values := h.Headers[strings.ToLower(name)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ParseHTTP2Frames parses the frames of a segment, skipping the connection
+// preface if present, the last frame is ignored if truncated.
+func ParseHTTP2Frames(payload []byte) ([]HTTP2Frame, error) {
+	if strings.HasPrefix(string(payload), HTTP2Preface) {
+		payload = payload[len(HTTP2Preface):]
+	}
+
+	frames := []HTTP2Frame{}
+	for len(payload) >= http2FrameHeaderSize {
+		size := int(payload[0])<<16 | int(payload[1])<<8 | int(payload[2])
+		frame := HTTP2Frame{
+			Type:     payload[3],
+			Flags:    payload[4],
+			StreamID: binary.BigEndian.Uint32(payload[5:]) & 0x7fffffff,
+		}
+		if frame.Type > HTTP2FrameContinuation || size > http2MaxFrameSize {
+			return nil, ErrHTTP2Invalid
+		} else if len(payload) < http2FrameHeaderSize+size {
+			break
+		}
+
+		frame.Payload = payload[http2FrameHeaderSize : http2FrameHeaderSize+size]
+		frames = append(frames, frame)
+		payload = payload[http2FrameHeaderSize+size:]
+	}
+
+	if len(frames) == 0 {
+		return nil, ErrHTTP2Invalid
+	}
+	return frames, nil
+}
+
+// the header block fragment of HEADERS, PUSH_PROMISE and CONTINUATION frames,
+// without padding and priority fields
+func (f *HTTP2Frame) headerFragment() ([]byte, error) {
+	data := f.Payload
+	if f.Type == HTTP2FrameContinuation {
+		return data, nil
+	}
+
+	padding := 0
+	if f.Flags&HTTP2FlagPadded != 0 {
+		if len(data) < 1 {
+			return nil, ErrHTTP2Invalid
+		}
+		padding = int(data[0])
+		data = data[1:]
+	}
+
+	skip := 0
+	if f.Type == HTTP2FramePushPromise {
+		// promised stream id
+		skip = 4
+	} else if f.Flags&HTTP2FlagPriority != 0 {
+		skip = 5
+	}
+	if len(data) < skip+padding {
+		return nil, ErrHTTP2Invalid
+	}
+
+	return data[skip : len(data)-padding], nil
+}
+
+// HTTP2Decoder decodes the header blocks sent in one direction of a
+// connection, it must see all of them to keep its HPACK dynamic table in
+// sync with the one of the sender.
+type HTTP2Decoder struct {
+	hpack   *hpack.Decoder
+	pending []byte
+	headers *HTTP2Headers
+}
+
+func NewHTTP2Decoder() *HTTP2Decoder {
+	d := &HTTP2Decoder{
+		hpack: hpack.NewDecoder(http2HeaderTableSize, nil),
+	}
+	d.hpack.SetAllowedMaxDynamicTableSize(http2MaxHeaderTableSize)
+	return d
+}
+
+// Decode feeds a frame to the decoder, returning the headers once their
+// block is complete, nil for the other frames or while waiting for its
+// CONTINUATION frames.
+func (d *HTTP2Decoder) Decode(f *HTTP2Frame) (*HTTP2Headers, error) {
+	switch f.Type {
+	case HTTP2FrameHeaders, HTTP2FramePushPromise:
+		d.headers = &HTTP2Headers{
+			StreamID:  f.StreamID,
+			Headers:   make(map[string][]string),
+			EndStream: f.Flags&HTTP2FlagEndStream != 0,
+			Pushed:    f.Type == HTTP2FramePushPromise,
+		}
+		d.pending = d.pending[:0]
+	case HTTP2FrameContinuation:
+		if d.headers == nil || d.headers.StreamID != f.StreamID {
+			return nil, ErrHTTP2Invalid
+		}
+	default:
+		return nil, nil
+	}
+
+	fragment, err := f.headerFragment()
+	if err != nil {
+		d.headers = nil
+		return nil, err
+	} else if len(d.pending)+len(fragment) > http2MaxHeaderBlock {
+		d.headers = nil
+		return nil, ErrHTTP2Invalid
+	}
+	d.pending = append(d.pending, fragment...)
+
+	if f.Flags&HTTP2FlagEndHeaders == 0 {
+		return nil, nil
+	}
+
+	headers := d.headers
+	d.headers = nil
+	fields, err := d.hpack.DecodeFull(d.pending)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		switch field.Name {
+		case ":method":
+			headers.Method = field.Value
+		case ":scheme":
+			headers.Scheme = field.Value
+		case ":authority":
+			headers.Authority = field.Value
+		case ":path":
+			headers.Path = field.Value
+		case ":status":
+			headers.Status = field.Value
+		default:
+			name := strings.ToLower(field.Name)
+			headers.Headers[name] = append(headers.Headers[name], field.Value)
+		}
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,111 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+func http2TestFrame(frameType, flags uint8, stream uint32, payload []byte) []byte {
+	frame := []byte{byte(len(payload) >> 16), byte(len(payload) >> 8), byte(len(payload)), frameType, flags, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(frame[5:], stream)
+	return append(frame, payload...)
+}
+
+func http2TestBlock(enc *hpack.Encoder, buf *bytes.Buffer, fields ...string) []byte {
+	buf.Reset()
+	for i := 0; i+1 < len(fields); i += 2 {
+		enc.WriteField(hpack.HeaderField{Name: fields[i], Value: fields[i+1]})
+	}
+	return append([]byte{}, buf.Bytes()...)
+}
+
+func TestHTTP2Request(t *testing.T) {
+	buf := bytes.Buffer{}
+	enc := hpack.NewEncoder(&buf)
+
+	first := http2TestBlock(enc, &buf, ":method", "GET", ":scheme", "http", ":authority", "example.com", ":path", "/index.html", "user-agent", "curl/7.64.1")
+	// the same headers again are encoded with the dynamic table
+	second := http2TestBlock(enc, &buf, ":method", "GET", ":scheme", "http", ":authority", "example.com", ":path", "/style.css", "user-agent", "curl/7.64.1")
+
+	payload := []byte(HTTP2Preface)
+	payload = append(payload, http2TestFrame(HTTP2FrameSettings, 0, 0, nil)...)
+	payload = append(payload, http2TestFrame(HTTP2FrameHeaders, HTTP2FlagEndHeaders|HTTP2FlagEndStream, 1, first)...)
+	// split in a HEADERS and a CONTINUATION frame
+	payload = append(payload, http2TestFrame(HTTP2FrameHeaders, HTTP2FlagEndStream, 3, second[:3])...)
+	payload = append(payload, http2TestFrame(HTTP2FrameContinuation, HTTP2FlagEndHeaders, 3, second[3:])...)
+
+	frames, err := ParseHTTP2Frames(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(frames) != 4 {
+		t.Fatalf("expected 4 frames, got %d", len(frames))
+	}
+
+	decoder := NewHTTP2Decoder()
+	requests := []*HTTP2Headers{}
+	for i := range frames {
+		if headers, err := decoder.Decode(&frames[i]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		} else if headers != nil {
+			requests = append(requests, headers)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	for i, path := range []string{"/index.html", "/style.css"} {
+		req := requests[i]
+		if !req.IsRequest() || req.Method != "GET" || req.Authority != "example.com" || req.Path != path || !req.EndStream {
+			t.Fatalf("unexpected request %+v", req)
+		} else if req.Header("User-Agent") != "curl/7.64.1" {
+			t.Fatalf("unexpected headers %v", req.Headers)
+		}
+	}
+	if requests[1].StreamID != 3 {
+		t.Fatalf("unexpected stream %d", requests[1].StreamID)
+	}
+}
+
+func TestHTTP2PaddedResponse(t *testing.T) {
+	buf := bytes.Buffer{}
+	enc := hpack.NewEncoder(&buf)
+	block := http2TestBlock(enc, &buf, ":status", "200", "content-type", "text/html")
+
+	// 2 bytes of padding and the priority fields
+	payload := append([]byte{2, 0, 0, 0, 0, 16}, block...)
+	payload = append(payload, 0, 0)
+	frames, err := ParseHTTP2Frames(http2TestFrame(HTTP2FrameHeaders, HTTP2FlagEndHeaders|HTTP2FlagPadded|HTTP2FlagPriority, 1, payload))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := NewHTTP2Decoder().Decode(&frames[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if res.IsRequest() || res.Status != "200" || res.Header("content-type") != "text/html" {
+		t.Fatalf("unexpected response %+v", res)
+	}
+}
+
+func TestParseHTTP2Invalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		[]byte("GET / HTTP/1.1\r\n\r\n"),
+		[]byte(HTTP2Preface),
+		// unknown frame type
+		http2TestFrame(0x42, 0, 0, nil),
+	} {
+		if _, err := ParseHTTP2Frames(payload); err != ErrHTTP2Invalid {
+			t.Fatalf("expected %v for %q, got %v", ErrHTTP2Invalid, payload, err)
+		}
+	}
+
+	// CONTINUATION without HEADERS
+	frame := HTTP2Frame{Type: HTTP2FrameContinuation, StreamID: 1}
+	if _, err := NewHTTP2Decoder().Decode(&frame); err != ErrHTTP2Invalid {
+		t.Fatalf("expected %v, got %v", ErrHTTP2Invalid, err)
+	}
+}
@@ -0,0 +1,130 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// NewICMPEcho creates an ICMP echo request, the source doesn't need to be our own address.
+func NewICMPEcho(from net.IP, fromHW net.HardwareAddr, to net.IP, toHW net.HardwareAddr, id uint16, seq uint16) (error, []byte) {
+	eth := layers.Ethernet{
+		SrcMAC:       fromHW,
+		DstMAC:       toHW,
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Protocol: layers.IPProtocolICMPv4,
+		Version:  4,
+		TTL:      64,
+		SrcIP:    from,
+		DstIP:    to,
+	}
+	icmp := layers.ICMPv4{
+		TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0),
+		Id:       id,
+		Seq:      seq,
+	}
+
+	return Serialize(&eth, &ip4, &icmp, gopacket.Payload([]byte("bettercap")))
+}
+
+var ErrICMPQuoteInvalid = errors.New("not a valid ICMP quoted header")
+
+// ICMPQuote is the header of the packet an ICMP error is about, quoted in
+// its payload.
+type ICMPQuote struct {
+	Protocol layers.IPProtocol `json:"protocol"`
+	SrcIP    net.IP            `json:"src"`
+	DstIP    net.IP            `json:"dst"`
+	SrcPort  uint16            `json:"src_port"`
+	DstPort  uint16            `json:"dst_port"`
+	TTL      uint8             `json:"ttl"`
+}
+
+// HasPorts returns true if the quoted packet was TCP, UDP or SCTP.
+func (q *ICMPQuote) HasPorts() bool {
+	switch q.Protocol {
+	case layers.IPProtocolTCP, layers.IPProtocolUDP, layers.IPProtocolSCTP:
+		return true
+	}
+	return false
+}
+
+// ParseICMPQuote parses the IPv4 or IPv6 header quoted by an ICMP error and
+// the ports of the transport layer, the payload of ICMPv6 errors starts
+// with 4 unused bytes.
+func ParseICMPQuote(payload []byte) (*ICMPQuote, error) {
+	if len(payload) < 20 {
+		return nil, ErrICMPQuoteInvalid
+	}
+
+	q := &ICMPQuote{}
+	var transport []byte
+	switch payload[0] >> 4 {
+	case 4:
+		size := int(payload[0]&0x0f) * 4
+		if size < 20 || len(payload) < size {
+			return nil, ErrICMPQuoteInvalid
+		}
+		q.TTL = payload[8]
+		q.Protocol = layers.IPProtocol(payload[9])
+		q.SrcIP = net.IP(append([]byte{}, payload[12:16]...))
+		q.DstIP = net.IP(append([]byte{}, payload[16:20]...))
+		transport = payload[size:]
+	case 6:
+		if len(payload) < 40 {
+			return nil, ErrICMPQuoteInvalid
+		}
+		q.Protocol = layers.IPProtocol(payload[6])
+		q.TTL = payload[7]
+		q.SrcIP = net.IP(append([]byte{}, payload[8:24]...))
+		q.DstIP = net.IP(append([]byte{}, payload[24:40]...))
+		transport = payload[40:]
+	default:
+		return nil, ErrICMPQuoteInvalid
+	}
+
+	// only the first 8 bytes of the transport layer are guaranteed
+	if q.HasPorts() && len(transport) >= 4 {
+		q.SrcPort = binary.BigEndian.Uint16(transport[0:])
+		q.DstPort = binary.BigEndian.Uint16(transport[2:])
+	}
+
+	return q, nil
+}
+
+// the bytes of the payload from offset on are their own offset
+func icmpSequential(payload []byte, offset int) bool {
+	if len(payload) <= offset {
+		return false
+	}
+	for i := offset; i < len(payload); i++ {
+		if payload[i] != byte(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// ICMPEchoHint guesses the operating system or the tool which sent an echo
+// request from the padding of its payload.
+func ICMPEchoHint(payload []byte) string {
+	switch {
+	case len(payload) == 0:
+		return "scanner"
+	case bytes.HasPrefix([]byte("abcdefghijklmnopqrstuvwabcdefghijklmnopqrstuvw"), payload):
+		return "windows"
+	// a 32 bits timeval followed by the pattern
+	case icmpSequential(payload, 8):
+		return "macos/bsd"
+	// a 64 bits timeval followed by the pattern
+	case icmpSequential(payload, 16):
+		return "linux"
+	}
+	return ""
+}
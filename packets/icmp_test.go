@@ -0,0 +1,119 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestNewICMPEcho(t *testing.T) {
+	from := net.ParseIP("192.168.1.1")
+	fromHW, _ := net.ParseMAC("01:23:45:67:89:ab")
+	to := net.ParseIP("192.168.1.10")
+	toHW, _ := net.ParseMAC("ab:89:67:45:23:01")
+
+	err, raw := NewICMPEcho(from, fromHW, to, toHW, 0xbeef, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	if !ok {
+		t.Fatalf("expected an ipv4 layer")
+	} else if !ip4.SrcIP.Equal(from) || !ip4.DstIP.Equal(to) {
+		t.Fatalf("unexpected addresses %s -> %s", ip4.SrcIP, ip4.DstIP)
+	}
+
+	icmp, ok := pkt.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4)
+	if !ok {
+		t.Fatalf("expected an icmp layer")
+	} else if icmp.TypeCode.Type() != layers.ICMPv4TypeEchoRequest {
+		t.Fatalf("expected an echo request, got %s", icmp.TypeCode)
+	} else if icmp.Id != 0xbeef || icmp.Seq != 7 {
+		t.Fatalf("unexpected id %x and seq %d", icmp.Id, icmp.Seq)
+	}
+}
+
+func TestParseICMPQuote(t *testing.T) {
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      1,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP("192.168.1.10"),
+		DstIP:    net.ParseIP("8.8.8.8"),
+	}
+	udp := layers.UDP{SrcPort: 54321, DstPort: 33434}
+	udp.SetNetworkLayerForChecksum(&ip4)
+	err, raw := Serialize(&ip4, &udp, gopacket.Payload([]byte("traceroute")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// routers only quote the header and 8 bytes of the payload
+	q, err := ParseICMPQuote(raw[:28])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if q.Protocol != layers.IPProtocolUDP || q.TTL != 1 || !q.HasPorts() {
+		t.Fatalf("unexpected quote %+v", q)
+	} else if !q.SrcIP.Equal(ip4.SrcIP) || !q.DstIP.Equal(ip4.DstIP) || q.SrcPort != 54321 || q.DstPort != 33434 {
+		t.Fatalf("unexpected quote %+v", q)
+	}
+
+	ip6 := layers.IPv6{
+		Version:    6,
+		HopLimit:   3,
+		NextHeader: layers.IPProtocolTCP,
+		SrcIP:      net.ParseIP("fe80::1"),
+		DstIP:      net.ParseIP("2001:db8::1"),
+	}
+	tcp := layers.TCP{SrcPort: 40000, DstPort: 443, SYN: true}
+	tcp.SetNetworkLayerForChecksum(&ip6)
+	if err, raw = Serialize(&ip6, &tcp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if q, err = ParseICMPQuote(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if q.Protocol != layers.IPProtocolTCP || q.TTL != 3 || !q.DstIP.Equal(ip6.DstIP) || q.DstPort != 443 {
+		t.Fatalf("unexpected quote %+v", q)
+	}
+}
+
+func TestParseICMPQuoteInvalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		{0x45, 0x00},
+		append([]byte{0x2f}, make([]byte, 40)...),
+		// header length bigger than the payload
+		append([]byte{0x4f}, make([]byte, 30)...),
+	} {
+		if _, err := ParseICMPQuote(payload); err != ErrICMPQuoteInvalid {
+			t.Fatalf("expected %v for %x, got %v", ErrICMPQuoteInvalid, payload, err)
+		}
+	}
+}
+
+func TestICMPEchoHint(t *testing.T) {
+	linux := make([]byte, 56)
+	macos := make([]byte, 56)
+	for i := range linux {
+		linux[i], macos[i] = byte(i), byte(i)
+	}
+	// the timestamps
+	copy(linux, []byte{0x5e, 0x0b, 0xe1, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3f, 0x4a, 0x0d, 0x00, 0x00, 0x00, 0x00, 0x00})
+	copy(macos, []byte{0x5e, 0x0b, 0xe1, 0x00, 0x3f, 0x4a, 0x0d, 0x00})
+
+	for payload, expected := range map[string]string{
+		"":                                 "scanner",
+		"abcdefghijklmnopqrstuvwabcdefghi": "windows",
+		string(linux):                      "linux",
+		string(macos):                      "macos/bsd",
+		"bettercap":                        "",
+	} {
+		if hint := ICMPEchoHint([]byte(payload)); hint != expected {
+			t.Fatalf("expected '%s' for %x, got '%s'", expected, payload, hint)
+		}
+	}
+}
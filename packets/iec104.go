@@ -0,0 +1,210 @@
+package packets
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	IEC104Port = 2404
+
+	iec104Start      = 0x68
+	iec104APCISize   = 6
+	iec104MinASDU    = 9
+	iec104MaxAPDU    = 253
+	iec104CauseMask  = 0x3f
+	iec104Negative   = 0x40
+	iec104Test       = 0x80
+	iec104Sequence   = 0x80
+	iec104CountMask  = 0x7f
+	iec104FormatMask = 0x03
+)
+
+// APCI formats
+const (
+	IEC104FormatI = "I"
+	IEC104FormatS = "S"
+	IEC104FormatU = "U"
+)
+
+var IEC104UFunctionNames = map[uint8]string{
+	0x07: "startdt act",
+	0x0b: "startdt con",
+	0x13: "stopdt act",
+	0x23: "stopdt con",
+	0x43: "testfr act",
+	0x83: "testfr con",
+}
+
+var IEC104TypeNames = map[uint8]string{
+	1:   "M_SP_NA_1",
+	3:   "M_DP_NA_1",
+	5:   "M_ST_NA_1",
+	7:   "M_BO_NA_1",
+	9:   "M_ME_NA_1",
+	11:  "M_ME_NB_1",
+	13:  "M_ME_NC_1",
+	15:  "M_IT_NA_1",
+	30:  "M_SP_TB_1",
+	31:  "M_DP_TB_1",
+	32:  "M_ST_TB_1",
+	33:  "M_BO_TB_1",
+	34:  "M_ME_TD_1",
+	35:  "M_ME_TE_1",
+	36:  "M_ME_TF_1",
+	37:  "M_IT_TB_1",
+	45:  "C_SC_NA_1",
+	46:  "C_DC_NA_1",
+	47:  "C_RC_NA_1",
+	48:  "C_SE_NA_1",
+	49:  "C_SE_NB_1",
+	50:  "C_SE_NC_1",
+	51:  "C_BO_NA_1",
+	58:  "C_SC_TA_1",
+	59:  "C_DC_TA_1",
+	60:  "C_RC_TA_1",
+	61:  "C_SE_TA_1",
+	62:  "C_SE_TB_1",
+	63:  "C_SE_TC_1",
+	64:  "C_BO_TA_1",
+	70:  "M_EI_NA_1",
+	100: "C_IC_NA_1",
+	101: "C_CI_NA_1",
+	102: "C_RD_NA_1",
+	103: "C_CS_NA_1",
+	105: "C_RP_NA_1",
+	107: "C_TS_TA_1",
+}
+
+var IEC104CauseNames = map[uint8]string{
+	1:  "periodic",
+	2:  "background",
+	3:  "spontaneous",
+	4:  "initialized",
+	5:  "request",
+	6:  "activation",
+	7:  "activation con",
+	8:  "deactivation",
+	9:  "deactivation con",
+	10: "activation term",
+	11: "remote command",
+	12: "local command",
+	13: "file transfer",
+	20: "interrogated",
+	37: "counter interrogated",
+	44: "unknown type",
+	45: "unknown cause",
+	46: "unknown common address",
+	47: "unknown object address",
+}
+
+var ErrIEC104Invalid = errors.New("not a valid IEC 60870-5-104 APDU")
+
+// IEC104APDU is an IEC 60870-5-104 APDU, the ASDU fields are only set for
+// I-format ones.
+type IEC104APDU struct {
+	Format string `json:"format"`
+	// send and receive sequence numbers, the send one is only set for I-format
+	SendSeq uint16 `json:"send_seq"`
+	RecvSeq uint16 `json:"recv_seq"`
+	// control function of the U-format ones
+	Function uint8 `json:"function"`
+
+	TypeID     uint8 `json:"type_id"`
+	Sequence   bool  `json:"sequence"`
+	Count      uint8 `json:"count"`
+	Cause      uint8 `json:"cause"`
+	Negative   bool  `json:"negative"`
+	Test       bool  `json:"test"`
+	Originator uint8 `json:"originator"`
+	// common address of the station and address of the first object
+	Address       uint16 `json:"address"`
+	ObjectAddress uint32 `json:"object_address"`
+}
+
+// FunctionName returns the name of the control function of U-format APDUs.
+func (a *IEC104APDU) FunctionName() string {
+	if name, found := IEC104UFunctionNames[a.Function]; found {
+		return name
+	}
+	return fmt.Sprintf("function 0x%02x", a.Function)
+}
+
+// TypeName returns the name of the ASDU type identification.
+func (a *IEC104APDU) TypeName() string {
+	if name, found := IEC104TypeNames[a.TypeID]; found {
+		return name
+	}
+	return fmt.Sprintf("type %d", a.TypeID)
+}
+
+// CauseName returns the name of the cause of transmission.
+func (a *IEC104APDU) CauseName() string {
+	if name, found := IEC104CauseNames[a.Cause]; found {
+		return name
+	}
+	return fmt.Sprintf("cause %d", a.Cause)
+}
+
+// IsCommand returns true for the ASDUs in the control direction operating
+// outputs or changing the state of the station.
+func (a *IEC104APDU) IsCommand() bool {
+	return a.Format == IEC104FormatI &&
+		((a.TypeID >= 45 && a.TypeID <= 69) || (a.TypeID >= 100 && a.TypeID <= 107))
+}
+
+// ParseIEC104 parses the IEC 60870-5-104 APDUs of a segment.
+func ParseIEC104(payload []byte) ([]*IEC104APDU, error) {
+	apdus := []*IEC104APDU{}
+	for len(payload) > 0 {
+		if len(payload) < iec104APCISize || payload[0] != iec104Start {
+			return nil, ErrIEC104Invalid
+		}
+
+		size := int(payload[1])
+		if size < 4 || size > iec104MaxAPDU || len(payload) < 2+size {
+			return nil, ErrIEC104Invalid
+		}
+
+		ctrl := payload[2:6]
+		apdu := &IEC104APDU{
+			RecvSeq: (uint16(ctrl[2]) | uint16(ctrl[3])<<8) >> 1,
+		}
+
+		switch {
+		case ctrl[0]&0x01 == 0:
+			apdu.Format = IEC104FormatI
+			apdu.SendSeq = (uint16(ctrl[0]) | uint16(ctrl[1])<<8) >> 1
+
+			asdu := payload[iec104APCISize : 2+size]
+			if len(asdu) < iec104MinASDU {
+				return nil, ErrIEC104Invalid
+			}
+			apdu.TypeID = asdu[0]
+			apdu.Sequence = asdu[1]&iec104Sequence != 0
+			apdu.Count = asdu[1] & iec104CountMask
+			apdu.Cause = asdu[2] & iec104CauseMask
+			apdu.Negative = asdu[2]&iec104Negative != 0
+			apdu.Test = asdu[2]&iec104Test != 0
+			apdu.Originator = asdu[3]
+			apdu.Address = uint16(asdu[4]) | uint16(asdu[5])<<8
+			apdu.ObjectAddress = uint32(asdu[6]) | uint32(asdu[7])<<8 | uint32(asdu[8])<<16
+		case ctrl[0]&iec104FormatMask == 0x01:
+			apdu.Format = IEC104FormatS
+			if size != 4 {
+				return nil, ErrIEC104Invalid
+			}
+		default:
+			apdu.Format = IEC104FormatU
+			apdu.Function = ctrl[0]
+			apdu.RecvSeq = 0
+			if _, found := IEC104UFunctionNames[apdu.Function]; !found || size != 4 {
+				return nil, ErrIEC104Invalid
+			}
+		}
+
+		apdus = append(apdus, apdu)
+		payload = payload[2+size:]
+	}
+	return apdus, nil
+}
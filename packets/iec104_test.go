@@ -0,0 +1,75 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestParseIEC104(t *testing.T) {
+	payload := []byte{
+		// STARTDT act
+		0x68, 0x04, 0x07, 0x00, 0x00, 0x00,
+		// general interrogation of station 1, send 2 receive 3
+		0x68, 0x0e, 0x04, 0x00, 0x06, 0x00, 100, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14,
+		// S-format acknowledging 5
+		0x68, 0x04, 0x01, 0x00, 0x0a, 0x00,
+	}
+
+	apdus, err := ParseIEC104(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(apdus) != 3 {
+		t.Fatalf("expected 3 apdus, got %d", len(apdus))
+	}
+
+	start, gi, ack := apdus[0], apdus[1], apdus[2]
+	if start.Format != IEC104FormatU || start.FunctionName() != "startdt act" || start.IsCommand() {
+		t.Fatalf("unexpected apdu %+v", start)
+	}
+
+	if gi.Format != IEC104FormatI || gi.SendSeq != 2 || gi.RecvSeq != 3 {
+		t.Fatalf("unexpected apdu %+v", gi)
+	} else if gi.TypeName() != "C_IC_NA_1" || gi.CauseName() != "activation" || !gi.IsCommand() {
+		t.Fatalf("unexpected apdu %s %s", gi.TypeName(), gi.CauseName())
+	} else if gi.Count != 1 || gi.Address != 1 || gi.ObjectAddress != 0 {
+		t.Fatalf("unexpected apdu %+v", gi)
+	}
+
+	if ack.Format != IEC104FormatS || ack.RecvSeq != 5 {
+		t.Fatalf("unexpected apdu %+v", ack)
+	}
+}
+
+func TestParseIEC104Monitor(t *testing.T) {
+	// spontaneous single point at 0x010203, negative test flags set
+	payload := []byte{0x68, 0x0e, 0x00, 0x00, 0x00, 0x00, 1, 0x81, 0xc3, 0x00, 0x34, 0x12, 0x03, 0x02, 0x01, 0x01}
+	apdus, err := ParseIEC104(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	apdu := apdus[0]
+	if apdu.TypeName() != "M_SP_NA_1" || apdu.IsCommand() || !apdu.Sequence || apdu.Count != 1 {
+		t.Fatalf("unexpected apdu %+v", apdu)
+	} else if apdu.CauseName() != "spontaneous" || !apdu.Negative || !apdu.Test {
+		t.Fatalf("unexpected apdu %+v", apdu)
+	} else if apdu.Address != 0x1234 || apdu.ObjectAddress != 0x010203 {
+		t.Fatalf("unexpected apdu %+v", apdu)
+	}
+}
+
+func TestParseIEC104Invalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		{0x68, 0x04},
+		// unknown U-format function
+		{0x68, 0x04, 0x0f, 0x00, 0x00, 0x00},
+		// I-format without an ASDU
+		{0x68, 0x04, 0x00, 0x00, 0x00, 0x00},
+		// truncated
+		{0x68, 0x0e, 0x04, 0x00, 0x06, 0x00, 100, 0x01},
+		[]byte("GET / HTTP/1.1\r\n\r\n"),
+	} {
+		if _, err := ParseIEC104(payload); err != ErrIEC104Invalid {
+			t.Fatalf("expected %v for %x, got %v", ErrIEC104Invalid, payload, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package packets
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Knock is a single step of a port knocking sequence.
+type Knock struct {
+	Protocol string `json:"protocol"`
+	Port     int    `json:"port"`
+}
+
+func (k Knock) String() string {
+	if k.Protocol == "tcp" {
+		return strconv.Itoa(k.Port)
+	}
+	return fmt.Sprintf("%s:%d", k.Protocol, k.Port)
+}
+
+func KnockSequenceString(seq []Knock) string {
+	parts := make([]string, len(seq))
+	for i, k := range seq {
+		parts[i] = k.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseKnockSequence parses a comma separated list of ports, each optionally
+// prefixed by the protocol, for instance: 7000,udp:8000,tcp:9000
+func ParseKnockSequence(s string) ([]Knock, error) {
+	seq := make([]Knock, 0)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		k := Knock{Protocol: "tcp"}
+		if idx := strings.Index(part, ":"); idx != -1 {
+			k.Protocol = strings.ToLower(part[:idx])
+			part = part[idx+1:]
+		}
+
+		if k.Protocol != "tcp" && k.Protocol != "udp" {
+			return nil, fmt.Errorf("unsupported knock protocol '%s'", k.Protocol)
+		}
+
+		port, err := strconv.Atoi(part)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid knock port '%s'", part)
+		}
+		k.Port = port
+
+		seq = append(seq, k)
+	}
+
+	if len(seq) == 0 {
+		return nil, fmt.Errorf("empty knock sequence")
+	}
+	return seq, nil
+}
+
+// NewKnockPacket creates a TCP SYN or an empty UDP datagram for the given knock.
+func NewKnockPacket(from net.IP, fromHW net.HardwareAddr, to net.IP, toHW net.HardwareAddr, srcPort int, k Knock) (error, []byte) {
+	if k.Protocol == "tcp" {
+		return NewTCPSyn(from, fromHW, to, toHW, srcPort, k.Port)
+	}
+
+	return newUDPPacket(from, fromHW, to, toHW, srcPort, k.Port, nil)
+}
@@ -0,0 +1,71 @@
+package packets
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestParseKnockSequence(t *testing.T) {
+	seq, err := ParseKnockSequence("7000, udp:8000,TCP:9000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Knock{
+		{Protocol: "tcp", Port: 7000},
+		{Protocol: "udp", Port: 8000},
+		{Protocol: "tcp", Port: 9000},
+	}
+	if !reflect.DeepEqual(seq, expected) {
+		t.Fatalf("expected %v, got %v", expected, seq)
+	}
+
+	if s := KnockSequenceString(seq); s != "7000,udp:8000,9000" {
+		t.Fatalf("unexpected sequence string '%s'", s)
+	}
+}
+
+func TestParseKnockSequenceErrors(t *testing.T) {
+	for _, s := range []string{"", " , ", "icmp:1", "tcp:0", "70000", "udp:abc"} {
+		if _, err := ParseKnockSequence(s); err == nil {
+			t.Fatalf("expected error for '%s'", s)
+		}
+	}
+}
+
+func TestNewKnockPacket(t *testing.T) {
+	from := net.ParseIP("192.168.1.2")
+	fromHW, _ := net.ParseMAC("01:23:45:67:89:ab")
+	to := net.ParseIP("192.168.1.3")
+	toHW, _ := net.ParseMAC("ab:89:67:45:23:01")
+
+	err, raw := NewKnockPacket(from, fromHW, to, toHW, 1234, Knock{Protocol: "udp", Port: 8000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok {
+		t.Fatalf("expected an udp layer")
+	} else if udp.SrcPort != 1234 || udp.DstPort != 8000 {
+		t.Fatalf("unexpected ports %d -> %d", udp.SrcPort, udp.DstPort)
+	}
+
+	err, raw = NewKnockPacket(from, fromHW, to, toHW, 1234, Knock{Protocol: "tcp", Port: 7000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt = gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	tcp, ok := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP)
+	if !ok {
+		t.Fatalf("expected a tcp layer")
+	} else if !tcp.SYN || tcp.DstPort != 7000 {
+		t.Fatalf("expected a SYN to port 7000, got %v", tcp)
+	}
+}
@@ -1,9 +1,11 @@
 package packets
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"encoding/asn1"
@@ -11,11 +13,23 @@ import (
 )
 
 const (
+	Krb5Port = 88
+
 	Krb5AsRequestType         = 10
+	Krb5AsReplyType           = 11
 	Krb5Krb5PrincipalNameType = 1
 	Krb5CryptDesCbcMd4        = 2
 	Krb5CryptDescCbcMd5       = 3
+	Krb5CryptAes128           = 17
+	Krb5CryptAes256           = 18
 	Krb5CryptRc4Hmac          = 23
+
+	Krb5PaEncTimestamp = 2
+
+	// size of the HMAC of the AES encryption types
+	krb5AesChecksumSize = 12
+	// size of the HMAC of RC4-HMAC
+	krb5Rc4ChecksumSize = 16
 )
 
 var (
@@ -24,6 +38,7 @@ var (
 	ErrNoCipher = errors.New("No encryption type or cipher found")
 
 	Krb5AsReqParam = "application,explicit,tag:10"
+	Krb5AsRepParam = "application,explicit,tag:11"
 )
 
 type Krb5PrincipalName struct {
@@ -76,6 +91,124 @@ type Krb5Request struct {
 	ReqBody    Krb5ReqBody  `asn1:"explicit,tag:4"`
 }
 
+// Krb5Reply is the reply of the KDC to an AS-REQ, its encrypted part is
+// encrypted with the key of the client.
+type Krb5Reply struct {
+	Pvno       int               `asn1:"explicit,tag:0"`
+	MsgType    int               `asn1:"explicit,tag:1"`
+	Krb5PnData []Krb5PnData      `asn1:"optional,explicit,tag:2"`
+	Crealm     string            `asn1:"general,explicit,tag:3"`
+	Cname      Krb5PrincipalName `asn1:"explicit,tag:4"`
+	Ticket     asn1.RawValue     `asn1:"explicit,tag:5"`
+	EncPart    Krb5EncryptedData `asn1:"explicit,tag:6"`
+}
+
+// Krb5MessageType returns the application tag of a Kerberos message, 0 if
+// the payload is not one.
+func Krb5MessageType(payload []byte) int {
+	if len(payload) == 0 || payload[0]&0xe0 != 0x60 {
+		return 0
+	}
+	return int(payload[0] & 0x1f)
+}
+
+// Krb5FromTCP strips the record mark of Kerberos messages over TCP, messages
+// split across more segments are not supported.
+func Krb5FromTCP(payload []byte) ([]byte, bool) {
+	if len(payload) < 5 {
+		return nil, false
+	}
+	size := binary.BigEndian.Uint32(payload)
+	if size == 0 || uint64(size) != uint64(len(payload)-4) {
+		return nil, false
+	}
+	return payload[4:], true
+}
+
+func ParseKrb5AsRequest(payload []byte) (*Krb5Request, error) {
+	req := &Krb5Request{}
+	if _, err := asn1.UnmarshalWithParams(payload, req, Krb5AsReqParam); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func ParseKrb5AsReply(payload []byte) (*Krb5Reply, error) {
+	rep := &Krb5Reply{}
+	if _, err := asn1.UnmarshalWithParams(payload, rep, Krb5AsRepParam); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+func (n Krb5PrincipalName) String() string {
+	return strings.Join(n.NameString, "/")
+}
+
+// PreAuth returns the encrypted timestamp of the request, if any.
+func (kdc Krb5Request) PreAuth() (*Krb5EncryptedData, error) {
+	for _, pn := range kdc.Krb5PnData {
+		if pn.Krb5PnDataType == Krb5PaEncTimestamp {
+			enc, err := pn.getParsedValue()
+			if err != nil {
+				return nil, ErrReqData
+			}
+			return &enc, nil
+		}
+	}
+	return nil, ErrNoCipher
+}
+
+// Hashcat returns the encrypted timestamp of the request in the format of
+// hashcat, mode 7500 for RC4-HMAC and 19800/19900 for AES, also supported
+// by john.
+func (kdc Krb5Request) Hashcat() (string, error) {
+	enc, err := kdc.PreAuth()
+	if err != nil {
+		return "", err
+	}
+
+	user := kdc.ReqBody.Cname.String()
+	realm := kdc.ReqBody.Realm
+	switch enc.Etype {
+	case Krb5CryptRc4Hmac:
+		if len(enc.Cipher) <= krb5Rc4ChecksumSize {
+			return "", ErrNoCipher
+		}
+		// encrypted timestamp followed by its checksum
+		return fmt.Sprintf("$krb5pa$%d$%s$%s$$%x%x", enc.Etype, user, realm,
+			enc.Cipher[krb5Rc4ChecksumSize:], enc.Cipher[:krb5Rc4ChecksumSize]), nil
+	case Krb5CryptAes128, Krb5CryptAes256:
+		return fmt.Sprintf("$krb5pa$%d$%s$%s$%x", enc.Etype, user, realm, enc.Cipher), nil
+	}
+
+	return "", ErrNoCrypt
+}
+
+// Hashcat returns the encrypted part of the reply in the format of hashcat,
+// mode 18200 for RC4-HMAC and 32100/32200 for AES.
+func (rep Krb5Reply) Hashcat() (string, error) {
+	user := rep.Cname.String()
+	cipher := rep.EncPart.Cipher
+	switch rep.EncPart.Etype {
+	case Krb5CryptRc4Hmac:
+		if len(cipher) <= krb5Rc4ChecksumSize {
+			return "", ErrNoCipher
+		}
+		return fmt.Sprintf("$krb5asrep$%d$%s@%s:%x$%x", rep.EncPart.Etype, user, rep.Crealm,
+			cipher[:krb5Rc4ChecksumSize], cipher[krb5Rc4ChecksumSize:]), nil
+	case Krb5CryptAes128, Krb5CryptAes256:
+		if len(cipher) <= krb5AesChecksumSize {
+			return "", ErrNoCipher
+		}
+		split := len(cipher) - krb5AesChecksumSize
+		return fmt.Sprintf("$krb5asrep$%d$%s$%s$%x$%x", rep.EncPart.Etype, user, rep.Crealm,
+			cipher[split:], cipher[:split]), nil
+	}
+
+	return "", ErrNoCrypt
+}
+
 func (kdc Krb5Request) String() (string, error) {
 	var eType, cipher string
 
@@ -1,9 +1,12 @@
 package packets
 
 import (
+	"bytes"
 	"encoding/asn1"
+	"encoding/binary"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -205,4 +208,143 @@ func TestKrb5Request(t *testing.T) {
 }
 
 // TODO: add test for func (kdc Krb5Request) String()
-// TODO: add test for func (pd Krb5PnData) getParsedValue()
+
+func krb5TestRequest(t *testing.T, etype int, cipher []byte) []byte {
+	req := Krb5Request{
+		Pvno:    5,
+		MsgType: Krb5AsRequestType,
+		ReqBody: Krb5ReqBody{
+			KDCOptions: asn1.BitString{Bytes: []byte{0x40, 0x81, 0x00, 0x10}, BitLength: 32},
+			Cname:      Krb5PrincipalName{NameType: Krb5Krb5PrincipalNameType, NameString: []string{"alice"}},
+			Realm:      "CORP.LOCAL",
+			Sname:      Krb5PrincipalName{NameType: 2, NameString: []string{"krbtgt", "CORP.LOCAL"}},
+			Nonce:      12345,
+			Etype:      []int{etype},
+		},
+	}
+
+	if cipher != nil {
+		value, err := asn1.Marshal(Krb5EncryptedData{Etype: etype, Cipher: cipher})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Krb5PnData = []Krb5PnData{{Krb5PnDataType: Krb5PaEncTimestamp, Krb5PnDataValue: value}}
+	}
+
+	raw, err := asn1.MarshalWithParams(req, Krb5AsReqParam)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestKrb5AsRequestHashcat(t *testing.T) {
+	checksum := bytes.Repeat([]byte{0xaa}, 16)
+	timestamp := bytes.Repeat([]byte{0xbb}, 36)
+
+	raw := krb5TestRequest(t, Krb5CryptRc4Hmac, append(append([]byte{}, checksum...), timestamp...))
+	if Krb5MessageType(raw) != Krb5AsRequestType {
+		t.Fatalf("unexpected message type %d", Krb5MessageType(raw))
+	}
+
+	req, err := ParseKrb5AsRequest(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "$krb5pa$23$alice$CORP.LOCAL$$" + strings.Repeat("bb", 36) + strings.Repeat("aa", 16)
+	if hash, err := req.Hashcat(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+
+	raw = krb5TestRequest(t, Krb5CryptAes256, []byte{0x01, 0x02, 0x03})
+	if req, err = ParseKrb5AsRequest(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hash, err := req.Hashcat(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hash != "$krb5pa$18$alice$CORP.LOCAL$010203" {
+		t.Fatalf("unexpected hash %s", hash)
+	}
+
+	// no pre-authentication
+	raw = krb5TestRequest(t, Krb5CryptRc4Hmac, nil)
+	if req, err = ParseKrb5AsRequest(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := req.PreAuth(); err != ErrNoCipher {
+		t.Fatalf("expected %v, got %v", ErrNoCipher, err)
+	}
+}
+
+func TestKrb5AsReplyHashcat(t *testing.T) {
+	ticket, err := asn1.MarshalWithParams(Krb5Ticket{
+		TktVno: 5,
+		Realm:  "CORP.LOCAL",
+		Sname:  Krb5PrincipalName{NameType: 2, NameString: []string{"krbtgt", "CORP.LOCAL"}},
+		EncPart: Krb5EncryptedData{
+			Etype:  Krb5CryptAes256,
+			Cipher: []byte{0x00},
+		},
+	}, "application,tag:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cipher := append(bytes.Repeat([]byte{0xcc}, 20), bytes.Repeat([]byte{0xdd}, 16)...)
+	rep := Krb5Reply{
+		Pvno:    5,
+		MsgType: Krb5AsReplyType,
+		Crealm:  "CORP.LOCAL",
+		Cname:   Krb5PrincipalName{NameType: Krb5Krb5PrincipalNameType, NameString: []string{"bob"}},
+		// marshalling ignores the explicit tag of raw values
+		Ticket:  asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 5, IsCompound: true, Bytes: ticket},
+		EncPart: Krb5EncryptedData{Etype: Krb5CryptRc4Hmac, Cipher: cipher},
+	}
+
+	raw, err := asn1.MarshalWithParams(rep, Krb5AsRepParam)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if Krb5MessageType(raw) != Krb5AsReplyType {
+		t.Fatalf("unexpected message type %d", Krb5MessageType(raw))
+	}
+
+	parsed, err := ParseKrb5AsReply(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if parsed.Cname.String() != "bob" || parsed.Crealm != "CORP.LOCAL" {
+		t.Fatalf("unexpected client %s@%s", parsed.Cname, parsed.Crealm)
+	}
+
+	expected := "$krb5asrep$23$bob@CORP.LOCAL:" + strings.Repeat("cc", 16) + "$" + strings.Repeat("cc", 4) + strings.Repeat("dd", 16)
+	if hash, err := parsed.Hashcat(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+
+	parsed.EncPart.Etype = Krb5CryptAes128
+	expected = "$krb5asrep$17$bob$CORP.LOCAL$" + strings.Repeat("dd", 12) + "$" + strings.Repeat("cc", 20) + strings.Repeat("dd", 4)
+	if hash, err := parsed.Hashcat(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+}
+
+func TestKrb5FromTCP(t *testing.T) {
+	msg := []byte{0x6a, 0x01, 0x00}
+	framed := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(framed, uint32(len(msg)))
+	framed = append(framed, msg...)
+
+	if payload, ok := Krb5FromTCP(framed); !ok || !bytes.Equal(payload, msg) {
+		t.Fatalf("unexpected payload %x", payload)
+	} else if _, ok := Krb5FromTCP(framed[:5]); ok {
+		t.Fatal("expected truncated message to be rejected")
+	} else if _, ok := Krb5FromTCP(msg); ok {
+		t.Fatal("expected message without record mark to be rejected")
+	}
+}
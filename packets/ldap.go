@@ -0,0 +1,284 @@
+package packets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	LDAPPort              = 389
+	LDAPGlobalCatalogPort = 3268
+
+	// application tags of the protocol operations
+	LDAPBindRequest  = 0
+	LDAPBindResponse = 1
+
+	// context specific tags of the bind authentication choice
+	LDAPAuthSimple = 0
+	LDAPAuthSASL   = 3
+
+	LDAPResultSuccess            = 0
+	LDAPResultSASLBindInProgress = 14
+)
+
+var LDAPResultNames = map[int]string{
+	0:  "success",
+	7:  "authMethodNotSupported",
+	8:  "strongerAuthRequired",
+	14: "saslBindInProgress",
+	32: "noSuchObject",
+	34: "invalidDNSyntax",
+	48: "inappropriateAuthentication",
+	49: "invalidCredentials",
+	50: "insufficientAccessRights",
+	51: "busy",
+	52: "unavailable",
+	53: "unwillingToPerform",
+}
+
+// SASL mechanisms that can't negotiate signing or sealing
+var ldapUnsignedMechanisms = map[string]bool{
+	"PLAIN":     true,
+	"LOGIN":     true,
+	"EXTERNAL":  true,
+	"ANONYMOUS": true,
+}
+
+var (
+	ErrLDAPInvalid = errors.New("not a valid LDAP message")
+	ErrLDAPNotBind = errors.New("not an LDAP bind")
+)
+
+// LDAPBind is a bind request, either simple or SASL.
+type LDAPBind struct {
+	MessageID int64  `json:"message_id"`
+	Version   int    `json:"version"`
+	DN        string `json:"dn"`
+	Simple    bool   `json:"simple"`
+	Password  string `json:"password,omitempty"`
+	Mechanism string `json:"mechanism,omitempty"`
+}
+
+// LDAPBindResult is the response of the server to a bind request.
+type LDAPBindResult struct {
+	MessageID  int64  `json:"message_id"`
+	ResultCode int    `json:"result_code"`
+	MatchedDN  string `json:"matched_dn,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// a BER element, LDAP implementations (Active Directory above all) use non
+// minimal lengths that encoding/asn1 would reject
+type ldapElement struct {
+	class       int
+	constructed bool
+	tag         int
+	data        []byte
+}
+
+const (
+	ldapClassUniversal   = 0
+	ldapClassApplication = 1
+	ldapClassContext     = 2
+
+	ldapTagInteger     = 2
+	ldapTagOctetString = 4
+	ldapTagEnumerated  = 10
+	ldapTagSequence    = 16
+)
+
+func ldapNext(raw []byte) (*ldapElement, []byte, error) {
+	if len(raw) < 2 {
+		return nil, nil, ErrLDAPInvalid
+	}
+
+	elem := &ldapElement{
+		class:       int(raw[0] >> 6),
+		constructed: raw[0]&0x20 != 0,
+		tag:         int(raw[0] & 0x1f),
+	}
+	if elem.tag == 0x1f {
+		// high tag numbers are not used by LDAP
+		return nil, nil, ErrLDAPInvalid
+	}
+
+	size, off := int(raw[1]), 2
+	if size&0x80 != 0 {
+		n := size & 0x7f
+		// no indefinite lengths and nothing bigger than 4 bytes
+		if n == 0 || n > 4 || len(raw) < 2+n {
+			return nil, nil, ErrLDAPInvalid
+		}
+		size = 0
+		for _, b := range raw[2 : 2+n] {
+			size = size<<8 | int(b)
+		}
+		off += n
+	}
+
+	if size < 0 || len(raw)-off < size {
+		return nil, nil, ErrLDAPInvalid
+	}
+	elem.data = raw[off : off+size]
+
+	return elem, raw[off+size:], nil
+}
+
+func ldapExpect(raw []byte, class int, tag int) (*ldapElement, []byte, error) {
+	elem, rest, err := ldapNext(raw)
+	if err != nil {
+		return nil, nil, err
+	} else if elem.class != class || elem.tag != tag {
+		return nil, nil, ErrLDAPInvalid
+	}
+	return elem, rest, nil
+}
+
+func (e *ldapElement) int() (int64, error) {
+	if len(e.data) == 0 || len(e.data) > 8 || e.constructed {
+		return 0, ErrLDAPInvalid
+	}
+	// sign extension
+	v := int64(int8(e.data[0]))
+	for _, b := range e.data[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+func ldapInt(raw []byte, tag int) (int64, []byte, error) {
+	elem, rest, err := ldapExpect(raw, ldapClassUniversal, tag)
+	if err != nil {
+		return 0, nil, err
+	}
+	v, err := elem.int()
+	return v, rest, err
+}
+
+func ldapString(raw []byte) (string, []byte, error) {
+	elem, rest, err := ldapExpect(raw, ldapClassUniversal, ldapTagOctetString)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(elem.data), rest, nil
+}
+
+// Anonymous returns true for simple binds without a password, which are
+// either anonymous or unauthenticated.
+func (b *LDAPBind) Anonymous() bool {
+	return b.Simple && b.Password == ""
+}
+
+// Unsigned returns true if the bind can't be protected by signing, simple
+// binds and some SASL mechanisms.
+func (b *LDAPBind) Unsigned() bool {
+	return b.Simple || ldapUnsignedMechanisms[b.Mechanism]
+}
+
+func (b *LDAPBind) Method() string {
+	if b.Simple {
+		return "simple"
+	}
+	return "sasl/" + b.Mechanism
+}
+
+func (r *LDAPBindResult) Success() bool {
+	return r.ResultCode == LDAPResultSuccess
+}
+
+func (r *LDAPBindResult) Result() string {
+	if name, found := LDAPResultNames[r.ResultCode]; found {
+		return name
+	}
+	return fmt.Sprintf("result %d", r.ResultCode)
+}
+
+func IsLDAPPort(port int) bool {
+	return port == LDAPPort || port == LDAPGlobalCatalogPort
+}
+
+func parseLDAPOperation(payload []byte, tag int) (int64, []byte, error) {
+	msg, _, err := ldapExpect(payload, ldapClassUniversal, ldapTagSequence)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	id, data, err := ldapInt(msg.data, ldapTagInteger)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	op, _, err := ldapNext(data)
+	if err != nil {
+		return 0, nil, err
+	} else if op.class != ldapClassApplication || !op.constructed {
+		return 0, nil, ErrLDAPInvalid
+	} else if op.tag != tag {
+		return 0, nil, ErrLDAPNotBind
+	}
+
+	return id, op.data, nil
+}
+
+// ParseLDAPBind parses a bind request from the payload of a TCP segment.
+func ParseLDAPBind(payload []byte) (*LDAPBind, error) {
+	id, data, err := parseLDAPOperation(payload, LDAPBindRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	bind := &LDAPBind{MessageID: id}
+	version := int64(0)
+	var auth *ldapElement
+
+	if version, data, err = ldapInt(data, ldapTagInteger); err != nil {
+		return nil, err
+	} else if version < 2 || version > 3 {
+		return nil, ErrLDAPInvalid
+	} else if bind.DN, data, err = ldapString(data); err != nil {
+		return nil, err
+	} else if auth, _, err = ldapNext(data); err != nil {
+		return nil, err
+	} else if auth.class != ldapClassContext {
+		return nil, ErrLDAPInvalid
+	}
+
+	bind.Version = int(version)
+	switch auth.tag {
+	case LDAPAuthSimple:
+		bind.Simple = true
+		bind.Password = string(auth.data)
+	case LDAPAuthSASL:
+		mechanism := ""
+		if mechanism, _, err = ldapString(auth.data); err != nil {
+			return nil, err
+		}
+		bind.Mechanism = strings.ToUpper(mechanism)
+	default:
+		return nil, ErrLDAPInvalid
+	}
+
+	return bind, nil
+}
+
+// ParseLDAPBindResult parses a bind response from the payload of a TCP segment.
+func ParseLDAPBindResult(payload []byte) (*LDAPBindResult, error) {
+	id, data, err := parseLDAPOperation(payload, LDAPBindResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &LDAPBindResult{MessageID: id}
+	code := int64(0)
+	if code, data, err = ldapInt(data, ldapTagEnumerated); err != nil {
+		return nil, err
+	} else if res.MatchedDN, data, err = ldapString(data); err != nil {
+		return nil, err
+	} else if res.Message, _, err = ldapString(data); err != nil {
+		return nil, err
+	}
+	res.ResultCode = int(code)
+
+	return res, nil
+}
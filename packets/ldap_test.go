@@ -0,0 +1,80 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestParseLDAPSimpleBind(t *testing.T) {
+	// v3 simple bind of cn=admin,dc=corp,dc=local with password S3cret!
+	payload := unhex(t, "302c02010160270201030419636e3d61646d696e2c64633d636f72702c64633d6c6f63616c80075333"+
+		"6372657421")
+
+	bind, err := ParseLDAPBind(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if bind.MessageID != 1 || bind.Version != 3 {
+		t.Fatalf("unexpected bind %+v", bind)
+	} else if bind.DN != "cn=admin,dc=corp,dc=local" || bind.Password != "S3cret!" {
+		t.Fatalf("unexpected credentials %s/%s", bind.DN, bind.Password)
+	} else if !bind.Simple || bind.Anonymous() || !bind.Unsigned() || bind.Method() != "simple" {
+		t.Fatalf("unexpected bind type %+v", bind)
+	}
+}
+
+func TestParseLDAPSASLBind(t *testing.T) {
+	// GSS-SPNEGO bind with the non minimal lengths used by Active Directory
+	payload := unhex(t, "3084000000300284000000010260840000002302840000000103048400000000a38400000010040a"+
+		"4753532d53504e45474f04026000")
+
+	bind, err := ParseLDAPBind(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if bind.MessageID != 2 || bind.DN != "" {
+		t.Fatalf("unexpected bind %+v", bind)
+	} else if bind.Simple || bind.Mechanism != "GSS-SPNEGO" || bind.Method() != "sasl/GSS-SPNEGO" {
+		t.Fatalf("unexpected mechanism %+v", bind)
+	} else if bind.Anonymous() || bind.Unsigned() {
+		t.Fatalf("unexpected flags for %+v", bind)
+	}
+}
+
+func TestParseLDAPBindResult(t *testing.T) {
+	payload := unhex(t, "302c02010161270a01310400042038303039303330383a204c6461704572723a20445349442d3043"+
+		"303930343241")
+
+	res, err := ParseLDAPBindResult(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if res.MessageID != 1 || res.ResultCode != 49 || res.Success() {
+		t.Fatalf("unexpected result %+v", res)
+	} else if res.Result() != "invalidCredentials" {
+		t.Fatalf("unexpected result name %s", res.Result())
+	} else if res.Message != "80090308: LdapErr: DSID-0C09042A" {
+		t.Fatalf("unexpected message '%s'", res.Message)
+	}
+
+	if _, err := ParseLDAPBind(payload); err != ErrLDAPNotBind {
+		t.Fatalf("expected %v, got %v", ErrLDAPNotBind, err)
+	}
+}
+
+func TestParseLDAPInvalid(t *testing.T) {
+	// search request
+	search := unhex(t, "300e0201036309040764633d636f7270")
+	if _, err := ParseLDAPBind(search); err != ErrLDAPNotBind {
+		t.Fatalf("expected %v, got %v", ErrLDAPNotBind, err)
+	}
+
+	for _, payload := range [][]byte{
+		{},
+		[]byte("GET / HTTP/1.1\r\n"),
+		// truncated
+		search[:10],
+		// indefinite length
+		{0x30, 0x80, 0x02, 0x01, 0x01, 0x00, 0x00},
+	} {
+		if _, err := ParseLDAPBind(payload); err != ErrLDAPInvalid {
+			t.Fatalf("expected %v for %x, got %v", ErrLDAPInvalid, payload, err)
+		}
+	}
+}
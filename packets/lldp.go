@@ -0,0 +1,148 @@
+package packets
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	LLDPMulticastMAC = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+)
+
+// LLDP-MED values
+const (
+	lldpMedCapabilities  = 1
+	lldpMedNetworkPolicy = 2
+	lldpMedClassIII      = 3
+	lldpMedNetworkDevice = 4
+	lldpMedAppVoice      = 1
+	lldp8021PortVLAN     = 1
+)
+
+func lldpValue(t layers.LLDPTLVType, value []byte) layers.LinkLayerDiscoveryValue {
+	return layers.LinkLayerDiscoveryValue{
+		Type:   t,
+		Length: uint16(len(value)),
+		Value:  value,
+	}
+}
+
+func lldpOrgValue(oui layers.IEEEOUI, subtype byte, info []byte) layers.LinkLayerDiscoveryValue {
+	value := []byte{byte(oui >> 16), byte(oui >> 8), byte(oui), subtype}
+	return lldpValue(layers.LLDPTLVOrgSpecific, append(value, info...))
+}
+
+func lldpCapabilities(id *DiscoveryIdentity) uint16 {
+	caps := uint16(0)
+	if id.Has(DiscoveryCapSwitch) {
+		caps |= layers.LLDPCapsBridge
+	}
+	if id.Has(DiscoveryCapAccessPoint) {
+		caps |= layers.LLDPCapsWLANAP
+	}
+	if id.Has(DiscoveryCapRouter) {
+		caps |= layers.LLDPCapsRouter
+	}
+	if id.Has(DiscoveryCapPhone) {
+		caps |= layers.LLDPCapsPhone
+	}
+	if id.Has(DiscoveryCapHost) {
+		caps |= layers.LLDPCapsStationOnly
+	}
+	return caps
+}
+
+// voice network policy, if vlan is zero the policy is flagged as unknown
+// which is what endpoints send to ask for one
+func lldpVoicePolicy(vlan uint16) layers.LinkLayerDiscoveryValue {
+	policy := uint32(0)
+	if vlan == 0 {
+		policy |= 1 << 23
+	} else {
+		// tagged, priority 5, dscp 46 (EF)
+		policy |= 1<<22 | uint32(vlan&0x0fff)<<9 | 5<<6 | 46
+	}
+	return lldpOrgValue(layers.IEEEOUIMedia, lldpMedNetworkPolicy,
+		[]byte{lldpMedAppVoice, byte(policy >> 16), byte(policy >> 8), byte(policy)})
+}
+
+func NewLLDPAnnounce(id *DiscoveryIdentity) (layers.Ethernet, layers.LinkLayerDiscovery) {
+	eth := layers.Ethernet{
+		SrcMAC:       id.HW,
+		DstMAC:       LLDPMulticastMAC,
+		EthernetType: layers.EthernetTypeLinkLayerDiscovery,
+	}
+
+	lldp := layers.LinkLayerDiscovery{
+		ChassisID: layers.LLDPChassisID{
+			Subtype: layers.LLDPChassisIDSubTypeMACAddr,
+			ID:      id.HW,
+		},
+		PortID: layers.LLDPPortID{
+			Subtype: layers.LLDPPortIDSubtypeIfaceName,
+			ID:      []byte(id.Port),
+		},
+		TTL:    id.TTL,
+		Values: make([]layers.LinkLayerDiscoveryValue, 0),
+	}
+
+	if id.Port != "" {
+		lldp.Values = append(lldp.Values, lldpValue(layers.LLDPTLVPortDescription, []byte(id.Port)))
+	}
+	if id.Name != "" {
+		lldp.Values = append(lldp.Values, lldpValue(layers.LLDPTLVSysName, []byte(id.Name)))
+	}
+	if id.Description != "" {
+		lldp.Values = append(lldp.Values, lldpValue(layers.LLDPTLVSysDescription, []byte(id.Description)))
+	}
+
+	caps := make([]byte, 4)
+	binary.BigEndian.PutUint16(caps[0:], lldpCapabilities(id))
+	binary.BigEndian.PutUint16(caps[2:], lldpCapabilities(id))
+	lldp.Values = append(lldp.Values, lldpValue(layers.LLDPTLVSysCapabilities, caps))
+
+	if ip4 := id.Address.To4(); ip4 != nil {
+		// address length, IPv4 subtype, address, ifIndex subtype, interface 0, no OID
+		addr := append([]byte{5, 1}, ip4...)
+		addr = append(addr, 2, 0, 0, 0, 0, 0)
+		lldp.Values = append(lldp.Values, lldpValue(layers.LLDPTLVMgmtAddress, addr))
+	}
+
+	if id.NativeVLAN != 0 {
+		pvid := make([]byte, 2)
+		binary.BigEndian.PutUint16(pvid, id.NativeVLAN)
+		lldp.Values = append(lldp.Values, lldpOrgValue(layers.IEEEOUI8021, lldp8021PortVLAN, pvid))
+	}
+
+	// LLDP-MED: endpoints announce themselves as class III and ask for a voice
+	// policy, network devices announce the voice VLAN
+	if id.VoiceQuery {
+		lldp.Values = append(lldp.Values,
+			lldpOrgValue(layers.IEEEOUIMedia, lldpMedCapabilities, []byte{0x00, 0x03, lldpMedClassIII}),
+			lldpVoicePolicy(0))
+	} else if id.VoiceVLAN != 0 {
+		lldp.Values = append(lldp.Values,
+			lldpOrgValue(layers.IEEEOUIMedia, lldpMedCapabilities, []byte{0x00, 0x03, lldpMedNetworkDevice}),
+			lldpVoicePolicy(id.VoiceVLAN))
+	}
+
+	return eth, lldp
+}
+
+func NewLLDPAnnouncePacket(id *DiscoveryIdentity) (error, []byte) {
+	eth, lldp := NewLLDPAnnounce(id)
+	return Serialize(&eth, &lldp)
+}
+
+// LLDPVoiceVLAN returns the voice VLAN announced with LLDP-MED by a network device, if any.
+func LLDPVoiceVLAN(info *layers.LinkLayerDiscoveryInfo) (uint16, bool) {
+	if media, err := info.DecodeMedia(); err == nil {
+		policy := media.NetworkPolicy
+		if policy.ApplicationType == layers.LLDPAppTypeVoice && policy.Defined && policy.VLANId != 0 {
+			return policy.VLANId, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,24 @@
+package packets
+
+import (
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const LLMNRPort = 5355
+
+var (
+	LLMNRDestIP  = net.ParseIP("224.0.0.252")
+	LLMNRDestIP6 = net.ParseIP("ff02::1:3")
+)
+
+// ParseLLMNR decodes a LLMNR message, which has the same format of DNS.
+func ParseLLMNR(payload []byte) (*layers.DNS, error) {
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil, err
+	}
+	return dns, nil
+}
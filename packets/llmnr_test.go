@@ -0,0 +1,24 @@
+package packets
+
+import (
+	"testing"
+
+	"github.com/google/gopacket/layers"
+)
+
+func TestParseLLMNR(t *testing.T) {
+	query := []byte{0x12, 0x34, 0x00, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, 4, 'w', 'p', 'a', 'd', 0, 0x00, 0x01, 0x00, 0x01}
+
+	dns, err := ParseLLMNR(query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if dns.QR || len(dns.Questions) != 1 {
+		t.Fatalf("unexpected message %+v", dns)
+	} else if string(dns.Questions[0].Name) != "wpad" || dns.Questions[0].Type != layers.DNSTypeA {
+		t.Fatalf("unexpected question %+v", dns.Questions[0])
+	}
+
+	if _, err = ParseLLMNR(query[:5]); err == nil {
+		t.Fatal("expected an error for a truncated message")
+	}
+}
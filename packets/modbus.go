@@ -0,0 +1,234 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	ModbusPort = 502
+
+	modbusHeaderSize    = 7
+	modbusExceptionFlag = 0x80
+	modbusMaxADUSize    = 260
+)
+
+const (
+	ModbusReadCoils              = 0x01
+	ModbusReadDiscreteInputs     = 0x02
+	ModbusReadHoldingRegisters   = 0x03
+	ModbusReadInputRegisters     = 0x04
+	ModbusWriteSingleCoil        = 0x05
+	ModbusWriteSingleRegister    = 0x06
+	ModbusDiagnostics            = 0x08
+	ModbusWriteMultipleCoils     = 0x0f
+	ModbusWriteMultipleRegisters = 0x10
+	ModbusReportServerID         = 0x11
+	ModbusMaskWriteRegister      = 0x16
+	ModbusReadWriteRegisters     = 0x17
+	ModbusEncapsulatedInterface  = 0x2b
+)
+
+var ModbusFunctionNames = map[uint8]string{
+	ModbusReadCoils:              "read coils",
+	ModbusReadDiscreteInputs:     "read discrete inputs",
+	ModbusReadHoldingRegisters:   "read holding registers",
+	ModbusReadInputRegisters:     "read input registers",
+	ModbusWriteSingleCoil:        "write single coil",
+	ModbusWriteSingleRegister:    "write single register",
+	ModbusDiagnostics:            "diagnostics",
+	ModbusWriteMultipleCoils:     "write multiple coils",
+	ModbusWriteMultipleRegisters: "write multiple registers",
+	ModbusReportServerID:         "report server id",
+	ModbusMaskWriteRegister:      "mask write register",
+	ModbusReadWriteRegisters:     "read/write multiple registers",
+	ModbusEncapsulatedInterface:  "encapsulated interface",
+}
+
+var ModbusExceptionNames = map[uint8]string{
+	0x01: "illegal function",
+	0x02: "illegal data address",
+	0x03: "illegal data value",
+	0x04: "server device failure",
+	0x05: "acknowledge",
+	0x06: "server device busy",
+	0x08: "memory parity error",
+	0x0a: "gateway path unavailable",
+	0x0b: "gateway target device failed to respond",
+}
+
+var ErrModbusInvalid = errors.New("not a valid Modbus/TCP message")
+
+// ModbusMessage is a Modbus/TCP request or response.
+type ModbusMessage struct {
+	TransactionID uint16 `json:"transaction_id"`
+	UnitID        uint8  `json:"unit_id"`
+	Function      uint8  `json:"function"`
+	// exception code of the error responses
+	Exception uint8 `json:"exception"`
+	// first register or coil read or written, and how many of them
+	Address  uint16 `json:"address"`
+	Quantity uint16 `json:"quantity"`
+	// registers written by a request or returned by a read response, coils
+	// are 0 or 1
+	Values []uint16 `json:"values"`
+}
+
+// FunctionName returns the name of the function code.
+func (m *ModbusMessage) FunctionName() string {
+	if name, found := ModbusFunctionNames[m.Function]; found {
+		return name
+	}
+	return fmt.Sprintf("function 0x%02x", m.Function)
+}
+
+// ExceptionName returns the name of the exception code of an error response.
+func (m *ModbusMessage) ExceptionName() string {
+	if name, found := ModbusExceptionNames[m.Exception]; found {
+		return name
+	}
+	return fmt.Sprintf("exception 0x%02x", m.Exception)
+}
+
+// IsWrite returns true if the function changes the state of the device.
+func (m *ModbusMessage) IsWrite() bool {
+	switch m.Function {
+	case ModbusWriteSingleCoil, ModbusWriteSingleRegister, ModbusWriteMultipleCoils,
+		ModbusWriteMultipleRegisters, ModbusMaskWriteRegister, ModbusReadWriteRegisters:
+		return true
+	}
+	return false
+}
+
+func modbusRegisters(data []byte) []uint16 {
+	values := make([]uint16, len(data)/2)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(data[i*2:])
+	}
+	return values
+}
+
+func modbusCoils(data []byte, count int) []uint16 {
+	if count > len(data)*8 {
+		count = len(data) * 8
+	}
+	values := make([]uint16, count)
+	for i := range values {
+		values[i] = uint16(data[i/8]>>(uint(i)%8)) & 1
+	}
+	return values
+}
+
+// the fields of the request data we care about
+func (m *ModbusMessage) parseRequest(data []byte) error {
+	switch m.Function {
+	case ModbusReadCoils, ModbusReadDiscreteInputs, ModbusReadHoldingRegisters, ModbusReadInputRegisters:
+		if len(data) < 4 {
+			return ErrModbusInvalid
+		}
+		m.Address = binary.BigEndian.Uint16(data)
+		m.Quantity = binary.BigEndian.Uint16(data[2:])
+	case ModbusWriteSingleCoil, ModbusWriteSingleRegister:
+		if len(data) < 4 {
+			return ErrModbusInvalid
+		}
+		m.Address = binary.BigEndian.Uint16(data)
+		m.Quantity = 1
+		value := binary.BigEndian.Uint16(data[2:])
+		if m.Function == ModbusWriteSingleCoil && value == 0xff00 {
+			value = 1
+		}
+		m.Values = []uint16{value}
+	case ModbusWriteMultipleCoils, ModbusWriteMultipleRegisters:
+		if len(data) < 5 || len(data) < 5+int(data[4]) {
+			return ErrModbusInvalid
+		}
+		m.Address = binary.BigEndian.Uint16(data)
+		m.Quantity = binary.BigEndian.Uint16(data[2:])
+		if m.Function == ModbusWriteMultipleCoils {
+			m.Values = modbusCoils(data[5:5+int(data[4])], int(m.Quantity))
+		} else {
+			m.Values = modbusRegisters(data[5 : 5+int(data[4])])
+		}
+	case ModbusReadWriteRegisters:
+		// the registers read come first, we report the written ones
+		if len(data) < 9 || len(data) < 9+int(data[8]) {
+			return ErrModbusInvalid
+		}
+		m.Address = binary.BigEndian.Uint16(data[4:])
+		m.Quantity = binary.BigEndian.Uint16(data[6:])
+		m.Values = modbusRegisters(data[9 : 9+int(data[8])])
+	}
+	return nil
+}
+
+func (m *ModbusMessage) parseResponse(data []byte) error {
+	switch m.Function {
+	case ModbusReadCoils, ModbusReadDiscreteInputs, ModbusReadHoldingRegisters, ModbusReadInputRegisters, ModbusReadWriteRegisters:
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return ErrModbusInvalid
+		}
+		values := data[1 : 1+int(data[0])]
+		if m.Function == ModbusReadCoils || m.Function == ModbusReadDiscreteInputs {
+			// the response doesn't tell how many of the bits are coils
+			m.Values = modbusCoils(values, len(values)*8)
+		} else {
+			m.Values = modbusRegisters(values)
+		}
+		m.Quantity = uint16(len(m.Values))
+	case ModbusWriteSingleCoil, ModbusWriteSingleRegister, ModbusWriteMultipleCoils, ModbusWriteMultipleRegisters:
+		if len(data) < 4 {
+			return ErrModbusInvalid
+		}
+		m.Address = binary.BigEndian.Uint16(data)
+		m.Quantity = binary.BigEndian.Uint16(data[2:])
+		if m.Function == ModbusWriteSingleCoil || m.Function == ModbusWriteSingleRegister {
+			m.Quantity = 1
+		}
+	}
+	return nil
+}
+
+// ParseModbus parses the Modbus/TCP messages of a segment, requests are the
+// ones sent to the server.
+func ParseModbus(payload []byte, request bool) ([]*ModbusMessage, error) {
+	messages := []*ModbusMessage{}
+	for len(payload) > 0 {
+		if len(payload) < modbusHeaderSize+1 {
+			return nil, ErrModbusInvalid
+		}
+
+		size := int(binary.BigEndian.Uint16(payload[4:]))
+		if binary.BigEndian.Uint16(payload[2:]) != 0 || size < 2 || size > modbusMaxADUSize-6 || len(payload) < 6+size {
+			return nil, ErrModbusInvalid
+		}
+
+		msg := &ModbusMessage{
+			TransactionID: binary.BigEndian.Uint16(payload),
+			UnitID:        payload[6],
+			Function:      payload[7],
+		}
+		data := payload[modbusHeaderSize+1 : 6+size]
+
+		var err error
+		if msg.Function&modbusExceptionFlag != 0 {
+			if request || len(data) < 1 {
+				return nil, ErrModbusInvalid
+			}
+			msg.Function &^= modbusExceptionFlag
+			msg.Exception = data[0]
+		} else if request {
+			err = msg.parseRequest(data)
+		} else {
+			err = msg.parseResponse(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+		payload = payload[6+size:]
+	}
+	return messages, nil
+}
@@ -0,0 +1,82 @@
+package packets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseModbusRequests(t *testing.T) {
+	// read 10 holding registers from 0x006b, then write two registers at 0x0001
+	payload := []byte{
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x11, 0x03, 0x00, 0x6b, 0x00, 0x0a,
+		0x00, 0x02, 0x00, 0x00, 0x00, 0x0b, 0x11, 0x10, 0x00, 0x01, 0x00, 0x02, 0x04, 0x00, 0x0a, 0x01, 0x02,
+	}
+
+	msgs, err := ParseModbus(payload, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(msgs))
+	}
+
+	read, write := msgs[0], msgs[1]
+	if read.TransactionID != 1 || read.UnitID != 0x11 || read.Function != ModbusReadHoldingRegisters {
+		t.Fatalf("unexpected message %+v", read)
+	} else if read.Address != 0x6b || read.Quantity != 10 || read.IsWrite() {
+		t.Fatalf("unexpected message %+v", read)
+	} else if read.FunctionName() != "read holding registers" {
+		t.Fatalf("unexpected function name %s", read.FunctionName())
+	}
+
+	if !write.IsWrite() || write.Address != 1 || write.Quantity != 2 || !reflect.DeepEqual(write.Values, []uint16{0x000a, 0x0102}) {
+		t.Fatalf("unexpected message %+v", write)
+	}
+
+	// single coil on
+	coil := []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x06, 0x01, 0x05, 0x00, 0xac, 0xff, 0x00}
+	if msgs, err = ParseModbus(coil, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msgs[0].Address != 0xac || !reflect.DeepEqual(msgs[0].Values, []uint16{1}) {
+		t.Fatalf("unexpected message %+v", msgs[0])
+	}
+}
+
+func TestParseModbusResponses(t *testing.T) {
+	registers := []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x07, 0x11, 0x03, 0x04, 0x02, 0x2b, 0x00, 0x00}
+	msgs, err := ParseModbus(registers, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !reflect.DeepEqual(msgs[0].Values, []uint16{0x022b, 0x0000}) || msgs[0].Quantity != 2 {
+		t.Fatalf("unexpected message %+v", msgs[0])
+	}
+
+	coils := []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x04, 0x11, 0x01, 0x01, 0x05}
+	if msgs, err = ParseModbus(coils, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !reflect.DeepEqual(msgs[0].Values, []uint16{1, 0, 1, 0, 0, 0, 0, 0}) {
+		t.Fatalf("unexpected coils %v", msgs[0].Values)
+	}
+
+	exception := []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x03, 0x11, 0x81, 0x02}
+	if msgs, err = ParseModbus(exception, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msgs[0].Function != ModbusReadCoils || msgs[0].Exception != 2 || msgs[0].ExceptionName() != "illegal data address" {
+		t.Fatalf("unexpected message %+v", msgs[0])
+	}
+}
+
+func TestParseModbusInvalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		{0x00, 0x01, 0x00, 0x00},
+		// not the modbus protocol id
+		{0x00, 0x01, 0x00, 0x01, 0x00, 0x06, 0x11, 0x03, 0x00, 0x6b, 0x00, 0x0a},
+		// truncated
+		{0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x11, 0x03, 0x00, 0x6b},
+		// http
+		[]byte("GET / HTTP/1.1\r\n\r\n"),
+	} {
+		if _, err := ParseModbus(payload, true); err == nil {
+			t.Fatalf("expected an error for %x", payload)
+		}
+	}
+}
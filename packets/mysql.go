@@ -1,5 +1,12 @@
 package packets
 
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
 var (
 	MySQLGreeting = []byte{
 		0x5b, 0x00, 0x00, 0x00, 0x0a, 0x35, 0x2e, 0x36,
@@ -31,3 +38,216 @@ func MySQLGetFile(infile string) []byte {
 		0x00, 0x00, 0x01, 0xfb,
 	}, infile...)
 }
+
+const (
+	MySQLPort = 3306
+
+	mySQLProtocolVersion = 10
+	mySQLInitDB          = 0x02
+
+	mySQLClientConnectWithDB       = 0x00000008
+	mySQLClientProtocol41          = 0x00000200
+	mySQLClientSSL                 = 0x00000800
+	mySQLClientSecureConnection    = 0x00008000
+	mySQLClientPluginAuth          = 0x00080000
+	mySQLClientPluginAuthLenEncode = 0x00200000
+
+	MySQLNativePassword = "mysql_native_password"
+)
+
+var ErrMySQLInvalid = errors.New("not a valid MySQL packet")
+
+// MySQLHandshake is the greeting sent by the server, with the salt of the
+// challenge the client has to answer to.
+type MySQLHandshake struct {
+	Version      string `json:"version"`
+	ConnectionID uint32 `json:"connection_id"`
+	Salt         []byte `json:"salt"`
+	Plugin       string `json:"plugin"`
+}
+
+// MySQLLogin is the response of the client to the handshake.
+type MySQLLogin struct {
+	User     string `json:"user"`
+	Database string `json:"database"`
+	Plugin   string `json:"plugin"`
+	Response []byte `json:"response"`
+	// the client is switching to TLS, everything else is empty
+	SSL bool `json:"ssl"`
+}
+
+// Hashcat returns the challenge and response of a mysql_native_password
+// login in the format of hashcat mode 11200.
+func (l *MySQLLogin) Hashcat(salt []byte) (string, error) {
+	if (l.Plugin != "" && l.Plugin != MySQLNativePassword) || len(l.Response) != 20 || len(salt) != 20 {
+		return "", fmt.Errorf("unsupported authentication %s", l.Plugin)
+	}
+	return fmt.Sprintf("$mysqlna$%x*%x", salt, l.Response), nil
+}
+
+// the payload of the first packet and its sequence number
+func mySQLPacket(data []byte) ([]byte, byte, error) {
+	if len(data) < 4 {
+		return nil, 0, ErrMySQLInvalid
+	}
+	size := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+	if size == 0 || len(data) < 4+size {
+		return nil, 0, ErrMySQLInvalid
+	}
+	return data[4 : 4+size], data[3], nil
+}
+
+func mySQLString(data []byte) (string, []byte, error) {
+	end := bytes.IndexByte(data, 0)
+	if end == -1 {
+		return "", nil, ErrMySQLInvalid
+	}
+	return string(data[:end]), data[end+1:], nil
+}
+
+// ParseMySQLHandshake parses the protocol 10 greeting of the server.
+func ParseMySQLHandshake(data []byte) (*MySQLHandshake, error) {
+	payload, seq, err := mySQLPacket(data)
+	if err != nil {
+		return nil, err
+	} else if seq != 0 || payload[0] != mySQLProtocolVersion {
+		return nil, ErrMySQLInvalid
+	}
+
+	hs := &MySQLHandshake{}
+	if hs.Version, payload, err = mySQLString(payload[1:]); err != nil {
+		return nil, err
+	} else if len(payload) < 13 {
+		return nil, ErrMySQLInvalid
+	}
+
+	hs.ConnectionID = binary.LittleEndian.Uint32(payload)
+	hs.Salt = append([]byte{}, payload[4:12]...)
+	payload = payload[13:]
+
+	// capabilities, charset, status, capabilities, salt length and reserved
+	if len(payload) >= 18 {
+		caps := uint32(binary.LittleEndian.Uint16(payload)) | uint32(binary.LittleEndian.Uint16(payload[5:]))<<16
+		saltSize := int(payload[7])
+		payload = payload[18:]
+
+		if caps&mySQLClientSecureConnection != 0 {
+			// the rest of the salt is null terminated
+			size := saltSize - 8
+			if size < 13 {
+				size = 13
+			}
+			if len(payload) < size {
+				return nil, ErrMySQLInvalid
+			}
+			hs.Salt = append(hs.Salt, bytes.TrimRight(payload[:size], "\x00")...)
+			payload = payload[size:]
+		}
+
+		if caps&mySQLClientPluginAuth != 0 {
+			if hs.Plugin, _, err = mySQLString(payload); err != nil {
+				hs.Plugin = string(payload)
+			}
+		}
+	}
+
+	return hs, nil
+}
+
+func mySQLLenEnc(data []byte) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, ErrMySQLInvalid
+	}
+
+	switch data[0] {
+	case 0xfc:
+		if len(data) >= 3 {
+			return int(binary.LittleEndian.Uint16(data[1:])), data[3:], nil
+		}
+	case 0xfd:
+		if len(data) >= 4 {
+			return int(data[1]) | int(data[2])<<8 | int(data[3])<<16, data[4:], nil
+		}
+	case 0xfe, 0xff:
+		// nobody sends authentication data this big
+	default:
+		return int(data[0]), data[1:], nil
+	}
+
+	return 0, nil, ErrMySQLInvalid
+}
+
+// ParseMySQLLogin parses the protocol 4.1 handshake response of the client.
+func ParseMySQLLogin(data []byte) (*MySQLLogin, error) {
+	payload, seq, err := mySQLPacket(data)
+	if err != nil {
+		return nil, err
+	} else if seq != 1 || len(payload) < 32 {
+		return nil, ErrMySQLInvalid
+	}
+
+	caps := binary.LittleEndian.Uint32(payload)
+	if caps&mySQLClientProtocol41 == 0 {
+		return nil, ErrMySQLInvalid
+	} else if len(payload) == 32 {
+		if caps&mySQLClientSSL == 0 {
+			return nil, ErrMySQLInvalid
+		}
+		return &MySQLLogin{SSL: true}, nil
+	}
+
+	login := &MySQLLogin{}
+	// capabilities, max packet size, charset and filler
+	if login.User, payload, err = mySQLString(payload[32:]); err != nil {
+		return nil, err
+	}
+
+	size := 0
+	if caps&mySQLClientPluginAuthLenEncode != 0 {
+		if size, payload, err = mySQLLenEnc(payload); err != nil {
+			return nil, err
+		}
+	} else if caps&mySQLClientSecureConnection != 0 {
+		if len(payload) == 0 {
+			return nil, ErrMySQLInvalid
+		}
+		size, payload = int(payload[0]), payload[1:]
+	} else if size = bytes.IndexByte(payload, 0); size == -1 {
+		return nil, ErrMySQLInvalid
+	}
+
+	if len(payload) < size {
+		return nil, ErrMySQLInvalid
+	}
+	login.Response = append([]byte{}, payload[:size]...)
+	payload = payload[size:]
+	if caps&mySQLClientSecureConnection == 0 && caps&mySQLClientPluginAuthLenEncode == 0 && len(payload) > 0 {
+		// null terminator of the response
+		payload = payload[1:]
+	}
+
+	if caps&mySQLClientConnectWithDB != 0 {
+		if login.Database, payload, err = mySQLString(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if caps&mySQLClientPluginAuth != 0 {
+		if login.Plugin, _, err = mySQLString(payload); err != nil {
+			login.Plugin = string(payload)
+		}
+	}
+
+	return login, nil
+}
+
+// ParseMySQLInitDB returns the database selected by a COM_INIT_DB command.
+func ParseMySQLInitDB(data []byte) (string, error) {
+	payload, seq, err := mySQLPacket(data)
+	if err != nil {
+		return "", err
+	} else if seq != 0 || payload[0] != mySQLInitDB || len(payload) < 2 {
+		return "", ErrMySQLInvalid
+	}
+	return string(payload[1:]), nil
+}
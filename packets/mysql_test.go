@@ -0,0 +1,61 @@
+package packets
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseMySQLHandshake(t *testing.T) {
+	hs, err := ParseMySQLHandshake(MySQLGreeting)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hs.Version != "5.6.28-0ubuntu0.14.04.1" || hs.ConnectionID != 45 {
+		t.Fatalf("unexpected handshake %+v", hs)
+	} else if hs.Plugin != MySQLNativePassword {
+		t.Fatalf("unexpected plugin %s", hs.Plugin)
+	}
+
+	expected := append([]byte{0x40, 0x3f, 0x59, 0x26, 0x4b, 0x2b, 0x34, 0x60}, []byte("hiY_R_cU`dSR")...)
+	if !bytes.Equal(hs.Salt, expected) {
+		t.Fatalf("expected salt %x, got %x", expected, hs.Salt)
+	}
+}
+
+func TestParseMySQLLogin(t *testing.T) {
+	payload := unhex(t, "550000010882080000000001210000000000000000000000000000000000000000000000726f6f7400"+
+		"140102030405060708090a0b0c0d0e0f101112131473686f70006d7973716c5f6e61746976655f70617373776f726400")
+
+	login, err := ParseMySQLLogin(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if login.User != "root" || login.Database != "shop" || login.Plugin != MySQLNativePassword || login.SSL {
+		t.Fatalf("unexpected login %+v", login)
+	}
+
+	salt := bytes.Repeat([]byte{0xaa}, 20)
+	expected := "$mysqlna$" + "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" + "*0102030405060708090a0b0c0d0e0f1011121314"
+	if hash, err := login.Hashcat(salt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+
+	ssl := unhex(t, "20000001088a080000000001210000000000000000000000000000000000000000000000")
+	if login, err = ParseMySQLLogin(ssl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !login.SSL || login.User != "" {
+		t.Fatalf("expected ssl request, got %+v", login)
+	}
+}
+
+func TestParseMySQLInitDB(t *testing.T) {
+	if db, err := ParseMySQLInitDB(unhex(t, "0a00000002696e76656e746f7279")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if db != "inventory" {
+		t.Fatalf("unexpected database %s", db)
+	}
+
+	if _, err := ParseMySQLInitDB(MySQLGreeting); err != ErrMySQLInvalid {
+		t.Fatalf("expected %v, got %v", ErrMySQLInvalid, err)
+	}
+}
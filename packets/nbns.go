@@ -1,6 +1,11 @@
 package packets
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
 	"strconv"
 
 	"github.com/evilsocket/islazy/str"
@@ -26,11 +31,235 @@ var (
 	}
 )
 
+const (
+	// offset of the number of names in a node status response
+	nbnsNumNamesOffset = 56
+	nbnsNameSize       = 18
+	nbnsGroupFlag      = 0x8000
+
+	// name suffixes
+	NBNSSuffixWorkstation       = 0x00
+	NBNSSuffixDomainControllers = 0x1c
+)
+
+// NBNSName is an entry of the names table of a NetBIOS node status response.
+type NBNSName struct {
+	Name   string
+	Suffix byte
+	Group  bool
+}
+
+// NBNSNodeStatus is the parsed payload of a NetBIOS node status response.
+type NBNSNodeStatus struct {
+	Names []NBNSName
+	MAC   net.HardwareAddr
+}
+
+const (
+	nbnsHeaderSize      = 12
+	nbnsEncodedNameSize = 32
+	nbnsResponseFlag    = 0x8000
+	nbnsBroadcastFlag   = 0x0010
+
+	NBNSOpQuery        = 0
+	NBNSOpRegistration = 5
+	NBNSTypeNB         = 0x0020
+	NBNSTypeNBSTAT     = 0x0021
+)
+
+var ErrNBNSInvalid = errors.New("not a valid NBNS message")
+
+// NBNSMessage is a NetBIOS name service query or response.
+type NBNSMessage struct {
+	ID        uint16
+	Response  bool
+	Broadcast bool
+	Opcode    int
+	Rcode     int
+	Name      string
+	Suffix    byte
+	Type      uint16
+	// addresses of a positive response or of a registration
+	Addresses []net.IP
+}
+
+// NBNSDecodeName decodes a first level encoded NetBIOS name and its suffix.
+func NBNSDecodeName(encoded []byte) (string, byte, error) {
+	if len(encoded) != nbnsEncodedNameSize {
+		return "", 0, ErrNBNSInvalid
+	}
+
+	raw := make([]byte, nbnsEncodedNameSize/2)
+	for i := range raw {
+		hi, lo := encoded[i*2]-'A', encoded[i*2+1]-'A'
+		if hi > 0x0f || lo > 0x0f {
+			return "", 0, ErrNBNSInvalid
+		}
+		raw[i] = hi<<4 | lo
+	}
+
+	return str.Trim(string(raw[:15])), raw[15], nil
+}
+
+// the encoded name at the given offset, followed by the labels of the scope id
+func nbnsReadName(payload []byte, offset int) (string, byte, int, error) {
+	if len(payload) < offset+1+nbnsEncodedNameSize || payload[offset] != nbnsEncodedNameSize {
+		return "", 0, 0, ErrNBNSInvalid
+	}
+
+	name, suffix, err := NBNSDecodeName(payload[offset+1 : offset+1+nbnsEncodedNameSize])
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	offset += 1 + nbnsEncodedNameSize
+	for offset < len(payload) && payload[offset] != 0 {
+		offset += 1 + int(payload[offset])
+	}
+	if offset >= len(payload) {
+		return "", 0, 0, ErrNBNSInvalid
+	}
+	return name, suffix, offset + 1, nil
+}
+
+// NBNSParseMessage parses a name query, a registration or their responses.
+func NBNSParseMessage(payload []byte) (*NBNSMessage, error) {
+	if len(payload) < nbnsHeaderSize {
+		return nil, ErrNBNSInvalid
+	}
+
+	flags := binary.BigEndian.Uint16(payload[2:])
+	msg := &NBNSMessage{
+		ID:        binary.BigEndian.Uint16(payload),
+		Response:  flags&nbnsResponseFlag != 0,
+		Broadcast: flags&nbnsBroadcastFlag != 0,
+		Opcode:    int(flags>>11) & 0x0f,
+		Rcode:     int(flags & 0x0f),
+	}
+	questions := binary.BigEndian.Uint16(payload[4:])
+	records := binary.BigEndian.Uint16(payload[6:]) + binary.BigEndian.Uint16(payload[10:])
+
+	// queries and registrations start with the question, responses with the answer
+	if questions+records == 0 {
+		return nil, ErrNBNSInvalid
+	}
+
+	name, suffix, offset, err := nbnsReadName(payload, nbnsHeaderSize)
+	if err != nil {
+		return nil, err
+	} else if len(payload) < offset+4 {
+		return nil, ErrNBNSInvalid
+	}
+	msg.Name, msg.Suffix = name, suffix
+	msg.Type = binary.BigEndian.Uint16(payload[offset:])
+	offset += 4
+
+	if records > 0 && msg.Type == NBNSTypeNB {
+		if questions > 0 {
+			// registrations point back to the question name
+			if len(payload) < offset+2 {
+				return nil, ErrNBNSInvalid
+			} else if payload[offset]&0xc0 == 0xc0 {
+				offset += 2
+			} else if _, _, offset, err = nbnsReadName(payload, offset); err != nil {
+				return nil, err
+			}
+			offset += 4
+		}
+
+		// ttl and rdata length
+		if len(payload) < offset+6 {
+			return nil, ErrNBNSInvalid
+		}
+		size := int(binary.BigEndian.Uint16(payload[offset+4:]))
+		offset += 6
+		if len(payload) < offset+size {
+			return nil, ErrNBNSInvalid
+		}
+
+		// nb flags followed by the address
+		for i := 0; i+6 <= size; i += 6 {
+			msg.Addresses = append(msg.Addresses, net.IP(append([]byte{}, payload[offset+i+2:offset+i+6]...)))
+		}
+	}
+
+	return msg, nil
+}
+
+func NBNSParseNodeStatus(payload []byte) (*NBNSNodeStatus, error) {
+	if len(payload) < NBNSMinRespSize {
+		return nil, fmt.Errorf("node status response too short")
+	}
+
+	num := int(payload[nbnsNumNamesOffset])
+	offset := nbnsNumNamesOffset + 1
+	if len(payload) < offset+num*nbnsNameSize {
+		return nil, fmt.Errorf("node status response has %d names but only %d bytes", num, len(payload))
+	}
+
+	status := &NBNSNodeStatus{}
+	for i := 0; i < num; i++ {
+		raw := payload[offset : offset+nbnsNameSize]
+		status.Names = append(status.Names, NBNSName{
+			Name:   str.Trim(string(raw[:15])),
+			Suffix: raw[15],
+			Group:  binary.BigEndian.Uint16(raw[16:18])&nbnsGroupFlag != 0,
+		})
+		offset += nbnsNameSize
+	}
+
+	// the statistics start with the unit id, zeroed by samba
+	if len(payload) >= offset+6 {
+		if mac := net.HardwareAddr(payload[offset : offset+6]); !bytes.Equal(mac, make([]byte, 6)) {
+			status.MAC = mac
+		}
+	}
+
+	return status, nil
+}
+
+func (s *NBNSNodeStatus) find(suffix byte, group bool) string {
+	for _, n := range s.Names {
+		if n.Suffix == suffix && n.Group == group && n.Name != "" {
+			return n.Name
+		}
+	}
+	return ""
+}
+
+// Hostname returns the unique workstation name.
+func (s *NBNSNodeStatus) Hostname() string {
+	return s.find(NBNSSuffixWorkstation, false)
+}
+
+// Workgroup returns the workgroup or domain the host belongs to.
+func (s *NBNSNodeStatus) Workgroup() string {
+	return s.find(NBNSSuffixWorkstation, true)
+}
+
+// IsDomainController returns true if the host registered the domain controllers name.
+func (s *NBNSNodeStatus) IsDomainController() bool {
+	return s.find(NBNSSuffixDomainControllers, true) != ""
+}
+
 func NBNSGetMeta(pkt gopacket.Packet) map[string]string {
 	if ludp := pkt.Layer(layers.LayerTypeUDP); ludp != nil {
 		if udp := ludp.(*layers.UDP); udp != nil && udp.SrcPort == NBNSPort && len(udp.Payload) >= NBNSMinRespSize {
+			if status, err := NBNSParseNodeStatus(udp.Payload); err == nil && status.Hostname() != "" {
+				meta := map[string]string{
+					"nbns:hostname": status.Hostname(),
+				}
+				if workgroup := status.Workgroup(); workgroup != "" {
+					meta["nbns:workgroup"] = workgroup
+				}
+				if status.IsDomainController() {
+					meta["nbns:role"] = "domain controller"
+				}
+				return meta
+			}
+
 			hostname := str.Trim(string(udp.Payload[57:72]))
-			if strconv.IsPrint(rune(hostname[0])) {
+			if hostname != "" && strconv.IsPrint(rune(hostname[0])) {
 				return map[string]string{
 					"nbns:hostname": hostname,
 				}
@@ -0,0 +1,93 @@
+package packets
+
+import (
+	"testing"
+)
+
+func nbnsName(name string, suffix byte, group bool) []byte {
+	raw := make([]byte, nbnsNameSize)
+	copy(raw, []byte(name + "               ")[:15])
+	raw[15] = suffix
+	if group {
+		raw[16] = 0x84
+	} else {
+		raw[16] = 0x04
+	}
+	return raw
+}
+
+func TestNBNSParseNodeStatus(t *testing.T) {
+	payload := make([]byte, nbnsNumNamesOffset)
+	payload = append(payload, 3)
+	payload = append(payload, nbnsName("DESKTOP-1", NBNSSuffixWorkstation, false)...)
+	payload = append(payload, nbnsName("CORP", NBNSSuffixWorkstation, true)...)
+	payload = append(payload, nbnsName("CORP", NBNSSuffixDomainControllers, true)...)
+	payload = append(payload, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}...)
+
+	status, err := NBNSParseNodeStatus(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(status.Names) != 3 {
+		t.Fatalf("expected 3 names, got %d", len(status.Names))
+	} else if status.Hostname() != "DESKTOP-1" {
+		t.Fatalf("unexpected hostname '%s'", status.Hostname())
+	} else if status.Workgroup() != "CORP" {
+		t.Fatalf("unexpected workgroup '%s'", status.Workgroup())
+	} else if !status.IsDomainController() {
+		t.Fatalf("expected a domain controller")
+	} else if status.MAC.String() != "00:11:22:33:44:55" {
+		t.Fatalf("unexpected mac %s", status.MAC)
+	}
+
+	if _, err := NBNSParseNodeStatus(payload[:60]); err == nil {
+		t.Fatalf("expected an error for a truncated response")
+	}
+}
+
+func nbnsEncodedName(name string, suffix byte) []byte {
+	raw := []byte(name + "               ")[:15]
+	raw = append(raw, suffix)
+	encoded := []byte{nbnsEncodedNameSize}
+	for _, b := range raw {
+		encoded = append(encoded, 'A'+b>>4, 'A'+b&0x0f)
+	}
+	return append(encoded, 0)
+}
+
+func TestNBNSParseMessage(t *testing.T) {
+	// broadcast name query for WPAD
+	query := []byte{0x81, 0x02, 0x01, 0x10, 0, 1, 0, 0, 0, 0, 0, 0}
+	query = append(query, nbnsEncodedName("WPAD", NBNSSuffixWorkstation)...)
+	query = append(query, 0x00, 0x20, 0x00, 0x01)
+
+	msg, err := NBNSParseMessage(query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msg.Response || !msg.Broadcast || msg.Opcode != NBNSOpQuery || msg.ID != 0x8102 {
+		t.Fatalf("unexpected message %+v", msg)
+	} else if msg.Name != "WPAD" || msg.Suffix != NBNSSuffixWorkstation || msg.Type != NBNSTypeNB {
+		t.Fatalf("unexpected name %+v", msg)
+	} else if len(msg.Addresses) != 0 {
+		t.Fatalf("unexpected addresses %v", msg.Addresses)
+	}
+
+	// positive response with two addresses
+	resp := []byte{0x81, 0x02, 0x85, 0x00, 0, 0, 0, 1, 0, 0, 0, 0}
+	resp = append(resp, nbnsEncodedName("WPAD", NBNSSuffixWorkstation)...)
+	resp = append(resp, 0x00, 0x20, 0x00, 0x01, 0x00, 0x04, 0x93, 0xe0, 0x00, 0x0c)
+	resp = append(resp, 0x00, 0x00, 10, 0, 0, 66, 0x00, 0x00, 10, 0, 0, 67)
+
+	if msg, err = NBNSParseMessage(resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !msg.Response || msg.Name != "WPAD" || len(msg.Addresses) != 2 {
+		t.Fatalf("unexpected message %+v", msg)
+	} else if msg.Addresses[1].String() != "10.0.0.67" {
+		t.Fatalf("unexpected address %s", msg.Addresses[1])
+	}
+
+	if _, err = NBNSParseMessage(resp[:len(resp)-10]); err == nil {
+		t.Fatal("expected an error for a truncated response")
+	} else if _, _, err = NBNSDecodeName([]byte("not an encoded name at all.....!")); err == nil {
+		t.Fatal("expected an error for an invalid name")
+	}
+}
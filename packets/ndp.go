@@ -0,0 +1,165 @@
+package packets
+
+import (
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// the neighbor advertisement is sent by a router
+	ndpRouterFlag = 0x80
+	// NDP messages coming from outside the link are invalid
+	ndpHopLimit = 255
+)
+
+var ipv6AllRouters = net.ParseIP("ff02::2")
+
+// NDPNeighbor is an IPv6 address a host announced on the link.
+type NDPNeighbor struct {
+	HW     net.HardwareAddr
+	IP     net.IP
+	Router bool
+	// ns, na, rs, ra or dad for the duplicate address detection of an
+	// address being assigned
+	Source string
+}
+
+// IPv6MulticastMAC returns the Ethernet address of an IPv6 multicast group.
+func IPv6MulticastMAC(ip net.IP) net.HardwareAddr {
+	ip = ip.To16()
+	return net.HardwareAddr{0x33, 0x33, ip[12], ip[13], ip[14], ip[15]}
+}
+
+// IPv6SolicitedNode returns the solicited-node multicast group of an address.
+func IPv6SolicitedNode(ip net.IP) net.IP {
+	ip = ip.To16()
+	return net.IP{0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0xff, ip[13], ip[14], ip[15]}
+}
+
+func icmp6Multicast(srcHW net.HardwareAddr, srcIP net.IP, dstIP net.IP, hopLimit uint8, typeCode layers.ICMPv6TypeCode, msg gopacket.SerializableLayer) (error, []byte) {
+	eth := layers.Ethernet{
+		SrcMAC:       srcHW,
+		DstMAC:       IPv6MulticastMAC(dstIP),
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ip6 := layers.IPv6{
+		Version:    6,
+		NextHeader: layers.IPProtocolICMPv6,
+		HopLimit:   hopLimit,
+		SrcIP:      srcIP,
+		DstIP:      dstIP,
+	}
+	icmp6 := layers.ICMPv6{
+		TypeCode: typeCode,
+	}
+	icmp6.SetNetworkLayerForChecksum(&ip6)
+
+	return Serialize(&eth, &ip6, &icmp6, msg)
+}
+
+// ICMP6RouterSolicitation asks the routers of the link to advertise themselves.
+func ICMP6RouterSolicitation(srcHW net.HardwareAddr, srcIP net.IP) (error, []byte) {
+	return icmp6Multicast(srcHW, srcIP, ipv6AllRouters, ndpHopLimit,
+		layers.ICMPv6TypeRouterSolicitation<<8,
+		&layers.ICMPv6RouterSolicitation{
+			Options: []layers.ICMPv6Option{
+				{
+					Type: layers.ICMPv6OptSourceAddress,
+					Data: srcHW,
+				},
+			},
+		})
+}
+
+// ICMP6NeighborSolicitation asks the host with the target address for its
+// hardware address.
+func ICMP6NeighborSolicitation(srcHW net.HardwareAddr, srcIP net.IP, target net.IP) (error, []byte) {
+	return icmp6Multicast(srcHW, srcIP, IPv6SolicitedNode(target), ndpHopLimit,
+		layers.ICMPv6TypeNeighborSolicitation<<8,
+		&layers.ICMPv6NeighborSolicitation{
+			TargetAddress: target,
+			Options: []layers.ICMPv6Option{
+				{
+					Type: layers.ICMPv6OptSourceAddress,
+					Data: srcHW,
+				},
+			},
+		})
+}
+
+// ICMP6MulticastEcho pings all the nodes of the link, they answer from an
+// address of the same scope as the source one.
+func ICMP6MulticastEcho(srcHW net.HardwareAddr, srcIP net.IP, id uint16, seq uint16) (error, []byte) {
+	return icmp6Multicast(srcHW, srcIP, ipv6Multicast, 1,
+		layers.ICMPv6TypeEchoRequest<<8,
+		&layers.ICMPv6Echo{
+			Identifier: id,
+			SeqNumber:  seq,
+		})
+}
+
+func ndpLinkAddress(options layers.ICMPv6Options, kind layers.ICMPv6Opt) net.HardwareAddr {
+	for _, opt := range options {
+		if opt.Type == kind && len(opt.Data) == 6 {
+			return net.HardwareAddr(opt.Data)
+		}
+	}
+	return nil
+}
+
+func ndpUsable(ip net.IP) bool {
+	return ip != nil && !ip.IsUnspecified() && !ip.IsMulticast() && !ip.IsLoopback()
+}
+
+// ParseNDPNeighbor returns the address announced by a neighbor discovery
+// message, or nil if the packet is not one.
+func ParseNDPNeighbor(pkt gopacket.Packet) *NDPNeighbor {
+	eth, ok := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	if !ok {
+		return nil
+	}
+	ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+	if !ok || ip6.HopLimit != ndpHopLimit {
+		return nil
+	}
+
+	n := &NDPNeighbor{
+		HW: eth.SrcMAC,
+		IP: ip6.SrcIP,
+	}
+	var hw net.HardwareAddr
+
+	if ns, ok := pkt.Layer(layers.LayerTypeICMPv6NeighborSolicitation).(*layers.ICMPv6NeighborSolicitation); ok {
+		n.Source = "ns"
+		hw = ndpLinkAddress(ns.Options, layers.ICMPv6OptSourceAddress)
+		if ip6.SrcIP.IsUnspecified() {
+			// the address the host is about to use
+			n.Source = "dad"
+			n.IP = ns.TargetAddress
+		}
+	} else if na, ok := pkt.Layer(layers.LayerTypeICMPv6NeighborAdvertisement).(*layers.ICMPv6NeighborAdvertisement); ok {
+		n.Source = "na"
+		n.IP = na.TargetAddress
+		n.Router = na.Flags&ndpRouterFlag != 0
+		hw = ndpLinkAddress(na.Options, layers.ICMPv6OptTargetAddress)
+	} else if rs, ok := pkt.Layer(layers.LayerTypeICMPv6RouterSolicitation).(*layers.ICMPv6RouterSolicitation); ok {
+		n.Source = "rs"
+		hw = ndpLinkAddress(rs.Options, layers.ICMPv6OptSourceAddress)
+	} else if ra, ok := pkt.Layer(layers.LayerTypeICMPv6RouterAdvertisement).(*layers.ICMPv6RouterAdvertisement); ok {
+		n.Source = "ra"
+		n.Router = true
+		hw = ndpLinkAddress(ra.Options, layers.ICMPv6OptSourceAddress)
+	} else {
+		return nil
+	}
+
+	if hw != nil {
+		n.HW = hw
+	}
+	if !ndpUsable(n.IP) {
+		return nil
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	ndpTestHW        = net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	ndpTestLinkLocal = net.ParseIP("fe80::211:22ff:fe33:4455")
+	ndpTestGlobal    = net.ParseIP("2001:db8::1:2:3")
+)
+
+func TestIPv6Multicast(t *testing.T) {
+	if group := IPv6SolicitedNode(ndpTestGlobal); !group.Equal(net.ParseIP("ff02::1:ff02:3")) {
+		t.Fatalf("unexpected solicited-node group %s", group)
+	} else if hw := IPv6MulticastMAC(group); hw.String() != "33:33:ff:02:00:03" {
+		t.Fatalf("unexpected multicast MAC %s", hw)
+	}
+}
+
+func TestParseNDPNeighborSolicitation(t *testing.T) {
+	err, data := ICMP6NeighborSolicitation(ndpTestHW, ndpTestLinkLocal, ndpTestGlobal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	if eth := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); eth.DstMAC.String() != "33:33:ff:02:00:03" {
+		t.Fatalf("unexpected destination %s", eth.DstMAC)
+	}
+
+	n := ParseNDPNeighbor(pkt)
+	if n == nil {
+		t.Fatalf("expected a neighbor")
+	} else if n.Source != "ns" || !n.IP.Equal(ndpTestLinkLocal) || n.HW.String() != ndpTestHW.String() || n.Router {
+		t.Fatalf("unexpected neighbor %+v", n)
+	}
+}
+
+func TestParseNDPNeighborDAD(t *testing.T) {
+	err, data := ICMP6NeighborSolicitation(ndpTestHW, net.IPv6unspecified, ndpTestGlobal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := ParseNDPNeighbor(gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default))
+	if n == nil {
+		t.Fatalf("expected a neighbor")
+	} else if n.Source != "dad" || !n.IP.Equal(ndpTestGlobal) {
+		t.Fatalf("unexpected neighbor %+v", n)
+	}
+}
+
+func TestParseNDPNeighborAdvertisement(t *testing.T) {
+	err, data := ICMP6NeighborAdvertisement(ndpTestHW, ndpTestLinkLocal, net.HardwareAddr{0xde, 0xad, 0xbe, 0xef, 0x00, 0x01}, net.ParseIP("fe80::1"), ndpTestGlobal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := ParseNDPNeighbor(gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default))
+	if n == nil {
+		t.Fatalf("expected a neighbor")
+	} else if n.Source != "na" || !n.IP.Equal(ndpTestGlobal) || n.HW.String() != ndpTestHW.String() {
+		t.Fatalf("unexpected neighbor %+v", n)
+	}
+}
+
+func TestParseNDPNeighborRouter(t *testing.T) {
+	err, data := ICMP6RouterAdvertisement(ndpTestLinkLocal, ndpTestHW, "2001:db8::", 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := ParseNDPNeighbor(gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default))
+	if n == nil {
+		t.Fatalf("expected a neighbor")
+	} else if n.Source != "ra" || !n.Router || !n.IP.Equal(ndpTestLinkLocal) {
+		t.Fatalf("unexpected neighbor %+v", n)
+	}
+
+	if err, data = ICMP6RouterSolicitation(ndpTestHW, ndpTestLinkLocal); err != nil {
+		t.Fatal(err)
+	} else if n = ParseNDPNeighbor(gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)); n == nil || n.Source != "rs" || n.Router {
+		t.Fatalf("unexpected neighbor %+v", n)
+	}
+}
+
+func TestICMP6MulticastEcho(t *testing.T) {
+	err, data := ICMP6MulticastEcho(ndpTestHW, ndpTestGlobal, 0x1337, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	echo, ok := pkt.Layer(layers.LayerTypeICMPv6Echo).(*layers.ICMPv6Echo)
+	if !ok {
+		t.Fatalf("expected an echo request")
+	} else if echo.Identifier != 0x1337 || echo.SeqNumber != 1 {
+		t.Fatalf("unexpected echo %+v", echo)
+	} else if ParseNDPNeighbor(pkt) != nil {
+		t.Fatalf("an echo request is not a neighbor discovery message")
+	}
+}
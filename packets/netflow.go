@@ -0,0 +1,250 @@
+package packets
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+const (
+	NetFlowV9 = 9
+	IPFIX     = 10
+
+	// records per export packet, IPv6 records are 70 bytes so that the
+	// packets stay below the usual MTU
+	FlowMaxRecords = 20
+
+	netflowV9HeaderSize = 20
+	ipfixHeaderSize     = 16
+	netflowV9Templates  = 0
+	ipfixTemplates      = 2
+
+	flowTemplateIPv4 = 256
+	flowTemplateIPv6 = 257
+)
+
+// information element ids, the same for NetFlow v9 and IPFIX
+const (
+	flowFieldBytes       = 1
+	flowFieldPackets     = 2
+	flowFieldProtocol    = 4
+	flowFieldTCPFlags    = 6
+	flowFieldSrcPort     = 7
+	flowFieldSrcIPv4     = 8
+	flowFieldDstPort     = 11
+	flowFieldDstIPv4     = 12
+	flowFieldLastUptime  = 21
+	flowFieldFirstUptime = 22
+	flowFieldSrcIPv6     = 27
+	flowFieldDstIPv6     = 28
+	flowFieldStartMs     = 152
+	flowFieldEndMs       = 153
+)
+
+// FlowRecord is a unidirectional flow, as NetFlow and IPFIX see them.
+type FlowRecord struct {
+	SrcIP    net.IP
+	DstIP    net.IP
+	SrcPort  uint16
+	DstPort  uint16
+	Protocol uint8
+	TCPFlags uint8
+	Packets  uint64
+	Bytes    uint64
+	Start    time.Time
+	End      time.Time
+}
+
+func (r FlowRecord) IsIPv6() bool {
+	return r.SrcIP.To4() == nil
+}
+
+type flowField struct {
+	id     uint16
+	length uint16
+}
+
+// FlowExporter encodes flow records as NetFlow v9 or IPFIX packets, the
+// templates are sent with every packet since the transport is UDP.
+type FlowExporter struct {
+	Version int
+	// source id for NetFlow v9, observation domain id for IPFIX
+	Domain uint32
+	// when the exporter started, for the NetFlow v9 uptime
+	Boot time.Time
+
+	packets uint32
+	records uint32
+}
+
+func NewFlowExporter(version int, domain uint32) *FlowExporter {
+	return &FlowExporter{
+		Version: version,
+		Domain:  domain,
+		Boot:    time.Now(),
+	}
+}
+
+func (e *FlowExporter) fields(ipv6 bool) []flowField {
+	fields := []flowField{
+		{flowFieldSrcIPv4, 4},
+		{flowFieldDstIPv4, 4},
+	}
+	if ipv6 {
+		fields = []flowField{
+			{flowFieldSrcIPv6, 16},
+			{flowFieldDstIPv6, 16},
+		}
+	}
+
+	fields = append(fields,
+		flowField{flowFieldSrcPort, 2},
+		flowField{flowFieldDstPort, 2},
+		flowField{flowFieldProtocol, 1},
+		flowField{flowFieldTCPFlags, 1},
+		flowField{flowFieldPackets, 8},
+		flowField{flowFieldBytes, 8})
+
+	if e.Version == IPFIX {
+		return append(fields, flowField{flowFieldStartMs, 8}, flowField{flowFieldEndMs, 8})
+	}
+	return append(fields, flowField{flowFieldFirstUptime, 4}, flowField{flowFieldLastUptime, 4})
+}
+
+func (e *FlowExporter) template(id uint16, fields []flowField) []byte {
+	data := make([]byte, 4, 4+4*len(fields))
+	binary.BigEndian.PutUint16(data[0:], id)
+	binary.BigEndian.PutUint16(data[2:], uint16(len(fields)))
+	for _, f := range fields {
+		data = append(data, byte(f.id>>8), byte(f.id), byte(f.length>>8), byte(f.length))
+	}
+	return data
+}
+
+func (e *FlowExporter) uptime(t time.Time) uint32 {
+	if t.Before(e.Boot) {
+		return 0
+	}
+	return uint32(t.Sub(e.Boot) / time.Millisecond)
+}
+
+func (e *FlowExporter) record(r FlowRecord) []byte {
+	data := make([]byte, 0, 80)
+	if r.IsIPv6() {
+		data = append(data, r.SrcIP.To16()...)
+		data = append(data, r.DstIP.To16()...)
+	} else {
+		data = append(data, r.SrcIP.To4()...)
+		data = append(data, r.DstIP.To4()...)
+	}
+
+	num := make([]byte, 8)
+	binary.BigEndian.PutUint16(num, r.SrcPort)
+	data = append(data, num[:2]...)
+	binary.BigEndian.PutUint16(num, r.DstPort)
+	data = append(data, num[:2]...)
+	data = append(data, r.Protocol, r.TCPFlags)
+	binary.BigEndian.PutUint64(num, r.Packets)
+	data = append(data, num...)
+	binary.BigEndian.PutUint64(num, r.Bytes)
+	data = append(data, num...)
+
+	if e.Version == IPFIX {
+		binary.BigEndian.PutUint64(num, uint64(r.Start.UnixNano()/int64(time.Millisecond)))
+		data = append(data, num...)
+		binary.BigEndian.PutUint64(num, uint64(r.End.UnixNano()/int64(time.Millisecond)))
+		data = append(data, num...)
+	} else {
+		binary.BigEndian.PutUint32(num, e.uptime(r.Start))
+		data = append(data, num[:4]...)
+		binary.BigEndian.PutUint32(num, e.uptime(r.End))
+		data = append(data, num[:4]...)
+	}
+	return data
+}
+
+// a set with its header and padded to 32 bits
+func flowSet(id uint16, body []byte) []byte {
+	if pad := (4 - len(body)%4) % 4; pad > 0 {
+		body = append(body, make([]byte, pad)...)
+	}
+	set := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint16(set[0:], id)
+	binary.BigEndian.PutUint16(set[2:], uint16(4+len(body)))
+	return append(set, body...)
+}
+
+func (e *FlowExporter) encode(records []FlowRecord, now time.Time) []byte {
+	var v4, v6 []byte
+	num4, num6 := 0, 0
+	for _, r := range records {
+		if r.IsIPv6() {
+			v6 = append(v6, e.record(r)...)
+			num6++
+		} else {
+			v4 = append(v4, e.record(r)...)
+			num4++
+		}
+	}
+
+	templates := []byte{}
+	count := 0
+	if num4 > 0 {
+		templates = append(templates, e.template(flowTemplateIPv4, e.fields(false))...)
+		count++
+	}
+	if num6 > 0 {
+		templates = append(templates, e.template(flowTemplateIPv6, e.fields(true))...)
+		count++
+	}
+
+	setID := uint16(netflowV9Templates)
+	if e.Version == IPFIX {
+		setID = ipfixTemplates
+	}
+	body := flowSet(setID, templates)
+	if num4 > 0 {
+		body = append(body, flowSet(flowTemplateIPv4, v4)...)
+	}
+	if num6 > 0 {
+		body = append(body, flowSet(flowTemplateIPv6, v6)...)
+	}
+
+	var hdr []byte
+	if e.Version == IPFIX {
+		// the sequence number counts the data records sent before
+		hdr = make([]byte, ipfixHeaderSize)
+		binary.BigEndian.PutUint16(hdr[0:], IPFIX)
+		binary.BigEndian.PutUint16(hdr[2:], uint16(ipfixHeaderSize+len(body)))
+		binary.BigEndian.PutUint32(hdr[4:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(hdr[8:], e.records)
+		binary.BigEndian.PutUint32(hdr[12:], e.Domain)
+	} else {
+		// the count includes the templates, the sequence number counts the packets
+		hdr = make([]byte, netflowV9HeaderSize)
+		binary.BigEndian.PutUint16(hdr[0:], NetFlowV9)
+		binary.BigEndian.PutUint16(hdr[2:], uint16(count+len(records)))
+		binary.BigEndian.PutUint32(hdr[4:], e.uptime(now))
+		binary.BigEndian.PutUint32(hdr[8:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(hdr[12:], e.packets)
+		binary.BigEndian.PutUint32(hdr[16:], e.Domain)
+	}
+
+	e.packets++
+	e.records += uint32(len(records))
+	return append(hdr, body...)
+}
+
+// Encode returns the packets exporting the records.
+func (e *FlowExporter) Encode(records []FlowRecord, now time.Time) [][]byte {
+	packets := make([][]byte, 0)
+	for len(records) > 0 {
+		n := len(records)
+		if n > FlowMaxRecords {
+			n = FlowMaxRecords
+		}
+		packets = append(packets, e.encode(records[:n], now))
+		records = records[n:]
+	}
+	return packets
+}
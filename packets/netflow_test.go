@@ -0,0 +1,170 @@
+package packets
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func testFlowRecords(n int, ipv6 bool) []FlowRecord {
+	start := time.Unix(1500000000, 0)
+	src, dst := net.ParseIP("192.168.1.10"), net.ParseIP("8.8.8.8")
+	if ipv6 {
+		src, dst = net.ParseIP("fe80::1"), net.ParseIP("2001:4860:4860::8888")
+	}
+
+	records := make([]FlowRecord, n)
+	for i := range records {
+		records[i] = FlowRecord{
+			SrcIP:    src,
+			DstIP:    dst,
+			SrcPort:  uint16(40000 + i),
+			DstPort:  53,
+			Protocol: 17,
+			Packets:  2,
+			Bytes:    128,
+			Start:    start,
+			End:      start.Add(time.Second),
+		}
+	}
+	return records
+}
+
+// returns the id and body of every set in the message
+func parseFlowSets(t *testing.T, data []byte) map[uint16][]byte {
+	sets := make(map[uint16][]byte)
+	for len(data) > 0 {
+		if len(data) < 4 {
+			t.Fatalf("truncated set header")
+		}
+		id := binary.BigEndian.Uint16(data[0:])
+		size := int(binary.BigEndian.Uint16(data[2:]))
+		if size < 4 || size > len(data) {
+			t.Fatalf("invalid set length %d", size)
+		} else if size%4 != 0 {
+			t.Fatalf("set %d is not padded: %d bytes", id, size)
+		}
+		sets[id] = data[4:size]
+		data = data[size:]
+	}
+	return sets
+}
+
+func TestFlowExporterNetFlowV9(t *testing.T) {
+	e := NewFlowExporter(NetFlowV9, 42)
+	e.Boot = time.Unix(1499999990, 0)
+	now := time.Unix(1500000010, 0)
+
+	msgs := e.Encode(testFlowRecords(2, false), now)
+	if len(msgs) != 1 {
+		t.Fatalf("expected one message, got %d", len(msgs))
+	}
+
+	msg := msgs[0]
+	if v := binary.BigEndian.Uint16(msg[0:]); v != NetFlowV9 {
+		t.Fatalf("unexpected version %d", v)
+	} else if count := binary.BigEndian.Uint16(msg[2:]); count != 3 {
+		t.Fatalf("expected a count of 3 (1 template, 2 records), got %d", count)
+	} else if uptime := binary.BigEndian.Uint32(msg[4:]); uptime != 20000 {
+		t.Fatalf("unexpected uptime %d", uptime)
+	} else if secs := binary.BigEndian.Uint32(msg[8:]); secs != 1500000010 {
+		t.Fatalf("unexpected unix seconds %d", secs)
+	} else if seq := binary.BigEndian.Uint32(msg[12:]); seq != 0 {
+		t.Fatalf("unexpected sequence %d", seq)
+	} else if id := binary.BigEndian.Uint32(msg[16:]); id != 42 {
+		t.Fatalf("unexpected source id %d", id)
+	}
+
+	sets := parseFlowSets(t, msg[netflowV9HeaderSize:])
+	tpl, found := sets[netflowV9Templates]
+	if !found {
+		t.Fatalf("template flowset not found")
+	} else if id := binary.BigEndian.Uint16(tpl[0:]); id != flowTemplateIPv4 {
+		t.Fatalf("unexpected template id %d", id)
+	} else if fields := binary.BigEndian.Uint16(tpl[2:]); fields != 10 {
+		t.Fatalf("expected 10 fields, got %d", fields)
+	}
+
+	data, found := sets[flowTemplateIPv4]
+	if !found {
+		t.Fatalf("data flowset not found")
+	}
+	// 4+4+2+2+1+1+8+8+4+4 bytes per record, padded
+	if len(data) != 76 {
+		t.Fatalf("unexpected data flowset length %d", len(data))
+	}
+
+	rec := data[38:]
+	if src := net.IP(rec[0:4]); !src.Equal(net.ParseIP("192.168.1.10")) {
+		t.Fatalf("unexpected source %s", src)
+	} else if port := binary.BigEndian.Uint16(rec[8:]); port != 40001 {
+		t.Fatalf("unexpected source port %d", port)
+	} else if rec[12] != 17 {
+		t.Fatalf("unexpected protocol %d", rec[12])
+	} else if bytes := binary.BigEndian.Uint64(rec[22:]); bytes != 128 {
+		t.Fatalf("unexpected bytes %d", bytes)
+	} else if first := binary.BigEndian.Uint32(rec[30:]); first != 10000 {
+		t.Fatalf("unexpected first switched %d", first)
+	} else if last := binary.BigEndian.Uint32(rec[34:]); last != 11000 {
+		t.Fatalf("unexpected last switched %d", last)
+	}
+
+	msgs = e.Encode(testFlowRecords(1, false), now)
+	if seq := binary.BigEndian.Uint32(msgs[0][12:]); seq != 1 {
+		t.Fatalf("expected the sequence to count the packets, got %d", seq)
+	}
+}
+
+func TestFlowExporterIPFIX(t *testing.T) {
+	e := NewFlowExporter(IPFIX, 7)
+	now := time.Unix(1500000010, 0)
+
+	records := append(testFlowRecords(FlowMaxRecords, false), testFlowRecords(3, true)...)
+	msgs := e.Encode(records, now)
+	if len(msgs) != 2 {
+		t.Fatalf("expected two messages, got %d", len(msgs))
+	}
+
+	for i, msg := range msgs {
+		if v := binary.BigEndian.Uint16(msg[0:]); v != IPFIX {
+			t.Fatalf("unexpected version %d", v)
+		} else if size := binary.BigEndian.Uint16(msg[2:]); int(size) != len(msg) {
+			t.Fatalf("expected a length of %d, got %d", len(msg), size)
+		} else if secs := binary.BigEndian.Uint32(msg[4:]); secs != 1500000010 {
+			t.Fatalf("unexpected export time %d", secs)
+		} else if domain := binary.BigEndian.Uint32(msg[12:]); domain != 7 {
+			t.Fatalf("unexpected domain %d", domain)
+		}
+
+		// the sequence counts the data records sent before
+		expected := uint32(i * FlowMaxRecords)
+		if seq := binary.BigEndian.Uint32(msg[8:]); seq != expected {
+			t.Fatalf("expected sequence %d, got %d", expected, seq)
+		}
+	}
+
+	sets := parseFlowSets(t, msgs[1][ipfixHeaderSize:])
+	if _, found := sets[ipfixTemplates]; !found {
+		t.Fatalf("template set not found")
+	} else if _, found := sets[flowTemplateIPv4]; found {
+		t.Fatalf("unexpected IPv4 data set")
+	}
+
+	data, found := sets[flowTemplateIPv6]
+	if !found {
+		t.Fatalf("IPv6 data set not found")
+	}
+	// 16+16+2+2+1+1+8+8+8+8 bytes per record, padded
+	if len(data) != 212 {
+		t.Fatalf("unexpected data set length %d", len(data))
+	}
+
+	if dst := net.IP(data[16:32]); !dst.Equal(net.ParseIP("2001:4860:4860::8888")) {
+		t.Fatalf("unexpected destination %s", dst)
+	} else if start := binary.BigEndian.Uint64(data[54:]); start != 1500000000000 {
+		t.Fatalf("unexpected start %d", start)
+	} else if end := binary.BigEndian.Uint64(data[62:]); end != 1500000001000 {
+		t.Fatalf("unexpected end %d", end)
+	}
+}
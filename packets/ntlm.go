@@ -45,6 +45,11 @@ const (
 
 	NtlmV1 = 1
 	NtlmV2 = 2
+
+	// message types
+	NTLM_NEGOTIATE    = 1
+	NTLM_CHALLENGE    = 2
+	NTLM_AUTHENTICATE = 3
 )
 
 type NTLMChallengeResponse struct {
@@ -119,6 +124,40 @@ func NewNTLMState() *NTLMState {
 	}
 }
 
+// NewNTLMChallengeResponse pairs the raw NTLMSSP challenge and authenticate
+// messages, as they are found in protocols not encoding them in base64.
+func NewNTLMChallengeResponse(challenge []byte, response []byte) NTLMChallengeResponse {
+	return NTLMChallengeResponse{
+		Challenge: base64.StdEncoding.EncodeToString(challenge),
+		Response:  base64.StdEncoding.EncodeToString(response),
+	}
+}
+
+// complete returns true if the messages are not truncated.
+func (sr NTLMChallengeResponse) complete() bool {
+	if len(sr.getChallengeBytes()) < NTLM_TYPE2_CHALLENGE_OFFSET+8 {
+		return false
+	}
+
+	b := sr.getResponseBytes()
+	if len(b) < NTLM_TYPE3_MINSIZE {
+		return false
+	}
+
+	r := sr.getResponseHeader()
+	for _, buf := range [][2]uint16{
+		{r.LmOffset, r.LmLen},
+		{r.NtOffset, r.NtLen},
+		{r.DomainOffset, r.DomainLen},
+		{r.UserOffset, r.UserLen},
+	} {
+		if int(buf[0])+int(buf[1]) > len(b) {
+			return false
+		}
+	}
+	return r.NtLen == 24 || r.NtLen >= 16
+}
+
 func (sr NTLMChallengeResponse) getServerChallenge() string {
 	dataCallenge := sr.getChallengeBytes()
 	//offset to the challenge and the challenge is 8 bytes long
@@ -135,7 +174,9 @@ func (sr NTLMChallengeResponse) getResponseBytes() []byte {
 	return dataResponse
 }
 func (sr *NTLMChallengeResponse) Parsed() (NTLMChallengeResponseParsed, error) {
-	if sr.isNtlmV1() {
+	if !sr.complete() {
+		return NTLMChallengeResponseParsed{}, errors.New("Truncated NTLM messages")
+	} else if sr.isNtlmV1() {
 		return sr.ParsedNtLMv1()
 	}
 	return sr.ParsedNtLMv2()
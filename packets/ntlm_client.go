@@ -0,0 +1,146 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	ntlmFlagUnicode          = 0x00000001
+	ntlmFlagRequestTarget    = 0x00000004
+	ntlmFlagNTLM             = 0x00000200
+	ntlmFlagAnonymous        = 0x00000800
+	ntlmFlagAlwaysSign       = 0x00008000
+	ntlmFlagExtendedSecurity = 0x00080000
+	ntlmFlagTargetInfo       = 0x00800000
+	ntlmFlagVersion          = 0x02000000
+	ntlmFlag128              = 0x20000000
+	ntlmFlag56               = 0x80000000
+
+	ntlmClientFlags = ntlmFlagUnicode | ntlmFlagRequestTarget | ntlmFlagNTLM | ntlmFlagAlwaysSign |
+		ntlmFlagExtendedSecurity | ntlmFlagTargetInfo | ntlmFlagVersion | ntlmFlag128 | ntlmFlag56
+
+	// target info attributes
+	ntlmAvEOL             = 0
+	ntlmAvNbComputerName  = 1
+	ntlmAvNbDomainName    = 2
+	ntlmAvDNSComputerName = 3
+	ntlmAvDNSDomainName   = 4
+	ntlmAvDNSTreeName     = 5
+)
+
+var ErrNTLMChallengeInvalid = errors.New("not a valid NTLM challenge message")
+
+// NTLMChallengeInfo is what the challenge of a server tells about it.
+type NTLMChallengeInfo struct {
+	Flags           uint32 `json:"flags"`
+	Target          string `json:"target"`
+	NetBIOSComputer string `json:"netbios_computer"`
+	NetBIOSDomain   string `json:"netbios_domain"`
+	DNSComputer     string `json:"dns_computer"`
+	DNSDomain       string `json:"dns_domain"`
+	DNSTree         string `json:"dns_tree"`
+	// major.minor.build of the operating system, if sent
+	OSVersion string `json:"os_version"`
+}
+
+func ntlmHeader(msgType uint32, size int) []byte {
+	msg := make([]byte, size)
+	copy(msg, ntlmsspSignature)
+	binary.LittleEndian.PutUint32(msg[NTLM_TYPE_OFFSET:], msgType)
+	return msg
+}
+
+func ntlmBuffer(msg []byte, offset int) []byte {
+	if len(msg) < offset+NTLM_BUFFER_SIZE {
+		return nil
+	}
+	length := int(binary.LittleEndian.Uint16(msg[offset+NTLM_BUFFER_LEN_OFFSET:]))
+	start := int(binary.LittleEndian.Uint32(msg[offset+NTLM_BUFFER_OFFSET_OFFSET:]))
+	if length == 0 || start+length > len(msg) {
+		return nil
+	}
+	return msg[start : start+length]
+}
+
+// NewNTLMNegotiate creates the first NTLMSSP message of an authentication,
+// without domain and workstation.
+func NewNTLMNegotiate() []byte {
+	msg := ntlmHeader(NTLM_NEGOTIATE, 40)
+	binary.LittleEndian.PutUint32(msg[NTLM_TYPE1_FLAGS_OFFSET:], ntlmClientFlags)
+	return msg
+}
+
+// ParseNTLMChallengeInfo parses the target name and info of an NTLMSSP
+// challenge message.
+func ParseNTLMChallengeInfo(msg []byte) (*NTLMChallengeInfo, error) {
+	if len(msg) < NTLM_TYPE2_DATA_OFFSET || !bytes.Equal(msg[:len(ntlmsspSignature)], ntlmsspSignature) ||
+		binary.LittleEndian.Uint32(msg[NTLM_TYPE_OFFSET:]) != NTLM_CHALLENGE {
+		return nil, ErrNTLMChallengeInvalid
+	}
+
+	info := &NTLMChallengeInfo{
+		Flags:  binary.LittleEndian.Uint32(msg[NTLM_TYPE2_FLAGS_OFFSET:]),
+		Target: utf16String(ntlmBuffer(msg, NTLM_TYPE2_TARGET_OFFSET)),
+	}
+
+	if info.Flags&ntlmFlagVersion != 0 && len(msg) >= NTLM_TYPE2_DATA_OFFSET+8 {
+		version := msg[NTLM_TYPE2_DATA_OFFSET:]
+		info.OSVersion = fmt.Sprintf("%d.%d.%d", version[0], version[1], binary.LittleEndian.Uint16(version[2:]))
+	}
+
+	targetInfo := ntlmBuffer(msg, NTLM_TYPE2_TARGETINFO_OFFSET)
+	for len(targetInfo) >= 4 {
+		id := binary.LittleEndian.Uint16(targetInfo[0:])
+		size := int(binary.LittleEndian.Uint16(targetInfo[2:]))
+		if id == ntlmAvEOL || 4+size > len(targetInfo) {
+			break
+		}
+
+		value := utf16String(targetInfo[4 : 4+size])
+		switch id {
+		case ntlmAvNbComputerName:
+			info.NetBIOSComputer = value
+		case ntlmAvNbDomainName:
+			info.NetBIOSDomain = value
+		case ntlmAvDNSComputerName:
+			info.DNSComputer = value
+		case ntlmAvDNSDomainName:
+			info.DNSDomain = value
+		case ntlmAvDNSTreeName:
+			info.DNSTree = value
+		}
+
+		targetInfo = targetInfo[4+size:]
+	}
+
+	return info, nil
+}
+
+// NewNTLMAnonymousAuthenticate creates the last NTLMSSP message of an
+// anonymous authentication, with no user and an empty NT response.
+func NewNTLMAnonymousAuthenticate(challenge *NTLMChallengeInfo) []byte {
+	// the version comes before the payload, the LM response is a single zero
+	const payloadOffset = NTLM_TYPE3_DATA_OFFSET + 8
+
+	msg := ntlmHeader(NTLM_AUTHENTICATE, payloadOffset+1)
+	binary.LittleEndian.PutUint16(msg[NTLM_TYPE3_LMRESP_OFFSET+NTLM_BUFFER_LEN_OFFSET:], 1)
+	binary.LittleEndian.PutUint16(msg[NTLM_TYPE3_LMRESP_OFFSET+NTLM_BUFFER_MAXLEN_OFFSET:], 1)
+	for _, offset := range []int{
+		NTLM_TYPE3_LMRESP_OFFSET,
+		NTLM_TYPE3_NTRESP_OFFSET,
+		NTLM_TYPE3_DOMAIN_OFFSET,
+		NTLM_TYPE3_USER_OFFSET,
+		NTLM_TYPE3_WORKSTN_OFFSET,
+		NTLM_TYPE3_SESSIONKEY_OFFSET,
+	} {
+		binary.LittleEndian.PutUint32(msg[offset+NTLM_BUFFER_OFFSET_OFFSET:], payloadOffset)
+	}
+
+	flags := uint32(ntlmClientFlags|ntlmFlagAnonymous) & (challenge.Flags | ntlmFlagAnonymous)
+	binary.LittleEndian.PutUint32(msg[NTLM_TYPE3_FLAGS_OFFSET:], flags)
+
+	return msg
+}
@@ -0,0 +1,170 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	NTPPort = 123
+
+	ntpHeaderSize  = 48
+	ntpControlSize = 12
+	ntpPrivateSize = 8
+	// seconds between the NTP and the unix epochs
+	ntpEpochOffset = 2208988800
+)
+
+const (
+	NTPModeSymmetricActive  = 1
+	NTPModeSymmetricPassive = 2
+	NTPModeClient           = 3
+	NTPModeServer           = 4
+	NTPModeBroadcast        = 5
+	NTPModeControl          = 6
+	NTPModePrivate          = 7
+)
+
+const (
+	// mode 7 request codes returning the list of the last clients of the server
+	NTPMonGetList  = 20
+	NTPMonGetList1 = 42
+)
+
+var NTPModeNames = map[uint8]string{
+	NTPModeSymmetricActive:  "symmetric active",
+	NTPModeSymmetricPassive: "symmetric passive",
+	NTPModeClient:           "client",
+	NTPModeServer:           "server",
+	NTPModeBroadcast:        "broadcast",
+	NTPModeControl:          "control",
+	NTPModePrivate:          "private",
+}
+
+var NTPControlOpcodeNames = map[uint8]string{
+	1:  "read status",
+	2:  "read variables",
+	3:  "write variables",
+	4:  "read clock variables",
+	5:  "write clock variables",
+	6:  "set trap",
+	7:  "async message",
+	8:  "configure",
+	9:  "save config",
+	10: "read mru",
+	11: "read ordlist",
+	12: "request nonce",
+}
+
+var ErrNTPInvalid = errors.New("not a valid NTP packet")
+
+// NTPPacket is a NTP time packet or a mode 6 and mode 7 management one.
+type NTPPacket struct {
+	Leap    uint8 `json:"leap"`
+	Version uint8 `json:"version"`
+	Mode    uint8 `json:"mode"`
+	// time packets only
+	Stratum     uint8     `json:"stratum"`
+	ReferenceID string    `json:"reference_id"`
+	Transmit    time.Time `json:"transmit"`
+	// mode 6 and 7 only
+	Response bool   `json:"response"`
+	Opcode   uint8  `json:"opcode"`
+	Sequence uint16 `json:"sequence"`
+}
+
+// ModeName returns the name of the association mode.
+func (p *NTPPacket) ModeName() string {
+	if name, found := NTPModeNames[p.Mode]; found {
+		return name
+	}
+	return fmt.Sprintf("mode %d", p.Mode)
+}
+
+// OpcodeName returns the name of the mode 6 opcode or of the mode 7 request
+// code.
+func (p *NTPPacket) OpcodeName() string {
+	if p.Mode == NTPModePrivate {
+		if p.IsMonlist() {
+			return "monlist"
+		}
+		return fmt.Sprintf("request %d", p.Opcode)
+	} else if name, found := NTPControlOpcodeNames[p.Opcode]; found {
+		return name
+	}
+	return fmt.Sprintf("opcode %d", p.Opcode)
+}
+
+// IsManagement returns true for the mode 6 and mode 7 packets, whose
+// responses can be much bigger than the requests and are abused as
+// amplification vectors when the server answers anybody.
+func (p *NTPPacket) IsManagement() bool {
+	return p.Mode == NTPModeControl || p.Mode == NTPModePrivate
+}
+
+// IsMonlist returns true for the mode 7 monlist requests and responses.
+func (p *NTPPacket) IsMonlist() bool {
+	return p.Mode == NTPModePrivate && (p.Opcode == NTPMonGetList || p.Opcode == NTPMonGetList1)
+}
+
+func ntpTime(data []byte) time.Time {
+	secs := binary.BigEndian.Uint32(data)
+	frac := binary.BigEndian.Uint32(data[4:])
+	if secs == 0 && frac == 0 {
+		return time.Time{}
+	}
+	nsecs := (int64(frac) * 1e9) >> 32
+	return time.Unix(int64(secs)-ntpEpochOffset, nsecs).UTC()
+}
+
+// the reference id is a 4 characters code for stratum 0 and 1 servers, and
+// the address of the upstream server otherwise
+func ntpReferenceID(stratum uint8, data []byte) string {
+	if stratum <= 1 {
+		return strings.TrimRight(string(data[:4]), "\x00")
+	}
+	return net.IP(data[:4]).String()
+}
+
+// ParseNTP parses a NTP packet.
+func ParseNTP(payload []byte) (*NTPPacket, error) {
+	if len(payload) < ntpPrivateSize {
+		return nil, ErrNTPInvalid
+	}
+
+	p := &NTPPacket{
+		Leap:    payload[0] >> 6,
+		Version: (payload[0] >> 3) & 0x07,
+		Mode:    payload[0] & 0x07,
+	}
+	if p.Version < 1 || p.Version > 4 || p.Mode == 0 {
+		return nil, ErrNTPInvalid
+	}
+
+	switch p.Mode {
+	case NTPModeControl:
+		if len(payload) < ntpControlSize {
+			return nil, ErrNTPInvalid
+		}
+		p.Response = payload[1]&0x80 != 0
+		p.Opcode = payload[1] & 0x1f
+		p.Sequence = binary.BigEndian.Uint16(payload[2:])
+	case NTPModePrivate:
+		p.Response = payload[0]&0x80 != 0
+		p.Sequence = uint16(payload[1] & 0x7f)
+		p.Opcode = payload[3]
+	default:
+		if len(payload) < ntpHeaderSize {
+			return nil, ErrNTPInvalid
+		}
+		p.Stratum = payload[1]
+		p.ReferenceID = ntpReferenceID(p.Stratum, payload[12:])
+		p.Transmit = ntpTime(payload[40:])
+	}
+
+	return p, nil
+}
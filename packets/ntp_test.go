@@ -0,0 +1,78 @@
+package packets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNTPServer(t *testing.T) {
+	payload := make([]byte, ntpHeaderSize)
+	// version 4, server, stratum 2 synced to 192.168.1.1
+	payload[0] = 0x24
+	payload[1] = 2
+	copy(payload[12:], []byte{192, 168, 1, 1})
+	// 2020-01-01 00:00:00.5 UTC
+	copy(payload[40:], []byte{0xe1, 0xb6, 0x5f, 0x80, 0x80, 0x00, 0x00, 0x00})
+
+	p, err := ParseNTP(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.Version != 4 || p.Mode != NTPModeServer || p.ModeName() != "server" || p.IsManagement() {
+		t.Fatalf("unexpected packet %+v", p)
+	} else if p.Stratum != 2 || p.ReferenceID != "192.168.1.1" {
+		t.Fatalf("unexpected packet %+v", p)
+	} else if expected := time.Date(2020, 1, 1, 0, 0, 0, 5e8, time.UTC); !p.Transmit.Equal(expected) {
+		t.Fatalf("expected %v, got %v", expected, p.Transmit)
+	}
+
+	// stratum 1 with a reference clock
+	payload[1] = 1
+	copy(payload[12:], []byte("GPS\x00"))
+	if p, err = ParseNTP(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.ReferenceID != "GPS" {
+		t.Fatalf("unexpected reference id %s", p.ReferenceID)
+	}
+}
+
+func TestParseNTPManagement(t *testing.T) {
+	// ntpdc -c monlist
+	monlist := []byte{0x17, 0x00, 0x03, 0x2a, 0x00, 0x00, 0x00, 0x00}
+	p, err := ParseNTP(monlist)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !p.IsManagement() || !p.IsMonlist() || p.Response || p.OpcodeName() != "monlist" {
+		t.Fatalf("unexpected packet %+v", p)
+	}
+
+	monlist[0] |= 0x80
+	if p, err = ParseNTP(monlist); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !p.Response {
+		t.Fatalf("expected a response %+v", p)
+	}
+
+	// ntpq -c rv
+	control := []byte{0x16, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	if p, err = ParseNTP(control); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.Mode != NTPModeControl || p.OpcodeName() != "read variables" || p.Sequence != 1 || p.IsMonlist() {
+		t.Fatalf("unexpected packet %+v", p)
+	}
+}
+
+func TestParseNTPInvalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		{0x23},
+		// version 0
+		append([]byte{0x03}, make([]byte, ntpHeaderSize-1)...),
+		// truncated client
+		append([]byte{0x23}, make([]byte, 20)...),
+		// truncated control
+		{0x16, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00},
+	} {
+		if _, err := ParseNTP(payload); err != ErrNTPInvalid {
+			t.Fatalf("expected %v for %x, got %v", ErrNTPInvalid, payload, err)
+		}
+	}
+}
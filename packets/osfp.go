@@ -0,0 +1,209 @@
+package packets
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// the TTLs the stacks start from, the observed one is lower by the hops
+var osInitialTTLs = []uint8{32, 64, 128, 255}
+
+// SYN option layouts of the most common stacks, as in the p0f database
+var tcpOSSignatures = []struct {
+	ttl     uint8
+	options string
+	os      string
+}{
+	{64, "mss,sok,ts,nop,ws", "linux"},
+	{64, "mss,nop,nop,sok,nop,ws", "linux"},
+	{64, "mss,nop,nop,ts,nop,ws", "linux"},
+	{128, "mss,nop,ws,nop,nop,sok", "windows"},
+	{128, "mss,nop,ws,sok,ts", "windows"},
+	{128, "mss,nop,nop,sok", "windows"},
+	{64, "mss,nop,ws,nop,nop,ts,sok,eol", "macos"},
+	{64, "mss,nop,ws,sok,ts", "freebsd"},
+	{64, "mss,nop,nop,sok,nop,ws,nop,nop,ts", "openbsd"},
+}
+
+// tokens of the User-Agent header, the first matching one wins
+var httpUserAgentOS = []struct {
+	token string
+	os    string
+}{
+	{"Windows", "windows"},
+	{"Android", "android"},
+	{"CrOS", "chromeos"},
+	{"iPhone", "ios"},
+	{"iPad", "ios"},
+	{"Mac OS X", "macos"},
+	{"Macintosh", "macos"},
+	{"Linux", "linux"},
+}
+
+var tcpOptionNames = map[layers.TCPOptionKind]string{
+	layers.TCPOptionKindEndList:       "eol",
+	layers.TCPOptionKindNop:           "nop",
+	layers.TCPOptionKindMSS:           "mss",
+	layers.TCPOptionKindWindowScale:   "ws",
+	layers.TCPOptionKindSACKPermitted: "sok",
+	layers.TCPOptionKindSACK:          "sack",
+	layers.TCPOptionKindTimestamps:    "ts",
+}
+
+// TCPSignature is what a SYN tells about the TCP/IP stack that sent it.
+type TCPSignature struct {
+	TTL         uint8
+	DF          bool
+	Window      uint16
+	MSS         uint16
+	WindowScale int
+	Options     string
+}
+
+func initialTTL(ttl uint8) uint8 {
+	for _, initial := range osInitialTTLs {
+		if ttl <= initial {
+			return initial
+		}
+	}
+	return 255
+}
+
+// ParseTCPSignature returns the signature of a SYN packet, or nil if the
+// packet is not one.
+func ParseTCPSignature(pkt gopacket.Packet) *TCPSignature {
+	tcp, ok := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP)
+	if !ok || !tcp.SYN || tcp.ACK {
+		return nil
+	}
+
+	sig := &TCPSignature{
+		Window:      tcp.Window,
+		WindowScale: -1,
+	}
+
+	if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		sig.TTL = initialTTL(ip4.TTL)
+		sig.DF = ip4.Flags&layers.IPv4DontFragment != 0
+	} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		sig.TTL = initialTTL(ip6.HopLimit)
+	} else {
+		return nil
+	}
+
+	options := make([]string, 0, len(tcp.Options))
+	for _, opt := range tcp.Options {
+		name, found := tcpOptionNames[opt.OptionType]
+		if !found {
+			name = fmt.Sprintf("?%d", opt.OptionType)
+		}
+		options = append(options, name)
+
+		switch opt.OptionType {
+		case layers.TCPOptionKindMSS:
+			if len(opt.OptionData) == 2 {
+				sig.MSS = uint16(opt.OptionData[0])<<8 | uint16(opt.OptionData[1])
+			}
+		case layers.TCPOptionKindWindowScale:
+			if len(opt.OptionData) == 1 {
+				sig.WindowScale = int(opt.OptionData[0])
+			}
+		}
+	}
+	sig.Options = strings.Join(options, ",")
+
+	return sig
+}
+
+// String returns the signature in a p0f like format:
+// initial ttl:window,scale:options layout:flags
+func (s *TCPSignature) String() string {
+	window := fmt.Sprintf("%d", s.Window)
+	if s.MSS > 0 && s.Window > 0 && s.Window%s.MSS == 0 {
+		window = fmt.Sprintf("mss*%d", s.Window/s.MSS)
+	}
+
+	scale := "*"
+	if s.WindowScale >= 0 {
+		scale = fmt.Sprintf("%d", s.WindowScale)
+	}
+
+	flags := ""
+	if s.DF {
+		flags = "df"
+	}
+
+	return fmt.Sprintf("%d:%s,%s:%s:%s", s.TTL, window, scale, s.Options, flags)
+}
+
+// OS returns the operating system the signature belongs to, if known.
+func (s *TCPSignature) OS() string {
+	for _, known := range tcpOSSignatures {
+		if known.ttl == s.TTL && known.options == s.Options {
+			return known.os
+		}
+	}
+	return ""
+}
+
+// HTTPUserAgent returns the User-Agent of an HTTP request, if any.
+func HTTPUserAgent(payload []byte) string {
+	// only the first segment of a request has the headers
+	lines := bytes.Split(payload, []byte("\r\n"))
+	if len(lines) < 2 || !bytes.Contains(lines[0], []byte(" HTTP/1.")) || bytes.IndexByte(lines[0], ' ') < 3 {
+		return ""
+	}
+
+	for _, line := range lines[1:] {
+		if len(line) == 0 {
+			break
+		} else if colon := bytes.IndexByte(line, ':'); colon > 0 && strings.EqualFold(string(line[:colon]), "User-Agent") {
+			return string(bytes.TrimSpace(line[colon+1:]))
+		}
+	}
+	return ""
+}
+
+// UserAgentOS returns the operating system a User-Agent mentions, if any.
+func UserAgentOS(ua string) string {
+	for _, known := range httpUserAgentOS {
+		if strings.Contains(ua, known.token) {
+			return known.os
+		}
+	}
+	return ""
+}
+
+// OSGetMeta returns what the packet tells about the operating system of its
+// sender: its SYN signature, its User-Agent or its DHCP options, the meta
+// names tell where each guess comes from.
+func OSGetMeta(pkt gopacket.Packet) map[string]string {
+	if sig := ParseTCPSignature(pkt); sig != nil {
+		meta := map[string]string{
+			"tcp:signature": sig.String(),
+		}
+		if os := sig.OS(); os != "" {
+			meta["tcp:os"] = os
+		}
+		return meta
+	} else if tcp, ok := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
+		if ua := HTTPUserAgent(tcp.Payload); ua != "" {
+			if os := UserAgentOS(ua); os != "" {
+				return map[string]string{
+					"http:os": os,
+				}
+			}
+		}
+	} else if dhcp, ok := pkt.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4); ok && dhcp.Operation == layers.DHCPOpRequest {
+		if os := ParseDHCPOptions(dhcp).OS(); os != "" {
+			return map[string]string{
+				"dhcp:os": os,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func osTestSYN(t *testing.T, ttl uint8, window uint16, options []layers.TCPOption, payload string) gopacket.Packet {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0xde, 0xad, 0xbe, 0xef, 0x00, 0x01},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      ttl,
+		Flags:    layers.IPv4DontFragment,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP("192.168.1.10").To4(),
+		DstIP:    net.ParseIP("192.168.1.1").To4(),
+	}
+	tcp := layers.TCP{
+		SrcPort: 40000,
+		DstPort: 80,
+		SYN:     payload == "",
+		ACK:     payload != "",
+		PSH:     payload != "",
+		Window:  window,
+		Options: options,
+	}
+	tcp.SetNetworkLayerForChecksum(&ip4)
+
+	err, data := Serialize(&eth, &ip4, &tcp, gopacket.Payload([]byte(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func osTestOption(kind layers.TCPOptionKind, data ...byte) layers.TCPOption {
+	opt := layers.TCPOption{
+		OptionType:   kind,
+		OptionLength: uint8(2 + len(data)),
+		OptionData:   data,
+	}
+	if kind == layers.TCPOptionKindNop {
+		opt.OptionLength = 1
+	}
+	return opt
+}
+
+func TestParseTCPSignatureLinux(t *testing.T) {
+	pkt := osTestSYN(t, 61, 64240, []layers.TCPOption{
+		osTestOption(layers.TCPOptionKindMSS, 0x05, 0xb4),
+		osTestOption(layers.TCPOptionKindSACKPermitted),
+		osTestOption(layers.TCPOptionKindTimestamps, 0, 0, 0, 1, 0, 0, 0, 0),
+		osTestOption(layers.TCPOptionKindNop),
+		osTestOption(layers.TCPOptionKindWindowScale, 7),
+	}, "")
+
+	sig := ParseTCPSignature(pkt)
+	if sig == nil {
+		t.Fatalf("expected a signature")
+	} else if s := sig.String(); s != "64:mss*44,7:mss,sok,ts,nop,ws:df" {
+		t.Fatalf("unexpected signature %s", s)
+	} else if os := sig.OS(); os != "linux" {
+		t.Fatalf("expected linux, got '%s'", os)
+	}
+
+	meta := OSGetMeta(pkt)
+	if meta["tcp:os"] != "linux" || meta["tcp:signature"] != sig.String() {
+		t.Fatalf("unexpected meta %v", meta)
+	}
+}
+
+func TestParseTCPSignatureWindows(t *testing.T) {
+	pkt := osTestSYN(t, 126, 64240, []layers.TCPOption{
+		osTestOption(layers.TCPOptionKindMSS, 0x05, 0xb4),
+		osTestOption(layers.TCPOptionKindNop),
+		osTestOption(layers.TCPOptionKindWindowScale, 8),
+		osTestOption(layers.TCPOptionKindNop),
+		osTestOption(layers.TCPOptionKindNop),
+		osTestOption(layers.TCPOptionKindSACKPermitted),
+	}, "")
+
+	if sig := ParseTCPSignature(pkt); sig == nil {
+		t.Fatalf("expected a signature")
+	} else if os := sig.OS(); os != "windows" {
+		t.Fatalf("expected windows, got '%s' for %s", os, sig)
+	}
+}
+
+func TestParseTCPSignatureNotSYN(t *testing.T) {
+	pkt := osTestSYN(t, 64, 502, nil, "GET / HTTP/1.1\r\nHost: example.com\r\n"+
+		"User-Agent: Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15\r\n\r\n")
+
+	if sig := ParseTCPSignature(pkt); sig != nil {
+		t.Fatalf("unexpected signature %s", sig)
+	} else if meta := OSGetMeta(pkt); len(meta) != 1 || meta["http:os"] != "ios" {
+		t.Fatalf("unexpected meta %v", meta)
+	}
+}
+
+func TestUserAgentOS(t *testing.T) {
+	for ua, expected := range map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36":           "windows",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36":            "android",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15":   "macos",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0": "linux",
+		"curl/8.4.0": "",
+	} {
+		if os := UserAgentOS(ua); os != expected {
+			t.Fatalf("expected '%s' for %s, got '%s'", expected, ua, os)
+		}
+	}
+
+	if ua := HTTPUserAgent([]byte("HTTP/1.1 200 OK\r\nServer: nginx\r\n\r\n")); ua != "" {
+		t.Fatalf("unexpected user agent %s", ua)
+	}
+}
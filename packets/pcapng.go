@@ -0,0 +1,148 @@
+package packets
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// pcapng block types and options, the writer of gopacket can't add
+// comments to the packets
+const (
+	pcapngSectionHeader  = 0x0a0d0d0a
+	pcapngInterface      = 0x00000001
+	pcapngEnhancedPacket = 0x00000006
+	pcapngByteOrderMagic = 0x1a2b3c4d
+
+	pcapngOptEnd         = 0
+	pcapngOptComment     = 1
+	pcapngOptHardware    = 2
+	pcapngOptOS          = 3
+	pcapngOptApplication = 4
+	pcapngOptIfName      = 2
+	pcapngOptIfDesc      = 3
+	pcapngOptIfFilter    = 11
+	pcapngOptIfOS        = 12
+)
+
+type pcapngOption struct {
+	code  uint16
+	value []byte
+}
+
+// PcapNGWriter writes a single interface pcapng file where every packet can
+// have a comment, timestamps have the default microseconds resolution.
+type PcapNGWriter struct {
+	w *bufio.Writer
+}
+
+func pcapngPadding(n int) int {
+	return (4 - n%4) % 4
+}
+
+func pcapngStringOptions(options []pcapngOption, code uint16, value string) []pcapngOption {
+	if value != "" {
+		options = append(options, pcapngOption{code: code, value: []byte(value)})
+	}
+	return options
+}
+
+func pcapngOptions(options []pcapngOption) []byte {
+	if len(options) == 0 {
+		return nil
+	}
+
+	data := []byte{}
+	for _, opt := range options {
+		hdr := make([]byte, 4)
+		binary.LittleEndian.PutUint16(hdr[0:], opt.code)
+		binary.LittleEndian.PutUint16(hdr[2:], uint16(len(opt.value)))
+		data = append(data, hdr...)
+		data = append(data, opt.value...)
+		data = append(data, make([]byte, pcapngPadding(len(opt.value)))...)
+	}
+	return append(data, pcapngOptEnd, 0, 0, 0)
+}
+
+func (w *PcapNGWriter) writeBlock(blockType uint32, body []byte) error {
+	size := uint32(12 + len(body))
+	hdr := make([]byte, 8)
+	binary.LittleEndian.PutUint32(hdr[0:], blockType)
+	binary.LittleEndian.PutUint32(hdr[4:], size)
+	if _, err := w.w.Write(hdr); err != nil {
+		return err
+	} else if _, err := w.w.Write(body); err != nil {
+		return err
+	}
+	return binary.Write(w.w, binary.LittleEndian, size)
+}
+
+// NewPcapNGWriter writes the section header and the description of the
+// capture interface.
+func NewPcapNGWriter(w io.Writer, section pcapgo.NgSectionInfo, intf pcapgo.NgInterface) (*PcapNGWriter, error) {
+	writer := &PcapNGWriter{w: bufio.NewWriter(w)}
+
+	// byte order magic, version 1.0 and unknown section length
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1)
+	binary.LittleEndian.PutUint16(shb[6:], 0)
+	binary.LittleEndian.PutUint64(shb[8:], 0xffffffffffffffff)
+
+	options := pcapngStringOptions(nil, pcapngOptComment, section.Comment)
+	options = pcapngStringOptions(options, pcapngOptHardware, section.Hardware)
+	options = pcapngStringOptions(options, pcapngOptOS, section.OS)
+	options = pcapngStringOptions(options, pcapngOptApplication, section.Application)
+	if err := writer.writeBlock(pcapngSectionHeader, append(shb, pcapngOptions(options)...)); err != nil {
+		return nil, err
+	}
+
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], uint16(intf.LinkType))
+	binary.LittleEndian.PutUint32(idb[4:], intf.SnapLength)
+
+	options = pcapngStringOptions(nil, pcapngOptComment, intf.Comment)
+	options = pcapngStringOptions(options, pcapngOptIfName, intf.Name)
+	options = pcapngStringOptions(options, pcapngOptIfDesc, intf.Description)
+	if intf.Filter != "" {
+		// the first byte tells it's a libpcap filter string
+		options = append(options, pcapngOption{code: pcapngOptIfFilter, value: append([]byte{0}, intf.Filter...)})
+	}
+	options = pcapngStringOptions(options, pcapngOptIfOS, intf.OS)
+	if err := writer.writeBlock(pcapngInterface, append(idb, pcapngOptions(options)...)); err != nil {
+		return nil, err
+	}
+
+	return writer, writer.w.Flush()
+}
+
+// WritePacket writes a packet of the interface, with its comment if any.
+func (w *PcapNGWriter) WritePacket(ci gopacket.CaptureInfo, data []byte, comment string) error {
+	if ci.CaptureLength == 0 || ci.CaptureLength > len(data) {
+		ci.CaptureLength = len(data)
+	}
+	if ci.Length < ci.CaptureLength {
+		ci.Length = ci.CaptureLength
+	}
+
+	ts := uint64(ci.Timestamp.UnixNano() / 1000)
+	epb := make([]byte, 20)
+	binary.LittleEndian.PutUint32(epb[0:], 0)
+	binary.LittleEndian.PutUint32(epb[4:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(epb[8:], uint32(ts))
+	binary.LittleEndian.PutUint32(epb[12:], uint32(ci.CaptureLength))
+	binary.LittleEndian.PutUint32(epb[16:], uint32(ci.Length))
+
+	body := append(epb, data[:ci.CaptureLength]...)
+	body = append(body, make([]byte, pcapngPadding(ci.CaptureLength))...)
+	body = append(body, pcapngOptions(pcapngStringOptions(nil, pcapngOptComment, comment))...)
+
+	// like the pcap writer, the file can be read while it's being written
+	if err := w.writeBlock(pcapngEnhancedPacket, body); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
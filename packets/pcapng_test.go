@@ -0,0 +1,66 @@
+package packets
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+func TestPcapNGWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	w, err := NewPcapNGWriter(&buf,
+		pcapgo.NgSectionInfo{Application: "bettercap", Comment: "session 1"},
+		pcapgo.NgInterface{Name: "eth0", Description: "bettercap sniffer", Filter: "not arp", LinkType: layers.LinkTypeEthernet, SnapLength: 65536})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	at := time.Date(2020, 1, 1, 12, 0, 0, 123456000, time.UTC)
+	packets := [][]byte{
+		{0xde, 0xad, 0xbe, 0xef, 0x01},
+		{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+	}
+	for i, data := range packets {
+		comment := ""
+		if i == 0 {
+			comment = "http.request"
+		}
+		ci := gopacket.CaptureInfo{Timestamp: at, CaptureLength: len(data), Length: len(data)}
+		if err := w.WritePacket(ci, data, comment); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("http.request")) {
+		t.Fatal("expected the packet comment")
+	}
+
+	r, err := pcapgo.NewNgReader(&buf, pcapgo.DefaultNgReaderOptions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if info := r.SectionInfo(); info.Application != "bettercap" || info.Comment != "session 1" {
+		t.Fatalf("unexpected section %+v", info)
+	} else if r.LinkType() != layers.LinkTypeEthernet {
+		t.Fatalf("unexpected link type %v", r.LinkType())
+	}
+
+	for i, expected := range packets {
+		data, ci, err := r.ReadPacketData()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		} else if !bytes.Equal(data, expected) {
+			t.Fatalf("unexpected packet %d %x", i, data)
+		} else if !ci.Timestamp.Equal(at) {
+			t.Fatalf("unexpected timestamp %s", ci.Timestamp)
+		}
+	}
+
+	if intf, err := r.Interface(0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if intf.Name != "eth0" || intf.Description != "bettercap sniffer" || intf.Filter != "not arp" || intf.SnapLength != 65536 {
+		t.Fatalf("unexpected interface %+v", intf)
+	}
+}
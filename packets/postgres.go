@@ -0,0 +1,167 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	PostgresPort = 5432
+
+	postgresProtocol3  = 196608
+	postgresSSLRequest = 80877103
+	postgresGSSRequest = 80877104
+
+	// authentication request types
+	PostgresAuthOK        = 0
+	PostgresAuthCleartext = 3
+	PostgresAuthMD5       = 5
+	PostgresAuthSASL      = 10
+)
+
+var PostgresAuthNames = map[int]string{
+	PostgresAuthOK:        "ok",
+	PostgresAuthCleartext: "cleartext",
+	PostgresAuthMD5:       "md5",
+	PostgresAuthSASL:      "sasl",
+}
+
+var ErrPostgresInvalid = errors.New("not a valid PostgreSQL message")
+
+// PostgresStartup is the first message of a client, either the parameters of
+// the session or the request to switch to an encrypted connection.
+type PostgresStartup struct {
+	User       string            `json:"user"`
+	Database   string            `json:"database"`
+	Parameters map[string]string `json:"parameters"`
+	SSL        bool              `json:"ssl"`
+}
+
+// PostgresAuth is the authentication request of the server.
+type PostgresAuth struct {
+	Type int `json:"type"`
+	// only for md5
+	Salt []byte `json:"salt,omitempty"`
+	// only for sasl
+	Mechanisms []string `json:"mechanisms,omitempty"`
+}
+
+func (a *PostgresAuth) Name() string {
+	if name, found := PostgresAuthNames[a.Type]; found {
+		return name
+	}
+	return fmt.Sprintf("type %d", a.Type)
+}
+
+// PostgresHashcat returns a md5 password response in the format of hashcat
+// mode 11100.
+func PostgresHashcat(user string, salt []byte, response string) (string, error) {
+	if len(salt) != 4 || len(response) != 35 || !strings.HasPrefix(response, "md5") {
+		return "", ErrPostgresInvalid
+	}
+	return fmt.Sprintf("$postgres$%s*%x*%s", user, salt, response[3:]), nil
+}
+
+// the type, if any, and payload of the first message
+func postgresMessage(data []byte, typed bool) (byte, []byte, error) {
+	kind := byte(0)
+	if typed {
+		if len(data) == 0 {
+			return 0, nil, ErrPostgresInvalid
+		}
+		kind, data = data[0], data[1:]
+	}
+
+	if len(data) < 4 {
+		return 0, nil, ErrPostgresInvalid
+	}
+	size := int(binary.BigEndian.Uint32(data))
+	if size < 4 || len(data) < size {
+		return 0, nil, ErrPostgresInvalid
+	}
+
+	return kind, data[4:size], nil
+}
+
+// ParsePostgresStartup parses the startup message of the protocol 3.0.
+func ParsePostgresStartup(data []byte) (*PostgresStartup, error) {
+	_, payload, err := postgresMessage(data, false)
+	if err != nil {
+		return nil, err
+	} else if len(payload) < 4 {
+		return nil, ErrPostgresInvalid
+	}
+
+	switch binary.BigEndian.Uint32(payload) {
+	case postgresSSLRequest, postgresGSSRequest:
+		return &PostgresStartup{SSL: true}, nil
+	case postgresProtocol3:
+	default:
+		return nil, ErrPostgresInvalid
+	}
+
+	startup := &PostgresStartup{Parameters: make(map[string]string)}
+	fields := bytes.Split(payload[4:], []byte{0})
+	for i := 0; i+1 < len(fields) && len(fields[i]) > 0; i += 2 {
+		startup.Parameters[string(fields[i])] = string(fields[i+1])
+	}
+
+	if startup.User = startup.Parameters["user"]; startup.User == "" {
+		return nil, ErrPostgresInvalid
+	}
+	// the database defaults to the user name
+	if startup.Database = startup.Parameters["database"]; startup.Database == "" {
+		startup.Database = startup.User
+	}
+
+	return startup, nil
+}
+
+// ParsePostgresAuth parses an authentication request of the server.
+func ParsePostgresAuth(data []byte) (*PostgresAuth, error) {
+	kind, payload, err := postgresMessage(data, true)
+	if err != nil {
+		return nil, err
+	} else if kind != 'R' || len(payload) < 4 {
+		return nil, ErrPostgresInvalid
+	}
+
+	auth := &PostgresAuth{Type: int(binary.BigEndian.Uint32(payload))}
+	switch auth.Type {
+	case PostgresAuthMD5:
+		if len(payload) != 8 {
+			return nil, ErrPostgresInvalid
+		}
+		auth.Salt = append([]byte{}, payload[4:]...)
+	case PostgresAuthSASL:
+		for _, mech := range bytes.Split(payload[4:], []byte{0}) {
+			if len(mech) > 0 {
+				auth.Mechanisms = append(auth.Mechanisms, string(mech))
+			}
+		}
+	}
+
+	return auth, nil
+}
+
+// ParsePostgresPassword parses the password message of the client, either
+// the cleartext password or the md5 response.
+func ParsePostgresPassword(data []byte) (string, error) {
+	kind, payload, err := postgresMessage(data, true)
+	if err != nil {
+		return "", err
+	} else if kind != 'p' || len(payload) == 0 || payload[len(payload)-1] != 0 {
+		return "", ErrPostgresInvalid
+	}
+
+	password := payload[:len(payload)-1]
+	// SASL responses carry binary data after the mechanism name
+	if bytes.IndexByte(password, 0) != -1 {
+		return "", ErrPostgresInvalid
+	}
+
+	return string(password), nil
+}
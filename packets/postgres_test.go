@@ -0,0 +1,66 @@
+package packets
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParsePostgresStartup(t *testing.T) {
+	payload := unhex(t, "00000039000300007573657200616c6963650064617461626173650073616c6573006170706c6963"+
+		"6174696f6e5f6e616d65007073716c0000")
+
+	startup, err := ParsePostgresStartup(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if startup.User != "alice" || startup.Database != "sales" || startup.SSL {
+		t.Fatalf("unexpected startup %+v", startup)
+	} else if startup.Parameters["application_name"] != "psql" {
+		t.Fatalf("unexpected parameters %v", startup.Parameters)
+	}
+
+	if startup, err = ParsePostgresStartup(unhex(t, "0000000804d2162f")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !startup.SSL {
+		t.Fatalf("expected ssl request, got %+v", startup)
+	}
+}
+
+func TestParsePostgresAuth(t *testing.T) {
+	auth, err := ParsePostgresAuth(unhex(t, "520000000c00000005deadbeef"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if auth.Type != PostgresAuthMD5 || auth.Name() != "md5" || !bytes.Equal(auth.Salt, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatalf("unexpected auth %+v", auth)
+	}
+
+	auth, err = ParsePostgresAuth(unhex(t, "520000002a0000000a534352414d2d5348412d32353600534352414d2d5348412d3235362d504c5553"+
+		"0000"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if auth.Type != PostgresAuthSASL || !reflect.DeepEqual(auth.Mechanisms, []string{"SCRAM-SHA-256", "SCRAM-SHA-256-PLUS"}) {
+		t.Fatalf("unexpected auth %+v", auth)
+	}
+}
+
+func TestParsePostgresPassword(t *testing.T) {
+	payload := unhex(t, "70000000286d6435303132333435363738396162636465663031323334353637383961626364656600")
+
+	password, err := ParsePostgresPassword(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if password != "md50123456789abcdef0123456789abcdef" {
+		t.Fatalf("unexpected password %s", password)
+	}
+
+	expected := "$postgres$alice*deadbeef*0123456789abcdef0123456789abcdef"
+	if hash, err := PostgresHashcat("alice", []byte{0xde, 0xad, 0xbe, 0xef}, password); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+
+	if _, err := PostgresHashcat("alice", []byte{0xde, 0xad, 0xbe, 0xef}, "cleartext"); err != ErrPostgresInvalid {
+		t.Fatalf("expected %v, got %v", ErrPostgresInvalid, err)
+	}
+}
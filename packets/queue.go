@@ -173,6 +173,9 @@ func (q *Queue) getPacketMeta(pkt gopacket.Packet) map[string]string {
 	} else if upnp := UPNPGetMeta(pkt); upnp != nil {
 		meta = upnp
 	}
+	for name, value := range OSGetMeta(pkt) {
+		meta[name] = value
+	}
 	return meta
 }
 
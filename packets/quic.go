@@ -0,0 +1,332 @@
+package packets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	QUICVersion1      = 0x00000001
+	QUICVersion2      = 0x6b3343cf
+	QUICVersionDraft  = 0xff00001d
+	quicMaxConnIDSize = 20
+	quicSampleSize    = 16
+
+	quicFramePadding = 0x00
+	quicFramePing    = 0x01
+	quicFrameAck     = 0x02
+	quicFrameAckECN  = 0x03
+	quicFrameCrypto  = 0x06
+)
+
+// initial salts of RFC 9001, RFC 9369 and draft-29
+var quicInitialSalts = map[uint32][]byte{
+	QUICVersion1: {
+		0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+		0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a,
+	},
+	QUICVersion2: {
+		0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93,
+		0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9,
+	},
+	QUICVersionDraft: {
+		0xaf, 0xbf, 0xec, 0x28, 0x99, 0x93, 0xd2, 0x4c, 0x9e, 0x97,
+		0x86, 0xf1, 0x9c, 0x61, 0x11, 0xe0, 0x43, 0x90, 0xa8, 0x99,
+	},
+}
+
+var QUICVersionNames = map[uint32]string{
+	QUICVersion1:     "v1",
+	QUICVersion2:     "v2",
+	QUICVersionDraft: "draft-29",
+}
+
+var (
+	ErrQUICNotInitial = errors.New("not a QUIC initial packet")
+	ErrQUICInvalid    = errors.New("invalid QUIC initial packet")
+)
+
+// QUICKeys are the keys protecting the initial packets of one side.
+type QUICKeys struct {
+	Key []byte
+	IV  []byte
+	HP  []byte
+}
+
+// QUICCryptoFrame is a fragment of the TLS handshake.
+type QUICCryptoFrame struct {
+	Offset uint64
+	Data   []byte
+}
+
+// QUICInitial is a decrypted client initial packet.
+type QUICInitial struct {
+	Version      uint32
+	DCID         []byte
+	SCID         []byte
+	PacketNumber uint64
+	Crypto       []QUICCryptoFrame
+}
+
+// QUICVersionName returns the name of a QUIC version.
+func QUICVersionName(version uint32) string {
+	if name, found := QUICVersionNames[version]; found {
+		return name
+	}
+	return fmt.Sprintf("0x%08x", version)
+}
+
+func quicHKDFExtract(salt, secret []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(secret)
+	return mac.Sum(nil)
+}
+
+// HKDF-Expand-Label of TLS 1.3 with an empty context, the output is never
+// bigger than a SHA-256 hash
+func quicHKDFExpandLabel(secret []byte, label string, size int) []byte {
+	full := "tls13 " + label
+	info := make([]byte, 0, 4+len(full))
+	info = append(info, byte(size>>8), byte(size), byte(len(full)))
+	info = append(info, full...)
+	info = append(info, 0)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(info)
+	mac.Write([]byte{1})
+	return mac.Sum(nil)[:size]
+}
+
+// QUICClientKeys derives the keys of the client initial packets from the
+// destination connection id chosen by the client.
+func QUICClientKeys(version uint32, dcid []byte) (*QUICKeys, error) {
+	salt, found := quicInitialSalts[version]
+	if !found {
+		return nil, ErrQUICNotInitial
+	}
+
+	prefix := "quic "
+	if version == QUICVersion2 {
+		prefix = "quicv2 "
+	}
+
+	secret := quicHKDFExpandLabel(quicHKDFExtract(salt, dcid), "client in", sha256.Size)
+	return &QUICKeys{
+		Key: quicHKDFExpandLabel(secret, prefix+"key", 16),
+		IV:  quicHKDFExpandLabel(secret, prefix+"iv", 12),
+		HP:  quicHKDFExpandLabel(secret, prefix+"hp", 16),
+	}, nil
+}
+
+// variable length integer of RFC 9000 16
+func quicVarInt(data []byte) (uint64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, ErrQUICInvalid
+	}
+
+	size := 1 << (data[0] >> 6)
+	if len(data) < size {
+		return 0, 0, ErrQUICInvalid
+	}
+
+	v := uint64(data[0] & 0x3f)
+	for _, b := range data[1:size] {
+		v = v<<8 | uint64(b)
+	}
+	return v, size, nil
+}
+
+func quicIsInitial(version uint32, first byte) bool {
+	kind := (first >> 4) & 0x03
+	if version == QUICVersion2 {
+		return kind == 0x01
+	}
+	return kind == 0x00
+}
+
+// ParseQUICInitial decrypts a client initial packet, the payload is the first
+// packet of the UDP datagram.
+func ParseQUICInitial(data []byte) (*QUICInitial, error) {
+	// long header with the fixed bit set
+	if len(data) < 7 || data[0]&0xc0 != 0xc0 {
+		return nil, ErrQUICNotInitial
+	}
+
+	initial := &QUICInitial{Version: binary.BigEndian.Uint32(data[1:])}
+	if _, found := quicInitialSalts[initial.Version]; !found || !quicIsInitial(initial.Version, data[0]) {
+		return nil, ErrQUICNotInitial
+	}
+
+	off := 5
+	for _, id := range []*[]byte{&initial.DCID, &initial.SCID} {
+		if off >= len(data) {
+			return nil, ErrQUICInvalid
+		}
+		size := int(data[off])
+		if size > quicMaxConnIDSize || off+1+size > len(data) {
+			return nil, ErrQUICInvalid
+		}
+		*id = data[off+1 : off+1+size]
+		off += 1 + size
+	}
+
+	tokenSize, n, err := quicVarInt(data[off:])
+	if err != nil || uint64(len(data)-off-n) < tokenSize {
+		return nil, ErrQUICInvalid
+	}
+	off += n + int(tokenSize)
+
+	length, n, err := quicVarInt(data[off:])
+	if err != nil || uint64(len(data)-off-n) < length {
+		return nil, ErrQUICInvalid
+	}
+	off += n
+	pnOffset, end := off, off+int(length)
+	if pnOffset+4+quicSampleSize > end {
+		return nil, ErrQUICInvalid
+	}
+
+	keys, err := QUICClientKeys(initial.Version, initial.DCID)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove the header protection
+	hp, err := aes.NewCipher(keys.HP)
+	if err != nil {
+		return nil, err
+	}
+	mask := make([]byte, aes.BlockSize)
+	hp.Encrypt(mask, data[pnOffset+4:pnOffset+4+quicSampleSize])
+
+	header := append([]byte{}, data[:pnOffset+4]...)
+	header[0] ^= mask[0] & 0x0f
+	pnSize := int(header[0]&0x03) + 1
+	for i := 0; i < pnSize; i++ {
+		header[pnOffset+i] ^= mask[1+i]
+		initial.PacketNumber = initial.PacketNumber<<8 | uint64(header[pnOffset+i])
+	}
+	header = header[:pnOffset+pnSize]
+
+	block, err := aes.NewCipher(keys.Key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := append([]byte{}, keys.IV...)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(initial.PacketNumber >> (8 * uint(i)))
+	}
+
+	plain, err := aead.Open(nil, nonce, data[pnOffset+pnSize:end], header)
+	if err != nil {
+		return nil, ErrQUICInvalid
+	}
+
+	if initial.Crypto, err = quicCryptoFrames(plain); err != nil {
+		return nil, err
+	}
+	return initial, nil
+}
+
+func quicCryptoFrames(plain []byte) ([]QUICCryptoFrame, error) {
+	frames := []QUICCryptoFrame{}
+	for len(plain) > 0 {
+		kind := plain[0]
+		plain = plain[1:]
+
+		switch kind {
+		case quicFramePadding, quicFramePing:
+		case quicFrameAck, quicFrameAckECN:
+			// largest, delay, count, first range, then count ranges
+			fields := []uint64{0, 0, 0, 0}
+			for i := range fields {
+				v, n, err := quicVarInt(plain)
+				if err != nil {
+					return nil, err
+				}
+				fields[i], plain = v, plain[n:]
+			}
+			skip := fields[2] * 2
+			if kind == quicFrameAckECN {
+				skip += 3
+			}
+			for ; skip > 0; skip-- {
+				_, n, err := quicVarInt(plain)
+				if err != nil {
+					return nil, err
+				}
+				plain = plain[n:]
+			}
+		case quicFrameCrypto:
+			offset, n, err := quicVarInt(plain)
+			if err != nil {
+				return nil, err
+			}
+			plain = plain[n:]
+			size, n, err := quicVarInt(plain)
+			if err != nil || uint64(len(plain)-n) < size {
+				return nil, ErrQUICInvalid
+			}
+			plain = plain[n:]
+			frames = append(frames, QUICCryptoFrame{Offset: offset, Data: plain[:size]})
+			plain = plain[size:]
+		default:
+			// nothing else is allowed in client initial packets that we care about
+			return frames, nil
+		}
+	}
+	return frames, nil
+}
+
+// QUICCryptoStream reassembles the CRYPTO frames of one or more initial
+// packets into the TLS handshake.
+type QUICCryptoStream struct {
+	frames []QUICCryptoFrame
+}
+
+func (s *QUICCryptoStream) Add(frames ...QUICCryptoFrame) {
+	s.frames = append(s.frames, frames...)
+	sort.Slice(s.frames, func(i, j int) bool {
+		return s.frames[i].Offset < s.frames[j].Offset
+	})
+}
+
+// Bytes returns the contiguous data received from the beginning of the stream.
+func (s *QUICCryptoStream) Bytes() []byte {
+	data := []byte{}
+	for _, f := range s.frames {
+		if f.Offset > uint64(len(data)) {
+			break
+		} else if end := f.Offset + uint64(len(f.Data)); end > uint64(len(data)) {
+			data = append(data, f.Data[uint64(len(data))-f.Offset:]...)
+		}
+	}
+	return data
+}
+
+// ClientHello returns the ClientHello once all of its fragments are received.
+func (s *QUICCryptoStream) ClientHello() (*TLSClientHello, bool) {
+	data := s.Bytes()
+	if len(data) < 4 {
+		return nil, false
+	}
+
+	size := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if len(data) < 4+size {
+		return nil, false
+	}
+
+	hello, err := ParseTLSClientHelloMessage(data[:4+size])
+	return hello, err == nil
+}
@@ -0,0 +1,139 @@
+package packets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"testing"
+)
+
+func TestQUICClientKeys(t *testing.T) {
+	// RFC 9001 A.1 and RFC 9369 A.1
+	dcid := unhex(t, "8394c8f03e515708")
+	tests := []struct {
+		version uint32
+		key     string
+		iv      string
+		hp      string
+	}{
+		{QUICVersion1, "1f369613dd76d5467730efcbe3b1a22d", "fa044b2f42a3fd3b46fb255c", "9f50449e04a0e810283a1e9933adedd2"},
+		{QUICVersion2, "8b1a0bc121284290a29e0971b5cd045d", "91f73e2351d8fa91660e909f", "45b95e15235d6f45a6b19cbcb0294ba9"},
+	}
+
+	for _, test := range tests {
+		keys, err := QUICClientKeys(test.version, dcid)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		} else if !bytes.Equal(keys.Key, unhex(t, test.key)) {
+			t.Fatalf("unexpected %s key %x", QUICVersionName(test.version), keys.Key)
+		} else if !bytes.Equal(keys.IV, unhex(t, test.iv)) {
+			t.Fatalf("unexpected %s iv %x", QUICVersionName(test.version), keys.IV)
+		} else if !bytes.Equal(keys.HP, unhex(t, test.hp)) {
+			t.Fatalf("unexpected %s hp %x", QUICVersionName(test.version), keys.HP)
+		}
+	}
+}
+
+func quicTestCryptoFrame(offset int, data []byte) []byte {
+	frame := []byte{quicFrameCrypto, 0x40 | byte(offset>>8), byte(offset), 0x40 | byte(len(data)>>8), byte(len(data))}
+	return append(frame, data...)
+}
+
+// protects a client initial packet with a 4 bytes packet number
+func quicTestInitial(t *testing.T, version uint32, dcid []byte, pn uint32, plain []byte) []byte {
+	keys, err := QUICClientKeys(version, dcid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := byte(0xc3)
+	if version == QUICVersion2 {
+		first |= 0x10
+	}
+	header := []byte{first, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[1:], version)
+	header = append(header, byte(len(dcid)))
+	header = append(header, dcid...)
+	// no source connection id and no token
+	header = append(header, 0, 0)
+	length := 4 + len(plain) + 16
+	header = append(header, 0x40|byte(length>>8), byte(length))
+	pnOffset := len(header)
+	header = append(header, byte(pn>>24), byte(pn>>16), byte(pn>>8), byte(pn))
+
+	block, _ := aes.NewCipher(keys.Key)
+	aead, _ := cipher.NewGCM(block)
+	nonce := append([]byte{}, keys.IV...)
+	for i := 0; i < 4; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * uint(i)))
+	}
+	packet := aead.Seal(append([]byte{}, header...), nonce, plain, header)
+
+	hp, _ := aes.NewCipher(keys.HP)
+	mask := make([]byte, aes.BlockSize)
+	hp.Encrypt(mask, packet[pnOffset+4:pnOffset+4+quicSampleSize])
+	packet[0] ^= mask[0] & 0x0f
+	for i := 0; i < 4; i++ {
+		packet[pnOffset+i] ^= mask[1+i]
+	}
+
+	return packet
+}
+
+func TestParseQUICInitial(t *testing.T) {
+	// the ClientHello without the record layer, split in two frames out of order
+	hello := tlsTestClientHello(t)[5:]
+	plain := append(quicTestCryptoFrame(200, hello[200:]), quicTestCryptoFrame(0, hello[:200])...)
+	plain = append(plain, make([]byte, 1100-len(plain))...)
+
+	dcid := unhex(t, "8394c8f03e515708")
+	for _, version := range []uint32{QUICVersion1, QUICVersion2} {
+		initial, err := ParseQUICInitial(quicTestInitial(t, version, dcid, 2, plain))
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", QUICVersionName(version), err)
+		} else if initial.Version != version || initial.PacketNumber != 2 || !bytes.Equal(initial.DCID, dcid) {
+			t.Fatalf("unexpected initial %+v", initial)
+		} else if len(initial.Crypto) != 2 {
+			t.Fatalf("expected 2 crypto frames, got %d", len(initial.Crypto))
+		}
+
+		stream := QUICCryptoStream{}
+		stream.Add(initial.Crypto...)
+		if !bytes.Equal(stream.Bytes(), hello) {
+			t.Fatalf("unexpected crypto stream %x", stream.Bytes())
+		} else if parsed, ok := stream.ClientHello(); !ok {
+			t.Fatal("expected a client hello")
+		} else if parsed.SNI != "www.example.com" {
+			t.Fatalf("unexpected sni %s", parsed.SNI)
+		}
+	}
+
+	// tampered
+	packet := quicTestInitial(t, QUICVersion1, dcid, 2, plain)
+	packet[len(packet)-1] ^= 0xff
+	if _, err := ParseQUICInitial(packet); err != ErrQUICInvalid {
+		t.Fatalf("expected %v, got %v", ErrQUICInvalid, err)
+	}
+
+	// short header and unknown version
+	if _, err := ParseQUICInitial(append([]byte{0x40}, packet[1:]...)); err != ErrQUICNotInitial {
+		t.Fatalf("expected %v, got %v", ErrQUICNotInitial, err)
+	} else if _, err := ParseQUICInitial(append([]byte{0xc3, 0, 0, 0, 9}, packet[5:]...)); err != ErrQUICNotInitial {
+		t.Fatalf("expected %v, got %v", ErrQUICNotInitial, err)
+	}
+}
+
+func TestQUICCryptoStreamIncomplete(t *testing.T) {
+	hello := tlsTestClientHello(t)[5:]
+	stream := QUICCryptoStream{}
+	stream.Add(QUICCryptoFrame{Offset: 300, Data: hello[300:]})
+	if _, ok := stream.ClientHello(); ok {
+		t.Fatal("unexpected client hello without the first fragment")
+	}
+
+	stream.Add(QUICCryptoFrame{Offset: 0, Data: hello[:310]})
+	if parsed, ok := stream.ClientHello(); !ok || parsed.SNI != "www.example.com" {
+		t.Fatal("expected a client hello from overlapping fragments")
+	}
+}
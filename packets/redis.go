@@ -0,0 +1,164 @@
+package packets
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	RedisPort = 6379
+
+	// longest argument count or bulk string accepted
+	redisMaxArgs = 1024 * 1024
+)
+
+var ErrRedisInvalid = errors.New("not a valid RESP message")
+
+// RedisReply is the first value of a server reply.
+type RedisReply struct {
+	Type  byte   `json:"type"`
+	Value string `json:"value"`
+}
+
+// IsError returns true for error replies.
+func (r *RedisReply) IsError() bool {
+	return r.Type == '-'
+}
+
+// NeedsAuth returns true if the server refused a command because the client
+// didn't authenticate.
+func (r *RedisReply) NeedsAuth() bool {
+	return r.IsError() && strings.HasPrefix(r.Value, "NOAUTH")
+}
+
+// RedisAuth returns the username and password of an AUTH or HELLO command,
+// the username is empty for the legacy single password form.
+func RedisAuth(cmd []string) (username, password string, ok bool) {
+	if len(cmd) == 0 {
+		return "", "", false
+	}
+
+	switch strings.ToUpper(cmd[0]) {
+	case "AUTH":
+		if len(cmd) == 2 {
+			return "", cmd[1], true
+		} else if len(cmd) == 3 {
+			return cmd[1], cmd[2], true
+		}
+	case "HELLO":
+		for i := 1; i+2 < len(cmd); i++ {
+			if strings.EqualFold(cmd[i], "AUTH") {
+				return cmd[i+1], cmd[i+2], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// a CRLF terminated line
+func redisLine(data []byte) (string, []byte, error) {
+	end := bytes.Index(data, []byte("\r\n"))
+	if end == -1 {
+		return "", nil, ErrRedisInvalid
+	}
+	return string(data[:end]), data[end+2:], nil
+}
+
+func redisSize(line string) (int, error) {
+	size, err := strconv.Atoi(line)
+	if err != nil || size < -1 || size > redisMaxArgs {
+		return 0, ErrRedisInvalid
+	}
+	return size, nil
+}
+
+func redisCommand(data []byte) ([]string, []byte, error) {
+	line, data, err := redisLine(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// inline commands as sent by telnet and redis-cli pipes
+	if len(line) == 0 || line[0] != '*' {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return nil, nil, ErrRedisInvalid
+		}
+		for _, c := range fields[0] {
+			if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && c != '_' {
+				return nil, nil, ErrRedisInvalid
+			}
+		}
+		return fields, data, nil
+	}
+
+	count, err := redisSize(line[1:])
+	if err != nil || count < 1 {
+		return nil, nil, ErrRedisInvalid
+	}
+
+	cmd := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		if line, data, err = redisLine(data); err != nil {
+			return nil, nil, err
+		} else if len(line) == 0 || line[0] != '$' {
+			return nil, nil, ErrRedisInvalid
+		}
+
+		size, err := redisSize(line[1:])
+		if err != nil || size < 0 || len(data) < size+2 {
+			return nil, nil, ErrRedisInvalid
+		}
+		cmd = append(cmd, string(data[:size]))
+		data = data[size+2:]
+	}
+
+	return cmd, data, nil
+}
+
+// ParseRedisCommands parses the pipelined commands sent by a client, the ones
+// truncated at the end of the segment are ignored.
+func ParseRedisCommands(payload []byte) ([][]string, error) {
+	cmds := [][]string{}
+	for len(payload) > 0 {
+		cmd, rest, err := redisCommand(payload)
+		if err != nil {
+			break
+		}
+		cmds = append(cmds, cmd)
+		payload = rest
+	}
+
+	if len(cmds) == 0 {
+		return nil, ErrRedisInvalid
+	}
+	return cmds, nil
+}
+
+// ParseRedisReply parses the first value replied by a server, bulk strings
+// and arrays are only checked and their value is the announced size.
+func ParseRedisReply(payload []byte) (*RedisReply, error) {
+	if len(payload) < 3 {
+		return nil, ErrRedisInvalid
+	}
+
+	line, _, err := redisLine(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RedisReply{Type: line[0], Value: line[1:]}
+	switch r.Type {
+	case '+', '-':
+	case ':', '$', '*':
+		if _, err := strconv.Atoi(r.Value); err != nil {
+			return nil, ErrRedisInvalid
+		}
+	default:
+		return nil, ErrRedisInvalid
+	}
+
+	return r, nil
+}
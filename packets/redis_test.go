@@ -0,0 +1,68 @@
+package packets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRedisCommands(t *testing.T) {
+	payload := "*2\r\n$4\r\nAUTH\r\n$6\r\nsecret\r\n" +
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nva\r\nl\r\n" +
+		// truncated
+		"*2\r\n$3\r\nGET\r\n$3\r\nke"
+
+	cmds, err := ParseRedisCommands([]byte(payload))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(cmds) != 2 {
+		t.Fatalf("expected 2 commands, got %d", len(cmds))
+	} else if !reflect.DeepEqual(cmds[1], []string{"SET", "key", "va\r\nl"}) {
+		t.Fatalf("unexpected command %q", cmds[1])
+	}
+
+	if user, pass, ok := RedisAuth(cmds[0]); !ok || user != "" || pass != "secret" {
+		t.Fatalf("unexpected auth %s %s", user, pass)
+	} else if _, _, ok := RedisAuth(cmds[1]); ok {
+		t.Fatal("unexpected auth for SET")
+	}
+
+	if cmds, err = ParseRedisCommands([]byte("CONFIG GET dir\r\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !reflect.DeepEqual(cmds[0], []string{"CONFIG", "GET", "dir"}) {
+		t.Fatalf("unexpected inline command %q", cmds[0])
+	}
+}
+
+func TestRedisAuth(t *testing.T) {
+	if user, pass, ok := RedisAuth([]string{"AUTH", "default", "pass"}); !ok || user != "default" || pass != "pass" {
+		t.Fatalf("unexpected auth %s %s", user, pass)
+	} else if user, pass, ok = RedisAuth([]string{"hello", "3", "auth", "admin", "s3cr3t", "SETNAME", "app"}); !ok || user != "admin" || pass != "s3cr3t" {
+		t.Fatalf("unexpected auth %s %s", user, pass)
+	} else if _, _, ok = RedisAuth([]string{"HELLO", "3"}); ok {
+		t.Fatal("unexpected auth for HELLO without AUTH")
+	}
+}
+
+func TestParseRedisReply(t *testing.T) {
+	r, err := ParseRedisReply([]byte("-NOAUTH Authentication required.\r\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !r.IsError() || !r.NeedsAuth() {
+		t.Fatalf("unexpected reply %+v", r)
+	}
+
+	if r, err = ParseRedisReply([]byte("$5\r\nhello\r\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if r.Type != '$' || r.IsError() || r.Value != "5" {
+		t.Fatalf("unexpected reply %+v", r)
+	}
+
+	for _, payload := range []string{"+OK", ":abc\r\n", "HTTP/1.1 200 OK\r\n"} {
+		if _, err := ParseRedisReply([]byte(payload)); err != ErrRedisInvalid {
+			t.Fatalf("expected %v for %q, got %v", ErrRedisInvalid, payload, err)
+		}
+	}
+	if _, err := ParseRedisCommands([]byte("\x16\x03\x01\x02\x00")); err != ErrRedisInvalid {
+		t.Fatalf("expected %v, got %v", ErrRedisInvalid, err)
+	}
+}
@@ -0,0 +1,166 @@
+package packets
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Rewriter replaces MAC and IP addresses in the packets being replayed.
+type Rewriter struct {
+	MACs map[string]net.HardwareAddr
+	IPs  map[string]net.IP
+}
+
+// ParseRewriteRules parses a comma separated list of OLD>NEW rules, where
+// both OLD and NEW are either MAC or IP addresses, for instance:
+// 192.168.1.10>10.0.0.5,00:11:22:33:44:55>66:77:88:99:aa:bb
+func ParseRewriteRules(spec string) (*Rewriter, error) {
+	r := &Rewriter{
+		MACs: make(map[string]net.HardwareAddr),
+		IPs:  make(map[string]net.IP),
+	}
+
+	for _, rule := range strings.Split(spec, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+
+		parts := strings.Split(rule, ">")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("'%s' is not a valid rewrite rule, expected OLD>NEW", rule)
+		}
+		from, to := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		if fromIP, toIP := net.ParseIP(from), net.ParseIP(to); fromIP != nil && toIP != nil {
+			if (fromIP.To4() == nil) != (toIP.To4() == nil) {
+				return nil, fmt.Errorf("'%s' mixes IPv4 and IPv6 addresses", rule)
+			}
+			r.IPs[fromIP.String()] = toIP
+		} else if fromHW, err := net.ParseMAC(from); err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid IP or MAC address", from)
+		} else if toHW, err := net.ParseMAC(to); err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid IP or MAC address", to)
+		} else {
+			r.MACs[fromHW.String()] = toHW
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Rewriter) Empty() bool {
+	return r == nil || (len(r.MACs) == 0 && len(r.IPs) == 0)
+}
+
+func (r *Rewriter) mac(hw net.HardwareAddr) (net.HardwareAddr, bool) {
+	if to, found := r.MACs[hw.String()]; found {
+		return to, true
+	}
+	return hw, false
+}
+
+func (r *Rewriter) ip(ip net.IP) (net.IP, bool) {
+	if to, found := r.IPs[ip.String()]; found {
+		if ip.To4() != nil {
+			return to.To4(), true
+		}
+		return to.To16(), true
+	}
+	return ip, false
+}
+
+// Rewrite returns the packet with the addresses replaced and the checksums
+// fixed, and true if anything changed.
+func (r *Rewriter) Rewrite(data []byte, linkType layers.LinkType) ([]byte, bool, error) {
+	if r.Empty() {
+		return data, false, nil
+	}
+
+	pkt := gopacket.NewPacket(data, linkType, gopacket.Default)
+	changed := false
+	swap := func(ok bool) {
+		changed = changed || ok
+	}
+
+	var network gopacket.NetworkLayer
+	for _, layer := range pkt.Layers() {
+		var ok bool
+		switch l := layer.(type) {
+		case *layers.Ethernet:
+			l.SrcMAC, ok = r.mac(l.SrcMAC)
+			swap(ok)
+			l.DstMAC, ok = r.mac(l.DstMAC)
+			swap(ok)
+		case *layers.ARP:
+			var hw net.HardwareAddr
+			var ip net.IP
+			hw, ok = r.mac(l.SourceHwAddress)
+			l.SourceHwAddress = []byte(hw)
+			swap(ok)
+			hw, ok = r.mac(l.DstHwAddress)
+			l.DstHwAddress = []byte(hw)
+			swap(ok)
+			ip, ok = r.ip(l.SourceProtAddress)
+			l.SourceProtAddress = []byte(ip)
+			swap(ok)
+			ip, ok = r.ip(l.DstProtAddress)
+			l.DstProtAddress = []byte(ip)
+			swap(ok)
+		case *layers.IPv4:
+			l.SrcIP, ok = r.ip(l.SrcIP)
+			swap(ok)
+			l.DstIP, ok = r.ip(l.DstIP)
+			swap(ok)
+			network = l
+		case *layers.IPv6:
+			l.SrcIP, ok = r.ip(l.SrcIP)
+			swap(ok)
+			l.DstIP, ok = r.ip(l.DstIP)
+			swap(ok)
+			network = l
+		case *layers.TCP:
+			if network != nil {
+				l.SetNetworkLayerForChecksum(network)
+			}
+		case *layers.UDP:
+			if network != nil {
+				l.SetNetworkLayerForChecksum(network)
+			}
+		case *layers.ICMPv6:
+			if network != nil {
+				l.SetNetworkLayerForChecksum(network)
+			}
+		}
+	}
+
+	if !changed {
+		return data, false, nil
+	}
+
+	// the application layers are copied as they are, some of them can't
+	// be serialized
+	serializable := make([]gopacket.SerializableLayer, 0)
+	for _, layer := range pkt.Layers() {
+		if l, ok := layer.(gopacket.SerializableLayer); ok {
+			serializable = append(serializable, l)
+			if _, isTransport := layer.(gopacket.TransportLayer); isTransport {
+				serializable = append(serializable, gopacket.Payload(layer.LayerPayload()))
+				break
+			}
+		} else {
+			raw := append([]byte{}, layer.LayerContents()...)
+			serializable = append(serializable, gopacket.Payload(append(raw, layer.LayerPayload()...)))
+			break
+		}
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, SerializationOptions, serializable...); err != nil {
+		return data, false, err
+	}
+	return buf.Bytes(), true, nil
+}
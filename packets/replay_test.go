@@ -0,0 +1,109 @@
+package packets
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestParseRewriteRules(t *testing.T) {
+	r, err := ParseRewriteRules("192.168.1.10>10.0.0.5, 00:11:22:33:44:55>66:77:88:99:aa:bb,fe80::1>fe80::2")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(r.IPs) != 2 {
+		t.Fatalf("expected 2 IP rules, got %d", len(r.IPs))
+	} else if len(r.MACs) != 1 {
+		t.Fatalf("expected 1 MAC rule, got %d", len(r.MACs))
+	}
+
+	for _, spec := range []string{
+		"192.168.1.10",
+		"192.168.1.10>fe80::1",
+		"192.168.1.10>nope",
+		"00:11:22:33:44:55>10.0.0.1",
+	} {
+		if _, err := ParseRewriteRules(spec); err == nil {
+			t.Fatalf("expected an error for '%s'", spec)
+		}
+	}
+
+	if r, err = ParseRewriteRules(""); err != nil {
+		t.Fatal(err)
+	} else if !r.Empty() {
+		t.Fatalf("expected no rules")
+	}
+}
+
+func TestRewriterRewrite(t *testing.T) {
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0xde, 0xad, 0xbe, 0xef, 0x00, 0x01},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP("192.168.1.10").To4(),
+		DstIP:    net.ParseIP("192.168.1.1").To4(),
+	}
+	udp := layers.UDP{
+		SrcPort: 40000,
+		DstPort: 53,
+	}
+	udp.SetNetworkLayerForChecksum(&ip4)
+
+	err, data := Serialize(&eth, &ip4, &udp, gopacket.Payload([]byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ParseRewriteRules("192.168.1.10>10.0.0.5,00:11:22:33:44:55>66:77:88:99:aa:bb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewritten, changed, err := r.Rewrite(data, layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatal(err)
+	} else if !changed {
+		t.Fatalf("expected the packet to be rewritten")
+	}
+
+	pkt := gopacket.NewPacket(rewritten, layers.LayerTypeEthernet, gopacket.Default)
+	e := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	ip := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	u := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
+
+	if e.SrcMAC.String() != "66:77:88:99:aa:bb" {
+		t.Fatalf("unexpected source MAC %s", e.SrcMAC)
+	} else if e.DstMAC.String() != "de:ad:be:ef:00:01" {
+		t.Fatalf("unexpected destination MAC %s", e.DstMAC)
+	} else if !ip.SrcIP.Equal(net.ParseIP("10.0.0.5")) {
+		t.Fatalf("unexpected source IP %s", ip.SrcIP)
+	} else if !ip.DstIP.Equal(net.ParseIP("192.168.1.1")) {
+		t.Fatalf("unexpected destination IP %s", ip.DstIP)
+	} else if !bytes.Equal(u.Payload, []byte("hello")) {
+		t.Fatalf("unexpected payload %q", u.Payload)
+	}
+
+	// the checksums must match the new addresses
+	ip4.SrcIP = net.ParseIP("10.0.0.5").To4()
+	eth.SrcMAC = e.SrcMAC
+	udp.SetNetworkLayerForChecksum(&ip4)
+	if err, expected := Serialize(&eth, &ip4, &udp, gopacket.Payload([]byte("hello"))); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(rewritten, expected) {
+		t.Fatalf("expected\n%x\ngot\n%x", expected, rewritten)
+	}
+
+	// nothing left to rewrite
+	if same, changed, err := r.Rewrite(rewritten, layers.LinkTypeEthernet); err != nil {
+		t.Fatal(err)
+	} else if changed || !bytes.Equal(same, rewritten) {
+		t.Fatalf("expected the packet to be left untouched")
+	}
+}
@@ -0,0 +1,125 @@
+package packets
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const RTSPPort = 554
+
+var ErrRTSPInvalid = errors.New("not a RTSP message")
+
+var rtspMethods = map[string]bool{
+	"OPTIONS":       true,
+	"DESCRIBE":      true,
+	"ANNOUNCE":      true,
+	"SETUP":         true,
+	"PLAY":          true,
+	"PAUSE":         true,
+	"TEARDOWN":      true,
+	"GET_PARAMETER": true,
+	"SET_PARAMETER": true,
+	"REDIRECT":      true,
+	"RECORD":        true,
+}
+
+// RTSPMessage is either a RTSP request or a RTSP response.
+type RTSPMessage struct {
+	Method     string            `json:"method"`
+	URI        string            `json:"uri"`
+	StatusCode int               `json:"status_code"`
+	Status     string            `json:"status"`
+	Headers    map[string]string `json:"headers"`
+}
+
+func (m *RTSPMessage) IsRequest() bool {
+	return m.Method != ""
+}
+
+// Header returns the value of the header with the given name, case insensitive.
+func (m *RTSPMessage) Header(name string) string {
+	return m.Headers[strings.ToLower(name)]
+}
+
+// StreamURL returns the URI of the request without the credentials it might
+// have in its user info.
+func (m *RTSPMessage) StreamURL() string {
+	if u, err := url.Parse(m.URI); err == nil && u.User != nil {
+		u.User = nil
+		return u.String()
+	}
+	return m.URI
+}
+
+// BasicAuth returns the credentials of the request, either from a Basic
+// Authorization header or from the user info of the URI.
+func (m *RTSPMessage) BasicAuth() (username, password string, ok bool) {
+	if auth := m.Header("authorization"); len(auth) > 6 && strings.EqualFold(auth[:6], "basic ") {
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[6:])); err == nil {
+			if colon := strings.IndexByte(string(decoded), ':'); colon != -1 {
+				return string(decoded[:colon]), string(decoded[colon+1:]), true
+			}
+		}
+	}
+
+	if u, err := url.Parse(m.URI); err == nil && u.User != nil {
+		password, _ = u.User.Password()
+		return u.User.Username(), password, true
+	}
+
+	return "", "", false
+}
+
+// Digest returns the digest authentication of the request, if any, which
+// is the same of HTTP and SIP.
+func (m *RTSPMessage) Digest() *SIPDigest {
+	return ParseSIPDigest(m.Header("authorization"))
+}
+
+// ParseRTSP parses the headers of a RTSP request or response.
+func ParseRTSP(payload []byte) (*RTSPMessage, error) {
+	data := string(payload)
+	if idx := strings.Index(data, "\r\n\r\n"); idx != -1 {
+		data = data[:idx]
+	}
+
+	lines := strings.Split(data, "\r\n")
+	first := strings.SplitN(lines[0], " ", 3)
+	if len(first) != 3 {
+		return nil, ErrRTSPInvalid
+	}
+
+	m := &RTSPMessage{
+		Headers: make(map[string]string),
+	}
+
+	if strings.HasPrefix(first[0], "RTSP/") {
+		code, err := strconv.Atoi(first[1])
+		if err != nil {
+			return nil, ErrRTSPInvalid
+		}
+		m.StatusCode = code
+		m.Status = first[2]
+	} else if rtspMethods[first[0]] && strings.HasPrefix(first[2], "RTSP/") {
+		m.Method = first[0]
+		m.URI = first[1]
+	} else {
+		return nil, ErrRTSPInvalid
+	}
+
+	for _, line := range lines[1:] {
+		colon := strings.IndexByte(line, ':')
+		if colon == -1 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(line[:colon]))
+		if _, found := m.Headers[name]; !found {
+			m.Headers[name] = strings.TrimSpace(line[colon+1:])
+		}
+	}
+
+	return m, nil
+}
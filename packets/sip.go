@@ -0,0 +1,435 @@
+package packets
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	SIPPort = 5060
+
+	rtpVersion    = 2
+	rtpHeaderSize = 12
+)
+
+var (
+	ErrSIPInvalid = errors.New("not a SIP message")
+	ErrRTPInvalid = errors.New("not an RTP packet")
+
+	// compact forms of the SIP headers
+	sipCompactHeaders = map[string]string{
+		"i": "call-id",
+		"m": "contact",
+		"f": "from",
+		"t": "to",
+		"v": "via",
+		"l": "content-length",
+		"c": "content-type",
+	}
+
+	sipMethods = map[string]bool{
+		"REGISTER":  true,
+		"INVITE":    true,
+		"ACK":       true,
+		"BYE":       true,
+		"CANCEL":    true,
+		"OPTIONS":   true,
+		"PRACK":     true,
+		"SUBSCRIBE": true,
+		"NOTIFY":    true,
+		"PUBLISH":   true,
+		"INFO":      true,
+		"REFER":     true,
+		"MESSAGE":   true,
+		"UPDATE":    true,
+	}
+
+	// static RTP payload types, RFC 3551
+	RTPCodecs = map[uint8]string{
+		0:  "PCMU",
+		3:  "GSM",
+		4:  "G723",
+		5:  "DVI4",
+		6:  "DVI4",
+		7:  "LPC",
+		8:  "PCMA",
+		9:  "G722",
+		10: "L16",
+		11: "L16",
+		12: "QCELP",
+		13: "CN",
+		14: "MPA",
+		15: "G728",
+		16: "DVI4",
+		17: "DVI4",
+		18: "G729",
+		25: "CelB",
+		26: "JPEG",
+		28: "nv",
+		31: "H261",
+		32: "MPV",
+		33: "MP2T",
+		34: "H263",
+	}
+)
+
+// SIPMessage is either a SIP request or a SIP response.
+type SIPMessage struct {
+	Method     string
+	URI        string
+	StatusCode int
+	Status     string
+	Headers    map[string]string
+	Body       string
+}
+
+func (m *SIPMessage) IsRequest() bool {
+	return m.Method != ""
+}
+
+// Header returns the value of the header with the given name, case insensitive.
+func (m *SIPMessage) Header(name string) string {
+	return m.Headers[strings.ToLower(name)]
+}
+
+// CallID is the identifier shared by all the messages of a dialog.
+func (m *SIPMessage) CallID() string {
+	return m.Header("call-id")
+}
+
+// From returns the address of the caller.
+func (m *SIPMessage) From() string {
+	return SIPAddress(m.Header("from"))
+}
+
+// To returns the address of the callee.
+func (m *SIPMessage) To() string {
+	return SIPAddress(m.Header("to"))
+}
+
+// CSeqMethod returns the method a response refers to.
+func (m *SIPMessage) CSeqMethod() string {
+	if parts := strings.Fields(m.Header("cseq")); len(parts) == 2 {
+		return strings.ToUpper(parts[1])
+	}
+	return ""
+}
+
+// Digest returns the digest authentication of the request, if any.
+func (m *SIPMessage) Digest() *SIPDigest {
+	for _, name := range []string{"authorization", "proxy-authorization"} {
+		if d := ParseSIPDigest(m.Header(name)); d != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+// Media returns the media streams described by the SDP body, if any.
+func (m *SIPMessage) Media() []SDPMedia {
+	if !strings.Contains(strings.ToLower(m.Header("content-type")), "application/sdp") {
+		return nil
+	}
+	return ParseSDP(m.Body)
+}
+
+// ParseSIP parses a SIP request or response from a UDP datagram or TCP segment.
+func ParseSIP(payload []byte) (*SIPMessage, error) {
+	data := string(payload)
+	head, body := data, ""
+	if idx := strings.Index(data, "\r\n\r\n"); idx != -1 {
+		head, body = data[:idx], data[idx+4:]
+	}
+
+	lines := strings.Split(head, "\r\n")
+	first := strings.SplitN(lines[0], " ", 3)
+	if len(first) != 3 {
+		return nil, ErrSIPInvalid
+	}
+
+	m := &SIPMessage{
+		Headers: make(map[string]string),
+		Body:    body,
+	}
+
+	if strings.HasPrefix(first[0], "SIP/2.0") {
+		code, err := strconv.Atoi(first[1])
+		if err != nil {
+			return nil, ErrSIPInvalid
+		}
+		m.StatusCode = code
+		m.Status = first[2]
+	} else if sipMethods[first[0]] && first[2] == "SIP/2.0" {
+		m.Method = first[0]
+		m.URI = first[1]
+	} else {
+		return nil, ErrSIPInvalid
+	}
+
+	for _, line := range lines[1:] {
+		colon := strings.IndexByte(line, ':')
+		if colon == -1 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(line[:colon]))
+		if full, found := sipCompactHeaders[name]; found {
+			name = full
+		}
+		// only keep the first occurrence, Via headers are not relevant here
+		if _, found := m.Headers[name]; !found {
+			m.Headers[name] = strings.TrimSpace(line[colon+1:])
+		}
+	}
+
+	return m, nil
+}
+
+// SIPAddress extracts the URI from a From, To or Contact header value.
+func SIPAddress(value string) string {
+	if start := strings.IndexByte(value, '<'); start != -1 {
+		if end := strings.IndexByte(value[start:], '>'); end != -1 {
+			return value[start+1 : start+end]
+		}
+	}
+	if semi := strings.IndexByte(value, ';'); semi != -1 {
+		value = value[:semi]
+	}
+	return strings.TrimSpace(value)
+}
+
+// SIPDigest is the digest authentication of a REGISTER or INVITE request.
+type SIPDigest struct {
+	Username  string
+	Realm     string
+	Nonce     string
+	URI       string
+	Response  string
+	Algorithm string
+	CNonce    string
+	NC        string
+	QOP       string
+}
+
+// ParseSIPDigest parses the value of an Authorization or Proxy-Authorization
+// header, nil is returned if it's not a digest authentication.
+func ParseSIPDigest(value string) *SIPDigest {
+	if len(value) < 7 || !strings.EqualFold(value[:7], "digest ") {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for _, param := range sipSplitParams(value[7:]) {
+		if eq := strings.IndexByte(param, '='); eq != -1 {
+			key := strings.ToLower(strings.TrimSpace(param[:eq]))
+			params[key] = strings.Trim(strings.TrimSpace(param[eq+1:]), "\"")
+		}
+	}
+
+	d := &SIPDigest{
+		Username:  params["username"],
+		Realm:     params["realm"],
+		Nonce:     params["nonce"],
+		URI:       params["uri"],
+		Response:  params["response"],
+		Algorithm: params["algorithm"],
+		CNonce:    params["cnonce"],
+		NC:        params["nc"],
+		QOP:       params["qop"],
+	}
+	if d.Username == "" || d.Response == "" {
+		return nil
+	} else if d.Algorithm == "" {
+		d.Algorithm = "MD5"
+	}
+	return d
+}
+
+// comma separated parameters, commas can be quoted
+func sipSplitParams(s string) []string {
+	params := []string{}
+	quoted, start := false, 0
+	for i, c := range s {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ',' && !quoted {
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
+
+// Hashcat returns the digest in the format of the hashcat 11400 mode.
+func (d *SIPDigest) Hashcat(method string, server string, client string) string {
+	uri := strings.SplitN(d.URI, ":", 3)
+	for len(uri) < 3 {
+		uri = append(uri, "")
+	}
+	return fmt.Sprintf("$sip$*%s*%s*%s*%s*%s*%s*%s*%s*%s*%s*%s*%s*%s*%s",
+		server,
+		client,
+		d.Username,
+		d.Realm,
+		method,
+		uri[0],
+		uri[1],
+		uri[2],
+		d.Nonce,
+		d.CNonce,
+		d.NC,
+		d.QOP,
+		strings.ToUpper(d.Algorithm),
+		d.Response)
+}
+
+// SDPMedia is a media stream announced by an SDP body.
+type SDPMedia struct {
+	Type    string
+	Address net.IP
+	Port    int
+	// payload type -> codec name
+	Codecs map[uint8]string
+	// payload types in order of preference
+	Formats []uint8
+}
+
+// Codec returns the name of the preferred codec of the stream.
+func (m SDPMedia) Codec() string {
+	if len(m.Formats) > 0 {
+		return m.CodecOf(m.Formats[0])
+	}
+	return ""
+}
+
+// CodecOf returns the name of the codec for the given payload type.
+func (m SDPMedia) CodecOf(payloadType uint8) string {
+	if codec, found := m.Codecs[payloadType]; found {
+		return codec
+	} else if codec, found = RTPCodecs[payloadType]; found {
+		return codec
+	}
+	return fmt.Sprintf("%d", payloadType)
+}
+
+// ParseSDP returns the media streams of a session description, the
+// connection address of the session is used unless the media has its own.
+func ParseSDP(body string) []SDPMedia {
+	var sessionAddr net.IP
+	media := []SDPMedia{}
+	current := -1
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+
+		value := line[2:]
+		switch line[0] {
+		case 'c':
+			// c=IN IP4 192.168.1.10
+			if parts := strings.Fields(value); len(parts) == 3 {
+				// strip the ttl of multicast addresses
+				addr := net.ParseIP(strings.Split(parts[2], "/")[0])
+				if current == -1 {
+					sessionAddr = addr
+				} else {
+					media[current].Address = addr
+				}
+			}
+		case 'm':
+			// m=audio 49170 RTP/AVP 0 8 97
+			parts := strings.Fields(value)
+			if len(parts) < 3 {
+				continue
+			}
+			port, err := strconv.Atoi(strings.Split(parts[1], "/")[0])
+			if err != nil {
+				continue
+			}
+			m := SDPMedia{
+				Type:    parts[0],
+				Address: sessionAddr,
+				Port:    port,
+				Codecs:  make(map[uint8]string),
+				Formats: make([]uint8, 0),
+			}
+			for _, f := range parts[3:] {
+				if pt, err := strconv.ParseUint(f, 10, 8); err == nil {
+					m.Formats = append(m.Formats, uint8(pt))
+				}
+			}
+			media = append(media, m)
+			current = len(media) - 1
+		case 'a':
+			// a=rtpmap:97 opus/48000/2
+			if current == -1 || !strings.HasPrefix(value, "rtpmap:") {
+				continue
+			}
+			if parts := strings.Fields(value[7:]); len(parts) == 2 {
+				if pt, err := strconv.ParseUint(parts[0], 10, 8); err == nil {
+					media[current].Codecs[uint8(pt)] = strings.Split(parts[1], "/")[0]
+				}
+			}
+		}
+	}
+
+	return media
+}
+
+// RTPHeader is the fixed header of an RTP packet.
+type RTPHeader struct {
+	Marker      bool
+	PayloadType uint8
+	Sequence    uint16
+	Timestamp   uint32
+	SSRC        uint32
+	// offset of the payload in the packet
+	Size int
+}
+
+// ParseRTP returns the header and the payload of an RTP packet, the CSRC list,
+// the header extension and the padding are skipped.
+func ParseRTP(data []byte) (*RTPHeader, []byte, error) {
+	if len(data) < rtpHeaderSize || data[0]>>6 != rtpVersion {
+		return nil, nil, ErrRTPInvalid
+	}
+
+	h := &RTPHeader{
+		Marker:      data[1]&0x80 != 0,
+		PayloadType: data[1] & 0x7f,
+		Sequence:    binary.BigEndian.Uint16(data[2:4]),
+		Timestamp:   binary.BigEndian.Uint32(data[4:8]),
+		SSRC:        binary.BigEndian.Uint32(data[8:12]),
+		Size:        rtpHeaderSize + int(data[0]&0x0f)*4,
+	}
+
+	// payload types 72-76 are RTCP packets sharing the port
+	if h.PayloadType >= 72 && h.PayloadType <= 76 {
+		return nil, nil, ErrRTPInvalid
+	}
+
+	if data[0]&0x10 != 0 {
+		if len(data) < h.Size+4 {
+			return nil, nil, ErrRTPInvalid
+		}
+		h.Size += 4 + int(binary.BigEndian.Uint16(data[h.Size+2:h.Size+4]))*4
+	}
+
+	end := len(data)
+	if data[0]&0x20 != 0 && end > 0 {
+		end -= int(data[end-1])
+	}
+
+	if h.Size > end {
+		return nil, nil, ErrRTPInvalid
+	}
+
+	return h, data[h.Size:end], nil
+}
@@ -0,0 +1,114 @@
+package packets
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+const sipInvite = "INVITE sip:bob@biloxi.example.com SIP/2.0\r\n" +
+	"Via: SIP/2.0/UDP 192.168.1.10:5060;branch=z9hG4bK74bf9\r\n" +
+	"f: \"Alice\" <sip:alice@atlanta.example.com>;tag=9fxced76sl\r\n" +
+	"To: Bob <sip:bob@biloxi.example.com>\r\n" +
+	"Call-ID: 3848276298220188511@atlanta.example.com\r\n" +
+	"CSeq: 2 INVITE\r\n" +
+	"Proxy-Authorization: Digest username=\"alice\", realm=\"atlanta.example.com\", " +
+	"nonce=\"wf84f1ceczx41ae6cbe5aea9c8e88d359\", uri=\"sip:bob@biloxi.example.com\", " +
+	"response=\"42ce3cef44b22f50c6a6071bc8\"\r\n" +
+	"Content-Type: application/sdp\r\n" +
+	"\r\n" +
+	"v=0\r\n" +
+	"o=alice 2890844526 2890844526 IN IP4 192.168.1.10\r\n" +
+	"c=IN IP4 192.168.1.10\r\n" +
+	"m=audio 49170 RTP/AVP 97 0 8\r\n" +
+	"a=rtpmap:97 opus/48000/2\r\n" +
+	"m=video 51372 RTP/AVP 31\r\n" +
+	"c=IN IP4 192.168.1.11\r\n"
+
+func TestParseSIPRequest(t *testing.T) {
+	msg, err := ParseSIP([]byte(sipInvite))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !msg.IsRequest() || msg.Method != "INVITE" || msg.URI != "sip:bob@biloxi.example.com" {
+		t.Fatalf("unexpected request line %+v", msg)
+	} else if msg.From() != "sip:alice@atlanta.example.com" {
+		t.Fatalf("unexpected from '%s'", msg.From())
+	} else if msg.To() != "sip:bob@biloxi.example.com" {
+		t.Fatalf("unexpected to '%s'", msg.To())
+	} else if msg.CallID() != "3848276298220188511@atlanta.example.com" {
+		t.Fatalf("unexpected call id '%s'", msg.CallID())
+	} else if msg.CSeqMethod() != "INVITE" {
+		t.Fatalf("unexpected cseq method '%s'", msg.CSeqMethod())
+	}
+
+	d := msg.Digest()
+	if d == nil {
+		t.Fatal("expected digest authentication")
+	} else if d.Username != "alice" || d.Realm != "atlanta.example.com" || d.Algorithm != "MD5" {
+		t.Fatalf("unexpected digest %+v", d)
+	}
+
+	expected := "$sip$*10.0.0.1*192.168.1.10*alice*atlanta.example.com*INVITE*sip*bob@biloxi.example.com**" +
+		"wf84f1ceczx41ae6cbe5aea9c8e88d359****MD5*42ce3cef44b22f50c6a6071bc8"
+	if hash := d.Hashcat("INVITE", "10.0.0.1", "192.168.1.10"); hash != expected {
+		t.Fatalf("expected '%s', got '%s'", expected, hash)
+	}
+
+	media := msg.Media()
+	if len(media) != 2 {
+		t.Fatalf("expected 2 media streams, got %d", len(media))
+	} else if media[0].Type != "audio" || media[0].Port != 49170 || !media[0].Address.Equal(net.ParseIP("192.168.1.10")) {
+		t.Fatalf("unexpected audio stream %+v", media[0])
+	} else if media[0].Codec() != "opus" || media[0].CodecOf(8) != "PCMA" {
+		t.Fatalf("unexpected audio codecs %+v", media[0])
+	} else if media[1].Type != "video" || !media[1].Address.Equal(net.ParseIP("192.168.1.11")) || media[1].Codec() != "H261" {
+		t.Fatalf("unexpected video stream %+v", media[1])
+	}
+}
+
+func TestParseSIPResponse(t *testing.T) {
+	raw := "SIP/2.0 200 OK\r\nCall-ID: abc\r\nCSeq: 1 INVITE\r\n\r\n"
+	msg, err := ParseSIP([]byte(raw))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msg.IsRequest() || msg.StatusCode != 200 || msg.Status != "OK" {
+		t.Fatalf("unexpected status line %+v", msg)
+	} else if msg.Digest() != nil || msg.Media() != nil {
+		t.Fatalf("unexpected digest or media in %+v", msg)
+	}
+
+	for _, invalid := range []string{"", "GET / HTTP/1.1\r\n\r\n", "SIP/2.0 OK\r\n", "FOO sip:bob SIP/2.0\r\n"} {
+		if _, err := ParseSIP([]byte(invalid)); err != ErrSIPInvalid {
+			t.Fatalf("expected error for '%s', got %v", strings.TrimSpace(invalid), err)
+		}
+	}
+}
+
+func TestParseRTP(t *testing.T) {
+	payload := []byte{0xde, 0xad, 0xbe, 0xef}
+	// version 2 with padding and one CSRC, marker set, PCMA
+	data := []byte{0xa1, 0x88, 0x00, 0x01, 0x00, 0x00, 0x00, 0xa0, 0x12, 0x34, 0x56, 0x78, 0, 0, 0, 1}
+	data = append(data, payload...)
+	data = append(data, 0x00, 0x02)
+
+	h, got, err := ParseRTP(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !h.Marker || h.PayloadType != 8 || h.Sequence != 1 || h.Timestamp != 160 || h.SSRC != 0x12345678 {
+		t.Fatalf("unexpected header %+v", h)
+	} else if !bytes.Equal(got, payload) {
+		t.Fatalf("expected payload %x, got %x", payload, got)
+	}
+
+	// RTCP sender report and version 1
+	for _, invalid := range [][]byte{
+		{0x80, 0xc8, 0, 6, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0x40, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0x80},
+	} {
+		if _, _, err := ParseRTP(invalid); err != ErrRTPInvalid {
+			t.Fatalf("expected error for %x, got %v", invalid, err)
+		}
+	}
+}
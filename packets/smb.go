@@ -0,0 +1,321 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf16"
+)
+
+const (
+	SMBPort = 445
+
+	smb1HeaderSize = 32
+	smb2HeaderSize = 64
+
+	SMB1CommandSessionSetup = 0x73
+
+	smb2CommandNegotiate    = 0x0000
+	SMB2CommandSessionSetup = 0x0001
+	SMB2CommandTreeConnect  = 0x0003
+	SMB2CommandCreate       = 0x0005
+	SMB2CommandClose        = 0x0006
+	SMB2CommandRead         = 0x0008
+	SMB2CommandWrite        = 0x0009
+
+	smb1FlagsReply    = 0x80
+	smb2FlagsResponse = 0x00000001
+
+	SMB2SigningEnabled  = 0x01
+	SMB2SigningRequired = 0x02
+)
+
+var smb2Dialects = map[uint16]string{
+	0x0202: "2.0.2",
+	0x0210: "2.1",
+	0x0300: "3.0",
+	0x0302: "3.0.2",
+	0x0311: "3.1.1",
+}
+
+// SMBInfo is what an SMB2 negotiate response tells about a server.
+type SMBInfo struct {
+	Dialect         string
+	SigningEnabled  bool
+	SigningRequired bool
+}
+
+// Signing returns the signing requirement of the server, relaying is only
+// possible against servers not requiring it.
+func (i *SMBInfo) Signing() string {
+	if i.SigningRequired {
+		return "required"
+	} else if i.SigningEnabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// NewSMB2NegotiateRequest creates an SMB2 negotiate request for the
+// dialects up to 3.0.2, framed for a direct TCP session.
+func NewSMB2NegotiateRequest() []byte {
+	dialects := []uint16{0x0202, 0x0210, 0x0300, 0x0302}
+
+	header := make([]byte, smb2HeaderSize)
+	copy(header[0:4], []byte{0xfe, 'S', 'M', 'B'})
+	binary.LittleEndian.PutUint16(header[4:], smb2HeaderSize)
+	binary.LittleEndian.PutUint16(header[12:], smb2CommandNegotiate)
+	// credits requested
+	binary.LittleEndian.PutUint16(header[14:], 1)
+
+	body := make([]byte, 36+2*len(dialects))
+	binary.LittleEndian.PutUint16(body[0:], 36)
+	binary.LittleEndian.PutUint16(body[2:], uint16(len(dialects)))
+	binary.LittleEndian.PutUint16(body[4:], SMB2SigningEnabled)
+	// client guid, all zeros for anonymous negotiation
+	for i, d := range dialects {
+		binary.LittleEndian.PutUint16(body[36+2*i:], d)
+	}
+
+	msg := append(header, body...)
+
+	// netbios session service header
+	frame := make([]byte, 4)
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+
+	return append(frame, msg...)
+}
+
+// ParseSMB2NegotiateResponse parses a negotiate response including its
+// NetBIOS session service header.
+func ParseSMB2NegotiateResponse(data []byte) (*SMBInfo, error) {
+	if len(data) < 4+smb2HeaderSize+8 {
+		return nil, fmt.Errorf("negotiate response too short (%d bytes)", len(data))
+	}
+
+	header := data[4:]
+	if header[0] != 0xfe || string(header[1:4]) != "SMB" {
+		return nil, fmt.Errorf("not an SMB2 response")
+	} else if status := binary.LittleEndian.Uint32(header[8:]); status != 0 {
+		return nil, fmt.Errorf("negotiate failed with status 0x%08x", status)
+	} else if cmd := binary.LittleEndian.Uint16(header[12:]); cmd != smb2CommandNegotiate {
+		return nil, fmt.Errorf("unexpected command 0x%04x", cmd)
+	}
+
+	body := header[smb2HeaderSize:]
+	mode := binary.LittleEndian.Uint16(body[2:])
+	revision := binary.LittleEndian.Uint16(body[4:])
+
+	dialect, found := smb2Dialects[revision]
+	if !found {
+		dialect = fmt.Sprintf("0x%04x", revision)
+	}
+
+	return &SMBInfo{
+		Dialect:         dialect,
+		SigningEnabled:  mode&SMB2SigningEnabled != 0,
+		SigningRequired: mode&SMB2SigningRequired != 0,
+	}, nil
+}
+
+var ntlmsspSignature = []byte("NTLMSSP\x00")
+
+// SMBMessage is an SMB1 or SMB2 message, including its header.
+type SMBMessage struct {
+	Version   int
+	Command   uint16
+	Status    uint32
+	Response  bool
+	MessageID uint64
+	TreeID    uint32
+	SessionID uint64
+
+	raw []byte
+}
+
+func parseSMB2Messages(data []byte) []SMBMessage {
+	msgs := make([]SMBMessage, 0)
+	// compounded requests and responses are chained by the NextCommand offset
+	for len(data) >= smb2HeaderSize && data[0] == 0xfe && string(data[1:4]) == "SMB" {
+		next := binary.LittleEndian.Uint32(data[20:])
+		raw := data
+		if next > 0 && int(next) <= len(data) {
+			raw = data[:next]
+		}
+
+		msgs = append(msgs, SMBMessage{
+			Version:   2,
+			Command:   binary.LittleEndian.Uint16(data[12:]),
+			Status:    binary.LittleEndian.Uint32(data[8:]),
+			Response:  binary.LittleEndian.Uint32(data[16:])&smb2FlagsResponse != 0,
+			MessageID: binary.LittleEndian.Uint64(data[24:]),
+			TreeID:    binary.LittleEndian.Uint32(data[36:]),
+			SessionID: binary.LittleEndian.Uint64(data[40:]),
+			raw:       raw,
+		})
+
+		if next == 0 || int(next) >= len(data) {
+			break
+		}
+		data = data[next:]
+	}
+	return msgs
+}
+
+// ParseSMBMessages parses the SMB messages framed by the NetBIOS session
+// service headers in a TCP payload, the last one can be truncated.
+func ParseSMBMessages(payload []byte) []SMBMessage {
+	msgs := make([]SMBMessage, 0)
+	for len(payload) >= 4+smb1HeaderSize {
+		// session message type followed by the 24 bits length
+		if payload[0] != 0x00 {
+			break
+		}
+		size := int(binary.BigEndian.Uint32(payload) & 0x00ffffff)
+		data := payload[4:]
+		if size < len(data) {
+			data = data[:size]
+		}
+
+		if data[0] == 0xfe && string(data[1:4]) == "SMB" {
+			msgs = append(msgs, parseSMB2Messages(data)...)
+		} else if data[0] == 0xff && string(data[1:4]) == "SMB" {
+			msgs = append(msgs, SMBMessage{
+				Version:  1,
+				Command:  uint16(data[4]),
+				Status:   binary.LittleEndian.Uint32(data[5:]),
+				Response: data[9]&smb1FlagsReply != 0,
+				raw:      data,
+			})
+		} else {
+			break
+		}
+
+		if 4+size >= len(payload) {
+			break
+		}
+		payload = payload[4+size:]
+	}
+	return msgs
+}
+
+func (m SMBMessage) body() []byte {
+	if m.Version == 1 {
+		return m.raw[smb1HeaderSize:]
+	}
+	return m.raw[smb2HeaderSize:]
+}
+
+// buffer returns the part of the message pointed by an offset from the
+// beginning of the SMB2 header and a length, nil if out of bounds.
+func (m SMBMessage) buffer(offset int, length int) []byte {
+	if offset < smb2HeaderSize || length <= 0 || offset+length > len(m.raw) {
+		return nil
+	}
+	return m.raw[offset : offset+length]
+}
+
+func (m SMBMessage) is(command uint16, response bool, minBody int) bool {
+	return m.Version == 2 && m.Command == command && m.Response == response && len(m.body()) >= minBody
+}
+
+func utf16String(b []byte) string {
+	chars := make([]uint16, len(b)/2)
+	for i := range chars {
+		chars[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(chars))
+}
+
+// IsSessionSetup returns true for SMB1 and SMB2 session setup messages.
+func (m SMBMessage) IsSessionSetup() bool {
+	return (m.Version == 1 && m.Command == SMB1CommandSessionSetup) ||
+		(m.Version == 2 && m.Command == SMB2CommandSessionSetup)
+}
+
+// NTLMSSP returns the NTLMSSP message carried by the security blob of a
+// session setup, whatever the GSS-API wrapping, and its type.
+func (m SMBMessage) NTLMSSP() ([]byte, uint32) {
+	body := m.body()
+	if i := bytes.Index(body, ntlmsspSignature); i >= 0 && len(body)-i >= 12 {
+		msg := body[i:]
+		return msg, binary.LittleEndian.Uint32(msg[8:])
+	}
+	return nil, 0
+}
+
+// TreePath returns the share path of an SMB2 tree connect request.
+func (m SMBMessage) TreePath() string {
+	if !m.is(SMB2CommandTreeConnect, false, 8) {
+		return ""
+	}
+	body := m.body()
+	return utf16String(m.buffer(int(binary.LittleEndian.Uint16(body[4:])), int(binary.LittleEndian.Uint16(body[6:]))))
+}
+
+// CreateName returns the file name of an SMB2 create request.
+func (m SMBMessage) CreateName() string {
+	if !m.is(SMB2CommandCreate, false, 48) {
+		return ""
+	}
+	body := m.body()
+	return utf16String(m.buffer(int(binary.LittleEndian.Uint16(body[44:])), int(binary.LittleEndian.Uint16(body[46:]))))
+}
+
+// CreateFileID returns the id of the file opened by a successful SMB2 create response.
+func (m SMBMessage) CreateFileID() string {
+	if !m.is(SMB2CommandCreate, true, 80) || m.Status != 0 {
+		return ""
+	}
+	return hex.EncodeToString(m.body()[64:80])
+}
+
+// IO returns the file id, the offset and the length of an SMB2 read or write request.
+func (m SMBMessage) IO() (fileID string, offset uint64, length uint32, ok bool) {
+	if !m.is(SMB2CommandRead, false, 32) && !m.is(SMB2CommandWrite, false, 32) {
+		return
+	}
+	body := m.body()
+	return hex.EncodeToString(body[16:32]), binary.LittleEndian.Uint64(body[8:]), binary.LittleEndian.Uint32(body[4:]), true
+}
+
+// truncated returns the part of the message pointed by an offset from the
+// beginning of the SMB2 header and a length that is there, the last message
+// of a payload can be truncated.
+func (m SMBMessage) truncated(offset int, length int) []byte {
+	if offset < smb2HeaderSize || length <= 0 || offset >= len(m.raw) {
+		return nil
+	} else if offset+length > len(m.raw) {
+		length = len(m.raw) - offset
+	}
+	return m.raw[offset : offset+length]
+}
+
+// ReadData returns the data of a successful SMB2 read response, or the part
+// of it in the payload if the message is truncated.
+func (m SMBMessage) ReadData() []byte {
+	if !m.is(SMB2CommandRead, true, 16) || m.Status != 0 {
+		return nil
+	}
+	body := m.body()
+	return m.truncated(int(body[2]), int(binary.LittleEndian.Uint32(body[4:])))
+}
+
+// WriteData returns the data of an SMB2 write request, or the part of it in
+// the payload if the message is truncated.
+func (m SMBMessage) WriteData() []byte {
+	if !m.is(SMB2CommandWrite, false, 48) {
+		return nil
+	}
+	body := m.body()
+	return m.truncated(int(binary.LittleEndian.Uint16(body[2:])), int(binary.LittleEndian.Uint32(body[4:])))
+}
+
+// CloseFileID returns the id of the file of an SMB2 close request.
+func (m SMBMessage) CloseFileID() string {
+	if !m.is(SMB2CommandClose, false, 24) {
+		return ""
+	}
+	return hex.EncodeToString(m.body()[8:24])
+}
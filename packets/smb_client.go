@@ -0,0 +1,136 @@
+package packets
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"unicode/utf16"
+)
+
+const (
+	SMB2StatusSuccess              = 0x00000000
+	SMB2StatusPending              = 0x00000103
+	SMB2StatusBufferOverflow       = 0x80000005
+	SMB2StatusMoreProcessingNeeded = 0xc0000016
+	SMB2StatusAccessDenied         = 0xc0000022
+	SMB2StatusLogonFailure         = 0xc000006d
+
+	// session setup response flags
+	SMB2SessionGuest = 0x0001
+	SMB2SessionNull  = 0x0002
+
+	// read, write and synchronize access to a named pipe
+	smb2PipeAccess = 0x0012019f
+)
+
+func utf16Encode(s string) []byte {
+	chars := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(chars))
+	for i, c := range chars {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+// smb2Request frames an SMB2 request for a direct TCP session, asking for
+// one credit.
+func smb2Request(command uint16, messageID uint64, sessionID uint64, treeID uint32, body []byte) []byte {
+	header := make([]byte, smb2HeaderSize)
+	copy(header[0:4], []byte{0xfe, 'S', 'M', 'B'})
+	binary.LittleEndian.PutUint16(header[4:], smb2HeaderSize)
+	binary.LittleEndian.PutUint16(header[12:], command)
+	binary.LittleEndian.PutUint16(header[14:], 1)
+	binary.LittleEndian.PutUint64(header[24:], messageID)
+	binary.LittleEndian.PutUint32(header[36:], treeID)
+	binary.LittleEndian.PutUint64(header[40:], sessionID)
+
+	msg := append(header, body...)
+
+	frame := make([]byte, 4)
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+
+	return append(frame, msg...)
+}
+
+func smb2FileID(fileID string) []byte {
+	id := make([]byte, 16)
+	if raw, err := hex.DecodeString(fileID); err == nil {
+		copy(id, raw)
+	}
+	return id
+}
+
+// NewSMB2SessionSetupRequest creates a session setup request carrying the
+// given security token, such as a bare NTLMSSP message.
+func NewSMB2SessionSetupRequest(messageID uint64, sessionID uint64, token []byte) []byte {
+	body := make([]byte, 24)
+	binary.LittleEndian.PutUint16(body[0:], 25)
+	body[3] = SMB2SigningEnabled
+	binary.LittleEndian.PutUint16(body[12:], smb2HeaderSize+24)
+	binary.LittleEndian.PutUint16(body[14:], uint16(len(token)))
+
+	return smb2Request(SMB2CommandSessionSetup, messageID, sessionID, 0, append(body, token...))
+}
+
+// NewSMB2TreeConnectRequest creates a tree connect request for a share path
+// such as \\host\IPC$
+func NewSMB2TreeConnectRequest(messageID uint64, sessionID uint64, path string) []byte {
+	name := utf16Encode(path)
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint16(body[0:], 9)
+	binary.LittleEndian.PutUint16(body[4:], smb2HeaderSize+8)
+	binary.LittleEndian.PutUint16(body[6:], uint16(len(name)))
+
+	return smb2Request(SMB2CommandTreeConnect, messageID, sessionID, 0, append(body, name...))
+}
+
+// NewSMB2CreatePipeRequest creates a request opening a named pipe of the
+// IPC$ share for reading and writing.
+func NewSMB2CreatePipeRequest(messageID uint64, sessionID uint64, treeID uint32, pipe string) []byte {
+	name := utf16Encode(pipe)
+	body := make([]byte, 56)
+	binary.LittleEndian.PutUint16(body[0:], 57)
+	// impersonation level
+	binary.LittleEndian.PutUint32(body[4:], 2)
+	binary.LittleEndian.PutUint32(body[24:], smb2PipeAccess)
+	// share read and write
+	binary.LittleEndian.PutUint32(body[32:], 3)
+	// open an existing file
+	binary.LittleEndian.PutUint32(body[36:], 1)
+	binary.LittleEndian.PutUint16(body[44:], smb2HeaderSize+56)
+	binary.LittleEndian.PutUint16(body[46:], uint16(len(name)))
+
+	return smb2Request(SMB2CommandCreate, messageID, sessionID, treeID, append(body, name...))
+}
+
+// NewSMB2WriteRequest creates a request writing data at the beginning of the
+// file with the given id, as returned by SMBMessage.CreateFileID.
+func NewSMB2WriteRequest(messageID uint64, sessionID uint64, treeID uint32, fileID string, data []byte) []byte {
+	body := make([]byte, 48)
+	binary.LittleEndian.PutUint16(body[0:], 49)
+	binary.LittleEndian.PutUint16(body[2:], smb2HeaderSize+48)
+	binary.LittleEndian.PutUint32(body[4:], uint32(len(data)))
+	copy(body[16:32], smb2FileID(fileID))
+
+	return smb2Request(SMB2CommandWrite, messageID, sessionID, treeID, append(body, data...))
+}
+
+// NewSMB2ReadRequest creates a request reading up to length bytes from the
+// beginning of the file with the given id.
+func NewSMB2ReadRequest(messageID uint64, sessionID uint64, treeID uint32, fileID string, length uint32) []byte {
+	// the variable part can't be empty
+	body := make([]byte, 49)
+	binary.LittleEndian.PutUint16(body[0:], 49)
+	binary.LittleEndian.PutUint32(body[4:], length)
+	copy(body[16:32], smb2FileID(fileID))
+
+	return smb2Request(SMB2CommandRead, messageID, sessionID, treeID, body)
+}
+
+// SessionFlags returns the flags of a successful SMB2 session setup
+// response, telling whether the session is a guest or an anonymous one.
+func (m SMBMessage) SessionFlags() (uint16, bool) {
+	if !m.is(SMB2CommandSessionSetup, true, 8) || m.Status != SMB2StatusSuccess {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint16(m.body()[2:]), true
+}
@@ -0,0 +1,135 @@
+package packets
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestSMB2ClientRequests(t *testing.T) {
+	fileID := "00112233445566778899aabbccddeeff"
+
+	tree := ParseSMBMessages(NewSMB2TreeConnectRequest(3, 0x1234, `\\192.168.1.2\IPC$`))
+	create := ParseSMBMessages(NewSMB2CreatePipeRequest(4, 0x1234, 5, SrvsvcPipe))
+	write := ParseSMBMessages(NewSMB2WriteRequest(5, 0x1234, 5, fileID, []byte("data")))
+	read := ParseSMBMessages(NewSMB2ReadRequest(6, 0x1234, 5, fileID, 1024))
+	if len(tree) != 1 || len(create) != 1 || len(write) != 1 || len(read) != 1 {
+		t.Fatalf("expected one message each")
+	}
+
+	if path := tree[0].TreePath(); path != `\\192.168.1.2\IPC$` {
+		t.Fatalf("unexpected tree path '%s'", path)
+	} else if tree[0].MessageID != 3 || tree[0].SessionID != 0x1234 || tree[0].Response {
+		t.Fatalf("unexpected header %+v", tree[0])
+	}
+
+	if name := create[0].CreateName(); name != SrvsvcPipe {
+		t.Fatalf("unexpected create name '%s'", name)
+	} else if create[0].TreeID != 5 {
+		t.Fatalf("unexpected tree id %d", create[0].TreeID)
+	}
+
+	if id, offset, length, ok := write[0].IO(); !ok || id != fileID || offset != 0 || length != 4 {
+		t.Fatalf("unexpected write %s %d %d %v", id, offset, length, ok)
+	} else if data := write[0].WriteData(); string(data) != "data" {
+		t.Fatalf("unexpected write data '%s'", data)
+	}
+
+	if id, _, length, ok := read[0].IO(); !ok || id != fileID || length != 1024 {
+		t.Fatalf("unexpected read %s %d %v", id, length, ok)
+	}
+}
+
+func TestSMB2SessionSetup(t *testing.T) {
+	req := ParseSMBMessages(NewSMB2SessionSetupRequest(1, 0, NewNTLMNegotiate()))
+	if len(req) != 1 || !req[0].IsSessionSetup() {
+		t.Fatalf("expected a session setup request")
+	} else if _, ntype := req[0].NTLMSSP(); ntype != NTLM_NEGOTIATE {
+		t.Fatalf("unexpected NTLMSSP type %d", ntype)
+	}
+
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint16(body[0:], 9)
+	binary.LittleEndian.PutUint16(body[2:], SMB2SessionNull)
+	resp := ParseSMBMessages(netbios(smb2Message(SMB2CommandSessionSetup, true, 2, body)))
+	if len(resp) != 1 {
+		t.Fatalf("expected a session setup response")
+	} else if flags, ok := resp[0].SessionFlags(); !ok || flags != SMB2SessionNull {
+		t.Fatalf("unexpected session flags %d %v", flags, ok)
+	} else if _, ok = req[0].SessionFlags(); ok {
+		t.Fatalf("unexpected session flags for a request")
+	}
+}
+
+func ntlmTargetInfoChallenge(target string, attrs map[uint16]string) []byte {
+	msg := ntlmChallengeMessage([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	binary.LittleEndian.PutUint32(msg[NTLM_TYPE2_FLAGS_OFFSET:], ntlmFlagUnicode|ntlmFlagTargetInfo|ntlmFlagVersion)
+	// windows 10.0 build 19041
+	msg = append(msg, 10, 0, 0x61, 0x4a, 0, 0, 0, 15)
+
+	field := func(at int, data []byte) {
+		binary.LittleEndian.PutUint16(msg[at:], uint16(len(data)))
+		binary.LittleEndian.PutUint16(msg[at+2:], uint16(len(data)))
+		binary.LittleEndian.PutUint32(msg[at+4:], uint32(len(msg)))
+		msg = append(msg, data...)
+	}
+
+	info := []byte{}
+	for _, id := range []uint16{ntlmAvNbDomainName, ntlmAvNbComputerName, ntlmAvDNSDomainName, ntlmAvDNSComputerName} {
+		value := utf16Encode(attrs[id])
+		header := make([]byte, 4)
+		binary.LittleEndian.PutUint16(header[0:], id)
+		binary.LittleEndian.PutUint16(header[2:], uint16(len(value)))
+		info = append(append(info, header...), value...)
+	}
+	info = append(info, 0, 0, 0, 0)
+
+	field(NTLM_TYPE2_TARGET_OFFSET, utf16Encode(target))
+	field(NTLM_TYPE2_TARGETINFO_OFFSET, info)
+	return msg
+}
+
+func TestParseNTLMChallengeInfo(t *testing.T) {
+	msg := ntlmTargetInfoChallenge("CORP", map[uint16]string{
+		ntlmAvNbDomainName:    "CORP",
+		ntlmAvNbComputerName:  "FILESRV",
+		ntlmAvDNSDomainName:   "corp.local",
+		ntlmAvDNSComputerName: "filesrv.corp.local",
+	})
+
+	info, err := ParseNTLMChallengeInfo(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if info.Target != "CORP" || info.NetBIOSDomain != "CORP" || info.NetBIOSComputer != "FILESRV" {
+		t.Fatalf("unexpected NetBIOS names %+v", info)
+	} else if info.DNSDomain != "corp.local" || info.DNSComputer != "filesrv.corp.local" || info.DNSTree != "" {
+		t.Fatalf("unexpected DNS names %+v", info)
+	} else if info.OSVersion != "10.0.19041" {
+		t.Fatalf("unexpected OS version '%s'", info.OSVersion)
+	}
+
+	if _, err = ParseNTLMChallengeInfo(NewNTLMNegotiate()); err != ErrNTLMChallengeInvalid {
+		t.Fatalf("expected an error for a negotiate message, got %v", err)
+	} else if _, err = ParseNTLMChallengeInfo(msg[:20]); err != ErrNTLMChallengeInvalid {
+		t.Fatalf("expected an error for a truncated message, got %v", err)
+	}
+}
+
+func TestNTLMAnonymousAuthenticate(t *testing.T) {
+	info, err := ParseNTLMChallengeInfo(ntlmTargetInfoChallenge("CORP", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := NewNTLMAnonymousAuthenticate(info)
+	if binary.LittleEndian.Uint32(msg[NTLM_TYPE_OFFSET:]) != NTLM_AUTHENTICATE {
+		t.Fatalf("expected an authenticate message")
+	} else if flags := binary.LittleEndian.Uint32(msg[NTLM_TYPE3_FLAGS_OFFSET:]); flags&ntlmFlagAnonymous == 0 || flags&ntlmFlagNTLM != 0 {
+		t.Fatalf("unexpected flags 0x%08x", flags)
+	} else if lm := ntlmBuffer(msg, NTLM_TYPE3_LMRESP_OFFSET); len(lm) != 1 || lm[0] != 0 {
+		t.Fatalf("unexpected LM response %x", lm)
+	} else if nt := ntlmBuffer(msg, NTLM_TYPE3_NTRESP_OFFSET); nt != nil {
+		t.Fatalf("unexpected NT response %x", nt)
+	} else if user := ntlmBuffer(msg, NTLM_TYPE3_USER_OFFSET); user != nil {
+		t.Fatalf("unexpected user %x", user)
+	}
+}
@@ -0,0 +1,237 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSMB2NegotiateRequest(t *testing.T) {
+	req := NewSMB2NegotiateRequest()
+
+	if size := binary.BigEndian.Uint32(req[0:4]); int(size) != len(req)-4 {
+		t.Fatalf("expected a session length of %d, got %d", len(req)-4, size)
+	} else if string(req[5:8]) != "SMB" || req[4] != 0xfe {
+		t.Fatalf("unexpected protocol id %x", req[4:8])
+	} else if count := binary.LittleEndian.Uint16(req[4+smb2HeaderSize+2:]); count != 4 {
+		t.Fatalf("expected 4 dialects, got %d", count)
+	}
+}
+
+func smb2NegotiateResponse(mode uint16, revision uint16) []byte {
+	msg := make([]byte, smb2HeaderSize+65)
+	copy(msg, []byte{0xfe, 'S', 'M', 'B'})
+	binary.LittleEndian.PutUint16(msg[4:], smb2HeaderSize)
+	binary.LittleEndian.PutUint16(msg[smb2HeaderSize:], 65)
+	binary.LittleEndian.PutUint16(msg[smb2HeaderSize+2:], mode)
+	binary.LittleEndian.PutUint16(msg[smb2HeaderSize+4:], revision)
+
+	frame := make([]byte, 4)
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+func TestParseSMB2NegotiateResponse(t *testing.T) {
+	cases := []struct {
+		mode     uint16
+		revision uint16
+		dialect  string
+		signing  string
+	}{
+		{SMB2SigningEnabled | SMB2SigningRequired, 0x0311, "3.1.1", "required"},
+		{SMB2SigningEnabled, 0x0210, "2.1", "enabled"},
+		{0, 0x0202, "2.0.2", "disabled"},
+		{SMB2SigningEnabled, 0x02ff, "0x02ff", "enabled"},
+	}
+
+	for _, c := range cases {
+		info, err := ParseSMB2NegotiateResponse(smb2NegotiateResponse(c.mode, c.revision))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		} else if info.Dialect != c.dialect {
+			t.Fatalf("expected dialect %s, got %s", c.dialect, info.Dialect)
+		} else if info.Signing() != c.signing {
+			t.Fatalf("expected signing %s, got %s", c.signing, info.Signing())
+		}
+	}
+
+	if _, err := ParseSMB2NegotiateResponse([]byte{0, 0, 0, 4, 0xff, 'S', 'M', 'B'}); err == nil {
+		t.Fatalf("expected an error for a short response")
+	}
+}
+
+func ntlmChallengeMessage(challenge []byte) []byte {
+	msg := make([]byte, NTLM_TYPE2_DATA_OFFSET)
+	copy(msg, ntlmsspSignature)
+	binary.LittleEndian.PutUint32(msg[NTLM_TYPE_OFFSET:], NTLM_CHALLENGE)
+	copy(msg[NTLM_TYPE2_CHALLENGE_OFFSET:], challenge)
+	return msg
+}
+
+func ntlmAuthenticateMessage(domain, user string, nt []byte) []byte {
+	msg := make([]byte, NTLM_TYPE3_DATA_OFFSET)
+	copy(msg, ntlmsspSignature)
+	binary.LittleEndian.PutUint32(msg[NTLM_TYPE_OFFSET:], NTLM_AUTHENTICATE)
+
+	field := func(at int, data []byte) {
+		binary.LittleEndian.PutUint16(msg[at:], uint16(len(data)))
+		binary.LittleEndian.PutUint16(msg[at+2:], uint16(len(data)))
+		binary.LittleEndian.PutUint16(msg[at+4:], uint16(len(msg)))
+		msg = append(msg, data...)
+	}
+	field(NTLM_TYPE3_LMRESP_OFFSET, make([]byte, 24))
+	field(NTLM_TYPE3_NTRESP_OFFSET, nt)
+	field(NTLM_TYPE3_DOMAIN_OFFSET, []byte(domain))
+	field(NTLM_TYPE3_USER_OFFSET, []byte(user))
+	return msg
+}
+
+func smb2Message(command uint16, response bool, messageID uint64, body []byte) []byte {
+	msg := make([]byte, smb2HeaderSize)
+	copy(msg, []byte{0xfe, 'S', 'M', 'B'})
+	binary.LittleEndian.PutUint16(msg[4:], smb2HeaderSize)
+	binary.LittleEndian.PutUint16(msg[12:], command)
+	if response {
+		binary.LittleEndian.PutUint32(msg[16:], smb2FlagsResponse)
+	}
+	binary.LittleEndian.PutUint64(msg[24:], messageID)
+	return append(msg, body...)
+}
+
+func netbios(msgs ...[]byte) []byte {
+	payload := []byte{}
+	for _, msg := range msgs {
+		frame := make([]byte, 4)
+		binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+		payload = append(append(payload, frame...), msg...)
+	}
+	return payload
+}
+
+func utf16Bytes(s string) []byte {
+	b := make([]byte, 0)
+	for _, c := range s {
+		b = append(b, byte(c), 0)
+	}
+	return b
+}
+
+func TestParseSMBMessagesSessionSetup(t *testing.T) {
+	challenge := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	nt := make([]byte, 48)
+	nt[0] = 0xaa
+
+	// a SPNEGO wrapper before the NTLMSSP messages
+	respBody := append(make([]byte, 8), append([]byte{0xa1, 0x81, 0x20}, ntlmChallengeMessage(challenge)...)...)
+	reqBody := append(make([]byte, 24), ntlmAuthenticateMessage("CORP", "alice", nt)...)
+
+	resp := ParseSMBMessages(netbios(smb2Message(SMB2CommandSessionSetup, true, 1, respBody)))
+	req := ParseSMBMessages(netbios(smb2Message(SMB2CommandSessionSetup, false, 2, reqBody)))
+	if len(resp) != 1 || len(req) != 1 {
+		t.Fatalf("expected one message each, got %d and %d", len(resp), len(req))
+	} else if !resp[0].Response || req[0].Response || !resp[0].IsSessionSetup() {
+		t.Fatalf("unexpected messages %+v %+v", resp[0], req[0])
+	}
+
+	chall, ctype := resp[0].NTLMSSP()
+	auth, atype := req[0].NTLMSSP()
+	if ctype != NTLM_CHALLENGE || atype != NTLM_AUTHENTICATE {
+		t.Fatalf("unexpected NTLMSSP types %d and %d", ctype, atype)
+	}
+
+	pair := NewNTLMChallengeResponse(chall, auth)
+	parsed, err := pair.Parsed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "alice::CORP:0102030405060708:aa000000000000000000000000000000:" + hex.EncodeToString(nt[16:]) + "\n"
+	if got := parsed.LcString(); got != exp {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+
+	truncated := NewNTLMChallengeResponse(chall, auth[:len(auth)-10])
+	if _, err := truncated.Parsed(); err == nil {
+		t.Fatalf("expected an error for truncated messages")
+	}
+}
+
+func TestParseSMBMessagesFiles(t *testing.T) {
+	name := utf16Bytes(`docs\secret.txt`)
+	create := make([]byte, 56)
+	binary.LittleEndian.PutUint16(create[44:], smb2HeaderSize+56)
+	binary.LittleEndian.PutUint16(create[46:], uint16(len(name)))
+	create = append(create, name...)
+
+	created := make([]byte, 88)
+	created[64] = 0x42
+
+	read := make([]byte, 48)
+	binary.LittleEndian.PutUint32(read[4:], 4096)
+	binary.LittleEndian.PutUint64(read[8:], 8192)
+	read[16] = 0x42
+
+	msgs := ParseSMBMessages(netbios(
+		smb2Message(SMB2CommandCreate, false, 5, create),
+		smb2Message(SMB2CommandCreate, true, 5, created),
+		smb2Message(SMB2CommandRead, false, 6, read)))
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	} else if got := msgs[0].CreateName(); got != `docs\secret.txt` {
+		t.Fatalf("unexpected file name '%s'", got)
+	} else if got := msgs[1].CreateFileID(); got != "42000000000000000000000000000000" {
+		t.Fatalf("unexpected file id '%s'", got)
+	}
+
+	fileID, offset, length, ok := msgs[2].IO()
+	if !ok || fileID != msgs[1].CreateFileID() || offset != 8192 || length != 4096 {
+		t.Fatalf("unexpected read %s %d %d %v", fileID, offset, length, ok)
+	}
+
+	if msgs := ParseSMBMessages([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")); len(msgs) != 0 {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+}
+
+func TestParseSMBMessagesData(t *testing.T) {
+	content := []byte("top secret content")
+
+	readResp := make([]byte, 16)
+	readResp[2] = smb2HeaderSize + 16
+	binary.LittleEndian.PutUint32(readResp[4:], uint32(len(content)))
+	readResp = append(readResp, content...)
+
+	write := make([]byte, 48)
+	binary.LittleEndian.PutUint16(write[2:], smb2HeaderSize+48)
+	binary.LittleEndian.PutUint32(write[4:], uint32(len(content)))
+	write[16] = 0x42
+	write = append(write, content...)
+
+	closeReq := make([]byte, 24)
+	closeReq[8] = 0x42
+
+	msgs := ParseSMBMessages(netbios(
+		smb2Message(SMB2CommandRead, true, 6, readResp),
+		smb2Message(SMB2CommandWrite, false, 7, write),
+		smb2Message(SMB2CommandClose, false, 8, closeReq)))
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	} else if got := msgs[0].ReadData(); !bytes.Equal(got, content) {
+		t.Fatalf("unexpected read data '%s'", got)
+	} else if got := msgs[1].WriteData(); !bytes.Equal(got, content) {
+		t.Fatalf("unexpected write data '%s'", got)
+	} else if got := msgs[2].CloseFileID(); got != "42000000000000000000000000000000" {
+		t.Fatalf("unexpected file id '%s'", got)
+	} else if msgs[1].ReadData() != nil || msgs[0].WriteData() != nil || msgs[0].CloseFileID() != "" {
+		t.Fatal("unexpected data for the other commands")
+	}
+
+	// the part of the data that is there
+	payload := netbios(smb2Message(SMB2CommandRead, true, 6, readResp))
+	truncated := ParseSMBMessages(payload[:len(payload)-5])
+	if len(truncated) != 1 {
+		t.Fatalf("expected one message, got %d", len(truncated))
+	} else if got := truncated[0].ReadData(); !bytes.Equal(got, content[:len(content)-5]) {
+		t.Fatalf("unexpected truncated data '%s'", got)
+	}
+}
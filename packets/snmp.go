@@ -0,0 +1,230 @@
+package packets
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"unicode"
+)
+
+const (
+	SNMPPort     = 161
+	SNMPTrapPort = 162
+
+	SNMPVersion1  = 0
+	SNMPVersion2c = 1
+)
+
+// context specific tags of the SNMP PDUs
+const (
+	SNMPGetRequest     = 0
+	SNMPGetNextRequest = 1
+	SNMPResponse       = 2
+	SNMPSetRequest     = 3
+	SNMPTrapV1         = 4
+	SNMPGetBulkRequest = 5
+	SNMPInformRequest  = 6
+	SNMPTrapV2         = 7
+	SNMPReport         = 8
+)
+
+var SNMPPDUNames = map[int]string{
+	SNMPGetRequest:     "get",
+	SNMPGetNextRequest: "getnext",
+	SNMPResponse:       "response",
+	SNMPSetRequest:     "set",
+	SNMPTrapV1:         "trap",
+	SNMPGetBulkRequest: "getbulk",
+	SNMPInformRequest:  "inform",
+	SNMPTrapV2:         "trap",
+	SNMPReport:         "report",
+}
+
+var ErrSNMPVersion = errors.New("unsupported SNMP version")
+
+type SNMPVarBind struct {
+	OID   string `json:"oid"`
+	Value string `json:"value"`
+}
+
+// SNMPMessage is a community based (v1 and v2c) SNMP message.
+type SNMPMessage struct {
+	Version   int           `json:"version"`
+	Community string        `json:"community"`
+	PDU       int           `json:"pdu"`
+	RequestID int64         `json:"request_id"`
+	VarBinds  []SNMPVarBind `json:"varbinds"`
+	// only set for v1 traps
+	Enterprise   string `json:"enterprise,omitempty"`
+	AgentAddress net.IP `json:"agent_address,omitempty"`
+	GenericTrap  int    `json:"generic_trap,omitempty"`
+	SpecificTrap int    `json:"specific_trap,omitempty"`
+}
+
+type snmpMessage struct {
+	Version   int
+	Community []byte
+	PDU       asn1.RawValue
+}
+
+type snmpVarBind struct {
+	Name  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type snmpPDU struct {
+	RequestID   int64
+	ErrorStatus int
+	ErrorIndex  int
+	VarBinds    []snmpVarBind
+}
+
+type snmpTrapPDU struct {
+	Enterprise   asn1.ObjectIdentifier
+	AgentAddress asn1.RawValue
+	GenericTrap  int
+	SpecificTrap int
+	Timestamp    asn1.RawValue
+	VarBinds     []snmpVarBind
+}
+
+func (m SNMPMessage) PDUName() string {
+	if name, found := SNMPPDUNames[m.PDU]; found {
+		return name
+	}
+	return fmt.Sprintf("pdu(%d)", m.PDU)
+}
+
+func (m SNMPMessage) VersionName() string {
+	if m.Version == SNMPVersion1 {
+		return "v1"
+	}
+	return "v2c"
+}
+
+// IsTrap returns true for the unsolicited notifications sent by the agents.
+func (m SNMPMessage) IsTrap() bool {
+	return m.PDU == SNMPTrapV1 || m.PDU == SNMPTrapV2 || m.PDU == SNMPInformRequest
+}
+
+func snmpUnsigned(raw []byte) uint64 {
+	n := uint64(0)
+	for _, b := range raw {
+		n = n<<8 | uint64(b)
+	}
+	return n
+}
+
+func snmpString(raw []byte) string {
+	for _, c := range string(raw) {
+		if !unicode.IsPrint(c) && !unicode.IsSpace(c) {
+			return hex.EncodeToString(raw)
+		}
+	}
+	return string(raw)
+}
+
+// snmpValue formats the value of a variable binding.
+func snmpValue(v asn1.RawValue) string {
+	switch v.Class {
+	case asn1.ClassUniversal:
+		switch v.Tag {
+		case asn1.TagInteger:
+			var n int64
+			if _, err := asn1.Unmarshal(v.FullBytes, &n); err == nil {
+				return strconv.FormatInt(n, 10)
+			}
+		case asn1.TagOctetString:
+			return snmpString(v.Bytes)
+		case asn1.TagOID:
+			var oid asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(v.FullBytes, &oid); err == nil {
+				return oid.String()
+			}
+		case asn1.TagNull:
+			return ""
+		}
+	case asn1.ClassApplication:
+		switch v.Tag {
+		case 0:
+			// IpAddress
+			if len(v.Bytes) == 4 {
+				return net.IP(v.Bytes).String()
+			}
+		case 1, 2, 3, 6:
+			// Counter32, Gauge32, TimeTicks and Counter64
+			return strconv.FormatUint(snmpUnsigned(v.Bytes), 10)
+		}
+	case asn1.ClassContextSpecific:
+		switch v.Tag {
+		case 0:
+			return "noSuchObject"
+		case 1:
+			return "noSuchInstance"
+		case 2:
+			return "endOfMibView"
+		}
+	}
+	return hex.EncodeToString(v.Bytes)
+}
+
+func snmpVarBinds(binds []snmpVarBind) []SNMPVarBind {
+	list := make([]SNMPVarBind, 0, len(binds))
+	for _, b := range binds {
+		list = append(list, SNMPVarBind{
+			OID:   b.Name.String(),
+			Value: snmpValue(b.Value),
+		})
+	}
+	return list
+}
+
+// ParseSNMP parses a v1 or v2c SNMP message, v3 ones are not community based
+// and ErrSNMPVersion is returned for them.
+func ParseSNMP(payload []byte) (*SNMPMessage, error) {
+	var raw snmpMessage
+	if rest, err := asn1.Unmarshal(payload, &raw); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("%d trailing bytes after SNMP message", len(rest))
+	} else if raw.Version != SNMPVersion1 && raw.Version != SNMPVersion2c {
+		return nil, ErrSNMPVersion
+	} else if raw.PDU.Class != asn1.ClassContextSpecific || !raw.PDU.IsCompound {
+		return nil, fmt.Errorf("unexpected SNMP PDU class %d", raw.PDU.Class)
+	}
+
+	msg := &SNMPMessage{
+		Version:   raw.Version,
+		Community: string(raw.Community),
+		PDU:       raw.PDU.Tag,
+	}
+
+	// the PDUs are implicitly tagged sequences
+	inner := append([]byte{0x30}, raw.PDU.FullBytes[1:]...)
+
+	if msg.PDU == SNMPTrapV1 {
+		var trap snmpTrapPDU
+		if _, err := asn1.Unmarshal(inner, &trap); err != nil {
+			return nil, err
+		}
+		msg.Enterprise = trap.Enterprise.String()
+		if len(trap.AgentAddress.Bytes) == 4 {
+			msg.AgentAddress = net.IP(trap.AgentAddress.Bytes)
+		}
+		msg.GenericTrap = trap.GenericTrap
+		msg.SpecificTrap = trap.SpecificTrap
+		msg.VarBinds = snmpVarBinds(trap.VarBinds)
+	} else {
+		var pdu snmpPDU
+		if _, err := asn1.Unmarshal(inner, &pdu); err != nil {
+			return nil, err
+		}
+		msg.RequestID = pdu.RequestID
+		msg.VarBinds = snmpVarBinds(pdu.VarBinds)
+	}
+
+	return msg, nil
+}
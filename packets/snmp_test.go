@@ -0,0 +1,74 @@
+package packets
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+func snmpPayload(t *testing.T, s string) []byte {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestParseSNMPGetRequest(t *testing.T) {
+	// v2c get-request for sysDescr.0 with community "public"
+	payload := snmpPayload(t, "302902010104067075626c6963a01c02041234567802010002010030"+
+		"0e300c06082b060102010101000500")
+
+	msg, err := ParseSNMP(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msg.Version != SNMPVersion2c || msg.VersionName() != "v2c" {
+		t.Fatalf("unexpected version %d", msg.Version)
+	} else if msg.Community != "public" {
+		t.Fatalf("unexpected community '%s'", msg.Community)
+	} else if msg.PDU != SNMPGetRequest || msg.PDUName() != "get" || msg.IsTrap() {
+		t.Fatalf("unexpected pdu %d", msg.PDU)
+	} else if msg.RequestID != 0x12345678 {
+		t.Fatalf("unexpected request id %x", msg.RequestID)
+	} else if len(msg.VarBinds) != 1 {
+		t.Fatalf("expected 1 varbind, got %d", len(msg.VarBinds))
+	} else if bind := msg.VarBinds[0]; bind.OID != "1.3.6.1.2.1.1.1.0" || bind.Value != "" {
+		t.Fatalf("unexpected varbind %+v", bind)
+	}
+}
+
+func TestParseSNMPTrapV1(t *testing.T) {
+	// v1 trap from 192.168.1.1 with a single string varbind
+	payload := snmpPayload(t, "303802010004067075626c6963a42b06062b06010401094004c0a8010102010602010143"+
+		"0204d23011300f06072b0601040109010404646f776e")
+
+	msg, err := ParseSNMP(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msg.Version != SNMPVersion1 || msg.PDU != SNMPTrapV1 || !msg.IsTrap() {
+		t.Fatalf("unexpected message %+v", msg)
+	} else if msg.Enterprise != "1.3.6.1.4.1.9" {
+		t.Fatalf("unexpected enterprise %s", msg.Enterprise)
+	} else if !msg.AgentAddress.Equal(net.ParseIP("192.168.1.1")) {
+		t.Fatalf("unexpected agent address %s", msg.AgentAddress)
+	} else if msg.GenericTrap != 6 || msg.SpecificTrap != 1 {
+		t.Fatalf("unexpected trap type %d/%d", msg.GenericTrap, msg.SpecificTrap)
+	} else if len(msg.VarBinds) != 1 {
+		t.Fatalf("expected 1 varbind, got %d", len(msg.VarBinds))
+	} else if bind := msg.VarBinds[0]; bind.OID != "1.3.6.1.4.1.9.1" || bind.Value != "down" {
+		t.Fatalf("unexpected varbind %+v", bind)
+	}
+}
+
+func TestParseSNMPInvalid(t *testing.T) {
+	// v3 messages are not community based
+	if _, err := ParseSNMP(snmpPayload(t, "30070201030400a000")); err != ErrSNMPVersion {
+		t.Fatalf("expected %v, got %v", ErrSNMPVersion, err)
+	}
+
+	for _, s := range []string{"", "3003020101", "deadbeef"} {
+		if _, err := ParseSNMP(snmpPayload(t, s)); err == nil {
+			t.Fatalf("expected error for '%s'", s)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// named pipe of the server service, relative to IPC$
+	SrvsvcPipe = "srvsvc"
+
+	dcerpcHeaderSize  = 16
+	dcerpcRequestSize = 24
+	dcerpcMaxFragment = 4280
+
+	dcerpcRequest  = 0
+	dcerpcResponse = 2
+	dcerpcFault    = 3
+	dcerpcBind     = 11
+	dcerpcBindAck  = 12
+
+	dcerpcFirstFrag = 0x01
+	dcerpcLastFrag  = 0x02
+
+	srvsvcNetShareEnumAll = 15
+
+	// share types, the special flag marks administrative shares
+	SMBShareDisk    = 0
+	SMBSharePrinter = 1
+	SMBShareDevice  = 2
+	SMBShareIPC     = 3
+	SMBShareSpecial = 0x80000000
+)
+
+var (
+	ErrDCERPCInvalid = errors.New("not a valid DCE/RPC message")
+
+	// 4b324fc8-1670-01d3-1278-5a47bf6ee188 v3.0
+	srvsvcInterface = []byte{
+		0xc8, 0x4f, 0x32, 0x4b, 0x70, 0x16, 0xd3, 0x01,
+		0x12, 0x78, 0x5a, 0x47, 0xbf, 0x6e, 0xe1, 0x88,
+		0x03, 0x00, 0x00, 0x00,
+	}
+	// 8a885d04-1ceb-11c9-9fe8-08002b104860 v2
+	ndrTransferSyntax = []byte{
+		0x04, 0x5d, 0x88, 0x8a, 0xeb, 0x1c, 0xc9, 0x11,
+		0x9f, 0xe8, 0x08, 0x00, 0x2b, 0x10, 0x48, 0x60,
+		0x02, 0x00, 0x00, 0x00,
+	}
+)
+
+// SMBShare is a share of a server as enumerated by NetShareEnumAll.
+type SMBShare struct {
+	Name   string `json:"name"`
+	Type   uint32 `json:"type"`
+	Remark string `json:"remark"`
+}
+
+// TypeName returns the kind of resource shared.
+func (s SMBShare) TypeName() string {
+	switch s.Type &^ SMBShareSpecial {
+	case SMBShareDisk:
+		return "disk"
+	case SMBSharePrinter:
+		return "printer"
+	case SMBShareDevice:
+		return "device"
+	case SMBShareIPC:
+		return "ipc"
+	}
+	return fmt.Sprintf("type(%d)", s.Type)
+}
+
+// IsSpecial returns true for the administrative shares such as C$.
+func (s SMBShare) IsSpecial() bool {
+	return s.Type&SMBShareSpecial != 0
+}
+
+func dcerpcHeader(ptype byte, size int, callID uint32) []byte {
+	pdu := make([]byte, size)
+	// version 5.0 of a single fragment, little endian
+	pdu[0] = 5
+	pdu[2] = ptype
+	pdu[3] = dcerpcFirstFrag | dcerpcLastFrag
+	pdu[4] = 0x10
+	binary.LittleEndian.PutUint16(pdu[8:], uint16(size))
+	binary.LittleEndian.PutUint32(pdu[12:], callID)
+	return pdu
+}
+
+// NewSrvsvcBind creates the DCE/RPC bind to the server service interface.
+func NewSrvsvcBind() []byte {
+	pdu := dcerpcHeader(dcerpcBind, dcerpcHeaderSize+12+4+len(srvsvcInterface)+len(ndrTransferSyntax), 1)
+	binary.LittleEndian.PutUint16(pdu[16:], dcerpcMaxFragment)
+	binary.LittleEndian.PutUint16(pdu[18:], dcerpcMaxFragment)
+	// one presentation context with one transfer syntax
+	pdu[24] = 1
+	pdu[30] = 1
+	copy(pdu[32:], srvsvcInterface)
+	copy(pdu[32+len(srvsvcInterface):], ndrTransferSyntax)
+	return pdu
+}
+
+// ParseDCERPCBindAck returns an error if the bind has not been accepted.
+func ParseDCERPCBindAck(pdu []byte) error {
+	if len(pdu) < dcerpcHeaderSize || pdu[0] != 5 {
+		return ErrDCERPCInvalid
+	} else if pdu[2] != dcerpcBindAck {
+		return fmt.Errorf("bind rejected (type %d)", pdu[2])
+	}
+	return nil
+}
+
+type ndrWriter struct {
+	bytes.Buffer
+	referent uint32
+}
+
+func (w *ndrWriter) uint32(v uint32) {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	w.Write(b)
+}
+
+// pointer writes the referent id of a non null unique pointer
+func (w *ndrWriter) pointer() {
+	w.referent += 4
+	w.uint32(0x00020000 + w.referent)
+}
+
+// conformant and varying null terminated UTF-16 string
+func (w *ndrWriter) string(s string) {
+	chars := utf16Encode(s + "\x00")
+	w.uint32(uint32(len(chars) / 2))
+	w.uint32(0)
+	w.uint32(uint32(len(chars) / 2))
+	w.Write(chars)
+	for w.Len()%4 != 0 {
+		w.WriteByte(0)
+	}
+}
+
+// NewSrvsvcNetShareEnumAll creates the DCE/RPC request enumerating the
+// shares of a server with their type and remark.
+func NewSrvsvcNetShareEnumAll(server string) []byte {
+	stub := ndrWriter{}
+	stub.pointer()
+	stub.string(`\\` + server)
+	// SHARE_INFO_1 level and container, with no entries
+	stub.uint32(1)
+	stub.uint32(1)
+	stub.pointer()
+	stub.uint32(0)
+	stub.uint32(0)
+	// preferred maximum length and resume handle
+	stub.uint32(0xffffffff)
+	stub.pointer()
+	stub.uint32(0)
+
+	pdu := dcerpcHeader(dcerpcRequest, dcerpcRequestSize+stub.Len(), 2)
+	binary.LittleEndian.PutUint32(pdu[16:], uint32(stub.Len()))
+	binary.LittleEndian.PutUint16(pdu[22:], srvsvcNetShareEnumAll)
+	copy(pdu[dcerpcRequestSize:], stub.Bytes())
+	return pdu
+}
+
+// ParseDCERPCResponse returns the stub data of a response fragment and
+// whether it's the last one.
+func ParseDCERPCResponse(pdu []byte) ([]byte, bool, error) {
+	if len(pdu) < dcerpcRequestSize || pdu[0] != 5 {
+		return nil, false, ErrDCERPCInvalid
+	} else if pdu[2] == dcerpcFault {
+		return nil, false, fmt.Errorf("rpc fault 0x%08x", binary.LittleEndian.Uint32(pdu[24:]))
+	} else if pdu[2] != dcerpcResponse {
+		return nil, false, ErrDCERPCInvalid
+	}
+
+	size := int(binary.LittleEndian.Uint16(pdu[8:]))
+	if size < dcerpcRequestSize || size > len(pdu) {
+		return nil, false, ErrDCERPCInvalid
+	}
+	return pdu[dcerpcRequestSize:size], pdu[3]&dcerpcLastFrag != 0, nil
+}
+
+type ndrReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *ndrReader) uint32() uint32 {
+	if r.err != nil || r.offset+4 > len(r.data) {
+		r.err = ErrDCERPCInvalid
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.data[r.offset:])
+	r.offset += 4
+	return v
+}
+
+func (r *ndrReader) string() string {
+	r.uint32()
+	r.uint32()
+	size := 2 * int(r.uint32())
+	if r.err != nil || r.offset+size > len(r.data) {
+		r.err = ErrDCERPCInvalid
+		return ""
+	}
+	s := utf16String(r.data[r.offset : r.offset+size])
+	r.offset += size
+	for r.offset%4 != 0 {
+		r.offset++
+	}
+	return strings.TrimRight(s, "\x00")
+}
+
+// ParseSrvsvcNetShareEnumAll parses the stub data of a NetShareEnumAll
+// response of level 1.
+func ParseSrvsvcNetShareEnumAll(stub []byte) ([]SMBShare, error) {
+	r := &ndrReader{data: stub}
+	if level := r.uint32(); r.err == nil && level != 1 {
+		return nil, fmt.Errorf("unexpected info level %d", level)
+	}
+	// switch value and container pointer
+	r.uint32()
+	r.uint32()
+	count := int(r.uint32())
+	array := r.uint32()
+
+	shares := make([]SMBShare, 0)
+	if array != 0 {
+		if max := int(r.uint32()); r.err != nil || max != count || count > len(stub)/12 {
+			return nil, ErrDCERPCInvalid
+		}
+
+		// the strings follow the fixed part of the entries
+		pointers := make([][2]uint32, count)
+		for i := 0; i < count; i++ {
+			pointers[i][0] = r.uint32()
+			shares = append(shares, SMBShare{Type: r.uint32()})
+			pointers[i][1] = r.uint32()
+		}
+		for i := 0; i < count; i++ {
+			if pointers[i][0] != 0 {
+				shares[i].Name = r.string()
+			}
+			if pointers[i][1] != 0 {
+				shares[i].Remark = r.string()
+			}
+		}
+	}
+
+	// total entries and resume handle before the result
+	r.offset = len(stub) - 4
+	if result := r.uint32(); r.err != nil {
+		return nil, r.err
+	} else if result != 0 {
+		return nil, fmt.Errorf("NetShareEnumAll failed with error 0x%08x", result)
+	}
+	return shares, nil
+}
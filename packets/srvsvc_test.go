@@ -0,0 +1,100 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSrvsvcBind(t *testing.T) {
+	bind := NewSrvsvcBind()
+	if len(bind) != 72 || int(binary.LittleEndian.Uint16(bind[8:])) != len(bind) {
+		t.Fatalf("unexpected bind of %d bytes", len(bind))
+	} else if bind[2] != dcerpcBind || !bytes.Equal(bind[32:52], srvsvcInterface) {
+		t.Fatalf("unexpected bind %x", bind)
+	}
+
+	ack := dcerpcHeader(dcerpcBindAck, 60, 1)
+	if err := ParseDCERPCBindAck(ack); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err = ParseDCERPCBindAck(dcerpcHeader(13, 20, 1)); err == nil {
+		t.Fatalf("expected an error for a bind nak")
+	}
+}
+
+func TestSrvsvcNetShareEnumAll(t *testing.T) {
+	req := NewSrvsvcNetShareEnumAll("192.168.1.2")
+	if int(binary.LittleEndian.Uint16(req[8:])) != len(req) || len(req)%4 != 0 {
+		t.Fatalf("unexpected request of %d bytes", len(req))
+	} else if opnum := binary.LittleEndian.Uint16(req[22:]); opnum != srvsvcNetShareEnumAll {
+		t.Fatalf("unexpected opnum %d", opnum)
+	} else if name := utf16Encode(`\\192.168.1.2`); !bytes.Contains(req, name) {
+		t.Fatalf("server name not found in %x", req)
+	}
+
+	shares := []SMBShare{
+		{Name: "ADMIN$", Type: SMBShareDisk | SMBShareSpecial, Remark: "Remote Admin"},
+		{Name: "IPC$", Type: SMBShareIPC | SMBShareSpecial, Remark: "Remote IPC"},
+		{Name: "public", Type: SMBShareDisk},
+	}
+
+	stub := ndrWriter{}
+	stub.uint32(1)
+	stub.uint32(1)
+	stub.pointer()
+	stub.uint32(uint32(len(shares)))
+	stub.pointer()
+	stub.uint32(uint32(len(shares)))
+	for _, share := range shares {
+		stub.pointer()
+		stub.uint32(share.Type)
+		if share.Remark != "" {
+			stub.pointer()
+		} else {
+			stub.uint32(0)
+		}
+	}
+	for _, share := range shares {
+		stub.string(share.Name)
+		if share.Remark != "" {
+			stub.string(share.Remark)
+		}
+	}
+	// total entries, resume handle and result
+	stub.uint32(uint32(len(shares)))
+	stub.pointer()
+	stub.uint32(0)
+	stub.uint32(0)
+
+	pdu := append(dcerpcHeader(dcerpcResponse, dcerpcRequestSize+stub.Len(), 2)[:dcerpcRequestSize], stub.Bytes()...)
+	data, last, err := ParseDCERPCResponse(pdu)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !last {
+		t.Fatalf("expected the last fragment")
+	}
+
+	parsed, err := ParseSrvsvcNetShareEnumAll(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(parsed) != len(shares) {
+		t.Fatalf("expected %d shares, got %d", len(shares), len(parsed))
+	}
+	for i, share := range shares {
+		if parsed[i] != share {
+			t.Fatalf("expected %+v, got %+v", share, parsed[i])
+		}
+	}
+
+	if parsed[0].TypeName() != "disk" || !parsed[0].IsSpecial() || parsed[1].TypeName() != "ipc" || parsed[2].IsSpecial() {
+		t.Fatalf("unexpected share types %+v", parsed)
+	}
+
+	// access denied
+	binary.LittleEndian.PutUint32(data[len(data)-4:], 5)
+	if _, err = ParseSrvsvcNetShareEnumAll(data); err == nil {
+		t.Fatalf("expected an error")
+	} else if _, err = ParseSrvsvcNetShareEnumAll(data[:10]); err == nil {
+		t.Fatalf("expected an error for a truncated stub")
+	}
+}
@@ -0,0 +1,50 @@
+package packets
+
+import (
+	"errors"
+	"strings"
+)
+
+const SSHPort = 22
+
+var ErrSSHVersionInvalid = errors.New("not a valid SSH identification string")
+
+// SSHVersion is the identification string both SSH peers send first:
+// SSH-protoversion-softwareversion SP comments CR LF
+type SSHVersion struct {
+	Protocol string `json:"protocol"`
+	Software string `json:"software"`
+	Comments string `json:"comments"`
+}
+
+// String returns the software version followed by the comments, if any.
+func (v *SSHVersion) String() string {
+	if v.Comments != "" {
+		return v.Software + " " + v.Comments
+	}
+	return v.Software
+}
+
+// ParseSSHVersion parses the identification string of an SSH peer.
+func ParseSSHVersion(line string) (*SSHVersion, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "SSH-") {
+		return nil, ErrSSHVersionInvalid
+	}
+
+	parts := strings.SplitN(line[4:], "-", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, ErrSSHVersionInvalid
+	}
+
+	v := &SSHVersion{
+		Protocol: parts[0],
+		Software: parts[1],
+	}
+	if idx := strings.IndexByte(v.Software, ' '); idx != -1 {
+		v.Comments = strings.TrimSpace(v.Software[idx+1:])
+		v.Software = v.Software[:idx]
+	}
+
+	return v, nil
+}
@@ -0,0 +1,32 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestParseSSHVersion(t *testing.T) {
+	v, err := ParseSSHVersion("SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6\r\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if v.Protocol != "2.0" || v.Software != "OpenSSH_8.9p1" || v.Comments != "Ubuntu-3ubuntu0.6" {
+		t.Fatalf("unexpected version %+v", v)
+	} else if s := v.String(); s != "OpenSSH_8.9p1 Ubuntu-3ubuntu0.6" {
+		t.Fatalf("unexpected string '%s'", s)
+	}
+
+	if v, err = ParseSSHVersion("SSH-1.99-dropbear_2022.83"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if v.Protocol != "1.99" || v.Software != "dropbear_2022.83" || v.Comments != "" {
+		t.Fatalf("unexpected version %+v", v)
+	} else if s := v.String(); s != "dropbear_2022.83" {
+		t.Fatalf("unexpected string '%s'", s)
+	}
+}
+
+func TestParseSSHVersionInvalid(t *testing.T) {
+	for _, line := range []string{"", "220 ftp.example.com FTP server", "SSH-", "SSH-2.0", "SSH-2.0-", "SSH--OpenSSH"} {
+		if _, err := ParseSSHVersion(line); err != ErrSSHVersionInvalid {
+			t.Fatalf("expected an error for '%s', got %v", line, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	STUNPort = 3478
+
+	stunHeaderSize  = 20
+	stunMagicCookie = 0x2112a442
+)
+
+const (
+	STUNBinding          = 0x001
+	STUNAllocate         = 0x003
+	STUNRefresh          = 0x004
+	STUNSend             = 0x006
+	STUNData             = 0x007
+	STUNCreatePermission = 0x008
+	STUNChannelBind      = 0x009
+)
+
+const (
+	STUNRequest    = 0x000
+	STUNIndication = 0x010
+	STUNSuccess    = 0x100
+	STUNError      = 0x110
+)
+
+const (
+	stunAttrMappedAddress     = 0x0001
+	stunAttrUsername          = 0x0006
+	stunAttrErrorCode         = 0x0009
+	stunAttrLifetime          = 0x000d
+	stunAttrXorPeerAddress    = 0x0012
+	stunAttrRealm             = 0x0014
+	stunAttrXorRelayedAddress = 0x0016
+	stunAttrXorMappedAddress  = 0x0020
+	stunAttrSoftware          = 0x8022
+)
+
+var STUNMethodNames = map[uint16]string{
+	STUNBinding:          "binding",
+	STUNAllocate:         "allocate",
+	STUNRefresh:          "refresh",
+	STUNSend:             "send",
+	STUNData:             "data",
+	STUNCreatePermission: "create permission",
+	STUNChannelBind:      "channel bind",
+}
+
+var STUNClassNames = map[uint16]string{
+	STUNRequest:    "request",
+	STUNIndication: "indication",
+	STUNSuccess:    "success",
+	STUNError:      "error",
+}
+
+var ErrSTUNInvalid = errors.New("not a valid STUN message")
+
+// STUNMessage is a STUN or TURN message with the attributes relevant to see
+// who is traversing the NAT and through which addresses.
+type STUNMessage struct {
+	Method        uint16 `json:"method"`
+	Class         uint16 `json:"class"`
+	TransactionID string `json:"transaction_id"`
+	// public address of the client as seen by the server
+	MappedAddress *net.UDPAddr `json:"mapped_address"`
+	// address allocated by a TURN server and the peer a client talks to
+	RelayedAddress *net.UDPAddr `json:"relayed_address"`
+	PeerAddress    *net.UDPAddr `json:"peer_address"`
+	Lifetime       uint32       `json:"lifetime"`
+	Username       string       `json:"username"`
+	Realm          string       `json:"realm"`
+	Software       string       `json:"software"`
+	ErrorCode      int          `json:"error_code"`
+	ErrorReason    string       `json:"error_reason"`
+}
+
+// MethodName returns the name of the method.
+func (m *STUNMessage) MethodName() string {
+	if name, found := STUNMethodNames[m.Method]; found {
+		return name
+	}
+	return fmt.Sprintf("method 0x%03x", m.Method)
+}
+
+// ClassName returns the name of the class.
+func (m *STUNMessage) ClassName() string {
+	return STUNClassNames[m.Class]
+}
+
+// IsTURN returns true for the methods only used by TURN.
+func (m *STUNMessage) IsTURN() bool {
+	return m.Method != STUNBinding
+}
+
+// the address attributes, the XOR ones are obfuscated with the magic cookie
+// and the transaction id
+func stunAddress(data []byte, xor []byte) *net.UDPAddr {
+	if len(data) < 8 {
+		return nil
+	}
+
+	size := 4
+	if data[1] == 0x02 {
+		size = 16
+	} else if data[1] != 0x01 {
+		return nil
+	}
+	if len(data) < 4+size {
+		return nil
+	}
+
+	port := binary.BigEndian.Uint16(data[2:])
+	ip := make(net.IP, size)
+	copy(ip, data[4:4+size])
+	if xor != nil {
+		port ^= uint16(stunMagicCookie >> 16)
+		for i := range ip {
+			ip[i] ^= xor[i]
+		}
+	}
+
+	return &net.UDPAddr{IP: ip, Port: int(port)}
+}
+
+// ParseSTUN parses a STUN message, checking its magic cookie.
+func ParseSTUN(payload []byte) (*STUNMessage, error) {
+	if len(payload) < stunHeaderSize || payload[0]&0xc0 != 0 || binary.BigEndian.Uint32(payload[4:]) != stunMagicCookie {
+		return nil, ErrSTUNInvalid
+	}
+
+	size := int(binary.BigEndian.Uint16(payload[2:]))
+	if size%4 != 0 || len(payload) != stunHeaderSize+size {
+		return nil, ErrSTUNInvalid
+	}
+
+	msgType := binary.BigEndian.Uint16(payload)
+	m := &STUNMessage{
+		Class:         msgType & 0x0110,
+		Method:        (msgType & 0x000f) | (msgType&0x00e0)>>1 | (msgType&0x3e00)>>2,
+		TransactionID: fmt.Sprintf("%x", payload[8:stunHeaderSize]),
+	}
+
+	// cookie and transaction id, to undo the XOR of the addresses
+	xor := payload[4:stunHeaderSize]
+	attrs := payload[stunHeaderSize:]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs)
+		attrSize := int(binary.BigEndian.Uint16(attrs[2:]))
+		if len(attrs) < 4+attrSize {
+			return nil, ErrSTUNInvalid
+		}
+		value := attrs[4 : 4+attrSize]
+
+		switch attrType {
+		case stunAttrMappedAddress:
+			if m.MappedAddress == nil {
+				m.MappedAddress = stunAddress(value, nil)
+			}
+		case stunAttrXorMappedAddress:
+			m.MappedAddress = stunAddress(value, xor)
+		case stunAttrXorRelayedAddress:
+			m.RelayedAddress = stunAddress(value, xor)
+		case stunAttrXorPeerAddress:
+			m.PeerAddress = stunAddress(value, xor)
+		case stunAttrLifetime:
+			if attrSize == 4 {
+				m.Lifetime = binary.BigEndian.Uint32(value)
+			}
+		case stunAttrUsername:
+			m.Username = string(value)
+		case stunAttrRealm:
+			m.Realm = string(value)
+		case stunAttrSoftware:
+			m.Software = strings.TrimRight(string(value), "\x00")
+		case stunAttrErrorCode:
+			if attrSize >= 4 {
+				m.ErrorCode = int(value[2]&0x07)*100 + int(value[3])
+				m.ErrorReason = string(value[4:])
+			}
+		}
+
+		// attributes are padded to 4 bytes
+		next := 4 + (attrSize+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+
+	return m, nil
+}
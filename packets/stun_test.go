@@ -0,0 +1,69 @@
+package packets
+
+import (
+	"testing"
+)
+
+// RFC 5769 2.2 and 2.3
+const (
+	stunTestResponse4 = "0101003c2112a442b7e7a701bc34d686fa87dfae" +
+		"8022000b7465737420766563746f7220" +
+		"002000080001a147e112a643" +
+		"000800142b91f599fd9e90c38c7489f92af9ba53f06be7d7" +
+		"80280004c07d4c96"
+	stunTestResponse6 = "010100482112a442b7e7a701bc34d686fa87dfae" +
+		"8022000b7465737420766563746f7220" +
+		"002000140002a1470113a9faa5d3f179bc25f4b5bed2b9d9" +
+		"00080014a382954e4be67bf11784c97c8292c275bfe3ed41" +
+		"80280004c8fb0b4c"
+)
+
+func TestParseSTUNBinding(t *testing.T) {
+	m, err := ParseSTUN(unhex(t, stunTestResponse4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if m.Method != STUNBinding || m.Class != STUNSuccess || m.MethodName() != "binding" || m.ClassName() != "success" {
+		t.Fatalf("unexpected message %+v", m)
+	} else if m.TransactionID != "b7e7a701bc34d686fa87dfae" || m.Software != "test vector" || m.IsTURN() {
+		t.Fatalf("unexpected message %+v", m)
+	} else if m.MappedAddress == nil || m.MappedAddress.String() != "192.0.2.1:32853" {
+		t.Fatalf("unexpected mapped address %v", m.MappedAddress)
+	}
+
+	if m, err = ParseSTUN(unhex(t, stunTestResponse6)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if m.MappedAddress == nil || m.MappedAddress.String() != "[2001:db8:1234:5678:11:2233:4455:6677]:32853" {
+		t.Fatalf("unexpected mapped address %v", m.MappedAddress)
+	}
+}
+
+func TestParseSTUNAllocate(t *testing.T) {
+	// allocate error response asking for credentials
+	payload := unhex(t, "011300142112a442000102030405060708090a0b"+
+		"0009001000000401556e617574686f72697a6564")
+	m, err := ParseSTUN(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if m.Method != STUNAllocate || m.Class != STUNError || !m.IsTURN() {
+		t.Fatalf("unexpected message %+v", m)
+	} else if m.ErrorCode != 401 || m.ErrorReason != "Unauthorized" {
+		t.Fatalf("unexpected error %d %s", m.ErrorCode, m.ErrorReason)
+	}
+}
+
+func TestParseSTUNInvalid(t *testing.T) {
+	valid := unhex(t, stunTestResponse4)
+	cookie := append([]byte{}, valid...)
+	cookie[4] = 0
+	for _, payload := range [][]byte{
+		valid[:19],
+		cookie,
+		// length not matching
+		valid[:len(valid)-4],
+		[]byte("GET / HTTP/1.1\r\n\r\n"),
+	} {
+		if _, err := ParseSTUN(payload); err != ErrSTUNInvalid {
+			t.Fatalf("expected %v for %x, got %v", ErrSTUNInvalid, payload, err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package packets
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const SyslogPort = 514
+
+var SyslogFacilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var SyslogSeverityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var ErrSyslogInvalid = errors.New("not a valid syslog message")
+
+// SyslogMessage is a RFC 3164 or RFC 5424 syslog message, the fields the
+// sender didn't fill are empty.
+type SyslogMessage struct {
+	Priority  int    `json:"priority"`
+	Facility  int    `json:"facility"`
+	Severity  int    `json:"severity"`
+	Version   int    `json:"version"`
+	Timestamp string `json:"timestamp"`
+	Hostname  string `json:"hostname"`
+	AppName   string `json:"app_name"`
+	ProcID    string `json:"proc_id"`
+	Message   string `json:"message"`
+}
+
+// FacilityName returns the name of the facility.
+func (m *SyslogMessage) FacilityName() string {
+	return SyslogFacilityNames[m.Facility]
+}
+
+// SeverityName returns the name of the severity.
+func (m *SyslogMessage) SeverityName() string {
+	return SyslogSeverityNames[m.Severity]
+}
+
+// RFC 5424 fields, with - meaning nil
+func syslogField(s string) (string, string) {
+	field, rest := s, ""
+	if idx := strings.IndexByte(s, ' '); idx >= 0 {
+		field, rest = s[:idx], s[idx+1:]
+	}
+	if field == "-" {
+		field = ""
+	}
+	return field, rest
+}
+
+// skips the structured data of a RFC 5424 message
+func syslogSkipData(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimPrefix(s[1:], " ")
+	}
+	for strings.HasPrefix(s, "[") {
+		escaped := false
+		end := -1
+		for i := 1; i < len(s) && end < 0; i++ {
+			switch {
+			case escaped:
+				escaped = false
+			case s[i] == '\\':
+				escaped = true
+			case s[i] == ']':
+				end = i
+			}
+		}
+		if end < 0 {
+			return ""
+		}
+		s = s[end+1:]
+	}
+	return strings.TrimPrefix(s, " ")
+}
+
+func (m *SyslogMessage) parse5424(s string) {
+	m.Timestamp, s = syslogField(s)
+	m.Hostname, s = syslogField(s)
+	m.AppName, s = syslogField(s)
+	m.ProcID, s = syslogField(s)
+	// message id
+	_, s = syslogField(s)
+	// UTF-8 messages start with a BOM
+	m.Message = strings.TrimPrefix(syslogSkipData(s), "\ufeff")
+}
+
+func (m *SyslogMessage) parse3164(s string) {
+	// Mmm dd hh:mm:ss, the hostname is optional
+	if len(s) >= 16 && s[15] == ' ' {
+		if _, err := time.Parse(time.Stamp, s[:15]); err == nil {
+			m.Timestamp = s[:15]
+			s = s[16:]
+			if idx := strings.IndexByte(s, ' '); idx > 0 && !strings.ContainsAny(s[:idx], ":[") {
+				m.Hostname, s = s[:idx], s[idx+1:]
+			}
+		}
+	}
+
+	// TAG[pid]: message
+	if idx := strings.IndexByte(s, ':'); idx > 0 && !strings.Contains(s[:idx], " ") {
+		tag := s[:idx]
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			m.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		m.AppName = tag
+		s = strings.TrimPrefix(s[idx+1:], " ")
+	}
+
+	m.Message = s
+}
+
+// ParseSyslog parses a syslog message in either the RFC 5424 or the BSD
+// RFC 3164 format.
+func ParseSyslog(payload []byte) (*SyslogMessage, error) {
+	s := strings.TrimRight(string(payload), "\x00\r\n")
+	end := strings.IndexByte(s, '>')
+	if len(s) < 3 || s[0] != '<' || end < 2 || end > 4 {
+		return nil, ErrSyslogInvalid
+	}
+
+	pri, err := strconv.Atoi(s[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return nil, ErrSyslogInvalid
+	}
+
+	m := &SyslogMessage{
+		Priority: pri,
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+
+	s = s[end+1:]
+	if len(s) > 2 && s[0] >= '1' && s[0] <= '9' && s[1] == ' ' {
+		m.Version = int(s[0] - '0')
+		m.parse5424(s[2:])
+	} else {
+		m.parse3164(s)
+	}
+
+	return m, nil
+}
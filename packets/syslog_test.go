@@ -0,0 +1,59 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestParseSyslog3164(t *testing.T) {
+	m, err := ParseSyslog([]byte("<34>Oct 11 22:14:15 mymachine su[1234]: 'su root' failed for lonvick on /dev/pts/8\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if m.Facility != 4 || m.Severity != 2 || m.FacilityName() != "auth" || m.SeverityName() != "crit" {
+		t.Fatalf("unexpected priority %+v", m)
+	} else if m.Timestamp != "Oct 11 22:14:15" || m.Hostname != "mymachine" || m.AppName != "su" || m.ProcID != "1234" {
+		t.Fatalf("unexpected header %+v", m)
+	} else if m.Message != "'su root' failed for lonvick on /dev/pts/8" {
+		t.Fatalf("unexpected message '%s'", m.Message)
+	}
+
+	// no header at all, as sent by many embedded devices
+	if m, err = ParseSyslog([]byte("<13>link up on port 3")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if m.Hostname != "" || m.Message != "link up on port 3" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+}
+
+func TestParseSyslog5424(t *testing.T) {
+	m, err := ParseSyslog([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application \]"] An application event`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if m.Version != 1 || m.FacilityName() != "local4" || m.SeverityName() != "notice" {
+		t.Fatalf("unexpected priority %+v", m)
+	} else if m.Timestamp != "2003-10-11T22:14:15.003Z" || m.Hostname != "mymachine.example.com" || m.AppName != "evntslog" || m.ProcID != "" {
+		t.Fatalf("unexpected header %+v", m)
+	} else if m.Message != "An application event" {
+		t.Fatalf("unexpected message '%s'", m.Message)
+	}
+
+	if m, err = ParseSyslog([]byte("<14>1 - router - - - - hello")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if m.Timestamp != "" || m.Hostname != "router" || m.Message != "hello" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+}
+
+func TestParseSyslogInvalid(t *testing.T) {
+	for _, payload := range []string{
+		"",
+		"hello",
+		"<>hello",
+		"<192>hello",
+		"<abc>hello",
+		"GET / HTTP/1.1\r\n\r\n",
+	} {
+		if _, err := ParseSyslog([]byte(payload)); err != ErrSyslogInvalid {
+			t.Fatalf("expected %v for '%s', got %v", ErrSyslogInvalid, payload, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+)
+
+const (
+	TDSPort = 1433
+
+	tdsHeaderSize = 8
+	tdsLogin7     = 0x10
+	// fixed part of the login, before the offsets of the variable fields
+	tdsLogin7FixedSize = 36
+	// offsets and lengths up to the attached database file, TDS 7.0
+	tdsLogin7MinSize = tdsLogin7FixedSize + 50
+)
+
+var ErrTDSInvalid = errors.New("not a valid TDS login")
+
+// TDSLogin is the LOGIN7 message of a MSSQL client, the password is only
+// obfuscated so it can be recovered.
+type TDSLogin struct {
+	Version     uint32 `json:"version"`
+	Hostname    string `json:"hostname"`
+	User        string `json:"user"`
+	Password    string `json:"password"`
+	Application string `json:"application"`
+	Server      string `json:"server"`
+	Library     string `json:"library"`
+	Database    string `json:"database"`
+	// integrated authentication, the NTLM or Kerberos token is in here
+	SSPI bool `json:"sspi"`
+}
+
+// every byte is xored with 0xa5 after swapping its nibbles
+func tdsDeobfuscate(raw []byte) []byte {
+	plain := make([]byte, len(raw))
+	for i, b := range raw {
+		b ^= 0xa5
+		plain[i] = b<<4 | b>>4
+	}
+	return plain
+}
+
+func tdsUCS2(raw []byte) string {
+	chars := make([]uint16, len(raw)/2)
+	for i := range chars {
+		chars[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+	return string(utf16.Decode(chars))
+}
+
+// the UCS-2 string at the offset and with the number of characters stored
+// at the given position of the login
+func tdsField(login []byte, at int, password bool) (string, error) {
+	offset := int(binary.LittleEndian.Uint16(login[at:]))
+	size := int(binary.LittleEndian.Uint16(login[at+2:])) * 2
+	if size == 0 {
+		return "", nil
+	} else if offset+size > len(login) {
+		return "", ErrTDSInvalid
+	}
+
+	raw := login[offset : offset+size]
+	if password {
+		raw = tdsDeobfuscate(raw)
+	}
+	return tdsUCS2(raw), nil
+}
+
+// ParseTDSLogin parses an unencrypted LOGIN7 message.
+func ParseTDSLogin(data []byte) (*TDSLogin, error) {
+	if len(data) < tdsHeaderSize+tdsLogin7MinSize || data[0] != tdsLogin7 {
+		return nil, ErrTDSInvalid
+	}
+
+	size := int(binary.BigEndian.Uint16(data[2:]))
+	// only logins in a single packet
+	if size < tdsHeaderSize+tdsLogin7MinSize || size > len(data) {
+		return nil, ErrTDSInvalid
+	}
+
+	login := data[tdsHeaderSize:size]
+	if int(binary.LittleEndian.Uint32(login)) != len(login) {
+		return nil, ErrTDSInvalid
+	}
+
+	parsed := &TDSLogin{
+		Version: binary.LittleEndian.Uint32(login[4:]),
+		// integrated security flag of OptionFlags2
+		SSPI: login[25]&0x80 != 0,
+	}
+
+	fields := []struct {
+		at       int
+		value    *string
+		password bool
+	}{
+		{36, &parsed.Hostname, false},
+		{40, &parsed.User, false},
+		{44, &parsed.Password, true},
+		{48, &parsed.Application, false},
+		{52, &parsed.Server, false},
+		{60, &parsed.Library, false},
+		{68, &parsed.Database, false},
+	}
+
+	var err error
+	for _, f := range fields {
+		if *f.value, err = tdsField(login, f.at, f.password); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,39 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestParseTDSLogin(t *testing.T) {
+	payload := unhex(t, "100100ac00000100a4000000040000740010000007000000d204000000000000e003000088ffffff"+
+		"090400005e000500680002006c0008007c0006008800040090000000900004009800000098000600"+
+		"000000000000a4000000a4000000a40000000000000057004b0053003000310073006100a0a5a1a5"+
+		"92a592a5d2a5a6a582a5e3a5730071006c0063006d00640064006200300031004f00440042004300"+
+		"6d0061007300740065007200")
+
+	login, err := ParseTDSLogin(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := TDSLogin{
+		Version:     0x74000004,
+		Hostname:    "WKS01",
+		User:        "sa",
+		Password:    "P@ssw0rd",
+		Application: "sqlcmd",
+		Server:      "db01",
+		Library:     "ODBC",
+		Database:    "master",
+	}
+	if *login != expected {
+		t.Fatalf("expected %+v, got %+v", expected, *login)
+	}
+
+	// prelogin
+	if _, err := ParseTDSLogin(append([]byte{0x12}, payload[1:]...)); err != ErrTDSInvalid {
+		t.Fatalf("expected %v, got %v", ErrTDSInvalid, err)
+	} else if _, err := ParseTDSLogin(payload[:100]); err != ErrTDSInvalid {
+		t.Fatalf("expected %v, got %v", ErrTDSInvalid, err)
+	}
+}
@@ -0,0 +1,137 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	TFTPPort = 69
+	// default size of the data blocks, can be negotiated with the blksize option
+	TFTPBlockSize = 512
+
+	tftpMaxBlockSize = 65464
+)
+
+const (
+	TFTPReadRequest  = 1
+	TFTPWriteRequest = 2
+	TFTPData         = 3
+	TFTPAck          = 4
+	TFTPError        = 5
+	TFTPOptionAck    = 6
+)
+
+var TFTPOpcodeNames = map[uint16]string{
+	TFTPReadRequest:  "RRQ",
+	TFTPWriteRequest: "WRQ",
+	TFTPData:         "DATA",
+	TFTPAck:          "ACK",
+	TFTPError:        "ERROR",
+	TFTPOptionAck:    "OACK",
+}
+
+var ErrTFTPInvalid = errors.New("not a valid TFTP packet")
+
+// TFTPPacket is a TFTP request, data block, acknowledgment or error.
+type TFTPPacket struct {
+	Opcode   uint16 `json:"opcode"`
+	Filename string `json:"filename"`
+	Mode     string `json:"mode"`
+	// options of requests and option acknowledgments, lowercase
+	Options map[string]string `json:"options"`
+	Block   uint16            `json:"block"`
+	Data    []byte            `json:"-"`
+	// error code and message
+	ErrorCode uint16 `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// IsRequest returns true for read and write requests.
+func (p *TFTPPacket) IsRequest() bool {
+	return p.Opcode == TFTPReadRequest || p.Opcode == TFTPWriteRequest
+}
+
+// BlockSize returns the size of the data blocks negotiated by the options of
+// a request or of an option acknowledgment.
+func (p *TFTPPacket) BlockSize() int {
+	if value, found := p.Options["blksize"]; found {
+		if size, err := strconv.Atoi(value); err == nil && size >= 8 && size <= tftpMaxBlockSize {
+			return size
+		}
+	}
+	return TFTPBlockSize
+}
+
+// the zero terminated strings of requests and option acknowledgments
+func tftpStrings(data []byte) ([]string, error) {
+	if len(data) == 0 || data[len(data)-1] != 0 {
+		return nil, ErrTFTPInvalid
+	}
+	return strings.Split(string(data[:len(data)-1]), "\x00"), nil
+}
+
+func (p *TFTPPacket) parseOptions(fields []string) error {
+	if len(fields)%2 != 0 {
+		return ErrTFTPInvalid
+	}
+	p.Options = make(map[string]string)
+	for i := 0; i < len(fields); i += 2 {
+		p.Options[strings.ToLower(fields[i])] = fields[i+1]
+	}
+	return nil
+}
+
+// ParseTFTP parses a TFTP packet.
+func ParseTFTP(payload []byte) (*TFTPPacket, error) {
+	if len(payload) < 4 {
+		return nil, ErrTFTPInvalid
+	}
+
+	p := &TFTPPacket{Opcode: binary.BigEndian.Uint16(payload)}
+	data := payload[2:]
+
+	switch p.Opcode {
+	case TFTPReadRequest, TFTPWriteRequest:
+		fields, err := tftpStrings(data)
+		if err != nil || len(fields) < 2 || fields[0] == "" {
+			return nil, ErrTFTPInvalid
+		}
+		p.Filename = fields[0]
+		p.Mode = strings.ToLower(fields[1])
+		if p.Mode != "netascii" && p.Mode != "octet" && p.Mode != "mail" {
+			return nil, ErrTFTPInvalid
+		}
+		if err = p.parseOptions(fields[2:]); err != nil {
+			return nil, err
+		}
+	case TFTPData:
+		p.Block = binary.BigEndian.Uint16(data)
+		p.Data = data[2:]
+		if len(p.Data) > tftpMaxBlockSize {
+			return nil, ErrTFTPInvalid
+		}
+	case TFTPAck:
+		if len(data) != 2 {
+			return nil, ErrTFTPInvalid
+		}
+		p.Block = binary.BigEndian.Uint16(data)
+	case TFTPError:
+		p.ErrorCode = binary.BigEndian.Uint16(data)
+		p.Message = string(bytes.TrimRight(data[2:], "\x00"))
+	case TFTPOptionAck:
+		fields, err := tftpStrings(data)
+		if err != nil {
+			return nil, err
+		} else if err = p.parseOptions(fields); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrTFTPInvalid
+	}
+
+	return p, nil
+}
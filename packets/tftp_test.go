@@ -0,0 +1,72 @@
+package packets
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseTFTPRequest(t *testing.T) {
+	payload := append([]byte{0x00, 0x01}, []byte("pxelinux.0\x00octet\x00blksize\x001468\x00tsize\x000\x00")...)
+	p, err := ParseTFTP(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !p.IsRequest() || p.Opcode != TFTPReadRequest || p.Filename != "pxelinux.0" || p.Mode != "octet" {
+		t.Fatalf("unexpected packet %+v", p)
+	} else if p.BlockSize() != 1468 || p.Options["tsize"] != "0" {
+		t.Fatalf("unexpected options %v", p.Options)
+	}
+
+	payload = append([]byte{0x00, 0x02}, []byte("running-config\x00NETASCII\x00")...)
+	if p, err = ParseTFTP(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.Opcode != TFTPWriteRequest || p.Mode != "netascii" || p.BlockSize() != TFTPBlockSize {
+		t.Fatalf("unexpected packet %+v", p)
+	}
+}
+
+func TestParseTFTPTransfer(t *testing.T) {
+	data := append([]byte{0x00, 0x03, 0x00, 0x02}, []byte("hello")...)
+	p, err := ParseTFTP(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.Opcode != TFTPData || p.Block != 2 || !bytes.Equal(p.Data, []byte("hello")) {
+		t.Fatalf("unexpected packet %+v", p)
+	}
+
+	if p, err = ParseTFTP([]byte{0x00, 0x04, 0x00, 0x02}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.Opcode != TFTPAck || p.Block != 2 {
+		t.Fatalf("unexpected packet %+v", p)
+	}
+
+	if p, err = ParseTFTP(append([]byte{0x00, 0x05, 0x00, 0x01}, []byte("File not found\x00")...)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.ErrorCode != 1 || p.Message != "File not found" {
+		t.Fatalf("unexpected packet %+v", p)
+	}
+
+	if p, err = ParseTFTP(append([]byte{0x00, 0x06}, []byte("blksize\x001024\x00")...)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.BlockSize() != 1024 {
+		t.Fatalf("unexpected options %v", p.Options)
+	}
+}
+
+func TestParseTFTPInvalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		{0x00, 0x01},
+		// unknown opcode
+		{0x00, 0x09, 0x00, 0x00},
+		// not terminated
+		append([]byte{0x00, 0x01}, []byte("file\x00octet")...),
+		// unknown mode
+		append([]byte{0x00, 0x01}, []byte("file\x00binary\x00")...),
+		// option without value
+		append([]byte{0x00, 0x01}, []byte("file\x00octet\x00blksize\x00")...),
+		[]byte("GET / HTTP/1.1\r\n\r\n"),
+	} {
+		if _, err := ParseTFTP(payload); err != ErrTFTPInvalid {
+			t.Fatalf("expected %v for %x, got %v", ErrTFTPInvalid, payload, err)
+		}
+	}
+}
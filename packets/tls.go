@@ -0,0 +1,353 @@
+package packets
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	tlsRecordHandshake = 0x16
+	tlsClientHello     = 0x01
+	tlsServerHello     = 0x02
+
+	tlsExtServerName        = 0x0000
+	tlsExtSupportedGroups   = 0x000a
+	tlsExtECPointFormats    = 0x000b
+	tlsExtALPN              = 0x0010
+	tlsExtSupportedVersions = 0x002b
+	tlsServerNameHostName   = 0x00
+
+	tlsRecordHeaderSize    = 5
+	tlsHandshakeHeaderSize = 4
+	tlsMaxRecordSize       = 1 << 14
+)
+
+var TLSVersionNames = map[uint16]string{
+	0x0300: "SSL 3.0",
+	0x0301: "TLS 1.0",
+	0x0302: "TLS 1.1",
+	0x0303: "TLS 1.2",
+	0x0304: "TLS 1.3",
+}
+
+var ErrTLSInvalid = errors.New("not a valid TLS hello")
+
+// TLSClientHello has the fields of a ClientHello needed to fingerprint the
+// client and to know where it's connecting to.
+type TLSClientHello struct {
+	Version           uint16   `json:"version"`
+	CipherSuites      []uint16 `json:"cipher_suites"`
+	Extensions        []uint16 `json:"extensions"`
+	Curves            []uint16 `json:"curves"`
+	PointFormats      []uint8  `json:"point_formats"`
+	SupportedVersions []uint16 `json:"supported_versions"`
+	SNI               string   `json:"sni"`
+	ALPN              []string `json:"alpn"`
+}
+
+// TLSServerHello has the fields of a ServerHello needed to fingerprint the
+// server and the parameters it picked.
+type TLSServerHello struct {
+	Version          uint16   `json:"version"`
+	CipherSuite      uint16   `json:"cipher_suite"`
+	Extensions       []uint16 `json:"extensions"`
+	SupportedVersion uint16   `json:"supported_version"`
+	ALPN             string   `json:"alpn"`
+}
+
+// GREASE values (RFC 8701) are random and excluded from the fingerprints
+func tlsIsGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func tlsJoin(values []uint16) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		if !tlsIsGREASE(v) {
+			parts = append(parts, strconv.Itoa(int(v)))
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func tlsHash(s string) string {
+	h := md5.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// TLSVersionName returns the name of a protocol version.
+func TLSVersionName(v uint16) string {
+	if name, found := TLSVersionNames[v]; found {
+		return name
+	} else if tlsIsGREASE(v) {
+		return "GREASE"
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// NegotiableVersion returns the highest version the client supports.
+func (h *TLSClientHello) NegotiableVersion() uint16 {
+	max := h.Version
+	for _, v := range h.SupportedVersions {
+		if !tlsIsGREASE(v) && v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// JA3String returns the fields of the JA3 fingerprint of the client.
+func (h *TLSClientHello) JA3String() string {
+	formats := make([]uint16, len(h.PointFormats))
+	for i, f := range h.PointFormats {
+		formats[i] = uint16(f)
+	}
+	return fmt.Sprintf("%d,%s,%s,%s,%s",
+		h.Version,
+		tlsJoin(h.CipherSuites),
+		tlsJoin(h.Extensions),
+		tlsJoin(h.Curves),
+		tlsJoin(formats))
+}
+
+// JA3 returns the JA3 fingerprint of the client.
+func (h *TLSClientHello) JA3() string {
+	return tlsHash(h.JA3String())
+}
+
+// NegotiatedVersion returns the version that was actually negotiated.
+func (h *TLSServerHello) NegotiatedVersion() uint16 {
+	if h.SupportedVersion != 0 {
+		return h.SupportedVersion
+	}
+	return h.Version
+}
+
+// JA3SString returns the fields of the JA3S fingerprint of the server.
+func (h *TLSServerHello) JA3SString() string {
+	return fmt.Sprintf("%d,%d,%s", h.Version, h.CipherSuite, tlsJoin(h.Extensions))
+}
+
+// JA3S returns the JA3S fingerprint of the server.
+func (h *TLSServerHello) JA3S() string {
+	return tlsHash(h.JA3SString())
+}
+
+// a cursor over the fields of a handshake message
+type tlsReader struct {
+	data []byte
+	err  error
+}
+
+func (r *tlsReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || len(r.data) < n {
+		r.err = ErrTLSInvalid
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *tlsReader) u8() int {
+	if b := r.bytes(1); b != nil {
+		return int(b[0])
+	}
+	return 0
+}
+
+func (r *tlsReader) u16() int {
+	if b := r.bytes(2); b != nil {
+		return int(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *tlsReader) u24() int {
+	if b := r.bytes(3); b != nil {
+		return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+	}
+	return 0
+}
+
+// a list of 16 bits values with a length of the given size
+func (r *tlsReader) list16(sizeLen int) []uint16 {
+	size := 0
+	if sizeLen == 1 {
+		size = r.u8()
+	} else {
+		size = r.u16()
+	}
+	raw := r.bytes(size)
+	if r.err != nil || size%2 != 0 {
+		r.err = ErrTLSInvalid
+		return nil
+	}
+
+	values := make([]uint16, size/2)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(raw[i*2:])
+	}
+	return values
+}
+
+// the handshake message of the given type from a TLS record
+func tlsHandshake(data []byte, kind byte) ([]byte, error) {
+	if len(data) < tlsRecordHeaderSize+tlsHandshakeHeaderSize || data[0] != tlsRecordHandshake || data[1] != 0x03 {
+		return nil, ErrTLSInvalid
+	}
+
+	size := int(binary.BigEndian.Uint16(data[3:]))
+	if size < tlsHandshakeHeaderSize || size > tlsMaxRecordSize || len(data) < tlsRecordHeaderSize+size {
+		return nil, ErrTLSInvalid
+	}
+
+	msg := data[tlsRecordHeaderSize : tlsRecordHeaderSize+size]
+	if msg[0] != kind {
+		return nil, ErrTLSInvalid
+	}
+	return msg, nil
+}
+
+// the body of the handshake message of the given type
+func tlsBody(msg []byte, kind byte) (*tlsReader, error) {
+	r := &tlsReader{data: msg}
+	if byte(r.u8()) != kind {
+		return nil, ErrTLSInvalid
+	}
+	// the message can be fragmented in more records, we only support one
+	body := r.bytes(r.u24())
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &tlsReader{data: body}, nil
+}
+
+func (h *TLSClientHello) parseExtension(kind uint16, ext *tlsReader) {
+	switch kind {
+	case tlsExtServerName:
+		names := &tlsReader{data: ext.bytes(ext.u16())}
+		for names.err == nil && len(names.data) > 0 {
+			nameType := names.u8()
+			name := names.bytes(names.u16())
+			if names.err == nil && nameType == tlsServerNameHostName {
+				h.SNI = string(name)
+			}
+		}
+	case tlsExtSupportedGroups:
+		h.Curves = ext.list16(2)
+	case tlsExtECPointFormats:
+		h.PointFormats = append([]uint8{}, ext.bytes(ext.u8())...)
+	case tlsExtALPN:
+		protos := &tlsReader{data: ext.bytes(ext.u16())}
+		for protos.err == nil && len(protos.data) > 0 {
+			if proto := protos.bytes(protos.u8()); protos.err == nil {
+				h.ALPN = append(h.ALPN, string(proto))
+			}
+		}
+	case tlsExtSupportedVersions:
+		h.SupportedVersions = ext.list16(1)
+	}
+}
+
+// ParseTLSClientHelloMessage parses a ClientHello handshake message without
+// the record layer, as found in the CRYPTO frames of QUIC.
+func ParseTLSClientHelloMessage(msg []byte) (*TLSClientHello, error) {
+	r, err := tlsBody(msg, tlsClientHello)
+	if err != nil {
+		return nil, err
+	}
+
+	hello := &TLSClientHello{}
+	hello.Version = uint16(r.u16())
+	r.bytes(32)
+	r.bytes(r.u8())
+	hello.CipherSuites = r.list16(2)
+	r.bytes(r.u8())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// no extensions at all
+	if len(r.data) == 0 {
+		return hello, nil
+	}
+
+	exts := &tlsReader{data: r.bytes(r.u16())}
+	for exts.err == nil && len(exts.data) > 0 {
+		kind := uint16(exts.u16())
+		data := exts.bytes(exts.u16())
+		if exts.err == nil {
+			hello.Extensions = append(hello.Extensions, kind)
+			hello.parseExtension(kind, &tlsReader{data: data})
+		}
+	}
+
+	if r.err != nil || exts.err != nil {
+		return nil, ErrTLSInvalid
+	}
+	return hello, nil
+}
+
+// ParseTLSClientHello parses the ClientHello in a TLS record.
+func ParseTLSClientHello(data []byte) (*TLSClientHello, error) {
+	msg, err := tlsHandshake(data, tlsClientHello)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTLSClientHelloMessage(msg)
+}
+
+// ParseTLSServerHello parses the ServerHello in a TLS record.
+func ParseTLSServerHello(data []byte) (*TLSServerHello, error) {
+	msg, err := tlsHandshake(data, tlsServerHello)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := tlsBody(msg, tlsServerHello)
+	if err != nil {
+		return nil, err
+	}
+
+	hello := &TLSServerHello{}
+	hello.Version = uint16(r.u16())
+	r.bytes(32)
+	r.bytes(r.u8())
+	hello.CipherSuite = uint16(r.u16())
+	// compression
+	r.u8()
+	if r.err != nil {
+		return nil, r.err
+	} else if len(r.data) == 0 {
+		return hello, nil
+	}
+
+	exts := &tlsReader{data: r.bytes(r.u16())}
+	for exts.err == nil && len(exts.data) > 0 {
+		kind := uint16(exts.u16())
+		data := &tlsReader{data: exts.bytes(exts.u16())}
+		if exts.err != nil {
+			break
+		}
+
+		hello.Extensions = append(hello.Extensions, kind)
+		switch kind {
+		case tlsExtSupportedVersions:
+			hello.SupportedVersion = uint16(data.u16())
+		case tlsExtALPN:
+			protos := &tlsReader{data: data.bytes(data.u16())}
+			hello.ALPN = string(protos.bytes(protos.u8()))
+		}
+	}
+
+	if r.err != nil || exts.err != nil {
+		return nil, ErrTLSInvalid
+	}
+	return hello, nil
+}
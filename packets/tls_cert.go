@@ -0,0 +1,191 @@
+package packets
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"time"
+)
+
+const (
+	tlsRecordChangeCipherSpec = 0x14
+	tlsRecordAlert            = 0x15
+	tlsRecordApplicationData  = 0x17
+	tlsCertificate            = 0x0b
+	tlsServerHelloDone        = 0x0e
+
+	// handshake data buffered while waiting for a message to be complete,
+	// certificate chains are usually way smaller than this
+	tlsMaxHandshakeBuffer = 256 * 1024
+)
+
+var ErrTLSHandshakeTooBig = errors.New("TLS handshake message too big")
+
+// TLSCertificate has the fields of a X.509 certificate seen in a handshake.
+type TLSCertificate struct {
+	CommonName string    `json:"common_name"`
+	Subject    string    `json:"subject"`
+	Issuer     string    `json:"issuer"`
+	Names      []string  `json:"names"`
+	Serial     string    `json:"serial"`
+	SHA1       string    `json:"sha1"`
+	SHA256     string    `json:"sha256"`
+	NotBefore  time.Time `json:"not_before"`
+	NotAfter   time.Time `json:"not_after"`
+	SelfSigned bool      `json:"self_signed"`
+	Raw        []byte    `json:"-"`
+}
+
+// NewTLSCertificate returns the fields of a parsed certificate.
+func NewTLSCertificate(cert *x509.Certificate) *TLSCertificate {
+	sum1 := sha1.Sum(cert.Raw)
+	sum256 := sha256.Sum256(cert.Raw)
+
+	c := &TLSCertificate{
+		CommonName: cert.Subject.CommonName,
+		Subject:    cert.Subject.String(),
+		Issuer:     cert.Issuer.String(),
+		Names:      append([]string{}, cert.DNSNames...),
+		SHA1:       hex.EncodeToString(sum1[:]),
+		SHA256:     hex.EncodeToString(sum256[:]),
+		NotBefore:  cert.NotBefore,
+		NotAfter:   cert.NotAfter,
+		Raw:        cert.Raw,
+	}
+
+	if cert.SerialNumber != nil {
+		c.Serial = cert.SerialNumber.Text(16)
+	}
+	for _, ip := range cert.IPAddresses {
+		c.Names = append(c.Names, ip.String())
+	}
+	for _, email := range cert.EmailAddresses {
+		c.Names = append(c.Names, email)
+	}
+
+	// the issuer is the subject and the certificate is signed by its own key
+	if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		c.SelfSigned = cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+	}
+
+	return c
+}
+
+// Expired returns true if the certificate is not valid anymore at the given time.
+func (c *TLSCertificate) Expired(at time.Time) bool {
+	return at.After(c.NotAfter)
+}
+
+// PEM returns the certificate PEM encoded.
+func (c *TLSCertificate) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: c.Raw,
+	})
+}
+
+// TLSHandshakeStream collects the handshake messages sent in a direction of
+// a connection, as records can be split across segments and messages across
+// records.
+type TLSHandshakeStream struct {
+	records   []byte
+	handshake []byte
+	// Closed is set once the handshake messages are encrypted
+	Closed bool
+}
+
+// Feed adds the data of the connection and returns the handshake messages
+// completed by it, with their headers.
+func (s *TLSHandshakeStream) Feed(data []byte) ([][]byte, error) {
+	if s.Closed {
+		return nil, nil
+	}
+
+	s.records = append(s.records, data...)
+	for len(s.records) >= tlsRecordHeaderSize {
+		if s.records[1] != 0x03 {
+			return nil, ErrTLSInvalid
+		}
+
+		size := int(binary.BigEndian.Uint16(s.records[3:]))
+		// encrypted records can be a bit bigger than plain ones
+		if size > tlsMaxRecordSize+2048 {
+			return nil, ErrTLSInvalid
+		} else if len(s.records) < tlsRecordHeaderSize+size {
+			break
+		}
+
+		if kind := s.records[0]; kind == tlsRecordHandshake {
+			s.handshake = append(s.handshake, s.records[tlsRecordHeaderSize:tlsRecordHeaderSize+size]...)
+		} else if kind == tlsRecordChangeCipherSpec || kind == tlsRecordAlert || kind == tlsRecordApplicationData {
+			s.Closed = true
+			break
+		} else {
+			return nil, ErrTLSInvalid
+		}
+		s.records = s.records[tlsRecordHeaderSize+size:]
+	}
+
+	messages := [][]byte{}
+	for len(s.handshake) >= tlsHandshakeHeaderSize {
+		size := int(s.handshake[1])<<16 | int(s.handshake[2])<<8 | int(s.handshake[3])
+		if len(s.handshake) < tlsHandshakeHeaderSize+size {
+			break
+		}
+		messages = append(messages, s.handshake[:tlsHandshakeHeaderSize+size])
+		s.handshake = s.handshake[tlsHandshakeHeaderSize+size:]
+	}
+
+	if len(s.records)+len(s.handshake) > tlsMaxHandshakeBuffer {
+		return messages, ErrTLSHandshakeTooBig
+	}
+	return messages, nil
+}
+
+// IsTLSCertificateMessage returns true if the handshake message is a Certificate one.
+func IsTLSCertificateMessage(msg []byte) bool {
+	return len(msg) > 0 && msg[0] == tlsCertificate
+}
+
+// IsTLSServerHelloDone returns true if the handshake message is the last one
+// sent by the server before the key exchange.
+func IsTLSServerHelloDone(msg []byte) bool {
+	return len(msg) > 0 && msg[0] == tlsServerHelloDone
+}
+
+// ParseTLSCertificates parses the chain of a Certificate handshake message
+// (up to TLS 1.2, they're encrypted in TLS 1.3), the leaf comes first.
+func ParseTLSCertificates(msg []byte) ([]*x509.Certificate, error) {
+	r, err := tlsBody(msg, tlsCertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &tlsReader{data: r.bytes(r.u24())}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	certs := []*x509.Certificate{}
+	for list.err == nil && len(list.data) > 0 {
+		der := list.bytes(list.u24())
+		if list.err != nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if list.err != nil || len(certs) == 0 {
+		return nil, ErrTLSInvalid
+	}
+	return certs, nil
+}
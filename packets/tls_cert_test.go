@@ -0,0 +1,138 @@
+package packets
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func tlsTestCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0x1337),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com", "example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("192.168.1.1")},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func tlsTestU24(n int) []byte {
+	return []byte{byte(n >> 16), byte(n >> 8), byte(n)}
+}
+
+// a Certificate message split in two records and a ServerHelloDone
+func tlsTestCertificateRecords(der []byte) []byte {
+	list := append(tlsTestU24(len(der)), der...)
+	body := append(tlsTestU24(len(list)), list...)
+	msg := append(append([]byte{tlsCertificate}, tlsTestU24(len(body))...), body...)
+	msg = append(msg, tlsServerHelloDone, 0, 0, 0)
+
+	records := []byte{}
+	for _, fragment := range [][]byte{msg[:100], msg[100:]} {
+		records = append(records, tlsRecordHandshake, 0x03, 0x03, byte(len(fragment)>>8), byte(len(fragment)))
+		records = append(records, fragment...)
+	}
+	return records
+}
+
+func TestTLSHandshakeStream(t *testing.T) {
+	der := tlsTestCertificate(t, time.Now().Add(time.Hour))
+	data := tlsTestCertificateRecords(der)
+
+	stream := &TLSHandshakeStream{}
+	messages := [][]byte{}
+	// as it comes segment by segment
+	for len(data) > 0 {
+		n := 37
+		if n > len(data) {
+			n = len(data)
+		}
+		msgs, err := stream.Feed(data[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msgs...)
+		data = data[n:]
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	} else if !IsTLSCertificateMessage(messages[0]) {
+		t.Fatalf("expected a certificate message")
+	} else if !IsTLSServerHelloDone(messages[1]) {
+		t.Fatalf("expected a server hello done message")
+	}
+
+	certs, err := ParseTLSCertificates(messages[0])
+	if err != nil {
+		t.Fatal(err)
+	} else if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(certs))
+	} else if !reflect.DeepEqual(certs[0].Raw, der) {
+		t.Fatalf("unexpected certificate")
+	}
+
+	// the rest is encrypted
+	if msgs, err := stream.Feed([]byte{tlsRecordChangeCipherSpec, 0x03, 0x03, 0x00, 0x01, 0x01}); err != nil {
+		t.Fatal(err)
+	} else if len(msgs) != 0 || !stream.Closed {
+		t.Fatalf("expected the stream to be closed")
+	}
+}
+
+func TestTLSHandshakeStreamInvalid(t *testing.T) {
+	stream := &TLSHandshakeStream{}
+	if _, err := stream.Feed([]byte("GET / HTTP/1.1\r\n")); err != ErrTLSInvalid {
+		t.Fatalf("expected ErrTLSInvalid, got %v", err)
+	}
+
+	if _, err := ParseTLSCertificates([]byte{tlsCertificate, 0, 0, 3, 0, 0, 0}); err != ErrTLSInvalid {
+		t.Fatalf("expected ErrTLSInvalid for an empty chain, got %v", err)
+	}
+}
+
+func TestNewTLSCertificate(t *testing.T) {
+	notAfter := time.Now().Add(-time.Hour).Truncate(time.Second)
+	cert, err := x509.ParseCertificate(tlsTestCertificate(t, notAfter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewTLSCertificate(cert)
+	if c.CommonName != "www.example.com" {
+		t.Fatalf("unexpected common name %s", c.CommonName)
+	} else if !reflect.DeepEqual(c.Names, []string{"www.example.com", "example.com", "192.168.1.1"}) {
+		t.Fatalf("unexpected names %v", c.Names)
+	} else if c.Serial != "1337" {
+		t.Fatalf("unexpected serial %s", c.Serial)
+	} else if len(c.SHA1) != 40 || len(c.SHA256) != 64 {
+		t.Fatalf("unexpected fingerprints %s %s", c.SHA1, c.SHA256)
+	} else if !c.SelfSigned {
+		t.Fatalf("expected the certificate to be self signed")
+	} else if !c.Expired(time.Now()) || c.Expired(notAfter.Add(-time.Minute)) {
+		t.Fatalf("unexpected expiration")
+	} else if !c.NotAfter.Equal(notAfter) {
+		t.Fatalf("unexpected expiry %s", c.NotAfter)
+	} else if len(c.PEM()) == 0 {
+		t.Fatalf("expected a PEM encoded certificate")
+	}
+}
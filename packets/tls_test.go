@@ -0,0 +1,94 @@
+package packets
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ClientHello of OpenSSL 3 for www.example.com with h2 and http/1.1
+func tlsTestClientHello(t *testing.T) []byte {
+	return unhex(t, ""+
+		"1603010200010001fc0303a0d9da5ceddea7d0c1952d1116ad4897b279251b67d10fdf272f8e0c32fff82c20ecb39af4"+
+		"d91a2c8f34155a000a7e346d5d0ff3e397b44f0f8d237e4289c2a7f30024130213031301c02cc030c02bc02fcca9cca8"+
+		"c024c028c023c027009f009e006b006700ff0100018f00000014001200000f7777772e6578616d706c652e636f6d000b"+
+		"000403000102000a00160014001d0017001e0019001801000101010201030104002300000010000e000c026832086874"+
+		"74702f312e310016000000170000000d002a0028040305030603080708080809080a080b080408050806040105010601"+
+		"030303010302040205020602002b00050403040303002d00020101003300260024001d00208d23e768fffdd7b6d2ef13"+
+		"4a442035a79989613c6d6f9a2ca38c01196b000d3c001500cc0000000000000000000000000000000000000000000000"+
+		"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"+
+		"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"+
+		"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"+
+		"00000000000000000000000000000000000000000000000000000000000000000000000000")
+}
+
+func TestParseTLSClientHello(t *testing.T) {
+	hello, err := ParseTLSClientHello(tlsTestClientHello(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hello.SNI != "www.example.com" {
+		t.Fatalf("unexpected sni %s", hello.SNI)
+	} else if !reflect.DeepEqual(hello.ALPN, []string{"h2", "http/1.1"}) {
+		t.Fatalf("unexpected alpn %v", hello.ALPN)
+	} else if hello.Version != 0x0303 || hello.NegotiableVersion() != 0x0304 {
+		t.Fatalf("unexpected versions %x/%x", hello.Version, hello.NegotiableVersion())
+	} else if TLSVersionName(hello.NegotiableVersion()) != "TLS 1.3" {
+		t.Fatalf("unexpected version name %s", TLSVersionName(hello.NegotiableVersion()))
+	}
+
+	expected := "771,4866-4867-4865-49196-49200-49195-49199-52393-52392-49188-49192-49187-49191-159-158-107-103-255," +
+		"0-11-10-35-16-22-23-13-43-45-51-21,29-23-30-25-24-256-257-258-259-260,0-1-2"
+	if s := hello.JA3String(); s != expected {
+		t.Fatalf("expected %s, got %s", expected, s)
+	} else if ja3 := hello.JA3(); ja3 != "304734bb1c086c3453b387400cf83f11" {
+		t.Fatalf("unexpected ja3 %s", ja3)
+	}
+}
+
+func TestParseTLSClientHelloGREASE(t *testing.T) {
+	hello := TLSClientHello{
+		Version:      0x0303,
+		CipherSuites: []uint16{0x1a1a, 4865, 4866},
+		Extensions:   []uint16{0x2a2a, 0, 10, 0xfafa},
+		Curves:       []uint16{0x3a3a, 29},
+		PointFormats: []uint8{0},
+	}
+	if s := hello.JA3String(); s != "771,4865-4866,0-10,29,0" {
+		t.Fatalf("unexpected ja3 string %s", s)
+	}
+}
+
+func TestParseTLSServerHello(t *testing.T) {
+	payload := unhex(t, ""+
+		"160303007a020000760303a5e73bee374f382e01ffe35aca267ad2d46240290c1505d82286f1c21d13bc1620ecb39af4"+
+		"d91a2c8f34155a000a7e346d5d0ff3e397b44f0f8d237e4289c2a7f3130200002e002b0002030400330024001d002001"+
+		"6b51f4663795f847b7fe1503a747b9d8b06f7087368ee6abae7f733ff6f03c")
+
+	hello, err := ParseTLSServerHello(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if hello.CipherSuite != 0x1302 || hello.NegotiatedVersion() != 0x0304 {
+		t.Fatalf("unexpected hello %+v", hello)
+	} else if s := hello.JA3SString(); s != "771,4866,43-51" {
+		t.Fatalf("unexpected ja3s string %s", s)
+	} else if ja3s := hello.JA3S(); ja3s != "15af977ce25de452b96affa2addb1036" {
+		t.Fatalf("unexpected ja3s %s", ja3s)
+	}
+
+	if _, err := ParseTLSClientHello(payload); err != ErrTLSInvalid {
+		t.Fatalf("expected %v, got %v", ErrTLSInvalid, err)
+	}
+}
+
+func TestParseTLSInvalid(t *testing.T) {
+	hello := tlsTestClientHello(t)
+	for _, payload := range [][]byte{
+		{},
+		[]byte("GET / HTTP/1.1\r\n"),
+		// split across more segments
+		hello[:300],
+	} {
+		if _, err := ParseTLSClientHello(payload); err != ErrTLSInvalid {
+			t.Fatalf("expected %v, got %v", ErrTLSInvalid, err)
+		}
+	}
+}
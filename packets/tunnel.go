@@ -0,0 +1,80 @@
+package packets
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Tunnel describes the GRE, VXLAN or Geneve overlay a packet was carried by.
+type Tunnel struct {
+	Protocol string `json:"protocol"`
+	Src      net.IP `json:"src"`
+	Dst      net.IP `json:"dst"`
+	// the GRE key or the network identifier of VXLAN and Geneve
+	ID      uint32 `json:"id,omitempty"`
+	idLabel string
+}
+
+func (t *Tunnel) String() string {
+	s := fmt.Sprintf("%s %s>%s", t.Protocol, t.Src, t.Dst)
+	if t.ID != 0 {
+		s += fmt.Sprintf(" %s %d", t.idLabel, t.ID)
+	}
+	return s
+}
+
+// the tunnel and the type of the first layer it carries
+func tunnelOf(layer gopacket.Layer) (*Tunnel, gopacket.LayerType) {
+	switch l := layer.(type) {
+	case *layers.GRE:
+		return &Tunnel{Protocol: "gre", ID: l.Key, idLabel: "key"}, l.NextLayerType()
+	case *layers.VXLAN:
+		return &Tunnel{Protocol: "vxlan", ID: l.VNI, idLabel: "vni"}, l.NextLayerType()
+	case *layers.Geneve:
+		return &Tunnel{Protocol: "geneve", ID: l.VNI, idLabel: "vni"}, l.NextLayerType()
+	}
+	return nil, gopacket.LayerTypeZero
+}
+
+// Decapsulate returns the packet carried by the outermost GRE, VXLAN or
+// Geneve tunnel of a packet and the tunnel itself, or nil if there's none.
+func Decapsulate(pkt gopacket.Packet) (gopacket.Packet, *Tunnel) {
+	var src, dst net.IP
+	for _, layer := range pkt.Layers() {
+		switch l := layer.(type) {
+		case *layers.IPv4:
+			src, dst = l.SrcIP, l.DstIP
+			continue
+		case *layers.IPv6:
+			src, dst = l.SrcIP, l.DstIP
+			continue
+		}
+
+		tunnel, next := tunnelOf(layer)
+		if tunnel == nil {
+			continue
+		} else if next != layers.LayerTypeEthernet && next != layers.LayerTypeIPv4 && next != layers.LayerTypeIPv6 {
+			// keepalives, ERSPAN and other non routable payloads
+			return nil, nil
+		}
+
+		payload := layer.LayerPayload()
+		if len(payload) == 0 {
+			return nil, nil
+		}
+
+		tunnel.Src, tunnel.Dst = src, dst
+		inner := gopacket.NewPacket(payload, next, gopacket.Default)
+		// same capture, with the size of what was carried
+		meta := inner.Metadata()
+		meta.CaptureInfo = pkt.Metadata().CaptureInfo
+		meta.CaptureLength = len(payload)
+		meta.Length = len(payload)
+
+		return inner, tunnel
+	}
+	return nil, nil
+}
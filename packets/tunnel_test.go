@@ -0,0 +1,114 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func tunnelTestInner(t *testing.T) []byte {
+	ip := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP("192.168.1.10"),
+		DstIP:    net.ParseIP("192.168.1.1"),
+	}
+	udp := layers.UDP{SrcPort: 12345, DstPort: 53}
+	udp.SetNetworkLayerForChecksum(&ip)
+	err, raw := Serialize(&ip, &udp, gopacket.Payload([]byte{0xde, 0xad}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return raw
+}
+
+func tunnelTestOuter(proto layers.IPProtocol) (layers.Ethernet, layers.IPv4) {
+	hw, _ := net.ParseMAC("00:11:22:33:44:55")
+	eth := layers.Ethernet{SrcMAC: hw, DstMAC: hw, EthernetType: layers.EthernetTypeIPv4}
+	ip := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: proto,
+		SrcIP:    net.ParseIP("10.0.0.1"),
+		DstIP:    net.ParseIP("10.0.0.2"),
+	}
+	return eth, ip
+}
+
+func TestDecapsulateGRE(t *testing.T) {
+	eth, ip := tunnelTestOuter(layers.IPProtocolGRE)
+	gre := layers.GRE{Protocol: layers.EthernetTypeIPv4, KeyPresent: true, Key: 42}
+	err, raw := Serialize(&eth, &ip, &gre, gopacket.Payload(tunnelTestInner(t)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inner, tunnel := Decapsulate(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default))
+	if inner == nil {
+		t.Fatal("expected an inner packet")
+	} else if s := tunnel.String(); s != "gre 10.0.0.1>10.0.0.2 key 42" {
+		t.Fatalf("unexpected tunnel %s", s)
+	} else if ip, ok := inner.NetworkLayer().(*layers.IPv4); !ok || !ip.SrcIP.Equal(net.ParseIP("192.168.1.10")) {
+		t.Fatalf("unexpected inner network layer %v", inner.NetworkLayer())
+	} else if udp, ok := inner.Layer(layers.LayerTypeUDP).(*layers.UDP); !ok || udp.DstPort != 53 {
+		t.Fatal("expected the inner udp layer")
+	}
+}
+
+func TestDecapsulateVXLAN(t *testing.T) {
+	eth, ip := tunnelTestOuter(layers.IPProtocolUDP)
+	udp := layers.UDP{SrcPort: 50000, DstPort: 4789}
+	udp.SetNetworkLayerForChecksum(&ip)
+	vxlan := layers.VXLAN{ValidIDFlag: true, VNI: 5000}
+	innerEth := layers.Ethernet{SrcMAC: eth.SrcMAC, DstMAC: eth.DstMAC, EthernetType: layers.EthernetTypeIPv4}
+	err, raw := Serialize(&eth, &ip, &udp, &vxlan, &innerEth, gopacket.Payload(tunnelTestInner(t)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inner, tunnel := Decapsulate(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default))
+	if inner == nil {
+		t.Fatal("expected an inner packet")
+	} else if s := tunnel.String(); s != "vxlan 10.0.0.1>10.0.0.2 vni 5000" {
+		t.Fatalf("unexpected tunnel %s", s)
+	} else if inner.Layer(layers.LayerTypeEthernet) == nil || inner.Layer(layers.LayerTypeUDP) == nil {
+		t.Fatal("expected the inner ethernet and udp layers")
+	} else if _, again := Decapsulate(inner); again != nil {
+		t.Fatalf("unexpected nested tunnel %s", again)
+	}
+}
+
+func TestDecapsulateGeneve(t *testing.T) {
+	eth, ip := tunnelTestOuter(layers.IPProtocolUDP)
+	udp := layers.UDP{SrcPort: 50000, DstPort: 6081}
+	udp.SetNetworkLayerForChecksum(&ip)
+	// version 0, no options, ipv4 payload, vni 0x000100
+	geneve := []byte{0x00, 0x00, 0x08, 0x00, 0x00, 0x01, 0x00, 0x00}
+	err, raw := Serialize(&eth, &ip, &udp, gopacket.Payload(append(geneve, tunnelTestInner(t)...)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inner, tunnel := Decapsulate(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default))
+	if inner == nil {
+		t.Fatal("expected an inner packet")
+	} else if s := tunnel.String(); s != "geneve 10.0.0.1>10.0.0.2 vni 256" {
+		t.Fatalf("unexpected tunnel %s", s)
+	} else if inner.Layer(layers.LayerTypeUDP) == nil {
+		t.Fatal("expected the inner udp layer")
+	}
+}
+
+func TestDecapsulateNone(t *testing.T) {
+	eth, ip := tunnelTestOuter(layers.IPProtocolUDP)
+	udp := layers.UDP{SrcPort: 50000, DstPort: 53}
+	udp.SetNetworkLayerForChecksum(&ip)
+	_, raw := Serialize(&eth, &ip, &udp, gopacket.Payload([]byte{0xde, 0xad}))
+
+	if inner, tunnel := Decapsulate(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)); inner != nil || tunnel != nil {
+		t.Fatalf("unexpected tunnel %v", tunnel)
+	}
+}
@@ -0,0 +1,152 @@
+package packets
+
+import (
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// UDP port states, without an answer a port is either open or filtered.
+const (
+	UDPPortOpen         = "open"
+	UDPPortClosed       = "closed"
+	UDPPortFiltered     = "filtered"
+	UDPPortOpenFiltered = "open|filtered"
+)
+
+// payloads the services answer to, the other ports get an empty datagram
+var udpScanPayloads = map[int][]byte{
+	// version.bind CHAOS TXT query, even refused it gets an answer
+	53: {
+		0xbe, 0xef, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x07, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0x04, 'b', 'i', 'n', 'd', 0x00,
+		0x00, 0x10, 0x00, 0x03,
+	},
+	// NTPv4 client request
+	123: append([]byte{0xe3}, make([]byte, 47)...),
+	// NetBIOS node status request
+	137: NBNSRequest,
+	// SNMPv1 get-request of sysDescr.0 with the public community
+	161: {
+		0x30, 0x26, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x19, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00,
+		0x05, 0x00,
+	},
+}
+
+// UDPScanPayload returns the payload of the probe for the given port.
+func UDPScanPayload(port int) []byte {
+	if payload, found := udpScanPayloads[port]; found {
+		return payload
+	}
+	return []byte{}
+}
+
+func newUDPPacket(from net.IP, fromHW net.HardwareAddr, to net.IP, toHW net.HardwareAddr, srcPort int, dstPort int, payload []byte) (error, []byte) {
+	eth := layers.Ethernet{
+		SrcMAC:       fromHW,
+		DstMAC:       toHW,
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	udp := layers.UDP{
+		SrcPort: layers.UDPPort(srcPort),
+		DstPort: layers.UDPPort(dstPort),
+	}
+
+	if to.To4() == nil {
+		eth.EthernetType = layers.EthernetTypeIPv6
+		ip6 := layers.IPv6{
+			NextHeader: layers.IPProtocolUDP,
+			Version:    6,
+			SrcIP:      from,
+			DstIP:      to,
+			HopLimit:   64,
+		}
+		udp.SetNetworkLayerForChecksum(&ip6)
+
+		return Serialize(&eth, &ip6, &udp, gopacket.Payload(payload))
+	}
+
+	ip4 := layers.IPv4{
+		Protocol: layers.IPProtocolUDP,
+		Version:  4,
+		TTL:      64,
+		SrcIP:    from,
+		DstIP:    to,
+	}
+	udp.SetNetworkLayerForChecksum(&ip4)
+
+	return Serialize(&eth, &ip4, &udp, gopacket.Payload(payload))
+}
+
+// NewUDPScanProbe creates a datagram for the given port with the payload
+// its service is most likely to answer to.
+func NewUDPScanProbe(from net.IP, fromHW net.HardwareAddr, to net.IP, toHW net.HardwareAddr, srcPort int, dstPort int) (error, []byte) {
+	return newUDPPacket(from, fromHW, to, toHW, srcPort, dstPort, UDPScanPayload(dstPort))
+}
+
+// UDPScanReply is what a packet tells about a port probed from srcPort.
+type UDPScanReply struct {
+	Address net.IP
+	Port    int
+	State   string
+	Payload []byte
+}
+
+// ParseUDPScanReply returns the state of the probed port a packet answers
+// for, either with a datagram or with an ICMP unreachable error, or nil if
+// the packet is not an answer to a probe sent from srcPort.
+func ParseUDPScanReply(pkt gopacket.Packet, srcPort int) *UDPScanReply {
+	if udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP); ok && int(udp.DstPort) == srcPort {
+		reply := &UDPScanReply{
+			Port:    int(udp.SrcPort),
+			State:   UDPPortOpen,
+			Payload: udp.Payload,
+		}
+		if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+			reply.Address = ip4.SrcIP
+		} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+			reply.Address = ip6.SrcIP
+		} else {
+			return nil
+		}
+		return reply
+	}
+
+	var quote []byte
+	closed := false
+	if icmp, ok := pkt.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4); ok {
+		if icmp.TypeCode.Type() != layers.ICMPv4TypeDestinationUnreachable {
+			return nil
+		}
+		quote = icmp.Payload
+		closed = icmp.TypeCode.Code() == layers.ICMPv4CodePort
+	} else if icmp6, ok := pkt.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6); ok {
+		// errors have 4 unused bytes before the quote
+		if icmp6.TypeCode.Type() != layers.ICMPv6TypeDestinationUnreachable || len(icmp6.Payload) <= 4 {
+			return nil
+		}
+		quote = icmp6.Payload[4:]
+		closed = icmp6.TypeCode.Code() == layers.ICMPv6CodePortUnreachable
+	} else {
+		return nil
+	}
+
+	q, err := ParseICMPQuote(quote)
+	if err != nil || q.Protocol != layers.IPProtocolUDP || int(q.SrcPort) != srcPort {
+		return nil
+	}
+
+	// any other unreachable error means something is in the way
+	reply := &UDPScanReply{
+		Address: q.DstIP,
+		Port:    int(q.DstPort),
+		State:   UDPPortFiltered,
+	}
+	if closed {
+		reply.State = UDPPortClosed
+	}
+	return reply
+}
@@ -0,0 +1,123 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	udpScanFrom   = net.ParseIP("192.168.1.2").To4()
+	udpScanFromHW = net.HardwareAddr{0x01, 0x23, 0x45, 0x67, 0x89, 0xab}
+	udpScanTo     = net.ParseIP("192.168.1.3").To4()
+	udpScanToHW   = net.HardwareAddr{0xab, 0x89, 0x67, 0x45, 0x23, 0x01}
+)
+
+func TestUDPScanPayloads(t *testing.T) {
+	if msg, err := ParseSNMP(UDPScanPayload(161)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msg.Community != "public" || msg.PDUName() != "get" || len(msg.VarBinds) != 1 {
+		t.Fatalf("unexpected SNMP request %+v", msg)
+	} else if msg.VarBinds[0].OID != "1.3.6.1.2.1.1.1.0" {
+		t.Fatalf("unexpected OID %s", msg.VarBinds[0].OID)
+	}
+
+	if p, err := ParseNTP(UDPScanPayload(123)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if p.Mode != NTPModeClient || p.Version != 4 {
+		t.Fatalf("unexpected NTP request %+v", p)
+	}
+
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(UDPScanPayload(53), gopacket.NilDecodeFeedback); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(dns.Questions) != 1 || string(dns.Questions[0].Name) != "version.bind" || dns.Questions[0].Class != layers.DNSClassCH {
+		t.Fatalf("unexpected DNS query %+v", dns.Questions)
+	}
+
+	if payload := UDPScanPayload(9999); len(payload) != 0 {
+		t.Fatalf("unexpected payload %x", payload)
+	}
+}
+
+func TestNewUDPScanProbe(t *testing.T) {
+	err, raw := NewUDPScanProbe(udpScanFrom, udpScanFromHW, udpScanTo, udpScanToHW, 666, 137)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	if udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP); !ok {
+		t.Fatalf("expected an udp layer")
+	} else if udp.SrcPort != 666 || udp.DstPort != 137 {
+		t.Fatalf("unexpected ports %d -> %d", udp.SrcPort, udp.DstPort)
+	} else if string(udp.Payload) != string(NBNSRequest) {
+		t.Fatalf("unexpected payload %x", udp.Payload)
+	}
+}
+
+func TestParseUDPScanReplyOpen(t *testing.T) {
+	err, raw := newUDPPacket(udpScanTo, udpScanToHW, udpScanFrom, udpScanFromHW, 53, 666, []byte("answer"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	if reply := ParseUDPScanReply(pkt, 666); reply == nil {
+		t.Fatalf("expected a reply")
+	} else if !reply.Address.Equal(udpScanTo) || reply.Port != 53 || reply.State != UDPPortOpen || string(reply.Payload) != "answer" {
+		t.Fatalf("unexpected reply %+v", reply)
+	} else if reply = ParseUDPScanReply(pkt, 667); reply != nil {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+}
+
+func udpScanUnreachable(t *testing.T, code uint8, srcPort int) gopacket.Packet {
+	err, probe := NewUDPScanProbe(udpScanFrom, udpScanFromHW, udpScanTo, udpScanToHW, srcPort, 161)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	eth := layers.Ethernet{
+		SrcMAC:       udpScanToHW,
+		DstMAC:       udpScanFromHW,
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolICMPv4,
+		SrcIP:    udpScanTo,
+		DstIP:    udpScanFrom,
+	}
+	icmp := layers.ICMPv4{
+		TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeDestinationUnreachable, code),
+	}
+
+	// the quote starts from the IP header of the probe
+	err, raw := Serialize(&eth, &ip4, &icmp, gopacket.Payload(probe[14:]))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestParseUDPScanReplyUnreachable(t *testing.T) {
+	for code, state := range map[uint8]string{
+		layers.ICMPv4CodePort:                UDPPortClosed,
+		layers.ICMPv4CodeCommAdminProhibited: UDPPortFiltered,
+		layers.ICMPv4CodeHost:                UDPPortFiltered,
+	} {
+		if reply := ParseUDPScanReply(udpScanUnreachable(t, code, 666), 666); reply == nil {
+			t.Fatalf("expected a reply for code %d", code)
+		} else if !reply.Address.Equal(udpScanTo) || reply.Port != 161 || reply.State != state {
+			t.Fatalf("unexpected reply %+v for code %d", reply, code)
+		}
+	}
+
+	if reply := ParseUDPScanReply(udpScanUnreachable(t, layers.ICMPv4CodePort, 1234), 666); reply != nil {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+}
@@ -29,22 +29,95 @@ var (
 		"\r\n")
 )
 
+// SSDPMessage is a SSDP search, announcement or search response.
+type SSDPMessage struct {
+	// M-SEARCH, NOTIFY or empty for the responses
+	Method string
+	// ST of searches and responses, NT of announcements
+	Target string
+	// ssdp:alive, ssdp:update or ssdp:byebye for announcements
+	SubType  string
+	USN      string
+	Server   string
+	Location string
+	Headers  http.Header
+}
+
+// ParseSSDP parses the HTTP over UDP messages of SSDP.
+func ParseSSDP(payload []byte) (*SSDPMessage, error) {
+	reader := bufio.NewReader(bytes.NewReader(payload))
+	msg := &SSDPMessage{}
+
+	if bytes.HasPrefix(payload, []byte("HTTP/")) {
+		response, err := http.ReadResponse(reader, &http.Request{})
+		if err != nil {
+			return nil, err
+		}
+		msg.Headers = response.Header
+		msg.Target = msg.Headers.Get("ST")
+	} else {
+		request, err := http.ReadRequest(reader)
+		if err != nil {
+			return nil, err
+		} else if request.Method != "M-SEARCH" && request.Method != "NOTIFY" {
+			return nil, fmt.Errorf("unexpected SSDP method %s", request.Method)
+		}
+		msg.Method = request.Method
+		msg.Headers = request.Header
+		if msg.Method == "NOTIFY" {
+			msg.Target = msg.Headers.Get("NT")
+			msg.SubType = msg.Headers.Get("NTS")
+		} else {
+			msg.Target = msg.Headers.Get("ST")
+		}
+	}
+
+	msg.USN = msg.Headers.Get("USN")
+	msg.Server = msg.Headers.Get("Server")
+	msg.Location = msg.Headers.Get("Location")
+	return msg, nil
+}
+
+// IsSearch returns true for the M-SEARCH requests of the control points.
+func (m *SSDPMessage) IsSearch() bool {
+	return m.Method == "M-SEARCH"
+}
+
+// DeviceType returns the type of a device target such as
+// urn:schemas-upnp-org:device:InternetGatewayDevice:1
+func (m *SSDPMessage) DeviceType() string {
+	parts := strings.Split(m.Target, ":")
+	for i, part := range parts {
+		if part == "device" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// Meta returns the headers of a device announcement or response.
+func (m *SSDPMessage) Meta() map[string]string {
+	meta := make(map[string]string)
+	for name, values := range m.Headers {
+		if name != "Cache-Control" && name != "Host" && len(values) > 0 {
+			if data := str.Trim(strings.Join(values, ", ")); data != "" {
+				meta["upnp:"+name] = data
+			}
+		}
+	}
+	if device := m.DeviceType(); device != "" {
+		meta["upnp:device"] = device
+	}
+	return meta
+}
+
+// UPNPGetMeta returns the metadata of the device sending an announcement or
+// a search response.
 func UPNPGetMeta(pkt gopacket.Packet) map[string]string {
 	if ludp := pkt.Layer(layers.LayerTypeUDP); ludp != nil {
-		if udp := ludp.(*layers.UDP); udp != nil && udp.SrcPort == UPNPPort && len(udp.Payload) > 0 {
-			request := &http.Request{}
-			reader := bufio.NewReader(bytes.NewReader(udp.Payload))
-			if response, err := http.ReadResponse(reader, request); err == nil {
-				meta := make(map[string]string)
-				for name, values := range response.Header {
-					if name != "Cache-Control" && len(values) > 0 {
-						if data := str.Trim(strings.Join(values, ", ")); data != "" {
-							meta["upnp:"+name] = data
-						}
-
-					}
-				}
-				return meta
+		if udp := ludp.(*layers.UDP); udp != nil && (udp.SrcPort == UPNPPort || udp.DstPort == UPNPPort) && len(udp.Payload) > 0 {
+			if msg, err := ParseSSDP(udp.Payload); err == nil && !msg.IsSearch() {
+				return msg.Meta()
 			}
 		}
 	}
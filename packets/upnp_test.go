@@ -0,0 +1,57 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestParseSSDP(t *testing.T) {
+	notify := []byte("NOTIFY * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"CACHE-CONTROL: max-age=1800\r\n" +
+		"LOCATION: http://192.168.1.1:5000/rootDesc.xml\r\n" +
+		"SERVER: Linux/4.14 UPnP/1.1 MiniUPnPd/2.1\r\n" +
+		"NT: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+		"NTS: ssdp:alive\r\n" +
+		"USN: uuid:1234::urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+		"\r\n")
+
+	msg, err := ParseSSDP(notify)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msg.Method != "NOTIFY" || msg.IsSearch() || msg.SubType != "ssdp:alive" {
+		t.Fatalf("unexpected message %+v", msg)
+	} else if msg.DeviceType() != "InternetGatewayDevice" {
+		t.Fatalf("unexpected device type '%s'", msg.DeviceType())
+	} else if msg.Location != "http://192.168.1.1:5000/rootDesc.xml" || msg.Server != "Linux/4.14 UPnP/1.1 MiniUPnPd/2.1" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	meta := msg.Meta()
+	if meta["upnp:device"] != "InternetGatewayDevice" || meta["upnp:Location"] != msg.Location {
+		t.Fatalf("unexpected meta %v", meta)
+	} else if _, found := meta["upnp:Cache-Control"]; found {
+		t.Fatalf("unexpected cache control in %v", meta)
+	}
+
+	response := []byte("HTTP/1.1 200 OK\r\n" +
+		"ST: upnp:rootdevice\r\n" +
+		"SERVER: Windows/10.0 UPnP/1.0\r\n" +
+		"LOCATION: http://192.168.1.20:2869/upnphost/udhisapi.dll?content=uuid:abcd\r\n" +
+		"\r\n")
+
+	if msg, err = ParseSSDP(response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if msg.Method != "" || msg.Target != "upnp:rootdevice" || msg.DeviceType() != "" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	if msg, err = ParseSSDP(UPNPDiscoveryPayload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !msg.IsSearch() || msg.Target != "ssdp:all" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	if _, err = ParseSSDP([]byte("GET / HTTP/1.1\r\n\r\n")); err == nil {
+		t.Fatal("expected an error for a non SSDP method")
+	}
+}
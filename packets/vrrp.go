@@ -0,0 +1,151 @@
+package packets
+
+import (
+	"errors"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	VRRPAuthNone   = 0
+	VRRPAuthSimple = 1
+	VRRPAuthAH     = 2
+
+	vrrpHeaderSize = 8
+)
+
+var (
+	VRRPDestIP = net.ParseIP("224.0.0.18")
+
+	ErrVRRPShort = errors.New("VRRP packet too short")
+)
+
+// VRRP represents version 2 and 3 VRRP advertisements for IPv4 groups.
+type VRRP struct {
+	Version  uint8
+	RouterID uint8
+	Priority uint8
+	// seconds for version 2, centiseconds for version 3
+	Interval  uint16
+	AuthType  uint8
+	Auth      []byte
+	Addresses []net.IP
+}
+
+// VRRPVirtualMAC returns the MAC address used by the master of the group.
+func VRRPVirtualMAC(routerID uint8) net.HardwareAddr {
+	return net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x01, routerID}
+}
+
+// WeakAuth returns true if the group uses no authentication or a plain text password.
+func (v *VRRP) WeakAuth() bool {
+	return v.AuthType == VRRPAuthNone || v.AuthType == VRRPAuthSimple
+}
+
+func ParseVRRP(data []byte) (*VRRP, error) {
+	if len(data) < vrrpHeaderSize {
+		return nil, ErrVRRPShort
+	}
+
+	v := &VRRP{
+		Version:   data[0] >> 4,
+		RouterID:  data[1],
+		Priority:  data[2],
+		Addresses: make([]net.IP, 0),
+	}
+
+	count := int(data[3])
+	if v.Version == 3 {
+		v.Interval = uint16(data[4]&0x0f)<<8 | uint16(data[5])
+	} else {
+		v.AuthType = data[4]
+		v.Interval = uint16(data[5])
+	}
+
+	data = data[vrrpHeaderSize:]
+	if len(data) < count*4 {
+		return nil, ErrVRRPShort
+	}
+	for i := 0; i < count; i++ {
+		v.Addresses = append(v.Addresses, net.IP(append([]byte{}, data[i*4:i*4+4]...)))
+	}
+
+	if data = data[count*4:]; v.Version == 2 && len(data) >= 8 {
+		v.Auth = append([]byte{}, data[:8]...)
+	}
+
+	return v, nil
+}
+
+func vrrpChecksum(data []byte) uint16 {
+	sum := uint32(0)
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// Payload serializes the advertisement, from is needed for the version 3
+// checksum which includes the IPv4 pseudo header.
+func (v *VRRP) Payload(from net.IP) []byte {
+	data := make([]byte, vrrpHeaderSize)
+	data[0] = v.Version<<4 | 1
+	data[1] = v.RouterID
+	data[2] = v.Priority
+	data[3] = byte(len(v.Addresses))
+	if v.Version == 3 {
+		data[4] = byte(v.Interval>>8) & 0x0f
+		data[5] = byte(v.Interval)
+	} else {
+		data[4] = v.AuthType
+		data[5] = byte(v.Interval)
+	}
+
+	for _, addr := range v.Addresses {
+		data = append(data, addr.To4()...)
+	}
+
+	if v.Version == 2 {
+		auth := make([]byte, 8)
+		copy(auth, v.Auth)
+		data = append(data, auth...)
+	}
+
+	var sum uint16
+	if v.Version == 3 {
+		pseudo := append(append([]byte{}, from.To4()...), VRRPDestIP.To4()...)
+		pseudo = append(pseudo, 0, byte(layers.IPProtocolVRRP), byte(len(data)>>8), byte(len(data)))
+		sum = vrrpChecksum(append(pseudo, data...))
+	} else {
+		sum = vrrpChecksum(data)
+	}
+	data[6], data[7] = byte(sum>>8), byte(sum)
+
+	return data
+}
+
+// NewVRRPPacket creates a VRRP advertisement from our address to the VRRP multicast address.
+func NewVRRPPacket(from net.IP, fromHW net.HardwareAddr, v *VRRP) (error, []byte) {
+	eth := layers.Ethernet{
+		SrcMAC:       fromHW,
+		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x12},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Protocol: layers.IPProtocolVRRP,
+		Version:  4,
+		TTL:      255,
+		SrcIP:    from,
+		DstIP:    VRRPDestIP,
+	}
+
+	return Serialize(&eth, &ip4, gopacket.Payload(v.Payload(from)))
+}
@@ -0,0 +1,64 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestVRRPv2(t *testing.T) {
+	hw, _ := net.ParseMAC("00:11:22:33:44:55")
+	v := &VRRP{
+		Version:   2,
+		RouterID:  10,
+		Priority:  255,
+		Interval:  1,
+		AuthType:  VRRPAuthSimple,
+		Auth:      []byte("pass"),
+		Addresses: []net.IP{net.ParseIP("10.0.0.1")},
+	}
+
+	err, raw := NewVRRPPacket(net.ParseIP("10.0.0.42"), hw, v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	vrrp, ok := pkt.Layer(layers.LayerTypeVRRP).(*layers.VRRPv2)
+	if !ok {
+		t.Fatalf("could not decode vrrp: %v", pkt.ErrorLayer())
+	} else if vrrp.VirtualRtrID != 10 || vrrp.Priority != 255 || len(vrrp.IPAddress) != 1 {
+		t.Fatalf("unexpected fields %+v", vrrp)
+	} else if sum := vrrpChecksum(vrrp.Contents); sum != 0 {
+		t.Fatalf("invalid checksum, residual %x", sum)
+	}
+
+	parsed, err := ParseVRRP(vrrp.Contents)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !parsed.WeakAuth() || string(parsed.Auth[:4]) != "pass" {
+		t.Fatalf("unexpected authentication %+v", parsed)
+	} else if !parsed.Addresses[0].Equal(v.Addresses[0]) {
+		t.Fatalf("expected address %s, got %s", v.Addresses[0], parsed.Addresses[0])
+	}
+}
+
+func TestVRRPv3(t *testing.T) {
+	from := net.ParseIP("10.0.0.42")
+	v := &VRRP{
+		Version:   3,
+		RouterID:  1,
+		Priority:  100,
+		Interval:  100,
+		Addresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+	}
+
+	parsed, err := ParseVRRP(v.Payload(from))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if parsed.Version != 3 || parsed.Interval != 100 || len(parsed.Addresses) != 2 || parsed.Auth != nil {
+		t.Fatalf("unexpected fields %+v", parsed)
+	}
+}
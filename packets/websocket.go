@@ -0,0 +1,124 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	WebSocketContinuation = 0x0
+	WebSocketText         = 0x1
+	WebSocketBinary       = 0x2
+	WebSocketClose        = 0x8
+	WebSocketPing         = 0x9
+	WebSocketPong         = 0xa
+)
+
+var WebSocketOpcodeNames = map[uint8]string{
+	WebSocketContinuation: "continuation",
+	WebSocketText:         "text",
+	WebSocketBinary:       "binary",
+	WebSocketClose:        "close",
+	WebSocketPing:         "ping",
+	WebSocketPong:         "pong",
+}
+
+var ErrWebSocketInvalid = errors.New("not a valid WebSocket frame")
+
+// WebSocketFrame is a WebSocket frame with its payload unmasked, the payload
+// of the last frame of a segment can be incomplete.
+type WebSocketFrame struct {
+	Fin     bool   `json:"fin"`
+	Opcode  uint8  `json:"opcode"`
+	Masked  bool   `json:"masked"`
+	Length  uint64 `json:"length"`
+	Payload []byte `json:"-"`
+	// bytes of the payload in the next segments
+	Missing uint64 `json:"missing"`
+}
+
+// OpcodeName returns the name of the opcode.
+func (f *WebSocketFrame) OpcodeName() string {
+	if name, found := WebSocketOpcodeNames[f.Opcode]; found {
+		return name
+	}
+	return fmt.Sprintf("opcode 0x%x", f.Opcode)
+}
+
+// IsControl returns true for close, ping and pong frames.
+func (f *WebSocketFrame) IsControl() bool {
+	return f.Opcode&0x8 != 0
+}
+
+// ParseWebSocketFrames parses the frames of a segment.
+func ParseWebSocketFrames(payload []byte) ([]*WebSocketFrame, error) {
+	frames := []*WebSocketFrame{}
+	for len(payload) > 0 {
+		if len(payload) < 2 {
+			return nil, ErrWebSocketInvalid
+		}
+
+		f := &WebSocketFrame{
+			Fin:    payload[0]&0x80 != 0,
+			Opcode: payload[0] & 0x0f,
+			Masked: payload[1]&0x80 != 0,
+			Length: uint64(payload[1] & 0x7f),
+		}
+		// no extensions, unknown opcodes and fragmented control frames
+		if payload[0]&0x70 != 0 {
+			return nil, ErrWebSocketInvalid
+		} else if _, found := WebSocketOpcodeNames[f.Opcode]; !found {
+			return nil, ErrWebSocketInvalid
+		} else if f.IsControl() && (!f.Fin || f.Length > 125) {
+			return nil, ErrWebSocketInvalid
+		}
+
+		offset := 2
+		switch f.Length {
+		case 126:
+			if len(payload) < 4 {
+				return nil, ErrWebSocketInvalid
+			}
+			f.Length = uint64(binary.BigEndian.Uint16(payload[2:]))
+			offset = 4
+		case 127:
+			if len(payload) < 10 {
+				return nil, ErrWebSocketInvalid
+			}
+			f.Length = binary.BigEndian.Uint64(payload[2:])
+			if f.Length&(1<<63) != 0 {
+				return nil, ErrWebSocketInvalid
+			}
+			offset = 10
+		}
+
+		var mask []byte
+		if f.Masked {
+			if len(payload) < offset+4 {
+				return nil, ErrWebSocketInvalid
+			}
+			mask = payload[offset : offset+4]
+			offset += 4
+		}
+
+		data := payload[offset:]
+		if uint64(len(data)) > f.Length {
+			data = data[:f.Length]
+		}
+		f.Missing = f.Length - uint64(len(data))
+		f.Payload = make([]byte, len(data))
+		for i := range data {
+			if mask != nil {
+				f.Payload[i] = data[i] ^ mask[i%4]
+			} else {
+				f.Payload[i] = data[i]
+			}
+		}
+
+		frames = append(frames, f)
+		payload = payload[offset+len(data):]
+	}
+
+	return frames, nil
+}
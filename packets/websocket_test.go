@@ -0,0 +1,62 @@
+package packets
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseWebSocketFrames(t *testing.T) {
+	// RFC 6455 5.7, a masked "Hello" from the client, an unmasked one and a ping
+	payload := []byte{
+		0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58,
+		0x81, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f,
+		0x89, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f,
+	}
+
+	frames, err := ParseWebSocketFrames(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(frames) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(frames))
+	}
+
+	for i, f := range frames[:2] {
+		if !f.Fin || f.Opcode != WebSocketText || f.OpcodeName() != "text" || f.IsControl() {
+			t.Fatalf("unexpected frame %d %+v", i, f)
+		} else if !bytes.Equal(f.Payload, []byte("Hello")) || f.Missing != 0 {
+			t.Fatalf("unexpected payload %q", f.Payload)
+		}
+	}
+	if !frames[0].Masked || frames[1].Masked {
+		t.Fatal("unexpected masking")
+	} else if !frames[2].IsControl() || frames[2].OpcodeName() != "ping" {
+		t.Fatalf("unexpected frame %+v", frames[2])
+	}
+}
+
+func TestParseWebSocketTruncated(t *testing.T) {
+	// 256 bytes binary frame with only 4 of them in the segment
+	frames, err := ParseWebSocketFrames([]byte{0x82, 0x7e, 0x01, 0x00, 0x01, 0x02, 0x03, 0x04})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if f := frames[0]; f.Length != 256 || f.Missing != 252 || len(f.Payload) != 4 {
+		t.Fatalf("unexpected frame %+v", f)
+	}
+}
+
+func TestParseWebSocketInvalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		{0x81},
+		// reserved bits
+		{0xc1, 0x00},
+		// unknown opcode
+		{0x83, 0x00},
+		// fragmented ping
+		{0x09, 0x00},
+		[]byte("HTTP/1.1 200 OK\r\n\r\n"),
+	} {
+		if _, err := ParseWebSocketFrames(payload); err != ErrWebSocketInvalid {
+			t.Fatalf("expected %v for %x, got %v", ErrWebSocketInvalid, payload, err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package session
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// facts derived from other facts deeper than this are dropped to break loops
+	MaxFactDepth = 8
+	// facts queued for a subscriber still busy with the previous ones
+	factsQueueSize = 256
+)
+
+// Fact is a piece of information published on the bus by a module, or derived
+// by an enrichment handler from another fact.
+type Fact struct {
+	ID     uint64      `json:"id"`
+	Topic  string      `json:"topic"`
+	Source string      `json:"source"`
+	Time   time.Time   `json:"time"`
+	Depth  int         `json:"depth"`
+	Data   interface{} `json:"data"`
+}
+
+// FactHandler is called for every fact matching the subscription.
+type FactHandler func(f Fact)
+
+type Subscription struct {
+	ID       uint64 `json:"id"`
+	Name     string `json:"name"`
+	Pattern  string `json:"pattern"`
+	Received uint64 `json:"received"`
+	Dropped  uint64 `json:"dropped"`
+
+	handler FactHandler
+	queue   chan Fact
+}
+
+func (s *Subscription) Matches(topic string) bool {
+	matched, _ := path.Match(s.Pattern, topic)
+	return matched
+}
+
+func (s *Subscription) worker() {
+	for f := range s.queue {
+		s.handler(f)
+	}
+}
+
+// Bus dispatches the facts published by modules to the subscribed handlers,
+// each subscription has its own queue so that a slow handler never blocks the
+// publisher or the other handlers.
+type Bus struct {
+	sync.RWMutex
+
+	seq    uint64
+	subs   []*Subscription
+	topics map[string]uint64
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs:   make([]*Subscription, 0),
+		topics: make(map[string]uint64),
+	}
+}
+
+// Subscribe registers the handler for the topics matching the pattern, such as
+// "credential" or "credential.*", and returns the subscription id.
+func (b *Bus) Subscribe(name string, pattern string, handler FactHandler) (uint64, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, fmt.Errorf("invalid topic pattern '%s': %v", pattern, err)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.seq++
+	sub := &Subscription{
+		ID:      b.seq,
+		Name:    name,
+		Pattern: pattern,
+		handler: handler,
+		queue:   make(chan Fact, factsQueueSize),
+	}
+	b.subs = append(b.subs, sub)
+
+	go sub.worker()
+
+	return sub.ID, nil
+}
+
+func (b *Bus) Unsubscribe(id uint64) {
+	b.Lock()
+	defer b.Unlock()
+
+	for i, sub := range b.subs {
+		if sub.ID == id {
+			close(sub.queue)
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *Bus) publish(topic string, source string, depth int, data interface{}) error {
+	if topic == "" {
+		return fmt.Errorf("empty topic")
+	} else if depth > MaxFactDepth {
+		return fmt.Errorf("fact %s from %s dropped, more than %d derivations", topic, source, MaxFactDepth)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.seq++
+	b.topics[topic]++
+	f := Fact{
+		ID:     b.seq,
+		Topic:  topic,
+		Source: source,
+		Time:   time.Now(),
+		Depth:  depth,
+		Data:   data,
+	}
+
+	for _, sub := range b.subs {
+		if sub.Matches(topic) {
+			select {
+			case sub.queue <- f:
+				atomic.AddUint64(&sub.Received, 1)
+			default:
+				atomic.AddUint64(&sub.Dropped, 1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Publish sends a new fact to the subscribers of its topic.
+func (b *Bus) Publish(topic string, source string, data interface{}) error {
+	return b.publish(topic, source, 0, data)
+}
+
+// Derive publishes a fact obtained from the parent one.
+func (b *Bus) Derive(parent Fact, topic string, source string, data interface{}) error {
+	return b.publish(topic, source, parent.Depth+1, data)
+}
+
+// Subscriptions returns a copy of the active subscriptions.
+func (b *Bus) Subscriptions() []Subscription {
+	b.RLock()
+	defer b.RUnlock()
+
+	list := make([]Subscription, 0, len(b.subs))
+	for _, sub := range b.subs {
+		list = append(list, Subscription{
+			ID:       sub.ID,
+			Name:     sub.Name,
+			Pattern:  sub.Pattern,
+			Received: atomic.LoadUint64(&sub.Received),
+			Dropped:  atomic.LoadUint64(&sub.Dropped),
+		})
+	}
+	return list
+}
+
+// Topics returns the topics published so far, sorted by name, and how many facts each one had.
+func (b *Bus) Topics() ([]string, map[string]uint64) {
+	b.RLock()
+	defer b.RUnlock()
+
+	names := make([]string, 0, len(b.topics))
+	counts := make(map[string]uint64)
+	for topic, n := range b.topics {
+		names = append(names, topic)
+		counts[topic] = n
+	}
+	sort.Strings(names)
+	return names, counts
+}
@@ -0,0 +1,46 @@
+package session
+
+import "time"
+
+// Topics of the facts published by the built-in modules, credentials are
+// published with the protocol appended, for instance "credential.ftp".
+const (
+	TopicCredential = "credential"
+	TopicHostname   = "hostname"
+	TopicOpenPort   = "port.open"
+	TopicDecrypted  = "wifi.decrypted"
+)
+
+// CredentialFact is a set of credentials seen or obtained for a service.
+type CredentialFact struct {
+	Client   string `json:"client"`
+	Server   string `json:"server"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// HostnameFact is a name learned for a host.
+type HostnameFact struct {
+	Address  string `json:"address"`
+	MAC      string `json:"mac"`
+	Hostname string `json:"hostname"`
+}
+
+// OpenPortFact is a port found open on a host.
+type OpenPortFact struct {
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service"`
+}
+
+// DecryptedFrameFact is an 802.11 data frame decrypted with a known key and
+// converted to an ethernet frame.
+type DecryptedFrameFact struct {
+	AP      string    `json:"ap"`
+	Station string    `json:"station"`
+	Time    time.Time `json:"time"`
+	Data    []byte    `json:"data"`
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func waitFact(t *testing.T, ch chan Fact) Fact {
+	select {
+	case f := <-ch:
+		return f
+	case <-time.After(time.Second):
+		t.Fatalf("fact not delivered")
+	}
+	return Fact{}
+}
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	creds := make(chan Fact, 10)
+	all := make(chan Fact, 10)
+	if _, err := bus.Subscribe("creds", TopicCredential+".*", func(f Fact) { creds <- f }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bus.Subscribe("all", "*", func(f Fact) { all <- f }); err != nil {
+		t.Fatal(err)
+	}
+
+	bus.Publish(TopicHostname, "test", HostnameFact{Hostname: "laptop"})
+	bus.Publish(TopicCredential+".ftp", "test", CredentialFact{Username: "user"})
+
+	if f := waitFact(t, all); f.Topic != TopicHostname {
+		t.Fatalf("unexpected fact %+v", f)
+	}
+	if f := waitFact(t, all); f.Topic != TopicCredential+".ftp" {
+		t.Fatalf("unexpected fact %+v", f)
+	}
+	if f := waitFact(t, creds); f.Data.(CredentialFact).Username != "user" || f.Source != "test" {
+		t.Fatalf("unexpected fact %+v", f)
+	}
+
+	select {
+	case f := <-creds:
+		t.Fatalf("unexpected fact %+v", f)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	names, counts := bus.Topics()
+	if len(names) != 2 || counts[TopicHostname] != 1 {
+		t.Fatalf("unexpected topics %v %v", names, counts)
+	}
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus()
+
+	id, _ := bus.Subscribe("test", "*", func(f Fact) {})
+	if subs := bus.Subscriptions(); len(subs) != 1 || subs[0].ID != id {
+		t.Fatalf("unexpected subscriptions %+v", subs)
+	}
+
+	bus.Unsubscribe(id)
+	if subs := bus.Subscriptions(); len(subs) != 0 {
+		t.Fatalf("unexpected subscriptions %+v", subs)
+	}
+	bus.Publish("topic", "test", nil)
+}
+
+func TestBus_Derive(t *testing.T) {
+	bus := NewBus()
+
+	if _, err := bus.Subscribe("test", "[", nil); err == nil {
+		t.Fatalf("expected an invalid pattern error")
+	}
+
+	parent := Fact{Depth: MaxFactDepth - 1}
+	if err := bus.Derive(parent, "topic", "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	parent.Depth = MaxFactDepth
+	if err := bus.Derive(parent, "topic", "test", nil); err == nil {
+		t.Fatalf("expected the fact to be dropped")
+	}
+}
@@ -12,22 +12,44 @@ import (
 )
 
 type Event struct {
-	Tag  string      `json:"tag"`
-	Time time.Time   `json:"time"`
-	Data interface{} `json:"data"`
+	// sequence number assigned by the pool, used as a cursor by clients
+	ID   uint64    `json:"id"`
+	Tag  string    `json:"tag"`
+	Time time.Time `json:"time"`
+	// version of the schema of the data, 0 if the event has no schema
+	Version int         `json:"version"`
+	Data    interface{} `json:"data"`
+	// where the event happened, if a gps fix was available
+	Position *Position `json:"position,omitempty"`
 }
 
+type Position struct {
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Altitude  float64   `json:"altitude"`
+	Updated   time.Time `json:"updated"`
+}
+
+// PositionSource returns the current position, or nil if it's not available.
+type PositionSource func() *Position
+
 type LogMessage struct {
 	Level   log.Verbosity
 	Message string
 }
 
 func NewEvent(tag string, data interface{}) Event {
-	return Event{
+	e := Event{
 		Tag:  tag,
 		Time: time.Now(),
 		Data: data,
 	}
+
+	if schema := EventSchemaFor(tag); schema != nil {
+		e.Version = schema.Version
+	}
+
+	return e
 }
 
 func (e Event) Label() string {
@@ -39,6 +61,12 @@ func (e Event) Label() string {
 
 type EventBus <-chan Event
 
+// EventSink receives every event synchronously and in sequence order, Push
+// is called with the pool locked so it must not block nor add events.
+type EventSink interface {
+	Push(e Event)
+}
+
 type PrintCallback func(format string, args ...interface{})
 
 type PrintWriter struct {
@@ -55,8 +83,11 @@ type EventPool struct {
 
 	debug     bool
 	silent    bool
+	seq       uint64
 	events    []Event
 	listeners []chan Event
+	sinks     []EventSink
+	position  PositionSource
 	printLock sync.Mutex
 	printCbs  []PrintCallback
 	Stdout    PrintWriter
@@ -118,6 +149,35 @@ func (p *EventPool) Unlisten(listener EventBus) {
 	}
 }
 
+// AddSink pushes the queued events to the sink, oldest first, and then every
+// new one as it's added.
+func (p *EventPool) AddSink(sink EventSink) {
+	p.Lock()
+	defer p.Unlock()
+
+	// Sorted reorders them by time
+	queued := append([]Event{}, p.events...)
+	sort.Slice(queued, func(i, j int) bool {
+		return queued[i].ID < queued[j].ID
+	})
+	for _, e := range queued {
+		sink.Push(e)
+	}
+	p.sinks = append(p.sinks, sink)
+}
+
+func (p *EventPool) RemoveSink(sink EventSink) {
+	p.Lock()
+	defer p.Unlock()
+
+	for i, s := range p.sinks {
+		if s == sink {
+			p.sinks = append(p.sinks[:i], p.sinks[i+1:]...)
+			return
+		}
+	}
+}
+
 func (p *EventPool) SetSilent(s bool) {
 	p.Lock()
 	defer p.Unlock()
@@ -130,13 +190,30 @@ func (p *EventPool) SetDebug(d bool) {
 	p.debug = d
 }
 
+// SetPositionSource sets the callback used to geotag the events, nil to disable it.
+func (p *EventPool) SetPositionSource(src PositionSource) {
+	p.Lock()
+	defer p.Unlock()
+	p.position = src
+}
+
 func (p *EventPool) Add(tag string, data interface{}) {
 	p.Lock()
 	defer p.Unlock()
 
 	e := NewEvent(tag, data)
+	p.seq++
+	e.ID = p.seq
+	// positions are already part of the gps events and pointless for logs
+	if p.position != nil && tag != "sys.log" && tag != "gps.new" {
+		e.Position = p.position()
+	}
 	p.events = append([]Event{e}, p.events...)
 
+	for _, s := range p.sinks {
+		s.Push(e)
+	}
+
 	// broadcast the event to every listener
 	for _, l := range p.listeners {
 		// do not block!
@@ -0,0 +1,253 @@
+package session
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+)
+
+// EventField is a field of the JSON payload of an event.
+type EventField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// EventSchema describes the payload of the events with a given tag, tags
+// ending with a dot describe a whole family of events like net.sniff.
+// The version is bumped every time a field is renamed, removed or changes
+// its type so that consumers can tell which payload they're dealing with.
+type EventSchema struct {
+	Tag         string       `json:"tag"`
+	Version     int          `json:"version"`
+	Description string       `json:"description"`
+	Type        string       `json:"type"`
+	Fields      []EventField `json:"fields,omitempty"`
+}
+
+var (
+	schemasLock = sync.RWMutex{}
+	schemas     = make(map[string]*EventSchema)
+
+	timeType      = reflect.TypeOf(time.Time{})
+	ipType        = reflect.TypeOf(net.IP{})
+	hwType        = reflect.TypeOf(net.HardwareAddr{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return "null"
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return "time"
+	case t == ipType || t == hwType:
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		// encoding/json encodes byte slices as base64
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return "any"
+}
+
+func structFields(t reflect.Type) []EventField {
+	fields := make([]EventField, 0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// embedded structs are flattened by encoding/json
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, structFields(ft)...)
+			continue
+		} else if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fields = append(fields, EventField{
+			Name:     name,
+			Type:     typeName(f.Type),
+			Optional: strings.Contains(tag, ",omitempty"),
+		})
+	}
+	return fields
+}
+
+// FieldsOf returns the fields of the JSON encoding of sample, objects with
+// a custom encoding need to be described field by field instead.
+func FieldsOf(sample interface{}) []EventField {
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		return nil
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType || reflect.PtrTo(t).Implements(marshalerType) {
+		return nil
+	}
+
+	return structFields(t)
+}
+
+// RegisterEventSchema registers the schema of the events with the given tag
+// using the fields of sample, replacing any previous definition.
+func RegisterEventSchema(tag string, version int, description string, sample interface{}) *EventSchema {
+	return RegisterEventFields(tag, version, description, typeName(reflect.TypeOf(sample)), FieldsOf(sample)...)
+}
+
+// RegisterEventFields registers the schema of the events with the given tag
+// with an explicit list of fields.
+func RegisterEventFields(tag string, version int, description string, kind string, fields ...EventField) *EventSchema {
+	schemasLock.Lock()
+	defer schemasLock.Unlock()
+
+	schema := &EventSchema{
+		Tag:         tag,
+		Version:     version,
+		Description: description,
+		Type:        kind,
+		Fields:      fields,
+	}
+	schemas[tag] = schema
+	return schema
+}
+
+// EventSchemaFor returns the schema of the events with the given tag or nil
+// if no schema has been registered for it.
+func EventSchemaFor(tag string) *EventSchema {
+	schemasLock.RLock()
+	defer schemasLock.RUnlock()
+
+	if schema, found := schemas[tag]; found {
+		return schema
+	}
+
+	var best *EventSchema
+	for prefix, schema := range schemas {
+		if strings.HasSuffix(prefix, ".") && strings.HasPrefix(tag, prefix) {
+			if best == nil || len(prefix) > len(best.Tag) {
+				best = schema
+			}
+		}
+	}
+	return best
+}
+
+// EventSchemas returns every registered schema sorted by tag.
+func EventSchemas() []*EventSchema {
+	schemasLock.RLock()
+	defer schemasLock.RUnlock()
+
+	list := make([]*EventSchema, 0, len(schemas))
+	for _, schema := range schemas {
+		list = append(list, schema)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Tag < list[j].Tag
+	})
+
+	return list
+}
+
+func init() {
+	RegisterEventSchema("sys.log", 1, "A log message.", LogMessage{})
+	RegisterEventFields("tick", 1, "Sent every ticker.period seconds by the ticker module.", "null")
+	RegisterEventFields("session.started", 1, "The session has been started.", "null")
+	RegisterEventFields("session.closing", 1, "The session is being closed.", "null")
+	RegisterEventSchema("session.operator.joined", 1, "An operator connected to the session.", OperatorEvent{})
+	RegisterEventSchema("session.operator.left", 1, "An operator disconnected from the session.", OperatorEvent{})
+	RegisterEventSchema("session.command", 1, "A command has been executed by an operator.", CommandEvent{})
+	RegisterEventFields("mod.started", 1, "A module has been started, the payload is its name.", "string")
+	RegisterEventFields("mod.stopped", 1, "A module has been stopped, the payload is its name.", "string")
+	RegisterEventSchema("session.limit", 1, "A resource limit has been exceeded by a module.", LimitEvent{})
+	RegisterEventSchema("gateway.change", 1, "The IPv4 or IPv6 default gateway changed.", GatewayChange{})
+
+	RegisterEventSchema("endpoint.new", 1, "A new host has been discovered on the network.", network.Endpoint{})
+	RegisterEventSchema("endpoint.lost", 1, "A host is not on the network anymore.", network.Endpoint{})
+	RegisterEventSchema("endpoint.changed", 1, "A known host got another IPv4 address or its mac, hostname or open ports changed.", network.EndpointChanged{})
+
+	// access points have a custom encoding with their clients
+	apFields := append(FieldsOf(network.Station{}),
+		EventField{Name: "clients", Type: "array"},
+		EventField{Name: "handshake", Type: "bool"},
+		EventField{Name: "psk", Type: "string", Optional: true})
+
+	RegisterEventFields("wifi.ap.new", 1, "A new WiFi access point has been discovered.", "object", apFields...)
+	RegisterEventFields("wifi.ap.lost", 1, "A WiFi access point is not in range anymore.", "object", apFields...)
+
+	bleFields := []EventField{
+		{Name: "last_seen", Type: "time"},
+		{Name: "name", Type: "string"},
+		{Name: "mac", Type: "string"},
+		{Name: "alias", Type: "string"},
+		{Name: "tags", Type: "array"},
+		{Name: "note", Type: "string"},
+		{Name: "vendor", Type: "string"},
+		{Name: "rssi", Type: "number"},
+		{Name: "connectable", Type: "bool"},
+		{Name: "flags", Type: "string"},
+		{Name: "services", Type: "array"},
+	}
+
+	RegisterEventFields("ble.device.new", 1, "A new BLE device has been discovered.", "object", bleFields...)
+	RegisterEventFields("ble.device.lost", 1, "A BLE device is not in range anymore.", "object", bleFields...)
+
+	hidFields := []EventField{
+		{Name: "last_seen", Type: "time"},
+		{Name: "type", Type: "string"},
+		{Name: "address", Type: "string"},
+		{Name: "alias", Type: "string"},
+		{Name: "channels", Type: "array"},
+		{Name: "payloads", Type: "array"},
+		{Name: "payloads_size", Type: "number"},
+	}
+
+	RegisterEventFields("hid.device.new", 1, "A new HID device has been discovered.", "object", hidFields...)
+	RegisterEventFields("hid.device.lost", 1, "A HID device is not in range anymore.", "object", hidFields...)
+}
@@ -0,0 +1,76 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+type schemaBase struct {
+	Seen time.Time `json:"seen"`
+}
+
+type schemaSample struct {
+	*schemaBase
+	Name    string            `json:"name"`
+	Port    uint16            `json:"port"`
+	Tags    []string          `json:"tags,omitempty"`
+	Raw     []byte            `json:"raw"`
+	Meta    map[string]string `json:"meta"`
+	Ignored int               `json:"-"`
+	NoTag   bool
+	private string
+}
+
+func TestFieldsOf(t *testing.T) {
+	expected := []EventField{
+		{Name: "seen", Type: "time"},
+		{Name: "name", Type: "string"},
+		{Name: "port", Type: "number"},
+		{Name: "tags", Type: "array", Optional: true},
+		{Name: "raw", Type: "string"},
+		{Name: "meta", Type: "object"},
+		{Name: "NoTag", Type: "bool"},
+	}
+
+	fields := FieldsOf(&schemaSample{})
+	if len(fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %+v", len(expected), fields)
+	}
+	for i, f := range fields {
+		if f != expected[i] {
+			t.Fatalf("expected field %d to be %+v, got %+v", i, expected[i], f)
+		}
+	}
+
+	if fields := FieldsOf("string"); fields != nil {
+		t.Fatalf("expected no fields for a string, got %+v", fields)
+	}
+}
+
+func TestEventSchemaFor(t *testing.T) {
+	RegisterEventSchema("schema.test.", 1, "family", schemaSample{})
+	RegisterEventSchema("schema.test.sub.", 2, "sub family", schemaSample{})
+	RegisterEventFields("schema.test.exact", 3, "exact", "string")
+
+	tests := map[string]int{
+		"schema.test.foo":     1,
+		"schema.test.sub.foo": 2,
+		"schema.test.exact":   3,
+		"schema.other":        0,
+	}
+
+	for tag, version := range tests {
+		schema := EventSchemaFor(tag)
+		if version == 0 {
+			if schema != nil {
+				t.Fatalf("expected no schema for %s, got %+v", tag, schema)
+			}
+		} else if schema == nil || schema.Version != version {
+			t.Fatalf("expected version %d for %s, got %+v", version, tag, schema)
+		}
+
+		if e := NewEvent(tag, nil); e.Version != version {
+			t.Fatalf("expected event %s to have version %d, got %d", tag, version, e.Version)
+		}
+	}
+}
@@ -299,3 +299,79 @@ func TestEventPool_Add(t *testing.T) {
 		})
 	}
 }
+
+func TestEventPool_AddIDs(t *testing.T) {
+	p := NewEventPool(false, false)
+	for i := 0; i < 3; i++ {
+		p.Add("tag", i)
+	}
+
+	// ids keep growing after the pool is cleared
+	p.Clear()
+	p.Add("tag", 3)
+
+	if got := p.Sorted(); len(got) != 1 || got[0].ID != 4 {
+		t.Fatalf("expected a single event with id 4, got %+v", got)
+	}
+}
+
+func TestEventPool_Position(t *testing.T) {
+	p := NewEventPool(false, false)
+	p.SetPositionSource(func() *Position {
+		return &Position{Latitude: 45.4, Longitude: 9.1}
+	})
+
+	p.Add("wifi.ap.new", nil)
+	p.Add("sys.log", nil)
+	p.SetPositionSource(nil)
+	p.Add("ble.device.new", nil)
+
+	for _, e := range p.Sorted() {
+		if tagged := e.Position != nil; tagged != (e.Tag == "wifi.ap.new") {
+			t.Fatalf("unexpected position %+v for %s", e.Position, e.Tag)
+		}
+	}
+}
+
+type idSink struct {
+	ids []uint64
+}
+
+func (s *idSink) Push(e Event) {
+	s.ids = append(s.ids, e.ID)
+}
+
+func TestEventPool_Sink(t *testing.T) {
+	p := NewEventPool(false, false)
+	p.Add("tag", 0)
+	p.Add("tag", 1)
+	// must not change the order the queued events are pushed in
+	p.Sorted()
+
+	sink := &idSink{}
+	p.AddSink(sink)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				p.Add("tag", j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	p.RemoveSink(sink)
+	p.Add("tag", nil)
+
+	if len(sink.ids) != 802 {
+		t.Fatalf("expected 802 events, got %d", len(sink.ids))
+	}
+	for i, id := range sink.ids {
+		if id != uint64(i+1) {
+			t.Fatalf("expected event %d at position %d, got %d", i+1, i, id)
+		}
+	}
+}
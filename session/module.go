@@ -22,6 +22,10 @@ type Module interface {
 
 	Extra() map[string]interface{}
 	Required() []string
+	Conflicting() []string
+	Claimed() []string
+	SavedTo() []string
+	Children() int
 	Running() bool
 	Start() error
 	Stop() error
@@ -63,10 +67,15 @@ type SessionModule struct {
 	StatusLock *sync.RWMutex
 	State      *sync.Map
 
-	handlers []ModuleHandler
-	params   map[string]*ModuleParam
-	requires []string
-	tag      string
+	handlers      []ModuleHandler
+	params        map[string]*ModuleParam
+	requires      []string
+	conflicts     []string
+	claims        []string
+	prerequisites []Prerequisite
+	saves         []string
+	children      *sync.Map
+	tag           string
 }
 
 func AsTag(name string) string {
@@ -81,10 +90,14 @@ func NewSessionModule(name string, s *Session) SessionModule {
 		StatusLock: &sync.RWMutex{},
 		State:      &sync.Map{},
 
-		requires: make([]string, 0),
-		handlers: make([]ModuleHandler, 0),
-		params:   make(map[string]*ModuleParam),
-		tag:      AsTag(name),
+		requires:      make([]string, 0),
+		conflicts:     make([]string, 0),
+		claims:        make([]string, 0),
+		prerequisites: make([]Prerequisite, 0),
+		handlers:      make([]ModuleHandler, 0),
+		params:        make(map[string]*ModuleParam),
+		children:      &sync.Map{},
+		tag:           AsTag(name),
 	}
 
 	return m
@@ -259,6 +272,10 @@ func (m *SessionModule) SetRunning(running bool, cb func()) error {
 	}
 
 	if running == true {
+		if err := m.CheckDependencies(); err != nil {
+			return err
+		}
+
 		for _, modName := range m.Required() {
 			if m.Session.IsOn(modName) == false {
 				m.Info("starting %s as a requirement for %s", modName, m.Name)
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"fmt"
+)
+
+// Prerequisite is something a module needs besides other modules, like a
+// firewall backend for the redirections or an interface in monitor mode.
+type Prerequisite struct {
+	Name  string
+	Check func() error
+}
+
+// Needs declares a prerequisite checked every time the module is started.
+func (m *SessionModule) Needs(what string, check func() error) {
+	m.prerequisites = append(m.prerequisites, Prerequisite{
+		Name:  what,
+		Check: check,
+	})
+}
+
+// Conflicts declares a module that can't run along with this one.
+func (m *SessionModule) Conflicts(modName string) {
+	m.conflicts = append(m.conflicts, modName)
+}
+
+func (m *SessionModule) Conflicting() []string {
+	return m.conflicts
+}
+
+// Claim sets the resources the module is going to use exclusively once
+// started, like an NFQUEUE number or a redirected port, usually from
+// Configure since they depend on the module parameters.
+func (m *SessionModule) Claim(resources ...string) {
+	m.claims = resources
+}
+
+func (m *SessionModule) Claimed() []string {
+	return m.claims
+}
+
+func contains(list []string, what string) bool {
+	for _, item := range list {
+		if item == what {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConflicts returns an error if a module with the given conflicts and
+// claimed resources can't be started because of one of the running modules.
+func checkConflicts(name string, conflicts []string, claims []string, running []Module) error {
+	for _, other := range running {
+		if other.Name() == name {
+			continue
+		} else if contains(conflicts, other.Name()) || contains(other.Conflicting(), name) {
+			return fmt.Errorf("%s can't run along with %s, stop it first", name, other.Name())
+		}
+
+		for _, resource := range claims {
+			if contains(other.Claimed(), resource) {
+				return fmt.Errorf("%s is already using %s, stop it first or change the %s configuration", other.Name(), resource, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *SessionModule) runningModules() []Module {
+	running := make([]Module, 0)
+	for _, other := range m.Session.Modules {
+		if other.Running() {
+			running = append(running, other)
+		}
+	}
+	return running
+}
+
+// CheckDependencies returns an error if the module conflicts with a running
+// module or if any of its prerequisites is missing. It's called when the
+// module is started, but modules that apply system changes while being
+// configured should call it before doing so.
+func (m *SessionModule) CheckDependencies() error {
+	if err := checkConflicts(m.Name, m.conflicts, m.claims, m.runningModules()); err != nil {
+		return err
+	}
+
+	return m.CheckPrerequisites()
+}
+
+// CheckPrerequisites returns an error if any of the prerequisites of the module is missing.
+func (m *SessionModule) CheckPrerequisites() error {
+	for _, p := range m.prerequisites {
+		if err := p.Check(); err != nil {
+			return fmt.Errorf("%s requires %s: %v", m.Name, p.Name, err)
+		}
+	}
+	return nil
+}
+
+// NotSupported declares the module as a stub for a platform it doesn't support.
+func (m *SessionModule) NotSupported() {
+	m.Needs("a supported OS", func() error {
+		return ErrNotSupported
+	})
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"testing"
+)
+
+type dummyModule struct {
+	SessionModule
+}
+
+func newDummyModule(name string) *dummyModule {
+	return &dummyModule{
+		SessionModule: NewSessionModule(name, nil),
+	}
+}
+
+func (d *dummyModule) Name() string        { return d.SessionModule.Name }
+func (d *dummyModule) Description() string { return "" }
+func (d *dummyModule) Author() string      { return "" }
+func (d *dummyModule) Start() error        { return nil }
+func (d *dummyModule) Stop() error         { return nil }
+
+func TestCheckConflicts(t *testing.T) {
+	a := newDummyModule("a")
+	b := newDummyModule("b")
+	c := newDummyModule("c")
+
+	a.Conflicts("b")
+
+	// declared by the module being started
+	if err := checkConflicts("a", a.Conflicting(), a.Claimed(), []Module{b}); err == nil {
+		t.Fatalf("expected a conflict between a and b")
+	}
+	// or by the running one
+	if err := checkConflicts("b", b.Conflicting(), b.Claimed(), []Module{a}); err == nil {
+		t.Fatalf("expected a conflict between b and a")
+	}
+	if err := checkConflicts("c", c.Conflicting(), c.Claimed(), []Module{a, b}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckClaims(t *testing.T) {
+	a := newDummyModule("a")
+	b := newDummyModule("b")
+
+	a.Claim("NFQUEUE 0")
+	b.Claim("NFQUEUE 1")
+
+	if err := checkConflicts("b", b.Conflicting(), b.Claimed(), []Module{a}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b.Claim("NFQUEUE 1", "NFQUEUE 0")
+	if err := checkConflicts("b", b.Conflicting(), b.Claimed(), []Module{a}); err == nil {
+		t.Fatalf("expected a conflict on NFQUEUE 0")
+	}
+
+	// a module never conflicts with itself
+	if err := checkConflicts("a", a.Conflicting(), a.Claimed(), []Module{a}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
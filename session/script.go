@@ -11,13 +11,22 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 // require("telegram.js")
 var requireParser = regexp.MustCompile(`(?msi)^\s*require\s*\(\s*["']([^"']+)["']\s*\);?\s*$`)
 
+// scriptsLock serializes the compilation of the scripts, the functions bound
+// to the script being compiled are handed to its vm through plugin.Defines.
+var scriptsLock = sync.Mutex{}
+
 type Script struct {
 	*plugin.Plugin
+
+	subsLock sync.Mutex
+	subs     []uint64
+	unloaded bool
 }
 
 // yo! we're doing c-like preprocessing on a javascript file from go :D
@@ -60,6 +69,8 @@ func preprocess(basePath string, code string, level int) (string, error) {
 		raw, err := ioutil.ReadFile(fileName)
 		if err != nil {
 			return "", fmt.Errorf("%s: %v", fileName, err)
+		} else if err = caplets.VerifyData(raw, fileName+caplets.SignatureSuffix); err != nil {
+			return "", err
 		}
 
 		if includedBody, err := preprocess(filepath.Dir(fileName), string(raw), level+1); err != nil {
@@ -76,18 +87,66 @@ func LoadScript(fileName string) (*Script, error) {
 	raw, err := ioutil.ReadFile(fileName)
 	if err != nil {
 		return nil, err
+	} else if err = caplets.VerifyData(raw, fileName+caplets.SignatureSuffix); err != nil {
+		return nil, err
 	}
 
 	basePath := filepath.Dir(fileName)
 	if code, err := preprocess(basePath, string(raw), 0); err != nil {
 		return nil, err
-	} else if p, err := plugin.Parse(code); err != nil {
+	} else if s, err := parseScript(code); err != nil {
+		return nil, err
+	} else {
+		s.Path = fileName
+		s.Name = strings.Replace(basePath, ".js", "", -1)
+		return s, nil
+	}
+}
+
+// LoadPlugin loads the module plugin at fileName, unlike LoadScript it's
+// neither preprocessed nor verified.
+func LoadPlugin(fileName string) (*Script, error) {
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	} else if s, err := parseScript(string(raw)); err != nil {
 		return nil, err
 	} else {
-		p.Path = fileName
-		p.Name = strings.Replace(basePath, ".js", "", -1)
-		return &Script{
-			Plugin: p,
-		}, nil
+		s.Path = fileName
+		s.Name = strings.Replace(filepath.Base(fileName), ".js", "", -1)
+		return s, nil
+	}
+}
+
+func parseScript(code string) (*Script, error) {
+	scriptsLock.Lock()
+	defer scriptsLock.Unlock()
+
+	s := &Script{}
+	plugin.Defines["onFact"] = s.jsOnFactFunc
+	defer delete(plugin.Defines, "onFact")
+
+	p, err := plugin.Parse(code)
+	if err != nil {
+		s.Unload()
+		return nil, err
+	}
+
+	s.subsLock.Lock()
+	defer s.subsLock.Unlock()
+	s.Plugin = p
+	return s, nil
+}
+
+// Unload removes the fact subscriptions of the script, the ones it
+// tries to add afterwards are refused.
+func (s *Script) Unload() {
+	s.subsLock.Lock()
+	defer s.subsLock.Unlock()
+
+	for _, id := range s.subs {
+		I.Bus.Unsubscribe(id)
 	}
+	s.subs = nil
+	s.unloaded = true
 }
@@ -79,3 +79,100 @@ func jsOnEventFunc(call otto.FunctionCall) otto.Value {
 
 	return js.NullValue
 }
+
+// some objects don't do well with js, so convert them to a generic map
+func jsOpaque(v interface{}) (interface{}, error) {
+	var opaque interface{}
+	if raw, err := json.Marshal(v); err != nil {
+		return nil, err
+	} else if err = json.Unmarshal(raw, &opaque); err != nil {
+		return nil, err
+	}
+	return opaque, nil
+}
+
+// onFact(pattern, callback)
+func (s *Script) jsOnFactFunc(call otto.FunctionCall) otto.Value {
+	argv := call.ArgumentList
+	if len(argv) != 2 {
+		return js.ReportError("expected two arguments (topic_pattern, callback), got %d", len(argv))
+	} else if argv[0].IsString() == false {
+		return js.ReportError("first argument must be a string")
+	} else if argv[1].IsFunction() == false {
+		return js.ReportError("second argument must be a function")
+	}
+
+	s.subsLock.Lock()
+	defer s.subsLock.Unlock()
+
+	if s.unloaded {
+		return js.ReportError("script has been unloaded")
+	}
+
+	cb := argv[1]
+	id, err := I.Bus.Subscribe("js", argv[0].String(), func(f Fact) {
+		s.subsLock.Lock()
+		p := s.Plugin
+		s.subsLock.Unlock()
+		// the vm is still running the top level code of the script
+		if p == nil {
+			return
+		}
+
+		opaque, err := jsOpaque(f)
+		if err != nil {
+			I.Events.Log(log.ERROR, "error serializing fact %s: %v", f.Topic, err)
+			return
+		}
+
+		// lock vm
+		p.Lock()
+		if _, err := cb.Call(otto.NullValue(), opaque); err != nil {
+			I.Events.Log(log.ERROR, "error dispatching fact %s: %v", f.Topic, err)
+		}
+		p.Unlock()
+	})
+	if err != nil {
+		return js.ReportError("%v", err)
+	}
+	s.subs = append(s.subs, id)
+
+	return js.NullValue
+}
+
+// publish(topic, data) or publish(topic, data, parent_fact)
+func jsPublishFunc(call otto.FunctionCall) otto.Value {
+	argv := call.ArgumentList
+	argc := len(argv)
+	if argc != 2 && argc != 3 {
+		return js.ReportError("expected two or three arguments (topic, data, parent), got %d", argc)
+	} else if argv[0].IsString() == false {
+		return js.ReportError("first argument must be a string")
+	}
+
+	data, err := argv[1].Export()
+	if err != nil {
+		return js.ReportError("could not export the fact data: %v", err)
+	}
+
+	parent := Fact{}
+	if argc == 3 {
+		if argv[2].IsObject() == false {
+			return js.ReportError("third argument must be a fact")
+		}
+		// only the depth matters, to stop derivation loops
+		if depth, err := argv[2].Object().Get("depth"); err == nil {
+			if n, err := depth.ToInteger(); err == nil {
+				parent.Depth = int(n)
+			}
+		}
+		err = I.Bus.Derive(parent, argv[0].String(), "js", data)
+	} else {
+		err = I.Bus.Publish(argv[0].String(), "js", data)
+	}
+
+	if err != nil {
+		return js.ReportError("%v", err)
+	}
+	return js.NullValue
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScriptUnloadFacts(t *testing.T) {
+	prev := I
+	I = &Session{Bus: NewBus()}
+	defer func() { I = prev }()
+
+	dir, err := ioutil.TempDir("", "script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "facts.js")
+	code := `var facts = 0; onFact("host.*", function(f) { facts++; });`
+	if err := ioutil.WriteFile(fileName, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadPlugin(fileName)
+	if err != nil {
+		t.Fatal(err)
+	} else if s.Name != "facts" {
+		t.Fatalf("unexpected name %s", s.Name)
+	} else if subs := I.Bus.Subscriptions(); len(subs) != 1 {
+		t.Fatalf("expected one subscription, got %d", len(subs))
+	}
+
+	s.Unload()
+	if subs := I.Bus.Subscriptions(); len(subs) != 0 {
+		t.Fatalf("expected no subscriptions after unload, got %d", len(subs))
+	}
+}
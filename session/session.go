@@ -82,16 +82,21 @@ type Session struct {
 	GPS       GPS
 	Modules   ModuleList
 	Aliases   *data.UnsortedKV
+	Tags      *data.UnsortedKV
+	Notes     *data.UnsortedKV
 
 	Input            *readline.Instance
 	Prompt           Prompt
 	CoreHandlers     []CommandHandler
 	Events           *EventPool
+	Bus              *Bus
 	EventsIgnoreList *EventsIgnoreList
 	UnkCmdCallback   UnknownCommandCallback
 	Firewall         firewall.FirewallManager
 
-	script *Script
+	script    *Script
+	operators *operatorList
+	watchdog  *watchdog
 }
 
 func New() (*Session, error) {
@@ -115,8 +120,12 @@ func New() (*Session, error) {
 		CoreHandlers:     make([]CommandHandler, 0),
 		Modules:          make([]Module, 0),
 		Events:           nil,
+		Bus:              NewBus(),
 		EventsIgnoreList: NewEventsIgnoreList(),
 		UnkCmdCallback:   nil,
+
+		operators: newOperatorList(),
+		watchdog:  newWatchdog(),
 	}
 
 	if *s.Options.CpuProfile != "" {
@@ -133,6 +142,10 @@ func New() (*Session, error) {
 
 	if s.Aliases, err = data.NewUnsortedKV(aliasesFileName, data.FlushOnEdit); err != nil {
 		return nil, err
+	} else if s.Tags, err = data.NewUnsortedKV(tagsFileName, data.FlushOnEdit); err != nil {
+		return nil, err
+	} else if s.Notes, err = data.NewUnsortedKV(notesFileName, data.FlushOnEdit); err != nil {
+		return nil, err
 	}
 
 	s.Events = NewEventPool(*s.Options.Debug, *s.Options.Silent)
@@ -183,6 +196,10 @@ func (s *Session) Close() {
 		}
 	}
 
+	if s.script != nil {
+		s.script.Unload()
+	}
+
 	s.Firewall.Restore()
 
 	if *s.Options.EnvFile != "" {
@@ -233,6 +250,12 @@ func (s *Session) Start() error {
 		}
 	}
 
+	caplets.RequireSigned = *s.Options.RequireSigned
+	if err = caplets.LoadTrustedKeys(*s.Options.TrustedKeys); err != nil {
+		return err
+	}
+	log.Debug("loaded %d trusted signing keys", len(caplets.TrustedKeys()))
+
 	if s.Interface, err = network.FindInterface(*s.Options.InterfaceName); err != nil {
 		return err
 	}
@@ -263,6 +286,9 @@ func (s *Session) Start() error {
 		go s.routeMon()
 	}
 
+	s.Interface.Tags, s.Interface.Note = s.TagsOf(s.Interface.HwAddress)
+	s.Gateway.Tags, s.Gateway.Note = s.TagsOf(s.Gateway.HwAddress)
+
 	s.Firewall = firewall.Make(s.Interface)
 
 	s.HID = network.NewHID(s.Aliases, func(dev *network.HIDDevice) {
@@ -272,22 +298,20 @@ func (s *Session) Start() error {
 	})
 
 	s.BLE = network.NewBLE(s.Aliases, func(dev *network.BLEDevice) {
+		dev.Tags, dev.Note = s.TagsOf(dev.MAC())
 		s.Events.Add("ble.device.new", dev)
 	}, func(dev *network.BLEDevice) {
 		s.Events.Add("ble.device.lost", dev)
 	})
 
 	s.WiFi = network.NewWiFi(s.Interface, s.Aliases, func(ap *network.AccessPoint) {
+		ap.Tags, ap.Note = s.TagsOf(ap.HwAddress)
 		s.Events.Add("wifi.ap.new", ap)
 	}, func(ap *network.AccessPoint) {
 		s.Events.Add("wifi.ap.lost", ap)
 	})
 
-	s.Lan = network.NewLAN(s.Interface, s.Gateway, s.Aliases, func(e *network.Endpoint) {
-		s.Events.Add("endpoint.new", e)
-	}, func(e *network.Endpoint) {
-		s.Events.Add("endpoint.lost", e)
-	})
+	s.Lan = s.newLAN()
 
 	s.setupEnv()
 
@@ -302,6 +326,8 @@ func (s *Session) Start() error {
 
 	s.startNetMon()
 
+	go s.watchdogWorker()
+
 	if *s.Options.Debug {
 		s.Events.Add("session.started", nil)
 	}
@@ -311,6 +337,7 @@ func (s *Session) Start() error {
 	plugin.Defines["env"] = jsEnvFunc
 	plugin.Defines["run"] = jsRunFunc
 	plugin.Defines["onEvent"] = jsOnEventFunc
+	plugin.Defines["publish"] = jsPublishFunc
 	plugin.Defines["session"] = s
 
 	// load the script here so the session and its internal objects are ready
@@ -324,6 +351,27 @@ func (s *Session) Start() error {
 	return nil
 }
 
+// the hosts of the network of the session interface
+func (s *Session) newLAN() *network.LAN {
+	return network.NewLAN(s.Interface, s.Gateway, s.Aliases, func(e *network.Endpoint) {
+		e.Tags, e.Note = s.TagsOf(e.HwAddress)
+		s.Events.Add("endpoint.new", e)
+		s.publishHostname(e)
+	}, func(e *network.Endpoint) {
+		s.Events.Add("endpoint.lost", e)
+	}, func(e *network.Endpoint, changes []network.EndpointChange) {
+		for _, c := range changes {
+			if c.Field == "hostname" {
+				s.publishHostname(e)
+			}
+		}
+		s.Events.Add("endpoint.changed", network.EndpointChanged{
+			Endpoint: e,
+			Changes:  changes,
+		})
+	})
+}
+
 func (s *Session) Skip(ip net.IP) bool {
 	if ip.IsLoopback() {
 		return true
@@ -445,7 +493,7 @@ func (s *Session) Run(line string) error {
 	for _, m := range s.Modules {
 		for _, h := range m.Handlers() {
 			if parsed, args := h.Parse(line); parsed {
-				return h.Exec(args)
+				return execLabeled(m, h, args)
 			}
 		}
 	}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/str"
+	"github.com/evilsocket/islazy/tui"
+)
+
+// publish the names learned for the hosts of the network
+func (s *Session) publishHostname(e *network.Endpoint) {
+	if e.Hostname != "" {
+		s.Bus.Publish(TopicHostname, "net.recon", HostnameFact{
+			Address:  e.IpAddress,
+			MAC:      e.HwAddress,
+			Hostname: e.Hostname,
+		})
+	}
+}
+
+func (s *Session) busShowHandler(args []string, sess *Session) error {
+	topics, counts := s.Bus.Topics()
+	subs := s.Bus.Subscriptions()
+
+	if len(topics) == 0 && len(subs) == 0 {
+		fmt.Fprintf(s.Events.Stdout, "\nno facts published and no subscriptions yet\n\n")
+		return nil
+	}
+
+	if len(topics) > 0 {
+		rows := [][]string{}
+		for _, topic := range topics {
+			rows = append(rows, []string{tui.Yellow(topic), strconv.FormatUint(counts[topic], 10)})
+		}
+		tui.Table(s.Events.Stdout, []string{"Topic", "Facts"}, rows)
+	}
+
+	if len(subs) > 0 {
+		rows := [][]string{}
+		for _, sub := range subs {
+			dropped := strconv.FormatUint(sub.Dropped, 10)
+			if sub.Dropped > 0 {
+				dropped = tui.Red(dropped)
+			}
+			rows = append(rows, []string{
+				strconv.FormatUint(sub.ID, 10),
+				tui.Bold(sub.Name),
+				tui.Yellow(sub.Pattern),
+				strconv.FormatUint(sub.Received, 10),
+				dropped,
+			})
+		}
+		tui.Table(s.Events.Stdout, []string{"ID", "Subscriber", "Pattern", "Received", "Dropped"}, rows)
+	}
+
+	s.Refresh()
+	return nil
+}
+
+func (s *Session) busPublishHandler(args []string, sess *Session) error {
+	// anything that is not JSON is published as a string
+	var data interface{}
+	raw := str.Trim(args[1])
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		data = raw
+	}
+	return s.Bus.Publish(args[0], "operator", data)
+}
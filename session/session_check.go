@@ -0,0 +1,192 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bettercap/bettercap/caplets"
+
+	"github.com/evilsocket/islazy/str"
+)
+
+var reCapletArg = regexp.MustCompile(`\$\d+`)
+
+// CapletIssue is a problem found while checking a caplet without running it.
+type CapletIssue struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Command string `json:"command"`
+	Warning bool   `json:"warning"`
+	Message string `json:"message"`
+}
+
+type capletChecker struct {
+	s *Session
+	// variables set or read by the caplets checked so far
+	defined map[string]bool
+	// caplets already checked, so that include loops are only walked once
+	visited map[string]bool
+	issues  []CapletIssue
+}
+
+// CheckCaplet parses the caplet and validates each of its commands, the values of
+// the parameters it sets and the availability of the modules it uses, including
+// the caplets it runs, without executing anything.
+func (s *Session) CheckCaplet(name string) ([]CapletIssue, error) {
+	caplet, err := caplets.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &capletChecker{
+		s:       s,
+		defined: make(map[string]bool),
+		visited: make(map[string]bool),
+		issues:  make([]CapletIssue, 0),
+	}
+	c.checkCaplet(caplet)
+
+	return c.issues, nil
+}
+
+func (c *capletChecker) report(path string, line int, command string, warning bool, format string, args ...interface{}) {
+	c.issues = append(c.issues, CapletIssue{
+		Path:    path,
+		Line:    line,
+		Command: command,
+		Warning: warning,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *capletChecker) checkCaplet(caplet *caplets.Caplet) {
+	if c.visited[caplet.Path] {
+		return
+	}
+	c.visited[caplet.Path] = true
+
+	for i, line := range caplet.Code {
+		if line = str.Trim(line); line != "" && line[0] != '#' {
+			c.checkCommand(caplet.Path, i+1, line)
+		}
+	}
+}
+
+// loadCaplet resolves the name of a caplet from the folder of the one using it,
+// the working directory it would be evaluated from, before the load paths.
+func (c *capletChecker) loadCaplet(parent string, name string) (*caplets.Caplet, error) {
+	if !filepath.IsAbs(name) {
+		if path, err := filepath.Abs(filepath.Join(filepath.Dir(parent), name)); err == nil {
+			if caplet, err := caplets.Load(path); err == nil {
+				return caplet, nil
+			}
+		}
+	}
+	return caplets.Load(name)
+}
+
+func (c *capletChecker) checkCommand(path string, num int, line string) {
+	line = reCmdSpaceCleaner.ReplaceAllString(line, "$1 $2")
+
+	for _, m := range reEnvVarCapture.FindAllStringSubmatch(line, -1) {
+		if found, _ := c.s.Env.Get(m[1]); !found && !c.defined[m[1]] {
+			c.report(path, num, line, false, "variable '%s' is not defined", m[1])
+		}
+	}
+
+	for _, h := range c.s.CoreHandlers {
+		if parsed, args := h.Parse(line); parsed {
+			switch h.Name {
+			case "set NAME VALUE":
+				c.checkSet(path, num, line, args[0], args[1])
+			case "read VARIABLE PROMPT":
+				c.defined[args[0]] = true
+			case "include CAPLET":
+				if reCapletArg.MatchString(args[0]) || reEnvVarCapture.MatchString(args[0]) {
+					c.report(path, num, line, true, "caplet name depends on arguments or variables, not checked")
+				} else if caplet, err := c.loadCaplet(path, args[0]); err != nil {
+					c.report(path, num, line, false, "%v", err)
+				} else {
+					c.checkCaplet(caplet)
+				}
+			}
+			return
+		}
+	}
+
+	for _, m := range c.s.Modules {
+		for _, h := range m.Handlers() {
+			if parsed, _ := h.Parse(line); parsed {
+				c.checkModule(path, num, line, m)
+				return
+			}
+		}
+	}
+
+	if caplet, err := c.loadCaplet(path, strings.Split(line, " ")[0]); err == nil {
+		c.checkCaplet(caplet)
+		return
+	}
+
+	// modules which are only stubs on this platform have no handlers
+	if m := c.moduleOf(strings.Split(line, " ")[0]); m != nil {
+		if !c.checkModule(path, num, line, m) {
+			return
+		}
+	}
+
+	c.report(path, num, line, false, "unknown or invalid command")
+}
+
+// returns false if the module can't be used on this system
+func (c *capletChecker) checkModule(path string, num int, line string, m Module) bool {
+	if p, ok := m.(interface{ CheckPrerequisites() error }); ok {
+		if err := p.CheckPrerequisites(); err != nil {
+			c.report(path, num, line, false, "%v", err)
+			return false
+		}
+	}
+	return true
+}
+
+// the module the parameter or command belongs to, by the longest name prefix
+func (c *capletChecker) moduleOf(name string) Module {
+	var found Module
+	for _, m := range c.s.Modules {
+		if name == m.Name() || strings.HasPrefix(name, m.Name()+".") {
+			if found == nil || len(m.Name()) > len(found.Name()) {
+				found = m
+			}
+		}
+	}
+	return found
+}
+
+func (c *capletChecker) checkSet(path string, num int, line string, name string, value string) {
+	c.defined[name] = true
+
+	for _, m := range c.s.Modules {
+		if p, found := m.Parameters()[name]; found {
+			if value == "\"\"" || value == "''" {
+				value = ""
+			}
+			// the actual value is only known at runtime
+			if reCapletArg.MatchString(value) || reEnvVarCapture.MatchString(value) {
+				return
+			} else if err, _ := p.validate(p.parse(c.s, value)); err != nil {
+				c.report(path, num, line, false, "%v", err)
+			}
+			return
+		}
+	}
+
+	if found, _ := c.s.Env.Get(name); found {
+		return
+	} else if m := c.moduleOf(name); m != nil {
+		if c.checkModule(path, num, line, m) {
+			c.report(path, num, line, true, "%s is not a parameter of %s", name, m.Name())
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testCheckCaplet(t *testing.T, code string) []CapletIssue {
+	dir, err := ioutil.TempDir("", "caplet-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "sub.cap")
+	if err := ioutil.WriteFile(sub, []byte("set foo.port nope\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	main := filepath.Join(dir, "main.cap")
+	code = strings.Replace(code, "SUB", sub, -1)
+	if err := ioutil.WriteFile(main, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env, _ := NewEnvironment("")
+	s := &Session{
+		Env:          env,
+		Modules:      make(ModuleList, 0),
+		CoreHandlers: make([]CommandHandler, 0),
+	}
+	s.registerCoreHandlers()
+
+	// parameters are registered in the session environment
+	foo := &dummyModule{SessionModule: NewSessionModule("foo", s)}
+	foo.AddParam(NewIntParameter("foo.port", "80", "Port."))
+	foo.AddHandler(NewModuleHandler("foo on", "", "Start foo.", nil))
+
+	bar := newDummyModule("bar")
+	bar.NotSupported()
+
+	s.Modules = append(s.Modules, foo, bar)
+
+	issues, err := s.CheckCaplet(main)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return issues
+}
+
+func TestCheckCapletValid(t *testing.T) {
+	issues := testCheckCaplet(t, `# comment
+set foo.port 8080
+set my.var hello
+set foo.port {env.my.var}
+foo on
+`)
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+}
+
+func TestCheckCapletIssues(t *testing.T) {
+	issues := testCheckCaplet(t, `set foo.port abc
+set foo.prot 8080
+foo onn
+bar on
+set x {env.undefined}
+include SUB
+`)
+
+	expected := []struct {
+		line    int
+		warning bool
+	}{
+		{1, false},
+		{2, true},
+		{3, false},
+		{4, false},
+		{5, false},
+		{1, false},
+	}
+
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, exp := range expected {
+		if issues[i].Line != exp.line || issues[i].Warning != exp.warning {
+			t.Fatalf("unexpected issue %d: %v", i, issues[i])
+		}
+	}
+
+	if !strings.HasSuffix(issues[5].Path, "sub.cap") {
+		t.Fatalf("expected the last issue to be in the included caplet, got %s", issues[5].Path)
+	}
+}
+
+func TestCheckCapletRelativeInclude(t *testing.T) {
+	issues := testCheckCaplet(t, "include sub.cap\n")
+	if len(issues) != 1 || issues[0].Line != 1 || !strings.HasSuffix(issues[0].Path, "sub.cap") {
+		t.Fatalf("expected the issue of the included caplet, got %v", issues)
+	}
+}
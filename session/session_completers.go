@@ -65,19 +65,25 @@ func (s *Session) EventsCompleter(prefix string) []string {
 		"sys.log",
 		"session.started",
 		"session.closing",
+		"session.command",
+		"session.operator.joined",
+		"session.operator.left",
 		"update.available",
 		"mod.started",
 		"mod.stopped",
 		"endpoint.new",
 		"endpoint.lost",
+		"endpoint.changed",
 		"wifi.client.lost",
 		"wifi.client.probe",
 		"wifi.client.new",
 		"wifi.client.handshake",
 		"wifi.ap.new",
 		"wifi.ap.lost",
+		"wifi.ap.cracked",
 		"ble.device.service.discovered",
 		"ble.device.characteristic.discovered",
+		"ble.device.characteristic.changed",
 		"ble.device.connected",
 		"ble.device.new",
 		"ble.device.lost",
@@ -88,7 +94,17 @@ func (s *Session) EventsCompleter(prefix string) []string {
 		"http.spoofed-response",
 		"https.spoofed-request",
 		"https.spoofed-response",
+		"http.proxy.replayed",
+		"https.proxy.replayed",
+		"lldp.spoof.vlan",
+		"fhrp.spoof.group.new",
+		"nac.bridge.victim",
+		"port.knock.detected",
+		"net.profile.anomaly",
+		"arp.spoof.unpoisonable",
+		"dhcp6.spoof.client.dns",
 		"syn.scan",
+		"syn.scan.service",
 		"net.sniff.mdns",
 		"net.sniff.mdns",
 		"net.sniff.dot11",
@@ -75,6 +75,16 @@ func (s *Session) moduleHelp(filter string) error {
 	}
 	s.Events.Printf("%s (%s): %s\n\n", tui.Yellow(m.Name()), status, tui.Dim(m.Description()))
 
+	if required := m.Required(); len(required) > 0 {
+		s.Events.Printf("  Requires  : %s\n", strings.Join(required, ", "))
+	}
+	if conflicting := m.Conflicting(); len(conflicting) > 0 {
+		s.Events.Printf("  Conflicts : %s\n", strings.Join(conflicting, ", "))
+	}
+	if len(m.Required()) > 0 || len(m.Conflicting()) > 0 {
+		fmt.Println()
+	}
+
 	maxLen := 0
 	handlers := m.Handlers()
 	for _, h := range handlers {
@@ -442,4 +452,39 @@ func (s *Session) registerCoreHandlers() {
 			return macs
 		})))
 
+	s.addHandler(NewCommandHandler("net.tag ADDRESS TAGS",
+		`^net\.tag\s+([^\s]+)\s+(.+)$`,
+		"Add a comma separated list of TAGS to the host, access point or BLE device with the given MAC or IP ADDRESS.",
+		s.tagHandler),
+		readline.PcItem("net.tag"))
+
+	s.addHandler(NewCommandHandler("net.untag ADDRESS TAGS",
+		`^net\.untag\s+([^\s]+)\s*(.*)$`,
+		"Remove a comma separated list of TAGS from the given ADDRESS, or all of them if no TAGS are specified.",
+		s.untagHandler),
+		readline.PcItem("net.untag"))
+
+	s.addHandler(NewCommandHandler("net.note ADDRESS NOTE",
+		`^net\.note\s+([^\s]+)\s*(.*)$`,
+		"Attach a free-text NOTE to the given ADDRESS, an empty NOTE removes it.",
+		s.noteHandler),
+		readline.PcItem("net.note"))
+
+	s.addHandler(NewCommandHandler("net.tags",
+		`^net\.tags$`,
+		"Show the tags and notes of every address.",
+		s.tagsHandler),
+		readline.PcItem("net.tags"))
+
+	s.addHandler(NewCommandHandler("bus.show",
+		`^bus\.show$`,
+		"Show the topics published on the facts bus and its subscribers.",
+		s.busShowHandler),
+		readline.PcItem("bus.show"))
+
+	s.addHandler(NewCommandHandler("bus.publish TOPIC DATA",
+		`^bus\.publish\s+([^\s]+)\s+(.+)$`,
+		"Publish a fact on the bus, DATA is parsed as JSON if possible, otherwise it is published as a string.",
+		s.busPublishHandler),
+		readline.PcItem("bus.publish"))
 }
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"fmt"
+
+	"github.com/bettercap/bettercap/firewall"
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/evilsocket/islazy/log"
+)
+
+// UseInterface switches the session to the interface with the given name,
+// creating a new packet queue, firewall manager and list of hosts for it, if
+// gateway is not nil it will replace the current session gateway.
+func (s *Session) UseInterface(name string, gateway *network.Endpoint) error {
+	iface, err := network.FindInterface(name)
+	if err != nil {
+		return err
+	}
+
+	queue, err := packets.NewQueue(iface)
+	if err != nil {
+		return err
+	}
+
+	prevQueue := s.Queue
+
+	if s.Firewall != nil {
+		s.Firewall.Restore()
+	}
+
+	s.Interface = iface
+	s.Queue = queue
+	s.Firewall = firewall.Make(iface)
+	if gateway != nil {
+		s.Gateway = gateway
+	}
+	// the hosts are matched against the interface and the gateway
+	s.Lan = s.newLAN()
+
+	s.Env.Set("iface.index", fmt.Sprintf("%d", s.Interface.Index))
+	s.Env.Set("iface.name", s.Interface.Name())
+	s.Env.Set("iface.ipv4", s.Interface.IpAddress)
+	s.Env.Set("iface.ipv6", s.Interface.Ip6Address)
+	s.Env.Set("iface.mac", s.Interface.HwAddress)
+	s.Env.Set("gateway.address", s.Gateway.IpAddress)
+	s.Env.Set("gateway.mac", s.Gateway.HwAddress)
+
+	s.startNetMon()
+
+	if prevQueue != nil {
+		prevQueue.Stop()
+	}
+
+	s.Events.Log(log.INFO, "session switched to interface %s (%s)", s.Interface.Name(), s.Interface.IpAddress)
+
+	return nil
+}
@@ -66,6 +66,7 @@ type SessionJSON struct {
 	GPS        GPS               `json:"gps"`
 	Modules    ModuleList        `json:"modules"`
 	Caplets    []*caplets.Caplet `json:"caplets"`
+	Operators  []*Operator       `json:"operators"`
 }
 
 func (s *Session) MarshalJSON() ([]byte, error) {
@@ -102,6 +103,7 @@ func (s *Session) MarshalJSON() ([]byte, error) {
 		GPS:        s.GPS,
 		Modules:    s.Modules,
 		Caplets:    caplets.List(),
+		Operators:  s.Operators(),
 	}
 
 	ifaces, err := net.Interfaces()
@@ -0,0 +1,304 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/log"
+)
+
+const (
+	// what the watchdog does when a limit is exceeded
+	LimitsAlert = "alert"
+	LimitsStop  = "stop"
+
+	// the pprof label of the goroutines started by the handlers of a module
+	moduleLabel = "module"
+)
+
+var (
+	reGoroutinesCount = regexp.MustCompile(`^(\d+) @`)
+	reGoroutinesLabel = regexp.MustCompile(`^# labels: .*"` + moduleLabel + `":"([^"]+)"`)
+)
+
+// Limits are the resources the session and its modules can use before the
+// watchdog intervenes, 0 means unlimited.
+type Limits struct {
+	Children   int
+	Goroutines int
+	Loot       int64
+	Action     string
+	Period     time.Duration
+}
+
+// LimitEvent is sent when a limit is exceeded by a module.
+type LimitEvent struct {
+	Limit  string `json:"limit"`
+	Module string `json:"module"`
+	Value  int64  `json:"value"`
+	Max    int64  `json:"max"`
+	Action string `json:"action"`
+}
+
+type watchdog struct {
+	sync.Mutex
+	limits Limits
+	// limit/module pairs already reported, until they go back under the limit
+	exceeded map[string]bool
+}
+
+func newWatchdog() *watchdog {
+	return &watchdog{
+		limits: Limits{
+			Action: LimitsAlert,
+			Period: 10 * time.Second,
+		},
+		exceeded: make(map[string]bool),
+	}
+}
+
+// SavesTo declares the parameters with the files or folders where the module
+// saves what it captures, their size is checked against session.limits.loot.
+func (m *SessionModule) SavesTo(params ...string) {
+	m.saves = append(m.saves, params...)
+}
+
+func (m *SessionModule) SavedTo() []string {
+	return m.saves
+}
+
+// AddChild declares a process started by the module, it counts against
+// session.limits.children until RemoveChild is called once it exited.
+func (m *SessionModule) AddChild(p *os.Process) {
+	m.children.Store(p.Pid, p)
+}
+
+func (m *SessionModule) RemoveChild(p *os.Process) {
+	m.children.Delete(p.Pid)
+}
+
+// Children returns the number of processes started by the module and still
+// running.
+func (m *SessionModule) Children() int {
+	n := 0
+	m.children.Range(func(k, v interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// execLabeled runs a module handler with a pprof label so that the goroutines
+// it starts can be attributed to the module.
+func execLabeled(mod Module, h ModuleHandler, args []string) (err error) {
+	pprof.Do(context.Background(), pprof.Labels(moduleLabel, mod.Name()), func(context.Context) {
+		err = h.Exec(args)
+	})
+	return
+}
+
+// parseGoroutines counts the goroutines of each module from a goroutine profile.
+func parseGoroutines(r io.Reader) map[string]int {
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	last := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := reGoroutinesCount.FindStringSubmatch(line); m != nil {
+			last, _ = strconv.Atoi(m[1])
+		} else if m := reGoroutinesLabel.FindStringSubmatch(line); m != nil {
+			counts[m[1]] += last
+			last = 0
+		}
+	}
+
+	return counts
+}
+
+// ModuleGoroutines returns the number of goroutines started by each module.
+func ModuleGoroutines() map[string]int {
+	buf := bytes.Buffer{}
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return map[string]int{}
+	}
+	return parseGoroutines(&buf)
+}
+
+func pathSize(path string) int64 {
+	size := int64(0)
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// LootSize returns how many bytes the module saved to its files or folders.
+func (s *Session) LootSize(m Module) int64 {
+	size := int64(0)
+	for _, name := range m.SavedTo() {
+		if p, found := m.Parameters()[name]; found {
+			if err, v := p.Get(s); err == nil {
+				if path, ok := v.(string); ok && path != "" {
+					if path, err = fs.Expand(path); err == nil {
+						size += pathSize(path)
+					}
+				}
+			}
+		}
+	}
+	return size
+}
+
+// Limits returns the current limits of the session.
+func (s *Session) Limits() Limits {
+	s.watchdog.Lock()
+	defer s.watchdog.Unlock()
+	return s.watchdog.limits
+}
+
+func (s *Session) setupLimits() {
+	configure := func(string) {
+		limits := Limits{}
+		var err error
+		var loot, period int
+
+		if err, limits.Children = s.Env.GetInt("session.limits.children"); err != nil {
+			s.Events.Log(log.ERROR, "session.limits.children: %s", err)
+		} else if err, limits.Goroutines = s.Env.GetInt("session.limits.goroutines"); err != nil {
+			s.Events.Log(log.ERROR, "session.limits.goroutines: %s", err)
+		} else if err, loot = s.Env.GetInt("session.limits.loot"); err != nil {
+			s.Events.Log(log.ERROR, "session.limits.loot: %s", err)
+		} else if err, period = s.Env.GetInt("session.limits.period"); err != nil || period <= 0 {
+			s.Events.Log(log.ERROR, "session.limits.period must be a positive number of seconds")
+		} else if _, limits.Action = s.Env.Get("session.limits.action"); limits.Action != LimitsAlert && limits.Action != LimitsStop {
+			s.Events.Log(log.ERROR, "session.limits.action must be either %s or %s", LimitsAlert, LimitsStop)
+		} else {
+			limits.Loot = int64(loot) * 1024 * 1024
+			limits.Period = time.Duration(period) * time.Second
+
+			s.watchdog.Lock()
+			s.watchdog.limits = limits
+			s.watchdog.Unlock()
+		}
+	}
+
+	defaults := map[string]string{
+		"session.limits.children":   "0",
+		"session.limits.goroutines": "0",
+		"session.limits.loot":       "0",
+		"session.limits.action":     LimitsAlert,
+		"session.limits.period":     "10",
+	}
+
+	// set them all before the callbacks so they don't see a partial config
+	for name, value := range defaults {
+		if found, _ := s.Env.Get(name); !found {
+			s.Env.Set(name, value)
+		}
+	}
+
+	for name := range defaults {
+		_, value := s.Env.Get(name)
+		s.Env.WithCallback(name, value, configure)
+	}
+}
+
+// forget clears the limits exceeded by a module that is not running anymore,
+// so they are reported again if it exceeds them once restarted.
+func (s *Session) forget(module string) {
+	s.watchdog.Lock()
+	defer s.watchdog.Unlock()
+	for key := range s.watchdog.exceeded {
+		if strings.HasSuffix(key, "/"+module) {
+			delete(s.watchdog.exceeded, key)
+		}
+	}
+}
+
+// exceeds reports the limit the first time it's exceeded by the module and
+// returns true if the module needs to be stopped.
+func (s *Session) exceeds(limit string, module string, value int64, max int64) bool {
+	key := limit + "/" + module
+
+	s.watchdog.Lock()
+	action := s.watchdog.limits.Action
+	if max <= 0 || value <= max {
+		delete(s.watchdog.exceeded, key)
+		s.watchdog.Unlock()
+		return false
+	} else if s.watchdog.exceeded[key] {
+		s.watchdog.Unlock()
+		return false
+	}
+	s.watchdog.exceeded[key] = true
+	s.watchdog.Unlock()
+
+	s.Events.Log(log.WARNING, "%s limit exceeded by %s: %d > %d", limit, module, value, max)
+
+	s.Events.Add("session.limit", LimitEvent{
+		Limit:  limit,
+		Module: module,
+		Value:  value,
+		Max:    max,
+		Action: action,
+	})
+
+	return action == LimitsStop
+}
+
+func (s *Session) stopOffender(m Module, reason string) {
+	s.Events.Log(log.WARNING, "stopping %s: %s", m.Name(), reason)
+	if err := m.Stop(); err != nil {
+		s.Events.Log(log.ERROR, "could not stop %s: %s", m.Name(), err)
+	} else {
+		s.forget(m.Name())
+	}
+}
+
+func (s *Session) checkLimits() {
+	limits := s.Limits()
+	goroutines := ModuleGoroutines()
+
+	for _, m := range s.Modules {
+		if !m.Running() {
+			s.forget(m.Name())
+			continue
+		}
+
+		if s.exceeds("goroutines", m.Name(), int64(goroutines[m.Name()]), int64(limits.Goroutines)) {
+			s.stopOffender(m, fmt.Sprintf("more than %d goroutines", limits.Goroutines))
+		} else if s.exceeds("children", m.Name(), int64(m.Children()), int64(limits.Children)) {
+			s.stopOffender(m, fmt.Sprintf("more than %d child processes", limits.Children))
+		} else if limits.Loot > 0 {
+			if s.exceeds("loot", m.Name(), s.LootSize(m), limits.Loot) {
+				s.stopOffender(m, fmt.Sprintf("more than %d MB of loot", limits.Loot/1024/1024))
+			}
+		}
+	}
+}
+
+func (s *Session) watchdogWorker() {
+	for s.Active {
+		time.Sleep(s.Limits().Period)
+		if s.Active {
+			s.checkLimits()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGoroutines(t *testing.T) {
+	profile := `goroutine profile: total 9
+4 @ 0x43a1c5 0x44a0c2 0x6c2d3e 0x46d1e1
+# labels: {"module":"net.sniff"}
+#	0x6c2d3d	github.com/bettercap/bettercap/modules/net_sniff.(*Sniffer).Start.func1+0x5d	/src/net_sniff.go:201
+
+3 @ 0x43a1c5 0x409e5a 0x46d1e1
+#	0x409e59	main.main+0x39	/src/main.go:12
+
+2 @ 0x43a1c5 0x44a0c2 0x46d1e1
+# labels: {"module":"wifi", "other":"label"}
+#	0x44a0c1	time.Sleep+0x121	/usr/lib/go/src/runtime/time.go:195
+`
+
+	counts := parseGoroutines(strings.NewReader(profile))
+	if len(counts) != 2 {
+		t.Fatalf("expected 2 modules, got %v", counts)
+	} else if counts["net.sniff"] != 4 {
+		t.Fatalf("expected 4 goroutines for net.sniff, got %d", counts["net.sniff"])
+	} else if counts["wifi"] != 2 {
+		t.Fatalf("expected 2 goroutines for wifi, got %d", counts["wifi"])
+	}
+}
+
+func TestModuleGoroutines(t *testing.T) {
+	done := make(chan bool)
+	defer close(done)
+
+	pprof.Do(context.Background(), pprof.Labels(moduleLabel, "test.module"), func(context.Context) {
+		for i := 0; i < 3; i++ {
+			go func() {
+				<-done
+			}()
+		}
+	})
+
+	// give them time to be scheduled
+	time.Sleep(10 * time.Millisecond)
+
+	if n := ModuleGoroutines()["test.module"]; n != 3 {
+		t.Fatalf("expected 3 goroutines, got %d", n)
+	}
+}
+
+func TestPathSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bettercap-loot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]int{
+		"a.pcap":     100,
+		"sub/b.pcap": 23,
+	}
+	for name, size := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if size := pathSize(dir); size != 123 {
+		t.Fatalf("expected 123 bytes, got %d", size)
+	} else if size = pathSize(filepath.Join(dir, "a.pcap")); size != 100 {
+		t.Fatalf("expected 100 bytes, got %d", size)
+	} else if size = pathSize(filepath.Join(dir, "missing")); size != 0 {
+		t.Fatalf("expected 0 bytes, got %d", size)
+	}
+}
+
+func TestExceedsForget(t *testing.T) {
+	s := &Session{
+		Events:   NewEventPool(false, true),
+		watchdog: newWatchdog(),
+	}
+	s.watchdog.limits.Action = LimitsStop
+
+	if !s.exceeds("goroutines", "net.sniff", 10, 5) {
+		t.Fatalf("expected the module to be stopped")
+	} else if s.exceeds("goroutines", "net.sniff", 10, 5) {
+		t.Fatalf("expected the module to be stopped only once")
+	} else if !s.exceeds("children", "net.sniff", 2, 1) {
+		t.Fatalf("expected the module to be stopped for its children")
+	}
+
+	s.forget("net.sniff")
+	if len(s.watchdog.exceeded) != 0 {
+		t.Fatalf("expected the exceeded limits to be cleared, got %v", s.watchdog.exceeded)
+	} else if !s.exceeds("goroutines", "net.sniff", 10, 5) {
+		t.Fatalf("expected the restarted module to be stopped again")
+	}
+
+	events := s.Events.Sorted()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	} else if e := events[1].Data.(LimitEvent); e.Limit != "children" || e.Module != "net.sniff" || e.Action != LimitsStop {
+		t.Fatalf("unexpected event %+v", e)
+	}
+}
+
+func TestModuleChildren(t *testing.T) {
+	m := NewSessionModule("test.module", nil)
+	a, b := &os.Process{Pid: 1000}, &os.Process{Pid: 1001}
+
+	m.AddChild(a)
+	m.AddChild(b)
+	if n := m.Children(); n != 2 {
+		t.Fatalf("expected 2 children, got %d", n)
+	}
+
+	m.RemoveChild(a)
+	if n := m.Children(); n != 1 {
+		t.Fatalf("expected 1 child, got %d", n)
+	}
+}
@@ -0,0 +1,227 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobwas/glob"
+)
+
+// Operator is someone interacting with the session, either from the
+// interactive terminal or from a remote client.
+type Operator struct {
+	Name     string    `json:"name"`
+	Origin   string    `json:"origin"`
+	Commands []string  `json:"commands"`
+	Since    time.Time `json:"since"`
+
+	allowed []glob.Glob
+}
+
+type OperatorEvent struct {
+	Name   string `json:"name"`
+	Origin string `json:"origin"`
+}
+
+type CommandEvent struct {
+	Operator string `json:"operator"`
+	Origin   string `json:"origin"`
+	Command  string `json:"command"`
+	Error    string `json:"error"`
+}
+
+var LocalOperator = &Operator{
+	Name:     "local",
+	Origin:   "tty",
+	Commands: []string{},
+	Since:    time.Now(),
+}
+
+// NewOperator creates an operator that can only run the commands matching
+// one of the commands glob expressions, or any command if the list is empty.
+func NewOperator(name string, origin string, commands []string) (*Operator, error) {
+	op := &Operator{
+		Name:     name,
+		Origin:   origin,
+		Commands: make([]string, 0),
+		Since:    time.Now(),
+		allowed:  make([]glob.Glob, 0),
+	}
+
+	for _, expr := range commands {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		} else if g, err := glob.Compile(expr); err != nil {
+			return nil, fmt.Errorf("invalid command expression '%s' for operator %s: %v", expr, name, err)
+		} else {
+			op.Commands = append(op.Commands, expr)
+			op.allowed = append(op.allowed, g)
+		}
+	}
+
+	return op, nil
+}
+
+func (op *Operator) String() string {
+	return fmt.Sprintf("%s@%s", op.Name, op.Origin)
+}
+
+// Unrestricted returns true if the operator can run any command.
+func (op *Operator) Unrestricted() bool {
+	return len(op.allowed) == 0
+}
+
+// Can returns true if the operator is allowed to run the command line.
+func (op *Operator) Can(line string) bool {
+	if op.Unrestricted() {
+		return true
+	}
+
+	for _, g := range op.allowed {
+		if g.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanRead returns true if the operator is allowed to read the value of
+// the name parameter or variable, that is to run "get name".
+func (op *Operator) CanRead(name string) bool {
+	return op.Can("get " + name)
+}
+
+// Redact removes the values the operator is not allowed to read from the
+// session, its environment or its modules serialized and decoded back as
+// generic JSON, the command line options are only left to who can run any
+// command since they can set parameters too.
+func (op *Operator) Redact(doc interface{}) interface{} {
+	if op.Unrestricted() {
+		return doc
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		// the session
+		if options, ok := v["options"].(map[string]interface{}); ok {
+			delete(options, "Commands")
+		}
+		if env, ok := v["env"]; ok {
+			v["env"] = op.Redact(env)
+		}
+		if modules, ok := v["modules"]; ok {
+			v["modules"] = op.Redact(modules)
+		}
+		// the environment
+		if data, ok := v["data"].(map[string]interface{}); ok {
+			for name := range data {
+				if !op.CanRead(name) {
+					delete(data, name)
+				}
+			}
+		}
+	case []interface{}:
+		// the modules
+		for _, m := range v {
+			if module, ok := m.(map[string]interface{}); ok {
+				if params, ok := module["parameters"].(map[string]interface{}); ok {
+					for name, p := range params {
+						if param, ok := p.(map[string]interface{}); ok && !op.CanRead(name) {
+							delete(param, "current_value")
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return doc
+}
+
+type operatorList struct {
+	sync.Mutex
+	list map[*Operator]bool
+}
+
+func newOperatorList() *operatorList {
+	return &operatorList{
+		list: make(map[*Operator]bool),
+	}
+}
+
+// Join registers a remote operator and notifies everyone else about it.
+func (s *Session) Join(op *Operator) {
+	s.operators.Lock()
+	found := s.operators.list[op]
+	s.operators.list[op] = true
+	s.operators.Unlock()
+
+	if !found {
+		s.Events.Add("session.operator.joined", OperatorEvent{
+			Name:   op.Name,
+			Origin: op.Origin,
+		})
+	}
+}
+
+// Leave removes a remote operator and notifies everyone else about it.
+func (s *Session) Leave(op *Operator) {
+	s.operators.Lock()
+	found := s.operators.list[op]
+	delete(s.operators.list, op)
+	s.operators.Unlock()
+
+	if found {
+		s.Events.Add("session.operator.left", OperatorEvent{
+			Name:   op.Name,
+			Origin: op.Origin,
+		})
+	}
+}
+
+// Operators returns the list of operators currently sharing the session.
+func (s *Session) Operators() []*Operator {
+	s.operators.Lock()
+	defer s.operators.Unlock()
+
+	remote := make([]*Operator, 0)
+	for op := range s.operators.list {
+		remote = append(remote, op)
+	}
+
+	sort.Slice(remote, func(i, j int) bool {
+		return remote[i].Since.Before(remote[j].Since)
+	})
+
+	return append([]*Operator{LocalOperator}, remote...)
+}
+
+// RunAs runs the command line on behalf of the operator, after checking its
+// permissions, and broadcasts the command and its result as an event.
+func (s *Session) RunAs(op *Operator, line string) error {
+	var err error
+
+	command := strings.TrimSpace(reCmdSpaceCleaner.ReplaceAllString(strings.TrimSpace(line), "$1 $2"))
+	if !op.Can(command) {
+		err = fmt.Errorf("operator %s is not allowed to run '%s'", op.Name, command)
+	} else {
+		err = s.Run(line)
+	}
+
+	if command != "" {
+		event := CommandEvent{
+			Operator: op.Name,
+			Origin:   op.Origin,
+			Command:  command,
+		}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		s.Events.Add("session.command", event)
+	}
+
+	return err
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"testing"
+)
+
+func TestOperatorCan(t *testing.T) {
+	op, err := NewOperator("bob", "127.0.0.1", []string{"net.show", "wifi.*", " "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if op.Unrestricted() {
+		t.Fatal("expected operator to be restricted")
+	} else if len(op.Commands) != 2 {
+		t.Fatalf("expected 2 command expressions, got %d", len(op.Commands))
+	}
+
+	for cmd, expected := range map[string]bool{
+		"net.show":          true,
+		"net.show.meta":     false,
+		"wifi.recon on":     true,
+		"arp.spoof on":      false,
+		"!rm -rf /":         false,
+		"set wifi.region x": false,
+	} {
+		if got := op.Can(cmd); got != expected {
+			t.Errorf("expected Can(%q) to be %v, got %v", cmd, expected, got)
+		}
+	}
+}
+
+func TestOperatorUnrestricted(t *testing.T) {
+	op, err := NewOperator("alice", "127.0.0.1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !op.Unrestricted() {
+		t.Fatal("expected operator to be unrestricted")
+	} else if !op.Can("!rm -rf /") {
+		t.Fatal("expected unrestricted operator to run any command")
+	}
+}
+
+func TestOperatorRedact(t *testing.T) {
+	op, err := NewOperator("bob", "127.0.0.1", []string{"get net.*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc := map[string]interface{}{
+		"options": map[string]interface{}{
+			"Caplet":   "",
+			"Commands": "set api.rest.password secret",
+		},
+		"env": map[string]interface{}{
+			"data": map[string]interface{}{
+				"net.probe":         "true",
+				"api.rest.password": "secret",
+			},
+		},
+		"modules": []interface{}{
+			map[string]interface{}{
+				"name": "api.rest",
+				"parameters": map[string]interface{}{
+					"api.rest.password": map[string]interface{}{
+						"default_value": "",
+						"current_value": "secret",
+					},
+				},
+			},
+		},
+	}
+
+	op.Redact(doc)
+
+	if _, found := doc["options"].(map[string]interface{})["Commands"]; found {
+		t.Fatal("expected the eval commands to be removed")
+	}
+
+	data := doc["env"].(map[string]interface{})["data"].(map[string]interface{})
+	if _, found := data["api.rest.password"]; found {
+		t.Fatal("expected api.rest.password to be removed from the environment")
+	} else if data["net.probe"] != "true" {
+		t.Fatalf("expected net.probe to be kept, got %v", data)
+	}
+
+	param := doc["modules"].([]interface{})[0].(map[string]interface{})["parameters"].(map[string]interface{})["api.rest.password"].(map[string]interface{})
+	if _, found := param["current_value"]; found {
+		t.Fatal("expected the current value of api.rest.password to be removed")
+	} else if _, found := param["default_value"]; !found {
+		t.Fatal("expected the default value of api.rest.password to be kept")
+	}
+
+	if LocalOperator.Redact(nil) != nil || !LocalOperator.CanRead("api.rest.password") {
+		t.Fatal("expected the local operator to read everything")
+	}
+}
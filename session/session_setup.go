@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/bettercap/bettercap/caplets"
+	"github.com/bettercap/bettercap/network"
 
 	"github.com/bettercap/readline"
 
@@ -106,7 +107,9 @@ func (s *Session) startNetMon() {
 				}
 
 				if existing != nil && event.Meta != nil {
+					hostname := existing.Hostname
 					existing.OnMeta(event.Meta)
+					s.Lan.Changed(existing, network.HostnameChange(hostname, existing.Hostname))
 				}
 			}
 		}
@@ -165,4 +168,23 @@ func (s *Session) setupEnv() {
 		}
 		s.Events.SetSilent(newSilent)
 	})
+
+	s.setupDNS()
+	s.setupLimits()
+}
+
+func (s *Session) setupDNS() {
+	configure := func(string) {
+		_, upstreams := s.Env.Get("dns.upstreams")
+		_, doh := s.Env.Get("dns.doh")
+		_, routes := s.Env.Get("dns.routes")
+		if err := network.DNS.Configure(upstreams, doh, routes); err != nil {
+			s.Events.Log(log.ERROR, "dns: %s", err)
+		}
+	}
+
+	for _, name := range []string{"dns.upstreams", "dns.doh", "dns.routes"} {
+		_, value := s.Env.Get(name)
+		s.Env.WithCallback(name, value, configure)
+	}
 }
@@ -0,0 +1,180 @@
+package session
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/str"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	TagsFile  = "~/bettercap.tags"
+	NotesFile = "~/bettercap.notes"
+)
+
+var (
+	tagsFileName, _  = fs.Expand(TagsFile)
+	notesFileName, _ = fs.Expand(NotesFile)
+)
+
+// resolve a MAC address or the IP address of a known host to the MAC
+// address tags and notes are stored by
+func (s *Session) tagTarget(address string) (string, error) {
+	if hw, err := net.ParseMAC(address); err == nil {
+		return normalizeMac(hw.String()), nil
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("'%s' is not a valid MAC or IP address", address)
+	}
+
+	for _, e := range []*network.Endpoint{s.Interface, s.Gateway} {
+		if e != nil && (e.IpAddress == address || e.Ip6Address == address) {
+			return e.HwAddress, nil
+		}
+	}
+
+	mac := ""
+	s.Lan.EachHost(func(m string, e *network.Endpoint) {
+		if e.IpAddress == address || e.Ip6Address == address {
+			mac = m
+		}
+	})
+	if mac != "" {
+		return mac, nil
+	}
+
+	if hw, err := s.FindMAC(ip, false); err == nil {
+		return normalizeMac(hw.String()), nil
+	}
+
+	return "", fmt.Errorf("could not find the MAC address of %s", address)
+}
+
+// TagsOf returns the tags and the note of the host, access point or device with this MAC address.
+func (s *Session) TagsOf(mac string) ([]string, string) {
+	mac = normalizeMac(mac)
+	return network.ParseTags(s.Tags.GetOr(mac, "")), s.Notes.GetOr(mac, "")
+}
+
+func (s *Session) propagateTags(mac string, tags []string, note string) {
+	mac = normalizeMac(mac)
+
+	if len(tags) == 0 {
+		s.Tags.Del(mac)
+	} else {
+		s.Tags.Set(mac, strings.Join(tags, ","))
+	}
+
+	if note == "" {
+		s.Notes.Del(mac)
+	} else {
+		s.Notes.Set(mac, note)
+	}
+
+	if dev, found := s.BLE.Get(mac); found {
+		dev.Tags, dev.Note = tags, note
+	}
+
+	if ap, found := s.WiFi.Get(mac); found {
+		ap.Tags, ap.Note = tags, note
+	}
+
+	if sta, found := s.WiFi.GetClient(mac); found {
+		sta.Tags, sta.Note = tags, note
+	}
+
+	if host, found := s.Lan.Get(mac); found {
+		host.Tags, host.Note = tags, note
+	}
+
+	for _, e := range []*network.Endpoint{s.Interface, s.Gateway} {
+		if e != nil && e.HwAddress == mac {
+			e.Tags, e.Note = tags, note
+		}
+	}
+}
+
+func (s *Session) tagHandler(args []string, sess *Session) error {
+	mac, err := s.tagTarget(args[0])
+	if err != nil {
+		return err
+	}
+
+	tags, note := s.TagsOf(mac)
+	s.propagateTags(mac, network.AddTags(tags, network.ParseTags(args[1])), note)
+	return nil
+}
+
+func (s *Session) untagHandler(args []string, sess *Session) error {
+	mac, err := s.tagTarget(args[0])
+	if err != nil {
+		return err
+	}
+
+	tags, note := s.TagsOf(mac)
+	if del := network.ParseTags(args[1]); len(del) > 0 {
+		tags = network.RemoveTags(tags, del)
+	} else {
+		tags = nil
+	}
+	s.propagateTags(mac, tags, note)
+	return nil
+}
+
+func (s *Session) noteHandler(args []string, sess *Session) error {
+	mac, err := s.tagTarget(args[0])
+	if err != nil {
+		return err
+	}
+
+	note := str.Trim(args[1])
+	if note == "\"\"" || note == "''" {
+		note = ""
+	}
+
+	tags, _ := s.TagsOf(mac)
+	s.propagateTags(mac, tags, note)
+	return nil
+}
+
+func (s *Session) tagsHandler(args []string, sess *Session) error {
+	macs := map[string]bool{}
+	collect := func(mac, v string) bool {
+		macs[mac] = true
+		return false
+	}
+	s.Tags.Each(collect)
+	s.Notes.Each(collect)
+
+	if len(macs) == 0 {
+		fmt.Fprintf(s.Events.Stdout, "\nno tags or notes, use net.tag ADDRESS TAGS or net.note ADDRESS NOTE to add them\n\n")
+		return nil
+	}
+
+	rows := [][]string{}
+	for mac := range macs {
+		tags, note := s.TagsOf(mac)
+		rows = append(rows, []string{
+			mac,
+			tui.Green(s.Aliases.GetOr(mac, "")),
+			tui.Yellow(strings.Join(tags, ", ")),
+			note,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+
+	tui.Table(s.Events.Stdout, []string{"MAC", "Alias", "Tags", "Note"}, rows)
+	s.Refresh()
+
+	return nil
+}